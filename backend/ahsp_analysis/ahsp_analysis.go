@@ -0,0 +1,331 @@
+package ahsp_analysis
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_labor_components"
+	ahsp_material_components "github.com/momokii/go-rab-maker/backend/repository/ahsp_material_components"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+const (
+	PRICE_SOURCE_CURRENT = "current" // default prices of the master materials and labor types
+	PRICE_SOURCE_PROJECT = "project" // prices captured in the cost lines of a project
+
+	DEFAULT_OVERHEAD_PERCENT = 10.0
+
+	EXCEL_FORMAT_COEFFICIENT = `0.0000`
+)
+
+type Builder struct {
+	materialComponentsRepo ahsp_material_components.Repository
+	laborComponentsRepo    ahsp_labor_components.Repository
+	itemCostsRepo          project_item_costs.Repository
+}
+
+func NewBuilder(
+	materialComponentsRepo ahsp_material_components.Repository,
+	laborComponentsRepo ahsp_labor_components.Repository,
+	itemCostsRepo project_item_costs.Repository,
+) *Builder {
+	return &Builder{
+		materialComponentsRepo: materialComponentsRepo,
+		laborComponentsRepo:    laborComponentsRepo,
+		itemCostsRepo:          itemCostsRepo,
+	}
+}
+
+// Build prices every template with the selected price source. With PRICE_SOURCE_PROJECT, items the
+// project has no price for keep their current master price.
+func (b *Builder) Build(ctx context.Context, tx *sql.Tx, templates []models.AHSPTemplate, options models.AhspAnalysisOptions) ([]models.AhspAnalysis, error) {
+	projectPrices := map[string]float64{}
+	if options.PriceSource == PRICE_SOURCE_PROJECT {
+		prices, err := b.itemCostsRepo.FindUnitPricesByProjectId(ctx, tx, options.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		for _, price := range prices {
+			projectPrices[priceKey(price.ItemType, price.ItemId)] = price.UnitPrice
+		}
+	}
+
+	priceOf := func(itemType string, itemId int, current float64) float64 {
+		if price, ok := projectPrices[priceKey(itemType, itemId)]; ok {
+			return price
+		}
+		return current
+	}
+
+	var analyses []models.AhspAnalysis
+	for _, template := range templates {
+		analysis := models.AhspAnalysis{
+			Template:        template,
+			OverheadPercent: options.OverheadPercent,
+		}
+
+		materialComponents, err := b.materialComponentsRepo.FindByTemplateIdWithMaterialInfo(ctx, tx, template.TemplateId)
+		if err != nil {
+			return nil, err
+		}
+		for _, component := range materialComponents {
+			line := models.AhspAnalysisLine{
+				Name:        component.MaterialName,
+				Unit:        component.MaterialUnit,
+				Coefficient: component.Coefficient,
+				UnitPrice:   priceOf(string(models.PROJECT_ITEM_TYPE_MATERIAL), component.MaterialId, component.MaterialPrice),
+			}
+			if component.IsEquipment {
+				analysis.Equipment = append(analysis.Equipment, line)
+			} else {
+				analysis.Materials = append(analysis.Materials, line)
+			}
+		}
+
+		laborComponents, err := b.laborComponentsRepo.FindByTemplateIdWithLaborInfo(ctx, tx, template.TemplateId)
+		if err != nil {
+			return nil, err
+		}
+		for _, component := range laborComponents {
+			analysis.Labor = append(analysis.Labor, models.AhspAnalysisLine{
+				Name:        component.LaborTypeName,
+				Unit:        component.LaborUnit,
+				Coefficient: component.Coefficient,
+				UnitPrice:   priceOf(string(models.PROJECT_ITEM_TYPE_LABOR), component.LaborTypeId, component.LaborWage),
+			})
+		}
+
+		Calculate(&analysis)
+		analyses = append(analyses, analysis)
+	}
+
+	return analyses, nil
+}
+
+// Calculate fills the line amounts and the A to F totals of an analysis
+func Calculate(analysis *models.AhspAnalysis) {
+	sum := func(lines []models.AhspAnalysisLine) float64 {
+		total := 0.0
+		for i := range lines {
+			lines[i].Amount = lines[i].Coefficient * lines[i].UnitPrice
+			total += lines[i].Amount
+		}
+		return total
+	}
+
+	analysis.MaterialTotal = sum(analysis.Materials)
+	analysis.LaborTotal = sum(analysis.Labor)
+	analysis.EquipmentTotal = sum(analysis.Equipment)
+	analysis.Subtotal = analysis.MaterialTotal + analysis.LaborTotal + analysis.EquipmentTotal
+	analysis.Overhead = analysis.Subtotal * analysis.OverheadPercent / 100
+	analysis.UnitPrice = analysis.Subtotal + analysis.Overhead
+}
+
+func priceKey(itemType string, itemId int) string {
+	return itemType + ":" + strconv.Itoa(itemId)
+}
+
+// Title returns the code and name of the analysed template, e.g. "SNI 2836:2008 6.1 - Galian tanah"
+func Title(template models.AHSPTemplate) string {
+	if template.Code == "" {
+		return template.TemplateName
+	}
+	return template.Code + " - " + template.TemplateName
+}
+
+type section struct {
+	letter string
+	label  string
+	lines  []models.AhspAnalysisLine
+	total  float64
+}
+
+func sections(analysis models.AhspAnalysis) []section {
+	return []section{
+		{"A", "Materials", analysis.Materials, analysis.MaterialTotal},
+		{"B", "Labor", analysis.Labor, analysis.LaborTotal},
+		{"C", "Equipment", analysis.Equipment, analysis.EquipmentTotal},
+	}
+}
+
+var analysisHeaders = []string{"No", "Description", "Unit", "Coefficient", "Unit Price", "Amount"}
+
+// WriteExcel writes one sheet per analysis. Amounts, section totals, overhead and the unit price
+// are formulas, so coefficients and prices can be adjusted in the workbook.
+func WriteExcel(analyses []models.AhspAnalysis, options models.AhspAnalysisOptions) ([]byte, error) {
+	excel := utils.NewExcelExporter()
+	usedNames := map[string]bool{}
+
+	for _, analysis := range analyses {
+		sheetOptions := utils.ExcelSheetOptions{
+			Title: "Unit Price Analysis (AHSP)",
+			Subtitles: []string{
+				Title(analysis.Template),
+				"Unit: " + analysis.Template.Unit,
+				"Prices: " + options.PriceLabel,
+			},
+			ColumnWidths:  []float64{6, 44, 10, 14, 18, 20},
+			ColumnFormats: []string{"", "", "", EXCEL_FORMAT_COEFFICIENT, utils.EXCEL_FORMAT_RUPIAH, utils.EXCEL_FORMAT_RUPIAH},
+			FreezeHeader:  true,
+		}
+
+		firstRow := sheetOptions.FirstDataRow()
+		var rows [][]interface{}
+		var totalRows []int
+
+		for _, section := range sections(analysis) {
+			sheetOptions.BoldRows = append(sheetOptions.BoldRows, len(rows))
+			rows = append(rows, []interface{}{section.letter, section.label, "", "", "", ""})
+
+			linesFirstRow := firstRow + len(rows)
+			for i, line := range section.lines {
+				rows = append(rows, []interface{}{
+					i + 1,
+					line.Name,
+					line.Unit,
+					line.Coefficient,
+					line.UnitPrice,
+					utils.ExcelFormula("D{row}*E{row}"),
+				})
+			}
+
+			total := utils.ExcelFormula("0")
+			if len(section.lines) > 0 {
+				total = utils.ExcelFormula(fmt.Sprintf("SUM(F%d:F%d)", linesFirstRow, firstRow+len(rows)-1))
+			}
+
+			totalRows = append(totalRows, firstRow+len(rows))
+			sheetOptions.BoldRows = append(sheetOptions.BoldRows, len(rows))
+			rows = append(rows, []interface{}{"", "Total " + section.letter, "", "", "", total})
+		}
+
+		subtotalRow := firstRow + len(rows)
+		overheadRow := subtotalRow + 1
+		sheetOptions.BoldRows = append(sheetOptions.BoldRows, len(rows), len(rows)+1, len(rows)+2)
+		rows = append(rows,
+			[]interface{}{"D", "Materials, labor and equipment (A + B + C)", "", "", "", utils.ExcelFormula(fmt.Sprintf("F%d+F%d+F%d", totalRows[0], totalRows[1], totalRows[2]))},
+			[]interface{}{"E", "Overhead & profit " + formatPercent(analysis.OverheadPercent) + " x D", "", "", "", utils.ExcelFormula(fmt.Sprintf("F%d*%s/100", subtotalRow, strconv.FormatFloat(analysis.OverheadPercent, 'f', -1, 64)))},
+			[]interface{}{"F", "Unit price (D + E) per " + analysis.Template.Unit, "", "", "", utils.ExcelFormula(fmt.Sprintf("F%d+F%d", subtotalRow, overheadRow))},
+		)
+
+		if err := excel.AddSheetWithOptions(sheetName(analysis.Template, usedNames), analysisHeaders, rows, sheetOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	return excel.Write()
+}
+
+// WritePDF writes one page per analysis
+func WritePDF(analyses []models.AhspAnalysis, options models.AhspAnalysisOptions) ([]byte, error) {
+	pdf := utils.NewPDFExporter("P", "mm", "A4")
+
+	tableOptions := utils.PDFTableOptions{
+		Widths: []float64{10, 70, 18, 24, 32, 36},
+		Aligns: []string{"C", "L", "C", "R", "R", "R"},
+	}
+
+	for i, analysis := range analyses {
+		if i > 0 {
+			pdf.AddPage()
+		}
+
+		pdf.AddTitle("Unit Price Analysis (AHSP)")
+		pdf.AddText(Title(analysis.Template))
+		pdf.AddText("Unit: " + analysis.Template.Unit)
+		pdf.AddText("Prices: " + options.PriceLabel)
+
+		var rows [][]string
+		var boldRows []int
+
+		for _, section := range sections(analysis) {
+			boldRows = append(boldRows, len(rows))
+			rows = append(rows, []string{section.letter, section.label, "", "", "", ""})
+
+			for j, line := range section.lines {
+				rows = append(rows, []string{
+					strconv.Itoa(j + 1),
+					line.Name,
+					line.Unit,
+					strconv.FormatFloat(line.Coefficient, 'f', 4, 64),
+					formatRupiah(line.UnitPrice),
+					formatRupiah(line.Amount),
+				})
+			}
+
+			boldRows = append(boldRows, len(rows))
+			rows = append(rows, []string{"", "Total " + section.letter, "", "", "", formatRupiah(section.total)})
+		}
+
+		boldRows = append(boldRows, len(rows), len(rows)+1, len(rows)+2)
+		rows = append(rows,
+			[]string{"D", "Materials, labor and equipment (A + B + C)", "", "", "", formatRupiah(analysis.Subtotal)},
+			[]string{"E", "Overhead & profit " + formatPercent(analysis.OverheadPercent) + " x D", "", "", "", formatRupiah(analysis.Overhead)},
+			[]string{"F", "Unit price (D + E) per " + analysis.Template.Unit, "", "", "", formatRupiah(analysis.UnitPrice)},
+		)
+
+		tableOptions.BoldRows = boldRows
+		pdf.AddTableWithOptions(analysisHeaders, rows, tableOptions)
+	}
+
+	return pdf.Write()
+}
+
+// sheetName derives a unique Excel sheet name (max 31 characters, no []:*?/\) from the template code
+func sheetName(template models.AHSPTemplate, usedNames map[string]bool) string {
+	name := template.Code
+	if name == "" {
+		name = "AHSP " + strconv.Itoa(template.TemplateId)
+	}
+
+	name = strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "", "/", "-", "\\", "-", "'", "").Replace(name)
+	name = truncateRunes(name, 31)
+
+	unique := name
+	for i := 2; usedNames[strings.ToLower(unique)]; i++ {
+		suffix := " (" + strconv.Itoa(i) + ")"
+		unique = truncateRunes(name, 31-len(suffix)) + suffix
+	}
+
+	usedNames[strings.ToLower(unique)] = true
+	return unique
+}
+
+// truncateRunes cuts s to at most n characters, never inside a multi-byte character
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+func formatPercent(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', -1, 64) + "%"
+}
+
+// formatRupiah formats a value as "Rp 1.250.000" like the web pages
+func formatRupiah(value float64) string {
+	rounded := strconv.FormatFloat(value, 'f', 0, 64)
+
+	negative := strings.HasPrefix(rounded, "-")
+	rounded = strings.TrimPrefix(rounded, "-")
+
+	var result strings.Builder
+	for i, c := range rounded {
+		if i > 0 && (len(rounded)-i)%3 == 0 {
+			result.WriteString(".")
+		}
+		result.WriteRune(c)
+	}
+
+	if negative {
+		return "-Rp " + result.String()
+	}
+	return "Rp " + result.String()
+}
@@ -0,0 +1,263 @@
+package ahsp_analysis
+
+import (
+	"bytes"
+	"database/sql"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_labor_components"
+	ahsp_material_components "github.com/momokii/go-rab-maker/backend/repository/ahsp_material_components"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/xuri/excelize/v2"
+	_ "modernc.org/sqlite"
+)
+
+// setupTestDB creates a temporary database for testing
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	tmpDB := t.TempDir() + "/test.db"
+
+	db, err := sql.Open("sqlite", "file:"+tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	// Create test schema
+	_, err = db.Exec(`
+		CREATE TABLE master_materials (
+			material_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			material_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			default_unit_price REAL NOT NULL DEFAULT 0,
+			is_equipment INTEGER NOT NULL DEFAULT 0,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE master_labor_types (
+			labor_type_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			role_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			default_daily_wage REAL NOT NULL DEFAULT 0,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE ahsp_material_components (
+			component_id INTEGER PRIMARY KEY,
+			template_id INTEGER NOT NULL,
+			material_id INTEGER NOT NULL,
+			coefficient REAL NOT NULL,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE ahsp_labor_components (
+			component_id INTEGER PRIMARY KEY,
+			template_id INTEGER NOT NULL,
+			labor_type_id INTEGER NOT NULL,
+			coefficient REAL NOT NULL,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE project_work_items (
+			work_item_id INTEGER PRIMARY KEY,
+			project_id INTEGER NOT NULL,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE project_item_costs (
+			cost_id INTEGER PRIMARY KEY,
+			work_item_id INTEGER NOT NULL,
+			item_type TEXT NOT NULL,
+			master_item_id INTEGER,
+			unit_price_at_creation REAL NOT NULL
+		);
+
+		INSERT INTO master_materials (material_id, material_name, unit, default_unit_price, is_equipment) VALUES
+			(1, 'Semen Portland', 'kg', 1500, 0),
+			(2, 'Pasir beton', 'm3', 250000, 0),
+			(3, 'Molen', 'jam', 50000, 1);
+		INSERT INTO master_labor_types (labor_type_id, role_name, unit, default_daily_wage) VALUES
+			(1, 'Pekerja', 'OH', 100000);
+		INSERT INTO ahsp_material_components (template_id, material_id, coefficient) VALUES
+			(1, 1, 300), (1, 2, 0.5), (1, 3, 0.25);
+		INSERT INTO ahsp_labor_components (template_id, labor_type_id, coefficient) VALUES
+			(1, 1, 1.5);
+
+		-- project 7 bought cement at 1.800, the newest line wins; sand has a manual line only
+		INSERT INTO project_work_items (work_item_id, project_id) VALUES (1, 7);
+		INSERT INTO project_item_costs (work_item_id, item_type, master_item_id, unit_price_at_creation) VALUES
+			(1, 'MATERIAL', 1, 1600),
+			(1, 'MATERIAL', 1, 1800),
+			(1, 'MATERIAL', 0, 999999);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+
+	return db
+}
+
+func buildAnalyses(t *testing.T, db *sql.DB, options models.AhspAnalysisOptions) []models.AhspAnalysis {
+	ctx := t.Context()
+
+	t.Helper()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	builder := NewBuilder(
+		ahsp_material_components.NewAHSPMaterialComponentsRepo(),
+		ahsp_labor_components.NewAHSPLaborComponentsRepo(),
+		project_item_costs.NewProjectItemCostsRepo(),
+	)
+
+	template := models.AHSPTemplate{TemplateId: 1, Code: "SNI 7394:2008 6.6", TemplateName: "Beton mutu f'c 19,3 MPa", Unit: "m3"}
+	analyses, err := builder.Build(ctx, tx, []models.AHSPTemplate{template}, options)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	return analyses
+}
+
+// TestBuild_CurrentPrices verifies components are split into materials, labor and equipment and totalled
+func TestBuild_CurrentPrices(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	analyses := buildAnalyses(t, db, models.AhspAnalysisOptions{PriceSource: PRICE_SOURCE_CURRENT, OverheadPercent: 10})
+	if len(analyses) != 1 {
+		t.Fatalf("Expected 1 analysis, got %d", len(analyses))
+	}
+
+	analysis := analyses[0]
+	if len(analysis.Materials) != 2 || len(analysis.Labor) != 1 || len(analysis.Equipment) != 1 {
+		t.Fatalf("Expected 2 materials, 1 labor and 1 equipment line, got %+v", analysis)
+	}
+
+	// A = 300*1500 + 0.5*250000, B = 1.5*100000, C = 0.25*50000
+	if analysis.MaterialTotal != 575000 || analysis.LaborTotal != 150000 || analysis.EquipmentTotal != 12500 {
+		t.Errorf("Unexpected section totals: %v %v %v", analysis.MaterialTotal, analysis.LaborTotal, analysis.EquipmentTotal)
+	}
+	if analysis.Subtotal != 737500 || analysis.Overhead != 73750 || analysis.UnitPrice != 811250 {
+		t.Errorf("Unexpected D/E/F: %v %v %v", analysis.Subtotal, analysis.Overhead, analysis.UnitPrice)
+	}
+}
+
+// TestBuild_ProjectPrices verifies project prices override master prices and missing ones fall back
+func TestBuild_ProjectPrices(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	analyses := buildAnalyses(t, db, models.AhspAnalysisOptions{PriceSource: PRICE_SOURCE_PROJECT, ProjectId: 7})
+	analysis := analyses[0]
+
+	if analysis.Materials[0].UnitPrice != 1800 {
+		t.Errorf("Expected the latest project price 1800 for cement, got %v", analysis.Materials[0].UnitPrice)
+	}
+	if analysis.Materials[1].UnitPrice != 250000 {
+		t.Errorf("Expected sand to keep its master price, got %v", analysis.Materials[1].UnitPrice)
+	}
+	if analysis.Overhead != 0 || analysis.UnitPrice != analysis.Subtotal {
+		t.Errorf("Expected no overhead, got %+v", analysis)
+	}
+}
+
+// TestWriteExcel_FormulasMatchCalculation verifies the workbook formulas compute the same unit price
+func TestWriteExcel_FormulasMatchCalculation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	options := models.AhspAnalysisOptions{PriceSource: PRICE_SOURCE_CURRENT, PriceLabel: "Current master prices", OverheadPercent: 15}
+	analyses := buildAnalyses(t, db, options)
+	analyses = append(analyses, analyses[0]) // same code twice needs unique sheet names
+
+	data, err := WriteExcel(analyses, options)
+	if err != nil {
+		t.Fatalf("WriteExcel failed: %v", err)
+	}
+
+	file, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to open workbook: %v", err)
+	}
+
+	sheets := file.GetSheetList()
+	if len(sheets) != 2 || sheets[0] != "SNI 7394-2008 6.6" || sheets[1] != "SNI 7394-2008 6.6 (2)" {
+		t.Fatalf("Unexpected sheet names %v", sheets)
+	}
+
+	// the unit price (F) is the last row of the table
+	rows, err := file.GetRows(sheets[0])
+	if err != nil {
+		t.Fatalf("GetRows failed: %v", err)
+	}
+
+	value, err := file.CalcCellValue(sheets[0], "F"+strconv.Itoa(len(rows)), excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatalf("CalcCellValue failed: %v", err)
+	}
+
+	got, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		t.Fatalf("Unit price %q is not a number", value)
+	}
+	if math.Abs(got-analyses[0].UnitPrice) > 0.001 {
+		t.Errorf("Workbook unit price %v, expected %v", got, analyses[0].UnitPrice)
+	}
+}
+
+// TestWritePDF verifies a PDF is produced with non-ASCII template names
+func TestWritePDF(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	options := models.AhspAnalysisOptions{PriceSource: PRICE_SOURCE_CURRENT, PriceLabel: "Current master prices", OverheadPercent: 10}
+	analyses := buildAnalyses(t, db, options)
+	analyses[0].Template.Unit = "m³"
+
+	data, err := WritePDF(analyses, options)
+	if err != nil {
+		t.Fatalf("WritePDF failed: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		t.Errorf("Output is not a PDF")
+	}
+}
+
+func TestSheetName_TruncatesCharacters(t *testing.T) {
+	code := strings.Repeat("é", 40)
+	usedNames := map[string]bool{}
+
+	first := sheetName(models.AHSPTemplate{Code: code}, usedNames)
+	second := sheetName(models.AHSPTemplate{Code: code}, usedNames)
+
+	for _, name := range []string{first, second} {
+		if !utf8.ValidString(name) {
+			t.Errorf("Sheet name %q is not valid UTF-8", name)
+		}
+		if count := utf8.RuneCountInString(name); count > 31 {
+			t.Errorf("Sheet name %q has %d characters, want at most 31", name, count)
+		}
+	}
+	if first != strings.Repeat("é", 31) {
+		t.Errorf("Expected the first name to keep 31 characters, got %q", first)
+	}
+	if !strings.HasSuffix(second, " (2)") {
+		t.Errorf("Expected the duplicate name to end with \" (2)\", got %q", second)
+	}
+}
@@ -0,0 +1,229 @@
+package ahsp_library
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_labor_components"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_material_components"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+)
+
+const (
+	COMPONENT_TYPE_MATERIAL  = "material"
+	COMPONENT_TYPE_LABOR     = "labor"
+	COMPONENT_TYPE_EQUIPMENT = "equipment"
+)
+
+//go:embed data/ahsp_standard.json
+var standardLibraryJSON []byte
+
+type Library struct {
+	Version   int               `json:"version"`
+	Name      string            `json:"name"`
+	Source    string            `json:"source"`
+	Notes     string            `json:"notes"`
+	Templates []LibraryTemplate `json:"templates"`
+}
+
+type LibraryTemplate struct {
+	Code       string             `json:"code"`
+	Name       string             `json:"name"`
+	Unit       string             `json:"unit"`
+	Components []LibraryComponent `json:"components"`
+}
+
+type LibraryComponent struct {
+	Type        string  `json:"type"` // material, labor or equipment
+	Name        string  `json:"name"`
+	Unit        string  `json:"unit"`
+	Coefficient float64 `json:"coefficient"`
+}
+
+type ImportResult struct {
+	TemplatesCreated  int `json:"templates_created"`
+	TemplatesSkipped  int `json:"templates_skipped"`
+	MaterialsCreated  int `json:"materials_created"`
+	MaterialsMatched  int `json:"materials_matched"`
+	LaborTypesCreated int `json:"labor_types_created"`
+	LaborTypesMatched int `json:"labor_types_matched"`
+}
+
+// Load parses the bundled standard AHSP dataset
+func Load() (Library, error) {
+	var library Library
+
+	if err := json.Unmarshal(standardLibraryJSON, &library); err != nil {
+		return library, err
+	}
+
+	return library, nil
+}
+
+type Importer struct {
+	materialsRepo          master_materials.Repository
+	laborTypesRepo         master_labor_types.Repository
+	ahspTemplatesRepo      ahsptemplates.Repository
+	materialComponentsRepo ahsp_material_components.Repository
+	laborComponentsRepo    ahsp_labor_components.Repository
+}
+
+func NewImporter(
+	materialsRepo master_materials.Repository,
+	laborTypesRepo master_labor_types.Repository,
+	ahspTemplatesRepo ahsptemplates.Repository,
+	materialComponentsRepo ahsp_material_components.Repository,
+	laborComponentsRepo ahsp_labor_components.Repository,
+) *Importer {
+	return &Importer{
+		materialsRepo:          materialsRepo,
+		laborTypesRepo:         laborTypesRepo,
+		ahspTemplatesRepo:      ahspTemplatesRepo,
+		materialComponentsRepo: materialComponentsRepo,
+		laborComponentsRepo:    laborComponentsRepo,
+	}
+}
+
+// Import writes the library as system-wide defaults (user_id IS NULL).
+// Templates whose code already exists are skipped, so running it twice is safe.
+// Components are mapped to existing system-wide masters by name and unit;
+// missing masters are created with a price of 0 for the user to fill in.
+func (i *Importer) Import(ctx context.Context, tx *sql.Tx, library Library) (ImportResult, error) {
+	var result ImportResult
+
+	// cache by name+unit so shared masters (e.g. "Pekerja") are only counted once
+	materialIds := map[string]int{}
+	laborTypeIds := map[string]int{}
+
+	for _, template := range library.Templates {
+		if _, err := i.ahspTemplatesRepo.FindByCode(ctx, tx, template.Code, models.Workspace{}); err == nil {
+			result.TemplatesSkipped++
+			continue
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return result, err
+		}
+
+		if err := i.ahspTemplatesRepo.Create(ctx, tx, models.AHSPTemplateCreate{
+			Code:         template.Code,
+			TemplateName: template.Name,
+			Unit:         template.Unit,
+		}); err != nil {
+			return result, err
+		}
+
+		newTemplate, err := i.ahspTemplatesRepo.FindByCode(ctx, tx, template.Code, models.Workspace{})
+		if err != nil {
+			return result, err
+		}
+
+		for _, component := range template.Components {
+			key := strings.ToLower(component.Name + "|" + component.Unit)
+
+			switch component.Type {
+			case COMPONENT_TYPE_MATERIAL, COMPONENT_TYPE_EQUIPMENT:
+				materialId, ok := materialIds[key]
+				if !ok {
+					materialId, err = i.resolveMaterial(ctx, tx, component, &result)
+					if err != nil {
+						return result, err
+					}
+					materialIds[key] = materialId
+				}
+
+				if err := i.materialComponentsRepo.Create(ctx, tx, models.AHSPMaterialComponentCreate{
+					TemplateId:  newTemplate.TemplateId,
+					MaterialId:  materialId,
+					Coefficient: component.Coefficient,
+				}); err != nil {
+					return result, err
+				}
+
+			case COMPONENT_TYPE_LABOR:
+				laborTypeId, ok := laborTypeIds[key]
+				if !ok {
+					laborTypeId, err = i.resolveLaborType(ctx, tx, component, &result)
+					if err != nil {
+						return result, err
+					}
+					laborTypeIds[key] = laborTypeId
+				}
+
+				if err := i.laborComponentsRepo.Create(ctx, tx, models.AHSPLaborComponentCreate{
+					TemplateId:  newTemplate.TemplateId,
+					LaborTypeId: laborTypeId,
+					Coefficient: component.Coefficient,
+				}); err != nil {
+					return result, err
+				}
+
+			default:
+				return result, fmt.Errorf("template %s: unknown component type %q", template.Code, component.Type)
+			}
+		}
+
+		result.TemplatesCreated++
+	}
+
+	return result, nil
+}
+
+func (i *Importer) resolveMaterial(ctx context.Context, tx *sql.Tx, component LibraryComponent, result *ImportResult) (int, error) {
+	material, err := i.materialsRepo.FindByNameAndUnit(ctx, tx, component.Name, component.Unit, models.Workspace{})
+	if err == nil {
+		result.MaterialsMatched++
+		return material.MaterialId, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	if err := i.materialsRepo.Create(ctx, tx, models.MasterMaterialCreate{
+		MaterialName: component.Name,
+		Unit:         component.Unit,
+		IsEquipment:  component.Type == COMPONENT_TYPE_EQUIPMENT,
+	}); err != nil {
+		return 0, err
+	}
+
+	material, err = i.materialsRepo.FindByNameAndUnit(ctx, tx, component.Name, component.Unit, models.Workspace{})
+	if err != nil {
+		return 0, err
+	}
+
+	result.MaterialsCreated++
+	return material.MaterialId, nil
+}
+
+func (i *Importer) resolveLaborType(ctx context.Context, tx *sql.Tx, component LibraryComponent, result *ImportResult) (int, error) {
+	laborType, err := i.laborTypesRepo.FindByNameAndUnit(ctx, tx, component.Name, component.Unit, models.Workspace{})
+	if err == nil {
+		result.LaborTypesMatched++
+		return laborType.LaborTypeId, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	if err := i.laborTypesRepo.Create(ctx, tx, models.MasterLaborTypeCreate{
+		RoleName: component.Name,
+		Unit:     component.Unit,
+	}); err != nil {
+		return 0, err
+	}
+
+	laborType, err = i.laborTypesRepo.FindByNameAndUnit(ctx, tx, component.Name, component.Unit, models.Workspace{})
+	if err != nil {
+		return 0, err
+	}
+
+	result.LaborTypesCreated++
+	return laborType.LaborTypeId, nil
+}
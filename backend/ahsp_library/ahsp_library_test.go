@@ -0,0 +1,305 @@
+package ahsp_library
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_labor_components"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_material_components"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	_ "modernc.org/sqlite"
+)
+
+// setupTestDB creates a temporary database for testing
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	tmpDB := t.TempDir() + "/test.db"
+
+	db, err := sql.Open("sqlite", "file:"+tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	// Enable foreign keys
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatalf("Failed to enable foreign keys: %v", err)
+	}
+
+	// Create test schema
+	_, err = db.Exec(`
+		CREATE TABLE users (
+			user_id INTEGER PRIMARY KEY,
+			username TEXT NOT NULL
+		);
+
+		CREATE TABLE master_materials (
+			material_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			material_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			default_unit_price REAL NOT NULL DEFAULT 0,
+			is_equipment INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+
+		CREATE TABLE master_labor_types (
+			labor_type_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			role_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			default_daily_wage REAL NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+
+		CREATE TABLE ahsp_templates (
+			template_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			code TEXT,
+			template_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(user_id)
+		);
+
+		CREATE TABLE ahsp_material_components (
+			component_id INTEGER PRIMARY KEY,
+			template_id INTEGER NOT NULL,
+			material_id INTEGER NOT NULL,
+			coefficient REAL NOT NULL,
+			FOREIGN KEY (template_id) REFERENCES ahsp_templates(template_id),
+			FOREIGN KEY (material_id) REFERENCES master_materials(material_id)
+		);
+
+		CREATE TABLE ahsp_labor_components (
+			component_id INTEGER PRIMARY KEY,
+			template_id INTEGER NOT NULL,
+			labor_type_id INTEGER NOT NULL,
+			coefficient REAL NOT NULL,
+			FOREIGN KEY (template_id) REFERENCES ahsp_templates(template_id),
+			FOREIGN KEY (labor_type_id) REFERENCES master_labor_types(labor_type_id)
+		);
+
+		CREATE TABLE audit_log (
+			audit_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			username TEXT NOT NULL DEFAULT '',
+			entity TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			project_id INTEGER,
+			action TEXT NOT NULL,
+			before_json TEXT NOT NULL DEFAULT '',
+			after_json TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+
+	return db
+}
+
+func newTestImporter() *Importer {
+	return NewImporter(
+		master_materials.NewMasterMaterialsRepo(),
+		master_labor_types.NewMasterLaborTypesRepo(),
+		ahsptemplates.NewAhspTemplatesRepo(),
+		ahsp_material_components.NewAHSPMaterialComponentsRepo(),
+		ahsp_labor_components.NewAHSPLaborComponentsRepo(),
+	)
+}
+
+func runImport(t *testing.T, db *sql.DB, library Library) ImportResult {
+	ctx := t.Context()
+
+	t.Helper()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+
+	result, err := newTestImporter().Import(ctx, tx, library)
+	if err != nil {
+		tx.Rollback()
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	return result
+}
+
+func countRows(t *testing.T, db *sql.DB, query string) int {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow(query).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+
+	return count
+}
+
+// TestLoad_BundledLibrary verifies the embedded dataset parses and is well formed
+func TestLoad_BundledLibrary(t *testing.T) {
+	library, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load bundled library: %v", err)
+	}
+
+	if len(library.Templates) == 0 {
+		t.Fatal("Expected bundled library to contain templates")
+	}
+
+	codes := map[string]bool{}
+	for _, template := range library.Templates {
+		if template.Code == "" || template.Name == "" || template.Unit == "" {
+			t.Errorf("Template %+v is missing code, name or unit", template)
+		}
+		if codes[template.Code] {
+			t.Errorf("Duplicate template code %s", template.Code)
+		}
+		codes[template.Code] = true
+
+		for _, component := range template.Components {
+			switch component.Type {
+			case COMPONENT_TYPE_MATERIAL, COMPONENT_TYPE_LABOR, COMPONENT_TYPE_EQUIPMENT:
+			default:
+				t.Errorf("Template %s has unknown component type %q", template.Code, component.Type)
+			}
+			if component.Coefficient <= 0 {
+				t.Errorf("Template %s component %s must have a positive coefficient", template.Code, component.Name)
+			}
+		}
+	}
+}
+
+// TestImport_Idempotent verifies a second import skips existing templates and creates nothing
+func TestImport_Idempotent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	library, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load bundled library: %v", err)
+	}
+
+	first := runImport(t, db, library)
+	if first.TemplatesCreated != len(library.Templates) {
+		t.Errorf("Expected %d templates created, got %d", len(library.Templates), first.TemplatesCreated)
+	}
+
+	materialsAfterFirst := countRows(t, db, "SELECT COUNT(*) FROM master_materials")
+	componentsAfterFirst := countRows(t, db, "SELECT COUNT(*) FROM ahsp_labor_components")
+
+	second := runImport(t, db, library)
+	if second.TemplatesCreated != 0 || second.TemplatesSkipped != len(library.Templates) {
+		t.Errorf("Expected all templates skipped on second import, got %+v", second)
+	}
+
+	if got := countRows(t, db, "SELECT COUNT(*) FROM master_materials"); got != materialsAfterFirst {
+		t.Errorf("Expected %d materials after second import, got %d", materialsAfterFirst, got)
+	}
+	if got := countRows(t, db, "SELECT COUNT(*) FROM ahsp_labor_components"); got != componentsAfterFirst {
+		t.Errorf("Expected %d labor components after second import, got %d", componentsAfterFirst, got)
+	}
+
+	// everything imported is system-wide
+	if got := countRows(t, db, "SELECT COUNT(*) FROM ahsp_templates WHERE user_id IS NOT NULL"); got != 0 {
+		t.Errorf("Expected only system-wide templates, found %d user-owned", got)
+	}
+}
+
+// TestImport_MatchesExistingMasters verifies masters are matched by name and unit instead of duplicated
+func TestImport_MatchesExistingMasters(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// existing system-wide material with different casing, and one with the same name but another unit
+	if _, err := db.Exec(`
+		INSERT INTO master_materials (material_id, user_id, material_name, unit, default_unit_price) VALUES
+			(1, NULL, 'semen portland', 'KG', 1500),
+			(2, NULL, 'Pasir urug', 'truk', 900000);
+		INSERT INTO master_labor_types (labor_type_id, user_id, role_name, unit, default_daily_wage) VALUES
+			(1, NULL, 'Pekerja', 'OH', 120000);
+	`); err != nil {
+		t.Fatalf("Failed to seed masters: %v", err)
+	}
+
+	library := Library{
+		Templates: []LibraryTemplate{
+			{
+				Code: "T-1",
+				Name: "Test analysis",
+				Unit: "m3",
+				Components: []LibraryComponent{
+					{Type: COMPONENT_TYPE_MATERIAL, Name: "Semen Portland", Unit: "kg", Coefficient: 10},
+					{Type: COMPONENT_TYPE_MATERIAL, Name: "Pasir urug", Unit: "m3", Coefficient: 1.2},
+					{Type: COMPONENT_TYPE_EQUIPMENT, Name: "Molen", Unit: "jam", Coefficient: 0.25},
+					{Type: COMPONENT_TYPE_LABOR, Name: "Pekerja", Unit: "OH", Coefficient: 0.3},
+					{Type: COMPONENT_TYPE_LABOR, Name: "Mandor", Unit: "OH", Coefficient: 0.01},
+				},
+			},
+		},
+	}
+
+	result := runImport(t, db, library)
+
+	expected := ImportResult{
+		TemplatesCreated:  1,
+		MaterialsCreated:  2, // pasir urug (different unit) and molen
+		MaterialsMatched:  1,
+		LaborTypesCreated: 1,
+		LaborTypesMatched: 1,
+	}
+	if result != expected {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	// existing price is kept and the component points at the matched material
+	var price float64
+	if err := db.QueryRow(`
+		SELECT m.default_unit_price FROM ahsp_material_components c
+		JOIN master_materials m ON m.material_id = c.material_id
+		WHERE c.material_id = 1`).Scan(&price); err != nil {
+		t.Fatalf("Expected component linked to existing material: %v", err)
+	}
+	if price != 1500 {
+		t.Errorf("Expected existing price 1500 to be kept, got %v", price)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	molen, err := master_materials.NewMasterMaterialsRepo().FindByNameAndUnit(ctx, tx, "Molen", "jam", models.Workspace{})
+	if err != nil {
+		t.Fatalf("Expected equipment to be created: %v", err)
+	}
+	if !molen.IsEquipment || molen.UserId != 0 || molen.DefaultUnitPrice != 0 {
+		t.Errorf("Expected system-wide equipment with price 0, got %+v", molen)
+	}
+
+}
@@ -0,0 +1,235 @@
+// Package audit records who created, updated or deleted the rows of projects, work items,
+// item costs, master data and AHSP components. The repositories call it in the transaction of
+// the change, so an entry is only kept if the change is committed and no handler, importer or
+// API route can change a row without one.
+//
+// A repository reads the row with Snapshot (or the rows with SnapshotWhere) before an update
+// or delete, makes the change, then calls Created, Updated or Deleted. The rows are kept as
+// stored, every column, so an entry shows what changed even after the row is gone.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/audit_log"
+)
+
+// Row is a row as stored, by column name
+type Row map[string]interface{}
+
+// source is where the rows of an entity are read from, t is the audited table. Rows of
+// entities that belong to a project carry its project_id.
+type source struct {
+	columns  string
+	from     string
+	idColumn string
+}
+
+var sources = map[string]source{
+	models.AUDIT_ENTITY_PROJECT:                 {"t.*", "projects t", "project_id"},
+	models.AUDIT_ENTITY_WORK_ITEM:               {"t.*", "project_work_items t", "work_item_id"},
+	models.AUDIT_ENTITY_ITEM_COST:               {"t.*, w.project_id", "project_item_costs t JOIN project_work_items w ON w.work_item_id = t.work_item_id", "cost_id"},
+	models.AUDIT_ENTITY_MATERIAL:                {"t.*", "master_materials t", "material_id"},
+	models.AUDIT_ENTITY_LABOR_TYPE:              {"t.*", "master_labor_types t", "labor_type_id"},
+	models.AUDIT_ENTITY_WORK_CATEGORY:           {"t.*", "master_work_categories t", "category_id"},
+	models.AUDIT_ENTITY_AHSP_TEMPLATE:           {"t.*", "ahsp_templates t", "template_id"},
+	models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT: {"t.*", "ahsp_material_components t", "component_id"},
+	models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT:    {"t.*", "ahsp_labor_components t", "component_id"},
+}
+
+var auditLogRepo audit_log.Repository = audit_log.NewAuditLogRepo()
+
+type userContextKey struct{}
+
+type contextUser struct {
+	id       int
+	username string
+}
+
+// WithUser returns a context whose changes are recorded as made by the user. The session
+// middlewares set it for every signed in request, changes without it are recorded as made
+// by the application.
+func WithUser(ctx context.Context, userId int, username string) context.Context {
+	return context.WithValue(ctx, userContextKey{}, contextUser{id: userId, username: username})
+}
+
+// UserFromContext returns the user set with WithUser, 0 and "" when there is none
+func UserFromContext(ctx context.Context) (int, string) {
+	user, _ := ctx.Value(userContextKey{}).(contextUser)
+	return user.id, user.username
+}
+
+// Snapshot reads the row of an entity, nil when it does not exist
+func Snapshot(ctx context.Context, tx *sql.Tx, entity string, id int) (Row, error) {
+	src, ok := sources[entity]
+	if !ok {
+		return nil, fmt.Errorf("audit: unknown entity %q", entity)
+	}
+
+	rows, err := SnapshotWhere(ctx, tx, entity, "t."+src.idColumn+" = ?", id)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	return rows[0], nil
+}
+
+// SnapshotWhere reads the rows of an entity matching condition, whose columns are prefixed
+// with t., before a change of many rows
+func SnapshotWhere(ctx context.Context, tx *sql.Tx, entity, condition string, args ...interface{}) ([]Row, error) {
+	src, ok := sources[entity]
+	if !ok {
+		return nil, fmt.Errorf("audit: unknown entity %q", entity)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+src.columns+" FROM "+src.from+" WHERE "+condition+" ORDER BY t."+src.idColumn, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Row
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(Row, len(columns))
+		for i, column := range columns {
+			// text may be scanned as bytes, JSON would show it as base64
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[column] = values[i]
+		}
+
+		snapshots = append(snapshots, row)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// Created records the row of an entity that was just inserted
+func Created(ctx context.Context, tx *sql.Tx, entity string, id int) error {
+	after, err := Snapshot(ctx, tx, entity, id)
+	if err != nil || after == nil {
+		return err
+	}
+
+	return record(ctx, tx, entity, models.AUDIT_ACTION_CREATE, nil, after)
+}
+
+// Updated records the change of a row read with Snapshot before the update. Nothing is
+// recorded when the row did not exist or the update left it as it was.
+func Updated(ctx context.Context, tx *sql.Tx, entity string, before Row) error {
+	if before == nil {
+		return nil
+	}
+
+	after, err := Snapshot(ctx, tx, entity, before.id(entity))
+	if err != nil || after == nil {
+		return err
+	}
+
+	return record(ctx, tx, entity, models.AUDIT_ACTION_UPDATE, before, after)
+}
+
+// Deleted records the rows read with Snapshot or SnapshotWhere before they were deleted
+func Deleted(ctx context.Context, tx *sql.Tx, entity string, before ...Row) error {
+	for _, row := range before {
+		if row == nil {
+			continue
+		}
+
+		if err := record(ctx, tx, entity, models.AUDIT_ACTION_DELETE, row, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func record(ctx context.Context, tx *sql.Tx, entity, action string, before, after Row) error {
+	beforeJSON, err := before.json()
+	if err != nil {
+		return err
+	}
+
+	afterJSON, err := after.json()
+	if err != nil {
+		return err
+	}
+
+	if action == models.AUDIT_ACTION_UPDATE && beforeJSON == afterJSON {
+		return nil
+	}
+
+	row := after
+	if row == nil {
+		row = before
+	}
+
+	userId, username := UserFromContext(ctx)
+	_, err = auditLogRepo.Create(ctx, tx, models.AuditEntryCreate{
+		UserId:     userId,
+		Username:   username,
+		Entity:     entity,
+		EntityId:   row.id(entity),
+		ProjectId:  toInt(row["project_id"]),
+		Action:     action,
+		BeforeJSON: beforeJSON,
+		AfterJSON:  afterJSON,
+	})
+
+	return err
+}
+
+// id is the primary key of the row
+func (r Row) id(entity string) int {
+	return toInt(r[sources[entity].idColumn])
+}
+
+// json is the row as a JSON object, "" for no row
+func (r Row) json() (string, error) {
+	if r == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// toInt reads an integer column, whatever type the driver scanned it as
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+
+	return 0
+}
@@ -0,0 +1,204 @@
+package audit_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/audit"
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/audit_log"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+)
+
+// TestRepositories_RecordChanges verifies the repositories record their creates, updates and
+// deletes with the user of the context and the rows before and after, skip updates that
+// change nothing and keep the history of a project moved to the trash and purged
+func TestRepositories_RecordChanges(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec("INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret')"); err != nil {
+			t.Fatalf("Failed to insert user: %v", err)
+		}
+		if _, err := tx.Exec("INSERT INTO master_work_categories (category_id, user_id, category_name) VALUES (1, 1, 'Pekerjaan Dinding')"); err != nil {
+			t.Fatalf("Failed to insert category: %v", err)
+		}
+
+		ctx := audit.WithUser(t.Context(), 1, "budi")
+		projectsRepo := projects.NewProjectsRepo()
+		workItemsRepo := project_work_items.NewProjectWorkItemRepo()
+		costsRepo := project_item_costs.NewProjectItemCostsRepo()
+		auditLogRepo := audit_log.NewAuditLogRepo()
+
+		projectId, err := projectsRepo.Create(ctx, tx, models.ProjectCreate{UserId: 1, ProjectName: "Rumah Tinggal", Location: "Bandung"})
+		if err != nil {
+			t.Fatalf("Failed to create project: %v", err)
+		}
+
+		project := models.Project{ProjectId: projectId, UserId: 1, ProjectName: "Rumah Tinggal 2 Lantai", Location: "Bandung"}
+		if err := projectsRepo.Update(ctx, tx, project); err != nil {
+			t.Fatalf("Failed to update project: %v", err)
+		}
+		// saving the same values again is not a change
+		if err := projectsRepo.Update(ctx, tx, project); err != nil {
+			t.Fatalf("Failed to update project: %v", err)
+		}
+
+		workItemId, err := workItemsRepo.Create(ctx, tx, models.ProjectWorkItemCreate{ProjectId: projectId, CategoryId: 1, Description: "Pasangan bata", Volume: 10, Unit: "m2"})
+		if err != nil {
+			t.Fatalf("Failed to create work item: %v", err)
+		}
+		if err := costsRepo.CreateMultiple(ctx, tx, []models.ProjectItemCostCreate{
+			{WorkItemId: workItemId, ItemType: "MATERIAL", MasterItemId: 1, ItemName: "Semen", Coefficient: 1, QuantityNeeded: 10, Unit: "kg", UnitPriceAtCreation: 1500, TotalCost: 15000},
+			{WorkItemId: workItemId, ItemType: "LABOR", MasterItemId: 2, ItemName: "Tukang", Coefficient: 0.1, QuantityNeeded: 1, Unit: "OH", UnitPriceAtCreation: 150000, TotalCost: 150000},
+		}); err != nil {
+			t.Fatalf("Failed to create costs: %v", err)
+		}
+
+		if err := projectsRepo.Delete(audit.WithUser(t.Context(), 0, ""), tx, project); err != nil {
+			t.Fatalf("Failed to delete project: %v", err)
+		}
+		if err := projectsRepo.Purge(audit.WithUser(t.Context(), 0, ""), tx, projectId); err != nil {
+			t.Fatalf("Failed to purge project: %v", err)
+		}
+
+		entries, pagination, err := auditLogRepo.Find(t.Context(), tx, models.AuditFilter{ProjectId: projectId}, models.TablePaginationDataInput{Page: 1, PerPage: 50})
+		if err != nil {
+			t.Fatalf("Failed to find entries: %v", err)
+		}
+
+		// newest first: the purge, the move to the trash, two costs, the work item, the update and the create
+		want := []struct{ entity, action string }{
+			{models.AUDIT_ENTITY_PROJECT, models.AUDIT_ACTION_DELETE},
+			{models.AUDIT_ENTITY_PROJECT, models.AUDIT_ACTION_UPDATE},
+			{models.AUDIT_ENTITY_ITEM_COST, models.AUDIT_ACTION_CREATE},
+			{models.AUDIT_ENTITY_ITEM_COST, models.AUDIT_ACTION_CREATE},
+			{models.AUDIT_ENTITY_WORK_ITEM, models.AUDIT_ACTION_CREATE},
+			{models.AUDIT_ENTITY_PROJECT, models.AUDIT_ACTION_UPDATE},
+			{models.AUDIT_ENTITY_PROJECT, models.AUDIT_ACTION_CREATE},
+		}
+		if len(entries) != len(want) || pagination.TotalItems != len(want) {
+			t.Fatalf("Expected %d entries, got %d: %+v", len(want), len(entries), entries)
+		}
+		for i, w := range want {
+			if entries[i].Entity != w.entity || entries[i].Action != w.action {
+				t.Errorf("Entry %d: expected %s %s, got %s %s", i, w.action, w.entity, entries[i].Action, entries[i].Entity)
+			}
+		}
+
+		trashed := entries[1]
+		if changes := trashed.Changes(); len(changes) != 1 || changes[0].Column != "deleted_at" || changes[0].Before != "" {
+			t.Errorf("Expected the move to the trash to set deleted_at, got %+v", changes)
+		}
+
+		update := entries[5]
+		if update.UserId != 1 || update.Username != "budi" || update.EntityId != projectId {
+			t.Errorf("Unexpected update entry: %+v", update)
+		}
+		changes := update.Changes()
+		if len(changes) != 1 || changes[0].Column != "project_name" || changes[0].Before != "Rumah Tinggal" || changes[0].After != "Rumah Tinggal 2 Lantai" {
+			t.Errorf("Expected only the name to change, got %+v", changes)
+		}
+
+		cost := entries[3]
+		var after map[string]interface{}
+		if err := json.Unmarshal([]byte(cost.AfterJSON), &after); err != nil || after["item_name"] != "Semen" || after["total_cost"] != float64(15000) {
+			t.Errorf("Expected the created cost as JSON, got %s (%v)", cost.AfterJSON, err)
+		}
+		if cost.BeforeJSON != "" || cost.ProjectId != projectId || cost.Name() != "Semen" {
+			t.Errorf("Unexpected cost entry: %+v", cost)
+		}
+
+		deleted := entries[0]
+		if deleted.UserId != 0 || deleted.Username != "" || deleted.AfterJSON != "" || deleted.Name() != "Rumah Tinggal 2 Lantai" {
+			t.Errorf("Expected the delete to be recorded as made by the application with the last row, got %+v", deleted)
+		}
+
+		// filters
+		for _, tc := range []struct {
+			name   string
+			filter models.AuditFilter
+			want   int
+		}{
+			{"entity", models.AuditFilter{Entity: models.AUDIT_ENTITY_ITEM_COST}, 2},
+			{"action", models.AuditFilter{Action: models.AUDIT_ACTION_UPDATE}, 2},
+			{"username", models.AuditFilter{Username: "BUD"}, 5},
+			{"future dates", models.AuditFilter{DateFrom: "2999-01-01"}, 0},
+			{"past dates", models.AuditFilter{DateFrom: "2000-01-01", DateTo: "2999-12-31"}, 7},
+			{"other project", models.AuditFilter{ProjectId: projectId + 1}, 0},
+		} {
+			found, err := auditLogRepo.FindAll(t.Context(), tx, tc.filter, 100)
+			if err != nil {
+				t.Fatalf("%s: failed to find entries: %v", tc.name, err)
+			}
+			if len(found) != tc.want {
+				t.Errorf("%s: expected %d entries, got %d", tc.name, tc.want, len(found))
+			}
+		}
+	})
+}
+
+// TestMasterDataDelete_RecordsCascade verifies purging a material also records the AHSP
+// components and project costs deleted with it, so a changed project total can be explained
+func TestMasterDataDelete_RecordsCascade(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		for _, query := range []string{
+			"INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret')",
+			"INSERT INTO master_work_categories (category_id, user_id, category_name) VALUES (1, 1, 'Pekerjaan Dinding')",
+			"INSERT INTO master_materials (material_id, user_id, material_name, unit, default_unit_price) VALUES (5, 1, 'Semen', 'kg', 1500)",
+			"INSERT INTO projects (project_id, user_id, project_name) VALUES (3, 1, 'Rumah Tinggal')",
+			"INSERT INTO project_work_items (work_item_id, project_id, category_id, description, volume, unit) VALUES (7, 3, 1, 'Pasangan bata', 10, 'm2')",
+			"INSERT INTO project_item_costs (work_item_id, item_type, master_item_id, item_name, coefficient, quantity_needed, unit, unit_price_at_creation, total_cost) VALUES (7, 'MATERIAL', 5, 'Semen', 1, 10, 'kg', 1500, 15000)",
+		} {
+			if _, err := tx.Exec(query); err != nil {
+				t.Fatalf("Failed to insert test data: %v", err)
+			}
+		}
+
+		ctx := audit.WithUser(t.Context(), 1, "budi")
+		materialsRepo := master_materials.NewMasterMaterialsRepo()
+		if err := materialsRepo.Update(ctx, tx, models.MasterMaterial{MaterialId: 5, UserId: 1, MaterialName: "Semen", Unit: "kg", DefaultUnitPrice: 1600}); err != nil {
+			t.Fatalf("Failed to update material: %v", err)
+		}
+		// a material of another user is not changed, so nothing is recorded
+		if err := materialsRepo.Update(ctx, tx, models.MasterMaterial{MaterialId: 5, UserId: 2, MaterialName: "Pasir", Unit: "m3"}); err != nil {
+			t.Fatalf("Failed to update material: %v", err)
+		}
+		if err := materialsRepo.Purge(ctx, tx, 5); err != nil {
+			t.Fatalf("Failed to purge material: %v", err)
+		}
+
+		entries, err := audit_log.NewAuditLogRepo().FindAll(t.Context(), tx, models.AuditFilter{}, 100)
+		if err != nil {
+			t.Fatalf("Failed to find entries: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("Expected the update, the material and the cost deletes, got %+v", entries)
+		}
+
+		if entries[0].Entity != models.AUDIT_ENTITY_MATERIAL || entries[0].Action != models.AUDIT_ACTION_DELETE {
+			t.Errorf("Expected the material delete last, got %+v", entries[0])
+		}
+		if entries[1].Entity != models.AUDIT_ENTITY_ITEM_COST || entries[1].Action != models.AUDIT_ACTION_DELETE || entries[1].ProjectId != 3 {
+			t.Errorf("Expected the cost delete with its project, got %+v", entries[1])
+		}
+		if changes := entries[2].Changes(); len(changes) != 1 || changes[0].Column != "default_unit_price" || changes[0].Before != "1500" || changes[0].After != "1600" {
+			t.Errorf("Expected the price change, got %+v", changes)
+		}
+	})
+}
@@ -0,0 +1,418 @@
+// Package container wires the application together: the repositories, the services
+// built on them, the handlers and the middlewares that need the database. main builds
+// it once at startup; tests build handlers with fake repositories instead.
+package container
+
+import (
+	"github.com/momokii/go-rab-maker/backend/ahsp_analysis"
+	"github.com/momokii/go-rab-maker/backend/ahsp_library"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/handlers"
+	"github.com/momokii/go-rab-maker/backend/master_import"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/project_bundle"
+	"github.com/momokii/go-rab-maker/backend/rab_import"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_labor_components"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_material_components"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/api_tokens"
+	"github.com/momokii/go-rab-maker/backend/repository/audit_log"
+	"github.com/momokii/go-rab-maker/backend/repository/dashboard"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	"github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/material_summary"
+	"github.com/momokii/go-rab-maker/backend/repository/organizations"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/repository/share_links"
+	"github.com/momokii/go-rab-maker/backend/repository/trash"
+	"github.com/momokii/go-rab-maker/backend/repository/users"
+	"github.com/momokii/go-rab-maker/backend/repository/webhook_deliveries"
+	"github.com/momokii/go-rab-maker/backend/repository/webhooks"
+	"github.com/momokii/go-rab-maker/backend/trash_bin"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
+)
+
+// Repositories holds one implementation of every repository
+type Repositories struct {
+	Users                  users.Repository
+	Materials              master_materials.Repository
+	LaborTypes             master_labor_types.Repository
+	WorkCategories         master_work_categories.Repository
+	AhspTemplates          ahsptemplates.Repository
+	AhspMaterialComponents ahsp_material_components.Repository
+	AhspLaborComponents    ahsp_labor_components.Repository
+	Projects               projects.Repository
+	ProjectWorkItems       project_work_items.Repository
+	ProjectItemCosts       project_item_costs.Repository
+	ProjectMembers         project_members.Repository
+	ShareLinks             share_links.Repository
+	ProjectApprovals       project_approvals.Repository
+	AuditLog               audit_log.Repository
+	Dashboard              dashboard.Repository
+	MaterialSummary        material_summary.Repository
+	APITokens              api_tokens.Repository
+	Webhooks               webhooks.Repository
+	WebhookDeliveries      webhook_deliveries.Repository
+	Organizations          organizations.Repository
+	Trash                  trash.Repository
+}
+
+// NewRepositories returns the SQL repositories
+func NewRepositories() Repositories {
+	return Repositories{
+		Users:                  users.NewUsersRepo(),
+		Materials:              master_materials.NewMasterMaterialsRepo(),
+		LaborTypes:             master_labor_types.NewMasterLaborTypesRepo(),
+		WorkCategories:         master_work_categories.NewMasterWorkCategoriesRepo(),
+		AhspTemplates:          ahsptemplates.NewAhspTemplatesRepo(),
+		AhspMaterialComponents: ahsp_material_components.NewAHSPMaterialComponentsRepo(),
+		AhspLaborComponents:    ahsp_labor_components.NewAHSPLaborComponentsRepo(),
+		Projects:               projects.NewProjectsRepo(),
+		ProjectWorkItems:       project_work_items.NewProjectWorkItemRepo(),
+		ProjectItemCosts:       project_item_costs.NewProjectItemCostsRepo(),
+		ProjectMembers:         project_members.NewProjectMembersRepo(),
+		ShareLinks:             share_links.NewShareLinksRepo(),
+		ProjectApprovals:       project_approvals.NewProjectApprovalsRepo(),
+		AuditLog:               audit_log.NewAuditLogRepo(),
+		Dashboard:              dashboard.NewDashboardRepo(),
+		MaterialSummary:        material_summary.NewMaterialSummaryRepo(),
+		APITokens:              api_tokens.NewAPITokensRepo(),
+		Webhooks:               webhooks.NewWebhooksRepo(),
+		WebhookDeliveries:      webhook_deliveries.NewWebhookDeliveriesRepo(),
+		Organizations:          organizations.NewOrganizationsRepo(),
+		Trash:                  trash.NewTrashRepo(),
+	}
+}
+
+// Handlers holds every HTTP handler, routes are registered on them in main
+type Handlers struct {
+	Auth                   *handlers.AuthHandler
+	Dashboard              *handlers.DashboardHandler
+	Materials              *handlers.MaterialHandler
+	LaborTypes             *handlers.LaborTypeHandler
+	MasterImport           *handlers.MasterImportHandler
+	WorkCategories         *handlers.WorkCategoryHandler
+	AhspTemplates          *handlers.AhspTemplateHandler
+	AhspLibrary            *handlers.AhspLibraryHandler
+	AhspAnalysis           *handlers.AhspAnalysisHandler
+	AhspMaterialComponents *handlers.AhspMaterialComponentHandler
+	AhspLaborComponents    *handlers.AhspLaborComponentHandler
+	Projects               *handlers.ProjectsHandler
+	ProjectWorkItems       *handlers.ProjectWorkItemsHandler
+	ProjectMembers         *handlers.ProjectMembersHandler
+	ShareLinks             *handlers.ShareLinksHandler
+	ProjectApprovals       *handlers.ProjectApprovalsHandler
+	AuditLog               *handlers.AuditLogHandler
+	RabImport              *handlers.RabImportHandler
+	ProjectBundle          *handlers.ProjectBundleHandler
+	ProjectExport          *handlers.ProjectExportHandler
+	MaterialSummary        *handlers.MaterialSummaryHandler
+	Backup                 *handlers.BackupHandler
+	APITokens              *handlers.APITokensHandler
+	Webhooks               *handlers.WebhooksHandler
+	Users                  *handlers.UsersHandler
+	Organizations          *handlers.OrganizationsHandler
+	Trash                  *handlers.TrashHandler
+	API                    handlers.APIHandlers
+	OpenAPI                *handlers.OpenAPIHandler
+}
+
+// Middlewares holds the middlewares that are built once and shared by the routes
+type Middlewares struct {
+	Session *middlewares.SessionMiddleware
+	Role    *middlewares.RoleMiddleware
+	Token   *middlewares.TokenMiddleware
+}
+
+type Container struct {
+	DB                databases.DatabaseServices
+	BackupScheduler   *databases.BackupScheduler
+	WebhookDispatcher *webhook_dispatch.Dispatcher
+	TrashBin          *trash_bin.Bin
+	Repos             Repositories
+	Handlers          Handlers
+	Middlewares       Middlewares
+}
+
+// New builds the services, handlers and middlewares on top of the given repositories
+func New(
+	db databases.DatabaseServices,
+	backupScheduler *databases.BackupScheduler,
+	webhookDispatcher *webhook_dispatch.Dispatcher,
+	trashBin *trash_bin.Bin,
+	repos Repositories,
+) *Container {
+	sessionMiddleware := middlewares.NewSessionMiddleware(db, repos.Users, repos.Organizations)
+
+	projectWorkItemsHandler := handlers.NewProjectWorkItemsHandler(
+		db,
+		repos.ProjectWorkItems,
+		repos.ProjectItemCosts,
+		repos.AhspTemplates,
+		repos.AhspMaterialComponents,
+		repos.Materials,
+		repos.LaborTypes,
+		repos.AhspLaborComponents,
+		repos.Projects,
+		repos.ProjectMembers,
+		repos.ProjectApprovals,
+		repos.WorkCategories,
+		webhookDispatcher,
+	)
+
+	apiHandlers := handlers.APIHandlers{
+		Projects: handlers.NewProjectsAPIHandler(
+			db,
+			repos.Projects,
+			repos.ProjectApprovals,
+			webhookDispatcher,
+		),
+		WorkItems: handlers.NewWorkItemsAPIHandler(
+			db,
+			repos.Projects,
+			repos.ProjectWorkItems,
+			repos.ProjectItemCosts,
+			repos.WorkCategories,
+			repos.AhspTemplates,
+			projectWorkItemsHandler,
+		),
+		Materials: handlers.NewMaterialsAPIHandler(
+			db,
+			repos.Materials,
+			webhookDispatcher,
+		),
+		LaborTypes: handlers.NewLaborTypesAPIHandler(
+			db,
+			repos.LaborTypes,
+			webhookDispatcher,
+		),
+		WorkCategories: handlers.NewWorkCategoriesAPIHandler(
+			db,
+			repos.WorkCategories,
+		),
+		AhspTemplates: handlers.NewAhspTemplatesAPIHandler(
+			db,
+			repos.AhspTemplates,
+			repos.AhspMaterialComponents,
+			repos.AhspLaborComponents,
+			repos.Materials,
+			repos.LaborTypes,
+		),
+		MaterialSummary: handlers.NewMaterialSummaryAPIHandler(
+			db,
+			repos.MaterialSummary,
+			repos.Projects,
+			repos.ProjectItemCosts,
+			repos.Dashboard,
+		),
+		Dashboard: handlers.NewDashboardAPIHandler(
+			db,
+			repos.Dashboard,
+		),
+	}
+
+	return &Container{
+		DB:                db,
+		BackupScheduler:   backupScheduler,
+		WebhookDispatcher: webhookDispatcher,
+		TrashBin:          trashBin,
+		Repos:             repos,
+		Handlers: Handlers{
+			Auth: handlers.NewAuthHandler(
+				db,
+				repos.Users,
+			),
+			Dashboard: handlers.NewDashboardHandler(
+				db,
+				repos.Dashboard,
+				repos.Projects,
+			),
+			Materials: handlers.NewMaterialsHandler(
+				db,
+				repos.Materials,
+				webhookDispatcher,
+			),
+			LaborTypes: handlers.NewLaborTypeHandler(
+				db,
+				repos.LaborTypes,
+				webhookDispatcher,
+			),
+			MasterImport: handlers.NewMasterImportHandler(
+				db,
+				master_import.NewImporter(
+					repos.Materials,
+					repos.LaborTypes,
+					webhookDispatcher,
+				),
+			),
+			WorkCategories: handlers.NewWorkCategoryHandler(
+				db,
+				repos.WorkCategories,
+			),
+			AhspTemplates: handlers.NewAhspTemplateHandler(
+				db,
+				repos.AhspTemplates,
+			),
+			AhspLibrary: handlers.NewAhspLibraryHandler(
+				db,
+				ahsp_library.NewImporter(
+					repos.Materials,
+					repos.LaborTypes,
+					repos.AhspTemplates,
+					repos.AhspMaterialComponents,
+					repos.AhspLaborComponents,
+				),
+			),
+			AhspAnalysis: handlers.NewAhspAnalysisHandler(
+				db,
+				ahsp_analysis.NewBuilder(
+					repos.AhspMaterialComponents,
+					repos.AhspLaborComponents,
+					repos.ProjectItemCosts,
+				),
+				repos.AhspTemplates,
+				repos.Projects,
+			),
+			AhspMaterialComponents: handlers.NewAhspMaterialComponentHandler(
+				db,
+				repos.AhspMaterialComponents,
+				repos.AhspTemplates,
+				repos.Materials,
+				repos.AhspLaborComponents,
+			),
+			AhspLaborComponents: handlers.NewAhspLaborComponentHandler(
+				db,
+				repos.AhspLaborComponents,
+				repos.AhspTemplates,
+				repos.LaborTypes,
+			),
+			Projects: handlers.NewProjectsHandler(
+				db,
+				repos.Projects,
+				repos.ProjectMembers,
+				repos.ProjectApprovals,
+				webhookDispatcher,
+			),
+			ProjectWorkItems: projectWorkItemsHandler,
+			ProjectMembers: handlers.NewProjectMembersHandler(
+				db,
+				repos.Projects,
+				repos.ProjectMembers,
+				repos.Users,
+				repos.Organizations,
+			),
+			ShareLinks: handlers.NewShareLinksHandler(
+				db,
+				repos.Projects,
+				repos.ShareLinks,
+				repos.ProjectWorkItems,
+				repos.ProjectItemCosts,
+				repos.MaterialSummary,
+			),
+			ProjectApprovals: handlers.NewProjectApprovalsHandler(
+				db,
+				repos.Projects,
+				repos.ProjectMembers,
+				repos.ProjectApprovals,
+				repos.ProjectWorkItems,
+				webhookDispatcher,
+			),
+			AuditLog: handlers.NewAuditLogHandler(
+				db,
+				repos.AuditLog,
+				repos.Projects,
+				repos.ProjectMembers,
+			),
+			RabImport: handlers.NewRabImportHandler(
+				db,
+				rab_import.NewImporter(
+					repos.WorkCategories,
+					repos.AhspTemplates,
+					repos.ProjectWorkItems,
+					repos.ProjectItemCosts,
+				),
+				repos.Projects,
+				repos.ProjectMembers,
+				repos.ProjectApprovals,
+				projectWorkItemsHandler,
+			),
+			ProjectBundle: handlers.NewProjectBundleHandler(
+				db,
+				NewBundler(repos),
+				repos.Projects,
+			),
+			ProjectExport: handlers.NewProjectExportHandler(
+				db,
+				repos.Projects,
+				repos.ProjectMembers,
+				repos.ProjectWorkItems,
+				repos.ProjectItemCosts,
+			),
+			MaterialSummary: handlers.NewMaterialSummaryHandler(
+				db,
+				repos.MaterialSummary,
+				repos.Projects,
+				repos.ProjectMembers,
+				repos.ProjectItemCosts,
+				repos.Dashboard,
+			),
+			Backup: handlers.NewBackupHandler(
+				db,
+				backupScheduler,
+			),
+			APITokens: handlers.NewAPITokensHandler(
+				db,
+				repos.APITokens,
+			),
+			Webhooks: handlers.NewWebhooksHandler(
+				db,
+				repos.Webhooks,
+				repos.WebhookDeliveries,
+				webhookDispatcher,
+			),
+			Users: handlers.NewUsersHandler(
+				db,
+				repos.Users,
+				sessionMiddleware,
+			),
+			Organizations: handlers.NewOrganizationsHandler(
+				db,
+				repos.Organizations,
+				repos.Users,
+			),
+			Trash: handlers.NewTrashHandler(
+				db,
+				trashBin,
+				repos.Trash,
+				repos.ProjectApprovals,
+			),
+			API:     apiHandlers,
+			OpenAPI: handlers.NewOpenAPIHandler(apiHandlers.Routes()),
+		},
+		Middlewares: Middlewares{
+			Session: sessionMiddleware,
+			Role:    middlewares.NewRoleMiddleware(),
+			Token:   middlewares.NewTokenMiddleware(db, repos.APITokens),
+		},
+	}
+}
+
+// NewBundler builds the project bundle exporter and importer, the CLI uses it without the handlers
+func NewBundler(repos Repositories) *project_bundle.Bundler {
+	return project_bundle.NewBundler(
+		repos.Projects,
+		repos.ProjectWorkItems,
+		repos.ProjectItemCosts,
+		repos.WorkCategories,
+		repos.Materials,
+		repos.LaborTypes,
+		repos.AhspTemplates,
+		repos.AhspMaterialComponents,
+		repos.AhspLaborComponents,
+	)
+}
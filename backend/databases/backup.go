@@ -0,0 +1,408 @@
+package databases
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+const (
+	BACKUP_FOLDER_NAME = "backups"
+	BACKUP_EXTENSION   = ".sqlite"
+	BACKUP_GZIP_SUFFIX = ".gz" // scheduled backups can be stored as .sqlite.gz
+
+	BACKUP_PREFIX_MANUAL      = "backup"
+	BACKUP_PREFIX_SCHEDULED   = "auto"
+	BACKUP_PREFIX_PRE_RESTORE = "pre-restore"
+	BACKUP_PREFIX_UPLOADED    = "uploaded"
+)
+
+type RestoreResult struct {
+	Info       models.BackupInfo
+	SafetyCopy string // path of the copy of the database taken right before it was replaced
+}
+
+// NewBackupFileName names a snapshot after its kind and the current time, e.g. backup-20260118-153000.250.sqlite.
+// The milliseconds keep two snapshots of the same kind taken within one second apart.
+func NewBackupFileName(prefix string) string {
+	return prefix + "-" + time.Now().Format("20060102-150405.000") + BACKUP_EXTENSION
+}
+
+// BackupDir is BACKUP_DIR when set, otherwise the backups folder next to the database file
+func (s *SQLiteDB) BackupDir() string {
+	if dir := os.Getenv("BACKUP_DIR"); dir != "" {
+		return dir
+	}
+
+	return filepath.Join(filepath.Dir(s.DatabasesPath), BACKUP_FOLDER_NAME)
+}
+
+// Backup writes a consistent copy of the live database to destPath with VACUUM INTO.
+// It runs on the single writer connection, so it sees a committed state and other
+// requests keep working; destPath must not exist yet.
+func (s *SQLiteDB) Backup(ctx context.Context, destPath string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.backup(ctx, destPath)
+}
+
+func (s *SQLiteDB) backup(ctx context.Context, destPath string) error {
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("backup file %s already exists", destPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	if _, err := s.Write.ExecContext(ctx, "VACUUM INTO ?", destPath); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	return nil
+}
+
+// Restore replaces the live database with the backup at srcPath, plain or gzip compressed.
+// The backup is checked first (CheckBackup), then a safety copy of the current database is
+// written to the backup directory. Transactions are blocked while the file is swapped, and
+// migrations missing from an older backup are applied afterwards. If the restored file
+// cannot be opened, the safety copy is put back.
+func (s *SQLiteDB) Restore(ctx context.Context, srcPath string) (RestoreResult, error) {
+	var result RestoreResult
+
+	plainPath, cleanup, err := decompressBackup(srcPath)
+	if err != nil {
+		return result, err
+	}
+	defer cleanup()
+
+	info, err := checkBackupFile(plainPath)
+	if err != nil {
+		return result, err
+	}
+	result.Info = info
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result.SafetyCopy = filepath.Join(s.BackupDir(), NewBackupFileName(BACKUP_PREFIX_PRE_RESTORE))
+	if err := s.backup(ctx, result.SafetyCopy); err != nil {
+		return result, fmt.Errorf("failed to write the pre-restore safety copy, nothing was restored: %w", err)
+	}
+
+	if err := s.close(); err != nil {
+		return result, fmt.Errorf("failed to close database: %w", err)
+	}
+
+	if err := s.replaceFile(plainPath); err != nil {
+		return result, s.rollbackRestore(result.SafetyCopy, err)
+	}
+
+	if err := s.open(); err != nil {
+		return result, s.rollbackRestore(result.SafetyCopy, err)
+	}
+
+	if err := runMigrations(&s.DB); err != nil {
+		s.close()
+		return result, s.rollbackRestore(result.SafetyCopy, err)
+	}
+
+	log.Printf("Database restored from %s (safety copy: %s)", srcPath, result.SafetyCopy)
+
+	return result, nil
+}
+
+// replaceFile copies srcPath over the database file. The copy is written next to the
+// database first and renamed into place, stale WAL and journal files are removed.
+func (s *SQLiteDB) replaceFile(srcPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpPath := s.DatabasesPath + ".restore"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(s.DatabasesPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	return os.Rename(tmpPath, s.DatabasesPath)
+}
+
+// rollbackRestore puts the safety copy back after a failed restore and reopens the database
+func (s *SQLiteDB) rollbackRestore(safetyCopy string, restoreErr error) error {
+	if err := s.replaceFile(safetyCopy); err != nil {
+		return fmt.Errorf("restore failed (%v) and the safety copy %s could not be put back: %w", restoreErr, safetyCopy, err)
+	}
+
+	if err := s.open(); err != nil {
+		return fmt.Errorf("restore failed (%v) and the database could not be reopened: %w", restoreErr, err)
+	}
+
+	return fmt.Errorf("restore failed, the previous database was kept: %w", restoreErr)
+}
+
+// CheckBackup verifies a file is an intact RAB Maker database this version can use:
+// PRAGMA integrity_check must pass and every migration recorded in its schema_migrations
+// must be known to this build. Older backups are accepted, their missing migrations run on restore.
+// Gzip compressed backups are checked on a temporary uncompressed copy.
+func CheckBackup(path string) (models.BackupInfo, error) {
+	plainPath, cleanup, err := decompressBackup(path)
+	if err != nil {
+		return models.BackupInfo{}, err
+	}
+	defer cleanup()
+
+	return checkBackupFile(plainPath)
+}
+
+func checkBackupFile(path string) (models.BackupInfo, error) {
+	var info models.BackupInfo
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return info, err
+	}
+	defer db.Close()
+
+	var integrity string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&integrity); err != nil {
+		return info, fmt.Errorf("the file is not a readable SQLite database: %w", err)
+	}
+	if integrity != "ok" {
+		return info, fmt.Errorf("integrity check failed: %s", integrity)
+	}
+
+	rows, err := db.Query("SELECT version FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return info, errors.New("the file has no schema_migrations table, it is not a RAB Maker database")
+	}
+	defer rows.Close()
+
+	migrations, err := parseMigrationFiles(DIALECT_SQLITE)
+	if err != nil {
+		return info, err
+	}
+
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.version] = true
+	}
+
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return info, err
+		}
+		if !known[version] {
+			return info, fmt.Errorf("the backup has migration %d which this version of RAB Maker does not know, upgrade the application before restoring it", version)
+		}
+
+		info.Migrations++
+		info.SchemaVersion = version
+	}
+	if err := rows.Err(); err != nil {
+		return info, err
+	}
+
+	if info.Migrations == 0 {
+		return info, errors.New("the backup has no applied migrations, it is not a RAB Maker database")
+	}
+
+	return info, nil
+}
+
+// IsCompressedBackup reports whether the file starts with the gzip magic bytes
+func IsCompressedBackup(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return false
+	}
+
+	return magic[0] == 0x1f && magic[1] == 0x8b
+}
+
+// decompressBackup returns a path to the uncompressed backup: the file itself when it is
+// not compressed, or a temporary copy that cleanup removes.
+func decompressBackup(path string) (string, func(), error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", nil, fmt.Errorf("backup file not found: %w", err)
+	}
+
+	if !IsCompressedBackup(path) {
+		return path, func() {}, nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+
+	reader, err := gzip.NewReader(src)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read compressed backup: %w", err)
+	}
+	defer reader.Close()
+
+	tmp, err := os.CreateTemp("", "rabmaker-backup-*"+BACKUP_EXTENSION)
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("failed to decompress backup: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return tmp.Name(), cleanup, nil
+}
+
+// compressBackup gzips srcPath into destPath and removes srcPath
+func compressBackup(srcPath, destPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(dest)
+	if _, err := io.Copy(writer, src); err != nil {
+		dest.Close()
+		os.Remove(destPath)
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		dest.Close()
+		os.Remove(destPath)
+		return err
+	}
+	if err := dest.Close(); err != nil {
+		os.Remove(destPath)
+		return err
+	}
+
+	src.Close()
+	return os.Remove(srcPath)
+}
+
+// isBackupName accepts plain .sqlite and compressed .sqlite.gz backup files
+func isBackupName(name string) bool {
+	return strings.HasSuffix(name, BACKUP_EXTENSION) || strings.HasSuffix(name, BACKUP_EXTENSION+BACKUP_GZIP_SUFFIX)
+}
+
+// backupKind is the prefix NewBackupFileName was called with, empty for files named by hand
+func backupKind(name string) string {
+	for _, prefix := range []string{BACKUP_PREFIX_MANUAL, BACKUP_PREFIX_SCHEDULED, BACKUP_PREFIX_PRE_RESTORE, BACKUP_PREFIX_UPLOADED} {
+		if strings.HasPrefix(name, prefix+"-") {
+			return prefix
+		}
+	}
+
+	return ""
+}
+
+// ListBackups returns the snapshots in dir, newest first. A missing dir has no backups.
+func ListBackups(dir string) ([]models.BackupFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []models.BackupFile{}, nil
+		}
+		return nil, err
+	}
+
+	backups := []models.BackupFile{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackupName(entry.Name()) {
+			continue
+		}
+
+		fileInfo, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		backups = append(backups, models.BackupFile{
+			Name:       entry.Name(),
+			Kind:       backupKind(entry.Name()),
+			Path:       filepath.Join(dir, entry.Name()),
+			Size:       fileInfo.Size(),
+			ModTime:    fileInfo.ModTime(),
+			Compressed: strings.HasSuffix(entry.Name(), BACKUP_GZIP_SUFFIX),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime.After(backups[j].ModTime)
+	})
+
+	return backups, nil
+}
+
+// FindBackup resolves a file name from ListBackups, refusing anything outside dir
+func FindBackup(dir, name string) (models.BackupFile, error) {
+	if name != filepath.Base(name) || !isBackupName(name) {
+		return models.BackupFile{}, errors.New("invalid backup name")
+	}
+
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return models.BackupFile{}, err
+	}
+
+	for _, backup := range backups {
+		if backup.Name == name {
+			return backup, nil
+		}
+	}
+
+	return models.BackupFile{}, os.ErrNotExist
+}
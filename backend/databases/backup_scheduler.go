@@ -0,0 +1,271 @@
+package databases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// BackupScheduleConfig controls the automatic backups, a zero Interval disables them
+type BackupScheduleConfig struct {
+	Interval       time.Duration
+	RetentionCount int           // keep only the newest N scheduled backups, 0 for no limit
+	RetentionAge   time.Duration // delete scheduled backups older than this, 0 for no limit
+	Gzip           bool
+}
+
+// LoadBackupScheduleConfig reads BACKUP_INTERVAL, BACKUP_RETENTION_COUNT, BACKUP_RETENTION_AGE
+// and BACKUP_GZIP. Durations use Go syntax (6h, 90m) and also accept whole days (7d).
+func LoadBackupScheduleConfig() (BackupScheduleConfig, error) {
+	var config BackupScheduleConfig
+	var err error
+
+	if config.Interval, err = parseBackupDuration(os.Getenv("BACKUP_INTERVAL")); err != nil {
+		return config, fmt.Errorf("invalid BACKUP_INTERVAL: %w", err)
+	}
+
+	if config.RetentionAge, err = parseBackupDuration(os.Getenv("BACKUP_RETENTION_AGE")); err != nil {
+		return config, fmt.Errorf("invalid BACKUP_RETENTION_AGE: %w", err)
+	}
+
+	if value := os.Getenv("BACKUP_RETENTION_COUNT"); value != "" {
+		config.RetentionCount, err = strconv.Atoi(value)
+		if err != nil || config.RetentionCount < 0 {
+			return config, fmt.Errorf("invalid BACKUP_RETENTION_COUNT: %q", value)
+		}
+	}
+
+	if value := os.Getenv("BACKUP_GZIP"); value != "" {
+		if config.Gzip, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid BACKUP_GZIP: %q", value)
+		}
+	}
+
+	return config, nil
+}
+
+func parseBackupDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	var duration time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number of days", value)
+		}
+		duration = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if duration, err = time.ParseDuration(value); err != nil {
+			return 0, err
+		}
+	}
+
+	if duration < 0 {
+		return 0, fmt.Errorf("%q is negative", value)
+	}
+
+	return duration, nil
+}
+
+// BackupScheduler takes a backup every Interval into the backup directory and prunes old
+// scheduled backups. Manual, uploaded and pre-restore backups are never pruned.
+type BackupScheduler struct {
+	dbService DatabaseServices
+	config    BackupScheduleConfig
+
+	mu         sync.Mutex
+	lastRunAt  time.Time
+	lastBackup string
+	lastError  string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewBackupScheduler(dbService DatabaseServices, config BackupScheduleConfig) *BackupScheduler {
+	return &BackupScheduler{
+		dbService: dbService,
+		config:    config,
+	}
+}
+
+// Start runs the schedule in a background goroutine until Stop is called
+func (s *BackupScheduler) Start() {
+	if s.config.Interval <= 0 {
+		log.Println("Scheduled backups are disabled (BACKUP_INTERVAL not set)")
+		return
+	}
+
+	if s.dbService.Dialect() != DIALECT_SQLITE {
+		log.Printf("Scheduled backups are disabled, they are not supported on %s", s.dbService.Dialect())
+		return
+	}
+
+	// continue from the newest scheduled backup, so a restart does not reset the interval
+	if backups, err := ListBackups(s.dbService.BackupDir()); err == nil {
+		for _, backup := range backups {
+			if backup.Kind == BACKUP_PREFIX_SCHEDULED {
+				s.lastRunAt = backup.ModTime
+				s.lastBackup = backup.Name
+				break
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.run(ctx)
+
+	log.Printf("Scheduled backups every %s into %s", s.config.Interval, s.dbService.BackupDir())
+}
+
+// Stop ends the schedule, waiting for a backup in progress to finish
+func (s *BackupScheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+
+	s.cancel()
+	<-s.done
+
+	log.Println("Scheduled backups stopped")
+}
+
+func (s *BackupScheduler) run(ctx context.Context) {
+	defer close(s.done)
+
+	for {
+		timer := time.NewTimer(time.Until(s.Status().NextRunAt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			// not tied to ctx, a backup that started is allowed to finish on shutdown
+			if _, err := s.RunOnce(context.Background()); err != nil {
+				log.Printf("Scheduled backup failed: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce takes one scheduled backup and applies the retention policy
+func (s *BackupScheduler) RunOnce(ctx context.Context) (string, error) {
+	name, err := s.backup(ctx)
+
+	s.mu.Lock()
+	s.lastRunAt = time.Now()
+	if err != nil {
+		s.lastError = err.Error()
+	} else {
+		s.lastBackup = name
+		s.lastError = ""
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.prune(); err != nil {
+		log.Printf("Failed to prune scheduled backups: %v", err)
+	}
+
+	return name, nil
+}
+
+func (s *BackupScheduler) backup(ctx context.Context) (string, error) {
+	dir := s.dbService.BackupDir()
+	name := NewBackupFileName(BACKUP_PREFIX_SCHEDULED)
+	path := filepath.Join(dir, name)
+
+	if !s.config.Gzip {
+		return name, s.dbService.Backup(ctx, path)
+	}
+
+	// the uncompressed copy gets a name ListBackups ignores until it is gzipped
+	tmpPath := path + ".tmp"
+	if err := s.dbService.Backup(ctx, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+
+	if err := compressBackup(tmpPath, path+BACKUP_GZIP_SUFFIX); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to compress backup: %w", err)
+	}
+
+	return name + BACKUP_GZIP_SUFFIX, nil
+}
+
+// prune deletes scheduled backups beyond RetentionCount or older than RetentionAge
+func (s *BackupScheduler) prune() error {
+	if s.config.RetentionCount == 0 && s.config.RetentionAge == 0 {
+		return nil
+	}
+
+	backups, err := ListBackups(s.dbService.BackupDir())
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, backup := range backups {
+		if backup.Kind != BACKUP_PREFIX_SCHEDULED {
+			continue
+		}
+
+		tooMany := s.config.RetentionCount > 0 && kept >= s.config.RetentionCount
+		tooOld := s.config.RetentionAge > 0 && time.Since(backup.ModTime) > s.config.RetentionAge
+		if !tooMany && !tooOld {
+			kept++
+			continue
+		}
+
+		if err := os.Remove(backup.Path); err != nil {
+			return err
+		}
+		log.Println("Pruned scheduled backup", backup.Name)
+	}
+
+	return nil
+}
+
+func (s *BackupScheduler) Status() models.BackupScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := models.BackupScheduleStatus{
+		Enabled:        s.config.Interval > 0,
+		Interval:       s.config.Interval,
+		RetentionCount: s.config.RetentionCount,
+		RetentionAge:   s.config.RetentionAge,
+		Gzip:           s.config.Gzip,
+		LastRunAt:      s.lastRunAt,
+		LastBackup:     s.lastBackup,
+		LastError:      s.lastError,
+	}
+
+	if status.Enabled {
+		status.NextRunAt = s.lastRunAt.Add(s.config.Interval)
+		if status.NextRunAt.Before(time.Now()) {
+			status.NextRunAt = time.Now()
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,268 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupMigratedDB opens a database in a temp dir with every migration applied
+func setupMigratedDB(t *testing.T) *SQLiteDB {
+	t.Helper()
+
+	db, err := NewSQLiteDatabases(filepath.Join(t.TempDir(), "database.sqlite"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.close() })
+
+	if err := runMigrations(db.GetDB()); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	return db
+}
+
+func countUsers(t *testing.T, db *SQLiteDB) int {
+	t.Helper()
+
+	var count int
+	if _, err := db.Transaction(context.Background(), func(tx *sql.Tx) (int, error) {
+		return 200, tx.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	}); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+
+	return count
+}
+
+func addUser(t *testing.T, db *SQLiteDB, username string) {
+	t.Helper()
+
+	if _, err := db.Transaction(context.Background(), func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec("INSERT INTO users (username, password) VALUES (?, 'x')", username)
+		return 200, err
+	}); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+}
+
+// TestBackupAndRestore verifies a restore brings back the backed up rows and keeps a safety copy
+func TestBackupAndRestore(t *testing.T) {
+	db := setupMigratedDB(t)
+	ctx := context.Background()
+
+	addUser(t, db, "before")
+	usersAtBackup := countUsers(t, db)
+
+	backupPath := filepath.Join(db.BackupDir(), NewBackupFileName(BACKUP_PREFIX_MANUAL))
+	if err := db.Backup(ctx, backupPath); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := db.Backup(ctx, backupPath); err == nil {
+		t.Errorf("Expected backing up over an existing file to fail")
+	}
+
+	addUser(t, db, "after")
+	if countUsers(t, db) != usersAtBackup+1 {
+		t.Fatalf("Expected %d users before restore", usersAtBackup+1)
+	}
+
+	result, err := db.Restore(ctx, backupPath)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if got := countUsers(t, db); got != usersAtBackup {
+		t.Errorf("Expected %d users after restore, got %d", usersAtBackup, got)
+	}
+	if result.Info.SchemaVersion == 0 {
+		t.Errorf("Expected the backup schema version, got %+v", result.Info)
+	}
+
+	// the safety copy holds the state right before the restore
+	info, err := CheckBackup(result.SafetyCopy)
+	if err != nil {
+		t.Fatalf("Safety copy is not a valid backup: %v", err)
+	}
+	if info.SchemaVersion != result.Info.SchemaVersion {
+		t.Errorf("Expected safety copy at schema %d, got %d", result.Info.SchemaVersion, info.SchemaVersion)
+	}
+
+	backups, err := ListBackups(db.BackupDir())
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Errorf("Expected the backup and the safety copy, got %d files", len(backups))
+	}
+}
+
+// TestNewBackupFileName_SubSecond verifies two snapshots taken within the same second get different names
+func TestNewBackupFileName_SubSecond(t *testing.T) {
+	first := NewBackupFileName(BACKUP_PREFIX_MANUAL)
+	time.Sleep(5 * time.Millisecond)
+	second := NewBackupFileName(BACKUP_PREFIX_MANUAL)
+
+	if first == second {
+		t.Errorf("Expected different names, both are %s", first)
+	}
+	if !isBackupName(first) || backupKind(first) != BACKUP_PREFIX_MANUAL {
+		t.Errorf("Expected %s to be listed as a manual backup", first)
+	}
+}
+
+// TestCheckBackup_RejectsIncompatibleFiles verifies garbage and newer schemas are refused before anything is replaced
+func TestCheckBackup_RejectsIncompatibleFiles(t *testing.T) {
+	db := setupMigratedDB(t)
+	ctx := context.Background()
+
+	garbage := filepath.Join(t.TempDir(), "garbage.sqlite")
+	if err := os.WriteFile(garbage, []byte("definitely not sqlite"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := CheckBackup(garbage); err == nil {
+		t.Errorf("Expected garbage file to be rejected")
+	}
+
+	newer := filepath.Join(t.TempDir(), "newer.sqlite")
+	if err := db.Backup(ctx, newer); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	newerDB, err := sql.Open("sqlite", "file:"+newer)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	if _, err := newerDB.Exec("INSERT INTO schema_migrations (version, name) VALUES (999999, 'from_the_future')"); err != nil {
+		t.Fatalf("Failed to add migration: %v", err)
+	}
+	newerDB.Close()
+
+	if _, err := db.Restore(ctx, newer); err == nil || !strings.Contains(err.Error(), "999999") {
+		t.Errorf("Expected unknown migration error, got %v", err)
+	}
+
+	// nothing was touched, not even a safety copy
+	if backups, _ := ListBackups(db.BackupDir()); len(backups) != 0 {
+		t.Errorf("Expected no safety copy for a rejected restore, got %d", len(backups))
+	}
+}
+
+// TestFindBackup_RejectsPaths verifies backup names cannot escape the backup directory
+func TestFindBackup_RejectsPaths(t *testing.T) {
+	for _, name := range []string{"../database.sqlite", "/etc/passwd", "notes.txt"} {
+		if _, err := FindBackup(t.TempDir(), name); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
+
+// TestBackupScheduler_GzipAndRetention verifies a compressed scheduled backup is taken, only
+// scheduled backups are pruned, and the compressed file can be restored
+func TestBackupScheduler_GzipAndRetention(t *testing.T) {
+	db := setupMigratedDB(t)
+	dir := db.BackupDir()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create backup dir: %v", err)
+	}
+
+	existing := map[string]time.Duration{
+		"auto-20200101-000000.sqlite":   time.Hour,
+		"auto-20200102-000000.sqlite":   2 * time.Hour,
+		"auto-20200103-000000.sqlite":   100 * time.Hour,
+		"backup-20200104-000000.sqlite": 100 * time.Hour,
+	}
+	for name, age := range existing {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set file time: %v", err)
+		}
+	}
+
+	scheduler := NewBackupScheduler(db, BackupScheduleConfig{
+		Interval:       time.Hour,
+		RetentionCount: 2,
+		RetentionAge:   72 * time.Hour,
+		Gzip:           true,
+	})
+
+	name, err := scheduler.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if !strings.HasSuffix(name, BACKUP_EXTENSION+BACKUP_GZIP_SUFFIX) {
+		t.Errorf("Expected a compressed backup, got %s", name)
+	}
+
+	backups, err := ListBackups(dir)
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, backup := range backups {
+		got[backup.Name] = true
+	}
+	want := []string{name, "auto-20200101-000000.sqlite", "backup-20200104-000000.sqlite"}
+	if len(got) != len(want) {
+		t.Errorf("Expected %v to be kept, got %v", want, got)
+	}
+	for _, name := range want {
+		if !got[name] {
+			t.Errorf("Expected %s to be kept", name)
+		}
+	}
+
+	status := scheduler.Status()
+	if status.LastBackup != name || status.LastError != "" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if !status.NextRunAt.After(status.LastRunAt) {
+		t.Errorf("Expected next run after the last run, got %+v", status)
+	}
+
+	if _, err := db.Restore(context.Background(), filepath.Join(dir, name)); err != nil {
+		t.Fatalf("Restore of compressed backup failed: %v", err)
+	}
+}
+
+func TestLoadBackupScheduleConfig(t *testing.T) {
+	t.Setenv("BACKUP_INTERVAL", "1d")
+	t.Setenv("BACKUP_RETENTION_COUNT", "7")
+	t.Setenv("BACKUP_RETENTION_AGE", "720h")
+	t.Setenv("BACKUP_GZIP", "true")
+
+	config, err := LoadBackupScheduleConfig()
+	if err != nil {
+		t.Fatalf("LoadBackupScheduleConfig failed: %v", err)
+	}
+
+	want := BackupScheduleConfig{Interval: 24 * time.Hour, RetentionCount: 7, RetentionAge: 30 * 24 * time.Hour, Gzip: true}
+	if config != want {
+		t.Errorf("Expected %+v, got %+v", want, config)
+	}
+
+	for key, value := range map[string]string{
+		"BACKUP_INTERVAL":        "daily",
+		"BACKUP_RETENTION_COUNT": "-1",
+		"BACKUP_RETENTION_AGE":   "-5d",
+		"BACKUP_GZIP":            "maybe",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := LoadBackupScheduleConfig(); err == nil {
+				t.Errorf("Expected %s=%s to be rejected", key, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// DatabaseServices is what handlers, middlewares and the CLI use, whatever engine is configured
+type DatabaseServices interface {
+	GetDB() *DB
+
+	Dialect() Dialect
+
+	Transaction(ctx context.Context, fn func(tx *sql.Tx) (statusCode int, err error)) (statusCode int, err error)
+
+	ReadTransaction(ctx context.Context, fn func(tx *sql.Tx) (statusCode int, err error)) (statusCode int, err error)
+
+	BackupDir() string
+
+	Backup(ctx context.Context, destPath string) error
+
+	Restore(ctx context.Context, srcPath string) (RestoreResult, error)
+}
+
+// DatabaseConfig selects the engine, read from DATABASE_DRIVER and DATABASE_URL
+type DatabaseConfig struct {
+	Driver      Dialect
+	SQLitePath  string // file of the SQLite database
+	PostgresURL string // connection string of the PostgreSQL database
+}
+
+// DB holds the read and write pools and runs transactions and migrations the same way
+// for both engines. SQLiteDB and PostgresDB embed it and add what differs: opening the
+// pools, backups and restores.
+type DB struct {
+	dialect Dialect
+	read    *sql.DB
+	Write   *sql.DB // Exported field for access
+
+	// mu is held for reading by every transaction and for writing while migrations run or a restore swaps the file
+	mu sync.RWMutex
+}
+
+// LoadDatabaseConfig reads DATABASE_DRIVER (sqlite by default) and, for postgres, DATABASE_URL
+func LoadDatabaseConfig() (DatabaseConfig, error) {
+	driver, err := ParseDialect(os.Getenv("DATABASE_DRIVER"))
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+
+	config := DatabaseConfig{
+		Driver:      driver,
+		SQLitePath:  DATABASE_SQLITE_PATH,
+		PostgresURL: os.Getenv("DATABASE_URL"),
+	}
+
+	if driver == DIALECT_POSTGRES && config.PostgresURL == "" {
+		return DatabaseConfig{}, fmt.Errorf("DATABASE_URL is required when DATABASE_DRIVER is %s", DIALECT_POSTGRES)
+	}
+
+	return config, nil
+}
+
+// NewDatabases opens the configured database without running migrations
+func NewDatabases(config DatabaseConfig) (DatabaseServices, error) {
+	if config.Driver == DIALECT_POSTGRES {
+		return NewPostgresDatabases(config.PostgresURL)
+	}
+
+	db, err := NewSQLiteDatabases(config.SQLitePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// InitDatabase opens the configured database, creating the SQLite file when needed,
+// and applies the pending migrations
+func InitDatabase(config DatabaseConfig) (DatabaseServices, error) {
+	if config.Driver == DIALECT_SQLITE {
+		db, err := InitDatabaseSQLite(config.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return db, nil
+	}
+
+	db, err := NewDatabases(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.GetDB().MigrateUp(); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return db, nil
+}
+
+func (s *DB) GetDB() *DB {
+	return s
+}
+
+// Dialect is the engine the pools are connected to
+func (s *DB) Dialect() Dialect {
+	return s.dialect
+}
+
+func (s *DB) Transaction(ctx context.Context, fn func(tx *sql.Tx) (statusCode int, err error)) (statusCode int, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.transaction(ctx, fn)
+}
+
+// ReadTransaction runs fn in a read-only transaction on the read pool. It sees a snapshot
+// of the last commit and does not wait for an open write transaction; writes fail.
+func (s *DB) ReadTransaction(ctx context.Context, fn func(tx *sql.Tx) (statusCode int, err error)) (statusCode int, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx, err := s.read.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// nothing to commit, rollback only ends the snapshot
+	defer tx.Rollback()
+
+	if statusCode, err = fn(tx); err != nil {
+		return statusCode, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// transaction runs fn without taking mu, for callers that already hold it (migrations during a restore)
+func (s *DB) transaction(ctx context.Context, fn func(tx *sql.Tx) (statusCode int, err error)) (statusCode int, err error) {
+	tx, err := s.Write.BeginTx(ctx, nil)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if statusCode, err = fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return http.StatusInternalServerError, fmt.Errorf("transaction rollback failed: %v, original error: %w", rbErr, err)
+		}
+
+		return statusCode, err
+	}
+
+	// commit tx if fn is success
+	if err := tx.Commit(); err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("transaction commit failed: %w", err)
+	}
+
+	return http.StatusAccepted, nil
+}
+
+// close releases both connection pools
+func (s *DB) close() error {
+	if s.read == s.Write {
+		return s.Write.Close()
+	}
+
+	readErr := s.read.Close()
+	if err := s.Write.Close(); err != nil {
+		return err
+	}
+
+	return readErr
+}
+
+// Close releases the connection pools, the service cannot be used afterwards
+func (s *DB) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.close()
+}
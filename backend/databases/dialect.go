@@ -0,0 +1,111 @@
+package databases
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the SQL engine behind a database service. Repositories write their queries
+// once with ? placeholders and the SQL both engines understand, the few differences the
+// database package itself needs are answered here.
+type Dialect string
+
+const (
+	DIALECT_SQLITE   Dialect = "sqlite"
+	DIALECT_POSTGRES Dialect = "postgres"
+)
+
+// ParseDialect reads a DATABASE_DRIVER value, sqlite when it is empty
+func ParseDialect(value string) (Dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", string(DIALECT_SQLITE), "sqlite3":
+		return DIALECT_SQLITE, nil
+	case string(DIALECT_POSTGRES), "postgresql", "pgx":
+		return DIALECT_POSTGRES, nil
+	default:
+		return "", fmt.Errorf("invalid DATABASE_DRIVER %q, use %s or %s", value, DIALECT_SQLITE, DIALECT_POSTGRES)
+	}
+}
+
+// migrationsDir is the folder of the embedded migrations written for the engine
+func (d Dialect) migrationsDir() string {
+	if d == DIALECT_POSTGRES {
+		return "migrations/postgres"
+	}
+
+	return "migrations"
+}
+
+// now is the SQL for the current UTC time as text, in the format SQLite's CURRENT_TIMESTAMP uses
+func (d Dialect) now() string {
+	if d == DIALECT_POSTGRES {
+		return "to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')"
+	}
+
+	return "CURRENT_TIMESTAMP"
+}
+
+// tableExistsQuery counts the tables named by its single parameter
+func (d Dialect) tableExistsQuery() string {
+	if d == DIALECT_POSTGRES {
+		return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	}
+
+	return "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+}
+
+// columnCountQuery counts the columns of the table named by the first parameter
+// whose name is one of the remaining parameters
+func (d Dialect) columnCountQuery(columns int) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", columns), ", ")
+	if d == DIALECT_POSTGRES {
+		return "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name IN (" + placeholders + ")"
+	}
+
+	return "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name IN (" + placeholders + ")"
+}
+
+// Rebind turns the ? placeholders of query into the $1, $2 ... form PostgreSQL expects.
+// Question marks inside quotes, identifiers and comments are left alone. SQLite accepts
+// both forms, so queries are only rewritten for PostgreSQL.
+func (d Dialect) Rebind(query string) string {
+	if d != DIALECT_POSTGRES || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var out strings.Builder
+	out.Grow(len(query) + 8)
+
+	n := 0
+	quote := byte(0)
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end - 1
+			continue
+		case ch == '?':
+			n++
+			out.WriteByte('$')
+			out.WriteString(strconv.Itoa(n))
+			continue
+		}
+
+		out.WriteByte(ch)
+	}
+
+	return out.String()
+}
@@ -0,0 +1,90 @@
+package databases
+
+import "testing"
+
+// TestRebind verifies placeholders are numbered for PostgreSQL, skipping quotes and comments
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM users WHERE user_id = ?", "SELECT * FROM users WHERE user_id = $1"},
+		{"INSERT INTO t (a, b, c) VALUES (?, ?, ?)", "INSERT INTO t (a, b, c) VALUES ($1, $2, $3)"},
+		{"SELECT '?' AS q, \"a?\" FROM t WHERE a = ?", "SELECT '?' AS q, \"a?\" FROM t WHERE a = $1"},
+		{"SELECT 'it''s ?' FROM t WHERE a = ? AND b = ?", "SELECT 'it''s ?' FROM t WHERE a = $1 AND b = $2"},
+		{"SELECT a -- why?\nFROM t WHERE a = ?", "SELECT a -- why?\nFROM t WHERE a = $1"},
+		{"SELECT 1", "SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		if got := DIALECT_POSTGRES.Rebind(tt.query); got != tt.expected {
+			t.Errorf("Rebind(%q) = %q, expected %q", tt.query, got, tt.expected)
+		}
+		if got := DIALECT_SQLITE.Rebind(tt.query); got != tt.query {
+			t.Errorf("SQLite Rebind changed %q to %q", tt.query, got)
+		}
+	}
+}
+
+// TestParseDialect verifies DATABASE_DRIVER values
+func TestParseDialect(t *testing.T) {
+	for value, expected := range map[string]Dialect{
+		"":           DIALECT_SQLITE,
+		"sqlite":     DIALECT_SQLITE,
+		"Postgres":   DIALECT_POSTGRES,
+		"postgresql": DIALECT_POSTGRES,
+	} {
+		if got, err := ParseDialect(value); err != nil || got != expected {
+			t.Errorf("ParseDialect(%q) = %q, %v, expected %q", value, got, err, expected)
+		}
+	}
+
+	if _, err := ParseDialect("mysql"); err == nil {
+		t.Errorf("Expected an error for an unknown driver")
+	}
+}
+
+// TestMigrations_EnginesShareVersions verifies the PostgreSQL migrations parse and that every
+// migration after the PostgreSQL baseline exists for both engines
+func TestMigrations_EnginesShareVersions(t *testing.T) {
+	sqliteMigrations, err := parseMigrationFiles(DIALECT_SQLITE)
+	if err != nil {
+		t.Fatalf("Failed to parse SQLite migrations: %v", err)
+	}
+	postgresMigrations, err := parseMigrationFiles(DIALECT_POSTGRES)
+	if err != nil {
+		t.Fatalf("Failed to parse PostgreSQL migrations: %v", err)
+	}
+	if len(postgresMigrations) == 0 {
+		t.Fatalf("Expected PostgreSQL migrations")
+	}
+
+	baseline := postgresMigrations[0].version
+	versions := func(migrations []migrationInfo) map[int]bool {
+		result := map[int]bool{}
+		for _, m := range migrations {
+			if m.version >= baseline {
+				result[m.version] = true
+			}
+		}
+		return result
+	}
+
+	sqliteVersions, postgresVersions := versions(sqliteMigrations), versions(postgresMigrations)
+	for version := range sqliteVersions {
+		if !postgresVersions[version] && version != baseline {
+			t.Errorf("Migration %d has no PostgreSQL file", version)
+		}
+	}
+	for version := range postgresVersions {
+		if !sqliteVersions[version] {
+			t.Errorf("Migration %d has no SQLite file", version)
+		}
+	}
+
+	for _, m := range postgresMigrations {
+		if _, err := upStep(m); err != nil {
+			t.Errorf("Failed to read %s: %v", m.fileName, err)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	MIGRATION_UP   = "up"
+	MIGRATION_DOWN = "down"
+)
+
+// MigrationState is a migration file, or a version recorded in schema_migrations, and whether it is applied
+type MigrationState struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+	HasDown   bool // a .down.sql file exists for it
+	Missing   bool // applied, but this build has no file for it
+	Dirty     bool // started but never recorded as finished, not Applied
+	Modified  bool // applied, and the file changed since
+}
+
+// MigrationStep is one migration file to run and the statements splitSQL produced from it
+type MigrationStep struct {
+	Version    int
+	Name       string
+	Direction  string // MIGRATION_UP or MIGRATION_DOWN
+	FileName   string
+	Statements []string
+	Checksum   string // of the statements, set on up steps
+}
+
+// MigrationStatus lists every known migration in version order. It does not create
+// schema_migrations, a database without it has nothing applied.
+func (s *DB) MigrationStatus() ([]MigrationState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	migrations, err := parseMigrationFiles(s.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums, err := migrationChecksums(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	states := map[int]*MigrationState{}
+	for _, m := range migrations {
+		states[m.version] = &MigrationState{
+			Version: m.version,
+			Name:    m.name,
+			HasDown: m.downFileName != "",
+		}
+	}
+
+	var tableExists int
+	if err := s.Write.QueryRow(s.dialect.tableExistsQuery(), "schema_migrations").Scan(&tableExists); err != nil {
+		return nil, err
+	}
+
+	if tableExists > 0 {
+		// tables created before checksums existed get their columns on the next migrate up
+		query := "SELECT version, name, applied_at, '', 0 FROM schema_migrations"
+		if upgraded, err := migrationsTableUpgraded(s); err != nil {
+			return nil, err
+		} else if upgraded {
+			query = "SELECT version, name, applied_at, checksum, dirty FROM schema_migrations"
+		}
+
+		rows, err := s.Write.Query(query)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var version int
+			var name, appliedAt, checksum string
+			var dirty bool
+			if err := rows.Scan(&version, &name, &appliedAt, &checksum, &dirty); err != nil {
+				return nil, err
+			}
+
+			state, ok := states[version]
+			if !ok {
+				state = &MigrationState{Version: version, Name: name, Missing: true}
+				states[version] = state
+			}
+			state.Applied = !dirty
+			state.Dirty = dirty
+			state.AppliedAt = appliedAt
+			state.Modified = !dirty && !state.Missing && checksum != "" && checksum != checksums[version]
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]MigrationState, 0, len(states))
+	for _, state := range states {
+		result = append(result, *state)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// CurrentVersion is the highest applied migration, 0 when nothing is applied
+func CurrentVersion(states []MigrationState) int {
+	version := 0
+	for _, state := range states {
+		if state.Applied && state.Version > version {
+			version = state.Version
+		}
+	}
+
+	return version
+}
+
+// PreviousVersion is the applied version right below the current one, the target of a single step rollback
+func PreviousVersion(states []MigrationState) int {
+	current := CurrentVersion(states)
+
+	previous := 0
+	for _, state := range states {
+		if state.Applied && state.Version < current {
+			previous = state.Version
+		}
+	}
+
+	return previous
+}
+
+// refuseDirty stops planning while a migration is marked dirty, it has to be repaired first
+func refuseDirty(states []MigrationState) error {
+	for _, state := range states {
+		if state.Dirty {
+			return fmt.Errorf("migration %d_%s was started but did not finish (dirty), run \"rabmaker migrate repair\" first", state.Version, state.Name)
+		}
+	}
+
+	return nil
+}
+
+// PlanUp returns the pending migrations as they would be executed by MigrateUp
+func (s *DB) PlanUp() ([]MigrationStep, error) {
+	states, err := s.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := refuseDirty(states); err != nil {
+		return nil, err
+	}
+
+	migrations, err := parseMigrationFiles(s.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int]bool{}
+	for _, state := range states {
+		applied[state.Version] = state.Applied
+	}
+
+	steps := []MigrationStep{}
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		step, err := upStep(m)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// PlanDown returns the down steps that take the database back to target, newest first.
+// Everything is checked before anything runs: every applied migration above target must be
+// known to this build, have a .down.sql file, and not drop the schema_migrations table.
+func (s *DB) PlanDown(target int) ([]MigrationStep, error) {
+	if target < 0 {
+		return nil, fmt.Errorf("invalid target version %d", target)
+	}
+
+	states, err := s.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := refuseDirty(states); err != nil {
+		return nil, err
+	}
+
+	if current := CurrentVersion(states); target >= current {
+		return nil, fmt.Errorf("the database is at version %d, nothing to roll back to reach %d", current, target)
+	}
+
+	migrations, err := parseMigrationFiles(s.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]migrationInfo{}
+	for _, m := range migrations {
+		byVersion[m.version] = m
+	}
+
+	steps := []MigrationStep{}
+	for i := len(states) - 1; i >= 0; i-- {
+		state := states[i]
+		if !state.Applied || state.Version <= target {
+			continue
+		}
+
+		if state.Missing {
+			return nil, fmt.Errorf("migration %d_%s is not known to this build and cannot be rolled back", state.Version, state.Name)
+		}
+
+		m := byVersion[state.Version]
+		if m.downFileName == "" {
+			return nil, fmt.Errorf("migration %d_%s has no .down.sql file, cannot roll back below version %d", m.version, m.name, m.version)
+		}
+
+		step, err := downStep(m)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, stmt := range step.Statements {
+			if strings.Contains(stmt, "schema_migrations") {
+				return nil, fmt.Errorf("migration %d_%s removes the migration tracking table, cannot roll back below version %d", m.version, m.name, m.version)
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// MigrateUp applies every pending migration, the same way the server does on startup
+func (s *DB) MigrateUp() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return runMigrations(s)
+}
+
+// MigrateDown rolls back the most recently applied migration
+func (s *DB) MigrateDown() ([]MigrationStep, error) {
+	states, err := s.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	if CurrentVersion(states) == 0 {
+		return nil, errors.New("no applied migrations to roll back")
+	}
+
+	return s.MigrateDownTo(PreviousVersion(states))
+}
+
+// MigrateDownTo rolls back every migration above target in a single transaction,
+// so a failing down file leaves the database as it was
+func (s *DB) MigrateDownTo(target int) ([]MigrationStep, error) {
+	steps, err := s.PlanDown(target)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, step := range steps {
+		log.Printf("Rolling back migration: %d_%s", step.Version, step.Name)
+	}
+
+	if err := executeSteps(s, steps); err != nil {
+		return nil, fmt.Errorf("failed to roll back to version %d, nothing was changed: %w", target, err)
+	}
+
+	log.Printf("Rolled back to version %d", target)
+
+	return steps, nil
+}
+
+func upStep(m migrationInfo) (MigrationStep, error) {
+	content, err := migrationsFS.ReadFile(m.dir + "/" + m.fileName)
+	if err != nil {
+		return MigrationStep{}, fmt.Errorf("failed to read migration file: %w", err)
+	}
+
+	step := MigrationStep{
+		Version:   m.version,
+		Name:      m.name,
+		Direction: MIGRATION_UP,
+		FileName:  m.fileName,
+	}
+
+	for _, stmt := range splitSQL(string(content)) {
+		// Skip CREATE TABLE IF NOT EXISTS for schema_migrations (we handle it separately)
+		if strings.Contains(stmt, "schema_migrations") && strings.Contains(stmt, "CREATE TABLE") {
+			continue
+		}
+		step.Statements = append(step.Statements, stmt)
+	}
+	step.Checksum = migrationChecksum(step.Statements)
+
+	return step, nil
+}
+
+func downStep(m migrationInfo) (MigrationStep, error) {
+	content, err := migrationsFS.ReadFile(m.dir + "/" + m.downFileName)
+	if err != nil {
+		return MigrationStep{}, fmt.Errorf("failed to read migration file: %w", err)
+	}
+
+	return MigrationStep{
+		Version:    m.version,
+		Name:       m.name,
+		Direction:  MIGRATION_DOWN,
+		FileName:   m.downFileName,
+		Statements: splitSQL(string(content)),
+	}, nil
+}
+
+// executeSteps runs the steps in one transaction that also records them in schema_migrations
+func executeSteps(db *DB, steps []MigrationStep) error {
+	statusCode, err := db.transaction(context.Background(), func(tx *sql.Tx) (int, error) {
+		for _, step := range steps {
+			for _, stmt := range step.Statements {
+				if _, err := tx.Exec(stmt); err != nil {
+					return http.StatusInternalServerError, fmt.Errorf("%s: failed to execute statement: %w", step.FileName, err)
+				}
+			}
+
+			if step.Direction == MIGRATION_UP {
+				// Record the migration, replacing the dirty mark left by markMigrationDirty
+				if _, err := tx.Exec(`
+					INSERT INTO schema_migrations (version, name, checksum, dirty) VALUES (?, ?, ?, 0)
+					ON CONFLICT(version) DO UPDATE SET name = excluded.name, checksum = excluded.checksum, dirty = 0, applied_at = CURRENT_TIMESTAMP
+				`, step.Version, step.Name, step.Checksum); err != nil {
+					return http.StatusInternalServerError, fmt.Errorf("failed to record migration: %w", err)
+				}
+			} else {
+				if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", step.Version); err != nil {
+					return http.StatusInternalServerError, fmt.Errorf("failed to remove migration record: %w", err)
+				}
+			}
+		}
+
+		return http.StatusOK, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusOK && statusCode != http.StatusAccepted {
+		return fmt.Errorf("migration failed with status code: %d", statusCode)
+	}
+
+	return nil
+}
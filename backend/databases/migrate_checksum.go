@@ -0,0 +1,194 @@
+package databases
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	// MIGRATION_DRIFT_REFUSE stops the start when an applied migration file was changed
+	MIGRATION_DRIFT_REFUSE = "refuse"
+	// MIGRATION_DRIFT_WARN only logs it
+	MIGRATION_DRIFT_WARN = "warn"
+)
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	version  int
+	name     string
+	checksum string // empty for migrations applied before checksums were recorded
+	dirty    bool   // the migration was started but never recorded as finished
+}
+
+// MigrationRepair lists what RepairMigrations changed
+type MigrationRepair struct {
+	Cleared  []MigrationState // dirty records removed, the migration is pending again
+	Accepted []MigrationState // changed files whose checksum is now the recorded one
+}
+
+// migrationChecksum hashes the statements the way splitSQL cut them,
+// so editing comments or blank lines in a migration file is not drift
+func migrationChecksum(statements []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(statements, ";\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// migrationChecksums returns the checksum of every embedded migration by version
+func migrationChecksums(migrations []migrationInfo) (map[int]string, error) {
+	checksums := map[int]string{}
+	for _, m := range migrations {
+		step, err := upStep(m)
+		if err != nil {
+			return nil, err
+		}
+		checksums[m.version] = step.Checksum
+	}
+
+	return checksums, nil
+}
+
+// migrationDriftMode reads MIGRATION_DRIFT, refusing to start is the default
+func migrationDriftMode() (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("MIGRATION_DRIFT"))); mode {
+	case "", MIGRATION_DRIFT_REFUSE:
+		return MIGRATION_DRIFT_REFUSE, nil
+	case MIGRATION_DRIFT_WARN:
+		return MIGRATION_DRIFT_WARN, nil
+	default:
+		return "", fmt.Errorf("invalid MIGRATION_DRIFT %q, use %s or %s", mode, MIGRATION_DRIFT_REFUSE, MIGRATION_DRIFT_WARN)
+	}
+}
+
+// migrationsTableUpgraded reports whether schema_migrations already has the checksum and dirty columns
+func migrationsTableUpgraded(db *DB) (bool, error) {
+	var count int
+	err := db.Write.QueryRow(db.dialect.columnCountQuery(2), "schema_migrations", "checksum", "dirty").Scan(&count)
+	return count == 2, err
+}
+
+// markMigrationDirty records the migration as started, outside of the transaction that runs it
+func markMigrationDirty(db *DB, step MigrationStep) error {
+	_, err := db.Write.Exec("INSERT INTO schema_migrations (version, name, checksum, dirty) VALUES (?, ?, ?, 1)", step.Version, step.Name, step.Checksum)
+	if err != nil {
+		return fmt.Errorf("failed to mark migration as started: %w", err)
+	}
+
+	return nil
+}
+
+// verifyAppliedMigrations refuses a database with a dirty migration and compares the recorded
+// checksums with the embedded files. Migrations applied before checksums existed get theirs recorded.
+func verifyAppliedMigrations(db *DB, migrations []migrationInfo, applied map[int]appliedMigration) error {
+	for _, m := range applied {
+		if m.dirty {
+			return fmt.Errorf("migration %d_%s was started but did not finish (dirty), check the database and run \"rabmaker migrate repair\"", m.version, m.name)
+		}
+	}
+
+	checksums, err := migrationChecksums(migrations)
+	if err != nil {
+		return err
+	}
+
+	var drifted []string
+	recorded := 0
+	for _, m := range migrations {
+		row, ok := applied[m.version]
+		if !ok {
+			continue
+		}
+
+		if row.checksum == "" {
+			if _, err := db.Write.Exec("UPDATE schema_migrations SET checksum = ? WHERE version = ?", checksums[m.version], m.version); err != nil {
+				return fmt.Errorf("failed to record migration checksum: %w", err)
+			}
+			recorded++
+			continue
+		}
+
+		if row.checksum != checksums[m.version] {
+			drifted = append(drifted, fmt.Sprintf("%d_%s", m.version, m.name))
+		}
+	}
+
+	if recorded > 0 {
+		log.Printf("Recorded checksums of %d applied migration(s)", recorded)
+	}
+
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	mode, err := migrationDriftMode()
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("migration file(s) changed after they were applied: %s", strings.Join(drifted, ", "))
+	if mode == MIGRATION_DRIFT_WARN {
+		log.Printf("WARNING: %s", message)
+		return nil
+	}
+
+	return fmt.Errorf("%s; restore the original files, or run \"rabmaker migrate repair\" to accept them (MIGRATION_DRIFT=warn only logs this)", message)
+}
+
+// RepairMigrations removes dirty records, so those migrations run again, and records the
+// checksum of changed migration files as the applied one. A dirty migration ran in a
+// transaction that was rolled back, so none of its statements are left in the database.
+func (s *DB) RepairMigrations() (MigrationRepair, error) {
+	var repair MigrationRepair
+
+	states, err := s.MigrationStatus()
+	if err != nil {
+		return repair, err
+	}
+
+	migrations, err := parseMigrationFiles(s.dialect)
+	if err != nil {
+		return repair, err
+	}
+
+	checksums, err := migrationChecksums(migrations)
+	if err != nil {
+		return repair, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ensureMigrationsTableExists(s); err != nil {
+		return repair, err
+	}
+
+	if _, err := s.transaction(context.Background(), func(tx *sql.Tx) (int, error) {
+		for _, state := range states {
+			switch {
+			case state.Dirty:
+				if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ? AND dirty = 1", state.Version); err != nil {
+					return http.StatusInternalServerError, err
+				}
+				repair.Cleared = append(repair.Cleared, state)
+
+			case state.Modified:
+				if _, err := tx.Exec("UPDATE schema_migrations SET checksum = ? WHERE version = ?", checksums[state.Version], state.Version); err != nil {
+					return http.StatusInternalServerError, err
+				}
+				repair.Accepted = append(repair.Accepted, state)
+			}
+		}
+
+		return http.StatusOK, nil
+	}); err != nil {
+		return MigrationRepair{}, fmt.Errorf("failed to repair schema_migrations: %w", err)
+	}
+
+	return repair, nil
+}
@@ -0,0 +1,172 @@
+package databases
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func migrationState(t *testing.T, db *SQLiteDB, version int) MigrationState {
+	t.Helper()
+
+	states, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	for _, state := range states {
+		if state.Version == version {
+			return state
+		}
+	}
+
+	t.Fatalf("Migration %d not listed", version)
+	return MigrationState{}
+}
+
+// TestMigrationChecksum_Drift verifies a changed migration file stops the start until it is accepted
+func TestMigrationChecksum_Drift(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	version := latestApplied(t, db).Version
+	if _, err := db.Write.Exec("UPDATE schema_migrations SET checksum = 'edited' WHERE version = ?", version); err != nil {
+		t.Fatalf("Failed to change checksum: %v", err)
+	}
+
+	if !migrationState(t, db, version).Modified {
+		t.Errorf("Expected migration %d to be reported as modified", version)
+	}
+
+	if err := runMigrations(db.GetDB()); err == nil || !strings.Contains(err.Error(), "changed after they were applied") {
+		t.Fatalf("Expected the changed file to be refused, got %v", err)
+	}
+
+	t.Setenv("MIGRATION_DRIFT", MIGRATION_DRIFT_WARN)
+	if err := runMigrations(db.GetDB()); err != nil {
+		t.Fatalf("Expected MIGRATION_DRIFT=warn to only log, got %v", err)
+	}
+	t.Setenv("MIGRATION_DRIFT", "")
+
+	repair, err := db.RepairMigrations()
+	if err != nil {
+		t.Fatalf("RepairMigrations failed: %v", err)
+	}
+	if len(repair.Accepted) != 1 || repair.Accepted[0].Version != version || len(repair.Cleared) != 0 {
+		t.Errorf("Expected only migration %d to be accepted, got %+v", version, repair)
+	}
+
+	if err := runMigrations(db.GetDB()); err != nil {
+		t.Errorf("Expected the repaired database to start, got %v", err)
+	}
+}
+
+// TestMigrationChecksum_Backfill verifies migrations applied before checksums existed get one recorded
+func TestMigrationChecksum_Backfill(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	if _, err := db.Write.Exec("UPDATE schema_migrations SET checksum = ''"); err != nil {
+		t.Fatalf("Failed to clear checksums: %v", err)
+	}
+	if err := runMigrations(db.GetDB()); err != nil {
+		t.Fatalf("runMigrations failed: %v", err)
+	}
+
+	var empty int
+	if err := db.Write.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE checksum = ''").Scan(&empty); err != nil {
+		t.Fatalf("Failed to count checksums: %v", err)
+	}
+	if empty != 0 {
+		t.Errorf("Expected every applied migration to have a checksum, %d without", empty)
+	}
+}
+
+// TestMigrationDirty_FailedMigration verifies a failing migration is left dirty, blocks the next
+// run and is pending again after a repair
+func TestMigrationDirty_FailedMigration(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	// 000007_add_ahsp_library_support adds ahsp_templates.code, then creates idx_ahsp_templates_code
+	const failing = 7
+	if _, err := db.MigrateDownTo(failing - 1); err != nil {
+		t.Fatalf("MigrateDownTo failed: %v", err)
+	}
+
+	// an index with the name the migration creates makes it fail halfway through
+	if _, err := db.Write.Exec("CREATE INDEX idx_ahsp_templates_code ON ahsp_templates(template_name)"); err != nil {
+		t.Fatalf("Failed to create conflicting index: %v", err)
+	}
+
+	if err := db.MigrateUp(); err == nil {
+		t.Fatalf("Expected the migration to fail")
+	}
+
+	state := migrationState(t, db, failing)
+	if !state.Dirty || state.Applied {
+		t.Fatalf("Expected migration %d to be dirty and not applied, got %+v", failing, state)
+	}
+
+	// the statements before the failing one were rolled back with it
+	var columns int
+	if err := db.Write.QueryRow("SELECT COUNT(*) FROM pragma_table_info('ahsp_templates') WHERE name = 'code'").Scan(&columns); err != nil {
+		t.Fatalf("Failed to read ahsp_templates columns: %v", err)
+	}
+	if columns != 0 {
+		t.Errorf("Expected ahsp_templates.code to be rolled back")
+	}
+
+	if err := db.MigrateUp(); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Fatalf("Expected a dirty database to be refused, got %v", err)
+	}
+	if _, err := db.PlanDown(1); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Errorf("Expected a rollback plan to be refused while dirty, got %v", err)
+	}
+
+	repair, err := db.RepairMigrations()
+	if err != nil {
+		t.Fatalf("RepairMigrations failed: %v", err)
+	}
+	if len(repair.Cleared) != 1 || repair.Cleared[0].Version != failing {
+		t.Errorf("Expected migration %d to be cleared, got %+v", failing, repair)
+	}
+
+	if _, err := db.Write.Exec("DROP INDEX idx_ahsp_templates_code"); err != nil {
+		t.Fatalf("Failed to drop conflicting index: %v", err)
+	}
+	if err := db.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp after repair failed: %v", err)
+	}
+	if state := migrationState(t, db, failing); !state.Applied || state.Dirty {
+		t.Errorf("Expected migration %d to be applied, got %+v", failing, state)
+	}
+}
+
+// TestMigrationsTable_Upgrade verifies a schema_migrations table without the new columns is upgraded
+func TestMigrationsTable_Upgrade(t *testing.T) {
+	services, err := NewSQLiteDatabases(t.TempDir() + "/old.sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db := services.GetDB()
+	t.Cleanup(func() { db.close() })
+
+	if _, err := db.Write.Exec("CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP)"); err != nil {
+		t.Fatalf("Failed to create old table: %v", err)
+	}
+
+	if _, err := db.MigrationStatus(); err != nil {
+		t.Fatalf("Expected status to read the old table, got %v", err)
+	}
+
+	if err := runMigrations(db.GetDB()); err != nil {
+		t.Fatalf("runMigrations failed: %v", err)
+	}
+
+	upgraded, err := migrationsTableUpgraded(db)
+	if err != nil || !upgraded {
+		t.Errorf("Expected the table to be upgraded, got %v %v", upgraded, err)
+	}
+
+	var checksum sql.NullString
+	if err := db.Write.QueryRow("SELECT checksum FROM schema_migrations WHERE version = 1").Scan(&checksum); err != nil || checksum.String == "" {
+		t.Errorf("Expected migration 1 to be recorded with a checksum, got %q %v", checksum.String, err)
+	}
+}
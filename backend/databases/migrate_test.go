@@ -0,0 +1,253 @@
+package databases
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func latestApplied(t *testing.T, db *SQLiteDB) MigrationState {
+	t.Helper()
+
+	states, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+
+	var latest MigrationState
+	for _, state := range states {
+		if state.Applied {
+			latest = state
+		}
+	}
+
+	return latest
+}
+
+// TestMigrateDownAndUp verifies the latest migration is rolled back with its down file and applied again
+func TestMigrateDownAndUp(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	before := latestApplied(t, db)
+	if before.Version == 0 || !before.HasDown {
+		t.Fatalf("Expected the latest migration to have a down file, got %+v", before)
+	}
+
+	reverted, err := db.MigrateDown()
+	if err != nil {
+		t.Fatalf("MigrateDown failed: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != before.Version {
+		t.Errorf("Expected only migration %d to be rolled back, got %+v", before.Version, reverted)
+	}
+	if after := latestApplied(t, db); after.Version >= before.Version {
+		t.Errorf("Expected migration %d to be pending, latest applied is %d", before.Version, after.Version)
+	}
+
+	if err := db.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+	if after := latestApplied(t, db); after.Version != before.Version {
+		t.Errorf("Expected migration %d to be applied again, latest applied is %d", before.Version, after.Version)
+	}
+}
+
+// TestMigrationStatus_FreshDatabase verifies status works before schema_migrations exists
+func TestMigrationStatus_FreshDatabase(t *testing.T) {
+	services, err := NewSQLiteDatabases(t.TempDir() + "/fresh.sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db := services.GetDB()
+	t.Cleanup(func() { db.close() })
+
+	states, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	if len(states) == 0 {
+		t.Fatalf("Expected the embedded migrations to be listed")
+	}
+	for _, state := range states {
+		if state.Applied {
+			t.Errorf("Expected nothing applied on a fresh database, got %+v", state)
+		}
+	}
+}
+
+// TestMigrateDownTo verifies several migrations are rolled back newest first and applied again
+func TestMigrateDownTo(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	before := latestApplied(t, db)
+
+	steps, err := db.MigrateDownTo(4)
+	if err != nil {
+		t.Fatalf("MigrateDownTo failed: %v", err)
+	}
+	if len(steps) != before.Version-4 {
+		t.Fatalf("Expected %d steps, got %d", before.Version-4, len(steps))
+	}
+	for i, step := range steps {
+		if step.Direction != MIGRATION_DOWN || step.Version != before.Version-i {
+			t.Errorf("Step %d: expected down %d, got %s %d", i, before.Version-i, step.Direction, step.Version)
+		}
+	}
+	if after := latestApplied(t, db); after.Version != 4 {
+		t.Errorf("Expected version 4 after rollback, got %d", after.Version)
+	}
+
+	var columns int
+	if err := db.Write.QueryRow("SELECT COUNT(*) FROM pragma_table_info('ahsp_templates') WHERE name = 'code'").Scan(&columns); err != nil {
+		t.Fatalf("Failed to read ahsp_templates columns: %v", err)
+	}
+	if columns != 0 {
+		t.Errorf("Expected ahsp_templates.code to be dropped")
+	}
+
+	if err := db.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+	if after := latestApplied(t, db); after.Version != before.Version {
+		t.Errorf("Expected version %d after migrating up again, got %d", before.Version, after.Version)
+	}
+}
+
+// TestPlanDown_Refusals verifies targets that cannot be reached are refused before anything runs
+func TestPlanDown_Refusals(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	before := latestApplied(t, db)
+
+	tests := []struct {
+		name   string
+		target int
+		want   string
+	}{
+		{"negative target", -1, "invalid target"},
+		{"current version", before.Version, "nothing to roll back"},
+		{"below the tracking table", 3, "migration tracking table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.MigrateDownTo(tt.target)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if after := latestApplied(t, db); after.Version != before.Version {
+		t.Errorf("Expected a refused rollback to change nothing, version went from %d to %d", before.Version, after.Version)
+	}
+}
+
+// TestPlan_DoesNotChangeDatabase verifies a dry run only reads
+func TestPlan_DoesNotChangeDatabase(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	before := latestApplied(t, db)
+
+	steps, err := db.PlanDown(4)
+	if err != nil {
+		t.Fatalf("PlanDown failed: %v", err)
+	}
+	if len(steps) == 0 || len(steps[0].Statements) == 0 {
+		t.Fatalf("Expected a plan with statements, got %+v", steps)
+	}
+
+	up, err := db.PlanUp()
+	if err != nil {
+		t.Fatalf("PlanUp failed: %v", err)
+	}
+	if len(up) != 0 {
+		t.Errorf("Expected nothing pending, got %d steps", len(up))
+	}
+
+	if after := latestApplied(t, db); after.Version != before.Version {
+		t.Errorf("Expected planning to change nothing, version went from %d to %d", before.Version, after.Version)
+	}
+}
+
+// TestParseMigrationsFS verifies malformed migration folders are rejected
+func TestParseMigrationsFS(t *testing.T) {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name:  "name off the pattern",
+			files: fstest.MapFS{"m/1_init.up.sql": file("CREATE TABLE a (id INTEGER);")},
+			want:  "naming",
+		},
+		{
+			name:  "upper case description",
+			files: fstest.MapFS{"m/000001_Init.up.sql": file("CREATE TABLE a (id INTEGER);")},
+			want:  "naming",
+		},
+		{
+			name: "duplicate version",
+			files: fstest.MapFS{
+				"m/000001_init.up.sql":  file("CREATE TABLE a (id INTEGER);"),
+				"m/000001_other.up.sql": file("CREATE TABLE b (id INTEGER);"),
+			},
+			want: "two up files",
+		},
+		{
+			name:  "down without up",
+			files: fstest.MapFS{"m/000002_init.down.sql": file("DROP TABLE a;")},
+			want:  "no matching",
+		},
+		{
+			name:  "up with only comments",
+			files: fstest.MapFS{"m/000001_init.up.sql": file("-- nothing here;\n/* CREATE TABLE a; */")},
+			want:  "no SQL statements",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseMigrationsFS(tt.files, "m")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	migrations, err := parseMigrationsFS(fstest.MapFS{
+		"m/000002_second.up.sql":  file("CREATE TABLE b (id INTEGER);"),
+		"m/000001_first.up.sql":   file("CREATE TABLE a (id INTEGER);"),
+		"m/000001_first.down.sql": file("DROP TABLE a;"),
+		"m/README.md":             file("ignored"),
+	}, "m")
+	if err != nil {
+		t.Fatalf("parseMigrationsFS failed: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].version != 1 || migrations[0].downFileName != "000001_first.down.sql" || migrations[1].downFileName != "" {
+		t.Errorf("Unexpected migrations: %+v", migrations)
+	}
+}
+
+// TestSplitSQL verifies comments are dropped and semicolons inside quotes or comments do not split
+func TestSplitSQL(t *testing.T) {
+	input := `-- header; it's a comment
+CREATE TABLE a (name TEXT DEFAULT 'x;y'); /* block; comment */
+INSERT INTO a (name) VALUES ('it''s -- not a comment');
+-- trailing`
+
+	want := []string{
+		"CREATE TABLE a (name TEXT DEFAULT 'x;y')",
+		"INSERT INTO a (name) VALUES ('it''s -- not a comment')",
+	}
+
+	if got := splitSQL(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitSQL:\n got  %q\n want %q", got, want)
+	}
+}
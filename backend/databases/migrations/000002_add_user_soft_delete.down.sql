@@ -10,4 +10,4 @@ CREATE UNIQUE INDEX idx_users_username ON users(username);
 DROP INDEX IF EXISTS idx_users_deleted_at;
 
 -- Remove the soft delete column
-ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+-- Rollback: Remove the test_new table created by migration 000005
+
+DROP TABLE IF EXISTS test_new;
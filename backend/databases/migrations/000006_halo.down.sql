@@ -0,0 +1,3 @@
+-- Rollback: Remove unit_test_new column from project_item_costs
+
+ALTER TABLE project_item_costs DROP COLUMN unit_test_new;
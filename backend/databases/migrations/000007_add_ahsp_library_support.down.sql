@@ -0,0 +1,7 @@
+-- Rollback: Remove bundled AHSP library support columns
+
+DROP INDEX IF EXISTS idx_ahsp_templates_code;
+
+ALTER TABLE master_materials DROP COLUMN is_equipment;
+
+ALTER TABLE ahsp_templates DROP COLUMN code;
@@ -0,0 +1,12 @@
+-- Migration: Add columns needed by the bundled standard AHSP library
+-- Purpose: Keep the official analysis code on templates and flag equipment
+-- rows inside master_materials (equipment is costed like a material: coefficient x unit price)
+
+-- Official analysis code (e.g. "SNI 7394:2008 6.9"), NULL for user-made templates
+ALTER TABLE ahsp_templates ADD COLUMN code TEXT;
+
+-- Equipment flag for master materials (0 = material, 1 = equipment / alat)
+ALTER TABLE master_materials ADD COLUMN is_equipment INTEGER NOT NULL DEFAULT 0;
+
+-- Lookup index used by the library importer to stay idempotent
+CREATE INDEX idx_ahsp_templates_code ON ahsp_templates(code);
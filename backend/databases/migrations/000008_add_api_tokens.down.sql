@@ -0,0 +1,5 @@
+-- Rollback: Remove personal access tokens
+
+DROP INDEX IF EXISTS idx_api_tokens_user_id;
+
+DROP TABLE IF EXISTS api_tokens;
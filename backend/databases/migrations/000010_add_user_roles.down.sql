@@ -0,0 +1,3 @@
+-- Rollback: Remove user roles
+
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,45 @@
+-- Rollback: Remove organizations
+-- Organization master data is handed to an owner of the organization, without its org_id it
+-- would otherwise become a system-wide default. The rollback stops when the owner already has
+-- a row of the same name.
+
+DROP INDEX IF EXISTS idx_ahsp_templates_org_id;
+DROP INDEX IF EXISTS idx_master_work_categories_org_id;
+DROP INDEX IF EXISTS idx_master_labor_types_org_id;
+DROP INDEX IF EXISTS idx_master_materials_org_id;
+DROP INDEX IF EXISTS idx_projects_org_id;
+
+UPDATE ahsp_templates SET user_id = (
+    SELECT m.user_id FROM organization_members m
+    WHERE m.org_id = ahsp_templates.org_id
+    ORDER BY m.role = 'owner' DESC, m.created_at, m.user_id
+    LIMIT 1
+) WHERE org_id IS NOT NULL;
+UPDATE master_work_categories SET user_id = (
+    SELECT m.user_id FROM organization_members m
+    WHERE m.org_id = master_work_categories.org_id
+    ORDER BY m.role = 'owner' DESC, m.created_at, m.user_id
+    LIMIT 1
+) WHERE org_id IS NOT NULL;
+UPDATE master_labor_types SET user_id = (
+    SELECT m.user_id FROM organization_members m
+    WHERE m.org_id = master_labor_types.org_id
+    ORDER BY m.role = 'owner' DESC, m.created_at, m.user_id
+    LIMIT 1
+) WHERE org_id IS NOT NULL;
+UPDATE master_materials SET user_id = (
+    SELECT m.user_id FROM organization_members m
+    WHERE m.org_id = master_materials.org_id
+    ORDER BY m.role = 'owner' DESC, m.created_at, m.user_id
+    LIMIT 1
+) WHERE org_id IS NOT NULL;
+
+ALTER TABLE ahsp_templates DROP COLUMN org_id;
+ALTER TABLE master_work_categories DROP COLUMN org_id;
+ALTER TABLE master_labor_types DROP COLUMN org_id;
+ALTER TABLE master_materials DROP COLUMN org_id;
+ALTER TABLE projects DROP COLUMN org_id;
+
+DROP INDEX IF EXISTS idx_organization_members_user_id;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
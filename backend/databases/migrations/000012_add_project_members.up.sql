@@ -0,0 +1,18 @@
+-- Migration: Add project members
+-- Purpose: Share a single project with colleagues without a whole organization. A member is
+-- an editor, who changes the work items, or a viewer, who only reads. The project's owner,
+-- or its organization, keeps editing it and managing who it is shared with.
+
+CREATE TABLE IF NOT EXISTS project_members (
+    project_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL DEFAULT 'viewer' CHECK(role IN ('editor', 'viewer')),
+    invited_by INTEGER, -- NULL once the inviting user is deleted
+    created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, user_id),
+    FOREIGN KEY (project_id) REFERENCES projects(project_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (invited_by) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_project_members_user_id ON project_members(user_id);
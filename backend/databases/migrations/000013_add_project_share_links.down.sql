@@ -0,0 +1,4 @@
+-- Rollback: Remove project share links
+
+DROP INDEX IF EXISTS idx_project_share_links_project_id;
+DROP TABLE IF EXISTS project_share_links;
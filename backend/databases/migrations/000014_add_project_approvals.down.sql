@@ -0,0 +1,5 @@
+-- Rollback: Remove project approvals
+
+DROP INDEX IF EXISTS idx_project_approval_events_project_id;
+DROP TABLE IF EXISTS project_approval_events;
+DROP TABLE IF EXISTS project_approvals;
@@ -0,0 +1,23 @@
+-- Migration: Add audit log
+-- Purpose: Record who created, updated or deleted projects, work items, item costs, master
+-- data and AHSP components, with the row before and after the change as JSON. The entries
+-- are written by the repositories in the transaction of the change. project_id has no
+-- foreign key so the history of a deleted project is kept.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    audit_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER, -- NULL for changes made by the application itself or once the user is deleted
+    username TEXT NOT NULL DEFAULT '', -- kept when the user is deleted
+    entity TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    project_id INTEGER, -- the project the row belongs to, NULL for master data
+    action TEXT NOT NULL CHECK(action IN ('create', 'update', 'delete')),
+    before_json TEXT NOT NULL DEFAULT '',
+    after_json TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_audit_log_project_id ON audit_log(project_id);
+CREATE INDEX idx_audit_log_entity ON audit_log(entity, entity_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
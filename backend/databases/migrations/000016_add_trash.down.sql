@@ -0,0 +1,24 @@
+-- Rollback: Remove trash
+-- Rows still in the trash are deleted first, without deleted_at they would come back. The
+-- costs captured in the remaining projects are kept.
+
+DELETE FROM project_work_items WHERE deleted_at IS NOT NULL;
+DELETE FROM projects WHERE deleted_at IS NOT NULL;
+DELETE FROM ahsp_templates WHERE deleted_at IS NOT NULL;
+DELETE FROM master_materials WHERE deleted_at IS NOT NULL;
+DELETE FROM master_labor_types WHERE deleted_at IS NOT NULL;
+DELETE FROM master_work_categories WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_ahsp_templates_deleted_at;
+DROP INDEX IF EXISTS idx_master_work_categories_deleted_at;
+DROP INDEX IF EXISTS idx_master_labor_types_deleted_at;
+DROP INDEX IF EXISTS idx_master_materials_deleted_at;
+DROP INDEX IF EXISTS idx_project_work_items_deleted_at;
+DROP INDEX IF EXISTS idx_projects_deleted_at;
+
+ALTER TABLE ahsp_templates DROP COLUMN deleted_at;
+ALTER TABLE master_work_categories DROP COLUMN deleted_at;
+ALTER TABLE master_labor_types DROP COLUMN deleted_at;
+ALTER TABLE master_materials DROP COLUMN deleted_at;
+ALTER TABLE project_work_items DROP COLUMN deleted_at;
+ALTER TABLE projects DROP COLUMN deleted_at;
@@ -0,0 +1,152 @@
+-- Migration: PostgreSQL baseline schema
+-- Purpose: Create the schema SQLite reaches after migrations 000001 to 000007 in one step.
+-- PostgreSQL starts at version 7 so both engines share the version numbers of every
+-- migration added after it (000008 and later need a file in both folders).
+--
+-- Types follow what the SQLite schema stores, so the repositories read the same values
+-- from both engines: INTEGER flags (0/1), REAL as DOUBLE PRECISION and dates as TEXT in
+-- the "YYYY-MM-DD HH:MM:SS" UTC format of SQLite's CURRENT_TIMESTAMP.
+
+--  users
+CREATE TABLE IF NOT EXISTS users (
+    user_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    deleted_at TEXT DEFAULT NULL
+);
+
+--  projects
+CREATE TABLE IF NOT EXISTS projects (
+    project_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    project_name TEXT NOT NULL,
+    location TEXT,
+    client_name TEXT,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+--  master_materials
+CREATE TABLE IF NOT EXISTS master_materials (
+    material_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INTEGER, -- NULL for system-wide defaults
+    material_name TEXT NOT NULL,
+    unit TEXT NOT NULL,
+    default_unit_price DOUBLE PRECISION NOT NULL DEFAULT 0,
+    is_equipment INTEGER NOT NULL DEFAULT 0, -- 0 = material, 1 = equipment / alat
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    UNIQUE (user_id, material_name),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+--  master_labor_types
+CREATE TABLE IF NOT EXISTS master_labor_types (
+    labor_type_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INTEGER, -- NULL for system-wide defaults
+    role_name TEXT NOT NULL,
+    unit TEXT NOT NULL DEFAULT 'HOK',
+    default_daily_wage DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    UNIQUE (user_id, role_name),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+--  master_work_categories
+CREATE TABLE IF NOT EXISTS master_work_categories (
+    category_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INTEGER, -- NULL for system-wide defaults
+    category_name TEXT NOT NULL,
+    display_order INTEGER DEFAULT 0,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    UNIQUE (user_id, category_name),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+--  ahsp_templates
+CREATE TABLE IF NOT EXISTS ahsp_templates (
+    template_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INTEGER, -- NULL for system-wide defaults
+    template_name TEXT NOT NULL,
+    unit TEXT NOT NULL,
+    code TEXT, -- official analysis code (e.g. "SNI 7394:2008 6.9"), NULL for user-made templates
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    UNIQUE (user_id, template_name),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+--  ahsp_material_components
+CREATE TABLE IF NOT EXISTS ahsp_material_components (
+    component_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    template_id INTEGER NOT NULL,
+    material_id INTEGER NOT NULL,
+    coefficient DOUBLE PRECISION NOT NULL,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    FOREIGN KEY (template_id) REFERENCES ahsp_templates(template_id) ON DELETE CASCADE,
+    FOREIGN KEY (material_id) REFERENCES master_materials(material_id) ON DELETE RESTRICT
+);
+
+--  ahsp_labor_components
+CREATE TABLE IF NOT EXISTS ahsp_labor_components (
+    component_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    template_id INTEGER NOT NULL,
+    labor_type_id INTEGER NOT NULL,
+    coefficient DOUBLE PRECISION NOT NULL,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    FOREIGN KEY (template_id) REFERENCES ahsp_templates(template_id) ON DELETE CASCADE,
+    FOREIGN KEY (labor_type_id) REFERENCES master_labor_types(labor_type_id) ON DELETE RESTRICT
+);
+
+--  project_work_items
+CREATE TABLE IF NOT EXISTS project_work_items (
+    work_item_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    project_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    volume DOUBLE PRECISION NOT NULL,
+    unit TEXT NOT NULL,
+    ahsp_template_id INTEGER,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    FOREIGN KEY (project_id) REFERENCES projects(project_id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES master_work_categories(category_id) ON DELETE RESTRICT,
+    FOREIGN KEY (ahsp_template_id) REFERENCES ahsp_templates(template_id) ON DELETE SET NULL
+);
+
+--  project_item_costs
+CREATE TABLE IF NOT EXISTS project_item_costs (
+    cost_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    work_item_id INTEGER NOT NULL,
+    item_type TEXT NOT NULL CHECK(item_type IN ('MATERIAL', 'LABOR')),
+    master_item_id INTEGER NOT NULL,
+    item_name TEXT NOT NULL,
+    unit TEXT, -- unit of manual cost entries
+    coefficient DOUBLE PRECISION NOT NULL,
+    quantity_needed DOUBLE PRECISION NOT NULL,
+    unit_price_at_creation DOUBLE PRECISION NOT NULL,
+    total_cost DOUBLE PRECISION NOT NULL,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    FOREIGN KEY (work_item_id) REFERENCES project_work_items(work_item_id) ON DELETE CASCADE
+);
+
+-- index
+CREATE INDEX IF NOT EXISTS idx_pwi_project_id ON project_work_items(project_id);
+CREATE INDEX IF NOT EXISTS idx_pic_work_item_id ON project_item_costs(work_item_id);
+CREATE INDEX IF NOT EXISTS idx_ahsp_mat_template_id ON ahsp_material_components(template_id);
+CREATE INDEX IF NOT EXISTS idx_ahsp_lab_template_id ON ahsp_labor_components(template_id);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_active ON users(username) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_ahsp_templates_code ON ahsp_templates(code);
+
+-- Insert default admin user (password: admin123)
+-- Note: In production, this should be changed or created through a proper setup process
+INSERT INTO users (username, password) VALUES ('admin', '$2a$10$xczsaOoRo5OCy1AC5hHWA.OoMshm7uY0Xq4gdO0ow.SMr11EEtUx6') ON CONFLICT DO NOTHING;
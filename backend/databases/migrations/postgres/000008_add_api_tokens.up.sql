@@ -0,0 +1,20 @@
+-- Migration: Add personal access tokens
+-- Purpose: Let scripts call the JSON API with a bearer token instead of a session cookie.
+-- Only the SHA-256 hash of a token is stored; token_prefix keeps its first characters so
+-- the settings page can tell tokens apart.
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    token_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    token_name TEXT NOT NULL,
+    token_prefix TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scope TEXT NOT NULL CHECK(scope IN ('read', 'write')),
+    expires_at TEXT, -- NULL for tokens that do not expire
+    last_used_at TEXT,
+    revoked_at TEXT,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
@@ -0,0 +1,6 @@
+-- Migration: Add user roles
+-- Purpose: Role-based access control. Estimators build their own RAB as before, reviewers
+-- approve, viewers only read and admins also manage users and the system-wide master data.
+-- Existing accounts keep working as estimators; ADMIN_USERNAMES still makes a user admin.
+
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'estimator' CHECK(role IN ('admin', 'estimator', 'reviewer', 'viewer'));
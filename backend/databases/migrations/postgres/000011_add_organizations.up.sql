@@ -0,0 +1,38 @@
+-- Migration: Add organizations
+-- Purpose: Let a team share master data and projects. A row is owned by a user (user_id), by
+-- an organization (org_id) or by nobody for the system-wide defaults. Organization master data
+-- leaves user_id NULL, so the per-user unique names of the members' own rows do not clash;
+-- organization projects keep their creator in user_id.
+-- org_id has no REFERENCES clause, like the SQLite schema; an organization is only deleted
+-- when it owns nothing.
+
+CREATE TABLE IF NOT EXISTS organizations (
+    org_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role TEXT NOT NULL DEFAULT 'member' CHECK(role IN ('owner', 'member')),
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    PRIMARY KEY (org_id, user_id),
+    FOREIGN KEY (org_id) REFERENCES organizations(org_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+
+ALTER TABLE projects ADD COLUMN org_id INTEGER;
+ALTER TABLE master_materials ADD COLUMN org_id INTEGER;
+ALTER TABLE master_labor_types ADD COLUMN org_id INTEGER;
+ALTER TABLE master_work_categories ADD COLUMN org_id INTEGER;
+ALTER TABLE ahsp_templates ADD COLUMN org_id INTEGER;
+
+CREATE INDEX idx_projects_org_id ON projects(org_id);
+CREATE INDEX idx_master_materials_org_id ON master_materials(org_id);
+CREATE INDEX idx_master_labor_types_org_id ON master_labor_types(org_id);
+CREATE INDEX idx_master_work_categories_org_id ON master_work_categories(org_id);
+CREATE INDEX idx_ahsp_templates_org_id ON ahsp_templates(org_id);
@@ -0,0 +1,4 @@
+-- Rollback: Remove project members
+
+DROP INDEX IF EXISTS idx_project_members_user_id;
+DROP TABLE IF EXISTS project_members;
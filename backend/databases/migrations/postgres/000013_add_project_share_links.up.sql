@@ -0,0 +1,23 @@
+-- Migration: Add project share links
+-- Purpose: Let clients without an account open a read-only version of a project. As with API
+-- tokens only the SHA-256 hash of a link's token is stored; a link can expire, be revoked and
+-- be protected with a bcrypt hashed password. access_count counts the views for the owner.
+
+CREATE TABLE IF NOT EXISTS project_share_links (
+    link_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    project_id INTEGER NOT NULL,
+    created_by INTEGER, -- NULL once the creating user is deleted
+    label TEXT NOT NULL,
+    token_prefix TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    password_hash TEXT, -- NULL for links without a password
+    expires_at TEXT, -- NULL for links that do not expire
+    revoked_at TEXT,
+    access_count INTEGER NOT NULL DEFAULT 0,
+    last_accessed_at TEXT,
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    FOREIGN KEY (project_id) REFERENCES projects(project_id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_project_share_links_project_id ON project_share_links(project_id);
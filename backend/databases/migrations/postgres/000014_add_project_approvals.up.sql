@@ -0,0 +1,33 @@
+-- Migration: Add project approvals
+-- Purpose: Review the RAB of a project before it is used. A project goes from draft to
+-- submitted, in review and approved or rejected; its work items cannot change from submission
+-- until it is rejected, withdrawn or a new revision of an approved project is started.
+-- Projects without a row are drafts of revision 1. Every step and reviewer comment is kept
+-- in project_approval_events with the project total at that moment.
+
+CREATE TABLE IF NOT EXISTS project_approvals (
+    project_id INTEGER PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'draft' CHECK(status IN ('draft', 'submitted', 'in_review', 'approved', 'rejected')),
+    revision INTEGER NOT NULL DEFAULT 1,
+    updated_by INTEGER, -- NULL once the user is deleted
+    updated_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    FOREIGN KEY (project_id) REFERENCES projects(project_id) ON DELETE CASCADE,
+    FOREIGN KEY (updated_by) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS project_approval_events (
+    event_id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    project_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL, -- the same as from_status for comments
+    comment TEXT NOT NULL DEFAULT '',
+    total_cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+    user_id INTEGER, -- NULL once the user is deleted
+    created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    FOREIGN KEY (project_id) REFERENCES projects(project_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_project_approval_events_project_id ON project_approval_events(project_id);
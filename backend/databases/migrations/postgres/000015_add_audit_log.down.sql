@@ -0,0 +1,6 @@
+-- Rollback: Remove audit log
+
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP INDEX IF EXISTS idx_audit_log_entity;
+DROP INDEX IF EXISTS idx_audit_log_project_id;
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,20 @@
+-- Migration: Add trash
+-- Purpose: Soft delete projects, work items, materials, labor types, work categories and AHSP
+-- templates. Deleting one sets deleted_at and every query leaves it out; the Trash page restores
+-- it or deletes it for good, rows older than TRASH_RETENTION_DAYS are deleted automatically.
+-- The work items of a deleted project keep their own deleted_at, so restoring the project
+-- brings back the items it had.
+
+ALTER TABLE projects ADD COLUMN deleted_at TEXT DEFAULT NULL;
+ALTER TABLE project_work_items ADD COLUMN deleted_at TEXT DEFAULT NULL;
+ALTER TABLE master_materials ADD COLUMN deleted_at TEXT DEFAULT NULL;
+ALTER TABLE master_labor_types ADD COLUMN deleted_at TEXT DEFAULT NULL;
+ALTER TABLE master_work_categories ADD COLUMN deleted_at TEXT DEFAULT NULL;
+ALTER TABLE ahsp_templates ADD COLUMN deleted_at TEXT DEFAULT NULL;
+
+CREATE INDEX idx_projects_deleted_at ON projects(deleted_at);
+CREATE INDEX idx_project_work_items_deleted_at ON project_work_items(deleted_at);
+CREATE INDEX idx_master_materials_deleted_at ON master_materials(deleted_at);
+CREATE INDEX idx_master_labor_types_deleted_at ON master_labor_types(deleted_at);
+CREATE INDEX idx_master_work_categories_deleted_at ON master_work_categories(deleted_at);
+CREATE INDEX idx_ahsp_templates_deleted_at ON ahsp_templates(deleted_at);
@@ -0,0 +1,121 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// POSTGRES_DRIVER_NAME is the database/sql driver registered for PostgreSQL, pgx with the
+// ? placeholders of the repositories rewritten to $n
+const POSTGRES_DRIVER_NAME = "pgx-rebind"
+
+// ErrBackupUnsupported is returned by backups and restores of a PostgreSQL database,
+// they belong to pg_dump and pg_restore and the tooling around the server
+var ErrBackupUnsupported = errors.New("backups and restores are not supported on PostgreSQL, use pg_dump and pg_restore")
+
+func init() {
+	sql.Register(POSTGRES_DRIVER_NAME, postgresDriver{})
+}
+
+// PostgresDB is the PostgreSQL database. Unlike SQLite it has many writers, so the read
+// and write pools are the same pool and read transactions only differ by being READ ONLY.
+type PostgresDB struct {
+	DB
+	URL string
+}
+
+func NewPostgresDatabases(url string) (*PostgresDB, error) {
+	db := &PostgresDB{
+		DB:  DB{dialect: DIALECT_POSTGRES},
+		URL: url,
+	}
+
+	pool, err := sql.Open(POSTGRES_DRIVER_NAME, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test the connection
+	if err := pool.Ping(); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	pool.SetMaxOpenConns(25)
+	pool.SetMaxIdleConns(5)
+	pool.SetConnMaxIdleTime(time.Minute)
+
+	db.read = pool
+	db.Write = pool
+
+	log.Println("PostgreSQL database connection established successfully")
+
+	return db, nil
+}
+
+// BackupDir is BACKUP_DIR, PostgreSQL has no database file to keep backups next to
+func (s *PostgresDB) BackupDir() string {
+	return os.Getenv("BACKUP_DIR")
+}
+
+func (s *PostgresDB) Backup(ctx context.Context, destPath string) error {
+	return ErrBackupUnsupported
+}
+
+func (s *PostgresDB) Restore(ctx context.Context, srcPath string) (RestoreResult, error) {
+	return RestoreResult{}, ErrBackupUnsupported
+}
+
+// postgresDriver opens pgx connections that accept the queries written for SQLite
+type postgresDriver struct{}
+
+func (postgresDriver) Open(name string) (driver.Conn, error) {
+	conn, err := stdlib.GetDefaultDriver().Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresConn{Conn: conn.(*stdlib.Conn)}, nil
+}
+
+// postgresConn rebinds placeholders and stores booleans as the 0/1 integers the schema uses
+type postgresConn struct {
+	*stdlib.Conn
+}
+
+func (c *postgresConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(DIALECT_POSTGRES.Rebind(query))
+}
+
+func (c *postgresConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.PrepareContext(ctx, DIALECT_POSTGRES.Rebind(query))
+}
+
+func (c *postgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.ExecContext(ctx, DIALECT_POSTGRES.Rebind(query), args)
+}
+
+func (c *postgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.QueryContext(ctx, DIALECT_POSTGRES.Rebind(query), args)
+}
+
+// CheckNamedValue runs for every argument, of statements and direct queries alike
+func (c *postgresConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if b, ok := nv.Value.(bool); ok {
+		nv.Value = int64(0)
+		if b {
+			nv.Value = int64(1)
+		}
+		return nil
+	}
+
+	return c.Conn.CheckNamedValue(nv)
+}
@@ -1,13 +1,11 @@
 package databases
 
 import (
-	"context"
 	"database/sql"
 	"embed"
 	"fmt"
 	"io/fs"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -22,9 +20,12 @@ import (
 
 const (
 	DATABASE_FOLDER_NAME = "databases"
+
+	// DATABASE_BUSY_TIMEOUT is how long a connection waits for a lock before SQLITE_BUSY
+	DATABASE_BUSY_TIMEOUT = 5 * time.Second
 )
 
-//go:embed migrations/*.sql
+//go:embed migrations/*.sql migrations/postgres/*.sql
 var migrationsFS embed.FS
 
 var (
@@ -38,26 +39,25 @@ func init() {
 	DATABASE_SQLITE_PATH = filepath.Join(DATABASE_SQLITE_FOLDERS, "database.sqlite")
 }
 
-type SQLiteServices interface {
-	GetDB() *SQLiteDB
-
-	Transaction(ctx context.Context, fn func(tx *sql.Tx) (statusCode int, err error)) (statusCode int, err error)
-}
-
+// SQLiteDB is the SQLite database, a single file with one writer connection
 type SQLiteDB struct {
+	DB
 	DatabasesPath string
-	read          *sql.DB
-	Write         *sql.DB // Exported field for access
 }
 
 // migrationInfo holds parsed migration information
 type migrationInfo struct {
-	version int
-	name    string
-	fileName string
+	version      int
+	name         string
+	dir          string // folder of the files inside migrationsFS
+	fileName     string
+	downFileName string // empty when there is no .down.sql file
 }
 
-func runMigrations(db *SQLiteDB) error {
+// migrationFilePattern is the only accepted file name: 000001_description.up.sql or 000001_description.down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d{6})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+func runMigrations(db *DB) error {
 	// Step 1: Ensure schema_migrations table exists
 	if err := ensureMigrationsTableExists(db); err != nil {
 		return fmt.Errorf("failed to ensure migrations table exists: %w", err)
@@ -70,12 +70,17 @@ func runMigrations(db *SQLiteDB) error {
 	}
 
 	// Step 3: Read and parse all migration files
-	migrations, err := parseMigrationFiles()
+	migrations, err := parseMigrationFiles(db.dialect)
 	if err != nil {
 		return fmt.Errorf("failed to parse migration files: %w", err)
 	}
 
-	// Step 4: Filter out already-applied migrations
+	// Step 4: Refuse a dirty database and compare checksums of the applied migrations
+	if err := verifyAppliedMigrations(db, migrations, appliedVersions); err != nil {
+		return err
+	}
+
+	// Step 5: Filter out already-applied migrations
 	var pendingMigrations []migrationInfo
 	for _, m := range migrations {
 		if _, applied := appliedVersions[m.version]; !applied {
@@ -90,7 +95,7 @@ func runMigrations(db *SQLiteDB) error {
 
 	log.Printf("Found %d new migration(s) to apply", len(pendingMigrations))
 
-	// Step 5: Execute pending migrations in order
+	// Step 6: Execute pending migrations in order
 	for _, m := range pendingMigrations {
 		log.Printf("Applying migration: %d_%s", m.version, m.name)
 		if err := applyMigration(db, m); err != nil {
@@ -102,68 +107,134 @@ func runMigrations(db *SQLiteDB) error {
 	return nil
 }
 
-// ensureMigrationsTableExists creates the schema_migrations table if it doesn't exist
-func ensureMigrationsTableExists(db *SQLiteDB) error {
+// ensureMigrationsTableExists creates the schema_migrations table if it doesn't exist,
+// and adds the checksum and dirty columns to tables created before they existed
+func ensureMigrationsTableExists(db *DB) error {
 	query := `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version INTEGER PRIMARY KEY,
 			name TEXT NOT NULL,
-			applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+			applied_at TEXT NOT NULL DEFAULT ` + db.dialect.now() + `,
+			checksum TEXT NOT NULL DEFAULT '',
+			dirty INTEGER NOT NULL DEFAULT 0
 		);
 	`
-	_, err := db.Write.Exec(query)
-	return err
+	if _, err := db.Write.Exec(query); err != nil {
+		return err
+	}
+
+	upgraded, err := migrationsTableUpgraded(db)
+	if err != nil || upgraded {
+		return err
+	}
+
+	for _, stmt := range []string{
+		"ALTER TABLE schema_migrations ADD COLUMN checksum TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE schema_migrations ADD COLUMN dirty INTEGER NOT NULL DEFAULT 0",
+	} {
+		if _, err := db.Write.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// getAppliedMigrations returns a map of applied migration versions
-func getAppliedMigrations(db *SQLiteDB) (map[int]bool, error) {
-	query := `SELECT version FROM schema_migrations`
+// getAppliedMigrations returns the schema_migrations rows by version, dirty ones included
+func getAppliedMigrations(db *DB) (map[int]appliedMigration, error) {
+	query := `SELECT version, name, checksum, dirty FROM schema_migrations`
 	rows, err := db.Write.Query(query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	applied := make(map[int]bool)
+	applied := make(map[int]appliedMigration)
 	for rows.Next() {
-		var version int
-		if err := rows.Scan(&version); err != nil {
+		var m appliedMigration
+		if err := rows.Scan(&m.version, &m.name, &m.checksum, &m.dirty); err != nil {
 			return nil, err
 		}
-		applied[version] = true
+		applied[m.version] = m
 	}
 
 	return applied, rows.Err()
 }
 
-// parseMigrationFiles reads and parses all .up.sql migration files
-func parseMigrationFiles() ([]migrationInfo, error) {
-	entries, err := fs.ReadDir(migrationsFS, "migrations")
+// parseMigrationFiles reads and validates the embedded migration files of the engine
+func parseMigrationFiles(dialect Dialect) ([]migrationInfo, error) {
+	return parseMigrationsFS(migrationsFS, dialect.migrationsDir())
+}
+
+// parseMigrationsFS reads the migrations in dir and refuses anything ambiguous instead of
+// skipping it: file names off the pattern, two files for one version, down files without
+// an up file and up files without a single statement.
+func parseMigrationsFS(fsys fs.FS, dir string) ([]migrationInfo, error) {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
 	}
 
-	var migrations []migrationInfo
-	// Pattern: 000001_description.up.sql
-	pattern := regexp.MustCompile(`^(\d+)_(.+)\.up\.sql$`)
+	ups := map[int]*migrationInfo{}
+	downs := map[int]string{}
 
 	for _, entry := range entries {
-		matches := pattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
 		if matches == nil {
-			continue // Skip files that don't match the pattern
+			return nil, fmt.Errorf("migration file %s does not follow the 000001_description.up.sql / .down.sql naming", entry.Name())
 		}
 
-		version, err := strconv.Atoi(matches[1])
-		if err != nil {
-			log.Printf("Warning: invalid version number in %s, skipping", entry.Name())
+		version, _ := strconv.Atoi(matches[1])
+		if version == 0 {
+			return nil, fmt.Errorf("migration file %s: versions start at 000001", entry.Name())
+		}
+
+		if matches[3] == "down" {
+			if existing, ok := downs[version]; ok {
+				return nil, fmt.Errorf("migration version %d has two down files: %s and %s", version, existing, entry.Name())
+			}
+			downs[version] = entry.Name()
 			continue
 		}
 
-		migrations = append(migrations, migrationInfo{
+		if existing, ok := ups[version]; ok {
+			return nil, fmt.Errorf("migration version %d has two up files: %s and %s", version, existing.fileName, entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file: %w", err)
+		}
+		if len(splitSQL(string(content))) == 0 {
+			return nil, fmt.Errorf("migration file %s has no SQL statements", entry.Name())
+		}
+
+		ups[version] = &migrationInfo{
 			version:  version,
 			name:     matches[2],
+			dir:      dir,
 			fileName: entry.Name(),
-		})
+		}
+	}
+
+	for version, downFile := range downs {
+		m, ok := ups[version]
+		if !ok {
+			return nil, fmt.Errorf("migration file %s has no matching up file", downFile)
+		}
+		if strings.TrimSuffix(downFile, ".down.sql") != strings.TrimSuffix(m.fileName, ".up.sql") {
+			return nil, fmt.Errorf("migration files %s and %s have different names", m.fileName, downFile)
+		}
+		m.downFileName = downFile
+	}
+
+	migrations := make([]migrationInfo, 0, len(ups))
+	for _, m := range ups {
+		migrations = append(migrations, *m)
 	}
 
 	// Sort by version
@@ -174,73 +245,58 @@ func parseMigrationFiles() ([]migrationInfo, error) {
 	return migrations, nil
 }
 
-// applyMigration executes a single migration and records it
-func applyMigration(db *SQLiteDB, m migrationInfo) error {
-	// Read migration file content
-	migrationPath := "migrations/" + m.fileName
-	content, err := migrationsFS.ReadFile(migrationPath)
-	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
-	}
-
-	// Split content by semicolons
-	statements := splitSQL(string(content))
-
-	// Execute migration in a transaction that also records it
-	statusCode, err := db.Transaction(context.Background(), func(tx *sql.Tx) (int, error) {
-		// Execute each SQL statement
-		for _, stmt := range statements {
-			if stmt == "" {
-				continue
-			}
-			// Skip CREATE TABLE IF NOT EXISTS for schema_migrations (we handle it separately)
-			if strings.Contains(stmt, "schema_migrations") && strings.Contains(stmt, "CREATE TABLE") {
-				continue
-			}
-			if _, err := tx.Exec(stmt); err != nil {
-				return http.StatusInternalServerError, fmt.Errorf("failed to execute statement: %w", err)
-			}
-		}
-
-		// Record the migration
-		recordQuery := `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`
-		if _, err := tx.Exec(recordQuery, m.version, m.name); err != nil {
-			return http.StatusInternalServerError, fmt.Errorf("failed to record migration: %w", err)
-		}
-
-		return http.StatusOK, nil
-	})
-
+// applyMigration executes a single migration and records it. The migration is marked
+// dirty first, in its own transaction, so a failure or a crash leaves a trace that stops
+// the next start until "rabmaker migrate repair" is run.
+func applyMigration(db *DB, m migrationInfo) error {
+	step, err := upStep(m)
 	if err != nil {
 		return err
 	}
 
-	if statusCode != http.StatusOK && statusCode != http.StatusAccepted {
-		return fmt.Errorf("migration failed with status code: %d", statusCode)
+	if err := markMigrationDirty(db, step); err != nil {
+		return err
 	}
 
-	return nil
+	return executeSteps(db, []MigrationStep{step})
 }
 
-// splitSQL splits SQL content by semicolons, ignoring empty statements
+// splitSQL splits SQL content by semicolons, ignoring empty statements.
+// -- and /* */ comments outside quotes are dropped, so a quote or semicolon
+// inside a comment cannot change how the rest of the file is split.
 func splitSQL(sql string) []string {
 	var statements []string
 	var current strings.Builder
 	inQuote := false
 	quoteChar := rune(0)
 
-	for _, ch := range sql {
+	runes := []rune(sql)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+
 		switch {
-		case (ch == '\'' || ch == '"' || ch == '`') && !inQuote:
+		case inQuote:
+			if ch == quoteChar {
+				inQuote = false
+			}
+			current.WriteRune(ch)
+		case ch == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i++ // the closing slash
+			current.WriteRune(' ')
+		case ch == '\'' || ch == '"' || ch == '`':
 			inQuote = true
 			quoteChar = ch
 			current.WriteRune(ch)
-		case ch == quoteChar && inQuote:
-			inQuote = false
-			current.WriteRune(ch)
-		case ch == ';' && !inQuote:
-			stmt := strings.TrimSpace(current.String())
-			if stmt != "" {
+		case ch == ';':
+			if stmt := strings.TrimSpace(current.String()); stmt != "" {
 				statements = append(statements, stmt)
 			}
 			current.Reset()
@@ -257,121 +313,98 @@ func splitSQL(sql string) []string {
 	return statements
 }
 
-func NewSQLiteDatabases(databasesPath string) (SQLiteServices, error) {
-	// setup for read database
-	write, err := sql.Open("sqlite", "file:"+databasesPath)
-	if err != nil {
+func NewSQLiteDatabases(databasesPath string) (*SQLiteDB, error) {
+	db := &SQLiteDB{
+		DB:            DB{dialect: DIALECT_SQLITE},
+		DatabasesPath: databasesPath,
+	}
+
+	if err := db.open(); err != nil {
 		return nil, err
 	}
 
+	log.Println("SQLite database connection established successfully at: ", databasesPath)
+
+	return db, nil
+}
+
+// open (re)creates the read and write connection pools on DatabasesPath.
+// The _pragma parameters run on every new connection of a pool, not only the first one.
+func (s *SQLiteDB) open() error {
+	// CRITICAL: Enable foreign keys on every connection
+	// Without this, all CASCADE/RESTRICT constraints in the schema are NOT enforced
+	// busy_timeout makes a connection wait for a lock held by another process
+	// (the rabmaker CLI) instead of failing with SQLITE_BUSY
+	common := fmt.Sprintf("_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)", DATABASE_BUSY_TIMEOUT.Milliseconds())
+
+	// setup for write database
+	// WAL lets readers keep reading the last committed data while a write transaction is open,
+	// _txlock=immediate takes the write lock on BEGIN so two writers never deadlock on upgrade
+	write, err := sql.Open("sqlite", "file:"+s.DatabasesPath+"?"+common+"&_pragma=journal_mode(WAL)&_txlock=immediate")
+	if err != nil {
+		return err
+	}
+
 	// Test the connection
 	if err := write.Ping(); err != nil {
-		return nil, err
+		return err
 	}
 
 	// only single writer to avoid SQLITE_BUSY
 	write.SetMaxOpenConns(1)
 
-	// CRITICAL: Enable foreign keys on write connection
-	// Without this, all CASCADE/RESTRICT constraints in the schema are NOT enforced
-	if _, err := write.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys on write connection: %w", err)
-	}
-
 	// setup for read database
-	read, err := sql.Open("sqlite", "file:"+databasesPath)
+	// query_only makes any write through the read pool fail instead of bypassing the single writer
+	read, err := sql.Open("sqlite", "file:"+s.DatabasesPath+"?"+common+"&_pragma=query_only(1)")
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Test the connection
 	if err := read.Ping(); err != nil {
-		return nil, err
+		return err
 	}
 
 	read.SetMaxOpenConns(100)
 	read.SetConnMaxIdleTime(time.Minute)
 
-	// CRITICAL: Enable foreign keys on read connection
-	// Without this, all CASCADE/RESTRICT constraints in the schema are NOT enforced
-	if _, err := read.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys on read connection: %w", err)
-	}
+	s.read = read
+	s.Write = write
 
-	log.Println("SQLite database connection established successfully at: ", databasesPath)
-
-	return &SQLiteDB{
-		DatabasesPath: databasesPath,
-		read:          read,
-		Write:         write,
-	}, nil
+	return nil
 }
 
-func InitDatabaseSQLite() error {
+// InitDatabaseSQLite opens the database file at path, creating it and its folder when
+// needed, and applies the pending migrations
+func InitDatabaseSQLite(path string) (*SQLiteDB, error) {
 	// Ensure the database directory exists
-	if err := os.MkdirAll(DATABASE_SQLITE_FOLDERS, 0755); err != nil {
-		return fmt.Errorf("failed to create database directory: %w", err)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
 	// Check if this is a new database
-	_, err := os.Stat(DATABASE_SQLITE_PATH)
+	_, err := os.Stat(path)
 	isNewDatabase := os.IsNotExist(err)
 	if isNewDatabase {
-		log.Println("Creating new database at:", DATABASE_SQLITE_PATH)
+		log.Println("Creating new database at:", path)
 	} else {
 		log.Println("Database file exists, checking for new migrations...")
 	}
 
 	// Always create/open the database connection
-	db, err := NewSQLiteDatabases(DATABASE_SQLITE_PATH)
+	db, err := NewSQLiteDatabases(path)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Always run migration checks (will only apply unapplied migrations)
-	if err := runMigrations(db.GetDB()); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	if err := db.MigrateUp(); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
 	if isNewDatabase {
 		log.Println("Database successfully initialized")
 	}
 
-	return nil
-}
-
-func (s *SQLiteDB) GetDB() *SQLiteDB {
-	return s
-}
-
-func (s *SQLiteDB) Transaction(ctx context.Context, fn func(tx *sql.Tx) (statusCode int, err error)) (statusCode int, err error) {
-
-	// get and separate conn justt for writer
-	// so that the tx queries are executed together
-	// conn, err := s.write.Conn(ctx)
-	// if err != nil {
-	// 	return fmt.Errorf("failed to get sqlite writer connection: %w", err), http.StatusInternalServerError
-	// }
-	// defer conn.Close()
-
-	// tx, err := conn.BeginTx(ctx, nil)
-	tx, err := s.Write.BeginTx(ctx, nil)
-	if err != nil {
-		return http.StatusInternalServerError, err
-	}
-
-	if statusCode, err = fn(tx); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return http.StatusInternalServerError, fmt.Errorf("transaction rollback failed: %v, original error: %w", rbErr, err)
-		}
-
-		return statusCode, err
-	}
-
-	// commit tx if fn is success
-	if err := tx.Commit(); err != nil {
-		return http.StatusInternalServerError, fmt.Errorf("transaction commit failed: %w", err)
-	}
-
-	return http.StatusAccepted, nil
+	return db, nil
 }
@@ -1,9 +1,13 @@
 package databases
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -199,3 +203,137 @@ func TestForeignKeyRestrict(t *testing.T) {
 		t.Errorf("Expected material to still exist after failed delete, got count %d, err: %v", count, err)
 	}
 }
+
+// TestConnectionPragmas verifies every pooled connection gets the pragmas, not only the first one
+func TestConnectionPragmas(t *testing.T) {
+	db := setupMigratedDB(t)
+	ctx := context.Background()
+
+	var journalMode string
+	if err := db.Write.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("Failed to read journal_mode: %v", err)
+	}
+	if journalMode != "wal" {
+		t.Errorf("Expected WAL journal mode, got %s", journalMode)
+	}
+
+	// hold several read connections at once so the pool has to open new ones
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		conn, err := db.read.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get read connection: %v", err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+
+	for i, conn := range conns {
+		var foreignKeys, busyTimeout, queryOnly int
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+			t.Fatalf("Failed to read foreign_keys: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+			t.Fatalf("Failed to read busy_timeout: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA query_only").Scan(&queryOnly); err != nil {
+			t.Fatalf("Failed to read query_only: %v", err)
+		}
+
+		if foreignKeys != 1 || busyTimeout != int(DATABASE_BUSY_TIMEOUT.Milliseconds()) || queryOnly != 1 {
+			t.Errorf("Read connection %d: foreign_keys=%d busy_timeout=%d query_only=%d", i, foreignKeys, busyTimeout, queryOnly)
+		}
+	}
+}
+
+// TestReadTransaction_RejectsWrites verifies the read pool cannot be used to write
+func TestReadTransaction_RejectsWrites(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	before := countUsers(t, db)
+
+	if _, err := db.ReadTransaction(context.Background(), func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec("INSERT INTO users (username, password) VALUES ('reader', 'x')")
+		return 500, err
+	}); err == nil {
+		t.Errorf("Expected a write in a read transaction to fail")
+	}
+
+	if after := countUsers(t, db); after != before {
+		t.Errorf("Expected %d users, got %d", before, after)
+	}
+}
+
+// TestReadTransaction_DoesNotWaitForWriter verifies reads run while a long write transaction
+// is open, see the last commit, and see the write once it commits
+func TestReadTransaction_DoesNotWaitForWriter(t *testing.T) {
+	db := setupMigratedDB(t)
+
+	before := countUsers(t, db)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := db.Transaction(context.Background(), func(tx *sql.Tx) (int, error) {
+			if _, err := tx.Exec("INSERT INTO users (username, password) VALUES ('writer', 'x')"); err != nil {
+				return 500, err
+			}
+			close(started)
+			<-release
+			return 200, nil
+		})
+		done <- err
+	}()
+	<-started
+
+	readUsers := func(ctx context.Context) (int, error) {
+		var count int
+		_, err := db.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+			return 200, tx.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+		})
+		return count, err
+	}
+
+	// a handful of concurrent readers, each must finish while the writer still holds its transaction
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, err := readUsers(ctx)
+			if err == nil && count != before {
+				err = fmt.Errorf("expected the last committed %d users, got %d", before, count)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Read during the write failed: %v", err)
+		}
+	}
+
+	// a second writer still waits for the first one
+	writeCtx, writeCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer writeCancel()
+	if _, err := db.Transaction(writeCtx, func(tx *sql.Tx) (int, error) { return 200, nil }); err == nil {
+		t.Errorf("Expected a second write transaction to wait for the first one")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Write transaction failed: %v", err)
+	}
+
+	if count, err := readUsers(context.Background()); err != nil || count != before+1 {
+		t.Errorf("Expected %d users after the commit, got %d (%v)", before+1, count, err)
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/ahsp_analysis"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// AhspAnalysisHandler exports priced AHSP analyses of one template (/ahsp_templates/:templateId/analysis)
+// or of every template visible to the user (/ahsp_templates/analysis)
+type AhspAnalysisHandler struct {
+	dbService         databases.DatabaseServices
+	builder           *ahsp_analysis.Builder
+	ahspTemplatesRepo ahsptemplates.Repository
+	projectsRepo      projects.Repository
+}
+
+func NewAhspAnalysisHandler(
+	dbService databases.DatabaseServices,
+	builder *ahsp_analysis.Builder,
+	ahspTemplatesRepo ahsptemplates.Repository,
+	projectsRepo projects.Repository,
+) *AhspAnalysisHandler {
+	return &AhspAnalysisHandler{
+		dbService:         dbService,
+		builder:           builder,
+		ahspTemplatesRepo: ahspTemplatesRepo,
+		projectsRepo:      projectsRepo,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+// AhspAnalysisExportModalView shows the export options, for a single template when the route has a template id
+func (h *AhspAnalysisHandler) AhspAnalysisExportModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	// Get user from session (using the same approach as in auth.handler.go)
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	title := "Export All AHSP Analyses"
+	downloadURL := "/ahsp_templates/analysis/download"
+
+	var userProjects []models.Project
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		if c.Params("templateId") != "" {
+			template, status, err := h.findVisibleTemplate(ctx, tx, c.Params("templateId"), userData)
+			if err != nil {
+				return status, err
+			}
+			title = "Export Analysis: " + ahsp_analysis.Title(template)
+			downloadURL = "/ahsp_templates/" + strconv.Itoa(template.TemplateId) + "/analysis/download"
+		}
+
+		var err error
+		userProjects, _, err = h.projectsRepo.FindByWorkspace(ctx, tx, userData.Workspace(), models.TablePaginationDataInput{Page: 1, PerPage: 100})
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return utils.ResponseErrorModal(c, "Error", err.Error())
+	}
+
+	modal := components.AhspAnalysisExportModal(title, downloadURL, userProjects, c.Params("templateId") == "")
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// ExportAhspAnalysis sends the analyses as PDF or Excel. Query: format (pdf or excel),
+// price_source ("current" or "project:<id>"), overhead (percent) and, for all templates, search.
+func (h *AhspAnalysisHandler) ExportAhspAnalysis(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	// Get user from session (using the same approach as in auth.handler.go)
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	format := c.Query("format", "pdf")
+	if format != "pdf" && format != "excel" {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid format. Use 'pdf' or 'excel'")
+	}
+
+	options := models.AhspAnalysisOptions{
+		PriceSource:     ahsp_analysis.PRICE_SOURCE_CURRENT,
+		PriceLabel:      "Current master prices",
+		OverheadPercent: ahsp_analysis.DEFAULT_OVERHEAD_PERCENT,
+	}
+
+	if priceSource := c.Query("price_source", ahsp_analysis.PRICE_SOURCE_CURRENT); priceSource != ahsp_analysis.PRICE_SOURCE_CURRENT {
+		projectIdStr, ok := strings.CutPrefix(priceSource, ahsp_analysis.PRICE_SOURCE_PROJECT+":")
+		projectId, err := strconv.Atoi(projectIdStr)
+		if !ok || err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid price source")
+		}
+		options.PriceSource = ahsp_analysis.PRICE_SOURCE_PROJECT
+		options.ProjectId = projectId
+	}
+
+	if overhead := c.Query("overhead"); overhead != "" {
+		overheadPercent, err := strconv.ParseFloat(overhead, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid overhead percentage")
+		}
+		options.OverheadPercent = overheadPercent
+	}
+
+	if err := utils.ValidateStruct(options); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Overhead must be between 0 and 100 percent")
+	}
+
+	// First, fetch data in transaction
+	var analyses []models.AhspAnalysis
+	filename := "ahsp-analyses"
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var templates []models.AHSPTemplate
+
+		if c.Params("templateId") != "" {
+			template, status, err := h.findVisibleTemplate(ctx, tx, c.Params("templateId"), userData)
+			if err != nil {
+				return status, err
+			}
+			templates = []models.AHSPTemplate{template}
+			filename = "ahsp-analysis-" + strconv.Itoa(template.TemplateId)
+		} else {
+			var err error
+			templates, err = h.ahspTemplatesRepo.FindAll(ctx, tx, userData.Workspace(), c.Query("search"))
+			if err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+			if len(templates) == 0 {
+				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "No templates to export")
+			}
+		}
+
+		if options.PriceSource == ahsp_analysis.PRICE_SOURCE_PROJECT {
+			project, err := h.projectsRepo.FindById(ctx, tx, options.ProjectId)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
+				}
+				return fiber.StatusInternalServerError, err
+			}
+
+			// Check if project belongs to current user or one of their organizations
+			if !userData.OwnsProject(project) {
+				return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+			}
+
+			options.PriceLabel = "Prices of project " + project.ProjectName
+		}
+
+		var err error
+		analyses, err = h.builder.Build(ctx, tx, templates, options)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).SendString(fiberErr.Message)
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString("Export failed")
+	}
+
+	// Then, export OUTSIDE of transaction (file is sent directly)
+	if format == "pdf" {
+		data, err := ahsp_analysis.WritePDF(analyses, options)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Export failed")
+		}
+
+		c.Set("Content-Type", "application/pdf")
+		c.Set("Content-Disposition", "attachment; filename="+filename+".pdf")
+		return c.Send(data)
+	}
+
+	data, err := ahsp_analysis.WriteExcel(analyses, options)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Export failed")
+	}
+
+	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Set("Content-Disposition", "attachment; filename="+filename+".xlsx")
+	return c.Send(data)
+}
+
+// findVisibleTemplate loads a template of the user, one of their organizations or a system-wide default
+func (h *AhspAnalysisHandler) findVisibleTemplate(ctx context.Context, tx *sql.Tx, templateIdStr string, userData models.SessionUser) (models.AHSPTemplate, int, error) {
+	templateId, err := strconv.Atoi(templateIdStr)
+	if err != nil {
+		return models.AHSPTemplate{}, fiber.StatusBadRequest, fiber.NewError(fiber.StatusBadRequest, "Invalid template ID")
+	}
+
+	template, err := h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
+	if err != nil {
+		return template, fiber.StatusInternalServerError, err
+	}
+
+	if template.TemplateId == 0 {
+		return template, fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Template not found")
+	}
+
+	if !userData.CanView(template.UserId, template.OrgId) {
+		return template, fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return template, fiber.StatusOK, nil
+}
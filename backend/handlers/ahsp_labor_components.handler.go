@@ -19,17 +19,17 @@ import (
 )
 
 type AhspLaborComponentHandler struct {
-	dbService               databases.SQLiteServices
-	ahspLaborComponentsRepo *ahsp_labor_components.AHSPLaborComponentsRepo
-	ahspTemplatesRepo       *ahsptemplates.AhspTemplatesRepo
-	laborTypesRepo          *master_labor_types.MasterLaborTypesRepo
+	dbService               databases.DatabaseServices
+	ahspLaborComponentsRepo ahsp_labor_components.Repository
+	ahspTemplatesRepo       ahsptemplates.Repository
+	laborTypesRepo          master_labor_types.Repository
 }
 
 func NewAhspLaborComponentHandler(
-	dbService databases.SQLiteServices,
-	ahspLaborComponentsRepo *ahsp_labor_components.AHSPLaborComponentsRepo,
-	ahspTemplatesRepo *ahsptemplates.AhspTemplatesRepo,
-	laborTypesRepo *master_labor_types.MasterLaborTypesRepo,
+	dbService databases.DatabaseServices,
+	ahspLaborComponentsRepo ahsp_labor_components.Repository,
+	ahspTemplatesRepo ahsptemplates.Repository,
+	laborTypesRepo master_labor_types.Repository,
 ) *AhspLaborComponentHandler {
 	return &AhspLaborComponentHandler{
 		dbService:               dbService,
@@ -44,6 +44,8 @@ func NewAhspLaborComponentHandler(
 // ==========================
 
 func (h *AhspLaborComponentHandler) AhspLaborComponentsPage(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	templateIdStr := c.Params("templateId")
 	templateId, err := strconv.Atoi(templateIdStr)
 	if err != nil {
@@ -58,9 +60,9 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentsPage(c *fiber.Ctx) error
 	var availableLaborTypes []models.MasterLaborType
 
 	// Fetch data from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get AHSP template
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, templateId)
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -68,13 +70,13 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentsPage(c *fiber.Ctx) error
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if template belongs to current user or one of their organizations, system-wide templates are read-only for everyone
+		if !userData.CanView(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Get labor components for this template
-		laborComponents, err = h.ahspLaborComponentsRepo.FindByTemplateIdWithLaborInfo(tx, templateId)
+		laborComponents, err = h.ahspLaborComponentsRepo.FindByTemplateIdWithLaborInfo(ctx, tx, templateId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -84,7 +86,7 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentsPage(c *fiber.Ctx) error
 			Page:    1,
 			PerPage: 1000, // Get all labor types
 		}
-		availableLaborTypes, _, err = h.laborTypesRepo.Find(tx, paginationData, userData.ID)
+		availableLaborTypes, _, err = h.laborTypesRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -100,6 +102,8 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentsPage(c *fiber.Ctx) error
 }
 
 func (h *AhspLaborComponentHandler) AhspLaborComponentCreateModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	templateIdStr := c.Params("templateId")
 	templateId, err := strconv.Atoi(templateIdStr)
 	if err != nil {
@@ -113,9 +117,9 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentCreateModalView(c *fiber.C
 	var availableLaborTypes []models.MasterLaborType
 
 	// Fetch data from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get AHSP template
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, templateId)
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -123,8 +127,8 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentCreateModalView(c *fiber.C
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if the current user may change the template, system-wide defaults need an admin
+		if !userData.CanModify(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -133,7 +137,7 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentCreateModalView(c *fiber.C
 			Page:    1,
 			PerPage: 1000, // Get all labor types
 		}
-		availableLaborTypes, _, err = h.laborTypesRepo.Find(tx, paginationData, userData.ID)
+		availableLaborTypes, _, err = h.laborTypesRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -164,6 +168,8 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentCreateModalView(c *fiber.C
 }
 
 func (h *AhspLaborComponentHandler) AhspLaborComponentEditModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	templateIdStr := c.Params("templateId")
 	componentIdStr := c.Params("componentId")
 
@@ -185,9 +191,9 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentEditModalView(c *fiber.Ctx
 	var availableLaborTypes []models.MasterLaborType
 
 	// Fetch data from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get AHSP template
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, templateId)
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -195,13 +201,13 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentEditModalView(c *fiber.Ctx
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if the current user may change the template, system-wide defaults need an admin
+		if !userData.CanModify(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Get the labor component to edit
-		laborComponent, err = h.ahspLaborComponentsRepo.FindById(tx, componentId)
+		laborComponent, err = h.ahspLaborComponentsRepo.FindById(ctx, tx, componentId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Labor component not found")
@@ -219,7 +225,7 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentEditModalView(c *fiber.Ctx
 			Page:    1,
 			PerPage: 1000, // Get all labor types
 		}
-		availableLaborTypes, _, err = h.laborTypesRepo.Find(tx, paginationData, userData.ID)
+		availableLaborTypes, _, err = h.laborTypesRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -244,6 +250,8 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentEditModalView(c *fiber.Ctx
 }
 
 func (h *AhspLaborComponentHandler) AhspLaborComponentDeleteModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	templateIdStr := c.Params("templateId")
 	componentIdStr := c.Params("componentId")
 
@@ -263,9 +271,9 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentDeleteModalView(c *fiber.C
 	var ahspTemplate models.AHSPTemplate
 
 	// Fetch data from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get AHSP template
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, templateId)
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -273,8 +281,8 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentDeleteModalView(c *fiber.C
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if the current user may change the template, system-wide defaults need an admin
+		if !userData.CanModify(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -300,6 +308,8 @@ func (h *AhspLaborComponentHandler) AhspLaborComponentDeleteModalView(c *fiber.C
 
 // CreateAhspLaborComponent handles the creation of a new AHSP labor component
 func (h *AhspLaborComponentHandler) CreateAhspLaborComponent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Add small delay for better UX
 	time.Sleep(500 * time.Millisecond)
 
@@ -343,9 +353,9 @@ func (h *AhspLaborComponentHandler) CreateAhspLaborComponent(c *fiber.Ctx) error
 	}
 
 	// Create AHSP labor component in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		// Check if template belongs to current user
-		template, err := h.ahspTemplatesRepo.FindById(tx, templateId)
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		// Check if template belongs to current user or one of their organizations
+		template, err := h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -353,11 +363,11 @@ func (h *AhspLaborComponentHandler) CreateAhspLaborComponent(c *fiber.Ctx) error
 			return fiber.StatusInternalServerError, err
 		}
 
-		if template.UserId != userData.ID {
+		if !userData.CanModify(template.UserId, template.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
-		if err := h.ahspLaborComponentsRepo.Create(tx, componentData); err != nil {
+		if err := h.ahspLaborComponentsRepo.Create(ctx, tx, componentData); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -371,6 +381,8 @@ func (h *AhspLaborComponentHandler) CreateAhspLaborComponent(c *fiber.Ctx) error
 
 // UpdateAhspLaborComponent handles the update of an existing AHSP labor component
 func (h *AhspLaborComponentHandler) UpdateAhspLaborComponent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Add small delay for better UX
 	time.Sleep(500 * time.Millisecond)
 
@@ -411,9 +423,9 @@ func (h *AhspLaborComponentHandler) UpdateAhspLaborComponent(c *fiber.Ctx) error
 	}
 
 	// Update AHSP labor component in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		// Check if template belongs to current user
-		template, err := h.ahspTemplatesRepo.FindById(tx, templateId)
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		// Check if template belongs to current user or one of their organizations
+		template, err := h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -421,7 +433,7 @@ func (h *AhspLaborComponentHandler) UpdateAhspLaborComponent(c *fiber.Ctx) error
 			return fiber.StatusInternalServerError, err
 		}
 
-		if template.UserId != userData.ID {
+		if !userData.CanModify(template.UserId, template.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -431,7 +443,7 @@ func (h *AhspLaborComponentHandler) UpdateAhspLaborComponent(c *fiber.Ctx) error
 			Coefficient: coefficient,
 		}
 
-		if err := h.ahspLaborComponentsRepo.Update(tx, componentId, componentData); err != nil {
+		if err := h.ahspLaborComponentsRepo.Update(ctx, tx, componentId, componentData); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -445,6 +457,8 @@ func (h *AhspLaborComponentHandler) UpdateAhspLaborComponent(c *fiber.Ctx) error
 
 // DeleteAhspLaborComponent handles the deletion of an AHSP labor component
 func (h *AhspLaborComponentHandler) DeleteAhspLaborComponent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Add small delay for better UX
 	time.Sleep(500 * time.Millisecond)
 
@@ -465,9 +479,9 @@ func (h *AhspLaborComponentHandler) DeleteAhspLaborComponent(c *fiber.Ctx) error
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// Delete AHSP labor component from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		// Check if template belongs to current user
-		template, err := h.ahspTemplatesRepo.FindById(tx, templateId)
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		// Check if template belongs to current user or one of their organizations
+		template, err := h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -475,12 +489,12 @@ func (h *AhspLaborComponentHandler) DeleteAhspLaborComponent(c *fiber.Ctx) error
 			return fiber.StatusInternalServerError, err
 		}
 
-		if template.UserId != userData.ID {
+		if !userData.CanModify(template.UserId, template.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Delete the labor component
-		if err := h.ahspLaborComponentsRepo.Delete(tx, componentId); err != nil {
+		if err := h.ahspLaborComponentsRepo.Delete(ctx, tx, componentId); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/ahsp_library"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+type AhspLibraryHandler struct {
+	dbService databases.DatabaseServices
+	importer  *ahsp_library.Importer
+}
+
+func NewAhspLibraryHandler(
+	dbService databases.DatabaseServices,
+	importer *ahsp_library.Importer,
+) *AhspLibraryHandler {
+	return &AhspLibraryHandler{
+		dbService: dbService,
+		importer:  importer,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+func (h *AhspLibraryHandler) AhspLibraryImportModalView(c *fiber.Ctx) error {
+	library, err := ahsp_library.Load()
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to load standard AHSP library")
+	}
+
+	modal := components.AhspLibraryImportModal(library)
+
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// ImportAhspLibrary imports the bundled standard AHSP library as system-wide defaults
+func (h *AhspLibraryHandler) ImportAhspLibrary(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	library, err := ahsp_library.Load()
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to load standard AHSP library")
+	}
+
+	var result ahsp_library.ImportResult
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		result, err = h.importer.Import(ctx, tx, library)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to import standard AHSP library: "+err.Error())
+	}
+
+	// refresh table data
+	utils.SetRefreshTableTriggerHeader(c)
+
+	message := fmt.Sprintf(
+		"Templates: %d imported, %d already present. Materials: %d created, %d reused. Labor types: %d created, %d reused. New materials and labor types have a price of 0, set your regional prices before using them.",
+		result.TemplatesCreated,
+		result.TemplatesSkipped,
+		result.MaterialsCreated,
+		result.MaterialsMatched,
+		result.LaborTypesCreated,
+		result.LaborTypesMatched,
+	)
+
+	return utils.ResponseSuccessModal(c, "Standard AHSP Library Imported", message, true)
+}
@@ -20,19 +20,19 @@ import (
 )
 
 type AhspMaterialComponentHandler struct {
-	dbService                  databases.SQLiteServices
-	ahspMaterialComponentsRepo *ahsp_material_components.AHSPMaterialComponentsRepo
-	ahspTemplatesRepo          *ahsptemplates.AhspTemplatesRepo
-	materialsRepo              *master_materials.MasterMaterialsRepo
-	ahspLaborComponentsRepo    *ahsp_labor_components.AHSPLaborComponentsRepo
+	dbService                  databases.DatabaseServices
+	ahspMaterialComponentsRepo ahsp_material_components.Repository
+	ahspTemplatesRepo          ahsptemplates.Repository
+	materialsRepo              master_materials.Repository
+	ahspLaborComponentsRepo    ahsp_labor_components.Repository
 }
 
 func NewAhspMaterialComponentHandler(
-	dbService databases.SQLiteServices,
-	ahspMaterialComponentsRepo *ahsp_material_components.AHSPMaterialComponentsRepo,
-	ahspTemplatesRepo *ahsptemplates.AhspTemplatesRepo,
-	materialsRepo *master_materials.MasterMaterialsRepo,
-	ahspLaborComponentsRepo *ahsp_labor_components.AHSPLaborComponentsRepo,
+	dbService databases.DatabaseServices,
+	ahspMaterialComponentsRepo ahsp_material_components.Repository,
+	ahspTemplatesRepo ahsptemplates.Repository,
+	materialsRepo master_materials.Repository,
+	ahspLaborComponentsRepo ahsp_labor_components.Repository,
 
 ) *AhspMaterialComponentHandler {
 	return &AhspMaterialComponentHandler{
@@ -49,6 +49,8 @@ func NewAhspMaterialComponentHandler(
 // ==========================
 
 func (h *AhspMaterialComponentHandler) AhspMaterialComponentsPage(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	templateIdStr := c.Params("templateId")
 	templateId, err := strconv.Atoi(templateIdStr)
 	if err != nil {
@@ -64,9 +66,9 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentsPage(c *fiber.Ctx)
 	var availableMaterials []models.MasterMaterial
 
 	// Fetch data from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get AHSP template
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, templateId)
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -74,19 +76,19 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentsPage(c *fiber.Ctx)
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if template belongs to current user or one of their organizations, system-wide templates are read-only for everyone
+		if !userData.CanView(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Get material components for this template
-		materialComponents, err = h.ahspMaterialComponentsRepo.FindByTemplateIdWithMaterialInfo(tx, templateId)
+		materialComponents, err = h.ahspMaterialComponentsRepo.FindByTemplateIdWithMaterialInfo(ctx, tx, templateId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
 		// get labor componetns
-		laborComponents, err = h.ahspLaborComponentsRepo.FindByTemplateIdWithLaborInfo(tx, templateId)
+		laborComponents, err = h.ahspLaborComponentsRepo.FindByTemplateIdWithLaborInfo(ctx, tx, templateId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -97,7 +99,7 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentsPage(c *fiber.Ctx)
 			PerPage: 1000, // Get all materials
 			Search:  "",
 		}
-		availableMaterials, _, err = h.materialsRepo.Find(tx, paginationData, userData.ID)
+		availableMaterials, _, err = h.materialsRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -122,6 +124,8 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentsPage(c *fiber.Ctx)
 }
 
 func (h *AhspMaterialComponentHandler) AhspMaterialComponentCreateModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	templateIdStr := c.Params("templateId")
 	templateId, err := strconv.Atoi(templateIdStr)
 	if err != nil {
@@ -135,9 +139,9 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentCreateModalView(c *f
 	var availableMaterials []models.MasterMaterial
 
 	// Fetch data from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get AHSP template
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, templateId)
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -145,8 +149,8 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentCreateModalView(c *f
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if the current user may change the template, system-wide defaults need an admin
+		if !userData.CanModify(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -156,7 +160,7 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentCreateModalView(c *f
 			PerPage: 1000, // Get all materials
 			Search:  "",
 		}
-		availableMaterials, _, err = h.materialsRepo.Find(tx, paginationData, userData.ID)
+		availableMaterials, _, err = h.materialsRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -180,6 +184,8 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentCreateModalView(c *f
 }
 
 func (h *AhspMaterialComponentHandler) AhspMaterialComponentEditModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	templateIdStr := c.Params("templateId")
 	componentIdStr := c.Params("componentId")
 
@@ -201,9 +207,9 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentEditModalView(c *fib
 	var availableMaterials []models.MasterMaterial
 
 	// Fetch data from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get AHSP template
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, templateId)
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -211,13 +217,13 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentEditModalView(c *fib
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if the current user may change the template, system-wide defaults need an admin
+		if !userData.CanModify(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Get material component
-		materialComponent, err = h.ahspMaterialComponentsRepo.FindById(tx, componentId)
+		materialComponent, err = h.ahspMaterialComponentsRepo.FindById(ctx, tx, componentId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Material component not found")
@@ -231,7 +237,7 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentEditModalView(c *fib
 			PerPage: 1000, // Get all materials
 			Search:  "",
 		}
-		availableMaterials, _, err = h.materialsRepo.Find(tx, paginationData, userData.ID)
+		availableMaterials, _, err = h.materialsRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -255,6 +261,8 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentEditModalView(c *fib
 }
 
 func (h *AhspMaterialComponentHandler) AhspMaterialComponentDeleteModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	templateIdStr := c.Params("templateId")
 	componentIdStr := c.Params("componentId")
 
@@ -275,9 +283,9 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentDeleteModalView(c *f
 	var materialComponent models.AHSPMaterialComponentWithMaterial
 
 	// Fetch data from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get AHSP template
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, templateId)
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -285,13 +293,13 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentDeleteModalView(c *f
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if the current user may change the template, system-wide defaults need an admin
+		if !userData.CanModify(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Get material component with material info
-		components, err := h.ahspMaterialComponentsRepo.FindByTemplateIdWithMaterialInfo(tx, templateId)
+		components, err := h.ahspMaterialComponentsRepo.FindByTemplateIdWithMaterialInfo(ctx, tx, templateId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -329,6 +337,8 @@ func (h *AhspMaterialComponentHandler) AhspMaterialComponentDeleteModalView(c *f
 
 // CreateAhspMaterialComponent handles the creation of a new AHSP material component
 func (h *AhspMaterialComponentHandler) CreateAhspMaterialComponent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Add small delay for better UX
 	time.Sleep(500 * time.Millisecond)
 
@@ -372,9 +382,9 @@ func (h *AhspMaterialComponentHandler) CreateAhspMaterialComponent(c *fiber.Ctx)
 	}
 
 	// Create AHSP material component in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		// Check if template belongs to current user
-		template, err := h.ahspTemplatesRepo.FindById(tx, templateId)
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		// Check if template belongs to current user or one of their organizations
+		template, err := h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -382,11 +392,11 @@ func (h *AhspMaterialComponentHandler) CreateAhspMaterialComponent(c *fiber.Ctx)
 			return fiber.StatusInternalServerError, err
 		}
 
-		if template.UserId != userData.ID {
+		if !userData.CanModify(template.UserId, template.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
-		if err := h.ahspMaterialComponentsRepo.Create(tx, componentData); err != nil {
+		if err := h.ahspMaterialComponentsRepo.Create(ctx, tx, componentData); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -400,6 +410,8 @@ func (h *AhspMaterialComponentHandler) CreateAhspMaterialComponent(c *fiber.Ctx)
 
 // UpdateAhspMaterialComponent handles the update of an existing AHSP material component
 func (h *AhspMaterialComponentHandler) UpdateAhspMaterialComponent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Add small delay for better UX
 	time.Sleep(500 * time.Millisecond)
 
@@ -440,9 +452,9 @@ func (h *AhspMaterialComponentHandler) UpdateAhspMaterialComponent(c *fiber.Ctx)
 	}
 
 	// Update AHSP material component in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		// Check if template belongs to current user
-		template, err := h.ahspTemplatesRepo.FindById(tx, templateId)
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		// Check if template belongs to current user or one of their organizations
+		template, err := h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -450,7 +462,7 @@ func (h *AhspMaterialComponentHandler) UpdateAhspMaterialComponent(c *fiber.Ctx)
 			return fiber.StatusInternalServerError, err
 		}
 
-		if template.UserId != userData.ID {
+		if !userData.CanModify(template.UserId, template.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -460,7 +472,7 @@ func (h *AhspMaterialComponentHandler) UpdateAhspMaterialComponent(c *fiber.Ctx)
 			Coefficient: coefficient,
 		}
 
-		if err := h.ahspMaterialComponentsRepo.Update(tx, componentId, componentData); err != nil {
+		if err := h.ahspMaterialComponentsRepo.Update(ctx, tx, componentId, componentData); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -474,6 +486,8 @@ func (h *AhspMaterialComponentHandler) UpdateAhspMaterialComponent(c *fiber.Ctx)
 
 // DeleteAhspMaterialComponent handles the deletion of an AHSP material component
 func (h *AhspMaterialComponentHandler) DeleteAhspMaterialComponent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Add small delay for better UX
 	time.Sleep(500 * time.Millisecond)
 
@@ -494,9 +508,9 @@ func (h *AhspMaterialComponentHandler) DeleteAhspMaterialComponent(c *fiber.Ctx)
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// Delete AHSP material component from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		// Check if template belongs to current user
-		template, err := h.ahspTemplatesRepo.FindById(tx, templateId)
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		// Check if template belongs to current user or one of their organizations
+		template, err := h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -504,12 +518,12 @@ func (h *AhspMaterialComponentHandler) DeleteAhspMaterialComponent(c *fiber.Ctx)
 			return fiber.StatusInternalServerError, err
 		}
 
-		if template.UserId != userData.ID {
+		if !userData.CanModify(template.UserId, template.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Delete the material component
-		if err := h.ahspMaterialComponentsRepo.Delete(tx, componentId); err != nil {
+		if err := h.ahspMaterialComponentsRepo.Delete(ctx, tx, componentId); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
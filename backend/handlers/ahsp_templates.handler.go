@@ -2,9 +2,9 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/a-h/templ"
 	"github.com/gofiber/fiber/v2"
@@ -18,13 +18,13 @@ import (
 )
 
 type AhspTemplateHandler struct {
-	dbService         databases.SQLiteServices
-	ahspTemplatesRepo *ahsptemplates.AhspTemplatesRepo
+	dbService         databases.DatabaseServices
+	ahspTemplatesRepo ahsptemplates.Repository
 }
 
 func NewAhspTemplateHandler(
-	dbService databases.SQLiteServices,
-	ahspTemplatesRepo *ahsptemplates.AhspTemplatesRepo,
+	dbService databases.DatabaseServices,
+	ahspTemplatesRepo ahsptemplates.Repository,
 ) *AhspTemplateHandler {
 	return &AhspTemplateHandler{
 		dbService:         dbService,
@@ -37,6 +37,8 @@ func NewAhspTemplateHandler(
 // ==========================
 
 func (h *AhspTemplateHandler) AhspTemplatesMainPageTableView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	var ahspTemplates []models.AHSPTemplate
 	var paginationInfo models.PaginationInfo
 
@@ -54,12 +56,12 @@ func (h *AhspTemplateHandler) AhspTemplatesMainPageTableView(c *fiber.Ctx) error
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// start transaction to get the data
-	if _, err := h.dbService.Transaction(
-		c.Context(),
+	if _, err := h.dbService.ReadTransaction(
+		ctx,
 		func(tx *sql.Tx) (int, error) {
 			// get the AHSP template data
 			ahspTemplatesData, paginationData, err := h.ahspTemplatesRepo.Find(
-				tx, paginationData, userData.ID,
+				ctx, tx, paginationData, userData.Workspace(),
 			)
 			if err != nil {
 				return fiber.StatusInternalServerError, err
@@ -114,6 +116,8 @@ func (h *AhspTemplateHandler) AhspTemplateCreateModalView(c *fiber.Ctx) error {
 }
 
 func (h *AhspTemplateHandler) AhspTemplateEditModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	ahspTemplateIdStr := c.Params("id")
 	ahspTemplateId, err := strconv.Atoi(ahspTemplateIdStr)
 	if err != nil {
@@ -126,8 +130,8 @@ func (h *AhspTemplateHandler) AhspTemplateEditModalView(c *fiber.Ctx) error {
 	var ahspTemplate models.AHSPTemplate
 
 	// Fetch AHSP template from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, ahspTemplateId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, ahspTemplateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -135,8 +139,8 @@ func (h *AhspTemplateHandler) AhspTemplateEditModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if AHSP template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if the current user may change the AHSP template, system-wide defaults need an admin
+		if !userData.CanModify(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -157,6 +161,8 @@ func (h *AhspTemplateHandler) AhspTemplateEditModalView(c *fiber.Ctx) error {
 }
 
 func (h *AhspTemplateHandler) AhspTemplateDeleteModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	ahspTemplateIdStr := c.Params("id")
 	ahspTemplateId, err := strconv.Atoi(ahspTemplateIdStr)
 	if err != nil {
@@ -169,8 +175,8 @@ func (h *AhspTemplateHandler) AhspTemplateDeleteModalView(c *fiber.Ctx) error {
 	var ahspTemplate models.AHSPTemplate
 
 	// Fetch AHSP template from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		ahspTemplate, err = h.ahspTemplatesRepo.FindById(tx, ahspTemplateId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		ahspTemplate, err = h.ahspTemplatesRepo.FindById(ctx, tx, ahspTemplateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -178,8 +184,8 @@ func (h *AhspTemplateHandler) AhspTemplateDeleteModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if AHSP template belongs to current user
-		if ahspTemplate.UserId != userData.ID {
+		// Check if the current user may change the AHSP template, system-wide defaults need an admin
+		if !userData.CanModify(ahspTemplate.UserId, ahspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -204,11 +210,14 @@ func (h *AhspTemplateHandler) AhspTemplateDeleteModalView(c *fiber.Ctx) error {
 
 // CreateAhspTemplate handles the creation of a new AHSP template
 func (h *AhspTemplateHandler) CreateAhspTemplate(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Get user from session (using the same approach as in auth.handler.go)
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// Extract form data
 	templateName := c.FormValue("template_name")
+	code := strings.TrimSpace(c.FormValue("code"))
 	unit := c.FormValue("unit")
 
 	// Validate input
@@ -222,13 +231,15 @@ func (h *AhspTemplateHandler) CreateAhspTemplate(c *fiber.Ctx) error {
 	// Create AHSP template data
 	ahspTemplateData := models.AHSPTemplateCreate{
 		TemplateName: templateName,
+		Code:         code,
 		Unit:         unit,
-		UserId:       userData.ID,
+		UserId:       userData.Workspace().OwnerUserId(),
+		OrgId:        userData.OrgId,
 	}
 
 	// Create AHSP template in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		if err := h.ahspTemplatesRepo.Create(tx, ahspTemplateData); err != nil {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if err := h.ahspTemplatesRepo.Create(ctx, tx, ahspTemplateData); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -245,6 +256,8 @@ func (h *AhspTemplateHandler) CreateAhspTemplate(c *fiber.Ctx) error {
 
 // UpdateAhspTemplate handles the update of an existing AHSP template
 func (h *AhspTemplateHandler) UpdateAhspTemplate(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	ahspTemplateIdStr := c.Params("id")
 	ahspTemplateId, err := strconv.Atoi(ahspTemplateIdStr)
 	if err != nil {
@@ -256,6 +269,7 @@ func (h *AhspTemplateHandler) UpdateAhspTemplate(c *fiber.Ctx) error {
 
 	// Extract form data
 	templateName := c.FormValue("template_name")
+	code := strings.TrimSpace(c.FormValue("code"))
 	unit := c.FormValue("unit")
 
 	// Validate input
@@ -267,9 +281,9 @@ func (h *AhspTemplateHandler) UpdateAhspTemplate(c *fiber.Ctx) error {
 	}
 
 	// Update AHSP template in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing AHSP template to ensure it belongs to the user
-		existingAhspTemplate, err := h.ahspTemplatesRepo.FindById(tx, ahspTemplateId)
+		existingAhspTemplate, err := h.ahspTemplatesRepo.FindById(ctx, tx, ahspTemplateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -277,22 +291,24 @@ func (h *AhspTemplateHandler) UpdateAhspTemplate(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if AHSP template belongs to current user
-		if existingAhspTemplate.UserId != userData.ID {
+		// Check if the current user may change the AHSP template, system-wide defaults need an admin
+		if !userData.CanModify(existingAhspTemplate.UserId, existingAhspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Update the AHSP template
 		updatedAhspTemplate := models.AHSPTemplate{
 			TemplateId:   ahspTemplateId,
-			UserId:       userData.ID,
+			UserId:       existingAhspTemplate.UserId,
+			OrgId:        existingAhspTemplate.OrgId,
+			Code:         code,
 			TemplateName: templateName,
 			Unit:         unit,
 			CreatedAt:    existingAhspTemplate.CreatedAt,
-			UpdatedAt:    time.Now().Format("2006-01-02 15:04:05"),
+			UpdatedAt:    models.UTCNow(),
 		}
 
-		if err := h.ahspTemplatesRepo.Update(tx, updatedAhspTemplate); err != nil {
+		if err := h.ahspTemplatesRepo.Update(ctx, tx, updatedAhspTemplate); err != nil {
 			return fiber.StatusInternalServerError, fiber.NewError(fiber.StatusInternalServerError, "Make sure template name is unique")
 		}
 		return fiber.StatusOK, nil
@@ -309,6 +325,8 @@ func (h *AhspTemplateHandler) UpdateAhspTemplate(c *fiber.Ctx) error {
 
 // DeleteAhspTemplate handles the deletion of an AHSP template
 func (h *AhspTemplateHandler) DeleteAhspTemplate(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	ahspTemplateIdStr := c.Params("id")
 	ahspTemplateId, err := strconv.Atoi(ahspTemplateIdStr)
 	if err != nil {
@@ -319,9 +337,9 @@ func (h *AhspTemplateHandler) DeleteAhspTemplate(c *fiber.Ctx) error {
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// Delete AHSP template from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing AHSP template to ensure it belongs to the user
-		existingAhspTemplate, err := h.ahspTemplatesRepo.FindById(tx, ahspTemplateId)
+		existingAhspTemplate, err := h.ahspTemplatesRepo.FindById(ctx, tx, ahspTemplateId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
@@ -329,13 +347,13 @@ func (h *AhspTemplateHandler) DeleteAhspTemplate(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if AHSP template belongs to current user
-		if existingAhspTemplate.UserId != userData.ID {
+		// Check if the current user may change the AHSP template, system-wide defaults need an admin
+		if !userData.CanModify(existingAhspTemplate.UserId, existingAhspTemplate.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Delete the AHSP template
-		if err := h.ahspTemplatesRepo.Delete(tx, existingAhspTemplate); err != nil {
+		if err := h.ahspTemplatesRepo.Delete(ctx, tx, existingAhspTemplate); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -344,7 +362,7 @@ func (h *AhspTemplateHandler) DeleteAhspTemplate(c *fiber.Ctx) error {
 		errLower := strings.ToLower(err.Error())
 		if strings.Contains(errLower, "foreign key") ||
 			strings.Contains(errLower, "constraint") ||
-			err == sql.ErrTxDone {
+			errors.Is(err, ahsptemplates.ErrTemplateInUse) {
 			return utils.ResponseErrorModal(c, "Cannot Delete",
 				"Cannot delete this AHSP template because it is used in work items")
 		}
@@ -355,5 +373,5 @@ func (h *AhspTemplateHandler) DeleteAhspTemplate(c *fiber.Ctx) error {
 	utils.SetRefreshTableTriggerHeader(c)
 
 	// Return success response with refresh
-	return utils.ResponseSuccessModal(c, "Success", "AHSP template deleted successfully", true)
+	return utils.ResponseSuccessModal(c, "Success", "AHSP template moved to the trash", true)
 }
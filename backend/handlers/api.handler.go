@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+const (
+	API_V1_PATH = "/api/v1"
+
+	API_DEFAULT_PER_PAGE = 10
+	API_MAX_PER_PAGE     = 100
+)
+
+// APIRoute is one endpoint of the JSON API, Path is relative to API_V1_PATH. Request and
+// Response are zero values of the JSON bodies it reads and answers with, they describe the
+// route in the OpenAPI document. A nil Response means 204 No Content, apiPage a paginated list.
+type APIRoute struct {
+	Method   string
+	Path     string
+	Summary  string
+	Handler  fiber.Handler
+	Request  interface{}
+	Response interface{}
+}
+
+// APIHandlers holds the handlers of the JSON API
+type APIHandlers struct {
+	Projects        *ProjectsAPIHandler
+	WorkItems       *WorkItemsAPIHandler
+	Materials       *MaterialsAPIHandler
+	LaborTypes      *LaborTypesAPIHandler
+	WorkCategories  *WorkCategoriesAPIHandler
+	AhspTemplates   *AhspTemplatesAPIHandler
+	MaterialSummary *MaterialSummaryAPIHandler
+	Dashboard       *DashboardAPIHandler
+}
+
+// Routes lists every endpoint of the JSON API, main registers them under API_V1_PATH
+func (h APIHandlers) Routes() []APIRoute {
+	return []APIRoute{
+		// projects
+		{fiber.MethodGet, "/projects", "List projects", h.Projects.ListProjects, nil, apiPage[models.Project]{}},
+		{fiber.MethodPost, "/projects", "Create a project", h.Projects.CreateProject, models.ProjectCreate{}, models.Project{}},
+		{fiber.MethodGet, "/projects/:id", "Get a project", h.Projects.GetProject, nil, models.Project{}},
+		{fiber.MethodPut, "/projects/:id", "Update a project", h.Projects.UpdateProject, models.ProjectCreate{}, models.Project{}},
+		{fiber.MethodDelete, "/projects/:id", "Delete a project and its work items", h.Projects.DeleteProject, nil, nil},
+
+		// project work items and their costs
+		{fiber.MethodGet, "/projects/:id/work-items", "List the work items of a project", h.WorkItems.ListWorkItems, nil, apiPage[models.ProjectWorkItemWithDetails]{}},
+		{fiber.MethodPost, "/projects/:id/work-items", "Create a work item and its costs", h.WorkItems.CreateWorkItem, models.ProjectWorkItemInput{}, models.ProjectWorkItemWithCosts{}},
+		{fiber.MethodGet, "/projects/:id/work-items/:workItemId", "Get a work item with its costs", h.WorkItems.GetWorkItem, nil, models.ProjectWorkItemWithCosts{}},
+		{fiber.MethodPut, "/projects/:id/work-items/:workItemId", "Update a work item and recalculate its costs", h.WorkItems.UpdateWorkItem, models.ProjectWorkItemInput{}, models.ProjectWorkItemWithCosts{}},
+		{fiber.MethodDelete, "/projects/:id/work-items/:workItemId", "Delete a work item", h.WorkItems.DeleteWorkItem, nil, nil},
+		{fiber.MethodGet, "/projects/:id/item-costs", "List the item costs of a project", h.WorkItems.ListProjectItemCosts, nil, apiPage[models.ProjectItemCostWithDetails]{}},
+		{fiber.MethodGet, "/projects/:id/material-summary", "Get the material summary of a project", h.MaterialSummary.GetProjectMaterialSummary, nil, []models.DetailedMaterialSummary{}},
+
+		// materials
+		{fiber.MethodGet, "/materials", "List materials", h.Materials.ListMaterials, nil, apiPage[models.MasterMaterial]{}},
+		{fiber.MethodPost, "/materials", "Create a material", h.Materials.CreateMaterial, models.MasterMaterialCreate{}, models.MasterMaterial{}},
+		{fiber.MethodGet, "/materials/:id", "Get a material", h.Materials.GetMaterial, nil, models.MasterMaterial{}},
+		{fiber.MethodPut, "/materials/:id", "Update a material", h.Materials.UpdateMaterial, models.MasterMaterialCreate{}, models.MasterMaterial{}},
+		{fiber.MethodDelete, "/materials/:id", "Delete a material", h.Materials.DeleteMaterial, nil, nil},
+
+		// labor types
+		{fiber.MethodGet, "/labor-types", "List labor types", h.LaborTypes.ListLaborTypes, nil, apiPage[models.MasterLaborType]{}},
+		{fiber.MethodPost, "/labor-types", "Create a labor type", h.LaborTypes.CreateLaborType, models.MasterLaborTypeCreate{}, models.MasterLaborType{}},
+		{fiber.MethodGet, "/labor-types/:id", "Get a labor type", h.LaborTypes.GetLaborType, nil, models.MasterLaborType{}},
+		{fiber.MethodPut, "/labor-types/:id", "Update a labor type", h.LaborTypes.UpdateLaborType, models.MasterLaborTypeCreate{}, models.MasterLaborType{}},
+		{fiber.MethodDelete, "/labor-types/:id", "Delete a labor type", h.LaborTypes.DeleteLaborType, nil, nil},
+
+		// work categories
+		{fiber.MethodGet, "/work-categories", "List work categories", h.WorkCategories.ListWorkCategories, nil, apiPage[models.MasterWorkCategory]{}},
+		{fiber.MethodPost, "/work-categories", "Create a work category", h.WorkCategories.CreateWorkCategory, models.MasterWorkCategoryCreate{}, models.MasterWorkCategory{}},
+		{fiber.MethodGet, "/work-categories/:id", "Get a work category", h.WorkCategories.GetWorkCategory, nil, models.MasterWorkCategory{}},
+		{fiber.MethodPut, "/work-categories/:id", "Update a work category", h.WorkCategories.UpdateWorkCategory, models.MasterWorkCategoryCreate{}, models.MasterWorkCategory{}},
+		{fiber.MethodDelete, "/work-categories/:id", "Delete a work category", h.WorkCategories.DeleteWorkCategory, nil, nil},
+
+		// AHSP templates and their components
+		{fiber.MethodGet, "/ahsp-templates", "List AHSP templates", h.AhspTemplates.ListTemplates, nil, apiPage[models.AHSPTemplate]{}},
+		{fiber.MethodPost, "/ahsp-templates", "Create an AHSP template", h.AhspTemplates.CreateTemplate, models.AHSPTemplateCreate{}, models.AHSPTemplateWithComponents{}},
+		{fiber.MethodGet, "/ahsp-templates/:id", "Get an AHSP template with its components", h.AhspTemplates.GetTemplate, nil, models.AHSPTemplateWithComponents{}},
+		{fiber.MethodPut, "/ahsp-templates/:id", "Update an AHSP template", h.AhspTemplates.UpdateTemplate, models.AHSPTemplateCreate{}, models.AHSPTemplateWithComponents{}},
+		{fiber.MethodDelete, "/ahsp-templates/:id", "Delete an AHSP template", h.AhspTemplates.DeleteTemplate, nil, nil},
+		{fiber.MethodPost, "/ahsp-templates/:id/material-components", "Add a material component", h.AhspTemplates.CreateMaterialComponent, models.AHSPMaterialComponentUpdate{}, models.AHSPTemplateWithComponents{}},
+		{fiber.MethodPut, "/ahsp-templates/:id/material-components/:componentId", "Update a material component", h.AhspTemplates.UpdateMaterialComponent, models.AHSPMaterialComponentUpdate{}, models.AHSPTemplateWithComponents{}},
+		{fiber.MethodDelete, "/ahsp-templates/:id/material-components/:componentId", "Delete a material component", h.AhspTemplates.DeleteMaterialComponent, nil, models.AHSPTemplateWithComponents{}},
+		{fiber.MethodPost, "/ahsp-templates/:id/labor-components", "Add a labor component", h.AhspTemplates.CreateLaborComponent, models.AHSPLaborComponentUpdate{}, models.AHSPTemplateWithComponents{}},
+		{fiber.MethodPut, "/ahsp-templates/:id/labor-components/:componentId", "Update a labor component", h.AhspTemplates.UpdateLaborComponent, models.AHSPLaborComponentUpdate{}, models.AHSPTemplateWithComponents{}},
+		{fiber.MethodDelete, "/ahsp-templates/:id/labor-components/:componentId", "Delete a labor component", h.AhspTemplates.DeleteLaborComponent, nil, models.AHSPTemplateWithComponents{}},
+
+		// reports
+		{fiber.MethodGet, "/material-summary", "Get the material summary of all projects", h.MaterialSummary.GetMaterialSummary, nil, models.MaterialSummaryOverview{}},
+		{fiber.MethodGet, "/dashboard", "Get the dashboard statistics", h.Dashboard.GetDashboard, nil, models.DashboardOverview{}},
+	}
+}
+
+// apiPaginationData reads page, per_page and search like the tables do, per_page is capped at API_MAX_PER_PAGE
+func apiPaginationData(c *fiber.Ctx) models.TablePaginationDataInput {
+	paginationData, _ := utils.GetPaginationData(c)
+
+	if paginationData.Page < 1 {
+		paginationData.Page = 1
+	}
+	if paginationData.PerPage < 1 {
+		paginationData.PerPage = API_DEFAULT_PER_PAGE
+	}
+	if paginationData.PerPage > API_MAX_PER_PAGE {
+		paginationData.PerPage = API_MAX_PER_PAGE
+	}
+
+	return paginationData
+}
+
+// paginateSlice pages a list that the repository returns whole
+func paginateSlice[T any](items []T, paginationData models.TablePaginationDataInput) ([]T, models.PaginationInfo) {
+	paginationInfo := models.PaginationInfo{
+		CurrentPage:  paginationData.Page,
+		TotalPages:   (len(items) + paginationData.PerPage - 1) / paginationData.PerPage,
+		TotalItems:   len(items),
+		ItemsPerPage: paginationData.PerPage,
+	}
+
+	start := (paginationData.Page - 1) * paginationData.PerPage
+	if start >= len(items) {
+		return []T{}, paginationInfo
+	}
+
+	end := start + paginationData.PerPage
+	if end > len(items) {
+		end = len(items)
+	}
+
+	return items[start:end], paginationInfo
+}
+
+// apiParamId reads a numeric path parameter, name is used in the error message
+func apiParamId(c *fiber.Ctx, param, name string) (int, error) {
+	id, err := strconv.Atoi(c.Params(param))
+	if err != nil || id < 1 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Invalid "+name+" ID")
+	}
+
+	return id, nil
+}
+
+// apiBody parses the JSON body into input and validates it with its validate tags
+func apiBody(c *fiber.Ctx, input interface{}) error {
+	// only JSON is accepted, a form posted from another site must not reach the session routes
+	if !c.Is("json") {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "The request body must be JSON")
+	}
+
+	if err := c.BodyParser(input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "The request body is not valid JSON")
+	}
+
+	return utils.ValidateStruct(input)
+}
+
+// apiError answers with the problem for err. Errors made with fiber.NewError keep their status
+// and message, validation errors list the invalid fields, constraint violations become 409 and
+// anything else is logged and reported as failedMessage.
+func apiError(c *fiber.Ctx, err error, failedMessage string) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return utils.ResponseProblem(c, fiberErr.Code, fiberErr.Message)
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return utils.ResponseValidationProblem(c, validationErrors)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return utils.ResponseProblem(c, fiber.StatusServiceUnavailable, "The request took too long, please try again")
+	}
+
+	if isConstraintError(err) {
+		return utils.ResponseProblem(c, fiber.StatusConflict, failedMessage+", it conflicts with existing data")
+	}
+
+	log.Printf("API %s %s: %v", c.Method(), c.Path(), err)
+	return utils.ResponseProblem(c, fiber.StatusInternalServerError, failedMessage)
+}
+
+// isConstraintError reports unique and foreign key violations of both SQLite and PostgreSQL
+func isConstraintError(err error) bool {
+	errLower := strings.ToLower(err.Error())
+	return strings.Contains(errLower, "constraint") || strings.Contains(errLower, "foreign key")
+}
+
+func apiData(c *fiber.Ctx, status int, data interface{}) error {
+	return c.Status(status).JSON(models.APIResponse{Data: data})
+}
+
+// apiList sends a page of data, an empty page is sent as [] rather than null
+func apiList(c *fiber.Ctx, data interface{}, paginationInfo models.PaginationInfo) error {
+	if value := reflect.ValueOf(data); value.Kind() == reflect.Slice && value.IsNil() {
+		data = []interface{}{}
+	}
+
+	return c.JSON(models.APIListResponse{Data: data, Pagination: paginationInfo})
+}
@@ -0,0 +1,551 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_labor_components"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_material_components"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+)
+
+type AhspTemplatesAPIHandler struct {
+	dbService                  databases.DatabaseServices
+	ahspTemplatesRepo          ahsptemplates.Repository
+	ahspMaterialComponentsRepo ahsp_material_components.Repository
+	ahspLaborComponentsRepo    ahsp_labor_components.Repository
+	materialsRepo              master_materials.Repository
+	laborTypesRepo             master_labor_types.Repository
+}
+
+func NewAhspTemplatesAPIHandler(
+	dbService databases.DatabaseServices,
+	ahspTemplatesRepo ahsptemplates.Repository,
+	ahspMaterialComponentsRepo ahsp_material_components.Repository,
+	ahspLaborComponentsRepo ahsp_labor_components.Repository,
+	materialsRepo master_materials.Repository,
+	laborTypesRepo master_labor_types.Repository,
+) *AhspTemplatesAPIHandler {
+	return &AhspTemplatesAPIHandler{
+		dbService:                  dbService,
+		ahspTemplatesRepo:          ahspTemplatesRepo,
+		ahspMaterialComponentsRepo: ahspMaterialComponentsRepo,
+		ahspLaborComponentsRepo:    ahspLaborComponentsRepo,
+		materialsRepo:              materialsRepo,
+		laborTypesRepo:             laborTypesRepo,
+	}
+}
+
+// ==========================
+// ========================== TEMPLATES
+// ==========================
+
+// ListTemplates returns a page of the user's AHSP templates and the system-wide defaults, without components
+func (h *AhspTemplatesAPIHandler) ListTemplates(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+	paginationData := apiPaginationData(c)
+
+	var templates []models.AHSPTemplate
+	var paginationInfo models.PaginationInfo
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		templates, paginationInfo, err = h.ahspTemplatesRepo.Find(ctx, tx, paginationData, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch AHSP templates")
+	}
+
+	return apiList(c, templates, paginationInfo)
+}
+
+// GetTemplate returns the template with its material and labor components
+func (h *AhspTemplatesAPIHandler) GetTemplate(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	templateId, err := apiParamId(c, "id", "AHSP template")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var template models.AHSPTemplateWithComponents
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		templateData, err := h.findTemplate(ctx, tx, templateId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		// system-wide defaults can be read by everyone
+		if !userData.CanView(templateData.UserId, templateData.OrgId) {
+			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		}
+
+		template, err = h.withComponents(ctx, tx, templateData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch AHSP template")
+	}
+
+	return apiData(c, fiber.StatusOK, template)
+}
+
+func (h *AhspTemplatesAPIHandler) CreateTemplate(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var templateData models.AHSPTemplateCreate
+	if err := apiBody(c, &templateData); err != nil {
+		return apiError(c, err, "")
+	}
+	templateData.TemplateName = strings.TrimSpace(templateData.TemplateName)
+	templateData.Code = strings.TrimSpace(templateData.Code)
+	templateData.Unit = strings.TrimSpace(templateData.Unit)
+	templateData.UserId = userData.Workspace().OwnerUserId()
+	templateData.OrgId = userData.OrgId
+
+	var template models.AHSPTemplateWithComponents
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if err := h.ahspTemplatesRepo.Create(ctx, tx, templateData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		// the repository does not return the new ID, the user's own template is found first
+		created, err := h.ahspTemplatesRepo.FindByNameAndUnit(ctx, tx, templateData.TemplateName, templateData.Unit, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		template, err = h.withComponents(ctx, tx, created)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to create AHSP template, make sure the template name is unique")
+	}
+
+	return apiData(c, fiber.StatusCreated, template)
+}
+
+func (h *AhspTemplatesAPIHandler) UpdateTemplate(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	templateId, err := apiParamId(c, "id", "AHSP template")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var templateData models.AHSPTemplateCreate
+	if err := apiBody(c, &templateData); err != nil {
+		return apiError(c, err, "")
+	}
+
+	var template models.AHSPTemplateWithComponents
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingTemplate, err := h.findOwnTemplate(ctx, tx, templateId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		updatedTemplate := models.AHSPTemplate{
+			TemplateId:   templateId,
+			UserId:       existingTemplate.UserId,
+			OrgId:        existingTemplate.OrgId,
+			Code:         strings.TrimSpace(templateData.Code),
+			TemplateName: strings.TrimSpace(templateData.TemplateName),
+			Unit:         strings.TrimSpace(templateData.Unit),
+			CreatedAt:    existingTemplate.CreatedAt,
+			UpdatedAt:    models.UTCNow(),
+		}
+
+		if err := h.ahspTemplatesRepo.Update(ctx, tx, updatedTemplate); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		template, err = h.withComponents(ctx, tx, updatedTemplate)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to update AHSP template, make sure the template name is unique")
+	}
+
+	return apiData(c, fiber.StatusOK, template)
+}
+
+func (h *AhspTemplatesAPIHandler) DeleteTemplate(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	templateId, err := apiParamId(c, "id", "AHSP template")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingTemplate, err := h.findOwnTemplate(ctx, tx, templateId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.ahspTemplatesRepo.Delete(ctx, tx, existingTemplate); err != nil {
+			if errors.Is(err, ahsptemplates.ErrTemplateInUse) || isConstraintError(err) {
+				return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "Cannot delete this AHSP template because it is used in work items")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to delete AHSP template")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ==========================
+// ========================== COMPONENTS
+// ==========================
+
+// CreateMaterialComponent adds a material to the template and returns the template with its components
+func (h *AhspTemplatesAPIHandler) CreateMaterialComponent(c *fiber.Ctx) error {
+	templateId, err := apiParamId(c, "id", "AHSP template")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var componentData models.AHSPMaterialComponentUpdate
+	if err := apiBody(c, &componentData); err != nil {
+		return apiError(c, err, "")
+	}
+
+	return h.changeComponents(c, templateId, userData, fiber.StatusCreated, "Failed to create material component",
+		func(ctx context.Context, tx *sql.Tx) error {
+			if err := h.checkMaterial(ctx, tx, componentData.MaterialId, userData); err != nil {
+				return err
+			}
+
+			return h.ahspMaterialComponentsRepo.Create(ctx, tx, models.AHSPMaterialComponentCreate{
+				TemplateId:  templateId,
+				MaterialId:  componentData.MaterialId,
+				Coefficient: componentData.Coefficient,
+			})
+		},
+	)
+}
+
+func (h *AhspTemplatesAPIHandler) UpdateMaterialComponent(c *fiber.Ctx) error {
+	templateId, componentId, err := apiComponentParams(c)
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var componentData models.AHSPMaterialComponentUpdate
+	if err := apiBody(c, &componentData); err != nil {
+		return apiError(c, err, "")
+	}
+
+	return h.changeComponents(c, templateId, userData, fiber.StatusOK, "Failed to update material component",
+		func(ctx context.Context, tx *sql.Tx) error {
+			if err := h.findMaterialComponent(ctx, tx, templateId, componentId); err != nil {
+				return err
+			}
+
+			if err := h.checkMaterial(ctx, tx, componentData.MaterialId, userData); err != nil {
+				return err
+			}
+
+			return h.ahspMaterialComponentsRepo.Update(ctx, tx, componentId, componentData)
+		},
+	)
+}
+
+func (h *AhspTemplatesAPIHandler) DeleteMaterialComponent(c *fiber.Ctx) error {
+	templateId, componentId, err := apiComponentParams(c)
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	return h.changeComponents(c, templateId, userData, fiber.StatusOK, "Failed to delete material component",
+		func(ctx context.Context, tx *sql.Tx) error {
+			if err := h.findMaterialComponent(ctx, tx, templateId, componentId); err != nil {
+				return err
+			}
+
+			return h.ahspMaterialComponentsRepo.Delete(ctx, tx, componentId)
+		},
+	)
+}
+
+// CreateLaborComponent adds a labor type to the template and returns the template with its components
+func (h *AhspTemplatesAPIHandler) CreateLaborComponent(c *fiber.Ctx) error {
+	templateId, err := apiParamId(c, "id", "AHSP template")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var componentData models.AHSPLaborComponentUpdate
+	if err := apiBody(c, &componentData); err != nil {
+		return apiError(c, err, "")
+	}
+
+	return h.changeComponents(c, templateId, userData, fiber.StatusCreated, "Failed to create labor component",
+		func(ctx context.Context, tx *sql.Tx) error {
+			if err := h.checkLaborType(ctx, tx, componentData.LaborTypeId, userData); err != nil {
+				return err
+			}
+
+			return h.ahspLaborComponentsRepo.Create(ctx, tx, models.AHSPLaborComponentCreate{
+				TemplateId:  templateId,
+				LaborTypeId: componentData.LaborTypeId,
+				Coefficient: componentData.Coefficient,
+			})
+		},
+	)
+}
+
+func (h *AhspTemplatesAPIHandler) UpdateLaborComponent(c *fiber.Ctx) error {
+	templateId, componentId, err := apiComponentParams(c)
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var componentData models.AHSPLaborComponentUpdate
+	if err := apiBody(c, &componentData); err != nil {
+		return apiError(c, err, "")
+	}
+
+	return h.changeComponents(c, templateId, userData, fiber.StatusOK, "Failed to update labor component",
+		func(ctx context.Context, tx *sql.Tx) error {
+			if err := h.findLaborComponent(ctx, tx, templateId, componentId); err != nil {
+				return err
+			}
+
+			if err := h.checkLaborType(ctx, tx, componentData.LaborTypeId, userData); err != nil {
+				return err
+			}
+
+			return h.ahspLaborComponentsRepo.Update(ctx, tx, componentId, componentData)
+		},
+	)
+}
+
+func (h *AhspTemplatesAPIHandler) DeleteLaborComponent(c *fiber.Ctx) error {
+	templateId, componentId, err := apiComponentParams(c)
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	return h.changeComponents(c, templateId, userData, fiber.StatusOK, "Failed to delete labor component",
+		func(ctx context.Context, tx *sql.Tx) error {
+			if err := h.findLaborComponent(ctx, tx, templateId, componentId); err != nil {
+				return err
+			}
+
+			return h.ahspLaborComponentsRepo.Delete(ctx, tx, componentId)
+		},
+	)
+}
+
+// changeComponents runs change on a template the user may change and answers with the template and
+// its components as they are afterwards. The component repositories do not return new IDs,
+// so the whole template is the response of every component change.
+func (h *AhspTemplatesAPIHandler) changeComponents(c *fiber.Ctx, templateId int, userData models.SessionUser, status int, failedMessage string, change func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx := c.UserContext()
+
+	var template models.AHSPTemplateWithComponents
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		templateData, err := h.findOwnTemplate(ctx, tx, templateId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := change(ctx, tx); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		template, err = h.withComponents(ctx, tx, templateData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, failedMessage)
+	}
+
+	return apiData(c, status, template)
+}
+
+// apiComponentParams reads the template and component IDs of the component routes
+func apiComponentParams(c *fiber.Ctx) (int, int, error) {
+	templateId, err := apiParamId(c, "id", "AHSP template")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	componentId, err := apiParamId(c, "componentId", "component")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return templateId, componentId, nil
+}
+
+func (h *AhspTemplatesAPIHandler) findTemplate(ctx context.Context, tx *sql.Tx, templateId int) (models.AHSPTemplate, error) {
+	template, err := h.ahspTemplatesRepo.FindById(ctx, tx, templateId)
+	if err == sql.ErrNoRows {
+		return template, fiber.NewError(fiber.StatusNotFound, "AHSP template not found")
+	}
+
+	return template, err
+}
+
+// findOwnTemplate returns the template when the user may change it, system-wide defaults only for admins
+func (h *AhspTemplatesAPIHandler) findOwnTemplate(ctx context.Context, tx *sql.Tx, templateId int, userData models.SessionUser) (models.AHSPTemplate, error) {
+	template, err := h.findTemplate(ctx, tx, templateId)
+	if err != nil {
+		return template, err
+	}
+
+	if !userData.CanModify(template.UserId, template.OrgId) {
+		return template, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return template, nil
+}
+
+func (h *AhspTemplatesAPIHandler) withComponents(ctx context.Context, tx *sql.Tx, template models.AHSPTemplate) (models.AHSPTemplateWithComponents, error) {
+	materialComponents, err := h.ahspMaterialComponentsRepo.FindByTemplateIdWithMaterialInfo(ctx, tx, template.TemplateId)
+	if err != nil {
+		return models.AHSPTemplateWithComponents{}, err
+	}
+	if materialComponents == nil {
+		materialComponents = []models.AHSPMaterialComponentWithMaterial{}
+	}
+
+	laborComponents, err := h.ahspLaborComponentsRepo.FindByTemplateIdWithLaborInfo(ctx, tx, template.TemplateId)
+	if err != nil {
+		return models.AHSPTemplateWithComponents{}, err
+	}
+	if laborComponents == nil {
+		laborComponents = []models.AHSPLaborComponentWithLabor{}
+	}
+
+	return models.AHSPTemplateWithComponents{
+		AHSPTemplate:       template,
+		MaterialComponents: materialComponents,
+		LaborComponents:    laborComponents,
+	}, nil
+}
+
+// findMaterialComponent makes sure the component belongs to the template
+func (h *AhspTemplatesAPIHandler) findMaterialComponent(ctx context.Context, tx *sql.Tx, templateId, componentId int) error {
+	component, err := h.ahspMaterialComponentsRepo.FindById(ctx, tx, componentId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "Material component not found")
+		}
+		return err
+	}
+
+	if component.TemplateId != templateId {
+		return fiber.NewError(fiber.StatusNotFound, "Material component not found")
+	}
+
+	return nil
+}
+
+// findLaborComponent makes sure the component belongs to the template
+func (h *AhspTemplatesAPIHandler) findLaborComponent(ctx context.Context, tx *sql.Tx, templateId, componentId int) error {
+	component, err := h.ahspLaborComponentsRepo.FindById(ctx, tx, componentId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "Labor component not found")
+		}
+		return err
+	}
+
+	if component.TemplateId != templateId {
+		return fiber.NewError(fiber.StatusNotFound, "Labor component not found")
+	}
+
+	return nil
+}
+
+// checkMaterial makes sure the material exists and is visible to the user
+func (h *AhspTemplatesAPIHandler) checkMaterial(ctx context.Context, tx *sql.Tx, materialId int, userData models.SessionUser) error {
+	material, err := h.materialsRepo.FindById(ctx, tx, materialId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, "Material not found")
+		}
+		return err
+	}
+
+	if !userData.CanView(material.UserId, material.OrgId) {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Material not found")
+	}
+
+	return nil
+}
+
+// checkLaborType makes sure the labor type exists and is visible to the user
+func (h *AhspTemplatesAPIHandler) checkLaborType(ctx context.Context, tx *sql.Tx, laborTypeId int, userData models.SessionUser) error {
+	laborType, err := h.laborTypesRepo.FindById(ctx, tx, laborTypeId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, "Labor type not found")
+		}
+		return err
+	}
+
+	if !userData.CanView(laborType.UserId, laborType.OrgId) {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Labor type not found")
+	}
+
+	return nil
+}
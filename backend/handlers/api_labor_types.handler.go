@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
+)
+
+type LaborTypesAPIHandler struct {
+	dbService      databases.DatabaseServices
+	laborTypesRepo master_labor_types.Repository
+	publisher      webhook_dispatch.Publisher
+}
+
+func NewLaborTypesAPIHandler(
+	dbService databases.DatabaseServices,
+	laborTypesRepo master_labor_types.Repository,
+	publisher webhook_dispatch.Publisher,
+) *LaborTypesAPIHandler {
+	return &LaborTypesAPIHandler{
+		dbService:      dbService,
+		laborTypesRepo: laborTypesRepo,
+		publisher:      publisher,
+	}
+}
+
+// ListLaborTypes returns a page of the user's labor types and the system-wide defaults
+func (h *LaborTypesAPIHandler) ListLaborTypes(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+	paginationData := apiPaginationData(c)
+
+	var laborTypes []models.MasterLaborType
+	var paginationInfo models.PaginationInfo
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		laborTypes, paginationInfo, err = h.laborTypesRepo.Find(ctx, tx, paginationData, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch labor types")
+	}
+
+	return apiList(c, laborTypes, paginationInfo)
+}
+
+func (h *LaborTypesAPIHandler) GetLaborType(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	laborTypeId, err := apiParamId(c, "id", "labor type")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var laborType models.MasterLaborType
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		laborType, err = h.findLaborType(ctx, tx, laborTypeId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		// system-wide defaults can be read by everyone
+		if !userData.CanView(laborType.UserId, laborType.OrgId) {
+			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch labor type")
+	}
+
+	return apiData(c, fiber.StatusOK, laborType)
+}
+
+func (h *LaborTypesAPIHandler) CreateLaborType(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var laborTypeData models.MasterLaborTypeCreate
+	if err := apiBody(c, &laborTypeData); err != nil {
+		return apiError(c, err, "")
+	}
+	laborTypeData.RoleName = strings.TrimSpace(laborTypeData.RoleName)
+	laborTypeData.Unit = strings.TrimSpace(laborTypeData.Unit)
+	laborTypeData.UserId = userData.Workspace().OwnerUserId()
+	laborTypeData.OrgId = userData.OrgId
+
+	var laborType models.MasterLaborType
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if err := h.laborTypesRepo.Create(ctx, tx, laborTypeData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		// the repository does not return the new ID, the user's own labor type is found first
+		var err error
+		laborType, err = h.laborTypesRepo.FindByNameAndUnit(ctx, tx, laborTypeData.RoleName, laborTypeData.Unit, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to create labor type, make sure the role name is unique")
+	}
+
+	return apiData(c, fiber.StatusCreated, laborType)
+}
+
+func (h *LaborTypesAPIHandler) UpdateLaborType(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	laborTypeId, err := apiParamId(c, "id", "labor type")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var laborTypeData models.MasterLaborTypeCreate
+	if err := apiBody(c, &laborTypeData); err != nil {
+		return apiError(c, err, "")
+	}
+
+	var laborType models.MasterLaborType
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingLaborType, err := h.findOwnLaborType(ctx, tx, laborTypeId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		laborType = models.MasterLaborType{
+			LaborTypeId:      laborTypeId,
+			UserId:           existingLaborType.UserId,
+			OrgId:            existingLaborType.OrgId,
+			RoleName:         strings.TrimSpace(laborTypeData.RoleName),
+			Unit:             strings.TrimSpace(laborTypeData.Unit),
+			DefaultDailyWage: laborTypeData.DefaultDailyWage,
+			CreatedAt:        existingLaborType.CreatedAt,
+			UpdatedAt:        models.UTCNow(),
+		}
+
+		if err := h.laborTypesRepo.Update(ctx, tx, laborType); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if existingLaborType.DefaultDailyWage != laborType.DefaultDailyWage {
+			change := models.NewLaborWageChange(laborType, existingLaborType.DefaultDailyWage)
+			if err := h.publisher.Publish(ctx, tx, userData.ID, models.WEBHOOK_EVENT_MASTER_PRICE_CHANGED, change); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to update labor type, make sure the role name is unique")
+	}
+
+	return apiData(c, fiber.StatusOK, laborType)
+}
+
+func (h *LaborTypesAPIHandler) DeleteLaborType(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	laborTypeId, err := apiParamId(c, "id", "labor type")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingLaborType, err := h.findOwnLaborType(ctx, tx, laborTypeId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.laborTypesRepo.Delete(ctx, tx, existingLaborType); err != nil {
+			// the repository refuses to delete a labor type that is in use
+			if strings.Contains(err.Error(), "used in") {
+				return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "Cannot delete this labor type because it is used in AHSP templates")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to delete labor type, it may still be used by AHSP templates")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *LaborTypesAPIHandler) findLaborType(ctx context.Context, tx *sql.Tx, laborTypeId int) (models.MasterLaborType, error) {
+	laborType, err := h.laborTypesRepo.FindById(ctx, tx, laborTypeId)
+	if err == sql.ErrNoRows {
+		return laborType, fiber.NewError(fiber.StatusNotFound, "Labor type not found")
+	}
+
+	return laborType, err
+}
+
+// findOwnLaborType returns the labor type when the user may change it, system-wide defaults only for admins
+func (h *LaborTypesAPIHandler) findOwnLaborType(ctx context.Context, tx *sql.Tx, laborTypeId int, userData models.SessionUser) (models.MasterLaborType, error) {
+	laborType, err := h.findLaborType(ctx, tx, laborTypeId)
+	if err != nil {
+		return laborType, err
+	}
+
+	if !userData.CanModify(laborType.UserId, laborType.OrgId) {
+		return laborType, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return laborType, nil
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
+)
+
+type MaterialsAPIHandler struct {
+	dbService     databases.DatabaseServices
+	materialsRepo master_materials.Repository
+	publisher     webhook_dispatch.Publisher
+}
+
+func NewMaterialsAPIHandler(
+	dbService databases.DatabaseServices,
+	materialsRepo master_materials.Repository,
+	publisher webhook_dispatch.Publisher,
+) *MaterialsAPIHandler {
+	return &MaterialsAPIHandler{
+		dbService:     dbService,
+		materialsRepo: materialsRepo,
+		publisher:     publisher,
+	}
+}
+
+// ListMaterials returns a page of the user's materials and the system-wide defaults
+func (h *MaterialsAPIHandler) ListMaterials(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+	paginationData := apiPaginationData(c)
+
+	var materials []models.MasterMaterial
+	var paginationInfo models.PaginationInfo
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		materials, paginationInfo, err = h.materialsRepo.Find(ctx, tx, paginationData, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch materials")
+	}
+
+	return apiList(c, materials, paginationInfo)
+}
+
+func (h *MaterialsAPIHandler) GetMaterial(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	materialId, err := apiParamId(c, "id", "material")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var material models.MasterMaterial
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		material, err = h.findMaterial(ctx, tx, materialId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		// system-wide defaults can be read by everyone
+		if !userData.CanView(material.UserId, material.OrgId) {
+			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch material")
+	}
+
+	return apiData(c, fiber.StatusOK, material)
+}
+
+func (h *MaterialsAPIHandler) CreateMaterial(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var materialData models.MasterMaterialCreate
+	if err := apiBody(c, &materialData); err != nil {
+		return apiError(c, err, "")
+	}
+	materialData.MaterialName = strings.TrimSpace(materialData.MaterialName)
+	materialData.Unit = strings.TrimSpace(materialData.Unit)
+	materialData.UserId = userData.Workspace().OwnerUserId()
+	materialData.OrgId = userData.OrgId
+
+	var material models.MasterMaterial
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if err := h.materialsRepo.Create(ctx, tx, materialData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		// the repository does not return the new ID, the user's own material is found first
+		var err error
+		material, err = h.materialsRepo.FindByNameAndUnit(ctx, tx, materialData.MaterialName, materialData.Unit, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to create material, make sure the material name is unique")
+	}
+
+	return apiData(c, fiber.StatusCreated, material)
+}
+
+func (h *MaterialsAPIHandler) UpdateMaterial(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	materialId, err := apiParamId(c, "id", "material")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var materialData models.MasterMaterialCreate
+	if err := apiBody(c, &materialData); err != nil {
+		return apiError(c, err, "")
+	}
+
+	var material models.MasterMaterial
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingMaterial, err := h.findOwnMaterial(ctx, tx, materialId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		material = models.MasterMaterial{
+			MaterialId:       materialId,
+			UserId:           existingMaterial.UserId,
+			OrgId:            existingMaterial.OrgId,
+			MaterialName:     strings.TrimSpace(materialData.MaterialName),
+			Unit:             strings.TrimSpace(materialData.Unit),
+			DefaultUnitPrice: materialData.DefaultUnitPrice,
+			IsEquipment:      materialData.IsEquipment,
+			CreatedAt:        existingMaterial.CreatedAt,
+			UpdatedAt:        models.UTCNow(),
+		}
+
+		if err := h.materialsRepo.Update(ctx, tx, material); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if existingMaterial.DefaultUnitPrice != material.DefaultUnitPrice {
+			change := models.NewMaterialPriceChange(material, existingMaterial.DefaultUnitPrice)
+			if err := h.publisher.Publish(ctx, tx, userData.ID, models.WEBHOOK_EVENT_MASTER_PRICE_CHANGED, change); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to update material, make sure the material name is unique")
+	}
+
+	return apiData(c, fiber.StatusOK, material)
+}
+
+func (h *MaterialsAPIHandler) DeleteMaterial(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	materialId, err := apiParamId(c, "id", "material")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingMaterial, err := h.findOwnMaterial(ctx, tx, materialId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.materialsRepo.Delete(ctx, tx, existingMaterial); err != nil {
+			// the repository refuses to delete a material that is in use
+			if strings.Contains(err.Error(), "used in") {
+				return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "Cannot delete this material because it is used in AHSP templates")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to delete material, it may still be used by AHSP templates")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *MaterialsAPIHandler) findMaterial(ctx context.Context, tx *sql.Tx, materialId int) (models.MasterMaterial, error) {
+	material, err := h.materialsRepo.FindById(ctx, tx, materialId)
+	if err == sql.ErrNoRows {
+		return material, fiber.NewError(fiber.StatusNotFound, "Material not found")
+	}
+
+	return material, err
+}
+
+// findOwnMaterial returns the material when the user may change it, system-wide defaults only for admins
+func (h *MaterialsAPIHandler) findOwnMaterial(ctx context.Context, tx *sql.Tx, materialId int, userData models.SessionUser) (models.MasterMaterial, error) {
+	material, err := h.findMaterial(ctx, tx, materialId)
+	if err != nil {
+		return material, err
+	}
+
+	if !userData.CanModify(material.UserId, material.OrgId) {
+		return material, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return material, nil
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+func newTestMaterialsAPIApp(t *testing.T, userId int, repo *fakeMaterialsRepo) (*fakeDatabase, func(method, target, body string) *http.Response) {
+	db := &fakeDatabase{}
+	handler := NewMaterialsAPIHandler(db, repo, &fakePublisher{})
+
+	app := newTestApp(userId)
+	api := app.Group(API_V1_PATH)
+	api.Get("/materials", handler.ListMaterials)
+	api.Post("/materials", handler.CreateMaterial)
+	api.Get("/materials/:id", handler.GetMaterial)
+	api.Put("/materials/:id", handler.UpdateMaterial)
+	api.Delete("/materials/:id", handler.DeleteMaterial)
+
+	return db, func(method, target, body string) *http.Response {
+		return doJSONRequest(t, app, method, target, body)
+	}
+}
+
+// doJSONRequest sends body, if any, as JSON and returns the response
+func doJSONRequest(t *testing.T, app *fiber.App, method, target, body string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, target, err)
+	}
+
+	return resp
+}
+
+// decodeJSON reads the response body into out
+func decodeJSON(t *testing.T, resp *http.Response, out interface{}) {
+	t.Helper()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+}
+
+// expectProblem checks the response is a problem+json document with the status
+func expectProblem(t *testing.T, resp *http.Response, status int) utils.Problem {
+	t.Helper()
+
+	if resp.StatusCode != status {
+		t.Fatalf("Expected %d, got %d", status, resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, utils.PROBLEM_CONTENT_TYPE) {
+		t.Fatalf("Expected a problem document, got content type %q", contentType)
+	}
+
+	var problem utils.Problem
+	decodeJSON(t, resp, &problem)
+	if problem.Status != status {
+		t.Errorf("Expected problem status %d, got %d", status, problem.Status)
+	}
+
+	return problem
+}
+
+// TestAPIListMaterials_Envelope verifies the list is wrapped with its pagination and per_page is capped
+func TestAPIListMaterials_Envelope(t *testing.T) {
+	repo := newFakeMaterialsRepo(
+		models.MasterMaterial{MaterialId: 1, UserId: 7, MaterialName: "Semen", Unit: "zak", DefaultUnitPrice: 65000},
+		models.MasterMaterial{MaterialId: 2, UserId: 8, MaterialName: "Pasir", Unit: "m3", DefaultUnitPrice: 250000},
+	)
+	db, do := newTestMaterialsAPIApp(t, 7, repo)
+
+	resp := do(http.MethodGet, "/api/v1/materials?page=2&per_page=500&search=sem", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data       []models.MasterMaterial `json:"data"`
+		Pagination models.PaginationInfo   `json:"pagination"`
+	}
+	decodeJSON(t, resp, &list)
+
+	if len(list.Data) != 1 || list.Data[0].MaterialName != "Semen" {
+		t.Errorf("Unexpected materials: %+v", list.Data)
+	}
+	if list.Pagination.CurrentPage != 2 || list.Pagination.ItemsPerPage != API_MAX_PER_PAGE {
+		t.Errorf("Unexpected pagination: %+v", list.Pagination)
+	}
+	if repo.lastFind.Search != "sem" {
+		t.Errorf("Expected the search to reach the repository, got %+v", repo.lastFind)
+	}
+	if db.readTransactions != 1 || db.transactions != 0 {
+		t.Errorf("Expected one read transaction, got %d read and %d write", db.readTransactions, db.transactions)
+	}
+}
+
+// TestAPICreateMaterial_Created verifies the material is saved for the session user and returned with its ID
+func TestAPICreateMaterial_Created(t *testing.T) {
+	repo := newFakeMaterialsRepo()
+	_, do := newTestMaterialsAPIApp(t, 7, repo)
+
+	resp := do(http.MethodPost, "/api/v1/materials", `{"material_name":"  Semen Portland ","unit":"zak","default_unit_price":65000,"user_id":99}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+
+	var created struct {
+		Data models.MasterMaterial `json:"data"`
+	}
+	decodeJSON(t, resp, &created)
+
+	if created.Data.MaterialId != 1 || created.Data.UserId != 7 || created.Data.MaterialName != "Semen Portland" {
+		t.Errorf("Unexpected material: %+v", created.Data)
+	}
+}
+
+// TestAPICreateMaterial_Validation verifies invalid input is answered with the invalid fields
+func TestAPICreateMaterial_Validation(t *testing.T) {
+	repo := newFakeMaterialsRepo()
+	db, do := newTestMaterialsAPIApp(t, 7, repo)
+
+	problem := expectProblem(t, do(http.MethodPost, "/api/v1/materials", `{"unit":"zak","default_unit_price":-1}`), http.StatusUnprocessableEntity)
+	if len(problem.Errors) != 2 {
+		t.Errorf("Expected the name and price errors, got %v", problem.Errors)
+	}
+	if db.transactions != 0 || len(repo.materials) != 0 {
+		t.Errorf("Invalid input reached the database")
+	}
+
+	expectProblem(t, do(http.MethodPost, "/api/v1/materials", `{"material_name":`), http.StatusBadRequest)
+}
+
+// TestAPIMaterials_OtherUsers verifies other users' materials cannot be read or changed,
+// while system-wide defaults are readable
+func TestAPIMaterials_OtherUsers(t *testing.T) {
+	repo := newFakeMaterialsRepo(
+		models.MasterMaterial{MaterialId: 1, UserId: 8, MaterialName: "Pasir", Unit: "m3", DefaultUnitPrice: 250000},
+		models.MasterMaterial{MaterialId: 2, UserId: 0, MaterialName: "Semen", Unit: "zak", DefaultUnitPrice: 65000},
+	)
+	_, do := newTestMaterialsAPIApp(t, 7, repo)
+
+	expectProblem(t, do(http.MethodGet, "/api/v1/materials/1", ""), http.StatusForbidden)
+	expectProblem(t, do(http.MethodDelete, "/api/v1/materials/2", ""), http.StatusForbidden)
+	expectProblem(t, do(http.MethodGet, "/api/v1/materials/99", ""), http.StatusNotFound)
+	expectProblem(t, do(http.MethodGet, "/api/v1/materials/abc", ""), http.StatusBadRequest)
+
+	if resp := do(http.MethodGet, "/api/v1/materials/2", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the system-wide material to be readable, got %d", resp.StatusCode)
+	}
+	if len(repo.materials) != 2 {
+		t.Errorf("A material was deleted")
+	}
+}
+
+// TestAPIUpdateAndDeleteMaterial verifies the owner can replace and delete a material
+func TestAPIUpdateAndDeleteMaterial(t *testing.T) {
+	repo := newFakeMaterialsRepo(
+		models.MasterMaterial{MaterialId: 1, UserId: 7, MaterialName: "Semen", Unit: "zak", DefaultUnitPrice: 65000, CreatedAt: "2024-01-01 00:00:00"},
+	)
+	_, do := newTestMaterialsAPIApp(t, 7, repo)
+
+	resp := do(http.MethodPut, "/api/v1/materials/1", `{"material_name":"Semen Putih","unit":"zak","default_unit_price":90000}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if material := repo.materials[1]; material.MaterialName != "Semen Putih" || material.DefaultUnitPrice != 90000 || material.CreatedAt != "2024-01-01 00:00:00" {
+		t.Errorf("Unexpected material: %+v", material)
+	}
+
+	expectProblem(t, do(http.MethodPut, "/api/v1/materials/1", ""), http.StatusUnsupportedMediaType)
+
+	if resp := do(http.MethodDelete, "/api/v1/materials/1", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", resp.StatusCode)
+	}
+	if len(repo.materials) != 0 {
+		t.Errorf("The material was not deleted")
+	}
+}
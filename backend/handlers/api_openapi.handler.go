@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/openapi"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+const (
+	OPENAPI_PATH  = "/api/openapi.json"
+	API_DOCS_PATH = "/api/docs"
+
+	API_VERSION = "1.0.0"
+)
+
+// apiPage is the Response of a route that answers with a page of T through apiList
+type apiPage[T any] struct{}
+
+func (apiPage[T]) pageItem() interface{} {
+	var item T
+	return item
+}
+
+// apiPaged is implemented by every apiPage
+type apiPaged interface {
+	pageItem() interface{}
+}
+
+// OpenAPIHandler serves the OpenAPI document of the JSON API and a viewer for it. The document
+// is built once from the routes, the viewer is a single page without outside scripts.
+type OpenAPIHandler struct {
+	document openapi.Document
+}
+
+func NewOpenAPIHandler(routes []APIRoute) *OpenAPIHandler {
+	return &OpenAPIHandler{
+		document: NewOpenAPIDocument(routes),
+	}
+}
+
+func (h *OpenAPIHandler) OpenAPIJSON(c *fiber.Ctx) error {
+	return c.JSON(h.document)
+}
+
+func (h *OpenAPIHandler) APIDocsView(c *fiber.Ctx) error {
+	page := components.APIDocsPage(OPENAPI_PATH)
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+// NewOpenAPIDocument describes the routes, their bodies come from the models and their
+// validate tags. Paths are relative to the API_V1_PATH server.
+func NewOpenAPIDocument(routes []APIRoute) openapi.Document {
+	schemas := openapi.NewSchemas()
+	problem := schemas.SchemaOf(utils.Problem{})
+
+	document := openapi.Document{
+		OpenAPI: openapi.OPENAPI_VERSION,
+		Info: openapi.Info{
+			Title:   "RAB Maker API",
+			Version: API_VERSION,
+			Description: "The projects, master data, AHSP templates and reports of RAB Maker as JSON. " +
+				"Single resources are sent as {\"data\": ...}, lists as {\"data\": [...], \"pagination\": ...}. " +
+				"Errors are RFC 9457 problem details.",
+		},
+		Servers: []openapi.Server{{URL: API_V1_PATH}},
+		Security: []openapi.SecurityRequirement{
+			{"bearerAuth": {}},
+			{"sessionCookie": {}},
+		},
+		Paths: map[string]*openapi.PathItem{},
+	}
+	tagged := map[string]bool{}
+
+	for _, route := range routes {
+		path, parameters := openAPIPath(route.Path)
+		tag := strings.Split(strings.Trim(route.Path, "/"), "/")[0]
+
+		operation := &openapi.Operation{
+			Tags:        []string{tag},
+			Summary:     route.Summary,
+			OperationId: apiOperationId(route.Handler),
+			Parameters:  append(parameters, openAPIOrganizationParameter()),
+			Responses:   map[string]*openapi.Response{},
+		}
+
+		paged, isPaged := route.Response.(apiPaged)
+		if isPaged {
+			operation.Parameters = append(operation.Parameters, openAPIPaginationParameters()...)
+		}
+
+		if route.Request != nil {
+			operation.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  openAPIContent(openapi.JSON_CONTENT_TYPE, schemas.SchemaOf(route.Request)),
+			}
+		}
+
+		switch {
+		case route.Response == nil:
+			operation.Responses["204"] = &openapi.Response{Description: http.StatusText(http.StatusNoContent)}
+		case isPaged:
+			operation.Responses["200"] = &openapi.Response{
+				Description: http.StatusText(http.StatusOK),
+				Content: openAPIContent(openapi.JSON_CONTENT_TYPE, &openapi.Schema{
+					Type: "object",
+					Properties: map[string]*openapi.Schema{
+						"data":       {Type: "array", Items: schemas.SchemaOf(paged.pageItem())},
+						"pagination": schemas.SchemaOf(models.PaginationInfo{}),
+					},
+					Required: []string{"data", "pagination"},
+				}),
+			}
+		default:
+			status := http.StatusOK
+			if route.Method == fiber.MethodPost {
+				status = http.StatusCreated
+			}
+			operation.Responses[strconv.Itoa(status)] = &openapi.Response{
+				Description: http.StatusText(status),
+				Content: openAPIContent(openapi.JSON_CONTENT_TYPE, &openapi.Schema{
+					Type:       "object",
+					Properties: map[string]*openapi.Schema{"data": schemas.SchemaOf(route.Response)},
+					Required:   []string{"data"},
+				}),
+			}
+		}
+
+		problems := map[int]string{
+			http.StatusUnauthorized: "Not logged in, or the token is invalid, expired or revoked",
+			http.StatusBadRequest:   "The " + middlewares.ORGANIZATION_HEADER + " header is not a number",
+			http.StatusForbidden:    "Not a member of the organization in " + middlewares.ORGANIZATION_HEADER,
+		}
+		if len(parameters) > 0 {
+			problems[http.StatusBadRequest] = "An ID in the path or " + middlewares.ORGANIZATION_HEADER + " is not a number"
+			problems[http.StatusForbidden] = "The data belongs to another user or organization"
+			problems[http.StatusNotFound] = "Not found"
+		}
+		if route.Method != fiber.MethodGet {
+			problems[http.StatusForbidden] = "The token is read-only, or the data belongs to another user or organization"
+		}
+		if route.Request != nil {
+			problems[http.StatusBadRequest] = "The body is not valid JSON, or an ID is not a number"
+			problems[http.StatusUnsupportedMediaType] = "The body is not sent as application/json"
+			problems[http.StatusUnprocessableEntity] = "The body is invalid, errors lists the invalid fields"
+		}
+		for status, description := range problems {
+			operation.Responses[strconv.Itoa(status)] = &openapi.Response{
+				Description: description,
+				Content:     openAPIContent(utils.PROBLEM_CONTENT_TYPE, problem),
+			}
+		}
+		operation.Responses["default"] = &openapi.Response{
+			Description: "Any other error, such as a conflict with existing data or too many requests",
+			Content:     openAPIContent(utils.PROBLEM_CONTENT_TYPE, problem),
+		}
+
+		pathItem, ok := document.Paths[path]
+		if !ok {
+			pathItem = &openapi.PathItem{}
+			document.Paths[path] = pathItem
+		}
+		if !pathItem.SetOperation(route.Method, operation) {
+			log.Printf("OpenAPI: %s %s is left out, the method cannot be described", route.Method, route.Path)
+			continue
+		}
+
+		if !tagged[tag] {
+			tagged[tag] = true
+			document.Tags = append(document.Tags, openapi.Tag{Name: tag})
+		}
+	}
+
+	document.Components = openapi.Components{
+		Schemas: schemas.Components(),
+		SecuritySchemes: map[string]openapi.SecurityScheme{
+			"bearerAuth": {
+				Type:         "http",
+				Scheme:       "bearer",
+				BearerFormat: models.API_TOKEN_PREFIX + "...",
+				Description:  "A personal access token from Settings, API Tokens. Read-only tokens can only send GET requests.",
+			},
+			"sessionCookie": {
+				Type:        "apiKey",
+				In:          "cookie",
+				Name:        middlewares.SESSION_APP_COOKIE_ID,
+				Description: "The session cookie set by logging in to the web app.",
+			},
+		},
+	}
+
+	return document
+}
+
+// openAPIPath turns the :params of a fiber path into {params} and describes them, every path
+// parameter of the API is an ID
+func openAPIPath(path string) (string, []openapi.Parameter) {
+	var parameters []openapi.Parameter
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+
+		name := strings.TrimPrefix(segment, ":")
+		minimum := 1.0
+		parameters = append(parameters, openapi.Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &openapi.Schema{Type: "integer", Minimum: &minimum},
+		})
+		segments[i] = "{" + name + "}"
+	}
+
+	return strings.Join(segments, "/"), parameters
+}
+
+// openAPIOrganizationParameter describes the header IsAuthAPI reads the workspace from
+func openAPIOrganizationParameter() openapi.Parameter {
+	minimum := 1.0
+
+	return openapi.Parameter{
+		Name:        middlewares.ORGANIZATION_HEADER,
+		In:          "header",
+		Description: "Work in this organization instead of your own workspace, you must be a member. A browser session uses the workspace picked in the app.",
+		Schema:      &openapi.Schema{Type: "integer", Minimum: &minimum},
+	}
+}
+
+// openAPIPaginationParameters describes the query parameters read by apiPaginationData
+func openAPIPaginationParameters() []openapi.Parameter {
+	minimum, maximum := 1.0, float64(API_MAX_PER_PAGE)
+
+	return []openapi.Parameter{
+		{
+			Name:        "page",
+			In:          "query",
+			Description: "The page to return, starting at 1",
+			Schema:      &openapi.Schema{Type: "integer", Minimum: &minimum, Default: 1},
+		},
+		{
+			Name:        "per_page",
+			In:          "query",
+			Description: "Items per page, larger values are capped",
+			Schema:      &openapi.Schema{Type: "integer", Minimum: &minimum, Maximum: &maximum, Default: API_DEFAULT_PER_PAGE},
+		},
+		{
+			Name:        "search",
+			In:          "query",
+			Description: "Only items that contain the text, ignoring case",
+			Schema:      &openapi.Schema{Type: "string"},
+		},
+	}
+}
+
+func openAPIContent(contentType string, schema *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{contentType: {Schema: schema}}
+}
+
+// apiOperationId names an operation after its handler method, ListProjects becomes listProjects
+func apiOperationId(handler fiber.Handler) string {
+	if handler == nil {
+		return ""
+	}
+
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimSuffix(name[strings.LastIndex(name, ".")+1:], "-fm")
+
+	return strings.ToLower(name[:1]) + name[1:]
+}
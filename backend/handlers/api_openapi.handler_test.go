@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/openapi"
+)
+
+// OPENAPI_DOCUMENT_FILE is the checked in OpenAPI document, relative to this package
+const OPENAPI_DOCUMENT_FILE = "../../docs/openapi.json"
+
+var updateOpenAPI = flag.Bool("update-openapi", false, "rewrite docs/openapi.json from the API routes")
+
+func openAPIDocumentJSON(t *testing.T) []byte {
+	t.Helper()
+
+	// the handlers are nil, the document only needs the routes and their types
+	document, err := json.MarshalIndent(NewOpenAPIDocument(APIHandlers{}.Routes()), "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to encode the OpenAPI document: %v", err)
+	}
+
+	return append(document, '\n')
+}
+
+// TestOpenAPI_NoDrift fails when the routes or models change without docs/openapi.json.
+// Run go test ./backend/handlers -run TestOpenAPI_NoDrift -update-openapi to rewrite it.
+func TestOpenAPI_NoDrift(t *testing.T) {
+	generated := openAPIDocumentJSON(t)
+
+	if *updateOpenAPI {
+		if err := os.WriteFile(filepath.FromSlash(OPENAPI_DOCUMENT_FILE), generated, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", OPENAPI_DOCUMENT_FILE, err)
+		}
+		return
+	}
+
+	checkedIn, err := os.ReadFile(filepath.FromSlash(OPENAPI_DOCUMENT_FILE))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", OPENAPI_DOCUMENT_FILE, err)
+	}
+
+	if !bytes.Equal(generated, checkedIn) {
+		t.Fatalf("%s is out of date with the API routes, run: go test ./backend/handlers -run TestOpenAPI_NoDrift -update-openapi", OPENAPI_DOCUMENT_FILE)
+	}
+}
+
+// TestOpenAPI_Routes verifies every route is described with its parameters, bodies and constraints
+func TestOpenAPI_Routes(t *testing.T) {
+	routes := APIHandlers{}.Routes()
+	document := NewOpenAPIDocument(routes)
+
+	operations := 0
+	for _, pathItem := range document.Paths {
+		for _, operation := range []*openapi.Operation{pathItem.Get, pathItem.Put, pathItem.Post, pathItem.Delete, pathItem.Patch} {
+			if operation != nil {
+				operations++
+			}
+		}
+	}
+	if operations != len(routes) {
+		t.Errorf("Expected %d operations, got %d", len(routes), operations)
+	}
+
+	list := document.Paths["/projects/{id}/work-items"].Get
+	if list == nil || list.OperationId != "listWorkItems" {
+		t.Fatalf("Unexpected work item list operation: %+v", list)
+	}
+	if len(list.Parameters) != 5 || list.Parameters[0].Name != "id" || list.Parameters[0].In != "path" || list.Parameters[3].Name != "per_page" {
+		t.Errorf("Unexpected parameters: %+v", list.Parameters)
+	}
+	if header := list.Parameters[1]; header.Name != middlewares.ORGANIZATION_HEADER || header.In != "header" || header.Required {
+		t.Errorf("Unexpected parameters: %+v", list.Parameters)
+	}
+
+	create := document.Paths["/projects"].Post
+	if create.RequestBody == nil || create.Responses["201"] == nil || create.Responses["422"] == nil {
+		t.Errorf("Expected a request body, 201 and 422 for creating a project, got %+v", create)
+	}
+	if remove := document.Paths["/projects/{id}"].Delete; remove.Responses["204"] == nil {
+		t.Errorf("Expected 204 for deleting a project, got %+v", remove.Responses)
+	}
+
+	projectCreate := document.Components.Schemas["ProjectCreate"]
+	if projectCreate == nil {
+		t.Fatalf("ProjectCreate is not a component")
+	}
+	name := projectCreate.Properties["project_name"]
+	if name == nil || name.MinLength == nil || *name.MinLength != 3 || name.MaxLength == nil || *name.MaxLength != 100 {
+		t.Errorf("Expected project_name to be 3 to 100 characters, got %+v", name)
+	}
+	if len(projectCreate.Required) != 3 {
+		t.Errorf("Expected three required fields, got %v", projectCreate.Required)
+	}
+
+	// the embedded work item fields are listed next to the costs
+	withCosts := document.Components.Schemas["ProjectWorkItemWithCosts"]
+	if withCosts == nil || withCosts.Properties["description"] == nil || withCosts.Properties["costs"] == nil {
+		t.Errorf("Unexpected work item with costs schema: %+v", withCosts)
+	}
+}
+
+// TestOpenAPI_Served verifies the document and the docs viewer are served
+func TestOpenAPI_Served(t *testing.T) {
+	handler := NewOpenAPIHandler(APIHandlers{}.Routes())
+
+	app := newTestApp(7)
+	app.Get(OPENAPI_PATH, handler.OpenAPIJSON)
+	app.Get(API_DOCS_PATH, handler.APIDocsView)
+
+	resp := doRequest(t, app, http.MethodGet, OPENAPI_PATH, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	var document openapi.Document
+	decodeJSON(t, resp, &document)
+	if document.OpenAPI != openapi.OPENAPI_VERSION || len(document.Paths) == 0 {
+		t.Errorf("Unexpected document: %s %d paths", document.OpenAPI, len(document.Paths))
+	}
+
+	body := responseBody(t, doRequest(t, app, http.MethodGet, API_DOCS_PATH, nil))
+	if !strings.Contains(body, `data-spec="`+OPENAPI_PATH+`"`) {
+		t.Errorf("The docs page does not load the document")
+	}
+}
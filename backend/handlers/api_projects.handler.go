@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
+)
+
+type ProjectsAPIHandler struct {
+	dbService            databases.DatabaseServices
+	projectsRepo         projects.Repository
+	projectApprovalsRepo project_approvals.Repository
+	publisher            webhook_dispatch.Publisher
+}
+
+func NewProjectsAPIHandler(
+	dbService databases.DatabaseServices,
+	projectsRepo projects.Repository,
+	projectApprovalsRepo project_approvals.Repository,
+	publisher webhook_dispatch.Publisher,
+) *ProjectsAPIHandler {
+	return &ProjectsAPIHandler{
+		dbService:            dbService,
+		projectsRepo:         projectsRepo,
+		projectApprovalsRepo: projectApprovalsRepo,
+		publisher:            publisher,
+	}
+}
+
+// ListProjects returns a page of the session user's projects
+func (h *ProjectsAPIHandler) ListProjects(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+	paginationData := apiPaginationData(c)
+
+	var projectsData []models.Project
+	var paginationInfo models.PaginationInfo
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		projectsData, paginationInfo, err = h.projectsRepo.FindByWorkspace(ctx, tx, userData.Workspace(), paginationData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch projects")
+	}
+
+	return apiList(c, projectsData, paginationInfo)
+}
+
+func (h *ProjectsAPIHandler) GetProject(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := apiParamId(c, "id", "project")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var project models.Project
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, err = findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch project")
+	}
+
+	return apiData(c, fiber.StatusOK, project)
+}
+
+func (h *ProjectsAPIHandler) CreateProject(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var projectData models.ProjectCreate
+	if err := apiBody(c, &projectData); err != nil {
+		return apiError(c, err, "")
+	}
+	projectData.UserId = userData.ID
+	projectData.OrgId = userData.OrgId
+
+	var project models.Project
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		projectId, err := h.projectsRepo.Create(ctx, tx, projectData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		project, err = h.projectsRepo.FindById(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.publisher.Publish(ctx, tx, userData.ID, models.WEBHOOK_EVENT_PROJECT_CREATED, project); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to create project")
+	}
+
+	return apiData(c, fiber.StatusCreated, project)
+}
+
+func (h *ProjectsAPIHandler) UpdateProject(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := apiParamId(c, "id", "project")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var projectData models.ProjectCreate
+	if err := apiBody(c, &projectData); err != nil {
+		return apiError(c, err, "")
+	}
+
+	var project models.Project
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingProject, err := findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
+		}
+
+		project = models.Project{
+			ProjectId:   projectId,
+			UserId:      existingProject.UserId,
+			OrgId:       existingProject.OrgId,
+			ProjectName: projectData.ProjectName,
+			Location:    projectData.Location,
+			ClientName:  projectData.ClientName,
+			CreatedAt:   existingProject.CreatedAt,
+			UpdatedAt:   models.UTCNow(),
+		}
+
+		if err := h.projectsRepo.Update(ctx, tx, project); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.publisher.Publish(ctx, tx, project.UserId, models.WEBHOOK_EVENT_PROJECT_UPDATED, project); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to update project")
+	}
+
+	return apiData(c, fiber.StatusOK, project)
+}
+
+// DeleteProject deletes the project, its work items and their costs go with it
+func (h *ProjectsAPIHandler) DeleteProject(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := apiParamId(c, "id", "project")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingProject, err := findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
+		}
+
+		if err := h.projectsRepo.Delete(ctx, tx, existingProject); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.publisher.Publish(ctx, tx, existingProject.UserId, models.WEBHOOK_EVENT_PROJECT_DELETED, existingProject); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to delete project")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// findOwnedProject returns the project when it belongs to the user or one of their organizations,
+// otherwise a not found or access denied error
+func findOwnedProject(ctx context.Context, tx *sql.Tx, projectsRepo projects.Repository, projectId int, userData models.SessionUser) (models.Project, error) {
+	project, err := projectsRepo.FindById(ctx, tx, projectId)
+	if err != nil {
+		return project, err
+	}
+
+	// the repository returns the zero project when nothing matches
+	if project.ProjectId == 0 {
+		return project, fiber.NewError(fiber.StatusNotFound, "Project not found")
+	}
+
+	if !userData.OwnsProject(project) {
+		return project, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return project, nil
+}
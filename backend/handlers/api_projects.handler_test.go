@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+func newTestProjectsAPIApp(t *testing.T, userId int, repo *fakeProjectsRepo, approvals ...models.ProjectApproval) func(method, target, body string) *http.Response {
+	handler := NewProjectsAPIHandler(&fakeDatabase{}, repo, newFakeProjectApprovalsRepo(approvals...), &fakePublisher{})
+
+	app := newTestApp(userId)
+	api := app.Group(API_V1_PATH)
+	api.Get("/projects", handler.ListProjects)
+	api.Post("/projects", handler.CreateProject)
+	api.Get("/projects/:id", handler.GetProject)
+	api.Put("/projects/:id", handler.UpdateProject)
+	api.Delete("/projects/:id", handler.DeleteProject)
+
+	return func(method, target, body string) *http.Response {
+		return doJSONRequest(t, app, method, target, body)
+	}
+}
+
+// TestAPIProjects_OnlyOwnProjects verifies the list and the single project routes only serve the user's projects,
+// and a missing project is not found rather than refused
+func TestAPIProjects_OnlyOwnProjects(t *testing.T) {
+	repo := newFakeProjectsRepo(
+		models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"},
+		models.Project{ProjectId: 2, UserId: 8, ProjectName: "Gudang"},
+	)
+	do := newTestProjectsAPIApp(t, 7, repo)
+
+	var list struct {
+		Data []models.Project `json:"data"`
+	}
+	decodeJSON(t, do(http.MethodGet, "/api/v1/projects", ""), &list)
+	if len(list.Data) != 1 || list.Data[0].ProjectId != 1 {
+		t.Errorf("Unexpected projects: %+v", list.Data)
+	}
+
+	expectProblem(t, do(http.MethodGet, "/api/v1/projects/2", ""), http.StatusForbidden)
+	expectProblem(t, do(http.MethodPut, "/api/v1/projects/2", `{"project_name":"Gudang Baru","location":"Bandung","client_name":"PT Maju"}`), http.StatusForbidden)
+	expectProblem(t, do(http.MethodDelete, "/api/v1/projects/2", ""), http.StatusForbidden)
+	expectProblem(t, do(http.MethodGet, "/api/v1/projects/99", ""), http.StatusNotFound)
+	expectProblem(t, do(http.MethodPut, "/api/v1/projects/99", `{"project_name":"Gudang Baru","location":"Bandung","client_name":"PT Maju"}`), http.StatusNotFound)
+	expectProblem(t, do(http.MethodDelete, "/api/v1/projects/99", ""), http.StatusNotFound)
+	if repo.projects[2].ProjectName != "Gudang" {
+		t.Errorf("Another user's project was changed")
+	}
+}
+
+// TestAPICreateProject_Created verifies the project is created for the session user
+func TestAPICreateProject_Created(t *testing.T) {
+	repo := newFakeProjectsRepo()
+	do := newTestProjectsAPIApp(t, 7, repo)
+
+	resp := do(http.MethodPost, "/api/v1/projects", `{"project_name":"Rumah Tinggal","location":"Bandung","client_name":"Pak Budi"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+
+	var created struct {
+		Data models.Project `json:"data"`
+	}
+	decodeJSON(t, resp, &created)
+	if created.Data.ProjectId != 1 || created.Data.UserId != 7 || created.Data.ProjectName != "Rumah Tinggal" {
+		t.Errorf("Unexpected project: %+v", created.Data)
+	}
+
+	problem := expectProblem(t, do(http.MethodPost, "/api/v1/projects", `{"project_name":"RT"}`), http.StatusUnprocessableEntity)
+	if len(problem.Errors) != 3 {
+		t.Errorf("Expected three invalid fields, got %v", problem.Errors)
+	}
+}
+
+// TestAPIProjects_LockedProject verifies an approved project cannot be updated or deleted
+// until a new revision is started
+func TestAPIProjects_LockedProject(t *testing.T) {
+	repo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	do := newTestProjectsAPIApp(t, 7, repo, models.ProjectApproval{ProjectId: 1, Status: models.APPROVAL_STATUS_APPROVED})
+
+	expectProblem(t, do(http.MethodPut, "/api/v1/projects/1", `{"project_name":"Rumah Dua Lantai","location":"Bandung","client_name":"Pak Budi"}`), http.StatusConflict)
+	expectProblem(t, do(http.MethodDelete, "/api/v1/projects/1", ""), http.StatusConflict)
+	if project, ok := repo.projects[1]; !ok || project.ProjectName != "Rumah Tinggal" {
+		t.Errorf("The approved project was changed: %+v", project)
+	}
+}
+
+// TestAPIProjects_PublishedToOwner verifies a change by another member of the project's
+// organization is published to the webhooks of the project owner
+func TestAPIProjects_PublishedToOwner(t *testing.T) {
+	repo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 8, OrgId: 3, ProjectName: "Rumah Tinggal"})
+	publisher := &fakePublisher{}
+	handler := NewProjectsAPIHandler(&fakeDatabase{}, repo, newFakeProjectApprovalsRepo(), publisher)
+
+	app := newTestAppFor(models.SessionUser{ID: 7, Role: models.ROLE_ESTIMATOR, OrgId: 3, OrgIds: []int{3}})
+	app.Put(API_V1_PATH+"/projects/:id", handler.UpdateProject)
+	app.Delete(API_V1_PATH+"/projects/:id", handler.DeleteProject)
+
+	if resp := doJSONRequest(t, app, http.MethodPut, "/api/v1/projects/1", `{"project_name":"Rumah Dua Lantai","location":"Bandung","client_name":"Pak Budi"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if resp := doJSONRequest(t, app, http.MethodDelete, "/api/v1/projects/1", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", resp.StatusCode)
+	}
+
+	if len(publisher.userIds) != 2 || publisher.userIds[0] != 8 || publisher.userIds[1] != 8 {
+		t.Errorf("Expected both changes to be published to the owner, got %v", publisher.userIds)
+	}
+}
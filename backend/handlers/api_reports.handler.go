@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/dashboard"
+	"github.com/momokii/go-rab-maker/backend/repository/material_summary"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+)
+
+type MaterialSummaryAPIHandler struct {
+	dbService            databases.DatabaseServices
+	materialSummaryRepo  material_summary.Repository
+	projectsRepo         projects.Repository
+	projectItemCostsRepo project_item_costs.Repository
+	dashboardRepo        dashboard.Repository
+}
+
+func NewMaterialSummaryAPIHandler(
+	dbService databases.DatabaseServices,
+	materialSummaryRepo material_summary.Repository,
+	projectsRepo projects.Repository,
+	projectItemCostsRepo project_item_costs.Repository,
+	dashboardRepo dashboard.Repository,
+) *MaterialSummaryAPIHandler {
+	return &MaterialSummaryAPIHandler{
+		dbService:            dbService,
+		materialSummaryRepo:  materialSummaryRepo,
+		projectsRepo:         projectsRepo,
+		projectItemCostsRepo: projectItemCostsRepo,
+		dashboardRepo:        dashboardRepo,
+	}
+}
+
+// GetMaterialSummary returns the materials and labor needed across all of the user's projects
+func (h *MaterialSummaryAPIHandler) GetMaterialSummary(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var summary models.MaterialSummaryOverview
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		summary.Items, err = h.materialSummaryRepo.GetAllMaterialsSummary(ctx, tx, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		summary.Stats, err = h.dashboardRepo.GetMaterialSummaryStats(ctx, tx, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		summary.ProjectBreakdown, err = h.dashboardRepo.GetProjectBreakdown(ctx, tx, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch material summary")
+	}
+
+	if summary.Items == nil {
+		summary.Items = []models.MaterialSummary{}
+	}
+	if summary.ProjectBreakdown == nil {
+		summary.ProjectBreakdown = []models.ProjectBreakdown{}
+	}
+
+	return apiData(c, fiber.StatusOK, summary)
+}
+
+// GetProjectMaterialSummary returns the materials and labor of one project, with the work items that use them
+func (h *MaterialSummaryAPIHandler) GetProjectMaterialSummary(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := apiParamId(c, "id", "project")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var summaries []models.DetailedMaterialSummary
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		summaries, err = h.projectItemCostsRepo.GetDetailedMaterialSummaryByProjectId(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch project material summary")
+	}
+
+	if summaries == nil {
+		summaries = []models.DetailedMaterialSummary{}
+	}
+
+	return apiData(c, fiber.StatusOK, summaries)
+}
+
+type DashboardAPIHandler struct {
+	dbService     databases.DatabaseServices
+	dashboardRepo dashboard.Repository
+}
+
+func NewDashboardAPIHandler(
+	dbService databases.DatabaseServices,
+	dashboardRepo dashboard.Repository,
+) *DashboardAPIHandler {
+	return &DashboardAPIHandler{
+		dbService:     dbService,
+		dashboardRepo: dashboardRepo,
+	}
+}
+
+// GetDashboard returns the dashboard figures, with the same limits as the dashboard page
+func (h *DashboardAPIHandler) GetDashboard(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var overview models.DashboardOverview
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		if overview.RecentProjects, err = h.dashboardRepo.GetEnhancedRecentProjects(ctx, tx, userData.Workspace(), 10); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if overview.TotalProjects, err = h.dashboardRepo.GetProjectCount(ctx, tx, userData.Workspace()); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if overview.TotalWorkItems, err = h.dashboardRepo.GetWorkItemsCount(ctx, tx, userData.Workspace()); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if overview.TotalCost, err = h.dashboardRepo.GetProjectsTotalCost(ctx, tx, userData.Workspace()); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if overview.TypeCostBreakdown, err = h.dashboardRepo.GetTypeCostBreakdown(ctx, tx, userData.Workspace()); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if overview.CategoryBreakdown, err = h.dashboardRepo.GetCategoryBreakdown(ctx, tx, userData.Workspace(), 5); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if overview.TopExpensiveItems, err = h.dashboardRepo.GetTopExpensiveItems(ctx, tx, userData.Workspace(), 10); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to load dashboard data")
+	}
+
+	if overview.RecentProjects == nil {
+		overview.RecentProjects = []models.EnhancedProjectData{}
+	}
+	if overview.TypeCostBreakdown == nil {
+		overview.TypeCostBreakdown = []models.TypeCostBreakdown{}
+	}
+	if overview.CategoryBreakdown == nil {
+		overview.CategoryBreakdown = []models.CategoryBreakdown{}
+	}
+	if overview.TopExpensiveItems == nil {
+		overview.TopExpensiveItems = []models.TopExpensiveItem{}
+	}
+
+	return apiData(c, fiber.StatusOK, overview)
+}
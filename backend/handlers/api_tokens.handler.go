@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/api_tokens"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// API_TOKEN_MAX_EXPIRY_DAYS is the longest expiry that can be chosen, 0 days means no expiry
+const API_TOKEN_MAX_EXPIRY_DAYS = 365
+
+// APITokensHandler lets users manage their personal access tokens on the settings page
+type APITokensHandler struct {
+	dbService  databases.DatabaseServices
+	tokensRepo api_tokens.Repository
+}
+
+func NewAPITokensHandler(
+	dbService databases.DatabaseServices,
+	tokensRepo api_tokens.Repository,
+) *APITokensHandler {
+	return &APITokensHandler{
+		dbService:  dbService,
+		tokensRepo: tokensRepo,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+func (h *APITokensHandler) APITokensView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var tokens []models.APIToken
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		tokens, err = h.tokensRepo.FindByUserId(ctx, tx, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to load API tokens")
+	}
+
+	page := components.APITokensPage(tokens, time.Now())
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+func (h *APITokensHandler) APITokenCreateModalView(c *fiber.Ctx) error {
+	modal := components.APITokenCreateModal()
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// CreateAPIToken creates a token and shows it once, only its hash is kept
+func (h *APITokensHandler) CreateAPIToken(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	expiryDays, err := strconv.Atoi(c.FormValue("token_expiry_days"))
+	if err != nil || expiryDays < 0 || expiryDays > API_TOKEN_MAX_EXPIRY_DAYS {
+		return utils.ResponseErrorModal(c, "Validation Error", "Choose an expiry of at most "+strconv.Itoa(API_TOKEN_MAX_EXPIRY_DAYS)+" days")
+	}
+
+	plainToken, prefix, hash, err := models.NewAPIToken()
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to generate token")
+	}
+
+	tokenData := models.APITokenCreate{
+		UserId:      userData.ID,
+		TokenName:   strings.TrimSpace(c.FormValue("token_name")),
+		TokenPrefix: prefix,
+		TokenHash:   hash,
+		Scope:       models.APITokenScope(c.FormValue("token_scope")),
+	}
+	if expiryDays > 0 {
+		tokenData.ExpiresAt = time.Now().UTC().AddDate(0, 0, expiryDays).Format(models.API_TOKEN_TIME_FORMAT)
+	}
+
+	if err := utils.ValidateStruct(tokenData); err != nil {
+		errors := utils.GetValidationErrors(err)
+		return utils.ResponseErrorModal(c, "Validation Error", strings.Join(errors, "; "))
+	}
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := h.tokensRepo.Create(ctx, tx, tokenData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to create token")
+	}
+
+	modal := components.APITokenCreatedModal(tokenData, plainToken)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// RevokeAPIToken stops a token from authenticating, it stays in the list as revoked
+func (h *APITokensHandler) RevokeAPIToken(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	tokenId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid token ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		token, err := h.tokensRepo.FindById(ctx, tx, tokenId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Token not found")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		if token.UserId != userData.ID {
+			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		}
+
+		if err := h.tokensRepo.Revoke(ctx, tx, tokenId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to revoke token")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Token Revoked", "The token can no longer be used", "/settings/tokens")
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// fakeAPITokensRepo keeps tokens in a map and counts the last-used updates
+type fakeAPITokensRepo struct {
+	tokens      map[int]models.APIToken
+	lastUsedSet int
+}
+
+func (r *fakeAPITokensRepo) FindById(ctx context.Context, tx *sql.Tx, tokenId int) (models.APIToken, error) {
+	token, ok := r.tokens[tokenId]
+	if !ok {
+		return token, sql.ErrNoRows
+	}
+
+	return token, nil
+}
+
+func (r *fakeAPITokensRepo) FindByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.APIToken, error) {
+	tokens := []models.APIToken{}
+	for _, token := range r.tokens {
+		if token.UserId == userId {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
+
+func (r *fakeAPITokensRepo) FindByHash(ctx context.Context, tx *sql.Tx, tokenHash string) (models.APIToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return models.APIToken{}, sql.ErrNoRows
+}
+
+func (r *fakeAPITokensRepo) Create(ctx context.Context, tx *sql.Tx, tokenData models.APITokenCreate) (int, error) {
+	tokenId := len(r.tokens) + 1
+	r.tokens[tokenId] = models.APIToken{
+		TokenId:     tokenId,
+		UserId:      tokenData.UserId,
+		TokenName:   tokenData.TokenName,
+		TokenPrefix: tokenData.TokenPrefix,
+		TokenHash:   tokenData.TokenHash,
+		Scope:       tokenData.Scope,
+		ExpiresAt:   tokenData.ExpiresAt,
+	}
+
+	return tokenId, nil
+}
+
+func (r *fakeAPITokensRepo) Revoke(ctx context.Context, tx *sql.Tx, tokenId int) error {
+	token := r.tokens[tokenId]
+	token.RevokedAt = time.Now().UTC().Format(models.API_TOKEN_TIME_FORMAT)
+	r.tokens[tokenId] = token
+	return nil
+}
+
+func (r *fakeAPITokensRepo) UpdateLastUsed(ctx context.Context, tx *sql.Tx, tokenId int) error {
+	r.lastUsedSet++
+	token := r.tokens[tokenId]
+	token.LastUsedAt = time.Now().UTC().Format(models.API_TOKEN_TIME_FORMAT)
+	r.tokens[tokenId] = token
+	return nil
+}
+
+// newTestBearerApp returns the materials API behind the same middleware chain as main.go.
+// User 7 is an estimator and member of organization 3, 8 a viewer and 9 a disabled estimator.
+func newTestBearerApp(tokensRepo *fakeAPITokensRepo, materialsRepo *fakeMaterialsRepo) *fiber.App {
+	db := &fakeDatabase{}
+	tokenMiddleware := middlewares.NewTokenMiddleware(db, tokensRepo)
+	session := middlewares.NewSessionMiddleware(db, newFakeUsersRepo(
+		models.User{UserId: 7, Username: "budi", Role: models.ROLE_ESTIMATOR},
+		models.User{UserId: 8, Username: "sari", Role: models.ROLE_VIEWER},
+		models.User{UserId: 9, Username: "joko", Role: models.ROLE_ESTIMATOR, DeletedAt: sql.NullString{String: "2024-01-01 00:00:00", Valid: true}},
+	), newFakeOrganizationsRepo(
+		models.OrganizationMember{OrgId: 3, UserId: 7, Role: models.ORG_ROLE_MEMBER},
+	))
+	roles := middlewares.NewRoleMiddleware()
+	handler := NewMaterialsAPIHandler(db, materialsRepo, &fakePublisher{})
+
+	app := fiber.New()
+	api := app.Group(API_V1_PATH, tokenMiddleware.IsAuthBearer, session.IsAuthAPI, roles.Writes(models.PERMISSION_EDIT))
+	api.Get("/materials", handler.ListMaterials)
+	api.Post("/materials", handler.CreateMaterial)
+
+	return app
+}
+
+// doBearerRequest sends the request with the token, if any, as a bearer token
+func doBearerRequest(t *testing.T, app *fiber.App, method, target, token, body string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, target, err)
+	}
+
+	return resp
+}
+
+// TestIsAuthBearer_Scopes verifies a token acts as its owner and read-only tokens cannot write
+func TestIsAuthBearer_Scopes(t *testing.T) {
+	readToken, _, readHash, _ := models.NewAPIToken()
+	writeToken, _, writeHash, _ := models.NewAPIToken()
+	tokensRepo := &fakeAPITokensRepo{tokens: map[int]models.APIToken{
+		1: {TokenId: 1, UserId: 7, TokenHash: readHash, Scope: models.API_TOKEN_SCOPE_READ},
+		2: {TokenId: 2, UserId: 7, TokenHash: writeHash, Scope: models.API_TOKEN_SCOPE_WRITE},
+	}}
+	materialsRepo := newFakeMaterialsRepo()
+	app := newTestBearerApp(tokensRepo, materialsRepo)
+
+	if resp := doBearerRequest(t, app, http.MethodGet, "/api/v1/materials", "Bearer "+readToken, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 with the read token, got %d", resp.StatusCode)
+	}
+
+	body := `{"material_name":"Semen","unit":"zak","default_unit_price":65000}`
+	expectProblem(t, doBearerRequest(t, app, http.MethodPost, "/api/v1/materials", "Bearer "+readToken, body), http.StatusForbidden)
+	if len(materialsRepo.materials) != 0 {
+		t.Fatalf("The read-only token created a material")
+	}
+
+	if resp := doBearerRequest(t, app, http.MethodPost, "/api/v1/materials", "Bearer "+writeToken, body); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201 with the write token, got %d", resp.StatusCode)
+	}
+	if material := materialsRepo.materials[1]; material.UserId != 7 {
+		t.Errorf("Expected the material to belong to the token owner, got %+v", material)
+	}
+
+	if tokensRepo.lastUsedSet != 2 {
+		t.Errorf("Expected the last use of each token to be recorded once, got %d updates", tokensRepo.lastUsedSet)
+	}
+}
+
+// TestIsAuthBearer_Rejected verifies unknown, revoked and expired tokens and a missing login are refused
+func TestIsAuthBearer_Rejected(t *testing.T) {
+	revokedToken, _, revokedHash, _ := models.NewAPIToken()
+	expiredToken, _, expiredHash, _ := models.NewAPIToken()
+	unknownToken, _, _, _ := models.NewAPIToken()
+	tokensRepo := &fakeAPITokensRepo{tokens: map[int]models.APIToken{
+		1: {TokenId: 1, UserId: 7, TokenHash: revokedHash, Scope: models.API_TOKEN_SCOPE_WRITE, RevokedAt: "2024-01-01 00:00:00"},
+		2: {TokenId: 2, UserId: 7, TokenHash: expiredHash, Scope: models.API_TOKEN_SCOPE_WRITE, ExpiresAt: "2024-01-01 00:00:00"},
+	}}
+	app := newTestBearerApp(tokensRepo, newFakeMaterialsRepo())
+
+	for name, header := range map[string]string{
+		"revoked":   "Bearer " + revokedToken,
+		"expired":   "Bearer " + expiredToken,
+		"unknown":   "Bearer " + unknownToken,
+		"no prefix": "Bearer abc",
+		"basic":     "Basic dXNlcjpwYXNz",
+		"none":      "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			expectProblem(t, doBearerRequest(t, app, http.MethodGet, "/api/v1/materials", header, ""), http.StatusUnauthorized)
+		})
+	}
+
+	if tokensRepo.lastUsedSet != 0 {
+		t.Errorf("A rejected token was marked as used")
+	}
+}
+
+// TestIsAuthBearer_Roles verifies a token has the role of its owner: a viewer's write token
+// only reads, and the tokens of a disabled user stop working
+func TestIsAuthBearer_Roles(t *testing.T) {
+	viewerToken, _, viewerHash, _ := models.NewAPIToken()
+	disabledToken, _, disabledHash, _ := models.NewAPIToken()
+	tokensRepo := &fakeAPITokensRepo{tokens: map[int]models.APIToken{
+		1: {TokenId: 1, UserId: 8, TokenHash: viewerHash, Scope: models.API_TOKEN_SCOPE_WRITE},
+		2: {TokenId: 2, UserId: 9, TokenHash: disabledHash, Scope: models.API_TOKEN_SCOPE_WRITE},
+	}}
+	materialsRepo := newFakeMaterialsRepo()
+	app := newTestBearerApp(tokensRepo, materialsRepo)
+
+	if resp := doBearerRequest(t, app, http.MethodGet, "/api/v1/materials", "Bearer "+viewerToken, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a viewer to read, got %d", resp.StatusCode)
+	}
+
+	body := `{"material_name":"Semen","unit":"zak","default_unit_price":65000}`
+	expectProblem(t, doBearerRequest(t, app, http.MethodPost, "/api/v1/materials", "Bearer "+viewerToken, body), http.StatusForbidden)
+	if len(materialsRepo.materials) != 0 {
+		t.Fatalf("A viewer created a material")
+	}
+
+	expectProblem(t, doBearerRequest(t, app, http.MethodGet, "/api/v1/materials", "Bearer "+disabledToken, ""), http.StatusUnauthorized)
+}
+
+// TestIsAuthAPI_OrganizationHeader verifies X-Organization-Id makes a member work in the
+// organization, and is refused for anyone else
+func TestIsAuthAPI_OrganizationHeader(t *testing.T) {
+	memberToken, _, memberHash, _ := models.NewAPIToken()
+	viewerToken, _, viewerHash, _ := models.NewAPIToken()
+	tokensRepo := &fakeAPITokensRepo{tokens: map[int]models.APIToken{
+		1: {TokenId: 1, UserId: 7, TokenHash: memberHash, Scope: models.API_TOKEN_SCOPE_WRITE},
+		2: {TokenId: 2, UserId: 8, TokenHash: viewerHash, Scope: models.API_TOKEN_SCOPE_READ},
+	}}
+	materialsRepo := newFakeMaterialsRepo()
+	app := newTestBearerApp(tokensRepo, materialsRepo)
+
+	send := func(token, orgId, body string) *http.Response {
+		method := http.MethodGet
+		if body != "" {
+			method = http.MethodPost
+		}
+
+		req := httptest.NewRequest(method, "/api/v1/materials", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(middlewares.ORGANIZATION_HEADER, orgId)
+
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	body := `{"material_name":"Semen","unit":"zak","default_unit_price":65000,"org_id":99}`
+	if resp := send(memberToken, "3", body); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected a member to create in the organization, got %d", resp.StatusCode)
+	}
+	if material := materialsRepo.materials[1]; material.OrgId != 3 || material.UserId != 0 {
+		t.Errorf("Expected an organization material without an owning user, got %+v", material)
+	}
+
+	expectProblem(t, send(viewerToken, "3", ""), http.StatusForbidden)
+	expectProblem(t, send(memberToken, "three", ""), http.StatusBadRequest)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+)
+
+type WorkCategoriesAPIHandler struct {
+	dbService          databases.DatabaseServices
+	workCategoriesRepo master_work_categories.Repository
+}
+
+func NewWorkCategoriesAPIHandler(
+	dbService databases.DatabaseServices,
+	workCategoriesRepo master_work_categories.Repository,
+) *WorkCategoriesAPIHandler {
+	return &WorkCategoriesAPIHandler{
+		dbService:          dbService,
+		workCategoriesRepo: workCategoriesRepo,
+	}
+}
+
+// ListWorkCategories returns a page of the workspace's and the system-wide work categories in display order
+func (h *WorkCategoriesAPIHandler) ListWorkCategories(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+	paginationData := apiPaginationData(c)
+
+	var workCategories []models.MasterWorkCategory
+	var paginationInfo models.PaginationInfo
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		workCategories, paginationInfo, err = h.workCategoriesRepo.Find(ctx, tx, paginationData, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch work categories")
+	}
+
+	return apiList(c, workCategories, paginationInfo)
+}
+
+func (h *WorkCategoriesAPIHandler) GetWorkCategory(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	workCategoryId, err := apiParamId(c, "id", "work category")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	var workCategory models.MasterWorkCategory
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		workCategory, err = h.findWorkCategory(ctx, tx, workCategoryId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch work category")
+	}
+
+	return apiData(c, fiber.StatusOK, workCategory)
+}
+
+// CreateWorkCategory creates a category, names are matched without case like the importers do
+func (h *WorkCategoriesAPIHandler) CreateWorkCategory(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var workCategoryData models.MasterWorkCategoryCreate
+	if err := apiBody(c, &workCategoryData); err != nil {
+		return apiError(c, err, "")
+	}
+	workCategoryData.CategoryName = strings.TrimSpace(workCategoryData.CategoryName)
+	workCategoryData.UserId = userData.Workspace().OwnerUserId()
+	workCategoryData.OrgId = userData.OrgId
+
+	var workCategory models.MasterWorkCategory
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		// a system-wide category with the same name does not stop the workspace from having its own
+		existing, err := h.workCategoriesRepo.FindByName(ctx, tx, workCategoryData.CategoryName, userData.Workspace())
+		if err == nil && (existing.UserId != 0 || existing.OrgId != 0) {
+			return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "A work category with this name already exists")
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return fiber.StatusInternalServerError, err
+		}
+
+		workCategoryId, err := h.workCategoriesRepo.Create(ctx, tx, workCategoryData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		workCategory, err = h.workCategoriesRepo.FindById(ctx, tx, workCategoryId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to create work category")
+	}
+
+	return apiData(c, fiber.StatusCreated, workCategory)
+}
+
+func (h *WorkCategoriesAPIHandler) UpdateWorkCategory(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	workCategoryId, err := apiParamId(c, "id", "work category")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var workCategoryData models.MasterWorkCategoryCreate
+	if err := apiBody(c, &workCategoryData); err != nil {
+		return apiError(c, err, "")
+	}
+
+	var workCategory models.MasterWorkCategory
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingWorkCategory, err := h.findOwnWorkCategory(ctx, tx, workCategoryId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		workCategory = models.MasterWorkCategory{
+			CategoryId:   workCategoryId,
+			UserId:       existingWorkCategory.UserId,
+			OrgId:        existingWorkCategory.OrgId,
+			CategoryName: strings.TrimSpace(workCategoryData.CategoryName),
+			DisplayOrder: workCategoryData.DisplayOrder,
+			CreatedAt:    existingWorkCategory.CreatedAt,
+			UpdatedAt:    models.UTCNow(),
+		}
+
+		if err := h.workCategoriesRepo.Update(ctx, tx, workCategory); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to update work category, make sure the category name is unique")
+	}
+
+	return apiData(c, fiber.StatusOK, workCategory)
+}
+
+// DeleteWorkCategory deletes a category that no work item uses
+func (h *WorkCategoriesAPIHandler) DeleteWorkCategory(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	workCategoryId, err := apiParamId(c, "id", "work category")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		existingWorkCategory, err := h.findOwnWorkCategory(ctx, tx, workCategoryId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.workCategoriesRepo.Delete(ctx, tx, existingWorkCategory); err != nil {
+			// the repository refuses to delete a category that is in use
+			if errors.Is(err, master_work_categories.ErrCategoryInUse) || isConstraintError(err) {
+				return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "Cannot delete this work category because it is used in work items")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to delete work category")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *WorkCategoriesAPIHandler) findWorkCategory(ctx context.Context, tx *sql.Tx, workCategoryId int) (models.MasterWorkCategory, error) {
+	workCategory, err := h.workCategoriesRepo.FindById(ctx, tx, workCategoryId)
+	if err == sql.ErrNoRows {
+		return workCategory, fiber.NewError(fiber.StatusNotFound, "Work category not found")
+	}
+
+	return workCategory, err
+}
+
+func (h *WorkCategoriesAPIHandler) findOwnWorkCategory(ctx context.Context, tx *sql.Tx, workCategoryId int, userData models.SessionUser) (models.MasterWorkCategory, error) {
+	workCategory, err := h.findWorkCategory(ctx, tx, workCategoryId)
+	if err != nil {
+		return workCategory, err
+	}
+
+	if !userData.CanModify(workCategory.UserId, workCategory.OrgId) {
+		return workCategory, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return workCategory, nil
+}
@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+)
+
+type WorkItemsAPIHandler struct {
+	dbService          databases.DatabaseServices
+	projectsRepo       projects.Repository
+	workItemsRepo      project_work_items.Repository
+	itemCostsRepo      project_item_costs.Repository
+	workCategoriesRepo master_work_categories.Repository
+	ahspTemplatesRepo  ahsptemplates.Repository
+	costs              *ProjectWorkItemsHandler // calculates the costs and publishes changes the same way as the project page
+}
+
+func NewWorkItemsAPIHandler(
+	dbService databases.DatabaseServices,
+	projectsRepo projects.Repository,
+	workItemsRepo project_work_items.Repository,
+	itemCostsRepo project_item_costs.Repository,
+	workCategoriesRepo master_work_categories.Repository,
+	ahspTemplatesRepo ahsptemplates.Repository,
+	projectWorkItemsHandler *ProjectWorkItemsHandler,
+) *WorkItemsAPIHandler {
+	return &WorkItemsAPIHandler{
+		dbService:          dbService,
+		projectsRepo:       projectsRepo,
+		workItemsRepo:      workItemsRepo,
+		itemCostsRepo:      itemCostsRepo,
+		workCategoriesRepo: workCategoriesRepo,
+		ahspTemplatesRepo:  ahspTemplatesRepo,
+		costs:              projectWorkItemsHandler,
+	}
+}
+
+// ListWorkItems returns a page of the work items of a project, search matches the description or category
+func (h *WorkItemsAPIHandler) ListWorkItems(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := apiParamId(c, "id", "project")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+	paginationData := apiPaginationData(c)
+
+	var workItems []models.ProjectWorkItemWithDetails
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		workItems, err = h.workItemsRepo.FindByProjectIdWithDetails(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch work items")
+	}
+
+	if search := strings.ToLower(paginationData.Search); search != "" {
+		filtered := []models.ProjectWorkItemWithDetails{}
+		for _, workItem := range workItems {
+			if strings.Contains(strings.ToLower(workItem.Description), search) ||
+				strings.Contains(strings.ToLower(workItem.CategoryName), search) {
+				filtered = append(filtered, workItem)
+			}
+		}
+		workItems = filtered
+	}
+
+	page, paginationInfo := paginateSlice(workItems, paginationData)
+	return apiList(c, page, paginationInfo)
+}
+
+func (h *WorkItemsAPIHandler) GetWorkItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, workItemId, err := apiWorkItemParams(c)
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var workItem models.ProjectWorkItemWithCosts
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		workItem, err = h.findWorkItemWithCosts(ctx, tx, projectId, workItemId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch work item")
+	}
+
+	return apiData(c, fiber.StatusOK, workItem)
+}
+
+// CreateWorkItem creates the work item with its costs, from the AHSP template or the manual costs
+func (h *WorkItemsAPIHandler) CreateWorkItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := apiParamId(c, "id", "project")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var input models.ProjectWorkItemInput
+	if err := apiWorkItemBody(c, &input); err != nil {
+		return apiError(c, err, "")
+	}
+
+	var workItem models.ProjectWorkItemWithCosts
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, err := findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if status, err := checkProjectUnlocked(ctx, tx, h.costs.projectApprovalsRepo, projectId); err != nil {
+			return status, err
+		}
+
+		if err := h.checkReferences(ctx, tx, input, userData); err != nil {
+			return fiber.StatusUnprocessableEntity, err
+		}
+
+		workItemId, err := h.workItemsRepo.Create(ctx, tx, models.ProjectWorkItemCreate{
+			ProjectId:      projectId,
+			CategoryId:     input.CategoryId,
+			Description:    input.Description,
+			Volume:         input.Volume,
+			Unit:           input.Unit,
+			AHSPTemplateId: input.AHSPTemplateId,
+		})
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.createCosts(ctx, tx, input, workItemId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		workItem, err = h.findWorkItemWithCosts(ctx, tx, projectId, workItemId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.costs.publishWorkItemChange(ctx, tx, project.UserId, models.WEBHOOK_ACTION_CREATED, projectId, workItemId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to create work item")
+	}
+
+	return apiData(c, fiber.StatusCreated, workItem)
+}
+
+// UpdateWorkItem replaces the work item and recalculates its costs
+func (h *WorkItemsAPIHandler) UpdateWorkItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, workItemId, err := apiWorkItemParams(c)
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var input models.ProjectWorkItemInput
+	if err := apiWorkItemBody(c, &input); err != nil {
+		return apiError(c, err, "")
+	}
+
+	var workItem models.ProjectWorkItemWithCosts
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, err := findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if status, err := checkProjectUnlocked(ctx, tx, h.costs.projectApprovalsRepo, projectId); err != nil {
+			return status, err
+		}
+
+		existingWorkItem, err := h.findWorkItem(ctx, tx, projectId, workItemId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.checkReferences(ctx, tx, input, userData); err != nil {
+			return fiber.StatusUnprocessableEntity, err
+		}
+
+		if err := h.workItemsRepo.Update(ctx, tx, models.ProjectWorkItem{
+			WorkItemId:     workItemId,
+			ProjectId:      projectId,
+			CategoryId:     input.CategoryId,
+			Description:    input.Description,
+			Volume:         input.Volume,
+			Unit:           input.Unit,
+			AHSPTemplateId: input.AHSPTemplateId,
+			CreatedAt:      existingWorkItem.CreatedAt,
+			UpdatedAt:      models.UTCNow(),
+		}); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.itemCostsRepo.DeleteByWorkItemId(ctx, tx, workItemId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.createCosts(ctx, tx, input, workItemId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		workItem, err = h.findWorkItemWithCosts(ctx, tx, projectId, workItemId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.costs.publishWorkItemChange(ctx, tx, project.UserId, models.WEBHOOK_ACTION_UPDATED, projectId, workItemId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to update work item")
+	}
+
+	return apiData(c, fiber.StatusOK, workItem)
+}
+
+func (h *WorkItemsAPIHandler) DeleteWorkItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, workItemId, err := apiWorkItemParams(c)
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, err := findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if status, err := checkProjectUnlocked(ctx, tx, h.costs.projectApprovalsRepo, projectId); err != nil {
+			return status, err
+		}
+
+		existingWorkItem, err := h.findWorkItem(ctx, tx, projectId, workItemId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.workItemsRepo.Delete(ctx, tx, existingWorkItem); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.costs.publishWorkItemChange(ctx, tx, project.UserId, models.WEBHOOK_ACTION_DELETED, projectId, workItemId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to delete work item")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListProjectItemCosts returns a page of the material and labor cost lines of every work item of a project
+func (h *WorkItemsAPIHandler) ListProjectItemCosts(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := apiParamId(c, "id", "project")
+	if err != nil {
+		return apiError(c, err, "")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+	paginationData := apiPaginationData(c)
+
+	var costs []models.ProjectItemCostWithDetails
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := findOwnedProject(ctx, tx, h.projectsRepo, projectId, userData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		costs, err = h.itemCostsRepo.FindByProjectId(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return apiError(c, err, "Failed to fetch item costs")
+	}
+
+	if search := strings.ToLower(paginationData.Search); search != "" {
+		filtered := []models.ProjectItemCostWithDetails{}
+		for _, cost := range costs {
+			if strings.Contains(strings.ToLower(cost.ItemName), search) {
+				filtered = append(filtered, cost)
+			}
+		}
+		costs = filtered
+	}
+
+	page, paginationInfo := paginateSlice(costs, paginationData)
+	return apiList(c, page, paginationInfo)
+}
+
+// apiWorkItemParams reads the project and work item IDs of the work item routes
+func apiWorkItemParams(c *fiber.Ctx) (int, int, error) {
+	projectId, err := apiParamId(c, "id", "project")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	workItemId, err := apiParamId(c, "workItemId", "work item")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return projectId, workItemId, nil
+}
+
+// apiWorkItemBody parses the work item, a work item without a template needs manual costs
+func apiWorkItemBody(c *fiber.Ctx, input *models.ProjectWorkItemInput) error {
+	if err := apiBody(c, input); err != nil {
+		return err
+	}
+
+	if input.AHSPTemplateId == nil && len(input.ManualCosts) == 0 {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Either ahsp_template_id or manual_costs is required")
+	}
+
+	return nil
+}
+
+// checkReferences makes sure the category exists and the template, if any, is visible to the user
+func (h *WorkItemsAPIHandler) checkReferences(ctx context.Context, tx *sql.Tx, input models.ProjectWorkItemInput, userData models.SessionUser) error {
+	if _, err := h.workCategoriesRepo.FindById(ctx, tx, input.CategoryId); err != nil {
+		if err == sql.ErrNoRows {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, "Work category not found")
+		}
+		return err
+	}
+
+	if input.AHSPTemplateId != nil {
+		template, err := h.ahspTemplatesRepo.FindById(ctx, tx, *input.AHSPTemplateId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fiber.NewError(fiber.StatusUnprocessableEntity, "AHSP template not found")
+			}
+			return err
+		}
+
+		if !userData.CanView(template.UserId, template.OrgId) {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, "AHSP template not found")
+		}
+	}
+
+	return nil
+}
+
+func (h *WorkItemsAPIHandler) createCosts(ctx context.Context, tx *sql.Tx, input models.ProjectWorkItemInput, workItemId int) error {
+	if input.AHSPTemplateId != nil {
+		if err := h.costs.calculateAndCreateCosts(ctx, tx, *input.AHSPTemplateId, input.Volume, workItemId); err != nil {
+			return fmt.Errorf("cost calculation failed: %w", err)
+		}
+		return nil
+	}
+
+	return h.costs.createManualCosts(ctx, tx, workItemId, input.Volume, input.ManualCosts)
+}
+
+// findWorkItem returns the work item when it belongs to the project
+func (h *WorkItemsAPIHandler) findWorkItem(ctx context.Context, tx *sql.Tx, projectId, workItemId int) (models.ProjectWorkItem, error) {
+	workItem, err := h.workItemsRepo.FindById(ctx, tx, workItemId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return workItem, fiber.NewError(fiber.StatusNotFound, "Work item not found")
+		}
+		return workItem, err
+	}
+
+	if workItem.ProjectId != projectId {
+		return workItem, fiber.NewError(fiber.StatusNotFound, "Work item not found")
+	}
+
+	return workItem, nil
+}
+
+func (h *WorkItemsAPIHandler) findWorkItemWithCosts(ctx context.Context, tx *sql.Tx, projectId, workItemId int) (models.ProjectWorkItemWithCosts, error) {
+	workItem, err := h.findWorkItem(ctx, tx, projectId, workItemId)
+	if err != nil {
+		return models.ProjectWorkItemWithCosts{}, err
+	}
+
+	costs, err := h.itemCostsRepo.FindByWorkItemId(ctx, tx, workItemId)
+	if err != nil {
+		return models.ProjectWorkItemWithCosts{}, err
+	}
+	if costs == nil {
+		costs = []models.ProjectItemCostWithDetails{}
+	}
+
+	return models.ProjectWorkItemWithCosts{ProjectWorkItem: workItem, Costs: costs}, nil
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/audit_log"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// AUDIT_PAGE_SIZE is how many entries the audit pages show at once
+const AUDIT_PAGE_SIZE = 25
+
+// AuditLogHandler shows the audit trail the repositories record: all of it to administrators,
+// the history of a project to anyone who can see the project
+type AuditLogHandler struct {
+	dbService          databases.DatabaseServices
+	auditLogRepo       audit_log.Repository
+	projectsRepo       projects.Repository
+	projectMembersRepo project_members.Repository
+}
+
+func NewAuditLogHandler(
+	dbService databases.DatabaseServices,
+	auditLogRepo audit_log.Repository,
+	projectsRepo projects.Repository,
+	projectMembersRepo project_members.Repository,
+) *AuditLogHandler {
+	return &AuditLogHandler{
+		dbService:          dbService,
+		auditLogRepo:       auditLogRepo,
+		projectsRepo:       projectsRepo,
+		projectMembersRepo: projectMembersRepo,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+// AuditLogView lists the entries matching the filters of the query string, newest first
+func (h *AuditLogHandler) AuditLogView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	var entries []models.AuditEntry
+	var pagination models.PaginationInfo
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		entries, pagination, err = h.auditLogRepo.Find(ctx, tx, filter, auditPagination(c))
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to load the audit log")
+	}
+
+	page := components.AuditLogPage(entries, pagination, filter)
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+// ProjectHistoryView is the history tab of a project, the changes to the project, its work
+// items and their costs. The entity query parameter narrows it to one of them.
+func (h *AuditLogHandler) ProjectHistoryView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	filter := models.AuditFilter{ProjectId: projectId, Entity: c.Query("entity")}
+
+	var entries []models.AuditEntry
+	var pagination models.PaginationInfo
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		if status, err := h.checkProjectViewer(c, tx, projectId, userData); err != nil {
+			return status, err
+		}
+
+		entries, pagination, err = h.auditLogRepo.Find(ctx, tx, filter, auditPagination(c))
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to fetch the project history")
+	}
+
+	history := components.ProjectHistory(projectId, entries, pagination, filter.Entity)
+	return adaptor.HTTPHandler(templ.Handler(history))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// ExportAuditLog downloads the entries matching the filters as csv or excel (format query
+// parameter), with the rows before and after each change as JSON
+func (h *AuditLogHandler) ExportAuditLog(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	format := c.Query("format", "excel")
+	if format != "csv" && format != "excel" {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid format. Use 'csv' or 'excel'")
+	}
+
+	var entries []models.AuditEntry
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		entries, err = h.auditLogRepo.FindAll(ctx, tx, filter, models.AUDIT_EXPORT_LIMIT)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Export failed")
+	}
+
+	return exportAuditEntries(c, entries, "audit-log", format)
+}
+
+// ExportProjectHistory downloads the history of a project as csv or excel
+func (h *AuditLogHandler) ExportProjectHistory(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid project ID")
+	}
+
+	format := c.Query("format", "excel")
+	if format != "csv" && format != "excel" {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid format. Use 'csv' or 'excel'")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var entries []models.AuditEntry
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		if status, err := h.checkProjectViewer(c, tx, projectId, userData); err != nil {
+			return status, err
+		}
+
+		entries, err = h.auditLogRepo.FindAll(ctx, tx, models.AuditFilter{ProjectId: projectId}, models.AUDIT_EXPORT_LIMIT)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).SendString(fiberErr.Message)
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString("Export failed")
+	}
+
+	return exportAuditEntries(c, entries, "project-"+strconv.Itoa(projectId)+"-history", format)
+}
+
+// checkProjectViewer refuses the history of a project the user cannot see
+func (h *AuditLogHandler) checkProjectViewer(c *fiber.Ctx, tx *sql.Tx, projectId int, userData models.SessionUser) (int, error) {
+	ctx := c.UserContext()
+
+	project, err := h.projectsRepo.FindById(ctx, tx, projectId)
+	if err != nil && err != sql.ErrNoRows {
+		return fiber.StatusInternalServerError, err
+	}
+	if project.ProjectId == 0 {
+		return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
+	}
+
+	return checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_VIEWER)
+}
+
+// auditFilterFromQuery reads the filters of the audit page, the dates must be YYYY-MM-DD
+func auditFilterFromQuery(c *fiber.Ctx) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Entity:   c.Query("entity"),
+		Action:   c.Query("action"),
+		Username: strings.TrimSpace(c.Query("username")),
+		DateFrom: c.Query("date_from"),
+		DateTo:   c.Query("date_to"),
+	}
+
+	if projectId := c.Query("project_id"); projectId != "" {
+		id, err := strconv.Atoi(projectId)
+		if err != nil || id <= 0 {
+			return filter, fiber.NewError(fiber.StatusBadRequest, "Invalid project ID")
+		}
+		filter.ProjectId = id
+	}
+
+	for _, date := range []string{filter.DateFrom, filter.DateTo} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			return filter, fiber.NewError(fiber.StatusBadRequest, "Invalid date "+date+", use YYYY-MM-DD")
+		}
+	}
+
+	return filter, nil
+}
+
+// auditPagination is the page of the query string, AUDIT_PAGE_SIZE entries each
+func auditPagination(c *fiber.Ctx) models.TablePaginationDataInput {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	return models.TablePaginationDataInput{Page: page, PerPage: AUDIT_PAGE_SIZE}
+}
+
+// exportAuditEntries sends the entries as a csv or excel file named filename
+func exportAuditEntries(c *fiber.Ctx, entries []models.AuditEntry, filename, format string) error {
+	headers := []string{"Time (UTC)", "User", "Action", "Entity", "Entity ID", "Name", "Project ID", "Before", "After"}
+
+	if format == "csv" {
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		if err := writer.Write(headers); err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			projectId := ""
+			if entry.ProjectId != 0 {
+				projectId = strconv.Itoa(entry.ProjectId)
+			}
+
+			if err := writer.Write([]string{
+				entry.CreatedAt,
+				entry.Username,
+				entry.Action,
+				entry.Entity,
+				strconv.Itoa(entry.EntityId),
+				entry.Name(),
+				projectId,
+				entry.BeforeJSON,
+				entry.AfterJSON,
+			}); err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+
+		c.Set("Content-Type", "text/csv; charset=utf-8")
+		c.Set("Content-Disposition", "attachment; filename="+filename+".csv")
+		return c.Send(buf.Bytes())
+	}
+
+	var rows [][]interface{}
+	for _, entry := range entries {
+		var projectId interface{} = ""
+		if entry.ProjectId != 0 {
+			projectId = entry.ProjectId
+		}
+
+		rows = append(rows, []interface{}{
+			entry.CreatedAt,
+			entry.Username,
+			entry.Action,
+			entry.EntityLabel(),
+			entry.EntityId,
+			entry.Name(),
+			projectId,
+			entry.BeforeJSON,
+			entry.AfterJSON,
+		})
+	}
+
+	excel := utils.NewExcelExporter()
+	if err := excel.AddSheetWithOptions("Audit Log", headers, rows, utils.ExcelSheetOptions{
+		Title:        "Audit Log",
+		ColumnWidths: []float64{20, 16, 10, 22, 10, 32, 10, 60, 60},
+		FreezeHeader: true,
+	}); err != nil {
+		return err
+	}
+
+	excelData, err := excel.Write()
+	if err != nil {
+		return err
+	}
+
+	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Set("Content-Disposition", "attachment; filename="+filename+".xlsx")
+	return c.Send(excelData)
+}
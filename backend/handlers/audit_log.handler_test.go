@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// TestProjectHistory verifies anyone who can see a project reads and exports its history,
+// narrowed to an entity, while others cannot
+func TestProjectHistory(t *testing.T) {
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	membersRepo := newFakeProjectMembersRepo(models.ProjectMember{ProjectId: 1, UserId: 9, Role: models.PROJECT_ROLE_VIEWER})
+	auditLogRepo := newFakeAuditLogRepo(
+		models.AuditEntry{AuditId: 3, Username: "budi", Entity: models.AUDIT_ENTITY_WORK_ITEM, EntityId: 4, ProjectId: 1, Action: models.AUDIT_ACTION_UPDATE, BeforeJSON: `{"description":"Pasangan bata","volume":10}`, AfterJSON: `{"description":"Pasangan bata","volume":12.5}`},
+		models.AuditEntry{AuditId: 2, Entity: models.AUDIT_ENTITY_PROJECT, EntityId: 2, ProjectId: 2, Action: models.AUDIT_ACTION_CREATE, AfterJSON: `{"project_name":"Gudang"}`},
+		models.AuditEntry{AuditId: 1, Username: "budi", Entity: models.AUDIT_ENTITY_PROJECT, EntityId: 1, ProjectId: 1, Action: models.AUDIT_ACTION_CREATE, AfterJSON: `{"project_name":"Rumah Tinggal"}`},
+	)
+	handler := NewAuditLogHandler(&fakeDatabase{}, auditLogRepo, projectsRepo, membersRepo)
+
+	viewer := newTestApp(9)
+	viewer.Get("/project/:id/history", handler.ProjectHistoryView)
+	viewer.Get("/project/:id/history/export", handler.ExportProjectHistory)
+
+	body := responseBody(t, doRequest(t, viewer, http.MethodGet, "/project/1/history", nil))
+	if !strings.Contains(body, "Pasangan bata") || !strings.Contains(body, "12.5") || !strings.Contains(body, "Rumah Tinggal") {
+		t.Errorf("Expected the changes of the project, got %s", body)
+	}
+	if strings.Contains(body, "Gudang") {
+		t.Errorf("Expected no changes of other projects, got %s", body)
+	}
+
+	body = responseBody(t, doRequest(t, viewer, http.MethodGet, "/project/1/history?entity=project", nil))
+	if strings.Contains(body, "Pasangan bata") || !strings.Contains(body, "Rumah Tinggal") {
+		t.Errorf("Expected only the changes of the project row, got %s", body)
+	}
+
+	resp := doRequest(t, viewer, http.MethodGet, "/project/1/history/export?format=csv", nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Disposition"), "project-1-history.csv") {
+		t.Fatalf("Expected a csv download, got %d %v", resp.StatusCode, resp.Header)
+	}
+	records, err := csv.NewReader(strings.NewReader(responseBody(t, resp))).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read csv: %v", err)
+	}
+	if len(records) != 3 || records[1][2] != models.AUDIT_ACTION_UPDATE || records[1][5] != "Pasangan bata" || records[2][1] != "budi" {
+		t.Errorf("Unexpected export: %v", records)
+	}
+
+	if resp := doRequest(t, viewer, http.MethodGet, "/project/1/history/export?format=pdf", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an unknown format to be refused, got %d", resp.StatusCode)
+	}
+
+	stranger := newTestApp(8)
+	stranger.Get("/project/:id/history", handler.ProjectHistoryView)
+	stranger.Get("/project/:id/history/export", handler.ExportProjectHistory)
+
+	if body := responseBody(t, doRequest(t, stranger, http.MethodGet, "/project/1/history", nil)); !strings.Contains(body, "Access denied") || strings.Contains(body, "Pasangan bata") {
+		t.Errorf("Expected another user to be refused, got %s", body)
+	}
+	if resp := doRequest(t, stranger, http.MethodGet, "/project/1/history/export?format=csv", nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected another user's export to be refused, got %d", resp.StatusCode)
+	}
+	if body := responseBody(t, doRequest(t, viewer, http.MethodGet, "/project/5/history", nil)); !strings.Contains(body, "Project not found") {
+		t.Errorf("Expected a missing project to be not found, got %s", body)
+	}
+}
+
+// TestAuditLogView_Filters verifies the filters of the audit page reach the repository and
+// malformed ones are refused
+func TestAuditLogView_Filters(t *testing.T) {
+	auditLogRepo := newFakeAuditLogRepo()
+	handler := NewAuditLogHandler(&fakeDatabase{}, auditLogRepo, newFakeProjectsRepo(), newFakeProjectMembersRepo())
+
+	app := newTestAppAs(1, models.ROLE_ADMIN)
+	app.Get("/admin/audit", handler.AuditLogView)
+	app.Get("/admin/audit/export", handler.ExportAuditLog)
+
+	if resp := doRequest(t, app, http.MethodGet, "/admin/audit?entity=material&action=delete&username=+budi+&project_id=3&date_from=2024-01-01&date_to=2024-01-31", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the audit page, got %d", resp.StatusCode)
+	}
+	want := models.AuditFilter{Entity: "material", Action: "delete", Username: "budi", ProjectId: 3, DateFrom: "2024-01-01", DateTo: "2024-01-31"}
+	if len(auditLogRepo.filters) != 1 || auditLogRepo.filters[0] != want {
+		t.Errorf("Expected filter %+v, got %+v", want, auditLogRepo.filters)
+	}
+
+	for _, target := range []string{
+		"/admin/audit?date_from=01-02-2024",
+		"/admin/audit?project_id=abc",
+		"/admin/audit/export?date_to=2024-13-01",
+	} {
+		if resp := doRequest(t, app, http.MethodGet, target, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, resp.StatusCode)
+		}
+	}
+
+	resp := doRequest(t, app, http.MethodGet, "/admin/audit/export", nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Disposition"), "audit-log.xlsx") {
+		t.Errorf("Expected an excel download by default, got %d %v", resp.StatusCode, resp.Header)
+	}
+}
@@ -16,14 +16,17 @@ import (
 )
 
 type AuthHandler struct {
-	dbService databases.SQLiteServices
-	usersRepo *users.UsersRepo
+	dbService databases.DatabaseServices
+	usersRepo users.Repository
 }
 
-func NewAuthHandler(dbService databases.SQLiteServices) *AuthHandler {
+func NewAuthHandler(
+	dbService databases.DatabaseServices,
+	usersRepo users.Repository,
+) *AuthHandler {
 	return &AuthHandler{
 		dbService: dbService,
-		usersRepo: users.NewUsersRepo(),
+		usersRepo: usersRepo,
 	}
 }
 
@@ -34,6 +37,7 @@ func (h *AuthHandler) LoginView(c *fiber.Ctx) error {
 
 // Login handles user authentication
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	username := c.FormValue("username")
 	password := c.FormValue("password")
@@ -47,8 +51,8 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var err error
 
 	// Find user in database
-	if _, err = h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		user, err = h.usersRepo.FindByUsername(tx, username)
+	if _, err = h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		user, err = h.usersRepo.FindByUsername(ctx, tx, username)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusUnauthorized, fiber.NewError(fiber.StatusUnauthorized, "Invalid username or password")
@@ -98,6 +102,7 @@ func (h *AuthHandler) RegisterView(c *fiber.Ctx) error {
 
 // Register handles user registration
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	username := c.FormValue("username")
 	password := c.FormValue("password")
@@ -129,9 +134,9 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	}
 
 	// Check if username already exists and create user
-	_, err = h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	_, err = h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Check if user already exists
-		_, err := h.usersRepo.FindByUsername(tx, username)
+		_, err := h.usersRepo.FindByUsername(ctx, tx, username)
 		if err == nil {
 			return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "Username already exists")
 		}
@@ -140,7 +145,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		}
 
 		// Create new user
-		if err := h.usersRepo.Create(tx, userData); err != nil {
+		if err := h.usersRepo.Create(ctx, tx, userData); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
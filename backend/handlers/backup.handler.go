@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// BackupHandler lets administrators back up and restore the whole database
+type BackupHandler struct {
+	dbService databases.DatabaseServices
+	scheduler *databases.BackupScheduler
+}
+
+func NewBackupHandler(
+	dbService databases.DatabaseServices,
+	scheduler *databases.BackupScheduler,
+) *BackupHandler {
+	return &BackupHandler{
+		dbService: dbService,
+		scheduler: scheduler,
+	}
+}
+
+// backupsSupported is false on PostgreSQL, its backups are taken with pg_dump outside the application
+func (h *BackupHandler) backupsSupported() bool {
+	return h.dbService.Dialect() == databases.DIALECT_SQLITE
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+func (h *BackupHandler) BackupsView(c *fiber.Ctx) error {
+	if !h.backupsSupported() {
+		page := components.BackupsUnsupportedPage(string(h.dbService.Dialect()))
+		return adaptor.HTTPHandler(templ.Handler(page))(c)
+	}
+
+	backups, err := databases.ListBackups(h.dbService.BackupDir())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to list backups")
+	}
+
+	page := components.BackupsPage(backups, h.dbService.BackupDir(), h.scheduler.Status())
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+func (h *BackupHandler) RestoreBackupModalView(c *fiber.Ctx) error {
+	if !h.backupsSupported() {
+		return utils.ResponseErrorModal(c, "Not Supported", databases.ErrBackupUnsupported.Error())
+	}
+
+	backup, err := databases.FindBackup(h.dbService.BackupDir(), c.Params("name"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Not Found", "Backup not found")
+	}
+
+	info, err := databases.CheckBackup(backup.Path)
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Invalid Backup", err.Error())
+	}
+
+	modal := components.BackupRestoreModal(backup, info)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+func (h *BackupHandler) UploadBackupModalView(c *fiber.Ctx) error {
+	modal := components.BackupUploadModal()
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// CreateBackup writes a snapshot of the live database to the backup directory
+func (h *BackupHandler) CreateBackup(c *fiber.Ctx) error {
+	name := databases.NewBackupFileName(databases.BACKUP_PREFIX_MANUAL)
+
+	// c.Context is not bound by REQUEST_TIMEOUT, a large database may take longer to copy
+	if err := h.dbService.Backup(c.Context(), filepath.Join(h.dbService.BackupDir(), name)); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Backup failed: "+err.Error())
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Backup Created", "The database was saved as "+name, "/admin/backups")
+}
+
+func (h *BackupHandler) DownloadBackup(c *fiber.Ctx) error {
+	if !h.backupsSupported() {
+		return c.Status(fiber.StatusNotFound).SendString("Backup not found")
+	}
+
+	backup, err := databases.FindBackup(h.dbService.BackupDir(), c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString("Backup not found")
+	}
+
+	return c.Download(backup.Path, backup.Name)
+}
+
+// RestoreBackup replaces the live database with a backup from the list
+func (h *BackupHandler) RestoreBackup(c *fiber.Ctx) error {
+	backup, err := databases.FindBackup(h.dbService.BackupDir(), c.Params("name"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Not Found", "Backup not found")
+	}
+
+	result, err := h.dbService.Restore(c.Context(), backup.Path)
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Restore Failed", err.Error())
+	}
+
+	message := fmt.Sprintf(
+		"The database was restored from %s. The previous database was saved as %s.",
+		backup.Name,
+		filepath.Base(result.SafetyCopy),
+	)
+
+	return utils.ResponseSuccessWithRedirect(c, "Backup Restored", message, "/admin/backups")
+}
+
+// UploadBackup checks an uploaded backup and adds it to the backup list without restoring it
+func (h *BackupHandler) UploadBackup(c *fiber.Ctx) error {
+	if !h.backupsSupported() {
+		return utils.ResponseErrorModal(c, "Not Supported", databases.ErrBackupUnsupported.Error())
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Upload Error", "Choose a backup file to upload")
+	}
+
+	backupDir := h.dbService.BackupDir()
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to create backup directory")
+	}
+
+	name := databases.NewBackupFileName(databases.BACKUP_PREFIX_UPLOADED)
+
+	// saved under a temporary name so a rejected file never shows up in the list
+	tmpPath := filepath.Join(backupDir, name+".upload")
+	if err := c.SaveFile(fileHeader, tmpPath); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to save uploaded file")
+	}
+	defer os.Remove(tmpPath)
+
+	if _, err := databases.CheckBackup(tmpPath); err != nil {
+		return utils.ResponseErrorModal(c, "Invalid Backup", err.Error())
+	}
+
+	if databases.IsCompressedBackup(tmpPath) {
+		name += databases.BACKUP_GZIP_SUFFIX
+	}
+	path := filepath.Join(backupDir, name)
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to save uploaded file")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Backup Uploaded", "The backup was added as "+name+", restore it from the list when ready.", "/admin/backups")
+}
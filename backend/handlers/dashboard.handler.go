@@ -16,15 +16,15 @@ import (
 )
 
 type DashboardHandler struct {
-	dbService     databases.SQLiteServices
-	dashboardRepo dashboard.DashboardRepo
-	projectsRepo  projects.ProjectsRepo
+	dbService     databases.DatabaseServices
+	dashboardRepo dashboard.Repository
+	projectsRepo  projects.Repository
 }
 
 func NewDashboardHandler(
-	dbService databases.SQLiteServices,
-	dashboardRepo dashboard.DashboardRepo,
-	projectsRepo projects.ProjectsRepo,
+	dbService databases.DatabaseServices,
+	dashboardRepo dashboard.Repository,
+	projectsRepo projects.Repository,
 ) *DashboardHandler {
 	return &DashboardHandler{
 		dbService:     dbService,
@@ -35,6 +35,8 @@ func NewDashboardHandler(
 
 // Dashboard displays the main dashboard with project overview
 func (h *DashboardHandler) Dashboard(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	var enhancedProjects []models.EnhancedProjectData
 	var totalProjects int
 	var totalCost float64
@@ -46,10 +48,10 @@ func (h *DashboardHandler) Dashboard(c *fiber.Ctx) error {
 	// Get user from session
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
-	// Use the database service transaction
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	// Read-only, so it uses the read pool and does not wait for writers
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get enhanced recent projects for the user
-		enhancedProjectsData, err := h.dashboardRepo.GetEnhancedRecentProjects(tx, userData.ID, 10)
+		enhancedProjectsData, err := h.dashboardRepo.GetEnhancedRecentProjects(ctx, tx, userData.Workspace(), 10)
 		if err != nil {
 			enhancedProjects = []models.EnhancedProjectData{}
 		} else {
@@ -57,7 +59,7 @@ func (h *DashboardHandler) Dashboard(c *fiber.Ctx) error {
 		}
 
 		// Get total projects count
-		totalProjectsData, err := h.dashboardRepo.GetProjectCount(tx, userData.ID)
+		totalProjectsData, err := h.dashboardRepo.GetProjectCount(ctx, tx, userData.Workspace())
 		if err != nil {
 			totalProjects = 0
 		} else {
@@ -65,7 +67,7 @@ func (h *DashboardHandler) Dashboard(c *fiber.Ctx) error {
 		}
 
 		// Get total work items count
-		workItemsData, err := h.dashboardRepo.GetWorkItemsCount(tx, userData.ID)
+		workItemsData, err := h.dashboardRepo.GetWorkItemsCount(ctx, tx, userData.Workspace())
 		if err != nil {
 			totalWorkItems = 0
 		} else {
@@ -73,7 +75,7 @@ func (h *DashboardHandler) Dashboard(c *fiber.Ctx) error {
 		}
 
 		// Get total cost of all projects
-		totalCostData, err := h.dashboardRepo.GetProjectsTotalCost(tx, userData.ID)
+		totalCostData, err := h.dashboardRepo.GetProjectsTotalCost(ctx, tx, userData.Workspace())
 		if err != nil {
 			totalCost = 0
 		} else {
@@ -81,7 +83,7 @@ func (h *DashboardHandler) Dashboard(c *fiber.Ctx) error {
 		}
 
 		// Get cost breakdown by type (Material vs Labor)
-		typeCostData, err := h.dashboardRepo.GetTypeCostBreakdown(tx, userData.ID)
+		typeCostData, err := h.dashboardRepo.GetTypeCostBreakdown(ctx, tx, userData.Workspace())
 		if err != nil {
 			typeCostBreakdown = []models.TypeCostBreakdown{}
 		} else {
@@ -89,7 +91,7 @@ func (h *DashboardHandler) Dashboard(c *fiber.Ctx) error {
 		}
 
 		// Get category breakdown (top 5)
-		categoryData, err := h.dashboardRepo.GetCategoryBreakdown(tx, userData.ID, 5)
+		categoryData, err := h.dashboardRepo.GetCategoryBreakdown(ctx, tx, userData.Workspace(), 5)
 		if err != nil {
 			categoryBreakdown = []models.CategoryBreakdown{}
 		} else {
@@ -97,7 +99,7 @@ func (h *DashboardHandler) Dashboard(c *fiber.Ctx) error {
 		}
 
 		// Get top 10 expensive items
-		topItemsData, err := h.dashboardRepo.GetTopExpensiveItems(tx, userData.ID, 10)
+		topItemsData, err := h.dashboardRepo.GetTopExpensiveItems(ctx, tx, userData.Workspace(), 10)
 		if err != nil {
 			topExpensiveItems = []models.TopExpensiveItem{}
 		} else {
@@ -0,0 +1,829 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+)
+
+// fakeDatabase runs transaction functions with a nil *sql.Tx, the fake repositories never use it
+type fakeDatabase struct {
+	transactions     int
+	readTransactions int
+}
+
+func (f *fakeDatabase) GetDB() *databases.DB { return nil }
+
+func (f *fakeDatabase) Dialect() databases.Dialect { return databases.DIALECT_SQLITE }
+
+func (f *fakeDatabase) Transaction(ctx context.Context, fn func(tx *sql.Tx) (int, error)) (int, error) {
+	f.transactions++
+	if statusCode, err := fn(nil); err != nil {
+		return statusCode, err
+	}
+
+	return http.StatusAccepted, nil
+}
+
+func (f *fakeDatabase) ReadTransaction(ctx context.Context, fn func(tx *sql.Tx) (int, error)) (int, error) {
+	f.readTransactions++
+	if statusCode, err := fn(nil); err != nil {
+		return statusCode, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func (f *fakeDatabase) BackupDir() string { return "" }
+
+func (f *fakeDatabase) Backup(ctx context.Context, destPath string) error {
+	return errors.New("not supported by the fake database")
+}
+
+func (f *fakeDatabase) Restore(ctx context.Context, srcPath string) (databases.RestoreResult, error) {
+	return databases.RestoreResult{}, errors.New("not supported by the fake database")
+}
+
+// fakePublisher records the webhook events handlers publish
+type fakePublisher struct {
+	events  []string
+	data    []interface{}
+	userIds []int
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, tx *sql.Tx, userId int, event string, data interface{}) error {
+	p.events = append(p.events, event)
+	p.userIds = append(p.userIds, userId)
+	p.data = append(p.data, data)
+	return nil
+}
+
+// fakeMaterialsRepo keeps materials in a map, the deleted ones in trashed, and remembers the
+// last context it was called with
+type fakeMaterialsRepo struct {
+	materials map[int]models.MasterMaterial
+	trashed   map[int]models.MasterMaterial
+	nextId    int
+	lastCtx   context.Context
+	lastFind  models.TablePaginationDataInput
+}
+
+func newFakeMaterialsRepo(materials ...models.MasterMaterial) *fakeMaterialsRepo {
+	repo := &fakeMaterialsRepo{materials: map[int]models.MasterMaterial{}, trashed: map[int]models.MasterMaterial{}, nextId: 1}
+	for _, material := range materials {
+		repo.materials[material.MaterialId] = material
+		if material.MaterialId >= repo.nextId {
+			repo.nextId = material.MaterialId + 1
+		}
+	}
+
+	return repo
+}
+
+func (r *fakeMaterialsRepo) FindById(ctx context.Context, tx *sql.Tx, masterMaterialId int) (models.MasterMaterial, error) {
+	r.lastCtx = ctx
+	material, ok := r.materials[masterMaterialId]
+	if !ok {
+		return material, sql.ErrNoRows
+	}
+
+	return material, nil
+}
+
+func (r *fakeMaterialsRepo) FindByName(ctx context.Context, tx *sql.Tx, materialName string, workspace models.Workspace) (models.MasterMaterial, error) {
+	r.lastCtx = ctx
+	for _, material := range r.materials {
+		if strings.EqualFold(material.MaterialName, materialName) &&
+			visibleInWorkspace(workspace, material.UserId, material.OrgId) {
+			return material, nil
+		}
+	}
+
+	return models.MasterMaterial{}, sql.ErrNoRows
+}
+
+func (r *fakeMaterialsRepo) FindByNameAndUnit(ctx context.Context, tx *sql.Tx, materialName, unit string, workspace models.Workspace) (models.MasterMaterial, error) {
+	r.lastCtx = ctx
+	for _, material := range r.materials {
+		if strings.EqualFold(material.MaterialName, materialName) && strings.EqualFold(material.Unit, unit) &&
+			visibleInWorkspace(workspace, material.UserId, material.OrgId) {
+			return material, nil
+		}
+	}
+
+	return models.MasterMaterial{}, sql.ErrNoRows
+}
+
+func (r *fakeMaterialsRepo) Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput, workspace models.Workspace) ([]models.MasterMaterial, models.PaginationInfo, error) {
+	r.lastCtx = ctx
+	r.lastFind = paginationInput
+
+	materials := []models.MasterMaterial{}
+	for _, material := range r.materials {
+		if visibleInWorkspace(workspace, material.UserId, material.OrgId) {
+			materials = append(materials, material)
+		}
+	}
+	sort.Slice(materials, func(i, j int) bool { return materials[i].MaterialId < materials[j].MaterialId })
+
+	return materials, models.PaginationInfo{
+		TotalItems:   len(materials),
+		ItemsPerPage: paginationInput.PerPage,
+		CurrentPage:  paginationInput.Page,
+		TotalPages:   1,
+	}, nil
+}
+
+func (r *fakeMaterialsRepo) Create(ctx context.Context, tx *sql.Tx, materialData models.MasterMaterialCreate) error {
+	r.lastCtx = ctx
+	r.materials[r.nextId] = models.MasterMaterial{
+		MaterialId:       r.nextId,
+		UserId:           materialData.UserId,
+		OrgId:            materialData.OrgId,
+		MaterialName:     materialData.MaterialName,
+		Unit:             materialData.Unit,
+		DefaultUnitPrice: materialData.DefaultUnitPrice,
+		IsEquipment:      materialData.IsEquipment,
+	}
+	r.nextId++
+
+	return nil
+}
+
+func (r *fakeMaterialsRepo) Update(ctx context.Context, tx *sql.Tx, materialData models.MasterMaterial) error {
+	r.lastCtx = ctx
+	r.materials[materialData.MaterialId] = materialData
+
+	return nil
+}
+
+func (r *fakeMaterialsRepo) Delete(ctx context.Context, tx *sql.Tx, materialData models.MasterMaterial) error {
+	r.lastCtx = ctx
+	if material, ok := r.materials[materialData.MaterialId]; ok {
+		r.trashed[material.MaterialId] = material
+		delete(r.materials, material.MaterialId)
+	}
+
+	return nil
+}
+
+func (r *fakeMaterialsRepo) Restore(ctx context.Context, tx *sql.Tx, materialId int) error {
+	r.lastCtx = ctx
+	if material, ok := r.trashed[materialId]; ok {
+		r.materials[materialId] = material
+		delete(r.trashed, materialId)
+	}
+
+	return nil
+}
+
+func (r *fakeMaterialsRepo) Purge(ctx context.Context, tx *sql.Tx, materialId int) error {
+	r.lastCtx = ctx
+	delete(r.trashed, materialId)
+
+	return nil
+}
+
+// fakeProjectsRepo keeps projects in a map, the deleted ones in trashed
+type fakeProjectsRepo struct {
+	projects map[int]models.Project
+	trashed  map[int]models.Project
+}
+
+func newFakeProjectsRepo(projects ...models.Project) *fakeProjectsRepo {
+	repo := &fakeProjectsRepo{projects: map[int]models.Project{}, trashed: map[int]models.Project{}}
+	for _, project := range projects {
+		repo.projects[project.ProjectId] = project
+	}
+
+	return repo
+}
+
+// FindById returns the zero project when nothing matches, like the repository
+func (r *fakeProjectsRepo) FindById(ctx context.Context, tx *sql.Tx, projectId int) (models.Project, error) {
+	return r.projects[projectId], nil
+}
+
+func (r *fakeProjectsRepo) Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput) ([]models.Project, models.PaginationInfo, error) {
+	return r.filter(paginationInput, func(project models.Project) bool { return true })
+}
+
+func (r *fakeProjectsRepo) FindByWorkspace(ctx context.Context, tx *sql.Tx, workspace models.Workspace, paginationInput models.TablePaginationDataInput) ([]models.Project, models.PaginationInfo, error) {
+	return r.filter(paginationInput, func(project models.Project) bool {
+		if workspace.OrgId != 0 {
+			return project.OrgId == workspace.OrgId
+		}
+		return project.OrgId == 0 && project.UserId == workspace.UserId
+	})
+}
+
+func (r *fakeProjectsRepo) filter(paginationInput models.TablePaginationDataInput, keep func(models.Project) bool) ([]models.Project, models.PaginationInfo, error) {
+	projects := []models.Project{}
+	for _, project := range r.projects {
+		if keep(project) {
+			projects = append(projects, project)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ProjectId < projects[j].ProjectId })
+
+	return projects, models.PaginationInfo{TotalItems: len(projects), ItemsPerPage: paginationInput.PerPage, CurrentPage: paginationInput.Page, TotalPages: 1}, nil
+}
+
+func (r *fakeProjectsRepo) Create(ctx context.Context, tx *sql.Tx, projectData models.ProjectCreate) (int, error) {
+	projectId := len(r.projects) + 1
+	r.projects[projectId] = models.Project{
+		ProjectId:   projectId,
+		UserId:      projectData.UserId,
+		OrgId:       projectData.OrgId,
+		ProjectName: projectData.ProjectName,
+		Location:    projectData.Location,
+		ClientName:  projectData.ClientName,
+	}
+
+	return projectId, nil
+}
+
+func (r *fakeProjectsRepo) Update(ctx context.Context, tx *sql.Tx, projectData models.Project) error {
+	r.projects[projectData.ProjectId] = projectData
+	return nil
+}
+
+func (r *fakeProjectsRepo) Delete(ctx context.Context, tx *sql.Tx, projectData models.Project) error {
+	if project, ok := r.projects[projectData.ProjectId]; ok {
+		r.trashed[project.ProjectId] = project
+		delete(r.projects, project.ProjectId)
+	}
+	return nil
+}
+
+func (r *fakeProjectsRepo) Restore(ctx context.Context, tx *sql.Tx, projectId int) error {
+	if project, ok := r.trashed[projectId]; ok {
+		r.projects[projectId] = project
+		delete(r.trashed, projectId)
+	}
+	return nil
+}
+
+func (r *fakeProjectsRepo) Purge(ctx context.Context, tx *sql.Tx, projectId int) error {
+	delete(r.trashed, projectId)
+	return nil
+}
+
+// visibleInWorkspace mirrors models.Workspace.MasterDataCondition for the fake repositories
+func visibleInWorkspace(workspace models.Workspace, userId, orgId int) bool {
+	if userId == 0 && orgId == 0 {
+		return true
+	}
+	if workspace.OrgId != 0 {
+		return orgId == workspace.OrgId
+	}
+
+	return orgId == 0 && userId == workspace.UserId
+}
+
+type testContextKey struct{}
+
+// newTestApp returns an app whose requests are made by userId as an estimator, the way
+// IsAuth leaves them. The user context carries testContextKey so tests can check it reaches
+// the repositories.
+func newTestApp(userId int) *fiber.App {
+	return newTestAppAs(userId, models.ROLE_ESTIMATOR)
+}
+
+// newTestAppAs is newTestApp for a user with another role
+func newTestAppAs(userId int, role string) *fiber.App {
+	return newTestAppFor(models.SessionUser{ID: userId, Role: role})
+}
+
+// newTestAppFor is newTestApp for any session user, e.g. one working in an organization
+func newTestAppFor(user models.SessionUser) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middlewares.SESSION_USER_NAME, user)
+		c.SetUserContext(context.WithValue(c.UserContext(), testContextKey{}, "request"))
+		return c.Next()
+	})
+
+	return app
+}
+
+// doRequest sends the form values, if any, and returns the response
+func doRequest(t *testing.T, app *fiber.App, method, target string, form url.Values) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, target, err)
+	}
+
+	return resp
+}
+
+// responseBody reads the rendered page or modal. Error modals are sent with status 200
+// like every templ response, so tests look at the message instead of the status.
+func responseBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	return string(body)
+}
+
+// fakeUsersRepo keeps users by ID, for the middlewares that load the session user
+type fakeUsersRepo struct {
+	users map[int]models.User
+}
+
+func newFakeUsersRepo(users ...models.User) *fakeUsersRepo {
+	repo := &fakeUsersRepo{users: map[int]models.User{}}
+	for _, user := range users {
+		repo.users[user.UserId] = user
+	}
+
+	return repo
+}
+
+func (r *fakeUsersRepo) FindById(ctx context.Context, tx *sql.Tx, userId int) (models.User, error) {
+	user, ok := r.users[userId]
+	if !ok {
+		return user, sql.ErrNoRows
+	}
+
+	return user, nil
+}
+
+func (r *fakeUsersRepo) FindByUsername(ctx context.Context, tx *sql.Tx, username string) (models.User, error) {
+	for _, user := range r.users {
+		if user.Username == username && !user.IsDisabled() {
+			return user, nil
+		}
+	}
+
+	return models.User{}, sql.ErrNoRows
+}
+
+func (r *fakeUsersRepo) FindAll(ctx context.Context, tx *sql.Tx) ([]models.User, error) {
+	var users []models.User
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserId < users[j].UserId })
+
+	return users, nil
+}
+
+func (r *fakeUsersRepo) CountActiveByRole(ctx context.Context, tx *sql.Tx, role string) (int, error) {
+	count := 0
+	for _, user := range r.users {
+		if user.Role == role && !user.IsDisabled() {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *fakeUsersRepo) Create(ctx context.Context, tx *sql.Tx, userData models.UserCreate) error {
+	userId := len(r.users) + 1
+	r.users[userId] = models.User{UserId: userId, Username: userData.Username, Password: userData.Password, Role: userData.Role}
+	return nil
+}
+
+func (r *fakeUsersRepo) Update(ctx context.Context, tx *sql.Tx, userData models.User) error {
+	r.users[userData.UserId] = userData
+	return nil
+}
+
+func (r *fakeUsersRepo) UpdateRole(ctx context.Context, tx *sql.Tx, userId int, role string) error {
+	user := r.users[userId]
+	user.Role = role
+	r.users[userId] = user
+	return nil
+}
+
+func (r *fakeUsersRepo) SoftDelete(ctx context.Context, tx *sql.Tx, userId int) error {
+	user := r.users[userId]
+	user.DeletedAt = sql.NullString{String: "2024-01-01 00:00:00", Valid: true}
+	r.users[userId] = user
+	return nil
+}
+
+func (r *fakeUsersRepo) Restore(ctx context.Context, tx *sql.Tx, userId int) error {
+	user := r.users[userId]
+	user.DeletedAt = sql.NullString{}
+	r.users[userId] = user
+	return nil
+}
+
+// fakeOrganizationsRepo keeps the members of organizations, enough for loading the session user
+type fakeOrganizationsRepo struct {
+	members []models.OrganizationMember
+}
+
+func newFakeOrganizationsRepo(members ...models.OrganizationMember) *fakeOrganizationsRepo {
+	return &fakeOrganizationsRepo{members: members}
+}
+
+func (r *fakeOrganizationsRepo) FindById(ctx context.Context, tx *sql.Tx, orgId int) (models.Organization, error) {
+	for _, member := range r.members {
+		if member.OrgId == orgId {
+			return models.Organization{OrgId: orgId}, nil
+		}
+	}
+
+	return models.Organization{}, sql.ErrNoRows
+}
+
+func (r *fakeOrganizationsRepo) FindByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.OrganizationMembership, error) {
+	var memberships []models.OrganizationMembership
+	for _, member := range r.members {
+		if member.UserId == userId {
+			memberships = append(memberships, models.OrganizationMembership{Organization: models.Organization{OrgId: member.OrgId}, Role: member.Role})
+		}
+	}
+
+	return memberships, nil
+}
+
+func (r *fakeOrganizationsRepo) FindMembers(ctx context.Context, tx *sql.Tx, orgId int) ([]models.OrganizationMember, error) {
+	var members []models.OrganizationMember
+	for _, member := range r.members {
+		if member.OrgId == orgId {
+			members = append(members, member)
+		}
+	}
+
+	return members, nil
+}
+
+func (r *fakeOrganizationsRepo) FindMember(ctx context.Context, tx *sql.Tx, orgId, userId int) (models.OrganizationMember, error) {
+	for _, member := range r.members {
+		if member.OrgId == orgId && member.UserId == userId {
+			return member, nil
+		}
+	}
+
+	return models.OrganizationMember{}, sql.ErrNoRows
+}
+
+func (r *fakeOrganizationsRepo) Create(ctx context.Context, tx *sql.Tx, orgData models.OrganizationCreate, ownerId int) (int, error) {
+	orgId := len(r.members) + 1
+	return orgId, r.AddMember(ctx, tx, orgId, ownerId, models.ORG_ROLE_OWNER)
+}
+
+func (r *fakeOrganizationsRepo) AddMember(ctx context.Context, tx *sql.Tx, orgId, userId int, role string) error {
+	r.members = append(r.members, models.OrganizationMember{OrgId: orgId, UserId: userId, Role: role})
+	return nil
+}
+
+func (r *fakeOrganizationsRepo) RemoveMember(ctx context.Context, tx *sql.Tx, orgId, userId int) error {
+	kept := r.members[:0]
+	for _, member := range r.members {
+		if member.OrgId != orgId || member.UserId != userId {
+			kept = append(kept, member)
+		}
+	}
+	r.members = kept
+
+	return nil
+}
+
+func (r *fakeOrganizationsRepo) CountOwners(ctx context.Context, tx *sql.Tx, orgId int) (int, error) {
+	count := 0
+	for _, member := range r.members {
+		if member.OrgId == orgId && member.Role == models.ORG_ROLE_OWNER {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *fakeOrganizationsRepo) CountOwnedRows(ctx context.Context, tx *sql.Tx, orgId int) (int, error) {
+	return 0, nil
+}
+
+func (r *fakeOrganizationsRepo) Delete(ctx context.Context, tx *sql.Tx, orgId int) error {
+	kept := r.members[:0]
+	for _, member := range r.members {
+		if member.OrgId != orgId {
+			kept = append(kept, member)
+		}
+	}
+	r.members = kept
+
+	return nil
+}
+
+// fakeProjectMembersRepo keeps the users projects are shared with
+type fakeProjectMembersRepo struct {
+	members []models.ProjectMember
+}
+
+func newFakeProjectMembersRepo(members ...models.ProjectMember) *fakeProjectMembersRepo {
+	return &fakeProjectMembersRepo{members: members}
+}
+
+func (r *fakeProjectMembersRepo) FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	for _, member := range r.members {
+		if member.ProjectId == projectId {
+			members = append(members, member)
+		}
+	}
+
+	return members, nil
+}
+
+func (r *fakeProjectMembersRepo) FindMember(ctx context.Context, tx *sql.Tx, projectId, userId int) (models.ProjectMember, error) {
+	for _, member := range r.members {
+		if member.ProjectId == projectId && member.UserId == userId {
+			return member, nil
+		}
+	}
+
+	return models.ProjectMember{}, sql.ErrNoRows
+}
+
+func (r *fakeProjectMembersRepo) FindSharedWithUser(ctx context.Context, tx *sql.Tx, userId int) ([]models.SharedProject, error) {
+	var shared []models.SharedProject
+	for _, member := range r.members {
+		if member.UserId == userId {
+			shared = append(shared, models.SharedProject{Project: models.Project{ProjectId: member.ProjectId}, Role: member.Role})
+		}
+	}
+
+	return shared, nil
+}
+
+func (r *fakeProjectMembersRepo) Save(ctx context.Context, tx *sql.Tx, member models.ProjectMember) error {
+	for i, existing := range r.members {
+		if existing.ProjectId == member.ProjectId && existing.UserId == member.UserId {
+			r.members[i].Role = member.Role
+			return nil
+		}
+	}
+	r.members = append(r.members, member)
+
+	return nil
+}
+
+func (r *fakeProjectMembersRepo) Remove(ctx context.Context, tx *sql.Tx, projectId, userId int) error {
+	kept := r.members[:0]
+	for _, member := range r.members {
+		if member.ProjectId != projectId || member.UserId != userId {
+			kept = append(kept, member)
+		}
+	}
+	r.members = kept
+
+	return nil
+}
+
+// fakeShareLinksRepo keeps the share links of projects
+type fakeShareLinksRepo struct {
+	links []models.ShareLink
+}
+
+func newFakeShareLinksRepo(links ...models.ShareLink) *fakeShareLinksRepo {
+	return &fakeShareLinksRepo{links: links}
+}
+
+func (r *fakeShareLinksRepo) FindById(ctx context.Context, tx *sql.Tx, linkId int) (models.ShareLink, error) {
+	for _, link := range r.links {
+		if link.LinkId == linkId {
+			return link, nil
+		}
+	}
+
+	return models.ShareLink{}, sql.ErrNoRows
+}
+
+func (r *fakeShareLinksRepo) FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ShareLink, error) {
+	var links []models.ShareLink
+	for _, link := range r.links {
+		if link.ProjectId == projectId {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+func (r *fakeShareLinksRepo) FindByHash(ctx context.Context, tx *sql.Tx, tokenHash string) (models.ShareLink, error) {
+	for _, link := range r.links {
+		if link.TokenHash == tokenHash {
+			return link, nil
+		}
+	}
+
+	return models.ShareLink{}, sql.ErrNoRows
+}
+
+func (r *fakeShareLinksRepo) Create(ctx context.Context, tx *sql.Tx, linkData models.ShareLinkCreate) (int, error) {
+	linkId := len(r.links) + 1
+	r.links = append(r.links, models.ShareLink{
+		LinkId:       linkId,
+		ProjectId:    linkData.ProjectId,
+		CreatedBy:    linkData.CreatedBy,
+		Label:        linkData.Label,
+		TokenPrefix:  linkData.TokenPrefix,
+		TokenHash:    linkData.TokenHash,
+		PasswordHash: linkData.PasswordHash,
+		ExpiresAt:    linkData.ExpiresAt,
+	})
+
+	return linkId, nil
+}
+
+func (r *fakeShareLinksRepo) Revoke(ctx context.Context, tx *sql.Tx, linkId int) error {
+	for i, link := range r.links {
+		if link.LinkId == linkId && link.RevokedAt == "" {
+			r.links[i].RevokedAt = "2024-01-01 00:00:00"
+		}
+	}
+
+	return nil
+}
+
+func (r *fakeShareLinksRepo) RecordAccess(ctx context.Context, tx *sql.Tx, linkId int) error {
+	for i, link := range r.links {
+		if link.LinkId == linkId {
+			r.links[i].AccessCount++
+		}
+	}
+
+	return nil
+}
+
+// fakeProjectApprovalsRepo keeps the approval state and history of projects
+type fakeProjectApprovalsRepo struct {
+	approvals map[int]models.ProjectApproval
+	events    []models.ApprovalEvent
+}
+
+func newFakeProjectApprovalsRepo(approvals ...models.ProjectApproval) *fakeProjectApprovalsRepo {
+	repo := &fakeProjectApprovalsRepo{approvals: map[int]models.ProjectApproval{}}
+	for _, approval := range approvals {
+		repo.approvals[approval.ProjectId] = approval
+	}
+	return repo
+}
+
+func (r *fakeProjectApprovalsRepo) FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) (models.ProjectApproval, error) {
+	if approval, ok := r.approvals[projectId]; ok {
+		return approval, nil
+	}
+
+	return models.NewProjectApproval(projectId), nil
+}
+
+func (r *fakeProjectApprovalsRepo) FindEvents(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ApprovalEvent, error) {
+	var events []models.ApprovalEvent
+	for i := len(r.events) - 1; i >= 0; i-- {
+		if r.events[i].ProjectId == projectId {
+			events = append(events, r.events[i])
+		}
+	}
+
+	return events, nil
+}
+
+func (r *fakeProjectApprovalsRepo) FindPending(ctx context.Context, tx *sql.Tx, workspace models.Workspace, userId int) ([]models.PendingApproval, error) {
+	return nil, nil
+}
+
+func (r *fakeProjectApprovalsRepo) Save(ctx context.Context, tx *sql.Tx, approval models.ProjectApproval) error {
+	r.approvals[approval.ProjectId] = approval
+	return nil
+}
+
+// AddEvent copies the form values it keeps, fiber reuses their memory after the request
+func (r *fakeProjectApprovalsRepo) AddEvent(ctx context.Context, tx *sql.Tx, eventData models.ApprovalEventCreate) (int, error) {
+	eventId := len(r.events) + 1
+	r.events = append(r.events, models.ApprovalEvent{
+		EventId:    eventId,
+		ProjectId:  eventData.ProjectId,
+		Revision:   eventData.Revision,
+		Action:     strings.Clone(eventData.Action),
+		FromStatus: eventData.FromStatus,
+		ToStatus:   eventData.ToStatus,
+		Comment:    strings.Clone(eventData.Comment),
+		TotalCost:  eventData.TotalCost,
+		UserId:     eventData.UserId,
+	})
+
+	return eventId, nil
+}
+
+// fakeProjectTotalsRepo answers the project total, the other work item methods are not used
+// by the handlers tested with it
+type fakeProjectTotalsRepo struct {
+	project_work_items.Repository
+	total float64
+}
+
+func (r *fakeProjectTotalsRepo) GetProjectTotalCost(ctx context.Context, tx *sql.Tx, projectId int) (float64, error) {
+	return r.total, nil
+}
+
+// fakeAuditLogRepo keeps entries newest first and filters them by project and entity, the
+// filters the repository tests cover in full
+type fakeAuditLogRepo struct {
+	entries []models.AuditEntry
+	filters []models.AuditFilter
+}
+
+func newFakeAuditLogRepo(entries ...models.AuditEntry) *fakeAuditLogRepo {
+	return &fakeAuditLogRepo{entries: entries}
+}
+
+func (r *fakeAuditLogRepo) Find(ctx context.Context, tx *sql.Tx, filter models.AuditFilter, paginationInput models.TablePaginationDataInput) ([]models.AuditEntry, models.PaginationInfo, error) {
+	entries, err := r.FindAll(ctx, tx, filter, 0)
+	return entries, models.PaginationInfo{CurrentPage: paginationInput.Page, TotalPages: 1, TotalItems: len(entries)}, err
+}
+
+func (r *fakeAuditLogRepo) FindAll(ctx context.Context, tx *sql.Tx, filter models.AuditFilter, limit int) ([]models.AuditEntry, error) {
+	r.filters = append(r.filters, filter)
+
+	var entries []models.AuditEntry
+	for _, entry := range r.entries {
+		if (filter.ProjectId == 0 || entry.ProjectId == filter.ProjectId) && (filter.Entity == "" || entry.Entity == filter.Entity) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (r *fakeAuditLogRepo) Create(ctx context.Context, tx *sql.Tx, entryData models.AuditEntryCreate) (int, error) {
+	r.entries = append([]models.AuditEntry{{AuditId: len(r.entries) + 1, UserId: entryData.UserId, Username: entryData.Username, Entity: entryData.Entity, EntityId: entryData.EntityId, ProjectId: entryData.ProjectId, Action: entryData.Action, BeforeJSON: entryData.BeforeJSON, AfterJSON: entryData.AfterJSON}}, r.entries...)
+	return len(r.entries), nil
+}
+
+// fakeTrashRepo lists what the fake projects and materials repositories keep in the trash,
+// along with workItems that no fake repository keeps
+type fakeTrashRepo struct {
+	projects  *fakeProjectsRepo
+	materials *fakeMaterialsRepo
+	workItems []models.TrashItem
+}
+
+func (r *fakeTrashRepo) items() []models.TrashItem {
+	items := append([]models.TrashItem{}, r.workItems...)
+	for _, project := range r.projects.trashed {
+		items = append(items, models.TrashItem{Entity: models.AUDIT_ENTITY_PROJECT, Id: project.ProjectId, Name: project.ProjectName, UserId: project.UserId, OrgId: project.OrgId, ProjectId: project.ProjectId})
+	}
+	for _, material := range r.materials.trashed {
+		items = append(items, models.TrashItem{Entity: models.AUDIT_ENTITY_MATERIAL, Id: material.MaterialId, Name: material.MaterialName, Detail: material.Unit, UserId: material.UserId, OrgId: material.OrgId})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+
+	return items
+}
+
+func (r *fakeTrashRepo) Find(ctx context.Context, tx *sql.Tx, workspace models.Workspace, entity string) ([]models.TrashItem, error) {
+	var items []models.TrashItem
+	for _, item := range r.items() {
+		if (entity == "" || item.Entity == entity) && visibleInWorkspace(workspace, item.UserId, item.OrgId) {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (r *fakeTrashRepo) FindById(ctx context.Context, tx *sql.Tx, entity string, id int) (models.TrashItem, error) {
+	for _, item := range r.items() {
+		if item.Entity == entity && item.Id == id {
+			return item, nil
+		}
+	}
+
+	return models.TrashItem{}, sql.ErrNoRows
+}
+
+func (r *fakeTrashRepo) FindDeletedBefore(ctx context.Context, tx *sql.Tx, before string) ([]models.TrashItem, error) {
+	return nil, nil
+}
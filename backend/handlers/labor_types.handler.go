@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/a-h/templ"
 	"github.com/gofiber/fiber/v2"
@@ -14,21 +13,25 @@ import (
 	"github.com/momokii/go-rab-maker/backend/models"
 	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
 	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
 	"github.com/momokii/go-rab-maker/frontend/components"
 )
 
 type LaborTypeHandler struct {
-	dbService      databases.SQLiteServices
-	laborTypesRepo master_labor_types.MasterLaborTypesRepo
+	dbService      databases.DatabaseServices
+	laborTypesRepo master_labor_types.Repository
+	publisher      webhook_dispatch.Publisher
 }
 
 func NewLaborTypeHandler(
-	dbService databases.SQLiteServices,
-	laborTypesRepo master_labor_types.MasterLaborTypesRepo,
+	dbService databases.DatabaseServices,
+	laborTypesRepo master_labor_types.Repository,
+	publisher webhook_dispatch.Publisher,
 ) *LaborTypeHandler {
 	return &LaborTypeHandler{
 		dbService:      dbService,
 		laborTypesRepo: laborTypesRepo,
+		publisher:      publisher,
 	}
 }
 
@@ -37,6 +40,8 @@ func NewLaborTypeHandler(
 // ==========================
 
 func (h *LaborTypeHandler) LaborTypesMainPageTableView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	var laborTypes []models.MasterLaborType
 	var paginationInfo models.PaginationInfo
 
@@ -53,12 +58,12 @@ func (h *LaborTypeHandler) LaborTypesMainPageTableView(c *fiber.Ctx) error {
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// start transaction to get the data
-	if _, err := h.dbService.Transaction(
-		c.Context(),
+	if _, err := h.dbService.ReadTransaction(
+		ctx,
 		func(tx *sql.Tx) (int, error) {
 			// get the labor type data
 			laborTypesData, paginationData, err := h.laborTypesRepo.Find(
-				tx, paginationData, userData.ID,
+				ctx, tx, paginationData, userData.Workspace(),
 			)
 			if err != nil {
 				return fiber.StatusInternalServerError, err
@@ -114,6 +119,8 @@ func (h *LaborTypeHandler) LaborTypeCreateModalView(c *fiber.Ctx) error {
 }
 
 func (h *LaborTypeHandler) LaborTypeEditModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	laborTypeIdStr := c.Params("id")
 	laborTypeId, err := strconv.Atoi(laborTypeIdStr)
 	if err != nil {
@@ -126,8 +133,8 @@ func (h *LaborTypeHandler) LaborTypeEditModalView(c *fiber.Ctx) error {
 	var laborType models.MasterLaborType
 
 	// Fetch labor type from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		laborType, err = h.laborTypesRepo.FindById(tx, laborTypeId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		laborType, err = h.laborTypesRepo.FindById(ctx, tx, laborTypeId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Labor type not found")
@@ -135,8 +142,8 @@ func (h *LaborTypeHandler) LaborTypeEditModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if labor type belongs to current user
-		if laborType.UserId != userData.ID {
+		// Check if the current user may change the labor type, system-wide defaults need an admin
+		if !userData.CanModify(laborType.UserId, laborType.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -157,6 +164,8 @@ func (h *LaborTypeHandler) LaborTypeEditModalView(c *fiber.Ctx) error {
 }
 
 func (h *LaborTypeHandler) LaborTypeDeleteModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	laborTypeIdStr := c.Params("id")
 	laborTypeId, err := strconv.Atoi(laborTypeIdStr)
 	if err != nil {
@@ -169,8 +178,8 @@ func (h *LaborTypeHandler) LaborTypeDeleteModalView(c *fiber.Ctx) error {
 	var laborType models.MasterLaborType
 
 	// Fetch labor type from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		laborType, err = h.laborTypesRepo.FindById(tx, laborTypeId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		laborType, err = h.laborTypesRepo.FindById(ctx, tx, laborTypeId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Labor type not found")
@@ -178,8 +187,8 @@ func (h *LaborTypeHandler) LaborTypeDeleteModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if labor type belongs to current user
-		if laborType.UserId != userData.ID {
+		// Check if the current user may change the labor type, system-wide defaults need an admin
+		if !userData.CanModify(laborType.UserId, laborType.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -204,6 +213,7 @@ func (h *LaborTypeHandler) LaborTypeDeleteModalView(c *fiber.Ctx) error {
 
 // CreateLaborType handles the creation of a new labor type
 func (h *LaborTypeHandler) CreateLaborType(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	// Get user from session (using the same approach as in auth.handler.go)
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
@@ -235,12 +245,13 @@ func (h *LaborTypeHandler) CreateLaborType(c *fiber.Ctx) error {
 		RoleName:         roleName,
 		Unit:             unit,
 		DefaultDailyWage: defaultWage,
-		UserId:           userData.ID,
+		UserId:           userData.Workspace().OwnerUserId(),
+		OrgId:            userData.OrgId,
 	}
 
 	// Create labor type in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		if err := h.laborTypesRepo.Create(tx, laborTypeData); err != nil {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if err := h.laborTypesRepo.Create(ctx, tx, laborTypeData); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -257,6 +268,7 @@ func (h *LaborTypeHandler) CreateLaborType(c *fiber.Ctx) error {
 
 // UpdateLaborType handles the update of an existing labor type
 func (h *LaborTypeHandler) UpdateLaborType(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	laborTypeIdStr := c.Params("id")
 	laborTypeId, err := strconv.Atoi(laborTypeIdStr)
@@ -290,9 +302,9 @@ func (h *LaborTypeHandler) UpdateLaborType(c *fiber.Ctx) error {
 	}
 
 	// Update labor type in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing labor type to ensure it belongs to the user
-		existingLaborType, err := h.laborTypesRepo.FindById(tx, laborTypeId)
+		existingLaborType, err := h.laborTypesRepo.FindById(ctx, tx, laborTypeId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Labor type not found")
@@ -300,25 +312,33 @@ func (h *LaborTypeHandler) UpdateLaborType(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if labor type belongs to current user
-		if existingLaborType.UserId != userData.ID {
+		// Check if the current user may change the labor type, system-wide defaults need an admin
+		if !userData.CanModify(existingLaborType.UserId, existingLaborType.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Update the labor type
 		updatedLaborType := models.MasterLaborType{
 			LaborTypeId:      laborTypeId,
-			UserId:           userData.ID,
+			UserId:           existingLaborType.UserId,
+			OrgId:            existingLaborType.OrgId,
 			RoleName:         roleName,
 			Unit:             unit,
 			DefaultDailyWage: defaultWage,
 			CreatedAt:        existingLaborType.CreatedAt,
-			UpdatedAt:        time.Now().Format("2006-01-02 15:04:05"),
+			UpdatedAt:        models.UTCNow(),
 		}
 
-		if err := h.laborTypesRepo.Update(tx, updatedLaborType); err != nil {
+		if err := h.laborTypesRepo.Update(ctx, tx, updatedLaborType); err != nil {
 			return fiber.StatusInternalServerError, fiber.NewError(fiber.StatusInternalServerError, "Make sure Role Name is Unique")
 		}
+
+		if existingLaborType.DefaultDailyWage != updatedLaborType.DefaultDailyWage {
+			change := models.NewLaborWageChange(updatedLaborType, existingLaborType.DefaultDailyWage)
+			if err := h.publisher.Publish(ctx, tx, userData.ID, models.WEBHOOK_EVENT_MASTER_PRICE_CHANGED, change); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+		}
 		return fiber.StatusOK, nil
 	}); err != nil {
 		return utils.ResponseErrorModal(c, "Error", "Failed to update labor type: "+err.Error())
@@ -333,6 +353,7 @@ func (h *LaborTypeHandler) UpdateLaborType(c *fiber.Ctx) error {
 
 // DeleteLaborType handles the deletion of a labor type
 func (h *LaborTypeHandler) DeleteLaborType(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	laborTypeIdStr := c.Params("id")
 	laborTypeId, err := strconv.Atoi(laborTypeIdStr)
@@ -344,9 +365,9 @@ func (h *LaborTypeHandler) DeleteLaborType(c *fiber.Ctx) error {
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// Delete labor type from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing labor type to ensure it belongs to the user
-		existingLaborType, err := h.laborTypesRepo.FindById(tx, laborTypeId)
+		existingLaborType, err := h.laborTypesRepo.FindById(ctx, tx, laborTypeId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Labor type not found")
@@ -354,13 +375,13 @@ func (h *LaborTypeHandler) DeleteLaborType(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if labor type belongs to current user
-		if existingLaborType.UserId != userData.ID {
+		// Check if the current user may change the labor type, system-wide defaults need an admin
+		if !userData.CanModify(existingLaborType.UserId, existingLaborType.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
-		// Delete the labor type
-		if err := h.laborTypesRepo.Delete(tx, existingLaborType); err != nil {
+		// Move the labor type to the trash, refused while AHSP templates use it
+		if err := h.laborTypesRepo.Delete(ctx, tx, existingLaborType); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -368,7 +389,8 @@ func (h *LaborTypeHandler) DeleteLaborType(c *fiber.Ctx) error {
 		// Check for foreign key constraint error (case-insensitive)
 		errLower := strings.ToLower(err.Error())
 		if strings.Contains(errLower, "foreign key") ||
-			strings.Contains(errLower, "constraint") {
+			strings.Contains(errLower, "constraint") ||
+			strings.Contains(errLower, "used in") {
 			return utils.ResponseErrorModal(c, "Cannot Delete",
 				"Cannot delete this labor type because it is used in AHSP templates")
 		}
@@ -379,5 +401,5 @@ func (h *LaborTypeHandler) DeleteLaborType(c *fiber.Ctx) error {
 	utils.SetRefreshTableTriggerHeader(c)
 
 	// Return success response with refresh
-	return utils.ResponseSuccessModal(c, "Success", "Labor type deleted successfully", true)
+	return utils.ResponseSuccessModal(c, "Success", "Labor type moved to the trash", true)
 }
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/master_import"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// MasterImportHandler serves the spreadsheet import of master materials and labor types,
+// every method returns the fiber handler for one kind (master_import.KIND_*)
+type MasterImportHandler struct {
+	dbService databases.DatabaseServices
+	importer  *master_import.Importer
+}
+
+func NewMasterImportHandler(
+	dbService databases.DatabaseServices,
+	importer *master_import.Importer,
+) *MasterImportHandler {
+	return &MasterImportHandler{
+		dbService: dbService,
+		importer:  importer,
+	}
+}
+
+func masterImportConfig(kind string) models.MasterImportConfig {
+	if kind == master_import.KIND_LABOR_TYPES {
+		return models.MasterImportConfig{
+			Kind:       kind,
+			Title:      "Import Labor Types",
+			BaseURL:    "/labor_types/import",
+			NameLabel:  "Role Name",
+			PriceLabel: "Daily Wage",
+		}
+	}
+
+	return models.MasterImportConfig{
+		Kind:       kind,
+		Title:      "Import Materials",
+		BaseURL:    "/materials/import",
+		NameLabel:  "Material Name",
+		PriceLabel: "Unit Price",
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+func (h *MasterImportHandler) MasterImportModalView(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		modal := components.MasterImportUploadModal(masterImportConfig(kind))
+		return adaptor.HTTPHandler(templ.Handler(modal))(c)
+	}
+}
+
+// MasterImportPreviewView reads the uploaded file (or the sheet carried over from the previous step),
+// applies the column mapping and shows what the import would change
+func (h *MasterImportHandler) MasterImportPreviewView(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+
+		config := masterImportConfig(kind)
+
+		// Get user from session (using the same approach as in auth.handler.go)
+		userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+		var rows [][]string
+		var mapping models.MasterImportColumnMapping
+
+		if fileHeader, err := c.FormFile("file"); err == nil {
+			file, err := fileHeader.Open()
+			if err != nil {
+				return utils.ResponseErrorModal(c, "Error", "Failed to read uploaded file")
+			}
+			defer file.Close()
+
+			data, err := io.ReadAll(file)
+			if err != nil {
+				return utils.ResponseErrorModal(c, "Error", "Failed to read uploaded file")
+			}
+
+			rows, err = utils.ReadSpreadsheet(fileHeader.Filename, data)
+			if err != nil {
+				return utils.ResponseErrorModal(c, "Import Error", err.Error())
+			}
+
+			if len(rows) > 0 {
+				mapping = master_import.GuessMapping(rows[0])
+			}
+		} else {
+			rows, mapping, err = parseMasterImportForm(c)
+			if err != nil {
+				return utils.ResponseErrorModal(c, "Import Error", err.Error())
+			}
+		}
+
+		if len(rows) < 2 {
+			return utils.ResponseErrorModal(c, "Import Error", "The file needs a header row and at least one data row")
+		}
+
+		sheetData, err := json.Marshal(rows)
+		if err != nil {
+			return utils.ResponseErrorModal(c, "Error", "Failed to process uploaded file")
+		}
+
+		var preview models.MasterImportPreview
+		mappingError := ""
+
+		if mapping.Name < 0 || mapping.Unit < 0 || mapping.Price < 0 {
+			mappingError = "Select the " + config.NameLabel + ", Unit and " + config.PriceLabel + " columns to see the preview."
+		} else if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+			preview, err = h.importer.Preview(ctx, tx, kind, rows, mapping, userData.Workspace())
+			if err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+			return fiber.StatusOK, nil
+		}); err != nil {
+			return utils.ResponseErrorModal(c, "Error", "Failed to preview import: "+err.Error())
+		}
+
+		modal := components.MasterImportPreviewModal(config, string(sheetData), rows[0], mapping, preview, mappingError)
+		return adaptor.HTTPHandler(templ.Handler(modal))(c)
+	}
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// CommitMasterImport recomputes the preview and applies it in a single transaction
+func (h *MasterImportHandler) CommitMasterImport(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+
+		// Get user from session (using the same approach as in auth.handler.go)
+		userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+		rows, mapping, err := parseMasterImportForm(c)
+		if err != nil {
+			return utils.ResponseErrorModal(c, "Import Error", err.Error())
+		}
+
+		var preview models.MasterImportPreview
+
+		if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+			preview, err = h.importer.Preview(ctx, tx, kind, rows, mapping, userData.Workspace())
+			if err != nil {
+				return fiber.StatusBadRequest, err
+			}
+
+			if err := h.importer.Commit(ctx, tx, kind, preview, userData.Workspace()); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+
+			return fiber.StatusOK, nil
+		}); err != nil {
+			return utils.ResponseErrorModal(c, "Error", "Import failed, nothing was saved: "+err.Error())
+		}
+
+		// refresh table data
+		utils.SetRefreshTableTriggerHeader(c)
+
+		message := fmt.Sprintf(
+			"%d added, %d updated, %d unchanged, %d rows skipped because of errors.",
+			preview.Inserts,
+			preview.Updates,
+			preview.Unchanged,
+			preview.Errors,
+		)
+
+		return utils.ResponseSuccessModal(c, "Import Completed", message, true)
+	}
+}
+
+// DownloadMasterImportTemplate sends an xlsx with the expected headers and example rows
+func (h *MasterImportHandler) DownloadMasterImportTemplate(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		config := masterImportConfig(kind)
+
+		var rows [][]interface{}
+		filename := "materials-import-template.xlsx"
+		if kind == master_import.KIND_LABOR_TYPES {
+			filename = "labor-types-import-template.xlsx"
+			rows = [][]interface{}{
+				{"Pekerja", "OH", 120000},
+				{"Tukang batu", "OH", 150000},
+			}
+		} else {
+			rows = [][]interface{}{
+				{"Semen Portland", "kg", 1500},
+				{"Pasir beton", "m3", 250000},
+			}
+		}
+
+		excel := utils.NewExcelExporter()
+		if err := excel.AddSheet("Import", []string{config.NameLabel, "Unit", config.PriceLabel}, rows); err != nil {
+			return utils.ResponseErrorModal(c, "Error", "Failed to generate template")
+		}
+
+		excelData, err := excel.Write()
+		if err != nil {
+			return utils.ResponseErrorModal(c, "Error", "Failed to generate template")
+		}
+
+		c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Set("Content-Disposition", "attachment; filename="+filename)
+
+		return c.Send(excelData)
+	}
+}
+
+// parseMasterImportForm reads the sheet and column mapping carried between the preview and commit steps
+func parseMasterImportForm(c *fiber.Ctx) ([][]string, models.MasterImportColumnMapping, error) {
+	var rows [][]string
+	mapping := models.MasterImportColumnMapping{Name: -1, Unit: -1, Price: -1}
+
+	if err := json.Unmarshal([]byte(c.FormValue("sheet_data")), &rows); err != nil {
+		return rows, mapping, fmt.Errorf("uploaded sheet is missing, please upload the file again")
+	}
+
+	for field, target := range map[string]*int{
+		"col_name":  &mapping.Name,
+		"col_unit":  &mapping.Unit,
+		"col_price": &mapping.Price,
+	} {
+		if value, err := strconv.Atoi(c.FormValue(field)); err == nil {
+			*target = value
+		}
+	}
+
+	return rows, mapping, nil
+}
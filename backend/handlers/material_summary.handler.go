@@ -14,30 +14,34 @@ import (
 	"github.com/momokii/go-rab-maker/backend/repository/dashboard"
 	"github.com/momokii/go-rab-maker/backend/repository/material_summary"
 	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
 	"github.com/momokii/go-rab-maker/backend/repository/projects"
 	"github.com/momokii/go-rab-maker/backend/utils"
 	"github.com/momokii/go-rab-maker/frontend/components"
 )
 
 type MaterialSummaryHandler struct {
-	dbService            databases.SQLiteServices
-	materialSummaryRepo  material_summary.MaterialSummaryRepo
-	projectsRepo         projects.ProjectsRepo
-	projectItemCostsRepo *project_item_costs.ProjectItemCostsRepo
-	dashboardRepo        dashboard.DashboardRepo
+	dbService            databases.DatabaseServices
+	materialSummaryRepo  material_summary.Repository
+	projectsRepo         projects.Repository
+	projectMembersRepo   project_members.Repository
+	projectItemCostsRepo project_item_costs.Repository
+	dashboardRepo        dashboard.Repository
 }
 
 func NewMaterialSummaryHandler(
-	dbService databases.SQLiteServices,
-	materialSummaryRepo material_summary.MaterialSummaryRepo,
-	projectsRepo projects.ProjectsRepo,
-	projectItemCostsRepo *project_item_costs.ProjectItemCostsRepo,
-	dashboardRepo dashboard.DashboardRepo,
+	dbService databases.DatabaseServices,
+	materialSummaryRepo material_summary.Repository,
+	projectsRepo projects.Repository,
+	projectMembersRepo project_members.Repository,
+	projectItemCostsRepo project_item_costs.Repository,
+	dashboardRepo dashboard.Repository,
 ) *MaterialSummaryHandler {
 	return &MaterialSummaryHandler{
 		dbService:            dbService,
 		materialSummaryRepo:  materialSummaryRepo,
 		projectsRepo:         projectsRepo,
+		projectMembersRepo:   projectMembersRepo,
 		projectItemCostsRepo: projectItemCostsRepo,
 		dashboardRepo:        dashboardRepo,
 	}
@@ -45,6 +49,8 @@ func NewMaterialSummaryHandler(
 
 // MaterialSummary displays a summary of all materials needed across projects
 func (h *MaterialSummaryHandler) MaterialSummary(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	var materialSummaries []models.MaterialSummary
 	var stats models.MaterialSummaryStats
 	var projectBreakdown []models.ProjectBreakdown
@@ -54,10 +60,10 @@ func (h *MaterialSummaryHandler) MaterialSummary(c *fiber.Ctx) error {
 	// Get user from session
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
-	// Use the database service transaction
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	// Read-only, so it uses the read pool and does not wait for writers
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get material summary data
-		materialSummariesData, err := h.materialSummaryRepo.GetAllMaterialsSummary(tx, userData.ID)
+		materialSummariesData, err := h.materialSummaryRepo.GetAllMaterialsSummary(ctx, tx, userData.Workspace())
 		if err != nil {
 			materialSummaries = []models.MaterialSummary{}
 		} else {
@@ -65,7 +71,7 @@ func (h *MaterialSummaryHandler) MaterialSummary(c *fiber.Ctx) error {
 		}
 
 		// Get material summary stats
-		statsData, err := h.dashboardRepo.GetMaterialSummaryStats(tx, userData.ID)
+		statsData, err := h.dashboardRepo.GetMaterialSummaryStats(ctx, tx, userData.Workspace())
 		if err != nil {
 			stats = models.MaterialSummaryStats{}
 		} else {
@@ -73,7 +79,7 @@ func (h *MaterialSummaryHandler) MaterialSummary(c *fiber.Ctx) error {
 		}
 
 		// Get project breakdown
-		projectBreakdownData, err := h.dashboardRepo.GetProjectBreakdown(tx, userData.ID)
+		projectBreakdownData, err := h.dashboardRepo.GetProjectBreakdown(ctx, tx, userData.Workspace())
 		if err != nil {
 			projectBreakdown = []models.ProjectBreakdown{}
 		} else {
@@ -81,7 +87,7 @@ func (h *MaterialSummaryHandler) MaterialSummary(c *fiber.Ctx) error {
 		}
 
 		// Get category breakdown (top 5)
-		categoryData, err := h.dashboardRepo.GetCategoryBreakdown(tx, userData.ID, 5)
+		categoryData, err := h.dashboardRepo.GetCategoryBreakdown(ctx, tx, userData.Workspace(), 5)
 		if err != nil {
 			categoryBreakdown = []models.CategoryBreakdown{}
 		} else {
@@ -89,7 +95,7 @@ func (h *MaterialSummaryHandler) MaterialSummary(c *fiber.Ctx) error {
 		}
 
 		// Get top 10 expensive items
-		topItemsData, err := h.dashboardRepo.GetTopExpensiveItems(tx, userData.ID, 10)
+		topItemsData, err := h.dashboardRepo.GetTopExpensiveItems(ctx, tx, userData.Workspace(), 10)
 		if err != nil {
 			topExpensiveItems = []models.TopExpensiveItem{}
 		} else {
@@ -114,6 +120,8 @@ func (h *MaterialSummaryHandler) MaterialSummary(c *fiber.Ctx) error {
 
 // ExportMaterialSummary exports the material summary to a file (PDF or Excel)
 func (h *MaterialSummaryHandler) ExportMaterialSummary(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Get export format from query parameter
 	format := c.Query("format", "pdf")
 
@@ -126,9 +134,9 @@ func (h *MaterialSummaryHandler) ExportMaterialSummary(c *fiber.Ctx) error {
 
 	// First, fetch data in transaction
 	var materialSummaries []models.MaterialSummary
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		var err error
-		materialSummaries, err = h.materialSummaryRepo.GetAllMaterialsSummary(tx, userData.ID)
+		materialSummaries, err = h.materialSummaryRepo.GetAllMaterialsSummary(ctx, tx, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -198,7 +206,10 @@ func (h *MaterialSummaryHandler) exportToExcel(c *fiber.Ctx, summaries []models.
 		})
 	}
 
-	if err := excel.AddSheet("Material Summary", headers, rows); err != nil {
+	options := materialSummarySheetOptions("Material Summary", nil)
+	rows = appendMaterialSummaryTotal(rows, &options)
+
+	if err := excel.AddSheetWithOptions("Material Summary", headers, rows, options); err != nil {
 		return err
 	}
 
@@ -213,6 +224,8 @@ func (h *MaterialSummaryHandler) exportToExcel(c *fiber.Ctx, summaries []models.
 
 // ProjectMaterialSummary displays a summary of materials needed for a specific project
 func (h *MaterialSummaryHandler) ProjectMaterialSummary(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	projectId, err := strconv.Atoi(projectIdStr)
 	if err != nil {
@@ -225,10 +238,10 @@ func (h *MaterialSummaryHandler) ProjectMaterialSummary(c *fiber.Ctx) error {
 	// Get user from session
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
-	// Use the database service transaction
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	// Read-only, so it uses the read pool and does not wait for writers
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Verify project ownership
-		projectData, err := h.projectsRepo.FindById(tx, projectId)
+		projectData, err := h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -236,16 +249,16 @@ func (h *MaterialSummaryHandler) ProjectMaterialSummary(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if projectData.UserId != userData.ID {
-			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		// Check the project is the user's, one of their organizations' or shared with them
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, projectData, userData, models.PROJECT_ROLE_VIEWER); err != nil {
+			return status, err
 		}
 
 		// Get detailed material summary data for the specific project
-		detailedSummaries, err := h.projectItemCostsRepo.GetDetailedMaterialSummaryByProjectId(tx, projectId)
+		detailedSummaries, err := h.projectItemCostsRepo.GetDetailedMaterialSummaryByProjectId(ctx, tx, projectId)
 		if err != nil {
 			// Fallback to regular summary if detailed fails
-			materialSummariesData, err := h.materialSummaryRepo.GetProjectMaterialSummary(tx, projectId)
+			materialSummariesData, err := h.materialSummaryRepo.GetProjectMaterialSummary(ctx, tx, projectId)
 			if err != nil {
 				materialSummaries = []models.MaterialSummary{}
 			} else {
@@ -280,6 +293,8 @@ func (h *MaterialSummaryHandler) ProjectMaterialSummary(c *fiber.Ctx) error {
 
 // ExportProjectMaterialSummary exports the material summary for a specific project to a file
 func (h *MaterialSummaryHandler) ExportProjectMaterialSummary(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	projectId, err := strconv.Atoi(projectIdStr)
 	if err != nil {
@@ -299,9 +314,9 @@ func (h *MaterialSummaryHandler) ExportProjectMaterialSummary(c *fiber.Ctx) erro
 	// First, fetch data in transaction
 	var project models.Project
 	var materialSummaries []models.MaterialSummary
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Verify project ownership
-		project, err = h.projectsRepo.FindById(tx, projectId)
+		project, err = h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -309,13 +324,13 @@ func (h *MaterialSummaryHandler) ExportProjectMaterialSummary(c *fiber.Ctx) erro
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if project.UserId != userData.ID {
-			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		// Check the project is the user's, one of their organizations' or shared with them
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_VIEWER); err != nil {
+			return status, err
 		}
 
 		// Get material summary data for the specific project
-		materialSummaries, err = h.materialSummaryRepo.GetProjectMaterialSummary(tx, projectId)
+		materialSummaries, err = h.materialSummaryRepo.GetProjectMaterialSummary(ctx, tx, projectId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -327,13 +342,13 @@ func (h *MaterialSummaryHandler) ExportProjectMaterialSummary(c *fiber.Ctx) erro
 
 	// Then, export OUTSIDE of transaction (file is sent directly)
 	if format == "pdf" {
-		return h.exportProjectToPDF(c, materialSummaries, project)
+		return exportProjectToPDF(c, materialSummaries, project)
 	}
-	return h.exportProjectToExcel(c, materialSummaries, project)
+	return exportProjectToExcel(c, materialSummaries, project)
 }
 
 // exportProjectToPDF exports the project material summary to PDF format
-func (h *MaterialSummaryHandler) exportProjectToPDF(c *fiber.Ctx, summaries []models.MaterialSummary, project models.Project) error {
+func exportProjectToPDF(c *fiber.Ctx, summaries []models.MaterialSummary, project models.Project) error {
 	c.Set("Content-Type", "application/pdf")
 	c.Set("Content-Disposition", "attachment; filename=material-summary-"+project.ProjectName+".pdf")
 
@@ -366,7 +381,7 @@ func (h *MaterialSummaryHandler) exportProjectToPDF(c *fiber.Ctx, summaries []mo
 }
 
 // exportProjectToExcel exports the project material summary to Excel format
-func (h *MaterialSummaryHandler) exportProjectToExcel(c *fiber.Ctx, summaries []models.MaterialSummary, project models.Project) error {
+func exportProjectToExcel(c *fiber.Ctx, summaries []models.MaterialSummary, project models.Project) error {
 	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 	c.Set("Content-Disposition", "attachment; filename=material-summary-"+project.ProjectName+".xlsx")
 
@@ -386,7 +401,10 @@ func (h *MaterialSummaryHandler) exportProjectToExcel(c *fiber.Ctx, summaries []
 		})
 	}
 
-	if err := excel.AddSheet(fmt.Sprintf("Materials - %s", project.ProjectName), headers, rows); err != nil {
+	options := materialSummarySheetOptions("Material Summary", []string{"Project: " + project.ProjectName, "Location: " + project.Location})
+	rows = appendMaterialSummaryTotal(rows, &options)
+
+	if err := excel.AddSheetWithOptions(fmt.Sprintf("Materials - %s", project.ProjectName), headers, rows, options); err != nil {
 		return err
 	}
 
@@ -398,3 +416,25 @@ func (h *MaterialSummaryHandler) exportProjectToExcel(c *fiber.Ctx, summaries []
 
 	return c.Send(excelData)
 }
+
+// materialSummarySheetOptions lays out the material summary columns (Item Name, Type, Total Quantity, Unit, Total Cost)
+func materialSummarySheetOptions(title string, subtitles []string) utils.ExcelSheetOptions {
+	return utils.ExcelSheetOptions{
+		Title:         title,
+		Subtitles:     subtitles,
+		ColumnWidths:  []float64{40, 12, 16, 10, 20},
+		ColumnFormats: []string{"", "", utils.EXCEL_FORMAT_NUMBER, "", utils.EXCEL_FORMAT_RUPIAH},
+		FreezeHeader:  true,
+	}
+}
+
+// appendMaterialSummaryTotal adds a bold total row summing the Total Cost column
+func appendMaterialSummaryTotal(rows [][]interface{}, options *utils.ExcelSheetOptions) [][]interface{} {
+	total := utils.ExcelFormula("0")
+	if len(rows) > 0 {
+		total = utils.ExcelFormula(fmt.Sprintf("SUM(E%d:E%d)", options.FirstDataRow(), options.FirstDataRow()+len(rows)-1))
+	}
+
+	options.BoldRows = append(options.BoldRows, len(rows))
+	return append(rows, []interface{}{"Total", "", "", "", total})
+}
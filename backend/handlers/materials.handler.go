@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/a-h/templ"
 	"github.com/gofiber/fiber/v2"
@@ -14,21 +13,25 @@ import (
 	"github.com/momokii/go-rab-maker/backend/models"
 	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
 	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
 	"github.com/momokii/go-rab-maker/frontend/components"
 )
 
 type MaterialHandler struct {
-	dbService     databases.SQLiteServices
-	materialsRepo master_materials.MasterMaterialsRepo
+	dbService     databases.DatabaseServices
+	materialsRepo master_materials.Repository
+	publisher     webhook_dispatch.Publisher
 }
 
 func NewMaterialsHandler(
-	dbService databases.SQLiteServices,
-	materialsRepo master_materials.MasterMaterialsRepo,
+	dbService databases.DatabaseServices,
+	materialsRepo master_materials.Repository,
+	publisher webhook_dispatch.Publisher,
 ) *MaterialHandler {
 	return &MaterialHandler{
 		dbService:     dbService,
 		materialsRepo: materialsRepo,
+		publisher:     publisher,
 	}
 }
 
@@ -36,6 +39,7 @@ func NewMaterialsHandler(
 // ========================== VIEWS
 // ==========================
 func (h *MaterialHandler) MaterialsMainPageTableView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	var materials []models.MasterMaterial
 	var paginationInfo models.PaginationInfo
@@ -54,13 +58,13 @@ func (h *MaterialHandler) MaterialsMainPageTableView(c *fiber.Ctx) error {
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// start transaction to get the data
-	if _, err := h.dbService.Transaction(
-		c.Context(),
+	if _, err := h.dbService.ReadTransaction(
+		ctx,
 		func(tx *sql.Tx) (int, error) {
 
 			// get the material data
 			materialsData, paginationData, err := h.materialsRepo.Find(
-				tx, paginationData, userData.ID,
+				ctx, tx, paginationData, userData.Workspace(),
 			)
 			if err != nil {
 				return fiber.StatusInternalServerError, err
@@ -114,6 +118,8 @@ func (h *MaterialHandler) MaterialCreateModalView(c *fiber.Ctx) error {
 }
 
 func (h *MaterialHandler) MaterialEditModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	materialIdStr := c.Params("id")
 	materialId, err := strconv.Atoi(materialIdStr)
 	if err != nil {
@@ -126,8 +132,8 @@ func (h *MaterialHandler) MaterialEditModalView(c *fiber.Ctx) error {
 	var material models.MasterMaterial
 
 	// Fetch material from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		material, err = h.materialsRepo.FindById(tx, materialId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		material, err = h.materialsRepo.FindById(ctx, tx, materialId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Material not found")
@@ -135,8 +141,8 @@ func (h *MaterialHandler) MaterialEditModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if material belongs to current user
-		if material.UserId != userData.ID {
+		// Check if the current user may change the material, system-wide defaults need an admin
+		if !userData.CanModify(material.UserId, material.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -157,6 +163,8 @@ func (h *MaterialHandler) MaterialEditModalView(c *fiber.Ctx) error {
 }
 
 func (h *MaterialHandler) MaterialDeleteModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	materialIdStr := c.Params("id")
 	materialId, err := strconv.Atoi(materialIdStr)
 	if err != nil {
@@ -169,8 +177,8 @@ func (h *MaterialHandler) MaterialDeleteModalView(c *fiber.Ctx) error {
 	var material models.MasterMaterial
 
 	// Fetch material from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		material, err = h.materialsRepo.FindById(tx, materialId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		material, err = h.materialsRepo.FindById(ctx, tx, materialId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Material not found")
@@ -178,8 +186,8 @@ func (h *MaterialHandler) MaterialDeleteModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if material belongs to current user
-		if material.UserId != userData.ID {
+		// Check if the current user may change the material, system-wide defaults need an admin
+		if !userData.CanModify(material.UserId, material.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -204,6 +212,7 @@ func (h *MaterialHandler) MaterialDeleteModalView(c *fiber.Ctx) error {
 
 // CreateMaterial handles the creation of a new material
 func (h *MaterialHandler) CreateMaterial(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	// Get user from session (using the same approach as in auth.handler.go)
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
@@ -212,6 +221,7 @@ func (h *MaterialHandler) CreateMaterial(c *fiber.Ctx) error {
 	materialName := strings.TrimSpace(c.FormValue("material_name"))
 	unit := strings.TrimSpace(c.FormValue("material_unit"))
 	defaultPriceStr := strings.TrimSpace(c.FormValue("material_defaultUnitPrice"))
+	isEquipment := c.FormValue("material_isEquipment") == "on"
 
 	// Convert price to float64
 	defaultPrice, err := strconv.ParseFloat(defaultPriceStr, 64)
@@ -224,7 +234,9 @@ func (h *MaterialHandler) CreateMaterial(c *fiber.Ctx) error {
 		MaterialName:     materialName,
 		Unit:             unit,
 		DefaultUnitPrice: defaultPrice,
-		UserId:           userData.ID,
+		IsEquipment:      isEquipment,
+		UserId:           userData.Workspace().OwnerUserId(),
+		OrgId:            userData.OrgId,
 	}
 
 	// Validate using validator tags
@@ -234,8 +246,8 @@ func (h *MaterialHandler) CreateMaterial(c *fiber.Ctx) error {
 	}
 
 	// Create material in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		if err := h.materialsRepo.Create(tx, materialData); err != nil {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if err := h.materialsRepo.Create(ctx, tx, materialData); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -252,6 +264,7 @@ func (h *MaterialHandler) CreateMaterial(c *fiber.Ctx) error {
 
 // UpdateMaterial handles the update of an existing material
 func (h *MaterialHandler) UpdateMaterial(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	materialIdStr := c.Params("id")
 	materialId, err := strconv.Atoi(materialIdStr)
@@ -266,6 +279,7 @@ func (h *MaterialHandler) UpdateMaterial(c *fiber.Ctx) error {
 	materialName := strings.TrimSpace(c.FormValue("material_name"))
 	unit := strings.TrimSpace(c.FormValue("material_unit"))
 	defaultPriceStr := strings.TrimSpace(c.FormValue("material_defaultUnitPrice"))
+	isEquipment := c.FormValue("material_isEquipment") == "on"
 
 	// Convert price to float64
 	defaultPrice, err := strconv.ParseFloat(defaultPriceStr, 64)
@@ -278,6 +292,7 @@ func (h *MaterialHandler) UpdateMaterial(c *fiber.Ctx) error {
 		MaterialName:     materialName,
 		Unit:             unit,
 		DefaultUnitPrice: defaultPrice,
+		IsEquipment:      isEquipment,
 		UserId:           userData.ID,
 	}
 
@@ -288,9 +303,9 @@ func (h *MaterialHandler) UpdateMaterial(c *fiber.Ctx) error {
 	}
 
 	// Update material in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing material to ensure it belongs to the user
-		existingMaterial, err := h.materialsRepo.FindById(tx, materialId)
+		existingMaterial, err := h.materialsRepo.FindById(ctx, tx, materialId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Material not found")
@@ -298,25 +313,34 @@ func (h *MaterialHandler) UpdateMaterial(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if material belongs to current user
-		if existingMaterial.UserId != userData.ID {
+		// Check if the current user may change the material, system-wide defaults need an admin
+		if !userData.CanModify(existingMaterial.UserId, existingMaterial.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Update the material
 		updatedMaterial := models.MasterMaterial{
 			MaterialId:       materialId,
-			UserId:           userData.ID,
+			UserId:           existingMaterial.UserId,
+			OrgId:            existingMaterial.OrgId,
 			MaterialName:     materialName,
 			Unit:             unit,
 			DefaultUnitPrice: defaultPrice,
+			IsEquipment:      isEquipment,
 			CreatedAt:        existingMaterial.CreatedAt,
-			UpdatedAt:        time.Now().Format("2006-01-02 15:04:05"),
+			UpdatedAt:        models.UTCNow(),
 		}
 
-		if err := h.materialsRepo.Update(tx, updatedMaterial); err != nil {
+		if err := h.materialsRepo.Update(ctx, tx, updatedMaterial); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
+
+		if existingMaterial.DefaultUnitPrice != updatedMaterial.DefaultUnitPrice {
+			change := models.NewMaterialPriceChange(updatedMaterial, existingMaterial.DefaultUnitPrice)
+			if err := h.publisher.Publish(ctx, tx, userData.ID, models.WEBHOOK_EVENT_MASTER_PRICE_CHANGED, change); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+		}
 		return fiber.StatusOK, nil
 	}); err != nil {
 		return utils.ResponseErrorModal(c, "Error", "Failed to update material")
@@ -331,6 +355,7 @@ func (h *MaterialHandler) UpdateMaterial(c *fiber.Ctx) error {
 
 // DeleteMaterial handles the deletion of a material
 func (h *MaterialHandler) DeleteMaterial(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	materialIdStr := c.Params("id")
 	materialId, err := strconv.Atoi(materialIdStr)
@@ -342,9 +367,9 @@ func (h *MaterialHandler) DeleteMaterial(c *fiber.Ctx) error {
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// Delete material from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing material to ensure it belongs to the user
-		existingMaterial, err := h.materialsRepo.FindById(tx, materialId)
+		existingMaterial, err := h.materialsRepo.FindById(ctx, tx, materialId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Material not found")
@@ -352,13 +377,13 @@ func (h *MaterialHandler) DeleteMaterial(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if material belongs to current user
-		if existingMaterial.UserId != userData.ID {
+		// Check if the current user may change the material, system-wide defaults need an admin
+		if !userData.CanModify(existingMaterial.UserId, existingMaterial.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
-		// Delete the material
-		if err := h.materialsRepo.Delete(tx, existingMaterial); err != nil {
+		// Move the material to the trash, refused while AHSP templates use it
+		if err := h.materialsRepo.Delete(ctx, tx, existingMaterial); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -366,7 +391,8 @@ func (h *MaterialHandler) DeleteMaterial(c *fiber.Ctx) error {
 		// Check for foreign key constraint error (case-insensitive)
 		errLower := strings.ToLower(err.Error())
 		if strings.Contains(errLower, "foreign key") ||
-			strings.Contains(errLower, "constraint") {
+			strings.Contains(errLower, "constraint") ||
+			strings.Contains(errLower, "used in") {
 			return utils.ResponseErrorModal(c, "Cannot Delete",
 				"Cannot delete this material because it is used in AHSP templates")
 		}
@@ -377,5 +403,5 @@ func (h *MaterialHandler) DeleteMaterial(c *fiber.Ctx) error {
 	utils.SetRefreshTableTriggerHeader(c)
 
 	// Return success response with refresh
-	return utils.ResponseSuccessModal(c, "Success", "Material deleted successfully", true)
+	return utils.ResponseSuccessModal(c, "Success", "Material moved to the trash", true)
 }
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+func newTestMaterialsApp(t *testing.T, userId int, repo *fakeMaterialsRepo) (*fakeDatabase, func(method, target string, form url.Values) *http.Response) {
+	return newTestMaterialsAppAs(t, userId, models.ROLE_ESTIMATOR, repo)
+}
+
+func newTestMaterialsAppAs(t *testing.T, userId int, role string, repo *fakeMaterialsRepo) (*fakeDatabase, func(method, target string, form url.Values) *http.Response) {
+	db := &fakeDatabase{}
+	handler := NewMaterialsHandler(db, repo, &fakePublisher{})
+
+	app := newTestAppAs(userId, role)
+	app.Get("/materials", handler.MaterialsMainPageTableView)
+	app.Post("/materials/new", handler.CreateMaterial)
+	app.Post("/materials/:id/edit", handler.UpdateMaterial)
+	app.Delete("/materials/:id/delete", handler.DeleteMaterial)
+
+	return db, func(method, target string, form url.Values) *http.Response {
+		return doRequest(t, app, method, target, form)
+	}
+}
+
+// TestMaterialsTable_PassesUserAndPagination verifies the table reads the session user's
+// materials with the requested page, inside a read transaction
+func TestMaterialsTable_PassesUserAndPagination(t *testing.T) {
+	repo := newFakeMaterialsRepo(
+		models.MasterMaterial{MaterialId: 1, UserId: 7, MaterialName: "Semen", Unit: "zak", DefaultUnitPrice: 65000},
+		models.MasterMaterial{MaterialId: 2, UserId: 8, MaterialName: "Pasir", Unit: "m3", DefaultUnitPrice: 250000},
+	)
+	db, do := newTestMaterialsApp(t, 7, repo)
+
+	resp := do(http.MethodGet, "/materials?page=2&per_page=5&search=sem", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	if repo.lastFind.Page != 2 || repo.lastFind.PerPage != 5 || repo.lastFind.Search != "sem" {
+		t.Errorf("Unexpected pagination input: %+v", repo.lastFind)
+	}
+	if db.readTransactions != 1 || db.transactions != 0 {
+		t.Errorf("Expected one read transaction, got %d read and %d write", db.readTransactions, db.transactions)
+	}
+	if repo.lastCtx == nil || repo.lastCtx.Value(testContextKey{}) != "request" {
+		t.Errorf("The repository did not receive the request context")
+	}
+}
+
+// TestCreateMaterial_SavesForSessionUser verifies the form is trimmed and saved as the session user's material
+func TestCreateMaterial_SavesForSessionUser(t *testing.T) {
+	repo := newFakeMaterialsRepo()
+	_, do := newTestMaterialsApp(t, 7, repo)
+
+	resp := do(http.MethodPost, "/materials/new", url.Values{
+		"material_name":             {"  Semen Portland  "},
+		"material_unit":             {"zak"},
+		"material_defaultUnitPrice": {"65000"},
+		"material_isEquipment":      {"on"},
+	})
+	if body := responseBody(t, resp); !strings.Contains(body, "Material created successfully") {
+		t.Fatalf("Expected the material to be created, got %s", body)
+	}
+	if resp.Header.Get("HX-Trigger") != "refreshTable" {
+		t.Errorf("Expected the table to be refreshed")
+	}
+
+	material, ok := repo.materials[1]
+	if !ok {
+		t.Fatalf("Material was not created")
+	}
+	if material.UserId != 7 || material.MaterialName != "Semen Portland" || material.DefaultUnitPrice != 65000 || !material.IsEquipment {
+		t.Errorf("Unexpected material: %+v", material)
+	}
+}
+
+// TestCreateMaterial_RejectsInvalidPrice verifies a bad price never reaches the repository
+func TestCreateMaterial_RejectsInvalidPrice(t *testing.T) {
+	repo := newFakeMaterialsRepo()
+	db, do := newTestMaterialsApp(t, 7, repo)
+
+	resp := do(http.MethodPost, "/materials/new", url.Values{
+		"material_name":             {"Semen"},
+		"material_unit":             {"zak"},
+		"material_defaultUnitPrice": {"sixty"},
+	})
+	if body := responseBody(t, resp); !strings.Contains(body, "Invalid default price format") {
+		t.Errorf("Expected a validation error, got %s", body)
+	}
+	if len(repo.materials) != 0 || db.transactions != 0 {
+		t.Errorf("Expected nothing to be saved, got %d materials and %d transactions", len(repo.materials), db.transactions)
+	}
+}
+
+// TestUpdateMaterial_OtherUsersMaterialIsDenied verifies a user cannot edit someone else's material
+func TestUpdateMaterial_OtherUsersMaterialIsDenied(t *testing.T) {
+	original := models.MasterMaterial{MaterialId: 1, UserId: 8, MaterialName: "Pasir", Unit: "m3", DefaultUnitPrice: 250000}
+	repo := newFakeMaterialsRepo(original)
+	_, do := newTestMaterialsApp(t, 7, repo)
+
+	resp := do(http.MethodPost, "/materials/1/edit", url.Values{
+		"material_name":             {"Pasir Beton"},
+		"material_unit":             {"m3"},
+		"material_defaultUnitPrice": {"1"},
+	})
+	if body := responseBody(t, resp); !strings.Contains(body, "Failed to update material") {
+		t.Errorf("Expected the update to fail, got %s", body)
+	}
+	if repo.materials[1] != original {
+		t.Errorf("Material was changed: %+v", repo.materials[1])
+	}
+}
+
+// TestUpdateMaterial_SystemWideNeedsAdmin verifies only an admin changes a system-wide default,
+// and it stays system-wide
+func TestUpdateMaterial_SystemWideNeedsAdmin(t *testing.T) {
+	original := models.MasterMaterial{MaterialId: 1, UserId: 0, MaterialName: "Semen", Unit: "zak", DefaultUnitPrice: 65000}
+	form := url.Values{
+		"material_name":             {"Semen"},
+		"material_unit":             {"zak"},
+		"material_defaultUnitPrice": {"70000"},
+	}
+
+	repo := newFakeMaterialsRepo(original)
+	_, do := newTestMaterialsApp(t, 7, repo)
+	if body := responseBody(t, do(http.MethodPost, "/materials/1/edit", form)); !strings.Contains(body, "Failed to update material") {
+		t.Errorf("Expected an estimator to be refused, got %s", body)
+	}
+	if repo.materials[1] != original {
+		t.Fatalf("Material was changed: %+v", repo.materials[1])
+	}
+
+	_, do = newTestMaterialsAppAs(t, 1, models.ROLE_ADMIN, repo)
+	if body := responseBody(t, do(http.MethodPost, "/materials/1/edit", form)); !strings.Contains(body, "Material updated successfully") {
+		t.Fatalf("Expected an admin to update it, got %s", body)
+	}
+	if updated := repo.materials[1]; updated.UserId != 0 || updated.DefaultUnitPrice != 70000 {
+		t.Errorf("Expected a system-wide material at the new price, got %+v", updated)
+	}
+}
+
+// TestDeleteMaterial_RemovesOwnMaterial verifies the owner can delete a material and a missing one is reported
+func TestDeleteMaterial_RemovesOwnMaterial(t *testing.T) {
+	repo := newFakeMaterialsRepo(models.MasterMaterial{MaterialId: 1, UserId: 7, MaterialName: "Semen", Unit: "zak"})
+	_, do := newTestMaterialsApp(t, 7, repo)
+
+	if body := responseBody(t, do(http.MethodDelete, "/materials/1/delete", nil)); !strings.Contains(body, "Material moved to the trash") {
+		t.Fatalf("Expected the material to be deleted, got %s", body)
+	}
+	if _, ok := repo.materials[1]; ok {
+		t.Errorf("Material was not deleted")
+	}
+
+	if body := responseBody(t, do(http.MethodDelete, "/materials/1/delete", nil)); !strings.Contains(body, "Material not found") {
+		t.Errorf("Expected a missing material to be reported, got %s", body)
+	}
+}
+
+// TestMaterials_OrganizationWorkspace verifies a material created in an organization belongs to
+// it, its members can change it and other users cannot
+func TestMaterials_OrganizationWorkspace(t *testing.T) {
+	repo := newFakeMaterialsRepo()
+	handler := NewMaterialsHandler(&fakeDatabase{}, repo, &fakePublisher{})
+	form := url.Values{
+		"material_name":             {"Semen"},
+		"material_unit":             {"zak"},
+		"material_defaultUnitPrice": {"65000"},
+	}
+
+	appFor := func(user models.SessionUser) func(method, target string, form url.Values) *http.Response {
+		app := newTestAppFor(user)
+		app.Post("/materials/new", handler.CreateMaterial)
+		app.Post("/materials/:id/edit", handler.UpdateMaterial)
+		return func(method, target string, form url.Values) *http.Response {
+			return doRequest(t, app, method, target, form)
+		}
+	}
+
+	member := appFor(models.SessionUser{ID: 7, Role: models.ROLE_ESTIMATOR, OrgId: 3, OrgIds: []int{3}})
+	if body := responseBody(t, member(http.MethodPost, "/materials/new", form)); !strings.Contains(body, "Material created successfully") {
+		t.Fatalf("Expected the material to be created, got %s", body)
+	}
+	if material := repo.materials[1]; material.OrgId != 3 || material.UserId != 0 {
+		t.Fatalf("Expected an organization material, got %+v", material)
+	}
+
+	form.Set("material_defaultUnitPrice", "70000")
+	other := appFor(models.SessionUser{ID: 8, Role: models.ROLE_ESTIMATOR})
+	if body := responseBody(t, other(http.MethodPost, "/materials/1/edit", form)); !strings.Contains(body, "Failed to update material") {
+		t.Errorf("Expected a non-member to be refused, got %s", body)
+	}
+
+	// a member changes it from their own workspace too, and it stays the organization's
+	colleague := appFor(models.SessionUser{ID: 9, Role: models.ROLE_ESTIMATOR, OrgIds: []int{3}})
+	if body := responseBody(t, colleague(http.MethodPost, "/materials/1/edit", form)); !strings.Contains(body, "Material updated successfully") {
+		t.Fatalf("Expected a member to update it, got %s", body)
+	}
+	if material := repo.materials[1]; material.OrgId != 3 || material.DefaultUnitPrice != 70000 {
+		t.Errorf("Expected the organization material at the new price, got %+v", material)
+	}
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/organizations"
+	"github.com/momokii/go-rab-maker/backend/repository/users"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// OrganizationsHandler lets users share master data and projects in organizations and pick
+// the workspace they work in
+type OrganizationsHandler struct {
+	dbService databases.DatabaseServices
+	orgsRepo  organizations.Repository
+	usersRepo users.Repository
+}
+
+func NewOrganizationsHandler(
+	dbService databases.DatabaseServices,
+	orgsRepo organizations.Repository,
+	usersRepo users.Repository,
+) *OrganizationsHandler {
+	return &OrganizationsHandler{
+		dbService: dbService,
+		orgsRepo:  orgsRepo,
+		usersRepo: usersRepo,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+func (h *OrganizationsHandler) OrganizationsView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var memberships []models.OrganizationMembership
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		memberships, err = h.orgsRepo.FindByUserId(ctx, tx, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to load organizations")
+	}
+
+	page := components.OrganizationsPage(memberships, userData.OrgId)
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+func (h *OrganizationsHandler) OrganizationCreateModalView(c *fiber.Ctx) error {
+	modal := components.OrganizationCreateModal()
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// OrganizationMembersView lists the members of an organization the user belongs to
+func (h *OrganizationsHandler) OrganizationMembersView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	orgId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid organization ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var org models.Organization
+	var membership models.OrganizationMember
+	var members []models.OrganizationMember
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		org, membership, err = h.findMembership(ctx, tx, orgId, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		members, err = h.orgsRepo.FindMembers(ctx, tx, orgId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).SendString(fiberErr.Message)
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to load organization")
+	}
+
+	page := components.OrganizationMembersPage(org, membership, members)
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+// WorkspaceSwitcherView is the organization switcher of the top bar, loaded by every page
+func (h *OrganizationsHandler) WorkspaceSwitcherView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var memberships []models.OrganizationMembership
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		memberships, err = h.orgsRepo.FindByUserId(ctx, tx, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("")
+	}
+
+	switcher := components.WorkspaceSwitcher(memberships, userData.OrgId)
+	return adaptor.HTTPHandler(templ.Handler(switcher))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// SwitchWorkspace makes an organization of the user, or their own workspace for 0, the one
+// the pages work in and reloads the page
+func (h *OrganizationsHandler) SwitchWorkspace(c *fiber.Ctx) error {
+	orgId, err := strconv.Atoi(c.FormValue("org_id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid organization ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if orgId != 0 && !userData.IsMemberOf(orgId) {
+		return utils.ResponseErrorModal(c, "Error", "You are not a member of this organization")
+	}
+
+	if err := middlewares.CreateSession(c, middlewares.SESSION_ORG_ID, orgId); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to switch workspace")
+	}
+
+	c.Set("HX-Refresh", "true")
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateOrganization creates an organization with the user as its owner and switches to it
+func (h *OrganizationsHandler) CreateOrganization(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	orgData := models.OrganizationCreate{
+		Name: strings.TrimSpace(c.FormValue("org_name")),
+	}
+
+	if err := utils.ValidateStruct(orgData); err != nil {
+		errors := utils.GetValidationErrors(err)
+		return utils.ResponseErrorModal(c, "Validation Error", strings.Join(errors, "; "))
+	}
+
+	var orgId int
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		orgId, err = h.orgsRepo.Create(ctx, tx, orgData, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to create organization, make sure the name is unique")
+	}
+
+	if err := middlewares.CreateSession(c, middlewares.SESSION_ORG_ID, orgId); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Organization created, but switching to it failed")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Organization Created", "You are now working in "+orgData.Name+", add its members next", "/settings/organizations/"+strconv.Itoa(orgId))
+}
+
+// AddOrganizationMember adds a user by username, only owners manage the members
+func (h *OrganizationsHandler) AddOrganizationMember(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	orgId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid organization ID")
+	}
+
+	username := strings.TrimSpace(c.FormValue("username"))
+	if username == "" {
+		return utils.ResponseErrorModal(c, "Validation Error", "Username is required")
+	}
+
+	role := c.FormValue("role")
+	if role != models.ORG_ROLE_OWNER && role != models.ORG_ROLE_MEMBER {
+		return utils.ResponseErrorModal(c, "Validation Error", "Unknown organization role "+role)
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := h.findOwnership(ctx, tx, orgId, userData.ID); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		user, err := h.usersRepo.FindByUsername(ctx, tx, username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "User "+username+" not found")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		if user.IsDisabled() {
+			return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "User "+username+" is disabled")
+		}
+
+		if _, err := h.orgsRepo.FindMember(ctx, tx, orgId, user.UserId); err == nil {
+			return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, username+" is already a member")
+		} else if err != sql.ErrNoRows {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.orgsRepo.AddMember(ctx, tx, orgId, user.UserId, role); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to add member")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Success", username+" added", "/settings/organizations/"+strconv.Itoa(orgId))
+}
+
+// RemoveOrganizationMember removes a member. Owners remove anyone, members only themselves to
+// leave; the last owner cannot leave, the organization is deleted instead.
+func (h *OrganizationsHandler) RemoveOrganizationMember(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	orgId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid organization ID")
+	}
+
+	memberId, err := strconv.Atoi(c.Params("userId"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid user ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+	leaving := memberId == userData.ID
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		_, membership, err := h.findMembership(ctx, tx, orgId, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if !leaving && membership.Role != models.ORG_ROLE_OWNER {
+			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Only owners manage the members")
+		}
+
+		member, err := h.orgsRepo.FindMember(ctx, tx, orgId, memberId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Member not found")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		if member.Role == models.ORG_ROLE_OWNER {
+			owners, err := h.orgsRepo.CountOwners(ctx, tx, orgId)
+			if err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+			if owners <= 1 {
+				return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "An organization needs an owner, add another owner first or delete the organization")
+			}
+		}
+
+		if err := h.orgsRepo.RemoveMember(ctx, tx, orgId, memberId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to remove member")
+	}
+
+	if leaving {
+		return utils.ResponseSuccessWithRedirect(c, "Success", "You left the organization", "/settings/organizations")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Success", "Member removed", "/settings/organizations/"+strconv.Itoa(orgId))
+}
+
+// DeleteOrganization deletes an organization that owns no projects or master data any more,
+// only its owners may
+func (h *OrganizationsHandler) DeleteOrganization(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	orgId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid organization ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := h.findOwnership(ctx, tx, orgId, userData.ID); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		owned, err := h.orgsRepo.CountOwnedRows(ctx, tx, orgId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+		if owned > 0 {
+			return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "The organization still owns "+strconv.Itoa(owned)+" projects or master data rows, delete them first")
+		}
+
+		if err := h.orgsRepo.Delete(ctx, tx, orgId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to delete organization")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Success", "Organization deleted", "/settings/organizations")
+}
+
+// findMembership returns the organization with the user's membership, access denied when they
+// are not a member
+func (h *OrganizationsHandler) findMembership(ctx context.Context, tx *sql.Tx, orgId, userId int) (models.Organization, models.OrganizationMember, error) {
+	org, err := h.orgsRepo.FindById(ctx, tx, orgId)
+	if err == sql.ErrNoRows {
+		return org, models.OrganizationMember{}, fiber.NewError(fiber.StatusNotFound, "Organization not found")
+	}
+	if err != nil {
+		return org, models.OrganizationMember{}, err
+	}
+
+	membership, err := h.orgsRepo.FindMember(ctx, tx, orgId, userId)
+	if err == sql.ErrNoRows {
+		return org, membership, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return org, membership, err
+}
+
+// findOwnership is findMembership for the actions only owners may take
+func (h *OrganizationsHandler) findOwnership(ctx context.Context, tx *sql.Tx, orgId, userId int) (models.Organization, error) {
+	org, membership, err := h.findMembership(ctx, tx, orgId, userId)
+	if err != nil {
+		return org, err
+	}
+
+	if membership.Role != models.ORG_ROLE_OWNER {
+		return org, fiber.NewError(fiber.StatusForbidden, "Only owners manage the organization")
+	}
+
+	return org, nil
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// ProjectApprovalsHandler moves projects through the approval workflow and keeps their history.
+// Its routes are outside the project group so reviewers, who cannot edit, can take their steps.
+type ProjectApprovalsHandler struct {
+	dbService            databases.DatabaseServices
+	projectsRepo         projects.Repository
+	projectMembersRepo   project_members.Repository
+	projectApprovalsRepo project_approvals.Repository
+	projectWorkItemsRepo project_work_items.Repository
+	publisher            webhook_dispatch.Publisher
+}
+
+func NewProjectApprovalsHandler(
+	dbService databases.DatabaseServices,
+	projectsRepo projects.Repository,
+	projectMembersRepo project_members.Repository,
+	projectApprovalsRepo project_approvals.Repository,
+	projectWorkItemsRepo project_work_items.Repository,
+	publisher webhook_dispatch.Publisher,
+) *ProjectApprovalsHandler {
+	return &ProjectApprovalsHandler{
+		dbService:            dbService,
+		projectsRepo:         projectsRepo,
+		projectMembersRepo:   projectMembersRepo,
+		projectApprovalsRepo: projectApprovalsRepo,
+		projectWorkItemsRepo: projectWorkItemsRepo,
+		publisher:            publisher,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+// ApprovalsView lists the projects of the workspace and those shared with the user that are
+// waiting for a reviewer
+func (h *ProjectApprovalsHandler) ApprovalsView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var pending []models.PendingApproval
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		pending, err = h.projectApprovalsRepo.FindPending(ctx, tx, userData.Workspace(), userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to load approvals")
+	}
+
+	page := components.ApprovalsPage(pending, userData.Can(models.PERMISSION_APPROVE))
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+// ProjectApprovalModalView shows the status of a project with the steps the user can take and
+// its history with the reviewers' comments
+func (h *ProjectApprovalsHandler) ProjectApprovalModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var panel models.ApprovalPanel
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		panel, err = h.findApprovalPanel(ctx, tx, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to fetch the approval")
+	}
+
+	modal := components.ProjectApprovalModal(panel)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// ChangeApprovalStatus takes a step of the workflow, or adds a comment, and records it in the
+// history with the project total. Status changes are published as approval.status_changed to
+// the webhooks of the project owner.
+func (h *ProjectApprovalsHandler) ChangeApprovalStatus(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	action := c.FormValue("action")
+	comment := strings.TrimSpace(c.FormValue("comment"))
+
+	transition, ok := models.APPROVAL_TRANSITIONS[action]
+	if !ok {
+		return utils.ResponseErrorModal(c, "Validation Error", "Unknown approval action")
+	}
+	if transition.CommentRequired && comment == "" {
+		return utils.ResponseErrorModal(c, "Validation Error", "A comment is required for this step")
+	}
+	if utf8.RuneCountInString(comment) > models.APPROVAL_COMMENT_MAX_LENGTH {
+		return utils.ResponseErrorModal(c, "Validation Error", "The comment is longer than "+strconv.Itoa(models.APPROVAL_COMMENT_MAX_LENGTH)+" characters")
+	}
+
+	var panel models.ApprovalPanel
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		panel, err = h.findApprovalPanel(ctx, tx, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if !models.CanTakeApprovalAction(userData, panel.Role, action) {
+			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "You are not allowed to take this step")
+		}
+
+		next, ok := panel.Approval.Apply(action)
+		if !ok {
+			return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "The project is "+strings.ToLower(panel.Approval.StatusLabel())+", it cannot take this step")
+		}
+		next.UpdatedBy = userData.ID
+
+		if action != models.APPROVAL_ACTION_COMMENT {
+			if err := h.projectApprovalsRepo.Save(ctx, tx, next); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+		}
+
+		// The total shows what was submitted, approved or rejected
+		totalCost, err := h.projectWorkItemsRepo.GetProjectTotalCost(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if _, err := h.projectApprovalsRepo.AddEvent(ctx, tx, models.ApprovalEventCreate{
+			ProjectId:  projectId,
+			Revision:   next.Revision,
+			Action:     action,
+			FromStatus: panel.Approval.Status,
+			ToStatus:   next.Status,
+			Comment:    comment,
+			TotalCost:  totalCost,
+			UserId:     userData.ID,
+		}); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if action != models.APPROVAL_ACTION_COMMENT {
+			if err := h.publisher.Publish(ctx, tx, panel.Project.UserId, models.WEBHOOK_EVENT_APPROVAL_STATUS_CHANGED, models.WebhookApprovalChange{
+				Action:       action,
+				ProjectId:    projectId,
+				ProjectName:  panel.Project.ProjectName,
+				Revision:     next.Revision,
+				FromStatus:   panel.Approval.Status,
+				ToStatus:     next.Status,
+				Comment:      comment,
+				ProjectTotal: totalCost,
+				Username:     userData.Username,
+			}); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+		}
+
+		panel, err = h.findApprovalPanel(ctx, tx, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to update the approval")
+	}
+
+	// A comment keeps the panel open, a new status changes what the project page allows
+	if action == models.APPROVAL_ACTION_COMMENT {
+		modal := components.ProjectApprovalModal(panel)
+		return adaptor.HTTPHandler(templ.Handler(modal))(c)
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Success", "The project is now "+strings.ToLower(panel.Approval.StatusLabel()), "/project/"+strconv.Itoa(projectId))
+}
+
+// findApprovalPanel returns the approval of a project with its history and the steps the user
+// can take, for anyone who can see the project
+func (h *ProjectApprovalsHandler) findApprovalPanel(ctx context.Context, tx *sql.Tx, projectId int, userData models.SessionUser) (models.ApprovalPanel, error) {
+	var panel models.ApprovalPanel
+
+	project, err := h.projectsRepo.FindById(ctx, tx, projectId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return panel, fiber.NewError(fiber.StatusNotFound, "Project not found")
+		}
+		return panel, err
+	}
+
+	role, err := projectRole(ctx, tx, h.projectMembersRepo, project, userData)
+	if err != nil {
+		return panel, err
+	}
+	if !models.ProjectRoleAllows(role, models.PROJECT_ROLE_VIEWER) {
+		return panel, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	approval, err := h.projectApprovalsRepo.FindByProjectId(ctx, tx, projectId)
+	if err != nil {
+		return panel, err
+	}
+
+	events, err := h.projectApprovalsRepo.FindEvents(ctx, tx, projectId)
+	if err != nil {
+		return panel, err
+	}
+
+	panel = models.ApprovalPanel{
+		Project:  project,
+		Role:     role,
+		Approval: approval,
+		Events:   events,
+	}
+	for _, action := range models.APPROVAL_ACTIONS {
+		if _, ok := approval.Apply(action); ok && models.CanTakeApprovalAction(userData, role, action) {
+			panel.Actions = append(panel.Actions, action)
+		}
+	}
+	panel.CanComment = models.CanTakeApprovalAction(userData, role, models.APPROVAL_ACTION_COMMENT)
+
+	return panel, nil
+}
+
+// checkProjectUnlocked refuses changes to the work items of a project that is submitted, in
+// review or approved
+func checkProjectUnlocked(ctx context.Context, tx *sql.Tx, projectApprovalsRepo project_approvals.Repository, projectId int) (int, error) {
+	approval, err := projectApprovalsRepo.FindByProjectId(ctx, tx, projectId)
+	if err != nil {
+		return fiber.StatusInternalServerError, err
+	}
+
+	if reason := approval.LockReason(); reason != "" {
+		return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, reason)
+	}
+
+	return fiber.StatusOK, nil
+}
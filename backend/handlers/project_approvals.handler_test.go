@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+func newTestApprovalsApp(user models.SessionUser, handler *ProjectApprovalsHandler) *fiber.App {
+	app := newTestAppFor(user)
+	app.Get("/approvals/:id", handler.ProjectApprovalModalView)
+	app.Post("/approvals/:id", handler.ChangeApprovalStatus)
+	return app
+}
+
+// TestApprovals_Workflow walks a project from draft to approved and into a new revision: the
+// owner submits, a reviewer the project is shared with reviews, rejects with a comment and
+// approves, and every status change is kept in the history and published
+func TestApprovals_Workflow(t *testing.T) {
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	membersRepo := newFakeProjectMembersRepo(models.ProjectMember{ProjectId: 1, UserId: 9, Role: models.PROJECT_ROLE_VIEWER})
+	approvalsRepo := newFakeProjectApprovalsRepo()
+	publisher := &fakePublisher{}
+	handler := NewProjectApprovalsHandler(&fakeDatabase{}, projectsRepo, membersRepo, approvalsRepo, &fakeProjectTotalsRepo{total: 2500000}, publisher)
+
+	owner := newTestApprovalsApp(models.SessionUser{ID: 7, Username: "budi", Role: models.ROLE_ESTIMATOR}, handler)
+	reviewer := newTestApprovalsApp(models.SessionUser{ID: 9, Username: "sari", Role: models.ROLE_REVIEWER}, handler)
+
+	step := func(app *fiber.App, action, comment string) string {
+		return responseBody(t, doRequest(t, app, http.MethodPost, "/approvals/1", url.Values{"action": {action}, "comment": {comment}}))
+	}
+	expectStatus := func(status string, revision int) {
+		t.Helper()
+		approval := approvalsRepo.approvals[1]
+		if approval.Status != status || approval.Revision != revision {
+			t.Fatalf("Expected %s revision %d, got %+v", status, revision, approval)
+		}
+	}
+
+	step(owner, models.APPROVAL_ACTION_SUBMIT, "")
+	expectStatus(models.APPROVAL_STATUS_SUBMITTED, 1)
+
+	step(reviewer, models.APPROVAL_ACTION_START_REVIEW, "")
+	expectStatus(models.APPROVAL_STATUS_IN_REVIEW, 1)
+
+	if body := step(reviewer, models.APPROVAL_ACTION_REJECT, " "); !strings.Contains(body, "A comment is required") {
+		t.Errorf("Expected a rejection without a comment to be refused, got %s", body)
+	}
+	expectStatus(models.APPROVAL_STATUS_IN_REVIEW, 1)
+
+	step(reviewer, models.APPROVAL_ACTION_REJECT, "Harga besi terlalu tinggi")
+	expectStatus(models.APPROVAL_STATUS_REJECTED, 1)
+
+	if body := step(owner, models.APPROVAL_ACTION_COMMENT, "Sudah diperbaiki"); !strings.Contains(body, "Sudah diperbaiki") || !strings.Contains(body, "Harga besi terlalu tinggi") {
+		t.Errorf("Expected the history with both comments, got %s", body)
+	}
+	expectStatus(models.APPROVAL_STATUS_REJECTED, 1)
+
+	step(owner, models.APPROVAL_ACTION_SUBMIT, "")
+	step(reviewer, models.APPROVAL_ACTION_START_REVIEW, "")
+	step(reviewer, models.APPROVAL_ACTION_APPROVE, "")
+	expectStatus(models.APPROVAL_STATUS_APPROVED, 1)
+
+	step(owner, models.APPROVAL_ACTION_NEW_REVISION, "")
+	expectStatus(models.APPROVAL_STATUS_DRAFT, 2)
+
+	if len(approvalsRepo.events) != 8 {
+		t.Fatalf("Expected eight history entries, got %+v", approvalsRepo.events)
+	}
+	rejected := approvalsRepo.events[2]
+	if rejected.Action != models.APPROVAL_ACTION_REJECT || rejected.FromStatus != models.APPROVAL_STATUS_IN_REVIEW || rejected.Comment != "Harga besi terlalu tinggi" || rejected.UserId != 9 || rejected.TotalCost != 2500000 {
+		t.Errorf("Unexpected rejection entry: %+v", rejected)
+	}
+	if comment := approvalsRepo.events[3]; comment.Action != models.APPROVAL_ACTION_COMMENT || comment.ToStatus != models.APPROVAL_STATUS_REJECTED {
+		t.Errorf("Expected the comment to keep the status, got %+v", comment)
+	}
+	if last := approvalsRepo.events[7]; last.Revision != 2 || last.ToStatus != models.APPROVAL_STATUS_DRAFT {
+		t.Errorf("Expected the new revision to be recorded, got %+v", last)
+	}
+
+	// the comment is history only, every status change is published
+	if len(publisher.events) != 7 {
+		t.Fatalf("Expected seven published events, got %v", publisher.events)
+	}
+	change, ok := publisher.data[2].(models.WebhookApprovalChange)
+	if !ok || publisher.events[2] != models.WEBHOOK_EVENT_APPROVAL_STATUS_CHANGED || change.FromStatus != models.APPROVAL_STATUS_IN_REVIEW || change.ToStatus != models.APPROVAL_STATUS_REJECTED || change.Username != "sari" || change.ProjectTotal != 2500000 {
+		t.Errorf("Unexpected published change: %s %+v", publisher.events[2], publisher.data[2])
+	}
+}
+
+// TestApprovals_CommentLength verifies the comment limit counts characters, so a comment in a
+// script of multi-byte letters can use the whole length
+func TestApprovals_CommentLength(t *testing.T) {
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	approvalsRepo := newFakeProjectApprovalsRepo()
+	handler := NewProjectApprovalsHandler(&fakeDatabase{}, projectsRepo, newFakeProjectMembersRepo(), approvalsRepo, &fakeProjectTotalsRepo{}, &fakePublisher{})
+	owner := newTestApprovalsApp(models.SessionUser{ID: 7, Username: "budi", Role: models.ROLE_ESTIMATOR}, handler)
+
+	comment := func(text string) string {
+		return responseBody(t, doRequest(t, owner, http.MethodPost, "/approvals/1", url.Values{"action": {models.APPROVAL_ACTION_COMMENT}, "comment": {text}}))
+	}
+
+	if body := comment(strings.Repeat("é", models.APPROVAL_COMMENT_MAX_LENGTH)); strings.Contains(body, "longer than") {
+		t.Errorf("Expected a comment of %d characters to be accepted, got %s", models.APPROVAL_COMMENT_MAX_LENGTH, body)
+	}
+	if body := comment(strings.Repeat("é", models.APPROVAL_COMMENT_MAX_LENGTH+1)); !strings.Contains(body, "longer than") {
+		t.Errorf("Expected a longer comment to be refused, got %s", body)
+	}
+	if len(approvalsRepo.events) != 1 {
+		t.Errorf("Expected only the first comment in the history, got %+v", approvalsRepo.events)
+	}
+}
+
+// TestApprovals_Permissions verifies each step needs its account permission and project role,
+// and can only be taken from the statuses it starts from
+func TestApprovals_Permissions(t *testing.T) {
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	membersRepo := newFakeProjectMembersRepo(
+		models.ProjectMember{ProjectId: 1, UserId: 8, Role: models.PROJECT_ROLE_VIEWER},
+		models.ProjectMember{ProjectId: 1, UserId: 9, Role: models.PROJECT_ROLE_VIEWER},
+	)
+
+	for _, tc := range []struct {
+		name     string
+		user     models.SessionUser
+		status   string
+		action   string
+		expected string // "" when the step is taken
+	}{
+		{"owner submits", models.SessionUser{ID: 7, Role: models.ROLE_ESTIMATOR}, models.APPROVAL_STATUS_DRAFT, models.APPROVAL_ACTION_SUBMIT, ""},
+		{"owner cannot approve", models.SessionUser{ID: 7, Role: models.ROLE_ESTIMATOR}, models.APPROVAL_STATUS_IN_REVIEW, models.APPROVAL_ACTION_APPROVE, "not allowed"},
+		{"viewer member cannot submit", models.SessionUser{ID: 8, Role: models.ROLE_ESTIMATOR}, models.APPROVAL_STATUS_DRAFT, models.APPROVAL_ACTION_SUBMIT, "not allowed"},
+		{"reviewer cannot submit", models.SessionUser{ID: 9, Role: models.ROLE_REVIEWER}, models.APPROVAL_STATUS_DRAFT, models.APPROVAL_ACTION_SUBMIT, "not allowed"},
+		{"reviewer approves", models.SessionUser{ID: 9, Role: models.ROLE_REVIEWER}, models.APPROVAL_STATUS_IN_REVIEW, models.APPROVAL_ACTION_APPROVE, ""},
+		{"review must start first", models.SessionUser{ID: 9, Role: models.ROLE_REVIEWER}, models.APPROVAL_STATUS_SUBMITTED, models.APPROVAL_ACTION_APPROVE, "cannot take this step"},
+		{"approved cannot be withdrawn", models.SessionUser{ID: 7, Role: models.ROLE_ESTIMATOR}, models.APPROVAL_STATUS_APPROVED, models.APPROVAL_ACTION_WITHDRAW, "cannot take this step"},
+		{"admin approves", models.SessionUser{ID: 7, Role: models.ROLE_ADMIN}, models.APPROVAL_STATUS_IN_REVIEW, models.APPROVAL_ACTION_APPROVE, ""},
+		{"stranger", models.SessionUser{ID: 10, Role: models.ROLE_REVIEWER}, models.APPROVAL_STATUS_IN_REVIEW, models.APPROVAL_ACTION_APPROVE, "Access denied"},
+		{"unknown action", models.SessionUser{ID: 7, Role: models.ROLE_ADMIN}, models.APPROVAL_STATUS_DRAFT, "publish", "Unknown approval action"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			approvalsRepo := newFakeProjectApprovalsRepo(models.ProjectApproval{ProjectId: 1, Status: tc.status, Revision: 1})
+			publisher := &fakePublisher{}
+			handler := NewProjectApprovalsHandler(&fakeDatabase{}, projectsRepo, membersRepo, approvalsRepo, &fakeProjectTotalsRepo{}, publisher)
+			app := newTestApprovalsApp(tc.user, handler)
+
+			body := responseBody(t, doRequest(t, app, http.MethodPost, "/approvals/1", url.Values{"action": {tc.action}}))
+			if tc.expected == "" {
+				if approvalsRepo.approvals[1].Status == tc.status || len(publisher.events) != 1 {
+					t.Errorf("Expected the step to be taken, got %s", body)
+				}
+				return
+			}
+
+			if !strings.Contains(body, tc.expected) {
+				t.Errorf("Expected %q, got %s", tc.expected, body)
+			}
+			if approvalsRepo.approvals[1].Status != tc.status || len(approvalsRepo.events) != 0 || len(publisher.events) != 0 {
+				t.Errorf("Expected nothing to change, got %+v %+v", approvalsRepo.approvals[1], approvalsRepo.events)
+			}
+		})
+	}
+}
+
+// TestWorkItems_LockedProject verifies the work items of a submitted or approved project cannot
+// be added, changed or removed from the project page, the JSON API or a RAB import
+func TestWorkItems_LockedProject(t *testing.T) {
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	membersRepo := newFakeProjectMembersRepo()
+
+	for _, status := range []string{models.APPROVAL_STATUS_SUBMITTED, models.APPROVAL_STATUS_IN_REVIEW, models.APPROVAL_STATUS_APPROVED} {
+		t.Run(status, func(t *testing.T) {
+			approvalsRepo := newFakeProjectApprovalsRepo(models.ProjectApproval{ProjectId: 1, Status: status, Revision: 1})
+			reason := approvalsRepo.approvals[1].LockReason()
+
+			// the work item repositories are nil, reaching them would panic
+			workItems := NewProjectWorkItemsHandler(&fakeDatabase{}, nil, nil, nil, nil, nil, nil, nil, projectsRepo, membersRepo, approvalsRepo, nil, &fakePublisher{})
+			workItemsAPI := NewWorkItemsAPIHandler(&fakeDatabase{}, projectsRepo, nil, nil, nil, nil, workItems)
+			rabImport := NewRabImportHandler(&fakeDatabase{}, nil, projectsRepo, membersRepo, approvalsRepo, workItems)
+
+			app := newTestApp(7)
+			app.Get("/project/:id/work-items/new", workItems.ProjectWorkItemCreateModalView)
+			app.Post("/project/:id/work-items", workItems.CreateProjectWorkItem)
+			app.Post("/project/:id/work-items/:workItemId/edit", workItems.UpdateProjectWorkItem)
+			app.Delete("/project/:id/work-items/:workItemId/delete", workItems.DeleteProjectWorkItem)
+			app.Post("/project/:id/import/commit", rabImport.CommitRabImport)
+			app.Put(API_V1_PATH+"/projects/:id/work-items/:workItemId", workItemsAPI.UpdateWorkItem)
+			app.Delete(API_V1_PATH+"/projects/:id/work-items/:workItemId", workItemsAPI.DeleteWorkItem)
+
+			form := url.Values{"category_id": {"1"}, "description": {"Galian tanah"}, "volume": {"10"}, "unit": {"m3"}}
+			for _, request := range []struct {
+				method string
+				target string
+				form   url.Values
+			}{
+				{http.MethodGet, "/project/1/work-items/new", nil},
+				{http.MethodPost, "/project/1/work-items", form},
+				{http.MethodPost, "/project/1/work-items/3/edit", form},
+				{http.MethodDelete, "/project/1/work-items/3/delete", nil},
+				{http.MethodPost, "/project/1/import/commit", url.Values{"sheet_data": {`[["Uraian","Volume","Satuan","Harga"],["Galian","1","m3","1000"]]`}}},
+			} {
+				if body := responseBody(t, doRequest(t, app, request.method, request.target, request.form)); !strings.Contains(body, reason) {
+					t.Errorf("%s %s: expected %q, got %s", request.method, request.target, reason, body)
+				}
+			}
+
+			body := `{"category_id":1,"description":"Galian tanah","volume":10,"unit":"m3","manual_costs":[{"item_type":"LABOR","item_name":"Pekerja","quantity":1,"unit":"OH","unit_price":100000}]}`
+			if problem := expectProblem(t, doJSONRequest(t, app, http.MethodPut, API_V1_PATH+"/projects/1/work-items/3", body), http.StatusConflict); problem.Detail != reason {
+				t.Errorf("Expected the API to explain the lock, got %+v", problem)
+			}
+			expectProblem(t, doJSONRequest(t, app, http.MethodDelete, API_V1_PATH+"/projects/1/work-items/3", ""), http.StatusConflict)
+		})
+	}
+}
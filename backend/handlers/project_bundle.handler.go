@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/project_bundle"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// ProjectBundleHandler moves whole projects between installs as JSON bundle files
+type ProjectBundleHandler struct {
+	dbService    databases.DatabaseServices
+	bundler      *project_bundle.Bundler
+	projectsRepo projects.Repository
+}
+
+func NewProjectBundleHandler(
+	dbService databases.DatabaseServices,
+	bundler *project_bundle.Bundler,
+	projectsRepo projects.Repository,
+) *ProjectBundleHandler {
+	return &ProjectBundleHandler{
+		dbService:    dbService,
+		bundler:      bundler,
+		projectsRepo: projectsRepo,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+func (h *ProjectBundleHandler) ProjectBundleImportModalView(c *fiber.Ctx) error {
+	modal := components.ProjectBundleImportModal()
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// ExportProjectBundle downloads the project as a .rab.json bundle
+func (h *ProjectBundleHandler) ExportProjectBundle(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid project ID")
+	}
+
+	// Get user from session (using the same approach as in auth.handler.go)
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var bundle models.ProjectBundle
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		if status, err := checkProjectOwner(ctx, tx, h.projectsRepo, projectId, userData); err != nil {
+			return status, err
+		}
+
+		bundle, err = h.bundler.Export(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).SendString(fiberErr.Message)
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString("Export failed")
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Export failed")
+	}
+
+	c.Set("Content-Type", "application/json")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=project-%d.rab.json", projectId))
+	return c.Send(data)
+}
+
+// ImportProjectBundle creates a new project of the user from an uploaded bundle, all or nothing
+func (h *ProjectBundleHandler) ImportProjectBundle(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	// Get user from session (using the same approach as in auth.handler.go)
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Import Error", "Choose a project bundle file to import")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to read uploaded file")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to read uploaded file")
+	}
+
+	bundle, err := project_bundle.Parse(data)
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Import Error", err.Error())
+	}
+
+	var result models.ProjectBundleImportResult
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		result, err = h.bundler.Import(ctx, tx, bundle, userData.Workspace())
+		if err != nil {
+			return fiber.StatusBadRequest, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Import failed, nothing was saved: "+err.Error())
+	}
+
+	message := fmt.Sprintf(
+		"%d work items imported. Materials: %d matched, %d created. Labor types: %d matched, %d created. AHSP templates: %d matched, %d created.",
+		result.WorkItems,
+		result.MaterialsMatched,
+		result.MaterialsCreated,
+		result.LaborTypesMatched,
+		result.LaborTypesCreated,
+		result.TemplatesMatched,
+		result.TemplatesCreated,
+	)
+
+	return utils.ResponseSuccessWithRedirect(c, "Project Imported", message, "/project/"+strconv.Itoa(result.ProjectId))
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+const (
+	RAB_SHEET_DETAIL  = "RAB"
+	RAB_SHEET_SUMMARY = "Summary"
+)
+
+type ProjectExportHandler struct {
+	dbService            databases.DatabaseServices
+	projectsRepo         projects.Repository
+	projectMembersRepo   project_members.Repository
+	projectWorkItemsRepo project_work_items.Repository
+	projectItemCostsRepo project_item_costs.Repository
+}
+
+func NewProjectExportHandler(
+	dbService databases.DatabaseServices,
+	projectsRepo projects.Repository,
+	projectMembersRepo project_members.Repository,
+	projectWorkItemsRepo project_work_items.Repository,
+	projectItemCostsRepo project_item_costs.Repository,
+) *ProjectExportHandler {
+	return &ProjectExportHandler{
+		dbService:            dbService,
+		projectsRepo:         projectsRepo,
+		projectMembersRepo:   projectMembersRepo,
+		projectWorkItemsRepo: projectWorkItemsRepo,
+		projectItemCostsRepo: projectItemCostsRepo,
+	}
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// ExportProjectRab exports the bill of quantities of a project as an Excel workbook with live formulas
+func (h *ProjectExportHandler) ExportProjectRab(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid project ID")
+	}
+
+	// Get user from session
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	// First, fetch data in transaction
+	var project models.Project
+	var workItems []models.ProjectWorkItemWithDetails
+	var itemTotals map[int]float64
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, err = h.projectsRepo.FindById(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+		if project.ProjectId == 0 {
+			return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
+		}
+
+		// Check the project is the user's, one of their organizations' or shared with them
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_VIEWER); err != nil {
+			return status, err
+		}
+
+		workItems, err = h.projectWorkItemsRepo.FindByProjectIdWithDetails(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		itemTotals, err = projectItemTotals(ctx, tx, h.projectItemCostsRepo, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).SendString(fiberErr.Message)
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString("Export failed")
+	}
+
+	// Then, export OUTSIDE of transaction (file is sent directly)
+	return sendRabWorkbook(c, project, workItems, itemTotals)
+}
+
+// sendRabWorkbook sends the RAB workbook of a project as a download
+func sendRabWorkbook(c *fiber.Ctx, project models.Project, workItems []models.ProjectWorkItemWithDetails, itemTotals map[int]float64) error {
+	excelData, err := buildRabWorkbook(project, workItems, itemTotals)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Export failed")
+	}
+
+	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Set("Content-Disposition", utils.AttachmentDisposition("rab-"+project.ProjectName+".xlsx"))
+
+	return c.Send(excelData)
+}
+
+// projectItemTotals sums the costs of a project per work item
+func projectItemTotals(ctx context.Context, tx *sql.Tx, projectItemCostsRepo project_item_costs.Repository, projectId int) (map[int]float64, error) {
+	costs, err := projectItemCostsRepo.FindByProjectId(ctx, tx, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	itemTotals := map[int]float64{}
+	for _, cost := range costs {
+		itemTotals[cost.WorkItemId] += cost.TotalCost
+	}
+
+	return itemTotals, nil
+}
+
+// recapByCategory totals the work items per category, in the order of the work item list like
+// the summary sheet of the workbook
+func recapByCategory(workItems []models.ProjectWorkItemWithDetails, itemTotals map[int]float64) []models.CategoryRecap {
+	var recap []models.CategoryRecap
+	index := map[string]int{}
+	for _, workItem := range workItems {
+		categoryName := workItem.CategoryName
+		if categoryName == "" {
+			categoryName = models.WORK_ITEM_UNCATEGORIZED
+		}
+		i, ok := index[categoryName]
+		if !ok {
+			i = len(recap)
+			index[categoryName] = i
+			recap = append(recap, models.CategoryRecap{CategoryName: categoryName})
+		}
+		recap[i].ItemCount++
+		recap[i].TotalCost += itemTotals[workItem.WorkItemId]
+	}
+
+	return recap
+}
+
+// buildRabWorkbook writes the RAB detail sheet grouped by work category, where every amount is
+// volume × unit price and every subtotal a SUM, and a summary sheet referencing those subtotals
+func buildRabWorkbook(project models.Project, workItems []models.ProjectWorkItemWithDetails, itemTotals map[int]float64) ([]byte, error) {
+	// group by category, keeping the order of the work item list
+	var categoryNames []string
+	itemsByCategory := map[string][]models.ProjectWorkItemWithDetails{}
+	for _, workItem := range workItems {
+		categoryName := workItem.CategoryName
+		if categoryName == "" {
+			categoryName = models.WORK_ITEM_UNCATEGORIZED
+		}
+		if _, ok := itemsByCategory[categoryName]; !ok {
+			categoryNames = append(categoryNames, categoryName)
+		}
+		itemsByCategory[categoryName] = append(itemsByCategory[categoryName], workItem)
+	}
+
+	subtitles := []string{
+		"Project: " + project.ProjectName,
+		"Location: " + project.Location,
+		"Client: " + project.ClientName,
+	}
+
+	detailOptions := utils.ExcelSheetOptions{
+		Title:         "Bill of Quantities (RAB)",
+		Subtitles:     subtitles,
+		ColumnWidths:  []float64{6, 50, 12, 10, 18, 20},
+		ColumnFormats: []string{"", "", utils.EXCEL_FORMAT_NUMBER, "", utils.EXCEL_FORMAT_RUPIAH, utils.EXCEL_FORMAT_RUPIAH},
+		FreezeHeader:  true,
+	}
+
+	var detailRows [][]interface{}
+	var subtotalRows []int // sheet row numbers of the category subtotals
+	firstRow := detailOptions.FirstDataRow()
+
+	for i, categoryName := range categoryNames {
+		letter, err := excelColumnLetter(i + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		detailOptions.BoldRows = append(detailOptions.BoldRows, len(detailRows))
+		detailRows = append(detailRows, []interface{}{letter, categoryName, "", "", "", ""})
+
+		itemsFirstRow := firstRow + len(detailRows)
+		for j, workItem := range itemsByCategory[categoryName] {
+			unitPrice := 0.0
+			if workItem.Volume > 0 {
+				unitPrice = itemTotals[workItem.WorkItemId] / workItem.Volume
+			}
+
+			detailRows = append(detailRows, []interface{}{
+				j + 1,
+				workItem.Description,
+				workItem.Volume,
+				workItem.Unit,
+				unitPrice,
+				utils.ExcelFormula("C{row}*E{row}"),
+			})
+		}
+		itemsLastRow := firstRow + len(detailRows) - 1
+
+		subtotalRows = append(subtotalRows, firstRow+len(detailRows))
+		detailOptions.BoldRows = append(detailOptions.BoldRows, len(detailRows))
+		detailRows = append(detailRows, []interface{}{
+			"",
+			"Subtotal " + letter,
+			"",
+			"",
+			"",
+			utils.ExcelFormula(fmt.Sprintf("SUM(F%d:F%d)", itemsFirstRow, itemsLastRow)),
+		})
+	}
+
+	detailOptions.BoldRows = append(detailOptions.BoldRows, len(detailRows))
+	detailRows = append(detailRows, []interface{}{"", "Total", "", "", "", utils.ExcelFormula(sumOfCells("F", subtotalRows))})
+
+	summaryOptions := utils.ExcelSheetOptions{
+		Title:         "Cost Summary (Rekapitulasi)",
+		Subtitles:     subtitles,
+		ColumnWidths:  []float64{6, 50, 22},
+		ColumnFormats: []string{"", "", utils.EXCEL_FORMAT_RUPIAH},
+		FreezeHeader:  true,
+	}
+
+	var summaryRows [][]interface{}
+	for i, categoryName := range categoryNames {
+		letter, err := excelColumnLetter(i + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		summaryRows = append(summaryRows, []interface{}{
+			letter,
+			categoryName,
+			utils.ExcelFormula(utils.ExcelSheetCellRef(RAB_SHEET_DETAIL, 6, subtotalRows[i])),
+		})
+	}
+
+	summaryTotal := utils.ExcelFormula("0")
+	if len(summaryRows) > 0 {
+		summaryTotal = utils.ExcelFormula(fmt.Sprintf("SUM(C%d:C%d)", summaryOptions.FirstDataRow(), summaryOptions.FirstDataRow()+len(summaryRows)-1))
+	}
+	summaryOptions.BoldRows = []int{len(summaryRows)}
+	summaryRows = append(summaryRows, []interface{}{"", "Total", summaryTotal})
+
+	excel := utils.NewExcelExporter()
+	if err := excel.AddSheetWithOptions(RAB_SHEET_SUMMARY, []string{"No", "Work Category", "Amount"}, summaryRows, summaryOptions); err != nil {
+		return nil, err
+	}
+	if err := excel.AddSheetWithOptions(RAB_SHEET_DETAIL, []string{"No", "Description", "Volume", "Unit", "Unit Price", "Amount"}, detailRows, detailOptions); err != nil {
+		return nil, err
+	}
+
+	return excel.Write()
+}
+
+// excelColumnLetter numbers categories A, B, ... Z, AA like spreadsheet columns
+func excelColumnLetter(index int) (string, error) {
+	name := utils.ExcelCellName(index, 1)
+	if name == "" {
+		return "", fmt.Errorf("invalid category index %d", index)
+	}
+	return name[:len(name)-1], nil
+}
+
+// sumOfCells adds up single cells of one column, e.g. F12+F20+F31
+func sumOfCells(col string, rows []int) string {
+	if len(rows) == 0 {
+		return "0"
+	}
+
+	formula := ""
+	for i, row := range rows {
+		if i > 0 {
+			formula += "+"
+		}
+		formula += col + strconv.Itoa(row)
+	}
+	return formula
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// TestExportProjectRab_Refused verifies a missing project is not found and another user's
+// project is refused, rather than both failing as a server error
+func TestExportProjectRab_Refused(t *testing.T) {
+	repo := newFakeProjectsRepo(models.Project{ProjectId: 2, UserId: 8, ProjectName: "Gudang"})
+	handler := NewProjectExportHandler(&fakeDatabase{}, repo, newFakeProjectMembersRepo(), nil, nil)
+
+	app := newTestApp(7)
+	app.Get("/project/:id/export", handler.ExportProjectRab)
+
+	for target, expected := range map[string]int{
+		"/project/99/export": http.StatusNotFound,
+		"/project/2/export":  http.StatusForbidden,
+	} {
+		if resp := doRequest(t, app, http.MethodGet, target, nil); resp.StatusCode != expected {
+			t.Errorf("%s: expected %d, got %d", target, expected, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/organizations"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/repository/users"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// ProjectMembersHandler shares a project with single users, as editors or viewers
+type ProjectMembersHandler struct {
+	dbService          databases.DatabaseServices
+	projectsRepo       projects.Repository
+	projectMembersRepo project_members.Repository
+	usersRepo          users.Repository
+	orgsRepo           organizations.Repository
+}
+
+func NewProjectMembersHandler(
+	dbService databases.DatabaseServices,
+	projectsRepo projects.Repository,
+	projectMembersRepo project_members.Repository,
+	usersRepo users.Repository,
+	orgsRepo organizations.Repository,
+) *ProjectMembersHandler {
+	return &ProjectMembersHandler{
+		dbService:          dbService,
+		projectsRepo:       projectsRepo,
+		projectMembersRepo: projectMembersRepo,
+		usersRepo:          usersRepo,
+		orgsRepo:           orgsRepo,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+// ProjectMembersModalView lists who a project is shared with, for its owner to invite and remove them
+func (h *ProjectMembersHandler) ProjectMembersModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var project models.Project
+	var members []models.ProjectMember
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, members, err = h.findSharing(ctx, tx, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to fetch project members")
+	}
+
+	modal := components.ProjectMembersModal(project, members)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// InviteProjectMember shares the project with a user by username, or changes their role when it already is
+func (h *ProjectMembersHandler) InviteProjectMember(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	username := strings.TrimSpace(c.FormValue("username"))
+	if username == "" {
+		return utils.ResponseErrorModal(c, "Validation Error", "Username is required")
+	}
+
+	role := c.FormValue("role")
+	if !models.IsProjectMemberRole(role) {
+		return utils.ResponseErrorModal(c, "Validation Error", "Unknown project role "+role)
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var project models.Project
+	var members []models.ProjectMember
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, _, err = h.findSharing(ctx, tx, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		user, err := h.usersRepo.FindByUsername(ctx, tx, username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "User "+username+" not found")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		if user.IsDisabled() {
+			return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "User "+username+" is disabled")
+		}
+
+		// the owners already have full access, a membership would only lower it on paper
+		if project.OrgId != 0 {
+			if _, err := h.orgsRepo.FindMember(ctx, tx, project.OrgId, user.UserId); err == nil {
+				return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, username+" already has access through the organization")
+			} else if err != sql.ErrNoRows {
+				return fiber.StatusInternalServerError, err
+			}
+		} else if user.UserId == project.UserId {
+			return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, username+" owns this project")
+		}
+
+		if err := h.projectMembersRepo.Save(ctx, tx, models.ProjectMember{
+			ProjectId: projectId,
+			UserId:    user.UserId,
+			Role:      role,
+			InvitedBy: userData.ID,
+		}); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		members, err = h.projectMembersRepo.FindByProjectId(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to share project")
+	}
+
+	modal := components.ProjectMembersModal(project, members)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// RemoveProjectMember stops sharing the project with a user. The owner removes anyone, a member can leave.
+func (h *ProjectMembersHandler) RemoveProjectMember(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	memberId, err := strconv.Atoi(c.Params("userId"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid user ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+	leaving := memberId == userData.ID
+
+	var project models.Project
+	var members []models.ProjectMember
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if !leaving {
+			if project, _, err = h.findSharing(ctx, tx, projectId, userData); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+		}
+
+		if _, err := h.projectMembersRepo.FindMember(ctx, tx, projectId, memberId); err != nil {
+			if err == sql.ErrNoRows {
+				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "The project is not shared with this user")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.projectMembersRepo.Remove(ctx, tx, projectId, memberId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if !leaving {
+			if members, err = h.projectMembersRepo.FindByProjectId(ctx, tx, projectId); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to remove member")
+	}
+
+	if leaving {
+		return utils.ResponseSuccessWithRedirect(c, "Success", "You left the project", "/projects")
+	}
+
+	modal := components.ProjectMembersModal(project, members)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// findSharing returns the project with its members when the user owns it, only owners manage sharing
+func (h *ProjectMembersHandler) findSharing(ctx context.Context, tx *sql.Tx, projectId int, userData models.SessionUser) (models.Project, []models.ProjectMember, error) {
+	project, err := h.projectsRepo.FindById(ctx, tx, projectId)
+	if err != nil {
+		return project, nil, err
+	}
+
+	if !userData.OwnsProject(project) {
+		return project, nil, fiber.NewError(fiber.StatusForbidden, "Only the owner of the project can share it")
+	}
+
+	members, err := h.projectMembersRepo.FindByProjectId(ctx, tx, projectId)
+	return project, members, err
+}
+
+// findProject loads a project for an access check, answering 404 when it does not exist
+func findProject(ctx context.Context, tx *sql.Tx, projectsRepo projects.Repository, projectId int) (models.Project, int, error) {
+	project, err := projectsRepo.FindById(ctx, tx, projectId)
+	if err != nil && err != sql.ErrNoRows {
+		return project, fiber.StatusInternalServerError, err
+	}
+	if err == sql.ErrNoRows || project.ProjectId == 0 {
+		return project, fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
+	}
+
+	return project, fiber.StatusOK, nil
+}
+
+// checkProjectOwner verifies the project exists and belongs to the user or one of their organizations
+func checkProjectOwner(ctx context.Context, tx *sql.Tx, projectsRepo projects.Repository, projectId int, userData models.SessionUser) (int, error) {
+	project, status, err := findProject(ctx, tx, projectsRepo, projectId)
+	if err != nil {
+		return status, err
+	}
+
+	if !userData.OwnsProject(project) {
+		return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return fiber.StatusOK, nil
+}
+
+// projectRole returns the role of the user in the project: owner for their own projects and
+// those of their organizations, the shared role for a project shared with them, "" otherwise
+func projectRole(ctx context.Context, tx *sql.Tx, projectMembersRepo project_members.Repository, project models.Project, userData models.SessionUser) (string, error) {
+	if userData.OwnsProject(project) {
+		return models.PROJECT_ROLE_OWNER, nil
+	}
+
+	if project.ProjectId == 0 {
+		return "", nil
+	}
+
+	member, err := projectMembersRepo.FindMember(ctx, tx, project.ProjectId, userData.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+// checkProjectRole verifies the user has at least the required role in the project
+func checkProjectRole(ctx context.Context, tx *sql.Tx, projectMembersRepo project_members.Repository, project models.Project, userData models.SessionUser, required string) (int, error) {
+	role, err := projectRole(ctx, tx, projectMembersRepo, project, userData)
+	if err != nil {
+		return fiber.StatusInternalServerError, err
+	}
+
+	if !models.ProjectRoleAllows(role, required) {
+		return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return fiber.StatusOK, nil
+}
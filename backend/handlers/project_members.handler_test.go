@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// TestCheckProjectRole verifies owners and organization members have full access, members of
+// a shared project have their role, and everyone else, or a missing project, none
+func TestCheckProjectRole(t *testing.T) {
+	ctx := t.Context()
+	members := newFakeProjectMembersRepo(
+		models.ProjectMember{ProjectId: 1, UserId: 8, Role: models.PROJECT_ROLE_EDITOR},
+		models.ProjectMember{ProjectId: 1, UserId: 9, Role: models.PROJECT_ROLE_VIEWER},
+	)
+	own := models.Project{ProjectId: 1, UserId: 7}
+	orgProject := models.Project{ProjectId: 2, UserId: 7, OrgId: 3}
+
+	tests := []struct {
+		name     string
+		user     models.SessionUser
+		project  models.Project
+		required string
+		allowed  bool
+	}{
+		{"owner edits", models.SessionUser{ID: 7}, own, models.PROJECT_ROLE_OWNER, true},
+		{"organization member edits", models.SessionUser{ID: 10, OrgIds: []int{3}}, orgProject, models.PROJECT_ROLE_OWNER, true},
+		{"former creator outside the organization", models.SessionUser{ID: 7}, orgProject, models.PROJECT_ROLE_VIEWER, false},
+		{"editor edits work items", models.SessionUser{ID: 8}, own, models.PROJECT_ROLE_EDITOR, true},
+		{"editor does not manage sharing", models.SessionUser{ID: 8}, own, models.PROJECT_ROLE_OWNER, false},
+		{"viewer reads", models.SessionUser{ID: 9}, own, models.PROJECT_ROLE_VIEWER, true},
+		{"viewer does not edit", models.SessionUser{ID: 9}, own, models.PROJECT_ROLE_EDITOR, false},
+		{"stranger", models.SessionUser{ID: 11}, own, models.PROJECT_ROLE_VIEWER, false},
+		{"missing project", models.SessionUser{ID: 7}, models.Project{}, models.PROJECT_ROLE_VIEWER, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := checkProjectRole(ctx, nil, members, tt.project, tt.user, tt.required)
+			if tt.allowed && err != nil {
+				t.Errorf("Expected access, got %d %v", status, err)
+			}
+			if !tt.allowed && status != http.StatusForbidden {
+				t.Errorf("Expected 403, got %d %v", status, err)
+			}
+		})
+	}
+}
+
+// TestCheckProjectOwner verifies a missing project answers 404 rather than access denied
+func TestCheckProjectOwner(t *testing.T) {
+	ctx := t.Context()
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7})
+
+	tests := []struct {
+		name      string
+		userId    int
+		projectId int
+		want      int
+	}{
+		{"owner", 7, 1, http.StatusOK},
+		{"stranger", 8, 1, http.StatusForbidden},
+		{"missing project", 7, 2, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := checkProjectOwner(ctx, nil, projectsRepo, tt.projectId, models.SessionUser{ID: tt.userId})
+			if status != tt.want {
+				t.Errorf("Expected %d, got %d %v", tt.want, status, err)
+			}
+		})
+	}
+}
+
+// TestProjectMembers_Invite verifies the owner shares a project by username and changes the
+// role by inviting again, while others cannot share it and the owner cannot be invited
+func TestProjectMembers_Invite(t *testing.T) {
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	membersRepo := newFakeProjectMembersRepo()
+	usersRepo := newFakeUsersRepo(
+		models.User{UserId: 7, Username: "budi"},
+		models.User{UserId: 8, Username: "sari"},
+	)
+	handler := NewProjectMembersHandler(&fakeDatabase{}, projectsRepo, membersRepo, usersRepo, newFakeOrganizationsRepo())
+
+	owner := newTestApp(7)
+	owner.Post("/project/:id/members", handler.InviteProjectMember)
+	owner.Delete("/project/:id/members/:userId", handler.RemoveProjectMember)
+
+	if body := responseBody(t, doRequest(t, owner, http.MethodPost, "/project/1/members", url.Values{"username": {"sari"}, "role": {models.PROJECT_ROLE_VIEWER}})); !strings.Contains(body, "/project/1/members/8") {
+		t.Fatalf("Expected sari to be listed, got %s", body)
+	}
+	if body := responseBody(t, doRequest(t, owner, http.MethodPost, "/project/1/members", url.Values{"username": {"sari"}, "role": {models.PROJECT_ROLE_EDITOR}})); strings.Contains(body, "Error") {
+		t.Fatalf("Expected the role change to succeed, got %s", body)
+	}
+	if len(membersRepo.members) != 1 || membersRepo.members[0].Role != models.PROJECT_ROLE_EDITOR || membersRepo.members[0].InvitedBy != 7 {
+		t.Errorf("Expected sari as editor invited by budi, got %+v", membersRepo.members)
+	}
+
+	if body := responseBody(t, doRequest(t, owner, http.MethodPost, "/project/1/members", url.Values{"username": {"budi"}, "role": {models.PROJECT_ROLE_VIEWER}})); !strings.Contains(body, "budi owns this project") {
+		t.Errorf("Expected inviting the owner to be refused, got %s", body)
+	}
+	if body := responseBody(t, doRequest(t, owner, http.MethodPost, "/project/1/members", url.Values{"username": {"sari"}, "role": {models.PROJECT_ROLE_OWNER}})); !strings.Contains(body, "Unknown project role") {
+		t.Errorf("Expected the owner role to be refused, got %s", body)
+	}
+
+	// an editor works on the project but does not share it further
+	editor := newTestApp(8)
+	editor.Post("/project/:id/members", handler.InviteProjectMember)
+	editor.Delete("/project/:id/members/:userId", handler.RemoveProjectMember)
+
+	if body := responseBody(t, doRequest(t, editor, http.MethodPost, "/project/1/members", url.Values{"username": {"budi"}, "role": {models.PROJECT_ROLE_VIEWER}})); !strings.Contains(body, "Only the owner of the project can share it") {
+		t.Errorf("Expected an editor to be refused, got %s", body)
+	}
+
+	if body := responseBody(t, doRequest(t, editor, http.MethodDelete, "/project/1/members/8", nil)); !strings.Contains(body, "You left the project") {
+		t.Fatalf("Expected sari to leave the project, got %s", body)
+	}
+	if len(membersRepo.members) != 0 {
+		t.Errorf("Expected no members left, got %+v", membersRepo.members)
+	}
+}
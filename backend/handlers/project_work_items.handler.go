@@ -1,11 +1,11 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"strconv"
-	"time"
 
 	"github.com/a-h/templ"
 	"github.com/gofiber/fiber/v2"
@@ -19,33 +19,46 @@ import (
 	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
 	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
 	master_work_categories "github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
 	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
 	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
 	"github.com/momokii/go-rab-maker/backend/repository/projects"
 	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
 	"github.com/momokii/go-rab-maker/frontend/components"
 )
 
 type ProjectWorkItemsHandler struct {
-	dbService                  databases.SQLiteServices
-	projectWorkItemsRepo       *project_work_items.ProjectWorkItemRepo
-	projectItemCostsRepo       *project_item_costs.ProjectItemCostsRepo
-	ahspTemplatesRepo          *ahsptemplates.AhspTemplatesRepo
-	ahspMaterialComponentsRepo *ahsp_material_components.AHSPMaterialComponentsRepo
-	masterMaterialsRepo        *master_materials.MasterMaterialsRepo
-	masterLaborTypesRepo       *master_labor_types.MasterLaborTypesRepo
-	ahspLaborComponentsRepo    *ahsp_labor_components.AHSPLaborComponentsRepo
+	dbService                  databases.DatabaseServices
+	projectWorkItemsRepo       project_work_items.Repository
+	projectItemCostsRepo       project_item_costs.Repository
+	ahspTemplatesRepo          ahsptemplates.Repository
+	ahspMaterialComponentsRepo ahsp_material_components.Repository
+	masterMaterialsRepo        master_materials.Repository
+	masterLaborTypesRepo       master_labor_types.Repository
+	ahspLaborComponentsRepo    ahsp_labor_components.Repository
+	projectsRepo               projects.Repository
+	projectMembersRepo         project_members.Repository
+	projectApprovalsRepo       project_approvals.Repository
+	workCategoriesRepo         master_work_categories.Repository
+	publisher                  webhook_dispatch.Publisher
 }
 
 func NewProjectWorkItemsHandler(
-	dbService databases.SQLiteServices,
-	projectWorkItemsRepo *project_work_items.ProjectWorkItemRepo,
-	projectItemCostsRepo *project_item_costs.ProjectItemCostsRepo,
-	ahspTemplatesRepo *ahsptemplates.AhspTemplatesRepo,
-	ahspMaterialComponentsRepo *ahsp_material_components.AHSPMaterialComponentsRepo,
-	masterMaterialsRepo *master_materials.MasterMaterialsRepo,
-	masterLaborTypesRepo *master_labor_types.MasterLaborTypesRepo,
-	ahspLaborComponentsRepo *ahsp_labor_components.AHSPLaborComponentsRepo,
+	dbService databases.DatabaseServices,
+	projectWorkItemsRepo project_work_items.Repository,
+	projectItemCostsRepo project_item_costs.Repository,
+	ahspTemplatesRepo ahsptemplates.Repository,
+	ahspMaterialComponentsRepo ahsp_material_components.Repository,
+	masterMaterialsRepo master_materials.Repository,
+	masterLaborTypesRepo master_labor_types.Repository,
+	ahspLaborComponentsRepo ahsp_labor_components.Repository,
+	projectsRepo projects.Repository,
+	projectMembersRepo project_members.Repository,
+	projectApprovalsRepo project_approvals.Repository,
+	workCategoriesRepo master_work_categories.Repository,
+	publisher webhook_dispatch.Publisher,
 ) *ProjectWorkItemsHandler {
 	return &ProjectWorkItemsHandler{
 		dbService:                  dbService,
@@ -56,6 +69,11 @@ func NewProjectWorkItemsHandler(
 		masterMaterialsRepo:        masterMaterialsRepo,
 		masterLaborTypesRepo:       masterLaborTypesRepo,
 		ahspLaborComponentsRepo:    ahspLaborComponentsRepo,
+		projectsRepo:               projectsRepo,
+		projectMembersRepo:         projectMembersRepo,
+		projectApprovalsRepo:       projectApprovalsRepo,
+		workCategoriesRepo:         workCategoriesRepo,
+		publisher:                  publisher,
 	}
 }
 
@@ -65,6 +83,8 @@ func NewProjectWorkItemsHandler(
 
 // ProjectDetailPage displays the project detail page with work items
 func (h *ProjectWorkItemsHandler) ProjectDetailPage(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	projectId, err := strconv.Atoi(projectIdStr)
 	if err != nil {
@@ -75,14 +95,15 @@ func (h *ProjectWorkItemsHandler) ProjectDetailPage(c *fiber.Ctx) error {
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	var project models.Project
+	var role string
+	var approval models.ProjectApproval
 	var workItems []models.ProjectWorkItemWithDetails
 	var totalCost float64
 
 	// Fetch project data
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get project details
-		projectsRepo := projects.NewProjectsRepo()
-		project, err = projectsRepo.FindById(tx, projectId)
+		project, err = h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -90,19 +111,28 @@ func (h *ProjectWorkItemsHandler) ProjectDetailPage(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if project.UserId != userData.ID {
+		// Check the project is the user's, one of their organizations' or shared with them
+		role, err = projectRole(ctx, tx, h.projectMembersRepo, project, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+		if !models.ProjectRoleAllows(role, models.PROJECT_ROLE_VIEWER) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
+		approval, err = h.projectApprovalsRepo.FindByProjectId(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
 		// Get work items with details
-		workItems, err = h.projectWorkItemsRepo.FindByProjectIdWithDetails(tx, projectId)
+		workItems, err = h.projectWorkItemsRepo.FindByProjectIdWithDetails(ctx, tx, projectId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
 		// Get total project cost
-		totalCost, err = h.projectWorkItemsRepo.GetProjectTotalCost(tx, projectId)
+		totalCost, err = h.projectWorkItemsRepo.GetProjectTotalCost(ctx, tx, projectId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -113,12 +143,14 @@ func (h *ProjectWorkItemsHandler) ProjectDetailPage(c *fiber.Ctx) error {
 	}
 
 	// Render the project detail page
-	projectDetailComponent := components.ProjectDetailPage(project, workItems, totalCost)
+	projectDetailComponent := components.ProjectDetailPage(project, role, approval, workItems, totalCost)
 	return adaptor.HTTPHandler(templ.Handler(projectDetailComponent))(c)
 }
 
 // ProjectWorkItemCreateModalView displays the modal to create a new work item
 func (h *ProjectWorkItemsHandler) ProjectWorkItemCreateModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	projectId, err := strconv.Atoi(projectIdStr)
 	if err != nil {
@@ -133,10 +165,9 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemCreateModalView(c *fiber.Ctx) e
 	var templates []models.AHSPTemplate
 
 	// Fetch required data
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get project details
-		projectsRepo := projects.NewProjectsRepo()
-		project, err = projectsRepo.FindById(tx, projectId)
+		project, err = h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -144,24 +175,28 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemCreateModalView(c *fiber.Ctx) e
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if project.UserId != userData.ID {
-			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		// Check the project is the user's, one of their organizations' or shared with them for editing
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_EDITOR); err != nil {
+			return status, err
+		}
+
+		// Submitted and approved projects keep their work items as the reviewers saw them
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
 		}
 
 		// Get work categories
-		workCategoriesRepo := master_work_categories.NewMasterWorkCategoriesRepo()
 		paginationData := models.TablePaginationDataInput{
 			Page:    1,
 			PerPage: 1000, // Get all categories
 		}
-		categoriesData, _, err := workCategoriesRepo.Find(tx, paginationData)
+		categoriesData, _, err := h.workCategoriesRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
 		// Get AHSP templates
-		templatesData, _, err := h.ahspTemplatesRepo.Find(tx, paginationData, userData.ID)
+		templatesData, _, err := h.ahspTemplatesRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -171,6 +206,9 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemCreateModalView(c *fiber.Ctx) e
 
 		return fiber.StatusOK, nil
 	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
 		return utils.ResponseErrorModal(c, "Error", "Failed to fetch required data")
 	}
 
@@ -189,6 +227,8 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemCreateModalView(c *fiber.Ctx) e
 
 // ProjectWorkItemEditModalView displays the modal to edit a work item
 func (h *ProjectWorkItemsHandler) ProjectWorkItemEditModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	workItemIdStr := c.Params("workItemId")
 
@@ -212,10 +252,9 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemEditModalView(c *fiber.Ctx) err
 	var allCosts []models.ProjectItemCostWithDetails
 
 	// Fetch required data
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get project details
-		projectsRepo := projects.NewProjectsRepo()
-		project, err = projectsRepo.FindById(tx, projectId)
+		project, err = h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -223,13 +262,18 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemEditModalView(c *fiber.Ctx) err
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if project.UserId != userData.ID {
-			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		// Check the project is the user's, one of their organizations' or shared with them for editing
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_EDITOR); err != nil {
+			return status, err
+		}
+
+		// Submitted and approved projects keep their work items as the reviewers saw them
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
 		}
 
 		// Get work item details
-		workItem, err = h.projectWorkItemsRepo.FindById(tx, workItemId)
+		workItem, err = h.projectWorkItemsRepo.FindById(ctx, tx, workItemId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Work item not found")
@@ -243,30 +287,32 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemEditModalView(c *fiber.Ctx) err
 		}
 
 		// Fetch existing costs for this work item
-		allCosts, err = h.projectItemCostsRepo.FindByWorkItemId(tx, workItemId)
+		allCosts, err = h.projectItemCostsRepo.FindByWorkItemId(ctx, tx, workItemId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
 		// Get work categories
-		workCategoriesRepo := master_work_categories.NewMasterWorkCategoriesRepo()
 		paginationData := models.TablePaginationDataInput{
 			Page:    1,
 			PerPage: 1000, // Get all categories
 		}
-		categories, _, err = workCategoriesRepo.Find(tx, paginationData)
+		categories, _, err = h.workCategoriesRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
 		// Get AHSP templates
-		templates, _, err = h.ahspTemplatesRepo.Find(tx, paginationData, userData.ID)
+		templates, _, err = h.ahspTemplatesRepo.Find(ctx, tx, paginationData, userData.Workspace())
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
 		return fiber.StatusOK, nil
 	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
 		return utils.ResponseErrorModal(c, "Error", "Failed to fetch required data")
 	}
 
@@ -310,6 +356,8 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemEditModalView(c *fiber.Ctx) err
 
 // ProjectWorkItemDeleteModalView displays the modal to delete a work item
 func (h *ProjectWorkItemsHandler) ProjectWorkItemDeleteModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	workItemIdStr := c.Params("workItemId")
 
@@ -329,10 +377,9 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemDeleteModalView(c *fiber.Ctx) e
 	var workItem models.ProjectWorkItem
 
 	// Fetch work item data
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get project details
-		projectsRepo := projects.NewProjectsRepo()
-		project, err := projectsRepo.FindById(tx, projectId)
+		project, err := h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -340,13 +387,18 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemDeleteModalView(c *fiber.Ctx) e
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if project.UserId != userData.ID {
-			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		// Check the project is the user's, one of their organizations' or shared with them for editing
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_EDITOR); err != nil {
+			return status, err
+		}
+
+		// Submitted and approved projects keep their work items as the reviewers saw them
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
 		}
 
 		// Get work item details
-		workItem, err = h.projectWorkItemsRepo.FindById(tx, workItemId)
+		workItem, err = h.projectWorkItemsRepo.FindById(ctx, tx, workItemId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Work item not found")
@@ -361,12 +413,15 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemDeleteModalView(c *fiber.Ctx) e
 
 		return fiber.StatusOK, nil
 	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
 		return utils.ResponseErrorModal(c, "Error", "Failed to fetch work item")
 	}
 
 	modal := components.ConfirmationDeleteModal(
 		"Delete Work Item",
-		"Are you sure you want to delete this work item '"+workItem.Description+"'? This will also delete all associated cost calculations.",
+		"Are you sure you want to delete this work item '"+workItem.Description+"'? It moves to the trash with its cost calculations and can be restored from there.",
 		"/project/"+projectIdStr+"/work-items/"+workItemIdStr+"/delete",
 		"Delete Work Item",
 	)
@@ -376,6 +431,8 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemDeleteModalView(c *fiber.Ctx) e
 
 // ProjectWorkItemCostsView displays the cost breakdown for a work item
 func (h *ProjectWorkItemsHandler) ProjectWorkItemCostsView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	workItemIdStr := c.Params("id")
 	workItemId, err := strconv.Atoi(workItemIdStr)
 	if err != nil {
@@ -388,9 +445,9 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemCostsView(c *fiber.Ctx) error {
 	var costs []models.ProjectItemCostWithDetails
 
 	// Fetch cost data
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Get work item details to verify ownership
-		workItem, err := h.projectWorkItemsRepo.FindById(tx, workItemId)
+		workItem, err := h.projectWorkItemsRepo.FindById(ctx, tx, workItemId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Work item not found")
@@ -399,19 +456,18 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemCostsView(c *fiber.Ctx) error {
 		}
 
 		// Get project details to verify ownership
-		projectsRepo := projects.NewProjectsRepo()
-		project, err := projectsRepo.FindById(tx, workItem.ProjectId)
+		project, err := h.projectsRepo.FindById(ctx, tx, workItem.ProjectId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if project.UserId != userData.ID {
-			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		// Check the project is the user's, one of their organizations' or shared with them
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_VIEWER); err != nil {
+			return status, err
 		}
 
 		// Get cost details for the work item
-		costs, err = h.projectItemCostsRepo.FindByWorkItemId(tx, workItemId)
+		costs, err = h.projectItemCostsRepo.FindByWorkItemId(ctx, tx, workItemId)
 		if err != nil {
 			return fiber.StatusInternalServerError, err
 		}
@@ -432,6 +488,8 @@ func (h *ProjectWorkItemsHandler) ProjectWorkItemCostsView(c *fiber.Ctx) error {
 
 // CreateProjectWorkItem handles the creation of a new work item
 func (h *ProjectWorkItemsHandler) CreateProjectWorkItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	projectId, err := strconv.Atoi(projectIdStr)
 	if err != nil {
@@ -497,10 +555,9 @@ func (h *ProjectWorkItemsHandler) CreateProjectWorkItem(c *fiber.Ctx) error {
 	}
 
 	// Create work item and calculate costs in a transaction
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Verify project ownership
-		projectsRepo := projects.NewProjectsRepo()
-		project, err := projectsRepo.FindById(tx, projectId)
+		project, err := h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -508,12 +565,18 @@ func (h *ProjectWorkItemsHandler) CreateProjectWorkItem(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		if project.UserId != userData.ID {
-			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		// Check the project is the user's, one of their organizations' or shared with them for editing
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_EDITOR); err != nil {
+			return status, err
+		}
+
+		// Submitted and approved projects keep their work items as the reviewers saw them
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
 		}
 
 		// Create work item and get the ID
-		newWorkItemId, err := h.projectWorkItemsRepo.Create(tx, workItemData)
+		newWorkItemId, err := h.projectWorkItemsRepo.Create(ctx, tx, workItemData)
 		if err != nil {
 
 			return fiber.StatusInternalServerError, err
@@ -522,21 +585,28 @@ func (h *ProjectWorkItemsHandler) CreateProjectWorkItem(c *fiber.Ctx) error {
 		// If AHSP template is selected, calculate and create cost items
 		if ahspTemplateId != nil {
 
-			if err := h.calculateAndCreateCosts(tx, *ahspTemplateId, volume, newWorkItemId); err != nil {
+			if err := h.calculateAndCreateCosts(ctx, tx, *ahspTemplateId, volume, newWorkItemId); err != nil {
 
 				return fiber.StatusInternalServerError, fmt.Errorf("cost calculation failed: %w", err)
 			}
 		} else {
 			// Handle manual cost entry
 
-			if err := h.processManualCostEntry(tx, c, newWorkItemId, volume); err != nil {
+			if err := h.processManualCostEntry(ctx, tx, c, newWorkItemId, volume); err != nil {
 
 				return fiber.StatusInternalServerError, fmt.Errorf("manual cost entry failed: %w", err)
 			}
 		}
 
+		if err := h.publishWorkItemChange(ctx, tx, project.UserId, models.WEBHOOK_ACTION_CREATED, projectId, newWorkItemId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
 		return fiber.StatusOK, nil
 	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
 		log.Println("sini5 ", err)
 		return utils.ResponseErrorModal(c, "Error", "Failed to create work item")
 	}
@@ -547,6 +617,8 @@ func (h *ProjectWorkItemsHandler) CreateProjectWorkItem(c *fiber.Ctx) error {
 
 // UpdateProjectWorkItem handles the update of an existing work item
 func (h *ProjectWorkItemsHandler) UpdateProjectWorkItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	workItemIdStr := c.Params("workItemId")
 
@@ -605,10 +677,9 @@ func (h *ProjectWorkItemsHandler) UpdateProjectWorkItem(c *fiber.Ctx) error {
 	}
 
 	// Update work item and recalculate costs in a transaction
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Verify project ownership
-		projectsRepo := projects.NewProjectsRepo()
-		project, err := projectsRepo.FindById(tx, projectId)
+		project, err := h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -616,12 +687,18 @@ func (h *ProjectWorkItemsHandler) UpdateProjectWorkItem(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		if project.UserId != userData.ID {
-			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		// Check the project is the user's, one of their organizations' or shared with them for editing
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_EDITOR); err != nil {
+			return status, err
+		}
+
+		// Submitted and approved projects keep their work items as the reviewers saw them
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
 		}
 
 		// Get existing work item
-		existingWorkItem, err := h.projectWorkItemsRepo.FindById(tx, workItemId)
+		existingWorkItem, err := h.projectWorkItemsRepo.FindById(ctx, tx, workItemId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Work item not found")
@@ -644,35 +721,42 @@ func (h *ProjectWorkItemsHandler) UpdateProjectWorkItem(c *fiber.Ctx) error {
 			Unit:           unit,
 			AHSPTemplateId: ahspTemplateId,
 			CreatedAt:      existingWorkItem.CreatedAt,
-			UpdatedAt:      time.Now().Format("2006-01-02 15:04:05"),
+			UpdatedAt:      models.UTCNow(),
 		}
 
-		if err := h.projectWorkItemsRepo.Update(tx, updatedWorkItem); err != nil {
+		if err := h.projectWorkItemsRepo.Update(ctx, tx, updatedWorkItem); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
 		// Delete existing cost calculations
-		if err := h.projectItemCostsRepo.DeleteByWorkItemId(tx, workItemId); err != nil {
+		if err := h.projectItemCostsRepo.DeleteByWorkItemId(ctx, tx, workItemId); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
 		// If AHSP template is selected, recalculate and create cost items
 		if ahspTemplateId != nil {
-			if err := h.calculateAndCreateCosts(tx, *ahspTemplateId, volume, workItemId); err != nil {
+			if err := h.calculateAndCreateCosts(ctx, tx, *ahspTemplateId, volume, workItemId); err != nil {
 
 				return fiber.StatusInternalServerError, fmt.Errorf("cost recalculation failed: %w", err)
 			}
 		} else {
 			// Handle manual cost entry for updates
 
-			if err := h.processManualCostEntry(tx, c, workItemId, volume); err != nil {
+			if err := h.processManualCostEntry(ctx, tx, c, workItemId, volume); err != nil {
 
 				return fiber.StatusInternalServerError, fmt.Errorf("manual cost entry failed: %w", err)
 			}
 		}
 
+		if err := h.publishWorkItemChange(ctx, tx, project.UserId, models.WEBHOOK_ACTION_UPDATED, projectId, workItemId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
 		return fiber.StatusOK, nil
 	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
 		return utils.ResponseErrorModal(c, "Error", "Failed to update work item")
 	}
 
@@ -682,6 +766,8 @@ func (h *ProjectWorkItemsHandler) UpdateProjectWorkItem(c *fiber.Ctx) error {
 
 // DeleteProjectWorkItem handles the deletion of a work item
 func (h *ProjectWorkItemsHandler) DeleteProjectWorkItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	workItemIdStr := c.Params("workItemId")
 
@@ -699,10 +785,9 @@ func (h *ProjectWorkItemsHandler) DeleteProjectWorkItem(c *fiber.Ctx) error {
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// Delete work item in a transaction
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// Verify project ownership
-		projectsRepo := projects.NewProjectsRepo()
-		project, err := projectsRepo.FindById(tx, projectId)
+		project, err := h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -710,12 +795,18 @@ func (h *ProjectWorkItemsHandler) DeleteProjectWorkItem(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		if project.UserId != userData.ID {
-			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+		// Check the project is the user's, one of their organizations' or shared with them for editing
+		if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_EDITOR); err != nil {
+			return status, err
+		}
+
+		// Submitted and approved projects keep their work items as the reviewers saw them
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
 		}
 
 		// Get existing work item
-		existingWorkItem, err := h.projectWorkItemsRepo.FindById(tx, workItemId)
+		existingWorkItem, err := h.projectWorkItemsRepo.FindById(ctx, tx, workItemId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Work item not found")
@@ -728,39 +819,66 @@ func (h *ProjectWorkItemsHandler) DeleteProjectWorkItem(c *fiber.Ctx) error {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
-		// First, explicitly delete all associated cost calculations
-		if err := h.projectItemCostsRepo.DeleteByWorkItemId(tx, workItemId); err != nil {
+		// Move the work item to the trash, its cost calculations are kept for a restore
+		if err := h.projectWorkItemsRepo.Delete(ctx, tx, existingWorkItem); err != nil {
 
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Then delete the work item
-		if err := h.projectWorkItemsRepo.Delete(tx, existingWorkItem); err != nil {
-
+		if err := h.publishWorkItemChange(ctx, tx, project.UserId, models.WEBHOOK_ACTION_DELETED, projectId, workItemId); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 
 		return fiber.StatusOK, nil
 	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
 		return utils.ResponseErrorModal(c, "Error", "Failed to delete work item")
 	}
 
 	// Return success response with redirect
-	return utils.ResponseSuccessWithRedirect(c, "Success", "Work item deleted successfully", "/project/"+projectIdStr)
+	return utils.ResponseSuccessWithRedirect(c, "Success", "Work item moved to the trash", "/project/"+projectIdStr)
+}
+
+// publishWorkItemChange queues work_item.changed for the project owner with the new project total,
+// the work item is left out when it was deleted
+func (h *ProjectWorkItemsHandler) publishWorkItemChange(ctx context.Context, tx *sql.Tx, userId int, action string, projectId, workItemId int) error {
+	change := models.WebhookWorkItemChange{
+		Action:     action,
+		ProjectId:  projectId,
+		WorkItemId: workItemId,
+	}
+
+	if action != models.WEBHOOK_ACTION_DELETED {
+		workItem, err := h.projectWorkItemsRepo.FindById(ctx, tx, workItemId)
+		if err != nil {
+			return err
+		}
+		change.WorkItem = &workItem
+	}
+
+	total, err := h.projectWorkItemsRepo.GetProjectTotalCost(ctx, tx, projectId)
+	if err != nil {
+		return err
+	}
+	change.ProjectTotal = total
+
+	return h.publisher.Publish(ctx, tx, userId, models.WEBHOOK_EVENT_WORK_ITEM_CHANGED, change)
 }
 
 // calculateAndCreateCosts calculates and creates cost items based on AHSP template
-func (h *ProjectWorkItemsHandler) calculateAndCreateCosts(tx *sql.Tx, templateId int, volume float64, workItemId int) error {
+func (h *ProjectWorkItemsHandler) calculateAndCreateCosts(ctx context.Context, tx *sql.Tx, templateId int, volume float64, workItemId int) error {
 
 	// Get material components for the template
-	materialComponents, err := h.ahspMaterialComponentsRepo.FindByTemplateId(tx, templateId)
+	materialComponents, err := h.ahspMaterialComponentsRepo.FindByTemplateId(ctx, tx, templateId)
 	if err != nil {
 
 		return err
 	}
 
 	// Get labor components for the template
-	laborComponents, err := h.ahspLaborComponentsRepo.FindByTemplateId(tx, templateId)
+	laborComponents, err := h.ahspLaborComponentsRepo.FindByTemplateId(ctx, tx, templateId)
 	if err != nil {
 
 		return err
@@ -772,7 +890,7 @@ func (h *ProjectWorkItemsHandler) calculateAndCreateCosts(tx *sql.Tx, templateId
 	for _, component := range materialComponents {
 
 		// Get material details
-		material, err := h.masterMaterialsRepo.FindById(tx, component.MaterialId)
+		material, err := h.masterMaterialsRepo.FindById(ctx, tx, component.MaterialId)
 		if err != nil {
 
 			continue // Skip if material not found
@@ -797,7 +915,7 @@ func (h *ProjectWorkItemsHandler) calculateAndCreateCosts(tx *sql.Tx, templateId
 	for _, component := range laborComponents {
 
 		// Get labor type details
-		laborType, err := h.masterLaborTypesRepo.FindById(tx, component.LaborTypeId)
+		laborType, err := h.masterLaborTypesRepo.FindById(ctx, tx, component.LaborTypeId)
 		if err != nil {
 
 			continue // Skip if labor type not found
@@ -821,7 +939,7 @@ func (h *ProjectWorkItemsHandler) calculateAndCreateCosts(tx *sql.Tx, templateId
 	// Create all cost items
 	if len(costItems) > 0 {
 
-		if err := h.projectItemCostsRepo.CreateMultiple(tx, costItems); err != nil {
+		if err := h.projectItemCostsRepo.CreateMultiple(ctx, tx, costItems); err != nil {
 
 			return err
 		}
@@ -834,7 +952,7 @@ func (h *ProjectWorkItemsHandler) calculateAndCreateCosts(tx *sql.Tx, templateId
 }
 
 // processManualCostEntry processes manual cost entry from form data
-func (h *ProjectWorkItemsHandler) processManualCostEntry(tx *sql.Tx, c *fiber.Ctx, workItemId int, volume float64) error {
+func (h *ProjectWorkItemsHandler) processManualCostEntry(ctx context.Context, tx *sql.Tx, c *fiber.Ctx, workItemId int, volume float64) error {
 	// Use PostArgs() for URL-encoded forms (default)
 	postArgs := c.Request().PostArgs()
 
@@ -867,94 +985,76 @@ func (h *ProjectWorkItemsHandler) processManualCostEntry(tx *sql.Tx, c *fiber.Ct
 			len(materialNames), len(laborNames))
 	}
 
-	var costItems []models.ProjectItemCostCreate
+	costs := manualCostRows(models.PROJECT_ITEM_TYPE_MATERIAL, materialNames, materialQuantities, materialUnits, materialPrices)
+	costs = append(costs, manualCostRows(models.PROJECT_ITEM_TYPE_LABOR, laborNames, laborQuantities, laborUnits, laborPrices)...)
 
-	// Process material costs
-	for i := 0; i < len(materialNames); i++ {
-		if materialNames[i] == "" {
+	return h.createManualCosts(ctx, tx, workItemId, volume, costs)
+}
+
+// manualCostRows reads the rows of one manual cost table of the form, empty and invalid rows are skipped
+func manualCostRows(itemType models.ItemType, names, quantities, units, prices []string) []models.ProjectManualCost {
+	var costs []models.ProjectManualCost
+
+	for i := 0; i < len(names); i++ {
+		if names[i] == "" {
 			continue // Skip empty rows
 		}
 
-		quantity, err := strconv.ParseFloat(materialQuantities[i], 64)
+		if i >= len(quantities) || i >= len(prices) {
+			continue
+		}
+
+		quantity, err := strconv.ParseFloat(quantities[i], 64)
 		if err != nil || quantity <= 0 {
 			continue // Skip invalid quantities
 		}
 
-		price, err := strconv.ParseFloat(materialPrices[i], 64)
+		price, err := strconv.ParseFloat(prices[i], 64)
 		if err != nil || price <= 0 {
 			continue // Skip invalid prices
 		}
 
-		totalCost := quantity * price
-
 		// Get unit with bounds checking
 		unit := ""
-		if i < len(materialUnits) {
-			unit = materialUnits[i]
+		if i < len(units) {
+			unit = units[i]
 		}
 
-		costItems = append(costItems, models.ProjectItemCostCreate{
-			WorkItemId:          workItemId,
-			ItemType:            string(models.PROJECT_ITEM_TYPE_MATERIAL),
-			MasterItemId:        0, // Manual entry doesn't have a master item ID
-			ItemName:            materialNames[i],
-			Coefficient:         quantity / volume, // Calculate coefficient based on volume
-			QuantityNeeded:      quantity,
-			Unit:                unit,
-			UnitPriceAtCreation: price,
-			TotalCost:           totalCost,
+		costs = append(costs, models.ProjectManualCost{
+			ItemType:  string(itemType),
+			ItemName:  names[i],
+			Quantity:  quantity,
+			Unit:      unit,
+			UnitPrice: price,
 		})
-
 	}
 
-	// Process labor costs
-	for i := 0; i < len(laborNames); i++ {
-		if laborNames[i] == "" {
-			continue // Skip empty rows
-		}
-
-		quantity, err := strconv.ParseFloat(laborQuantities[i], 64)
-		if err != nil || quantity <= 0 {
-			continue // Skip invalid quantities
-		}
-
-		price, err := strconv.ParseFloat(laborPrices[i], 64)
-		if err != nil || price <= 0 {
-			continue // Skip invalid prices
-		}
-
-		totalCost := quantity * price
+	return costs
+}
 
-		// Get unit with bounds checking
-		unit := ""
-		if i < len(laborUnits) {
-			unit = laborUnits[i]
-		}
+// createManualCosts saves manual cost lines of a work item, the form and the JSON API share it
+func (h *ProjectWorkItemsHandler) createManualCosts(ctx context.Context, tx *sql.Tx, workItemId int, volume float64, costs []models.ProjectManualCost) error {
+	var costItems []models.ProjectItemCostCreate
 
+	for _, cost := range costs {
 		costItems = append(costItems, models.ProjectItemCostCreate{
 			WorkItemId:          workItemId,
-			ItemType:            string(models.PROJECT_ITEM_TYPE_LABOR),
+			ItemType:            cost.ItemType,
 			MasterItemId:        0, // Manual entry doesn't have a master item ID
-			ItemName:            laborNames[i],
-			Coefficient:         quantity / volume, // Calculate coefficient based on volume
-			QuantityNeeded:      quantity,
-			Unit:                unit,
-			UnitPriceAtCreation: price,
-			TotalCost:           totalCost,
+			ItemName:            cost.ItemName,
+			Coefficient:         cost.Quantity / volume, // Calculate coefficient based on volume
+			QuantityNeeded:      cost.Quantity,
+			Unit:                cost.Unit,
+			UnitPriceAtCreation: cost.UnitPrice,
+			TotalCost:           cost.Quantity * cost.UnitPrice,
 		})
-
 	}
 
 	// Create all cost items
 	if len(costItems) > 0 {
-
-		if err := h.projectItemCostsRepo.CreateMultiple(tx, costItems); err != nil {
-
+		if err := h.projectItemCostsRepo.CreateMultiple(ctx, tx, costItems); err != nil {
 			return err
 		}
-
-	} else {
-
 	}
 
 	return nil
@@ -11,23 +11,35 @@ import (
 	"github.com/momokii/go-rab-maker/backend/databases"
 	"github.com/momokii/go-rab-maker/backend/middlewares"
 	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
 	"github.com/momokii/go-rab-maker/backend/repository/projects"
 	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
 	"github.com/momokii/go-rab-maker/frontend/components"
 )
 
 type ProjectsHandler struct {
-	dbService    databases.SQLiteServices
-	projectsRepo *projects.ProjectsRepo
+	dbService            databases.DatabaseServices
+	projectsRepo         projects.Repository
+	projectMembersRepo   project_members.Repository
+	projectApprovalsRepo project_approvals.Repository
+	publisher            webhook_dispatch.Publisher
 }
 
 func NewProjectsHandler(
-	dbService databases.SQLiteServices,
-	projectsRepo *projects.ProjectsRepo,
+	dbService databases.DatabaseServices,
+	projectsRepo projects.Repository,
+	projectMembersRepo project_members.Repository,
+	projectApprovalsRepo project_approvals.Repository,
+	publisher webhook_dispatch.Publisher,
 ) *ProjectsHandler {
 	return &ProjectsHandler{
-		dbService:    dbService,
-		projectsRepo: projectsRepo,
+		dbService:            dbService,
+		projectsRepo:         projectsRepo,
+		projectMembersRepo:   projectMembersRepo,
+		projectApprovalsRepo: projectApprovalsRepo,
+		publisher:            publisher,
 	}
 }
 
@@ -36,7 +48,10 @@ func NewProjectsHandler(
 // ==========================
 
 func (h *ProjectsHandler) ProjectsMainPageTableView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	var projects []models.Project
+	var shared []models.SharedProject
 	var paginationInfo models.PaginationInfo
 
 	// Get user from session
@@ -53,12 +68,12 @@ func (h *ProjectsHandler) ProjectsMainPageTableView(c *fiber.Ctx) error {
 	}
 
 	// start transaction to get the data
-	if _, err := h.dbService.Transaction(
-		c.Context(),
+	if _, err := h.dbService.ReadTransaction(
+		ctx,
 		func(tx *sql.Tx) (int, error) {
 			// get the projects data for current user
-			projectsData, paginationData, err := h.projectsRepo.FindByUserId(
-				tx, userData.ID, paginationData,
+			projectsData, paginationData, err := h.projectsRepo.FindByWorkspace(
+				ctx, tx, userData.Workspace(), paginationData,
 			)
 			if err != nil {
 				return fiber.StatusInternalServerError, err
@@ -68,6 +83,14 @@ func (h *ProjectsHandler) ProjectsMainPageTableView(c *fiber.Ctx) error {
 
 			paginationInfo = paginationData
 
+			// the shared list is only on the full page, the table refreshes alone
+			if c.Get("HX-Request") != "true" {
+				shared, err = h.projectMembersRepo.FindSharedWithUser(ctx, tx, userData.ID)
+				if err != nil {
+					return fiber.StatusInternalServerError, err
+				}
+			}
+
 			return fiber.StatusOK, nil
 		},
 	); err != nil {
@@ -96,6 +119,8 @@ func (h *ProjectsHandler) ProjectsMainPageTableView(c *fiber.Ctx) error {
 		projects,
 		paginationInfo,
 		tableConfig,
+		shared,
+		userData.ID,
 	)
 	return adaptor.HTTPHandler(templ.Handler(projectsComponent))(c)
 }
@@ -113,6 +138,8 @@ func (h *ProjectsHandler) ProjectCreateModalView(c *fiber.Ctx) error {
 }
 
 func (h *ProjectsHandler) ProjectEditModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	projectId, err := strconv.Atoi(projectIdStr)
 	if err != nil {
@@ -125,8 +152,8 @@ func (h *ProjectsHandler) ProjectEditModalView(c *fiber.Ctx) error {
 	var project models.Project
 
 	// Fetch project from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		project, err = h.projectsRepo.FindById(tx, projectId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, err = h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -134,8 +161,8 @@ func (h *ProjectsHandler) ProjectEditModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if project.UserId != userData.ID {
+		// Check if project belongs to current user or one of their organizations
+		if !userData.OwnsProject(project) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -156,6 +183,8 @@ func (h *ProjectsHandler) ProjectEditModalView(c *fiber.Ctx) error {
 }
 
 func (h *ProjectsHandler) ProjectDeleteModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	projectIdStr := c.Params("id")
 	projectId, err := strconv.Atoi(projectIdStr)
 	if err != nil {
@@ -168,8 +197,8 @@ func (h *ProjectsHandler) ProjectDeleteModalView(c *fiber.Ctx) error {
 	var project models.Project
 
 	// Fetch project from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		project, err = h.projectsRepo.FindById(tx, projectId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, err = h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -177,8 +206,8 @@ func (h *ProjectsHandler) ProjectDeleteModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if project.UserId != userData.ID {
+		// Check if project belongs to current user or one of their organizations
+		if !userData.OwnsProject(project) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -189,7 +218,7 @@ func (h *ProjectsHandler) ProjectDeleteModalView(c *fiber.Ctx) error {
 
 	modal := components.ConfirmationDeleteModal(
 		"Delete Project",
-		"Are you sure you want to delete this project "+project.ProjectName+"? This will also delete all associated work items.",
+		"Are you sure you want to delete this project "+project.ProjectName+"? It moves to the trash with its work items and can be restored from there.",
 		"/projects/"+projectIdStr+"/delete",
 		"Delete Project",
 	)
@@ -203,6 +232,8 @@ func (h *ProjectsHandler) ProjectDeleteModalView(c *fiber.Ctx) error {
 
 // CreateProject handles the creation of a new project
 func (h *ProjectsHandler) CreateProject(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Add small delay for better UX
 	time.Sleep(500 * time.Millisecond)
 
@@ -231,11 +262,22 @@ func (h *ProjectsHandler) CreateProject(c *fiber.Ctx) error {
 		Location:    location,
 		ClientName:  clientName,
 		UserId:      userData.ID,
+		OrgId:       userData.OrgId,
 	}
 
 	// Create project in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		if err := h.projectsRepo.Create(tx, projectData); err != nil {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		projectId, err := h.projectsRepo.Create(ctx, tx, projectData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		project, err := h.projectsRepo.FindById(ctx, tx, projectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.publisher.Publish(ctx, tx, userData.ID, models.WEBHOOK_EVENT_PROJECT_CREATED, project); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -252,6 +294,8 @@ func (h *ProjectsHandler) CreateProject(c *fiber.Ctx) error {
 
 // UpdateProject handles the update of an existing project
 func (h *ProjectsHandler) UpdateProject(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Add small delay for better UX
 	time.Sleep(500 * time.Millisecond)
 
@@ -281,9 +325,9 @@ func (h *ProjectsHandler) UpdateProject(c *fiber.Ctx) error {
 	}
 
 	// Update project in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing project to ensure it belongs to the user
-		existingProject, err := h.projectsRepo.FindById(tx, projectId)
+		existingProject, err := h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -291,27 +335,39 @@ func (h *ProjectsHandler) UpdateProject(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if existingProject.UserId != userData.ID {
+		// Check if project belongs to current user or one of their organizations
+		if !userData.OwnsProject(existingProject) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
+		}
+
 		// Update the project
 		updatedProject := models.Project{
 			ProjectId:   projectId,
-			UserId:      userData.ID,
+			UserId:      existingProject.UserId,
+			OrgId:       existingProject.OrgId,
 			ProjectName: projectName,
 			Location:    location,
 			ClientName:  clientName,
 			CreatedAt:   existingProject.CreatedAt,
-			UpdatedAt:   time.Now().Format("2006-01-02 15:04:05"),
+			UpdatedAt:   models.UTCNow(),
 		}
 
-		if err := h.projectsRepo.Update(tx, updatedProject); err != nil {
+		if err := h.projectsRepo.Update(ctx, tx, updatedProject); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.publisher.Publish(ctx, tx, updatedProject.UserId, models.WEBHOOK_EVENT_PROJECT_UPDATED, updatedProject); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
 	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
 		return utils.ResponseErrorModal(c, "Error", "Failed to update project")
 	}
 
@@ -324,6 +380,8 @@ func (h *ProjectsHandler) UpdateProject(c *fiber.Ctx) error {
 
 // DeleteProject handles the deletion of a project
 func (h *ProjectsHandler) DeleteProject(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	// Add small delay for better UX
 	time.Sleep(500 * time.Millisecond)
 
@@ -337,9 +395,9 @@ func (h *ProjectsHandler) DeleteProject(c *fiber.Ctx) error {
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// Delete project from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing project to ensure it belongs to the user
-		existingProject, err := h.projectsRepo.FindById(tx, projectId)
+		existingProject, err := h.projectsRepo.FindById(ctx, tx, projectId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Project not found")
@@ -347,17 +405,28 @@ func (h *ProjectsHandler) DeleteProject(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if project belongs to current user
-		if existingProject.UserId != userData.ID {
+		// Check if project belongs to current user or one of their organizations
+		if !userData.OwnsProject(existingProject) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
+		if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, projectId); err != nil {
+			return status, err
+		}
+
 		// Delete the project (cascade will handle related records)
-		if err := h.projectsRepo.Delete(tx, existingProject); err != nil {
+		if err := h.projectsRepo.Delete(ctx, tx, existingProject); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.publisher.Publish(ctx, tx, existingProject.UserId, models.WEBHOOK_EVENT_PROJECT_DELETED, existingProject); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
 	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
 		return utils.ResponseErrorModal(c, "Error", "Failed to delete project")
 	}
 
@@ -365,5 +434,5 @@ func (h *ProjectsHandler) DeleteProject(c *fiber.Ctx) error {
 	utils.SetRefreshTableTriggerHeader(c)
 
 	// Return success response with refresh
-	return utils.ResponseSuccessModal(c, "Success", "Project deleted successfully", true)
+	return utils.ResponseSuccessModal(c, "Success", "Project moved to the trash", true)
 }
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// TestProjects_OnlyOwnerCanChange verifies updates and deletes of another user's project are
+// refused and leave it untouched, while the owner's own changes go through
+func TestProjects_OnlyOwnerCanChange(t *testing.T) {
+	repo := newFakeProjectsRepo(
+		models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal", Location: "Bandung", ClientName: "Budi"},
+		models.Project{ProjectId: 2, UserId: 8, ProjectName: "Gudang", Location: "Bekasi", ClientName: "Sari"},
+	)
+	publisher := &fakePublisher{}
+	handler := NewProjectsHandler(&fakeDatabase{}, repo, newFakeProjectMembersRepo(), newFakeProjectApprovalsRepo(), publisher)
+
+	app := newTestApp(7)
+	app.Post("/projects/:id/edit", handler.UpdateProject)
+	app.Delete("/projects/:id/delete", handler.DeleteProject)
+
+	form := url.Values{
+		"project_name": {"Rumah Dua Lantai"},
+		"location":     {"Bandung"},
+		"client_name":  {"Budi"},
+	}
+
+	if body := responseBody(t, doRequest(t, app, http.MethodPost, "/projects/2/edit", form)); !strings.Contains(body, "Access denied") {
+		t.Errorf("Expected updating another user's project to fail, got %s", body)
+	}
+	if body := responseBody(t, doRequest(t, app, http.MethodDelete, "/projects/2/delete", nil)); !strings.Contains(body, "Access denied") {
+		t.Errorf("Expected deleting another user's project to fail, got %s", body)
+	}
+	if repo.projects[2].ProjectName != "Gudang" {
+		t.Errorf("Another user's project was changed: %+v", repo.projects[2])
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("Expected refused changes to publish nothing, got %v", publisher.events)
+	}
+
+	if body := responseBody(t, doRequest(t, app, http.MethodPost, "/projects/1/edit", form)); !strings.Contains(body, "Project updated successfully") {
+		t.Fatalf("Expected own project to be updated, got %s", body)
+	}
+	if repo.projects[1].ProjectName != "Rumah Dua Lantai" {
+		t.Errorf("Own project was not updated: %+v", repo.projects[1])
+	}
+
+	if body := responseBody(t, doRequest(t, app, http.MethodDelete, "/projects/1/delete", nil)); !strings.Contains(body, "Project moved to the trash") {
+		t.Fatalf("Expected own project to be deleted, got %s", body)
+	}
+	if _, ok := repo.projects[1]; ok {
+		t.Errorf("Own project was not deleted")
+	}
+
+	if len(publisher.events) != 2 || publisher.events[0] != models.WEBHOOK_EVENT_PROJECT_UPDATED || publisher.events[1] != models.WEBHOOK_EVENT_PROJECT_DELETED {
+		t.Errorf("Expected the update and delete to be published, got %v", publisher.events)
+	}
+}
+
+// TestProjects_LockedProject verifies an approved project cannot be updated or deleted until a
+// new revision is started, and the reason is shown
+func TestProjects_LockedProject(t *testing.T) {
+	repo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal", Location: "Bandung", ClientName: "Budi"})
+	approvalsRepo := newFakeProjectApprovalsRepo(models.ProjectApproval{ProjectId: 1, Status: models.APPROVAL_STATUS_APPROVED})
+	publisher := &fakePublisher{}
+	handler := NewProjectsHandler(&fakeDatabase{}, repo, newFakeProjectMembersRepo(), approvalsRepo, publisher)
+
+	app := newTestApp(7)
+	app.Post("/projects/:id/edit", handler.UpdateProject)
+	app.Delete("/projects/:id/delete", handler.DeleteProject)
+
+	form := url.Values{
+		"project_name": {"Rumah Dua Lantai"},
+		"location":     {"Bandung"},
+		"client_name":  {"Budi"},
+	}
+
+	if body := responseBody(t, doRequest(t, app, http.MethodPost, "/projects/1/edit", form)); !strings.Contains(body, "The project is approved") {
+		t.Errorf("Expected updating an approved project to fail, got %s", body)
+	}
+	if body := responseBody(t, doRequest(t, app, http.MethodDelete, "/projects/1/delete", nil)); !strings.Contains(body, "The project is approved") {
+		t.Errorf("Expected deleting an approved project to fail, got %s", body)
+	}
+	if project, ok := repo.projects[1]; !ok || project.ProjectName != "Rumah Tinggal" {
+		t.Errorf("The approved project was changed: %+v", project)
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("Expected refused changes to publish nothing, got %v", publisher.events)
+	}
+}
+
+// TestProjects_PublishedToOwner verifies a change by another member of the project's
+// organization is published to the webhooks of the project owner
+func TestProjects_PublishedToOwner(t *testing.T) {
+	repo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 8, OrgId: 3, ProjectName: "Rumah Tinggal", Location: "Bandung", ClientName: "Budi"})
+	publisher := &fakePublisher{}
+	handler := NewProjectsHandler(&fakeDatabase{}, repo, newFakeProjectMembersRepo(), newFakeProjectApprovalsRepo(), publisher)
+
+	app := newTestAppFor(models.SessionUser{ID: 7, Role: models.ROLE_ESTIMATOR, OrgId: 3, OrgIds: []int{3}})
+	app.Post("/projects/:id/edit", handler.UpdateProject)
+	app.Delete("/projects/:id/delete", handler.DeleteProject)
+
+	form := url.Values{
+		"project_name": {"Rumah Dua Lantai"},
+		"location":     {"Bandung"},
+		"client_name":  {"Budi"},
+	}
+
+	if body := responseBody(t, doRequest(t, app, http.MethodPost, "/projects/1/edit", form)); !strings.Contains(body, "Project updated successfully") {
+		t.Fatalf("Expected the organization's project to be updated, got %s", body)
+	}
+	if body := responseBody(t, doRequest(t, app, http.MethodDelete, "/projects/1/delete", nil)); !strings.Contains(body, "Project moved to the trash") {
+		t.Fatalf("Expected the organization's project to be deleted, got %s", body)
+	}
+
+	if len(publisher.userIds) != 2 || publisher.userIds[0] != 8 || publisher.userIds[1] != 8 {
+		t.Errorf("Expected both changes to be published to the owner, got %v", publisher.userIds)
+	}
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/rab_import"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// RabImportHandler serves the import of an existing RAB/BoQ spreadsheet into a project
+type RabImportHandler struct {
+	dbService          databases.DatabaseServices
+	importer           *rab_import.Importer
+	projectsRepo       projects.Repository
+	projectMembersRepo project_members.Repository
+	approvalsRepo      project_approvals.Repository
+	templateCosts      rab_import.TemplateCostFunc
+}
+
+// NewRabImportHandler reuses the work item handler to build the costs of rows matched to an AHSP template
+func NewRabImportHandler(
+	dbService databases.DatabaseServices,
+	importer *rab_import.Importer,
+	projectsRepo projects.Repository,
+	projectMembersRepo project_members.Repository,
+	approvalsRepo project_approvals.Repository,
+	projectWorkItemsHandler *ProjectWorkItemsHandler,
+) *RabImportHandler {
+	return &RabImportHandler{
+		dbService:          dbService,
+		importer:           importer,
+		projectsRepo:       projectsRepo,
+		projectMembersRepo: projectMembersRepo,
+		approvalsRepo:      approvalsRepo,
+		templateCosts:      projectWorkItemsHandler.calculateAndCreateCosts,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+func (h *RabImportHandler) RabImportModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	// Get user from session (using the same approach as in auth.handler.go)
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		return h.checkProjectEditor(ctx, tx, projectId, userData)
+	}); err != nil {
+		return utils.ResponseErrorModal(c, "Error", err.Error())
+	}
+
+	modal := components.RabImportUploadModal(projectId)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// RabImportPreviewView reads the uploaded file (or the sheet carried over from the previous step),
+// applies the column mapping and shows the work items the import would create
+func (h *RabImportHandler) RabImportPreviewView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	// Get user from session (using the same approach as in auth.handler.go)
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var rows [][]string
+	var mapping models.RabImportColumnMapping
+	matchTemplates := true
+
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return utils.ResponseErrorModal(c, "Error", "Failed to read uploaded file")
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return utils.ResponseErrorModal(c, "Error", "Failed to read uploaded file")
+		}
+
+		rows, err = utils.ReadSpreadsheet(fileHeader.Filename, data)
+		if err != nil {
+			return utils.ResponseErrorModal(c, "Import Error", err.Error())
+		}
+
+		if len(rows) > 0 {
+			mapping = rab_import.GuessMapping(rows[0])
+		}
+	} else {
+		rows, mapping, matchTemplates, err = parseRabImportForm(c)
+		if err != nil {
+			return utils.ResponseErrorModal(c, "Import Error", err.Error())
+		}
+	}
+
+	if len(rows) < 2 {
+		return utils.ResponseErrorModal(c, "Import Error", "The file needs a header row and at least one data row")
+	}
+
+	sheetData, err := json.Marshal(rows)
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to process uploaded file")
+	}
+
+	var preview models.RabImportPreview
+	mappingError := ""
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		if status, err := h.checkProjectEditor(ctx, tx, projectId, userData); err != nil {
+			return status, err
+		}
+
+		if mapping.Description < 0 || mapping.Volume < 0 || mapping.Unit < 0 || mapping.UnitPrice < 0 {
+			mappingError = "Select the Description, Volume, Unit and Unit Price columns to see the preview."
+			return fiber.StatusOK, nil
+		}
+
+		preview, err = h.importer.Preview(ctx, tx, rows, mapping, matchTemplates, userData.Workspace())
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to preview import: "+err.Error())
+	}
+
+	modal := components.RabImportPreviewModal(projectId, string(sheetData), rows[0], mapping, matchTemplates, preview, mappingError)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// CommitRabImport recomputes the preview and creates the work items in a single transaction
+func (h *RabImportHandler) CommitRabImport(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	// Get user from session (using the same approach as in auth.handler.go)
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	rows, mapping, matchTemplates, err := parseRabImportForm(c)
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Import Error", err.Error())
+	}
+
+	var preview models.RabImportPreview
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if status, err := h.checkProjectEditor(ctx, tx, projectId, userData); err != nil {
+			return status, err
+		}
+
+		preview, err = h.importer.Preview(ctx, tx, rows, mapping, matchTemplates, userData.Workspace())
+		if err != nil {
+			return fiber.StatusBadRequest, err
+		}
+
+		if err := h.importer.Commit(ctx, tx, projectId, preview, userData.Workspace(), h.templateCosts); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Import failed, nothing was saved: "+err.Error())
+	}
+
+	message := fmt.Sprintf(
+		"%d work items added (%d from AHSP templates), %d rows skipped because of errors.",
+		preview.Items,
+		preview.MatchedTemplates,
+		preview.Errors,
+	)
+
+	return utils.ResponseSuccessWithRedirect(c, "Import Completed", message, "/project/"+strconv.Itoa(projectId))
+}
+
+// checkProjectEditor verifies the project exists, the user may change its work items and it
+// is not locked by the approval workflow
+func (h *RabImportHandler) checkProjectEditor(ctx context.Context, tx *sql.Tx, projectId int, userData models.SessionUser) (int, error) {
+	project, status, err := findProject(ctx, tx, h.projectsRepo, projectId)
+	if err != nil {
+		return status, err
+	}
+
+	if status, err := checkProjectRole(ctx, tx, h.projectMembersRepo, project, userData, models.PROJECT_ROLE_EDITOR); err != nil {
+		return status, err
+	}
+
+	return checkProjectUnlocked(ctx, tx, h.approvalsRepo, projectId)
+}
+
+// parseRabImportForm reads the sheet, column mapping and template matching option carried between steps
+func parseRabImportForm(c *fiber.Ctx) ([][]string, models.RabImportColumnMapping, bool, error) {
+	var rows [][]string
+	mapping := models.RabImportColumnMapping{Description: -1, Volume: -1, Unit: -1, UnitPrice: -1, Category: -1}
+	matchTemplates := c.FormValue("match_templates") == "on"
+
+	if err := json.Unmarshal([]byte(c.FormValue("sheet_data")), &rows); err != nil {
+		return rows, mapping, matchTemplates, fmt.Errorf("uploaded sheet is missing, please upload the file again")
+	}
+
+	for field, target := range map[string]*int{
+		"col_description": &mapping.Description,
+		"col_volume":      &mapping.Volume,
+		"col_unit":        &mapping.Unit,
+		"col_unit_price":  &mapping.UnitPrice,
+		"col_category":    &mapping.Category,
+	} {
+		if value, err := strconv.Atoi(c.FormValue(field)); err == nil {
+			*target = value
+		}
+	}
+
+	return rows, mapping, matchTemplates, nil
+}
@@ -0,0 +1,481 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/material_summary"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/repository/share_links"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+const (
+	// SHARE_LINK_MAX_EXPIRY_DAYS is the longest expiry that can be chosen, 0 days means no expiry
+	SHARE_LINK_MAX_EXPIRY_DAYS = 365
+	// SHARE_LINK_PATH is where the shared pages are served, followed by the token of the link
+	SHARE_LINK_PATH = "/share/"
+	// SESSION_SHARE_LINK_PREFIX followed by the link ID marks a password protected link as
+	// unlocked in the visitor's session
+	SESSION_SHARE_LINK_PREFIX = "share_link_"
+)
+
+// ShareLinksHandler lets the owner of a project create read-only links for clients without an
+// account, and serves the shared project to whoever opens such a link
+type ShareLinksHandler struct {
+	dbService            databases.DatabaseServices
+	projectsRepo         projects.Repository
+	shareLinksRepo       share_links.Repository
+	projectWorkItemsRepo project_work_items.Repository
+	projectItemCostsRepo project_item_costs.Repository
+	materialSummaryRepo  material_summary.Repository
+}
+
+func NewShareLinksHandler(
+	dbService databases.DatabaseServices,
+	projectsRepo projects.Repository,
+	shareLinksRepo share_links.Repository,
+	projectWorkItemsRepo project_work_items.Repository,
+	projectItemCostsRepo project_item_costs.Repository,
+	materialSummaryRepo material_summary.Repository,
+) *ShareLinksHandler {
+	return &ShareLinksHandler{
+		dbService:            dbService,
+		projectsRepo:         projectsRepo,
+		shareLinksRepo:       shareLinksRepo,
+		projectWorkItemsRepo: projectWorkItemsRepo,
+		projectItemCostsRepo: projectItemCostsRepo,
+		materialSummaryRepo:  materialSummaryRepo,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+// ShareLinksModalView lists the share links of a project with their views, for its owner
+func (h *ShareLinksHandler) ShareLinksModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var project models.Project
+	var links []models.ShareLink
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, links, err = h.findLinks(ctx, tx, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to fetch share links")
+	}
+
+	modal := components.ShareLinksModal(project, links, time.Now())
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// SharedProjectView shows the read-only project of a link, or asks for the password of a
+// protected link first. Every view is counted for the owner.
+func (h *ShareLinksHandler) SharedProjectView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	token := c.Params("token")
+
+	var link models.ShareLink
+	var project models.Project
+	var workItems []models.ProjectWorkItemWithDetails
+	var itemTotals map[int]float64
+	var materialSummaries []models.MaterialSummary
+	locked := false
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		link, project, err = h.findSharedProject(ctx, tx, token)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if !isShareLinkUnlocked(c, link) {
+			locked = true
+			return fiber.StatusOK, nil
+		}
+
+		workItems, err = h.projectWorkItemsRepo.FindByProjectIdWithDetails(ctx, tx, project.ProjectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		itemTotals, err = projectItemTotals(ctx, tx, h.projectItemCostsRepo, project.ProjectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		materialSummaries, err = h.materialSummaryRepo.GetProjectMaterialSummary(ctx, tx, project.ProjectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.shareLinksRepo.RecordAccess(ctx, tx, link.LinkId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return sharedProjectError(c, err)
+	}
+
+	if locked {
+		page := components.SharedProjectPasswordPage(project, token, "")
+		return adaptor.HTTPHandler(templ.Handler(page))(c)
+	}
+
+	recap := recapByCategory(workItems, itemTotals)
+
+	page := components.SharedProjectPage(project, token, recap, workItems, itemTotals, materialSummaries)
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// CreateShareLink creates a link for the project and shows its URL once, only its hash is kept
+func (h *ShareLinksHandler) CreateShareLink(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	expiryDays, err := strconv.Atoi(c.FormValue("expiry_days"))
+	if err != nil || expiryDays < 0 || expiryDays > SHARE_LINK_MAX_EXPIRY_DAYS {
+		return utils.ResponseErrorModal(c, "Validation Error", "Choose an expiry of at most "+strconv.Itoa(SHARE_LINK_MAX_EXPIRY_DAYS)+" days")
+	}
+
+	password := c.FormValue("password")
+	if password != "" && len(password) < models.SHARE_LINK_PASSWORD_MIN_LENGTH {
+		return utils.ResponseErrorModal(c, "Validation Error", "The password needs at least "+strconv.Itoa(models.SHARE_LINK_PASSWORD_MIN_LENGTH)+" characters")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	plainToken, prefix, hash, err := models.NewShareLinkToken()
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to generate link")
+	}
+
+	linkData := models.ShareLinkCreate{
+		ProjectId:   projectId,
+		CreatedBy:   userData.ID,
+		Label:       strings.TrimSpace(c.FormValue("label")),
+		TokenPrefix: prefix,
+		TokenHash:   hash,
+	}
+	if expiryDays > 0 {
+		linkData.ExpiresAt = time.Now().UTC().AddDate(0, 0, expiryDays).Format(models.API_TOKEN_TIME_FORMAT)
+	}
+
+	if err := utils.ValidateStruct(linkData); err != nil {
+		errors := utils.GetValidationErrors(err)
+		return utils.ResponseErrorModal(c, "Validation Error", strings.Join(errors, "; "))
+	}
+
+	if password != "" {
+		if linkData.PasswordHash, err = models.HashPassword(password); err != nil {
+			return utils.ResponseErrorModal(c, "Error", "Failed to protect link")
+		}
+	}
+
+	var project models.Project
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		project, _, err = h.findLinks(ctx, tx, projectId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if _, err := h.shareLinksRepo.Create(ctx, tx, linkData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to create share link")
+	}
+
+	modal := components.ShareLinkCreatedModal(project, linkData, c.BaseURL()+SHARE_LINK_PATH+plainToken)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// RevokeShareLink stops a link from opening the project, it stays in the list as revoked
+func (h *ShareLinksHandler) RevokeShareLink(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	projectId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid project ID")
+	}
+
+	linkId, err := strconv.Atoi(c.Params("linkId"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid link ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var project models.Project
+	var links []models.ShareLink
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if project, _, err = h.findLinks(ctx, tx, projectId, userData); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		link, err := h.shareLinksRepo.FindById(ctx, tx, linkId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Share link not found")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		if link.ProjectId != projectId {
+			return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Share link not found")
+		}
+
+		if err := h.shareLinksRepo.Revoke(ctx, tx, linkId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if links, err = h.shareLinksRepo.FindByProjectId(ctx, tx, projectId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to revoke share link")
+	}
+
+	modal := components.ShareLinksModal(project, links, time.Now())
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// UnlockSharedProject checks the password of a protected link and remembers it in the
+// visitor's session, so the page and its downloads open until the session ends
+func (h *ShareLinksHandler) UnlockSharedProject(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	token := c.Params("token")
+
+	var link models.ShareLink
+	var project models.Project
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		link, project, err = h.findSharedProject(ctx, tx, token)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return sharedProjectError(c, err)
+	}
+
+	if link.HasPassword() {
+		if err := models.CheckPassword(link.PasswordHash, c.FormValue("password")); err != nil {
+			page := components.SharedProjectPasswordPage(project, token, "The password is not correct")
+			return adaptor.HTTPHandler(templ.Handler(page, templ.WithStatus(fiber.StatusUnauthorized)))(c)
+		}
+
+		if err := middlewares.CreateSession(c, SESSION_SHARE_LINK_PREFIX+strconv.Itoa(link.LinkId), true); err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to open the shared project")
+		}
+	}
+
+	return c.Redirect(SHARE_LINK_PATH+token, fiber.StatusSeeOther)
+}
+
+// ExportSharedRab downloads the RAB workbook of a shared project
+func (h *ShareLinksHandler) ExportSharedRab(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	var project models.Project
+	var workItems []models.ProjectWorkItemWithDetails
+	var itemTotals map[int]float64
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		if project, err = h.findUnlockedProject(ctx, tx, c); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		workItems, err = h.projectWorkItemsRepo.FindByProjectIdWithDetails(ctx, tx, project.ProjectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		itemTotals, err = projectItemTotals(ctx, tx, h.projectItemCostsRepo, project.ProjectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return sharedProjectError(c, err)
+	}
+
+	return sendRabWorkbook(c, project, workItems, itemTotals)
+}
+
+// ExportSharedMaterialSummary downloads the material summary of a shared project as pdf or excel
+func (h *ShareLinksHandler) ExportSharedMaterialSummary(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	format := c.Query("format", "pdf")
+	if format != "pdf" && format != "excel" {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid format. Use 'pdf' or 'excel'")
+	}
+
+	var project models.Project
+	var materialSummaries []models.MaterialSummary
+
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		if project, err = h.findUnlockedProject(ctx, tx, c); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		materialSummaries, err = h.materialSummaryRepo.GetProjectMaterialSummary(ctx, tx, project.ProjectId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return sharedProjectError(c, err)
+	}
+
+	if format == "pdf" {
+		return exportProjectToPDF(c, materialSummaries, project)
+	}
+	return exportProjectToExcel(c, materialSummaries, project)
+}
+
+// findLinks returns the project with its share links when the user owns it, only owners share
+// a project outside the app
+func (h *ShareLinksHandler) findLinks(ctx context.Context, tx *sql.Tx, projectId int, userData models.SessionUser) (models.Project, []models.ShareLink, error) {
+	project, err := h.projectsRepo.FindById(ctx, tx, projectId)
+	if err != nil {
+		return project, nil, err
+	}
+
+	if !userData.OwnsProject(project) {
+		return project, nil, fiber.NewError(fiber.StatusForbidden, "Only the owner of the project can share it")
+	}
+
+	links, err := h.shareLinksRepo.FindByProjectId(ctx, tx, projectId)
+	return project, links, err
+}
+
+// findSharedProject returns the active link with the token and its project. Unknown, revoked
+// and expired links are all answered the same, so a visitor cannot tell them apart.
+func (h *ShareLinksHandler) findSharedProject(ctx context.Context, tx *sql.Tx, token string) (models.ShareLink, models.Project, error) {
+	notFound := fiber.NewError(fiber.StatusNotFound, "This link does not exist, has expired or was revoked")
+
+	if !strings.HasPrefix(token, models.SHARE_LINK_PREFIX) {
+		return models.ShareLink{}, models.Project{}, notFound
+	}
+
+	link, err := h.shareLinksRepo.FindByHash(ctx, tx, models.HashAPIToken(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return link, models.Project{}, notFound
+		}
+		return link, models.Project{}, err
+	}
+
+	if !link.IsActive(time.Now()) {
+		return link, models.Project{}, notFound
+	}
+
+	project, err := h.projectsRepo.FindById(ctx, tx, link.ProjectId)
+	if err != nil {
+		return link, project, err
+	}
+
+	if project.ProjectId == 0 {
+		return link, project, notFound
+	}
+
+	return link, project, nil
+}
+
+// findUnlockedProject returns the project of the link in the URL when the visitor may see it
+func (h *ShareLinksHandler) findUnlockedProject(ctx context.Context, tx *sql.Tx, c *fiber.Ctx) (models.Project, error) {
+	link, project, err := h.findSharedProject(ctx, tx, c.Params("token"))
+	if err != nil {
+		return project, err
+	}
+
+	if !isShareLinkUnlocked(c, link) {
+		return project, fiber.NewError(fiber.StatusUnauthorized, "Open the link and enter its password first")
+	}
+
+	return project, nil
+}
+
+// isShareLinkUnlocked reports whether the visitor may see the project of the link: always for
+// links without a password, after UnlockSharedProject for the others
+func isShareLinkUnlocked(c *fiber.Ctx, link models.ShareLink) bool {
+	if !link.HasPassword() {
+		return true
+	}
+
+	unlocked, err := middlewares.CheckSession(c, SESSION_SHARE_LINK_PREFIX+strconv.Itoa(link.LinkId))
+	if err != nil {
+		return false
+	}
+
+	ok, _ := unlocked.(bool)
+	return ok
+}
+
+// sharedProjectError shows the error of a shared page to a visitor without an account
+func sharedProjectError(c *fiber.Ctx, err error) error {
+	status, message := fiber.StatusInternalServerError, "Failed to open the shared project"
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		status, message = fiberErr.Code, fiberErr.Message
+	}
+
+	page := components.SharedProjectErrorPage(message)
+	return adaptor.HTTPHandler(templ.Handler(page, templ.WithStatus(status)))(c)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// TestShareLinks_Create verifies the owner creates a link whose URL is shown once and whose
+// password is stored hashed, and revokes it, while others cannot share the project
+func TestShareLinks_Create(t *testing.T) {
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	linksRepo := newFakeShareLinksRepo()
+	handler := NewShareLinksHandler(&fakeDatabase{}, projectsRepo, linksRepo, nil, nil, nil)
+
+	owner := newTestApp(7)
+	owner.Post("/project/:id/share-links", handler.CreateShareLink)
+	owner.Delete("/project/:id/share-links/:linkId", handler.RevokeShareLink)
+
+	body := responseBody(t, doRequest(t, owner, http.MethodPost, "/project/1/share-links", url.Values{"label": {"Pak Andi"}, "expiry_days": {"7"}, "password": {"rahasia"}}))
+	if !strings.Contains(body, SHARE_LINK_PATH+models.SHARE_LINK_PREFIX) {
+		t.Fatalf("Expected the link URL to be shown, got %s", body)
+	}
+	if len(linksRepo.links) != 1 {
+		t.Fatalf("Expected one link, got %+v", linksRepo.links)
+	}
+	link := linksRepo.links[0]
+	if link.Label != "Pak Andi" || link.CreatedBy != 7 || link.ExpiresAt == "" || link.PasswordHash == "rahasia" || models.CheckPassword(link.PasswordHash, "rahasia") != nil {
+		t.Errorf("Unexpected link: %+v", link)
+	}
+	if strings.Contains(body, link.TokenHash) {
+		t.Errorf("Expected the hash not to be shown")
+	}
+
+	if body := responseBody(t, doRequest(t, owner, http.MethodPost, "/project/1/share-links", url.Values{"label": {"Bank"}, "expiry_days": {"0"}, "password": {"abc"}})); !strings.Contains(body, "at least") {
+		t.Errorf("Expected a short password to be refused, got %s", body)
+	}
+	if body := responseBody(t, doRequest(t, owner, http.MethodPost, "/project/1/share-links", url.Values{"label": {"Bank"}, "expiry_days": {"1000"}})); !strings.Contains(body, "Choose an expiry") {
+		t.Errorf("Expected a long expiry to be refused, got %s", body)
+	}
+
+	if body := responseBody(t, doRequest(t, owner, http.MethodDelete, "/project/1/share-links/1", nil)); !strings.Contains(body, "Revoked") {
+		t.Errorf("Expected the link to be listed as revoked, got %s", body)
+	}
+
+	stranger := newTestApp(8)
+	stranger.Post("/project/:id/share-links", handler.CreateShareLink)
+	stranger.Delete("/project/:id/share-links/:linkId", handler.RevokeShareLink)
+
+	if body := responseBody(t, doRequest(t, stranger, http.MethodPost, "/project/1/share-links", url.Values{"label": {"Mine"}, "expiry_days": {"7"}})); !strings.Contains(body, "Only the owner of the project can share it") {
+		t.Errorf("Expected another user to be refused, got %s", body)
+	}
+	if len(linksRepo.links) != 1 {
+		t.Errorf("Expected no new link, got %+v", linksRepo.links)
+	}
+}
+
+// TestSharedProject_Access verifies unknown, expired and revoked links are not found, and a
+// protected link asks for its password before the project or its exports open
+func TestSharedProject_Access(t *testing.T) {
+	if middlewares.Store == nil {
+		middlewares.Store = session.New()
+	}
+
+	passwordHash, err := models.HashPassword("rahasia")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	linksRepo := newFakeShareLinksRepo(
+		models.ShareLink{LinkId: 1, ProjectId: 1, TokenHash: models.HashAPIToken("rs_expired"), ExpiresAt: "2020-01-01 00:00:00"},
+		models.ShareLink{LinkId: 2, ProjectId: 1, TokenHash: models.HashAPIToken("rs_revoked"), RevokedAt: "2024-01-01 00:00:00"},
+		models.ShareLink{LinkId: 3, ProjectId: 1, TokenHash: models.HashAPIToken("rs_protected"), PasswordHash: passwordHash},
+	)
+	handler := NewShareLinksHandler(&fakeDatabase{}, projectsRepo, linksRepo, nil, nil, nil)
+
+	app := fiber.New()
+	app.Get("/share/:token", handler.SharedProjectView)
+	app.Post("/share/:token", handler.UnlockSharedProject)
+	app.Get("/share/:token/export/excel", handler.ExportSharedRab)
+
+	for _, token := range []string{"rs_unknown", "rs_expired", "rs_revoked", "rab_notalink"} {
+		if resp := doRequest(t, app, http.MethodGet, "/share/"+token, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s, got %d", token, resp.StatusCode)
+		}
+	}
+
+	resp := doRequest(t, app, http.MethodGet, "/share/rs_protected", nil)
+	if body := responseBody(t, resp); resp.StatusCode != http.StatusOK || !strings.Contains(body, "protected with a password") {
+		t.Errorf("Expected the password form, got %d %s", resp.StatusCode, body)
+	}
+	if linksRepo.links[2].AccessCount != 0 {
+		t.Errorf("Expected no view to be counted before the password, got %d", linksRepo.links[2].AccessCount)
+	}
+
+	if resp := doRequest(t, app, http.MethodGet, "/share/rs_protected/export/excel", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the export of a locked link to be refused, got %d", resp.StatusCode)
+	}
+
+	resp = doRequest(t, app, http.MethodPost, "/share/rs_protected", url.Values{"password": {"salah"}})
+	if body := responseBody(t, resp); resp.StatusCode != http.StatusUnauthorized || !strings.Contains(body, "not correct") {
+		t.Errorf("Expected a wrong password to be refused, got %d %s", resp.StatusCode, body)
+	}
+
+	resp = doRequest(t, app, http.MethodPost, "/share/rs_protected", url.Values{"password": {"rahasia"}})
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/share/rs_protected" || resp.Header.Get("Set-Cookie") == "" {
+		t.Errorf("Expected the link to be unlocked in the session, got %d %v", resp.StatusCode, resp.Header)
+	}
+}
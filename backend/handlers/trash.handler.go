@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
+	"github.com/momokii/go-rab-maker/backend/repository/trash"
+	"github.com/momokii/go-rab-maker/backend/trash_bin"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// TrashHandler lists the deleted projects, work items and master data of the workspace and
+// restores or purges them
+type TrashHandler struct {
+	dbService            databases.DatabaseServices
+	bin                  *trash_bin.Bin
+	trashRepo            trash.Repository
+	projectApprovalsRepo project_approvals.Repository
+}
+
+func NewTrashHandler(
+	dbService databases.DatabaseServices,
+	bin *trash_bin.Bin,
+	trashRepo trash.Repository,
+	projectApprovalsRepo project_approvals.Repository,
+) *TrashHandler {
+	return &TrashHandler{
+		dbService:            dbService,
+		bin:                  bin,
+		trashRepo:            trashRepo,
+		projectApprovalsRepo: projectApprovalsRepo,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+// TrashView lists the items of the trash the user may restore, narrowed to an entity by the
+// entity query parameter
+func (h *TrashHandler) TrashView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	entity := c.Query("entity")
+	if entity != "" && !slices.Contains(models.TRASH_ENTITIES, entity) {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid entity " + entity)
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var found []models.TrashItem
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		found, err = h.trashRepo.Find(ctx, tx, userData.Workspace(), entity)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to load the trash")
+	}
+
+	// system-wide master data shows up in every workspace, only admins may bring it back
+	items := []models.TrashItem{}
+	for _, item := range found {
+		if userData.CanManageTrashItem(item) {
+			items = append(items, item)
+		}
+	}
+
+	page := components.TrashPage(items, entity, h.bin.RetentionDays(), time.Now().UTC())
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// RestoreTrashItem takes an item out of the trash, a work item only while its project is not locked
+func (h *TrashHandler) RestoreTrashItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	entity, id, err := trashItemFromParams(c)
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", err.Error())
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var item models.TrashItem
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if status, err := h.findTrashItem(c, tx, entity, id, userData, &item); err != nil {
+			return status, err
+		}
+
+		if item.Entity == models.AUDIT_ENTITY_WORK_ITEM {
+			if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, item.ProjectId); err != nil {
+				return status, err
+			}
+		}
+
+		if err := h.bin.Restore(ctx, tx, item); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to restore the item: "+err.Error())
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Success", item.EntityLabel()+" "+item.Name+" restored", "/trash")
+}
+
+// PurgeTrashItem deletes an item of the trash for good, project data only while its project
+// is not locked
+func (h *TrashHandler) PurgeTrashItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	entity, id, err := trashItemFromParams(c)
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", err.Error())
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var item models.TrashItem
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if status, err := h.findTrashItem(c, tx, entity, id, userData, &item); err != nil {
+			return status, err
+		}
+
+		if item.IsProjectData() {
+			if status, err := checkProjectUnlocked(ctx, tx, h.projectApprovalsRepo, item.ProjectId); err != nil {
+				return status, err
+			}
+		}
+
+		if err := h.bin.Purge(ctx, tx, item); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		// categories and templates stay while work items, in the trash or not, use them
+		if errors.Is(err, master_work_categories.ErrCategoryInUse) || errors.Is(err, ahsptemplates.ErrTemplateInUse) {
+			return utils.ResponseErrorModal(c, "Cannot Delete",
+				"Cannot delete "+item.Name+" for good because it is used in work items, purge those first")
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to delete the item: "+err.Error())
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Success", item.EntityLabel()+" "+item.Name+" deleted for good", "/trash")
+}
+
+// findTrashItem loads an item of the trash the user may restore or purge into item
+func (h *TrashHandler) findTrashItem(c *fiber.Ctx, tx *sql.Tx, entity string, id int, userData models.SessionUser, item *models.TrashItem) (int, error) {
+	found, err := h.trashRepo.FindById(c.UserContext(), tx, entity, id)
+	if err == sql.ErrNoRows {
+		return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Item not found in the trash")
+	}
+	if err != nil {
+		return fiber.StatusInternalServerError, err
+	}
+
+	if !userData.CanManageTrashItem(found) {
+		return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	*item = found
+	return fiber.StatusOK, nil
+}
+
+// trashItemFromParams reads the entity and id of the route
+func trashItemFromParams(c *fiber.Ctx) (string, int, error) {
+	entity := c.Params("entity")
+	if !slices.Contains(models.TRASH_ENTITIES, entity) {
+		return "", 0, fiber.NewError(fiber.StatusBadRequest, "Invalid entity "+entity)
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return "", 0, fiber.NewError(fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	return entity, id, nil
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/trash_bin"
+)
+
+// TestTrash_RestoreAndPurge verifies a deleted project and material show up in the trash of
+// their owner, who restores or purges them, while system-wide master data is left to admins
+// and the work items of a locked project stay in the trash
+func TestTrash_RestoreAndPurge(t *testing.T) {
+	projectsRepo := newFakeProjectsRepo(models.Project{ProjectId: 1, UserId: 7, ProjectName: "Rumah Tinggal"})
+	materialsRepo := newFakeMaterialsRepo(
+		models.MasterMaterial{MaterialId: 1, UserId: 7, MaterialName: "Semen", Unit: "kg"},
+		models.MasterMaterial{MaterialId: 2, MaterialName: "Pasir", Unit: "m3"},
+	)
+	trashRepo := &fakeTrashRepo{
+		projects:  projectsRepo,
+		materials: materialsRepo,
+		workItems: []models.TrashItem{{Entity: models.AUDIT_ENTITY_WORK_ITEM, Id: 4, Name: "Pasangan bata", UserId: 7, ProjectId: 3}},
+	}
+	approvalsRepo := newFakeProjectApprovalsRepo(models.ProjectApproval{ProjectId: 3, Status: models.APPROVAL_STATUS_SUBMITTED})
+
+	bin := trash_bin.NewBin(&fakeDatabase{}, trashRepo, projectsRepo, nil, materialsRepo, nil, nil, nil, approvalsRepo, trash_bin.Config{RetentionDays: 30})
+	handler := NewTrashHandler(&fakeDatabase{}, bin, trashRepo, approvalsRepo)
+
+	for _, id := range []int{1, 2} {
+		materialsRepo.trashed[id] = materialsRepo.materials[id]
+		delete(materialsRepo.materials, id)
+	}
+	projectsRepo.trashed[1] = projectsRepo.projects[1]
+	delete(projectsRepo.projects, 1)
+
+	app := newTestApp(7)
+	app.Get("/trash", handler.TrashView)
+	app.Post("/trash/:entity/:id/restore", handler.RestoreTrashItem)
+	app.Delete("/trash/:entity/:id/delete", handler.PurgeTrashItem)
+
+	body := responseBody(t, doRequest(t, app, http.MethodGet, "/trash", nil))
+	if !strings.Contains(body, "Rumah Tinggal") || !strings.Contains(body, "Semen") || !strings.Contains(body, "Pasangan bata") {
+		t.Errorf("Expected the project, material and work item in the trash, got %s", body)
+	}
+	if strings.Contains(body, "Pasir") {
+		t.Errorf("Expected system-wide master data to be left to admins, got %s", body)
+	}
+
+	body = responseBody(t, doRequest(t, app, http.MethodGet, "/trash?entity=material", nil))
+	if strings.Contains(body, "Rumah Tinggal") || !strings.Contains(body, "Semen") {
+		t.Errorf("Expected only the materials, got %s", body)
+	}
+	if resp := doRequest(t, app, http.MethodGet, "/trash?entity=users", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an unknown entity to be refused, got %d", resp.StatusCode)
+	}
+
+	if body := responseBody(t, doRequest(t, app, http.MethodPost, "/trash/project/1/restore", nil)); !strings.Contains(body, "Project Rumah Tinggal restored") {
+		t.Errorf("Expected the project to be restored, got %s", body)
+	}
+	if _, ok := projectsRepo.projects[1]; !ok {
+		t.Error("Expected the project back outside the trash")
+	}
+	if body := responseBody(t, doRequest(t, app, http.MethodPost, "/trash/project/1/restore", nil)); !strings.Contains(body, "Item not found in the trash") {
+		t.Errorf("Expected a restored project to be gone from the trash, got %s", body)
+	}
+
+	if body := responseBody(t, doRequest(t, app, http.MethodDelete, "/trash/material/1/delete", nil)); !strings.Contains(body, "Material Semen deleted for good") {
+		t.Errorf("Expected the material to be purged, got %s", body)
+	}
+	if _, ok := materialsRepo.trashed[1]; ok {
+		t.Error("Expected the material to be gone from the trash")
+	}
+	if _, ok := materialsRepo.materials[1]; ok {
+		t.Error("Expected a purged material not to come back")
+	}
+
+	if body := responseBody(t, doRequest(t, app, http.MethodDelete, "/trash/material/2/delete", nil)); !strings.Contains(body, "Access denied") {
+		t.Errorf("Expected system-wide master data to be refused, got %s", body)
+	}
+	if body := responseBody(t, doRequest(t, app, http.MethodPost, "/trash/work_item/4/restore", nil)); !strings.Contains(body, "submitted for approval") {
+		t.Errorf("Expected a work item of a submitted project to stay in the trash, got %s", body)
+	}
+	if body := responseBody(t, doRequest(t, app, http.MethodDelete, "/trash/work_item/4/delete", nil)); !strings.Contains(body, "submitted for approval") {
+		t.Errorf("Expected a work item of a submitted project not to be purged, got %s", body)
+	}
+
+	admin := newTestAppAs(1, models.ROLE_ADMIN)
+	admin.Post("/trash/:entity/:id/restore", handler.RestoreTrashItem)
+	if body := responseBody(t, doRequest(t, admin, http.MethodPost, "/trash/material/2/restore", nil)); !strings.Contains(body, "Material Pasir restored") {
+		t.Errorf("Expected an admin to restore system-wide master data, got %s", body)
+	}
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/users"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// UsersHandler lets administrators assign roles and disable accounts
+type UsersHandler struct {
+	dbService databases.DatabaseServices
+	usersRepo users.Repository
+	session   *middlewares.SessionMiddleware
+}
+
+func NewUsersHandler(
+	dbService databases.DatabaseServices,
+	usersRepo users.Repository,
+	session *middlewares.SessionMiddleware,
+) *UsersHandler {
+	return &UsersHandler{
+		dbService: dbService,
+		usersRepo: usersRepo,
+		session:   session,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+func (h *UsersHandler) UsersView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var rows []models.UserRow
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		found, err := h.usersRepo.FindAll(ctx, tx)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		for _, user := range found {
+			rows = append(rows, models.UserRow{
+				User:            user,
+				ConfiguredAdmin: h.session.IsConfiguredAdmin(user.Username),
+				IsCurrentUser:   user.UserId == userData.ID,
+			})
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to list users")
+	}
+
+	page := components.UsersPage(rows)
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// UpdateUserRole assigns a role. Admins cannot change their own role, so there is always an
+// admin left, and the role of an ADMIN_USERNAMES account would not be used.
+func (h *UsersHandler) UpdateUserRole(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid user ID")
+	}
+
+	role := c.FormValue("role")
+	if !models.IsRole(role) {
+		return utils.ResponseErrorModal(c, "Validation Error", "Unknown role "+role)
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		user, err := h.findChangeableUser(ctx, tx, userId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.usersRepo.UpdateRole(ctx, tx, user.UserId, role); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to update the role")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Success", "Role updated", "/admin/users")
+}
+
+// DisableUser stops a user from logging in, their sessions and API tokens stop working
+func (h *UsersHandler) DisableUser(c *fiber.Ctx) error {
+	return h.setDisabled(c, true)
+}
+
+// EnableUser lets a disabled user log in again
+func (h *UsersHandler) EnableUser(c *fiber.Ctx) error {
+	return h.setDisabled(c, false)
+}
+
+func (h *UsersHandler) setDisabled(c *fiber.Ctx, disabled bool) error {
+	ctx := c.UserContext()
+
+	userId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid user ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		user, err := h.findChangeableUser(ctx, tx, userId, userData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if disabled {
+			err = h.usersRepo.SoftDelete(ctx, tx, user.UserId)
+		} else {
+			err = h.usersRepo.Restore(ctx, tx, user.UserId)
+		}
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		if !disabled {
+			return utils.ResponseErrorModal(c, "Error", "Failed to enable the user, the username may have been taken by another account")
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to disable the user")
+	}
+
+	message := "User enabled"
+	if disabled {
+		message = "User disabled"
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Success", message, "/admin/users")
+}
+
+// findChangeableUser returns the user when the admin may change them: not themselves and not
+// an account that ADMIN_USERNAMES makes admin
+func (h *UsersHandler) findChangeableUser(ctx context.Context, tx *sql.Tx, userId int, userData models.SessionUser) (models.User, error) {
+	user, err := h.usersRepo.FindById(ctx, tx, userId)
+	if err == sql.ErrNoRows {
+		return user, fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	if err != nil {
+		return user, err
+	}
+
+	if user.UserId == userData.ID {
+		return user, fiber.NewError(fiber.StatusConflict, "You cannot change your own account, ask another admin")
+	}
+
+	if h.session.IsConfiguredAdmin(user.Username) {
+		return user, fiber.NewError(fiber.StatusConflict, user.Username+" is an admin through ADMIN_USERNAMES, change the environment instead")
+	}
+
+	return user, nil
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/middlewares"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/webhook_deliveries"
+	"github.com/momokii/go-rab-maker/backend/repository/webhooks"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
+	"github.com/momokii/go-rab-maker/frontend/components"
+)
+
+// WEBHOOK_DELIVERY_LOG_LIMIT is how many of the newest deliveries the delivery log shows
+const WEBHOOK_DELIVERY_LOG_LIMIT = 100
+
+// WebhooksHandler lets users manage their webhooks and see their delivery log on the settings page
+type WebhooksHandler struct {
+	dbService      databases.DatabaseServices
+	webhooksRepo   webhooks.Repository
+	deliveriesRepo webhook_deliveries.Repository
+	dispatcher     *webhook_dispatch.Dispatcher
+}
+
+func NewWebhooksHandler(
+	dbService databases.DatabaseServices,
+	webhooksRepo webhooks.Repository,
+	deliveriesRepo webhook_deliveries.Repository,
+	dispatcher *webhook_dispatch.Dispatcher,
+) *WebhooksHandler {
+	return &WebhooksHandler{
+		dbService:      dbService,
+		webhooksRepo:   webhooksRepo,
+		deliveriesRepo: deliveriesRepo,
+		dispatcher:     dispatcher,
+	}
+}
+
+// ==========================
+// ========================== VIEWS
+// ==========================
+
+func (h *WebhooksHandler) WebhooksView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var userWebhooks []models.Webhook
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		userWebhooks, err = h.webhooksRepo.FindByUserId(ctx, tx, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to load webhooks")
+	}
+
+	page := components.WebhooksPage(userWebhooks)
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+func (h *WebhooksHandler) WebhookCreateModalView(c *fiber.Ctx) error {
+	modal := components.WebhookCreateModal(models.WEBHOOK_EVENTS)
+	return adaptor.HTTPHandler(templ.Handler(modal))(c)
+}
+
+// WebhookDeliveriesView shows a webhook with its signing secret and newest deliveries
+func (h *WebhooksHandler) WebhookDeliveriesView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	webhookId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid webhook ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	var webhook models.Webhook
+	var deliveries []models.WebhookDelivery
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		webhook, err = h.findOwnWebhook(ctx, tx, webhookId, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		deliveries, err = h.deliveriesRepo.FindByWebhookId(ctx, tx, webhookId, WEBHOOK_DELIVERY_LOG_LIMIT)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).SendString(fiberErr.Message)
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to load webhook deliveries")
+	}
+
+	page := components.WebhookDeliveriesPage(webhook, deliveries)
+	return adaptor.HTTPHandler(templ.Handler(page))(c)
+}
+
+// ==========================
+// ========================== FUNCTIONS
+// ==========================
+
+// CreateWebhook stores a webhook with a generated signing secret and opens its page
+func (h *WebhooksHandler) CreateWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	webhookData := models.WebhookCreate{
+		UserId: userData.ID,
+		URL:    strings.TrimSpace(c.FormValue("webhook_url")),
+	}
+	for _, event := range c.Request().PostArgs().PeekMulti("webhook_events") {
+		if !models.IsWebhookEvent(string(event)) {
+			return utils.ResponseErrorModal(c, "Validation Error", "Unknown event "+string(event))
+		}
+		webhookData.Events = append(webhookData.Events, string(event))
+	}
+
+	if err := utils.ValidateStruct(webhookData); err != nil {
+		errors := utils.GetValidationErrors(err)
+		return utils.ResponseErrorModal(c, "Validation Error", strings.Join(errors, "; "))
+	}
+	if !strings.HasPrefix(webhookData.URL, "http://") && !strings.HasPrefix(webhookData.URL, "https://") {
+		return utils.ResponseErrorModal(c, "Validation Error", "The URL must start with http:// or https://")
+	}
+
+	secret, err := models.NewWebhookSecret()
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to generate secret")
+	}
+	webhookData.Secret = secret
+
+	var webhookId int
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		webhookId, err = h.webhooksRepo.Create(ctx, tx, webhookData)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Failed to create webhook")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Webhook Created", "Verify deliveries with the signing secret shown on the webhook page", "/settings/webhooks/"+strconv.Itoa(webhookId))
+}
+
+// ToggleWebhook disables an active webhook or enables a disabled one. Deliveries queued while
+// it is disabled are kept and sent once it is enabled again.
+func (h *WebhooksHandler) ToggleWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	webhookId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid webhook ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		webhook, err := h.findOwnWebhook(ctx, tx, webhookId, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if webhook.IsActive() {
+			webhook.DisabledAt = time.Now().UTC().Format(models.WEBHOOK_TIME_FORMAT)
+		} else {
+			webhook.DisabledAt = ""
+		}
+
+		if err := h.webhooksRepo.Update(ctx, tx, webhook); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to update webhook")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Success", "Webhook updated", "/settings/webhooks")
+}
+
+// DeleteWebhook removes a webhook with its delivery log
+func (h *WebhooksHandler) DeleteWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	webhookId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid webhook ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := h.findOwnWebhook(ctx, tx, webhookId, userData.ID); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if err := h.webhooksRepo.Delete(ctx, tx, webhookId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to delete webhook")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Webhook Deleted", "No more events are sent to it", "/settings/webhooks")
+}
+
+// PingWebhook queues a ping event for the webhook, to check the receiver and its signature check
+func (h *WebhooksHandler) PingWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	webhookId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid webhook ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		webhook, err := h.findOwnWebhook(ctx, tx, webhookId, userData.ID)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		if _, err := h.dispatcher.Enqueue(ctx, tx, webhookId, models.WEBHOOK_EVENT_PING, fiber.Map{
+			"webhook_id": webhook.WebhookId,
+			"url":        webhook.URL,
+		}); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to queue the test event")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Test Event Queued", "A ping is sent within a few seconds", "/settings/webhooks/"+strconv.Itoa(webhookId))
+}
+
+// RetryWebhookDelivery sends a failed delivery again with a fresh set of attempts
+func (h *WebhooksHandler) RetryWebhookDelivery(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	webhookId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid webhook ID")
+	}
+
+	deliveryId, err := strconv.Atoi(c.Params("deliveryId"))
+	if err != nil {
+		return utils.ResponseErrorModal(c, "Error", "Invalid delivery ID")
+	}
+
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := h.findOwnWebhook(ctx, tx, webhookId, userData.ID); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		delivery, err := h.deliveriesRepo.FindById(ctx, tx, deliveryId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Delivery not found")
+			}
+			return fiber.StatusInternalServerError, err
+		}
+
+		if delivery.WebhookId != webhookId {
+			return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Delivery not found")
+		}
+
+		if delivery.Status != models.WEBHOOK_DELIVERY_FAILED {
+			return fiber.StatusConflict, fiber.NewError(fiber.StatusConflict, "Only failed deliveries can be retried")
+		}
+
+		if err := h.deliveriesRepo.Retry(ctx, tx, deliveryId); err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return utils.ResponseErrorModal(c, "Error", fiberErr.Message)
+		}
+		return utils.ResponseErrorModal(c, "Error", "Failed to retry delivery")
+	}
+
+	return utils.ResponseSuccessWithRedirect(c, "Retry Queued", "The delivery is sent again within a few seconds", "/settings/webhooks/"+strconv.Itoa(webhookId))
+}
+
+// findOwnWebhook returns the webhook when it belongs to userId, otherwise a not found or access denied error
+func (h *WebhooksHandler) findOwnWebhook(ctx context.Context, tx *sql.Tx, webhookId, userId int) (models.Webhook, error) {
+	webhook, err := h.webhooksRepo.FindById(ctx, tx, webhookId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return webhook, fiber.NewError(fiber.StatusNotFound, "Webhook not found")
+		}
+		return webhook, err
+	}
+
+	if webhook.UserId != userId {
+		return webhook, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return webhook, nil
+}
@@ -2,9 +2,9 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/a-h/templ"
 	"github.com/gofiber/fiber/v2"
@@ -18,13 +18,13 @@ import (
 )
 
 type WorkCategoryHandler struct {
-	dbService          databases.SQLiteServices
-	workCategoriesRepo *master_work_categories.MasterWorkCategoriesRepo
+	dbService          databases.DatabaseServices
+	workCategoriesRepo master_work_categories.Repository
 }
 
 func NewWorkCategoryHandler(
-	dbService databases.SQLiteServices,
-	workCategoriesRepo *master_work_categories.MasterWorkCategoriesRepo,
+	dbService databases.DatabaseServices,
+	workCategoriesRepo master_work_categories.Repository,
 ) *WorkCategoryHandler {
 	return &WorkCategoryHandler{
 		dbService:          dbService,
@@ -37,9 +37,14 @@ func NewWorkCategoryHandler(
 // ==========================
 
 func (h *WorkCategoryHandler) WorkCategoriesMainPageTableView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	var workCategories []models.MasterWorkCategory
 	var paginationInfo models.PaginationInfo
 
+	// Get user from session
+	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
+
 	// get pagination data
 	paginationData, err := utils.GetPaginationData(c)
 	if err != nil {
@@ -51,12 +56,12 @@ func (h *WorkCategoryHandler) WorkCategoriesMainPageTableView(c *fiber.Ctx) erro
 	}
 
 	// start transaction to get the data
-	if _, err := h.dbService.Transaction(
-		c.Context(),
+	if _, err := h.dbService.ReadTransaction(
+		ctx,
 		func(tx *sql.Tx) (int, error) {
 			// get the work category data
 			workCategoriesData, paginationData, err := h.workCategoriesRepo.Find(
-				tx, paginationData,
+				ctx, tx, paginationData, userData.Workspace(),
 			)
 			if err != nil {
 				return fiber.StatusInternalServerError, err
@@ -110,6 +115,8 @@ func (h *WorkCategoryHandler) WorkCategoryCreateModalView(c *fiber.Ctx) error {
 }
 
 func (h *WorkCategoryHandler) WorkCategoryEditModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	workCategoryIdStr := c.Params("id")
 	workCategoryId, err := strconv.Atoi(workCategoryIdStr)
 	if err != nil {
@@ -122,8 +129,8 @@ func (h *WorkCategoryHandler) WorkCategoryEditModalView(c *fiber.Ctx) error {
 	var workCategory models.MasterWorkCategory
 
 	// Fetch work category from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		workCategory, err = h.workCategoriesRepo.FindById(tx, workCategoryId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		workCategory, err = h.workCategoriesRepo.FindById(ctx, tx, workCategoryId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Work category not found")
@@ -131,8 +138,8 @@ func (h *WorkCategoryHandler) WorkCategoryEditModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if work category belongs to current user
-		if workCategory.UserId != userData.ID {
+		// Check if the current user may change the work category, system-wide defaults need an admin
+		if !userData.CanModify(workCategory.UserId, workCategory.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -153,6 +160,8 @@ func (h *WorkCategoryHandler) WorkCategoryEditModalView(c *fiber.Ctx) error {
 }
 
 func (h *WorkCategoryHandler) WorkCategoryDeleteModalView(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
 	workCategoryIdStr := c.Params("id")
 	workCategoryId, err := strconv.Atoi(workCategoryIdStr)
 	if err != nil {
@@ -165,8 +174,8 @@ func (h *WorkCategoryHandler) WorkCategoryDeleteModalView(c *fiber.Ctx) error {
 	var workCategory models.MasterWorkCategory
 
 	// Fetch work category from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		workCategory, err = h.workCategoriesRepo.FindById(tx, workCategoryId)
+	if _, err := h.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		workCategory, err = h.workCategoriesRepo.FindById(ctx, tx, workCategoryId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Work category not found")
@@ -174,8 +183,8 @@ func (h *WorkCategoryHandler) WorkCategoryDeleteModalView(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if work category belongs to current user
-		if workCategory.UserId != userData.ID {
+		// Check if the current user may change the work category, system-wide defaults need an admin
+		if !userData.CanModify(workCategory.UserId, workCategory.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
@@ -200,6 +209,7 @@ func (h *WorkCategoryHandler) WorkCategoryDeleteModalView(c *fiber.Ctx) error {
 
 // CreateWorkCategory handles the creation of a new work category
 func (h *WorkCategoryHandler) CreateWorkCategory(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	// Get user from session (using the same approach as in auth.handler.go)
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
@@ -226,12 +236,13 @@ func (h *WorkCategoryHandler) CreateWorkCategory(c *fiber.Ctx) error {
 	workCategoryData := models.MasterWorkCategoryCreate{
 		CategoryName: categoryName,
 		DisplayOrder: displayOrder,
-		UserId:       userData.ID,
+		UserId:       userData.Workspace().OwnerUserId(),
+		OrgId:        userData.OrgId,
 	}
 
 	// Create work category in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
-		if err := h.workCategoriesRepo.Create(tx, workCategoryData); err != nil {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := h.workCategoriesRepo.Create(ctx, tx, workCategoryData); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -248,6 +259,7 @@ func (h *WorkCategoryHandler) CreateWorkCategory(c *fiber.Ctx) error {
 
 // UpdateWorkCategory handles the update of an existing work category
 func (h *WorkCategoryHandler) UpdateWorkCategory(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	workCategoryIdStr := c.Params("id")
 	workCategoryId, err := strconv.Atoi(workCategoryIdStr)
@@ -277,9 +289,9 @@ func (h *WorkCategoryHandler) UpdateWorkCategory(c *fiber.Ctx) error {
 	}
 
 	// Update work category in database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing work category to ensure it belongs to the user
-		existingWorkCategory, err := h.workCategoriesRepo.FindById(tx, workCategoryId)
+		existingWorkCategory, err := h.workCategoriesRepo.FindById(ctx, tx, workCategoryId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Work category not found")
@@ -287,22 +299,23 @@ func (h *WorkCategoryHandler) UpdateWorkCategory(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if work category belongs to current user
-		if existingWorkCategory.UserId != userData.ID {
+		// Check if the current user may change the work category, system-wide defaults need an admin
+		if !userData.CanModify(existingWorkCategory.UserId, existingWorkCategory.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Update the work category
 		updatedWorkCategory := models.MasterWorkCategory{
 			CategoryId:   workCategoryId,
-			UserId:       userData.ID,
+			UserId:       existingWorkCategory.UserId,
+			OrgId:        existingWorkCategory.OrgId,
 			CategoryName: categoryName,
 			DisplayOrder: displayOrder,
 			CreatedAt:    existingWorkCategory.CreatedAt,
-			UpdatedAt:    time.Now().Format("2006-01-02 15:04:05"),
+			UpdatedAt:    models.UTCNow(),
 		}
 
-		if err := h.workCategoriesRepo.Update(tx, updatedWorkCategory); err != nil {
+		if err := h.workCategoriesRepo.Update(ctx, tx, updatedWorkCategory); err != nil {
 			return fiber.StatusInternalServerError, fiber.NewError(fiber.StatusInternalServerError, "Make sure the category name is set to unique value")
 		}
 		return fiber.StatusOK, nil
@@ -319,6 +332,7 @@ func (h *WorkCategoryHandler) UpdateWorkCategory(c *fiber.Ctx) error {
 
 // DeleteWorkCategory handles the deletion of a work category
 func (h *WorkCategoryHandler) DeleteWorkCategory(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 
 	workCategoryIdStr := c.Params("id")
 	workCategoryId, err := strconv.Atoi(workCategoryIdStr)
@@ -330,9 +344,9 @@ func (h *WorkCategoryHandler) DeleteWorkCategory(c *fiber.Ctx) error {
 	userData := c.Locals(middlewares.SESSION_USER_NAME).(models.SessionUser)
 
 	// Delete work category from database
-	if _, err := h.dbService.Transaction(c.Context(), func(tx *sql.Tx) (int, error) {
+	if _, err := h.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
 		// First, fetch the existing work category to ensure it belongs to the user
-		existingWorkCategory, err := h.workCategoriesRepo.FindById(tx, workCategoryId)
+		existingWorkCategory, err := h.workCategoriesRepo.FindById(ctx, tx, workCategoryId)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fiber.StatusNotFound, fiber.NewError(fiber.StatusNotFound, "Work category not found")
@@ -340,13 +354,13 @@ func (h *WorkCategoryHandler) DeleteWorkCategory(c *fiber.Ctx) error {
 			return fiber.StatusInternalServerError, err
 		}
 
-		// Check if work category belongs to current user
-		if existingWorkCategory.UserId != userData.ID {
+		// Check if the current user may change the work category, system-wide defaults need an admin
+		if !userData.CanModify(existingWorkCategory.UserId, existingWorkCategory.OrgId) {
 			return fiber.StatusForbidden, fiber.NewError(fiber.StatusForbidden, "Access denied")
 		}
 
 		// Delete the work category
-		if err := h.workCategoriesRepo.Delete(tx, existingWorkCategory); err != nil {
+		if err := h.workCategoriesRepo.Delete(ctx, tx, existingWorkCategory); err != nil {
 			return fiber.StatusInternalServerError, err
 		}
 		return fiber.StatusOK, nil
@@ -355,8 +369,7 @@ func (h *WorkCategoryHandler) DeleteWorkCategory(c *fiber.Ctx) error {
 		errLower := strings.ToLower(err.Error())
 		if strings.Contains(errLower, "foreign key") ||
 			strings.Contains(errLower, "constraint") ||
-			strings.Contains(errLower, "cannot delete") ||
-			strings.Contains(errLower, "used in") {
+			errors.Is(err, master_work_categories.ErrCategoryInUse) {
 			return utils.ResponseErrorModal(c, "Cannot Delete",
 				"Cannot delete this work category because it is used in work items")
 		}
@@ -367,5 +380,5 @@ func (h *WorkCategoryHandler) DeleteWorkCategory(c *fiber.Ctx) error {
 	utils.SetRefreshTableTriggerHeader(c)
 
 	// Return success response with refresh
-	return utils.ResponseSuccessModal(c, "Success", "Work category deleted successfully", true)
+	return utils.ResponseSuccessModal(c, "Success", "Work category moved to the trash", true)
 }
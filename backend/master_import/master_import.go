@@ -0,0 +1,306 @@
+package master_import
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
+)
+
+const (
+	KIND_MATERIALS   = "materials"
+	KIND_LABOR_TYPES = "labor_types"
+
+	ACTION_INSERT    = "insert"
+	ACTION_UPDATE    = "update"
+	ACTION_UNCHANGED = "unchanged"
+	ACTION_ERROR     = "error"
+)
+
+// header keywords (English and Indonesian) used to guess the column mapping
+var (
+	nameKeywords  = []string{"name", "nama", "material", "bahan", "role", "tenaga", "pekerja", "uraian", "description"}
+	unitKeywords  = []string{"unit", "satuan", "sat"}
+	priceKeywords = []string{"price", "harga", "wage", "upah", "cost", "biaya"}
+)
+
+// GuessMapping picks the first header matching each field, -1 when nothing matches
+func GuessMapping(headers []string) models.MasterImportColumnMapping {
+	mapping := models.MasterImportColumnMapping{Name: -1, Unit: -1, Price: -1}
+
+	for i, header := range headers {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+
+		switch {
+		case mapping.Price == -1 && containsAny(header, priceKeywords):
+			mapping.Price = i
+		case mapping.Unit == -1 && containsAny(header, unitKeywords):
+			mapping.Unit = i
+		case mapping.Name == -1 && containsAny(header, nameKeywords):
+			mapping.Name = i
+		}
+	}
+
+	return mapping
+}
+
+func containsAny(value string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(value, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+type Importer struct {
+	materialsRepo  master_materials.Repository
+	laborTypesRepo master_labor_types.Repository
+	publisher      webhook_dispatch.Publisher
+}
+
+func NewImporter(
+	materialsRepo master_materials.Repository,
+	laborTypesRepo master_labor_types.Repository,
+	publisher webhook_dispatch.Publisher,
+) *Importer {
+	return &Importer{
+		materialsRepo:  materialsRepo,
+		laborTypesRepo: laborTypesRepo,
+		publisher:      publisher,
+	}
+}
+
+// Preview classifies every data row (the first row is the header) as insert, update, unchanged or error.
+// Rows are matched against the items of the workspace by name, as names are unique per owner; a
+// match in another unit is an error row, and a match on a system-wide default becomes an insert
+// so the workspace gets its own priced copy.
+func (i *Importer) Preview(ctx context.Context, tx *sql.Tx, kind string, rows [][]string, mapping models.MasterImportColumnMapping, workspace models.Workspace) (models.MasterImportPreview, error) {
+	var preview models.MasterImportPreview
+
+	if kind != KIND_MATERIALS && kind != KIND_LABOR_TYPES {
+		return preview, fmt.Errorf("unknown import kind %q", kind)
+	}
+	if mapping.Name < 0 || mapping.Unit < 0 || mapping.Price < 0 {
+		return preview, errors.New("name, unit and price columns must all be mapped")
+	}
+
+	seenLines := map[string]int{}
+
+	for rowIdx := 1; rowIdx < len(rows); rowIdx++ {
+		row := rows[rowIdx]
+		if isBlankRow(row) {
+			continue
+		}
+
+		previewRow := models.MasterImportPreviewRow{
+			Line: rowIdx + 1,
+			Name: strings.TrimSpace(cell(row, mapping.Name)),
+			Unit: strings.TrimSpace(cell(row, mapping.Unit)),
+		}
+
+		price, err := utils.ParseNumber(cell(row, mapping.Price))
+		if err != nil {
+			previewRow.Action = ACTION_ERROR
+			previewRow.Message = "Invalid price: " + err.Error()
+			addRow(&preview, previewRow)
+			continue
+		}
+		previewRow.Price = price
+
+		if err := validateRow(kind, previewRow); err != nil {
+			previewRow.Action = ACTION_ERROR
+			previewRow.Message = err.Error()
+			addRow(&preview, previewRow)
+			continue
+		}
+
+		key := strings.ToLower(previewRow.Name)
+		if line, ok := seenLines[key]; ok {
+			previewRow.Action = ACTION_ERROR
+			previewRow.Message = fmt.Sprintf("Duplicate of row %d", line)
+			addRow(&preview, previewRow)
+			continue
+		}
+		seenLines[key] = previewRow.Line
+
+		existingId, existingSystemWide, existingUnit, existingPrice, err := i.findExisting(ctx, tx, kind, previewRow.Name, workspace)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return preview, err
+		}
+
+		switch {
+		case err != nil:
+			previewRow.Action = ACTION_INSERT
+		case existingSystemWide:
+			previewRow.Action = ACTION_INSERT
+			previewRow.Message = "Overrides the system default"
+		case !strings.EqualFold(existingUnit, previewRow.Unit):
+			previewRow.Action = ACTION_ERROR
+			previewRow.Message = fmt.Sprintf("Already exists with unit %s", existingUnit)
+		case existingPrice == previewRow.Price:
+			previewRow.Action = ACTION_UNCHANGED
+			previewRow.ExistingId = existingId
+			previewRow.OldPrice = existingPrice
+		default:
+			previewRow.Action = ACTION_UPDATE
+			previewRow.ExistingId = existingId
+			previewRow.OldPrice = existingPrice
+		}
+
+		addRow(&preview, previewRow)
+	}
+
+	return preview, nil
+}
+
+// Commit applies the insert and update rows of a preview, error and unchanged rows are skipped.
+// Call it inside the same transaction as Preview so the plan cannot go stale.
+func (i *Importer) Commit(ctx context.Context, tx *sql.Tx, kind string, preview models.MasterImportPreview, workspace models.Workspace) error {
+	for _, row := range preview.Rows {
+		var err error
+
+		switch row.Action {
+		case ACTION_INSERT:
+			err = i.insert(ctx, tx, kind, row, workspace)
+		case ACTION_UPDATE:
+			err = i.update(ctx, tx, kind, row, workspace)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("row %d (%s): %w", row.Line, row.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (i *Importer) findExisting(ctx context.Context, tx *sql.Tx, kind, name string, workspace models.Workspace) (id int, systemWide bool, unit string, price float64, err error) {
+	if kind == KIND_MATERIALS {
+		material, err := i.materialsRepo.FindByName(ctx, tx, name, workspace)
+		return material.MaterialId, material.UserId == 0 && material.OrgId == 0, material.Unit, material.DefaultUnitPrice, err
+	}
+
+	laborType, err := i.laborTypesRepo.FindByName(ctx, tx, name, workspace)
+	return laborType.LaborTypeId, laborType.UserId == 0 && laborType.OrgId == 0, laborType.Unit, laborType.DefaultDailyWage, err
+}
+
+func (i *Importer) insert(ctx context.Context, tx *sql.Tx, kind string, row models.MasterImportPreviewRow, workspace models.Workspace) error {
+	if kind == KIND_MATERIALS {
+		return i.materialsRepo.Create(ctx, tx, models.MasterMaterialCreate{
+			MaterialName:     row.Name,
+			Unit:             row.Unit,
+			DefaultUnitPrice: row.Price,
+			UserId:           workspace.OwnerUserId(),
+			OrgId:            workspace.OrgId,
+		})
+	}
+
+	return i.laborTypesRepo.Create(ctx, tx, models.MasterLaborTypeCreate{
+		RoleName:         row.Name,
+		Unit:             row.Unit,
+		DefaultDailyWage: row.Price,
+		UserId:           workspace.OwnerUserId(),
+		OrgId:            workspace.OrgId,
+	})
+}
+
+func (i *Importer) update(ctx context.Context, tx *sql.Tx, kind string, row models.MasterImportPreviewRow, workspace models.Workspace) error {
+	if kind == KIND_MATERIALS {
+		material, err := i.materialsRepo.FindById(ctx, tx, row.ExistingId)
+		if err != nil {
+			return err
+		}
+		oldPrice := material.DefaultUnitPrice
+		material.DefaultUnitPrice = row.Price
+		material.UserId = workspace.OwnerUserId()
+		material.OrgId = workspace.OrgId
+
+		if err := i.materialsRepo.Update(ctx, tx, material); err != nil {
+			return err
+		}
+
+		return i.publisher.Publish(ctx, tx, workspace.UserId, models.WEBHOOK_EVENT_MASTER_PRICE_CHANGED, models.NewMaterialPriceChange(material, oldPrice))
+	}
+
+	laborType, err := i.laborTypesRepo.FindById(ctx, tx, row.ExistingId)
+	if err != nil {
+		return err
+	}
+	oldWage := laborType.DefaultDailyWage
+	laborType.DefaultDailyWage = row.Price
+	laborType.UserId = workspace.OwnerUserId()
+	laborType.OrgId = workspace.OrgId
+
+	if err := i.laborTypesRepo.Update(ctx, tx, laborType); err != nil {
+		return err
+	}
+
+	return i.publisher.Publish(ctx, tx, workspace.UserId, models.WEBHOOK_EVENT_MASTER_PRICE_CHANGED, models.NewLaborWageChange(laborType, oldWage))
+}
+
+func validateRow(kind string, row models.MasterImportPreviewRow) error {
+	var data interface{}
+	if kind == KIND_MATERIALS {
+		data = models.MasterMaterialCreate{
+			MaterialName:     row.Name,
+			Unit:             row.Unit,
+			DefaultUnitPrice: row.Price,
+		}
+	} else {
+		data = models.MasterLaborTypeCreate{
+			RoleName:         row.Name,
+			Unit:             row.Unit,
+			DefaultDailyWage: row.Price,
+		}
+	}
+
+	if err := utils.ValidateStruct(data); err != nil {
+		return errors.New(strings.Join(utils.GetValidationErrors(err), "; "))
+	}
+
+	return nil
+}
+
+func addRow(preview *models.MasterImportPreview, row models.MasterImportPreviewRow) {
+	switch row.Action {
+	case ACTION_INSERT:
+		preview.Inserts++
+	case ACTION_UPDATE:
+		preview.Updates++
+	case ACTION_UNCHANGED:
+		preview.Unchanged++
+	case ACTION_ERROR:
+		preview.Errors++
+	}
+
+	preview.Rows = append(preview.Rows, row)
+}
+
+func cell(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
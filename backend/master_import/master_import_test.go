@@ -0,0 +1,376 @@
+package master_import
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	_ "modernc.org/sqlite"
+)
+
+// setupTestDB creates a temporary database for testing
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	tmpDB := t.TempDir() + "/test.db"
+
+	db, err := sql.Open("sqlite", "file:"+tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	// Create test schema
+	_, err = db.Exec(`
+		CREATE TABLE users (
+			user_id INTEGER PRIMARY KEY,
+			username TEXT NOT NULL
+		);
+
+		CREATE TABLE master_materials (
+			material_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			material_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			default_unit_price REAL NOT NULL DEFAULT 0,
+			is_equipment INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL,
+			UNIQUE (user_id, material_name)
+		);
+
+		CREATE TABLE master_labor_types (
+			labor_type_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			role_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			default_daily_wage REAL NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL,
+			UNIQUE (user_id, role_name)
+		);
+
+		CREATE TABLE audit_log (
+			audit_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			username TEXT NOT NULL DEFAULT '',
+			entity TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			project_id INTEGER,
+			action TEXT NOT NULL,
+			before_json TEXT NOT NULL DEFAULT '',
+			after_json TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		INSERT INTO users (user_id, username) VALUES (1, 'estimator'), (2, 'other');
+		INSERT INTO master_materials (material_id, user_id, material_name, unit, default_unit_price, is_equipment) VALUES
+			(1, 1, 'Semen Portland', 'kg', 1500, 0),
+			(2, 1, 'Pasir beton', 'm3', 250000, 0),
+			(3, NULL, 'Bata merah', 'buah', 800, 0),
+			(4, 2, 'Kerikil', 'm3', 300000, 0),
+			(5, 1, 'Molen', 'jam', 50000, 1);
+		INSERT INTO master_labor_types (labor_type_id, user_id, role_name, unit, default_daily_wage) VALUES
+			(1, 1, 'Pekerja', 'OH', 120000);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+
+	return db
+}
+
+// recordingPublisher keeps the webhook events an import publishes
+type recordingPublisher struct {
+	events []interface{}
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, tx *sql.Tx, userId int, event string, data interface{}) error {
+	p.events = append(p.events, data)
+	return nil
+}
+
+func newTestImporter() *Importer {
+	return NewImporter(
+		master_materials.NewMasterMaterialsRepo(),
+		master_labor_types.NewMasterLaborTypesRepo(),
+		&recordingPublisher{},
+	)
+}
+
+// TestGuessMapping verifies English and Indonesian headers are recognised
+func TestGuessMapping(t *testing.T) {
+	tests := []struct {
+		headers  []string
+		expected models.MasterImportColumnMapping
+	}{
+		{[]string{"Material Name", "Unit", "Unit Price"}, models.MasterImportColumnMapping{Name: 0, Unit: 1, Price: 2}},
+		{[]string{"No", "Nama Bahan", "Satuan", "Harga Satuan"}, models.MasterImportColumnMapping{Name: 1, Unit: 2, Price: 3}},
+		{[]string{"Upah", "Role Name", "Unit"}, models.MasterImportColumnMapping{Name: 1, Unit: 2, Price: 0}},
+		{[]string{"A", "B"}, models.MasterImportColumnMapping{Name: -1, Unit: -1, Price: -1}},
+	}
+
+	for _, test := range tests {
+		if got := GuessMapping(test.headers); got != test.expected {
+			t.Errorf("GuessMapping(%v) = %+v, expected %+v", test.headers, got, test.expected)
+		}
+	}
+}
+
+// TestPreview_ClassifiesRows verifies inserts, updates, unchanged rows and errors are detected
+func TestPreview_ClassifiesRows(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	rows := [][]string{
+		{"Material Name", "Unit", "Unit Price"},
+		{"semen portland", "KG", "Rp 1.650"}, // update, matched case-insensitively
+		{"Pasir beton", "m3", "250000"},      // unchanged
+		{"Bata merah", "buah", "900"},        // system default, user gets own copy
+		{"Kerikil", "m3", "310.000"},         // owned by another user, insert
+		{"", "", ""},                         // blank, skipped
+		{"Paku", "kg", "abc"},                // invalid price
+		{"Paku", "", "20000"},                // missing unit
+		{"Semen Portland", "kg", "1700"},     // duplicate of row 2
+	}
+	mapping := models.MasterImportColumnMapping{Name: 0, Unit: 1, Price: 2}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	preview, err := newTestImporter().Preview(ctx, tx, KIND_MATERIALS, rows, mapping, models.Workspace{UserId: 1})
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	expectedActions := map[int]string{
+		2: ACTION_UPDATE,
+		3: ACTION_UNCHANGED,
+		4: ACTION_INSERT,
+		5: ACTION_INSERT,
+		7: ACTION_ERROR,
+		8: ACTION_ERROR,
+		9: ACTION_ERROR,
+	}
+
+	if len(preview.Rows) != len(expectedActions) {
+		t.Fatalf("Expected %d preview rows, got %d: %+v", len(expectedActions), len(preview.Rows), preview.Rows)
+	}
+
+	for _, row := range preview.Rows {
+		if row.Action != expectedActions[row.Line] {
+			t.Errorf("Row %d: expected action %s, got %s (%s)", row.Line, expectedActions[row.Line], row.Action, row.Message)
+		}
+	}
+
+	if preview.Inserts != 2 || preview.Updates != 1 || preview.Unchanged != 1 || preview.Errors != 3 {
+		t.Errorf("Unexpected counts: %+v", preview)
+	}
+
+	if preview.Rows[0].OldPrice != 1500 || preview.Rows[0].Price != 1650 {
+		t.Errorf("Expected price change 1500 -> 1650, got %v -> %v", preview.Rows[0].OldPrice, preview.Rows[0].Price)
+	}
+}
+
+// TestCommit_AppliesPreview verifies the commit writes inserts and updates and keeps other fields
+func TestCommit_AppliesPreview(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	rows := [][]string{
+		{"Nama", "Satuan", "Harga"},
+		{"Molen", "jam", "55000"},
+		{"Bata merah", "buah", "900"},
+	}
+	mapping := models.MasterImportColumnMapping{Name: 0, Unit: 1, Price: 2}
+	importer := newTestImporter()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+
+	preview, err := importer.Preview(ctx, tx, KIND_MATERIALS, rows, mapping, models.Workspace{UserId: 1})
+	if err != nil {
+		tx.Rollback()
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	if err := importer.Commit(ctx, tx, KIND_MATERIALS, preview, models.Workspace{UserId: 1}); err != nil {
+		tx.Rollback()
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	var price float64
+	var isEquipment bool
+	if err := db.QueryRow("SELECT default_unit_price, is_equipment FROM master_materials WHERE material_id = 5").Scan(&price, &isEquipment); err != nil {
+		t.Fatalf("Failed to query updated material: %v", err)
+	}
+	if price != 55000 || !isEquipment {
+		t.Errorf("Expected price 55000 with equipment flag kept, got %v / %v", price, isEquipment)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM master_materials WHERE material_name = 'Bata merah'").Scan(&count); err != nil {
+		t.Fatalf("Failed to count materials: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected system default plus user copy of Bata merah, got %d rows", count)
+	}
+
+	// only the updated price is a price change, the inserted copy is new
+	events := importer.publisher.(*recordingPublisher).events
+	if len(events) != 1 {
+		t.Fatalf("Expected one price change, got %d", len(events))
+	}
+	if change := events[0].(models.WebhookPriceChange); change.ItemId != 5 || change.OldPrice != 50000 || change.NewPrice != 55000 {
+		t.Errorf("Unexpected price change: %+v", change)
+	}
+}
+
+// TestCommit_NameInTrash verifies a row named like a deleted material brings that material back
+// instead of failing the whole import on the unique name
+func TestCommit_NameInTrash(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec("UPDATE master_materials SET deleted_at = '2026-01-01 00:00:00' WHERE material_id = 2"); err != nil {
+		t.Fatalf("Failed to move the material to the trash: %v", err)
+	}
+
+	rows := [][]string{
+		{"Nama", "Satuan", "Harga"},
+		{"Pasir beton", "m3", "260000"},
+	}
+	mapping := models.MasterImportColumnMapping{Name: 0, Unit: 1, Price: 2}
+	importer := newTestImporter()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	preview, err := importer.Preview(ctx, tx, KIND_MATERIALS, rows, mapping, models.Workspace{UserId: 1})
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if preview.Inserts != 1 {
+		t.Fatalf("Expected the material in the trash to be inserted again, got %+v", preview.Rows)
+	}
+
+	if err := importer.Commit(ctx, tx, KIND_MATERIALS, preview, models.Workspace{UserId: 1}); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	var price float64
+	var deletedAt sql.NullString
+	if err := tx.QueryRow("SELECT default_unit_price, deleted_at FROM master_materials WHERE material_id = 2").Scan(&price, &deletedAt); err != nil {
+		t.Fatalf("Failed to query material: %v", err)
+	}
+	if price != 260000 || deletedAt.Valid {
+		t.Errorf("Expected the material back at 260000, got %v (deleted_at %v)", price, deletedAt)
+	}
+}
+
+// TestCommit_OtherUnit verifies a row named like a material of the user in another unit is an
+// error row, so the rest of the import still commits instead of colliding on the unique name
+func TestCommit_OtherUnit(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	rows := [][]string{
+		{"Nama", "Satuan", "Harga"},
+		{"Semen Portland", "zak", "65000"},
+		{"Pasir beton", "m3", "260000"},
+	}
+	mapping := models.MasterImportColumnMapping{Name: 0, Unit: 1, Price: 2}
+	importer := newTestImporter()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	preview, err := importer.Preview(ctx, tx, KIND_MATERIALS, rows, mapping, models.Workspace{UserId: 1})
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if preview.Errors != 1 || preview.Updates != 1 || preview.Inserts != 0 {
+		t.Fatalf("Expected 1 error and 1 update, got %+v", preview.Rows)
+	}
+	if preview.Rows[0].Action != ACTION_ERROR || preview.Rows[0].Message != "Already exists with unit kg" {
+		t.Errorf("Expected the unit clash reported on row 2, got %+v", preview.Rows[0])
+	}
+
+	if err := importer.Commit(ctx, tx, KIND_MATERIALS, preview, models.Workspace{UserId: 1}); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	var unit string
+	var price float64
+	if err := tx.QueryRow("SELECT unit, default_unit_price FROM master_materials WHERE material_id = 1").Scan(&unit, &price); err != nil {
+		t.Fatalf("Failed to query material: %v", err)
+	}
+	if unit != "kg" || price != 1500 {
+		t.Errorf("Expected Semen Portland untouched, got %v at %v", unit, price)
+	}
+
+	if err := tx.QueryRow("SELECT default_unit_price FROM master_materials WHERE material_id = 2").Scan(&price); err != nil {
+		t.Fatalf("Failed to query material: %v", err)
+	}
+	if price != 260000 {
+		t.Errorf("Expected Pasir beton updated to 260000, got %v", price)
+	}
+}
+
+// TestPreview_LaborTypes verifies labor types are matched against their own table
+func TestPreview_LaborTypes(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	rows := [][]string{
+		{"Role Name", "Unit", "Daily Wage"},
+		{"Pekerja", "OH", "125000"},
+		{"Mandor", "OH", "150000"},
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	preview, err := newTestImporter().Preview(ctx, tx, KIND_LABOR_TYPES, rows, GuessMapping(rows[0]), models.Workspace{UserId: 1})
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	if preview.Updates != 1 || preview.Inserts != 1 || preview.Errors != 0 {
+		t.Errorf("Expected 1 update and 1 insert, got %+v", preview)
+	}
+}
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/audit"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+// ORGANIZATION_HEADER picks the organization an API request works in, the user's own
+// workspace is used without it
+const ORGANIZATION_HEADER = "X-Organization-Id"
+
+// IsAuthAPI is IsAuth for the JSON API, a missing session is answered with a 401 problem
+// instead of a redirect to the login page. A request already authenticated by
+// TokenMiddleware.IsAuthBearer keeps its user. Either way the user and their role are
+// loaded from the database. The workspace is the organization of the X-Organization-Id
+// header, or for a browser session the one picked with the switcher.
+func (m *SessionMiddleware) IsAuthAPI(c *fiber.Ctx) error {
+	userId, orgId := 0, 0
+	if userData, ok := c.Locals(SESSION_USER_NAME).(models.SessionUser); ok && userData.ID != 0 {
+		userId = userData.ID
+	} else {
+		userid, err := CheckSession(c, SESSION_USER_ID)
+		if err != nil || userid == nil {
+			return utils.ResponseProblem(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		session_id, err := CheckSession(c, SESSION_ID)
+		if err != nil || session_id == nil {
+			return utils.ResponseProblem(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		userId = userid.(int)
+		orgId = sessionOrgId(c)
+	}
+
+	if header := c.Get(ORGANIZATION_HEADER); header != "" {
+		var err error
+		if orgId, err = strconv.Atoi(header); err != nil || orgId <= 0 {
+			return utils.ResponseProblem(c, fiber.StatusBadRequest, "Invalid "+ORGANIZATION_HEADER+" header")
+		}
+	}
+
+	userSession, err := m.LoadSessionUser(c.UserContext(), userId, orgId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, errUserDisabled) {
+			return utils.ResponseProblem(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+		log.Printf("Failed to load user %d: %v", userId, err)
+		return utils.ResponseProblem(c, fiber.StatusInternalServerError, "Failed to load user")
+	}
+
+	if c.Get(ORGANIZATION_HEADER) != "" && userSession.OrgId != orgId {
+		return utils.ResponseProblem(c, fiber.StatusForbidden, "You are not a member of this organization")
+	}
+
+	c.Locals(SESSION_USER_NAME, userSession)
+	c.SetUserContext(audit.WithUser(c.UserContext(), userSession.ID, userSession.Username))
+
+	return c.Next()
+}
@@ -1,12 +1,21 @@
 package middlewares
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
+	"github.com/momokii/go-rab-maker/backend/audit"
+	"github.com/momokii/go-rab-maker/backend/databases"
 	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/organizations"
+	"github.com/momokii/go-rab-maker/backend/repository/users"
 	"github.com/momokii/go-rab-maker/backend/utils"
 )
 
@@ -20,14 +29,30 @@ const (
 	SESSION_USER_ID       = "id"
 	SESSION_ID            = "session_id"
 	SESSION_USER_NAME     = "user"
+	// SESSION_ORG_ID is the organization picked with the switcher, unset for the user's own workspace
+	SESSION_ORG_ID = "org_id"
 
 	LOGIN_PAGE_URL = "/login"
 	DASHBOARD_URL  = "/"
+
+	// DEFAULT_ADMIN_USERNAME is the account seeded by the first migration
+	DEFAULT_ADMIN_USERNAME = "admin"
 )
 
-type SessionMiddleware struct{}
+// errUserDisabled is returned when the account of a session was disabled
+var errUserDisabled = errors.New("user is disabled")
+
+// SessionMiddleware authenticates requests and loads the signed in user with their role and
+// organizations. The usernames in ADMIN_USERNAMES (comma separated), or the seeded "admin"
+// account when it is not set, are admins whatever role is stored for them.
+type SessionMiddleware struct {
+	dbService databases.DatabaseServices
+	usersRepo users.Repository
+	orgsRepo  organizations.Repository
+	admins    map[string]bool
+}
 
-func NewSessionMiddleware() *SessionMiddleware {
+func NewSessionMiddleware(dbService databases.DatabaseServices, usersRepo users.Repository, orgsRepo organizations.Repository) *SessionMiddleware {
 	// Check if running in production environment
 	// Default to development mode if ENV is not set
 	isProduction := os.Getenv("ENV") == "production"
@@ -40,7 +65,90 @@ func NewSessionMiddleware() *SessionMiddleware {
 		KeyLookup: "cookie:" + SESSION_APP_COOKIE_ID,
 	})
 
-	return &SessionMiddleware{}
+	admins := map[string]bool{}
+	for _, username := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		if username = strings.TrimSpace(username); username != "" {
+			admins[username] = true
+		}
+	}
+
+	if len(admins) == 0 {
+		admins[DEFAULT_ADMIN_USERNAME] = true
+	}
+
+	return &SessionMiddleware{
+		dbService: dbService,
+		usersRepo: usersRepo,
+		orgsRepo:  orgsRepo,
+		admins:    admins,
+	}
+}
+
+// IsConfiguredAdmin reports whether the username is made admin by ADMIN_USERNAMES, its
+// stored role is not used then
+func (m *SessionMiddleware) IsConfiguredAdmin(username string) bool {
+	return m.admins[username]
+}
+
+// LoadSessionUser reads the user of a session or token from the database. A disabled or
+// removed account is an error, so its sessions and tokens stop working right away.
+// orgId becomes the active organization while the user is still a member of it, otherwise
+// the user works in their own workspace.
+func (m *SessionMiddleware) LoadSessionUser(ctx context.Context, userId, orgId int) (models.SessionUser, error) {
+	var user models.User
+	var memberships []models.OrganizationMembership
+	if _, err := m.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		user, err = m.usersRepo.FindById(ctx, tx, userId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		memberships, err = m.orgsRepo.FindByUserId(ctx, tx, userId)
+		if err != nil {
+			return fiber.StatusInternalServerError, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		return models.SessionUser{}, err
+	}
+
+	if user.IsDisabled() {
+		return models.SessionUser{}, errUserDisabled
+	}
+
+	role := user.Role
+	if m.IsConfiguredAdmin(user.Username) {
+		role = models.ROLE_ADMIN
+	}
+
+	sessionUser := models.SessionUser{
+		ID:       user.UserId,
+		Username: user.Username,
+		Role:     role,
+	}
+
+	for _, membership := range memberships {
+		sessionUser.OrgIds = append(sessionUser.OrgIds, membership.OrgId)
+	}
+
+	if sessionUser.IsMemberOf(orgId) {
+		sessionUser.OrgId = orgId
+	}
+
+	return sessionUser, nil
+}
+
+// sessionOrgId is the organization stored in the session, 0 for none
+func sessionOrgId(c *fiber.Ctx) int {
+	orgId, err := CheckSession(c, SESSION_ORG_ID)
+	if err != nil {
+		return 0
+	}
+
+	id, _ := orgId.(int)
+	return id
 }
 
 func CreateSession(c *fiber.Ctx, key string, value interface{}) error {
@@ -127,7 +235,8 @@ func (m *SessionMiddleware) GetUserSessionData(c *fiber.Ctx) (models.SessionUser
 	return sessionData, nil
 }
 
-// The function `IsAuth` in Go validates a JWT token from the Authorization header in a Fiber context.
+// IsAuth checks the session and sets the signed in user, with their role, in the locals.
+// Sessions of disabled users are ended.
 func (m *SessionMiddleware) IsAuth(c *fiber.Ctx) error {
 
 	userid, err := CheckSession(c, SESSION_USER_ID)
@@ -144,42 +253,20 @@ func (m *SessionMiddleware) IsAuth(c *fiber.Ctx) error {
 		return handleRedirectAuthMiddleware(c, LOGIN_PAGE_URL, true)
 	}
 
-	var userSession models.SessionUser
-
-	// TODO: Fetch real user data from database instead of using dummy data
-	// The commented code below shows the original intent, but it requires:
-	// 1. Adding dbService and userRepo to SessionMiddleware struct
-	// 2. Properly handling the users repository
-	// For now, we use the userid from session which is sufficient for authorization
-	//
-	// err, _ = m.dbService.Transaction(c.Context(), func(tx *sql.Tx) (error, int) {
-	//     usersRepo := users.NewUsersRepo()
-	//     userData, err := usersRepo.FindByID(tx, userid.(int))
-	//     if err != nil {
-	//         return err, fiber.StatusInternalServerError
-	//     }
-	//     userSession = models.SessionUser{
-	//         ID:    userData.Id,
-	//         Email: userData.Email,
-	//         Role:  userData.Role,
-	//     }
-	//     return nil, fiber.StatusOK
-	// })
-
+	userSession, err := m.LoadSessionUser(c.UserContext(), userid.(int), sessionOrgId(c))
 	if err != nil {
-		return handleRedirectAuthMiddleware(c, LOGIN_PAGE_URL, true)
-	}
-
-	// Temporary: Use session userid only
-	// In production, fetch full user data from database
-	userSession = models.SessionUser{
-		ID: userid.(int),
-		// Email and Role should be fetched from database
-		// Leaving these empty for now as they are not currently used
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, errUserDisabled) {
+			return handleRedirectAuthMiddleware(c, LOGIN_PAGE_URL, true)
+		}
+		log.Printf("Failed to load user %d: %v", userid.(int), err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load user")
 	}
 
-	// set user data session to local session data for parsing it to main handlers
+	// set user data session to local session data for parsing it to main handlers, a user
+	// removed from the organization of the session is back in their own workspace
 	c.Locals(SESSION_USER_NAME, userSession)
+	// changes made by the request are recorded in the audit log as made by the user
+	c.SetUserContext(audit.WithUser(c.UserContext(), userSession.ID, userSession.Username))
 
 	return c.Next()
 }
@@ -36,8 +36,12 @@ func SetupAppMiddlewares(app *fiber.App) {
 			return c.IP()
 		},
 		LimitReached: func(c *fiber.Ctx) error {
+			if utils.IsAPIRequest(c) {
+				return utils.ResponseProblem(c, fiber.StatusTooManyRequests, "You have exceeded the maximum number of requests. Please try again later.")
+			}
 			return utils.ResponseErrorModal(c, "Too Many Requests", "You have exceeded the maximum number of requests. Please try again later.")
 		},
 	}))
 	app.Use(recover.New())
+	app.Use(RequestTimeout(LoadRequestTimeout()))
 }
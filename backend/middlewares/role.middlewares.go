@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+// RoleMiddleware enforces the permission matrix of models.ROLE_PERMISSIONS on route groups.
+// It must run after IsAuth or IsAuthAPI, which set the user's role.
+type RoleMiddleware struct{}
+
+func NewRoleMiddleware() *RoleMiddleware {
+	return &RoleMiddleware{}
+}
+
+// Require lets a request through when the user has the permission
+func (m *RoleMiddleware) Require(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !sessionUser(c).Can(permission) {
+			return accessDenied(c, "Your role does not allow this")
+		}
+
+		return c.Next()
+	}
+}
+
+// Writes lets everyone who can read use GET and HEAD, other methods change data and need
+// the permission. It guards groups where pages are for everyone but changes are not.
+func (m *RoleMiddleware) Writes(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userData := sessionUser(c)
+
+		if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+			if !userData.Can(models.PERMISSION_READ) {
+				return accessDenied(c, "Your role does not allow this")
+			}
+			return c.Next()
+		}
+
+		if !userData.Can(permission) {
+			return accessDenied(c, "Your role can only view this, ask an admin for another role")
+		}
+
+		return c.Next()
+	}
+}
+
+// IsAdmin lets administrators through, it must run after IsAuth
+func (m *RoleMiddleware) IsAdmin(c *fiber.Ctx) error {
+	if !sessionUser(c).Can(models.PERMISSION_ADMINISTER) {
+		return accessDenied(c, "Only administrators can access this page")
+	}
+
+	return c.Next()
+}
+
+func sessionUser(c *fiber.Ctx) models.SessionUser {
+	userData, _ := c.Locals(SESSION_USER_NAME).(models.SessionUser)
+	return userData
+}
+
+// accessDenied answers in the form of the request: a problem for the API, an error modal
+// for HTMX and a plain 403 page otherwise
+func accessDenied(c *fiber.Ctx, message string) error {
+	if utils.IsAPIRequest(c) {
+		return utils.ResponseProblem(c, fiber.StatusForbidden, message)
+	}
+
+	if c.Get("HX-Request") == "true" {
+		return utils.ResponseErrorModal(c, "Access Denied", message)
+	}
+
+	return c.Status(fiber.StatusForbidden).SendString(message)
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DEFAULT_REQUEST_TIMEOUT bounds the database work of a request when REQUEST_TIMEOUT is not set
+const DEFAULT_REQUEST_TIMEOUT = 30 * time.Second
+
+// RequestTimeout sets a user context that is cancelled after timeout. Handlers pass
+// c.UserContext() to transactions and repositories, so a slow query is stopped instead
+// of holding a connection after the response has been given up on.
+func RequestTimeout(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+
+		return c.Next()
+	}
+}
+
+// LoadRequestTimeout reads REQUEST_TIMEOUT as a Go duration such as 30s or 2m
+func LoadRequestTimeout() time.Duration {
+	value := os.Getenv("REQUEST_TIMEOUT")
+	if value == "" {
+		return DEFAULT_REQUEST_TIMEOUT
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		log.Printf("Invalid REQUEST_TIMEOUT %q, using %s", value, DEFAULT_REQUEST_TIMEOUT)
+		return DEFAULT_REQUEST_TIMEOUT
+	}
+
+	return timeout
+}
@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/api_tokens"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+// API_TOKEN_LAST_USED_INTERVAL limits how often a token's last use is written, so a busy
+// script does not turn every read into a write
+const API_TOKEN_LAST_USED_INTERVAL = time.Minute
+
+// TokenMiddleware authenticates API requests with personal access tokens
+type TokenMiddleware struct {
+	dbService  databases.DatabaseServices
+	tokensRepo api_tokens.Repository
+}
+
+func NewTokenMiddleware(dbService databases.DatabaseServices, tokensRepo api_tokens.Repository) *TokenMiddleware {
+	return &TokenMiddleware{
+		dbService:  dbService,
+		tokensRepo: tokensRepo,
+	}
+}
+
+// IsAuthBearer authenticates a request that sends "Authorization: Bearer <token>" and sets the
+// same SessionUser locals as IsAuth. Requests without the header are passed on for the session
+// check; read-only tokens may only use GET and HEAD.
+func (m *TokenMiddleware) IsAuthBearer(c *fiber.Ctx) error {
+	header := c.Get(fiber.HeaderAuthorization)
+	if header == "" {
+		return c.Next()
+	}
+
+	scheme, plainToken, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || !strings.HasPrefix(plainToken, models.API_TOKEN_PREFIX) {
+		return utils.ResponseProblem(c, fiber.StatusUnauthorized, "Invalid authorization header, expected a bearer token")
+	}
+
+	ctx := c.UserContext()
+	now := time.Now()
+
+	var token models.APIToken
+	if _, err := m.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		token, err = m.tokensRepo.FindByHash(ctx, tx, models.HashAPIToken(strings.TrimSpace(plainToken)))
+		if err != nil {
+			return fiber.StatusUnauthorized, err
+		}
+
+		return fiber.StatusOK, nil
+	}); err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("API token lookup failed: %v", err)
+		}
+		return utils.ResponseProblem(c, fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	if !token.IsActive(now) {
+		return utils.ResponseProblem(c, fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	if !token.CanWrite() && c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+		return utils.ResponseProblem(c, fiber.StatusForbidden, "This token is read-only")
+	}
+
+	if lastUsed, err := time.Parse(models.API_TOKEN_TIME_FORMAT, token.LastUsedAt); err != nil || now.UTC().Sub(lastUsed) >= API_TOKEN_LAST_USED_INTERVAL {
+		if _, err := m.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+			if err := m.tokensRepo.UpdateLastUsed(ctx, tx, token.TokenId); err != nil {
+				return fiber.StatusInternalServerError, err
+			}
+
+			return fiber.StatusOK, nil
+		}); err != nil {
+			// the request is authenticated either way
+			log.Printf("Failed to record use of API token %d: %v", token.TokenId, err)
+		}
+	}
+
+	c.Locals(SESSION_USER_NAME, models.SessionUser{
+		ID: token.UserId,
+	})
+
+	return c.Next()
+}
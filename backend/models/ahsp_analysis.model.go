@@ -0,0 +1,34 @@
+package models
+
+// AhspAnalysisLine is one component row of a unit price analysis
+type AhspAnalysisLine struct {
+	Name        string  `json:"name"`
+	Unit        string  `json:"unit"`
+	Coefficient float64 `json:"coefficient"`
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+}
+
+// AhspAnalysis is an AHSP template priced in the standard layout:
+// A materials, B labor, C equipment, D = A + B + C, E overhead & profit, F unit price = D + E
+type AhspAnalysis struct {
+	Template        AHSPTemplate       `json:"template"`
+	Materials       []AhspAnalysisLine `json:"materials"`
+	Labor           []AhspAnalysisLine `json:"labor"`
+	Equipment       []AhspAnalysisLine `json:"equipment"`
+	MaterialTotal   float64            `json:"material_total"`
+	LaborTotal      float64            `json:"labor_total"`
+	EquipmentTotal  float64            `json:"equipment_total"`
+	Subtotal        float64            `json:"subtotal"`
+	OverheadPercent float64            `json:"overhead_percent"`
+	Overhead        float64            `json:"overhead"`
+	UnitPrice       float64            `json:"unit_price"`
+}
+
+// AhspAnalysisOptions selects the prices and overhead used to build analyses
+type AhspAnalysisOptions struct {
+	PriceSource     string  `validate:"required,oneof=current project"`
+	ProjectId       int     // project whose captured prices are used, with PriceSource project
+	PriceLabel      string  // printed on the analysis, e.g. "Current master prices"
+	OverheadPercent float64 `validate:"gte=0,lte=100"`
+}
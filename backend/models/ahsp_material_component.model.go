@@ -30,4 +30,5 @@ type AHSPMaterialComponentWithMaterial struct {
 	MaterialName  string  `json:"material_name"`
 	MaterialUnit  string  `json:"material_unit"`
 	MaterialPrice float64 `json:"material_price"`
+	IsEquipment   bool    `json:"is_equipment"` // equipment rental (e.g. concrete mixer per hour)
 }
@@ -3,6 +3,8 @@ package models
 type AHSPTemplate struct {
 	TemplateId   int    `json:"template_id"`
 	UserId       int    `json:"user_id"`
+	OrgId        int    `json:"org_id"`
+	Code         string `json:"code"` // official analysis code, empty for user-made templates
 	TemplateName string `json:"template_name"`
 	Unit         string `json:"unit"`
 	CreatedAt    string `json:"created_at"`
@@ -12,5 +14,13 @@ type AHSPTemplate struct {
 type AHSPTemplateCreate struct {
 	TemplateName string `json:"template_name" validate:"required,min=1,max=100"`
 	UserId       int    `json:"user_id"`
+	OrgId        int    `json:"org_id"`
+	Code         string `json:"code" validate:"max=50"`
 	Unit         string `json:"unit" validate:"required,min=1,max=20"`
 }
+
+type AHSPTemplateWithComponents struct {
+	AHSPTemplate
+	MaterialComponents []AHSPMaterialComponentWithMaterial `json:"material_components"`
+	LaborComponents    []AHSPLaborComponentWithLabor       `json:"labor_components"`
+}
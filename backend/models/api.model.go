@@ -0,0 +1,12 @@
+package models
+
+// APIResponse is the body of a JSON API response for a single resource
+type APIResponse struct {
+	Data interface{} `json:"data"`
+}
+
+// APIListResponse is the body of a paginated JSON API list
+type APIListResponse struct {
+	Data       interface{}    `json:"data"`
+	Pagination PaginationInfo `json:"pagination"`
+}
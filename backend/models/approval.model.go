@@ -0,0 +1,245 @@
+package models
+
+const (
+	APPROVAL_STATUS_DRAFT     = "draft"
+	APPROVAL_STATUS_SUBMITTED = "submitted"
+	APPROVAL_STATUS_IN_REVIEW = "in_review"
+	APPROVAL_STATUS_APPROVED  = "approved"
+	APPROVAL_STATUS_REJECTED  = "rejected"
+
+	APPROVAL_ACTION_SUBMIT       = "submit"
+	APPROVAL_ACTION_WITHDRAW     = "withdraw"
+	APPROVAL_ACTION_START_REVIEW = "start_review"
+	APPROVAL_ACTION_APPROVE      = "approve"
+	APPROVAL_ACTION_REJECT       = "reject"
+	// APPROVAL_ACTION_NEW_REVISION reopens an approved project as a draft of the next revision
+	APPROVAL_ACTION_NEW_REVISION = "new_revision"
+	// APPROVAL_ACTION_COMMENT adds a comment to the history without changing the status
+	APPROVAL_ACTION_COMMENT = "comment"
+
+	// APPROVAL_COMMENT_MAX_LENGTH is the longest comment a step can carry
+	APPROVAL_COMMENT_MAX_LENGTH = 2000
+)
+
+// APPROVAL_STATUS_LABELS are the statuses as the pages show them
+var APPROVAL_STATUS_LABELS = map[string]string{
+	APPROVAL_STATUS_DRAFT:     "Draft",
+	APPROVAL_STATUS_SUBMITTED: "Submitted",
+	APPROVAL_STATUS_IN_REVIEW: "In review",
+	APPROVAL_STATUS_APPROVED:  "Approved",
+	APPROVAL_STATUS_REJECTED:  "Rejected",
+}
+
+// ApprovalTransition is a step of the workflow: the statuses it starts from, the status it
+// leads to ("" keeps the status) and who may take it. The user needs one of Permissions for
+// their account role and at least ProjectRole in the project.
+type ApprovalTransition struct {
+	Label           string
+	From            []string
+	To              string
+	Permissions     []string
+	ProjectRole     string
+	CommentRequired bool
+}
+
+var allApprovalStatuses = []string{
+	APPROVAL_STATUS_DRAFT,
+	APPROVAL_STATUS_SUBMITTED,
+	APPROVAL_STATUS_IN_REVIEW,
+	APPROVAL_STATUS_APPROVED,
+	APPROVAL_STATUS_REJECTED,
+}
+
+// APPROVAL_TRANSITIONS is the workflow: Draft → Submitted → In review → Approved or Rejected.
+// The editors of a project submit it, reviewers (PERMISSION_APPROVE) decide.
+var APPROVAL_TRANSITIONS = map[string]ApprovalTransition{
+	APPROVAL_ACTION_SUBMIT: {
+		Label:       "Submit for approval",
+		From:        []string{APPROVAL_STATUS_DRAFT, APPROVAL_STATUS_REJECTED},
+		To:          APPROVAL_STATUS_SUBMITTED,
+		Permissions: []string{PERMISSION_EDIT},
+		ProjectRole: PROJECT_ROLE_EDITOR,
+	},
+	APPROVAL_ACTION_WITHDRAW: {
+		Label:       "Withdraw",
+		From:        []string{APPROVAL_STATUS_SUBMITTED},
+		To:          APPROVAL_STATUS_DRAFT,
+		Permissions: []string{PERMISSION_EDIT},
+		ProjectRole: PROJECT_ROLE_EDITOR,
+	},
+	APPROVAL_ACTION_START_REVIEW: {
+		Label:       "Start review",
+		From:        []string{APPROVAL_STATUS_SUBMITTED},
+		To:          APPROVAL_STATUS_IN_REVIEW,
+		Permissions: []string{PERMISSION_APPROVE},
+		ProjectRole: PROJECT_ROLE_VIEWER,
+	},
+	APPROVAL_ACTION_APPROVE: {
+		Label:       "Approve",
+		From:        []string{APPROVAL_STATUS_IN_REVIEW},
+		To:          APPROVAL_STATUS_APPROVED,
+		Permissions: []string{PERMISSION_APPROVE},
+		ProjectRole: PROJECT_ROLE_VIEWER,
+	},
+	APPROVAL_ACTION_REJECT: {
+		Label:           "Reject",
+		From:            []string{APPROVAL_STATUS_IN_REVIEW},
+		To:              APPROVAL_STATUS_REJECTED,
+		Permissions:     []string{PERMISSION_APPROVE},
+		ProjectRole:     PROJECT_ROLE_VIEWER,
+		CommentRequired: true,
+	},
+	APPROVAL_ACTION_NEW_REVISION: {
+		Label:       "Start new revision",
+		From:        []string{APPROVAL_STATUS_APPROVED},
+		To:          APPROVAL_STATUS_DRAFT,
+		Permissions: []string{PERMISSION_EDIT},
+		ProjectRole: PROJECT_ROLE_EDITOR,
+	},
+	APPROVAL_ACTION_COMMENT: {
+		Label:           "Comment",
+		From:            allApprovalStatuses,
+		Permissions:     []string{PERMISSION_EDIT, PERMISSION_APPROVE},
+		ProjectRole:     PROJECT_ROLE_VIEWER,
+		CommentRequired: true,
+	},
+}
+
+// APPROVAL_ACTIONS lists the actions in the order the approval panel shows them
+var APPROVAL_ACTIONS = []string{
+	APPROVAL_ACTION_SUBMIT,
+	APPROVAL_ACTION_WITHDRAW,
+	APPROVAL_ACTION_START_REVIEW,
+	APPROVAL_ACTION_APPROVE,
+	APPROVAL_ACTION_REJECT,
+	APPROVAL_ACTION_NEW_REVISION,
+}
+
+// ProjectApproval is where a project is in the workflow
+type ProjectApproval struct {
+	ProjectId int    `json:"project_id"`
+	Status    string `json:"status"`
+	Revision  int    `json:"revision"`
+	UpdatedBy int    `json:"updated_by"` // 0 for projects that never left the first draft
+	UpdatedAt string `json:"updated_at"`
+}
+
+// NewProjectApproval is the state of a project that was never submitted
+func NewProjectApproval(projectId int) ProjectApproval {
+	return ProjectApproval{ProjectId: projectId, Status: APPROVAL_STATUS_DRAFT, Revision: 1}
+}
+
+// StatusLabel is the status as the pages show it
+func (a ProjectApproval) StatusLabel() string {
+	return APPROVAL_STATUS_LABELS[a.Status]
+}
+
+// LockReason explains why the work items of the project cannot change, "" when they can.
+// A submitted project stays as the reviewers saw it; an approved one until a new revision.
+func (a ProjectApproval) LockReason() string {
+	switch a.Status {
+	case APPROVAL_STATUS_SUBMITTED:
+		return "The project is submitted for approval, withdraw it to make changes"
+	case APPROVAL_STATUS_IN_REVIEW:
+		return "The project is in review, it can be changed once it is rejected"
+	case APPROVAL_STATUS_APPROVED:
+		return "The project is approved, start a new revision to make changes"
+	}
+
+	return ""
+}
+
+// IsLocked reports whether the work items of the project cannot change
+func (a ProjectApproval) IsLocked() bool {
+	return a.LockReason() != ""
+}
+
+// Apply returns the state after the action, ok is false when the action cannot be taken from
+// the current status
+func (a ProjectApproval) Apply(action string) (ProjectApproval, bool) {
+	transition, ok := APPROVAL_TRANSITIONS[action]
+	if !ok || !containsString(transition.From, a.Status) {
+		return a, false
+	}
+
+	next := a
+	if transition.To != "" {
+		next.Status = transition.To
+	}
+	if action == APPROVAL_ACTION_NEW_REVISION {
+		next.Revision++
+	}
+
+	return next, true
+}
+
+// CanTakeApprovalAction reports whether a user with the project role may take the action,
+// whatever the current status
+func CanTakeApprovalAction(userData SessionUser, projectRole, action string) bool {
+	transition, ok := APPROVAL_TRANSITIONS[action]
+	if !ok || !ProjectRoleAllows(projectRole, transition.ProjectRole) {
+		return false
+	}
+
+	for _, permission := range transition.Permissions {
+		if userData.Can(permission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ApprovalEvent is a step of the workflow or a comment, in the history of a project
+type ApprovalEvent struct {
+	EventId    int     `json:"event_id"`
+	ProjectId  int     `json:"project_id"`
+	Revision   int     `json:"revision"`
+	Action     string  `json:"action"`
+	FromStatus string  `json:"from_status"`
+	ToStatus   string  `json:"to_status"`
+	Comment    string  `json:"comment"`
+	TotalCost  float64 `json:"total_cost"` // the project total when the step was taken
+	UserId     int     `json:"user_id"`    // 0 once the user is deleted
+	Username   string  `json:"username"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+type ApprovalEventCreate struct {
+	ProjectId  int
+	Revision   int
+	Action     string
+	FromStatus string
+	ToStatus   string
+	Comment    string
+	TotalCost  float64
+	UserId     int
+}
+
+// ApprovalPanel is the approval of a project as a user sees it: Actions are the steps they can
+// take from the current status
+type ApprovalPanel struct {
+	Project    Project
+	Role       string
+	Approval   ProjectApproval
+	Events     []ApprovalEvent
+	Actions    []string
+	CanComment bool
+}
+
+// PendingApproval is a project waiting for a reviewer
+type PendingApproval struct {
+	Project
+	Approval      ProjectApproval `json:"approval"`
+	OwnerUsername string          `json:"owner_username"`
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
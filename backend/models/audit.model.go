@@ -0,0 +1,200 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+const (
+	AUDIT_ACTION_CREATE = "create"
+	AUDIT_ACTION_UPDATE = "update"
+	AUDIT_ACTION_DELETE = "delete"
+
+	AUDIT_ENTITY_PROJECT                 = "project"
+	AUDIT_ENTITY_WORK_ITEM               = "work_item"
+	AUDIT_ENTITY_ITEM_COST               = "item_cost"
+	AUDIT_ENTITY_MATERIAL                = "material"
+	AUDIT_ENTITY_LABOR_TYPE              = "labor_type"
+	AUDIT_ENTITY_WORK_CATEGORY           = "work_category"
+	AUDIT_ENTITY_AHSP_TEMPLATE           = "ahsp_template"
+	AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT = "ahsp_material_component"
+	AUDIT_ENTITY_AHSP_LABOR_COMPONENT    = "ahsp_labor_component"
+
+	// AUDIT_EXPORT_LIMIT caps the entries of one export, narrow the filters for older ones
+	AUDIT_EXPORT_LIMIT = 10000
+)
+
+// AUDIT_ACTIONS lists the actions in the order the filters show them
+var AUDIT_ACTIONS = []string{AUDIT_ACTION_CREATE, AUDIT_ACTION_UPDATE, AUDIT_ACTION_DELETE}
+
+// AUDIT_ENTITIES lists the audited entities in the order the filters show them
+var AUDIT_ENTITIES = []string{
+	AUDIT_ENTITY_PROJECT,
+	AUDIT_ENTITY_WORK_ITEM,
+	AUDIT_ENTITY_ITEM_COST,
+	AUDIT_ENTITY_MATERIAL,
+	AUDIT_ENTITY_LABOR_TYPE,
+	AUDIT_ENTITY_WORK_CATEGORY,
+	AUDIT_ENTITY_AHSP_TEMPLATE,
+	AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT,
+	AUDIT_ENTITY_AHSP_LABOR_COMPONENT,
+}
+
+// AUDIT_ENTITY_LABELS are the entities as the pages show them
+var AUDIT_ENTITY_LABELS = map[string]string{
+	AUDIT_ENTITY_PROJECT:                 "Project",
+	AUDIT_ENTITY_WORK_ITEM:               "Work item",
+	AUDIT_ENTITY_ITEM_COST:               "Item cost",
+	AUDIT_ENTITY_MATERIAL:                "Material",
+	AUDIT_ENTITY_LABOR_TYPE:              "Labor type",
+	AUDIT_ENTITY_WORK_CATEGORY:           "Work category",
+	AUDIT_ENTITY_AHSP_TEMPLATE:           "AHSP template",
+	AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT: "AHSP material component",
+	AUDIT_ENTITY_AHSP_LABOR_COMPONENT:    "AHSP labor component",
+}
+
+// AuditEntry is a create, update or delete of a row, with the row as stored before and
+// after it as JSON. Before is empty for a create, After for a delete.
+type AuditEntry struct {
+	AuditId    int    `json:"audit_id"`
+	UserId     int    `json:"user_id"`  // 0 for changes made by the application or once the user is deleted
+	Username   string `json:"username"` // empty for changes made by the application
+	Entity     string `json:"entity"`
+	EntityId   int    `json:"entity_id"`
+	ProjectId  int    `json:"project_id"` // 0 for master data
+	Action     string `json:"action"`
+	BeforeJSON string `json:"before_json"`
+	AfterJSON  string `json:"after_json"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type AuditEntryCreate struct {
+	UserId     int
+	Username   string
+	Entity     string
+	EntityId   int
+	ProjectId  int
+	Action     string
+	BeforeJSON string
+	AfterJSON  string
+}
+
+// EntityLabel is the entity as the pages show it
+func (e AuditEntry) EntityLabel() string {
+	if label, ok := AUDIT_ENTITY_LABELS[e.Entity]; ok {
+		return label
+	}
+
+	return e.Entity
+}
+
+// auditNameColumns are the columns that name a row, in the order they are looked for
+var auditNameColumns = []string{"project_name", "description", "item_name", "material_name", "role_name", "category_name", "template_name"}
+
+// AuditChange is a column an update changed, with its values before and after
+type AuditChange struct {
+	Column string
+	Before string
+	After  string
+}
+
+// Name names the changed row, "" for rows without a name such as AHSP components
+func (e AuditEntry) Name() string {
+	row := e.after()
+	if row == nil {
+		row = e.before()
+	}
+
+	for _, column := range auditNameColumns {
+		if name, ok := row[column].(string); ok && name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Changes lists the columns an update changed, by column name. For a create or delete it
+// lists every column of the row.
+func (e AuditEntry) Changes() []AuditChange {
+	before, after := e.before(), e.after()
+
+	columns := map[string]bool{}
+	for column := range before {
+		columns[column] = true
+	}
+	for column := range after {
+		columns[column] = true
+	}
+
+	names := make([]string, 0, len(columns))
+	for column := range columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+
+	var changes []AuditChange
+	for _, column := range names {
+		change := AuditChange{Column: column}
+		if before != nil {
+			change.Before = formatAuditValue(before[column])
+		}
+		if after != nil {
+			change.After = formatAuditValue(after[column])
+		}
+
+		if e.Action == AUDIT_ACTION_UPDATE && change.Before == change.After {
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+func (e AuditEntry) before() map[string]interface{} {
+	return parseAuditRow(e.BeforeJSON)
+}
+
+func (e AuditEntry) after() map[string]interface{} {
+	return parseAuditRow(e.AfterJSON)
+}
+
+func parseAuditRow(data string) map[string]interface{} {
+	if data == "" {
+		return nil
+	}
+
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &row); err != nil {
+		return nil
+	}
+
+	return row
+}
+
+func formatAuditValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// AuditFilter narrows the audit log, zero values match everything. The dates are
+// YYYY-MM-DD and both days are included.
+type AuditFilter struct {
+	Entity    string `json:"entity"`
+	Action    string `json:"action"`
+	Username  string `json:"username"`
+	ProjectId int    `json:"project_id"`
+	DateFrom  string `json:"date_from"`
+	DateTo    string `json:"date_to"`
+}
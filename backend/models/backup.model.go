@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// BackupFile is a database snapshot found in the backup directory
+type BackupFile struct {
+	Name       string
+	Kind       string // file name prefix: backup, auto, pre-restore or uploaded
+	Path       string
+	Size       int64
+	ModTime    time.Time
+	Compressed bool
+}
+
+// BackupInfo describes a backup that passed the integrity and schema checks
+type BackupInfo struct {
+	SchemaVersion int // highest migration recorded in the backup's schema_migrations
+	Migrations    int // number of migrations recorded
+}
+
+// BackupScheduleStatus is the configuration and last outcome of the automatic backups
+type BackupScheduleStatus struct {
+	Enabled        bool
+	Interval       time.Duration
+	RetentionCount int           // 0 keeps any number of scheduled backups
+	RetentionAge   time.Duration // 0 keeps scheduled backups regardless of age
+	Gzip           bool
+	LastRunAt      time.Time
+	LastBackup     string
+	LastError      string
+	NextRunAt      time.Time
+}
@@ -2,76 +2,94 @@ package models
 
 // DashboardStats represents overall statistics for the main dashboard
 type DashboardStats struct {
-	TotalProjects     int     `db:"total_projects"`
-	TotalWorkItems    int     `db:"total_work_items"`
-	TotalCost         float64 `db:"total_cost"`
-	ActiveUsersCount  int     `db:"active_users_count"`
+	TotalProjects    int     `db:"total_projects" json:"total_projects"`
+	TotalWorkItems   int     `db:"total_work_items" json:"total_work_items"`
+	TotalCost        float64 `db:"total_cost" json:"total_cost"`
+	ActiveUsersCount int     `db:"active_users_count" json:"active_users_count"`
 }
 
 // TypeCostBreakdown represents cost breakdown by type (Material vs Labor)
 type TypeCostBreakdown struct {
-	ItemType  string  `db:"item_type"` // "MATERIAL" or "LABOR"
-	TotalCost float64 `db:"total_cost"`
+	ItemType  string  `db:"item_type" json:"item_type"` // "MATERIAL" or "LABOR"
+	TotalCost float64 `db:"total_cost" json:"total_cost"`
 }
 
 // CategoryBreakdown represents statistics grouped by work category
 type CategoryBreakdown struct {
-	CategoryID   int     `db:"category_id"`
-	CategoryName string  `db:"category_name"`
-	ItemCount    int     `db:"item_count"`
-	TotalVolume  float64 `db:"total_volume"`
-	TotalCost    float64 `db:"total_cost"`
+	CategoryID   int     `db:"category_id" json:"category_id"`
+	CategoryName string  `db:"category_name" json:"category_name"`
+	ItemCount    int     `db:"item_count" json:"item_count"`
+	TotalVolume  float64 `db:"total_volume" json:"total_volume"`
+	TotalCost    float64 `db:"total_cost" json:"total_cost"`
 }
 
 // TopExpensiveItem represents the most expensive items across all projects
 type TopExpensiveItem struct {
-	ProjectName string  `db:"project_name"`
-	ItemName    string  `db:"item_name"`
-	ItemType    string  `db:"item_type"`
-	TotalCost   float64 `db:"total_cost"`
-	TotalQty    float64 `db:"total_quantity"`
-	Unit        string  `db:"unit"`
+	ProjectName string  `db:"project_name" json:"project_name"`
+	ItemName    string  `db:"item_name" json:"item_name"`
+	ItemType    string  `db:"item_type" json:"item_type"`
+	TotalCost   float64 `db:"total_cost" json:"total_cost"`
+	TotalQty    float64 `db:"total_quantity" json:"total_quantity"`
+	Unit        string  `db:"unit" json:"unit"`
 }
 
 // ProjectBreakdown represents cost breakdown by project
 type ProjectBreakdown struct {
-	ProjectID      int     `db:"project_id"`
-	ProjectName    string  `db:"project_name"`
-	WorkItemCount  int     `db:"work_item_count"`
-	MaterialCost   float64 `db:"material_cost"`
-	LaborCost      float64 `db:"labor_cost"`
-	TotalCost      float64 `db:"total_cost"`
+	ProjectID     int     `db:"project_id" json:"project_id"`
+	ProjectName   string  `db:"project_name" json:"project_name"`
+	WorkItemCount int     `db:"work_item_count" json:"work_item_count"`
+	MaterialCost  float64 `db:"material_cost" json:"material_cost"`
+	LaborCost     float64 `db:"labor_cost" json:"labor_cost"`
+	TotalCost     float64 `db:"total_cost" json:"total_cost"`
 }
 
 // EnhancedProjectData represents project data with additional statistics
 type EnhancedProjectData struct {
-	ProjectID        int     `db:"project_id"`
-	ProjectName      string  `db:"project_name"`
-	Location         string  `db:"location"`
-	ClientName       string  `db:"client_name"`
-	WorkItemCount    int     `db:"work_item_count"`
-	TotalCost        float64 `db:"total_cost"`
-	CreatedAt        string  `db:"created_at"`
-	UpdatedAt        string  `db:"updated_at"`
+	ProjectID     int     `db:"project_id" json:"project_id"`
+	ProjectName   string  `db:"project_name" json:"project_name"`
+	Location      string  `db:"location" json:"location"`
+	ClientName    string  `db:"client_name" json:"client_name"`
+	WorkItemCount int     `db:"work_item_count" json:"work_item_count"`
+	TotalCost     float64 `db:"total_cost" json:"total_cost"`
+	CreatedAt     string  `db:"created_at" json:"created_at"`
+	UpdatedAt     string  `db:"updated_at" json:"updated_at"`
 }
 
 // MaterialSummaryStats represents statistics for the Material Summary page
 type MaterialSummaryStats struct {
-	TotalItems      int     `db:"total_items"`
-	MaterialCost    float64 `db:"material_cost"`
-	LaborCost       float64 `db:"labor_cost"`
-	TotalCost       float64 `db:"total_cost"`
-	UniqueProjects  int     `db:"unique_projects"`
+	TotalItems     int     `db:"total_items" json:"total_items"`
+	MaterialCost   float64 `db:"material_cost" json:"material_cost"`
+	LaborCost      float64 `db:"labor_cost" json:"labor_cost"`
+	TotalCost      float64 `db:"total_cost" json:"total_cost"`
+	UniqueProjects int     `db:"unique_projects" json:"unique_projects"`
 }
 
 // ProjectMaterialBreakdown represents material/labor breakdown for a specific project
 type ProjectMaterialBreakdown struct {
-	ProjectID       int              `db:"project_id"`
-	ProjectName     string           `db:"project_name"`
-	MaterialItems   []MaterialSummary `db:"-"` // Items for this project
-	LaborItems      []MaterialSummary `db:"-"` // Items for this project
-	MaterialCost    float64          `db:"material_cost"`
-	LaborCost       float64          `db:"labor_cost"`
-	TotalCost       float64          `db:"total_cost"`
-	ItemCount       int              `db:"item_count"`
+	ProjectID     int               `db:"project_id" json:"project_id"`
+	ProjectName   string            `db:"project_name" json:"project_name"`
+	MaterialItems []MaterialSummary `db:"-" json:"-"` // Items for this project
+	LaborItems    []MaterialSummary `db:"-" json:"-"` // Items for this project
+	MaterialCost  float64           `db:"material_cost" json:"material_cost"`
+	LaborCost     float64           `db:"labor_cost" json:"labor_cost"`
+	TotalCost     float64           `db:"total_cost" json:"total_cost"`
+	ItemCount     int               `db:"item_count" json:"item_count"`
+}
+
+// DashboardOverview is the data of the dashboard page as served by the JSON API
+type DashboardOverview struct {
+	TotalProjects     int                   `json:"total_projects"`
+	TotalWorkItems    int                   `json:"total_work_items"`
+	TotalCost         float64               `json:"total_cost"`
+	RecentProjects    []EnhancedProjectData `json:"recent_projects"`
+	TypeCostBreakdown []TypeCostBreakdown   `json:"type_cost_breakdown"`
+	CategoryBreakdown []CategoryBreakdown   `json:"category_breakdown"`
+	TopExpensiveItems []TopExpensiveItem    `json:"top_expensive_items"`
+}
+
+// MaterialSummaryOverview is the data of the material summary page as served by the JSON API
+type MaterialSummaryOverview struct {
+	Stats            MaterialSummaryStats `json:"stats"`
+	Items            []MaterialSummary    `json:"items"`
+	ProjectBreakdown []ProjectBreakdown   `json:"project_breakdown"`
 }
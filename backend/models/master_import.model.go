@@ -0,0 +1,36 @@
+package models
+
+// MasterImportColumnMapping maps spreadsheet column indexes to master data fields, -1 means not mapped
+type MasterImportColumnMapping struct {
+	Name  int `json:"name"`
+	Unit  int `json:"unit"`
+	Price int `json:"price"`
+}
+
+type MasterImportPreviewRow struct {
+	Line       int     `json:"line"` // 1-based row number in the uploaded sheet
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	Price      float64 `json:"price"`
+	Action     string  `json:"action"` // insert, update, unchanged or error
+	ExistingId int     `json:"existing_id"`
+	OldPrice   float64 `json:"old_price"`
+	Message    string  `json:"message"`
+}
+
+type MasterImportPreview struct {
+	Rows      []MasterImportPreviewRow `json:"rows"`
+	Inserts   int                      `json:"inserts"`
+	Updates   int                      `json:"updates"`
+	Unchanged int                      `json:"unchanged"`
+	Errors    int                      `json:"errors"`
+}
+
+// MasterImportConfig holds the labels and URLs of the import modal for one kind of master data
+type MasterImportConfig struct {
+	Kind       string // materials or labor_types
+	Title      string
+	BaseURL    string // e.g. /materials/import
+	NameLabel  string
+	PriceLabel string
+}
@@ -3,6 +3,7 @@ package models
 type MasterLaborType struct {
 	LaborTypeId      int     `json:"labor_type_id"`
 	UserId           int     `json:"user_id"`
+	OrgId            int     `json:"org_id"`
 	RoleName         string  `json:"role_name"`
 	Unit             string  `json:"unit"`
 	DefaultDailyWage float64 `json:"default_daily_wage"`
@@ -15,4 +16,5 @@ type MasterLaborTypeCreate struct {
 	Unit             string  `json:"unit" validate:"required,min=1,max=20"`
 	DefaultDailyWage float64 `json:"default_daily_wage" validate:"required,gte=0"`
 	UserId           int     `json:"user_id"`
+	OrgId            int     `json:"org_id"`
 }
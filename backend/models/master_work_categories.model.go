@@ -3,6 +3,7 @@ package models
 type MasterWorkCategory struct {
 	CategoryId   int    `json:"category_id"`
 	UserId       int    `json:"user_id"`
+	OrgId        int    `json:"org_id"`
 	CategoryName string `json:"category_name"`
 	DisplayOrder int    `json:"display_order"`
 	CreatedAt    string `json:"created_at"`
@@ -12,5 +13,6 @@ type MasterWorkCategory struct {
 type MasterWorkCategoryCreate struct {
 	CategoryName string `json:"category_name" validate:"required,min=1,max=100"`
 	UserId       int    `json:"user_id"`
+	OrgId        int    `json:"org_id"`
 	DisplayOrder int    `json:"display_order"`
 }
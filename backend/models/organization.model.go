@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+const (
+	// ORG_ROLE_OWNER manages the members of an organization
+	ORG_ROLE_OWNER  = "owner"
+	ORG_ROLE_MEMBER = "member"
+)
+
+type Organization struct {
+	OrgId     int    `json:"org_id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type OrganizationCreate struct {
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+// OrganizationMembership is an organization of a user with their role in it
+type OrganizationMembership struct {
+	Organization
+	Role        string `json:"role"`
+	MemberCount int    `json:"member_count"`
+}
+
+// OrganizationMember is a user of an organization
+type OrganizationMember struct {
+	OrgId     int    `json:"org_id"`
+	UserId    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Workspace is the data a user works in: their own rows, or the rows shared by an
+// organization when OrgId is set. The system-wide defaults are part of every workspace.
+// The zero Workspace holds only the system-wide defaults.
+type Workspace struct {
+	UserId int
+	OrgId  int
+}
+
+// OwnerUserId is the user_id of master data created in the workspace. Organization master
+// data has none, so the names of the members' own rows stay free.
+func (w Workspace) OwnerUserId() int {
+	if w.OrgId != 0 {
+		return 0
+	}
+
+	return w.UserId
+}
+
+// MasterDataCondition returns the condition that keeps the rows of a master data table (a
+// table name or alias, or "" for none) visible in the workspace, with its arguments
+func (w Workspace) MasterDataCondition(table string) (string, []interface{}) {
+	userId, orgId := ownerColumns(table)
+	system := "(" + userId + " IS NULL AND " + orgId + " IS NULL)"
+
+	if w.OrgId != 0 {
+		return "(" + orgId + " = ? OR " + system + ")", []interface{}{w.OrgId}
+	}
+
+	if w.UserId != 0 {
+		return "(" + userId + " = ? OR " + system + ")", []interface{}{w.UserId}
+	}
+
+	return system, nil
+}
+
+// ProjectCondition returns the condition that keeps the projects of the workspace. Projects
+// of an organization keep their creator in user_id, the user's own have no org_id.
+func (w Workspace) ProjectCondition(table string) (string, []interface{}) {
+	userId, orgId := ownerColumns(table)
+
+	if w.OrgId != 0 {
+		return orgId + " = ?", []interface{}{w.OrgId}
+	}
+
+	return "(" + userId + " = ? AND " + orgId + " IS NULL)", []interface{}{w.UserId}
+}
+
+func ownerColumns(table string) (string, string) {
+	if table == "" {
+		return "user_id", "org_id"
+	}
+
+	table = strings.TrimSuffix(table, ".")
+	return table + ".user_id", table + ".org_id"
+}
+
+// NullableOrgId converts an organization id into a value for the nullable org_id columns
+func NullableOrgId(orgId int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(orgId), Valid: orgId != 0}
+}
@@ -18,8 +18,8 @@ type TableConfig struct {
 
 // PaginationInfo holds pagination related data
 type PaginationInfo struct {
-	CurrentPage  int
-	TotalPages   int
-	TotalItems   int
-	ItemsPerPage int
+	CurrentPage  int `json:"current_page"`
+	TotalPages   int `json:"total_pages"`
+	TotalItems   int `json:"total_items"`
+	ItemsPerPage int `json:"items_per_page"`
 }
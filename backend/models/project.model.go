@@ -3,6 +3,7 @@ package models
 type Project struct {
 	ProjectId   int    `json:"project_id"`
 	UserId      int    `json:"user_id"`
+	OrgId       int    `json:"org_id"`
 	ProjectName string `json:"project_name"`
 	Location    string `json:"location"`
 	ClientName  string `json:"client_name"`
@@ -15,4 +16,5 @@ type ProjectCreate struct {
 	Location    string `json:"location" validate:"required,min=3,max=100"`
 	ClientName  string `json:"client_name" validate:"required,min=3,max=100"`
 	UserId      int    `json:"user_id"`
+	OrgId       int    `json:"org_id"`
 }
@@ -0,0 +1,84 @@
+package models
+
+// ProjectBundle is the portable JSON form of one project. Ids inside the bundle are the ids of
+// the exporting install and are only used to link entries together, they are remapped on import.
+type ProjectBundle struct {
+	Format     string                   `json:"format"`
+	Version    int                      `json:"version"`
+	ExportedAt string                   `json:"exported_at"`
+	Project    ProjectBundleProject     `json:"project"`
+	Materials  []ProjectBundleMaterial  `json:"materials"`
+	LaborTypes []ProjectBundleLaborType `json:"labor_types"`
+	Templates  []ProjectBundleTemplate  `json:"templates"`
+	WorkItems  []ProjectBundleWorkItem  `json:"work_items"`
+}
+
+type ProjectBundleProject struct {
+	ProjectName string `json:"project_name"`
+	Location    string `json:"location"`
+	ClientName  string `json:"client_name"`
+}
+
+type ProjectBundleMaterial struct {
+	Id               int     `json:"id"`
+	MaterialName     string  `json:"material_name"`
+	Unit             string  `json:"unit"`
+	DefaultUnitPrice float64 `json:"default_unit_price"`
+	IsEquipment      bool    `json:"is_equipment"`
+}
+
+type ProjectBundleLaborType struct {
+	Id               int     `json:"id"`
+	RoleName         string  `json:"role_name"`
+	Unit             string  `json:"unit"`
+	DefaultDailyWage float64 `json:"default_daily_wage"`
+}
+
+type ProjectBundleTemplate struct {
+	Id                 int                      `json:"id"`
+	Code               string                   `json:"code"`
+	TemplateName       string                   `json:"template_name"`
+	Unit               string                   `json:"unit"`
+	MaterialComponents []ProjectBundleComponent `json:"material_components"`
+	LaborComponents    []ProjectBundleComponent `json:"labor_components"`
+}
+
+// ProjectBundleComponent points to a material or labor type of the bundle by its bundle id
+type ProjectBundleComponent struct {
+	ItemId      int     `json:"item_id"`
+	Coefficient float64 `json:"coefficient"`
+}
+
+type ProjectBundleWorkItem struct {
+	CategoryName string              `json:"category_name"`
+	Description  string              `json:"description"`
+	Volume       float64             `json:"volume"`
+	Unit         string              `json:"unit"`
+	TemplateId   int                 `json:"template_id"` // bundle template id, 0 when entered manually
+	Costs        []ProjectBundleCost `json:"costs"`
+}
+
+// ProjectBundleCost keeps the prices captured in the project, not the current master prices
+type ProjectBundleCost struct {
+	ItemType            string  `json:"item_type"`
+	ItemId              int     `json:"item_id"` // bundle material or labor type id, 0 for lump sum lines
+	ItemName            string  `json:"item_name"`
+	Coefficient         float64 `json:"coefficient"`
+	QuantityNeeded      float64 `json:"quantity_needed"`
+	Unit                string  `json:"unit"`
+	UnitPriceAtCreation float64 `json:"unit_price_at_creation"`
+	TotalCost           float64 `json:"total_cost"`
+}
+
+type ProjectBundleImportResult struct {
+	ProjectId         int `json:"project_id"`
+	WorkItems         int `json:"work_items"`
+	Costs             int `json:"costs"`
+	MaterialsMatched  int `json:"materials_matched"`
+	MaterialsCreated  int `json:"materials_created"`
+	LaborTypesMatched int `json:"labor_types_matched"`
+	LaborTypesCreated int `json:"labor_types_created"`
+	TemplatesMatched  int `json:"templates_matched"`
+	TemplatesCreated  int `json:"templates_created"`
+	CategoriesCreated int `json:"categories_created"`
+}
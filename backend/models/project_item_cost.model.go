@@ -50,6 +50,13 @@ type ProjectItemCostWithDetails struct {
 	WorkItemDescription string  `json:"work_item_description"`
 }
 
+// ProjectItemPrice is the unit price of a master material or labor type as captured in a project
+type ProjectItemPrice struct {
+	ItemType  string  `json:"item_type"`
+	ItemId    int     `json:"item_id"`
+	UnitPrice float64 `json:"unit_price"`
+}
+
 type MaterialSummary struct {
 	ProjectId     int     `json:"project_id"`
 	ProjectName   string  `json:"project_name"`
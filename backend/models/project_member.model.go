@@ -0,0 +1,46 @@
+package models
+
+const (
+	// PROJECT_ROLE_OWNER is the project's user, or a member of its organization. It is not
+	// stored, the owner edits the project and manages who it is shared with.
+	PROJECT_ROLE_OWNER = "owner"
+	// PROJECT_ROLE_EDITOR changes the work items of a project shared with them
+	PROJECT_ROLE_EDITOR = "editor"
+	// PROJECT_ROLE_VIEWER reads a project shared with them
+	PROJECT_ROLE_VIEWER = "viewer"
+)
+
+// projectRoleRanks orders the project roles, a role allows what the lower ones do
+var projectRoleRanks = map[string]int{
+	PROJECT_ROLE_VIEWER: 1,
+	PROJECT_ROLE_EDITOR: 2,
+	PROJECT_ROLE_OWNER:  3,
+}
+
+// ProjectRoleAllows reports whether role gives at least the access of required. The empty
+// role is no access.
+func ProjectRoleAllows(role, required string) bool {
+	return role != "" && projectRoleRanks[role] >= projectRoleRanks[required]
+}
+
+// IsProjectMemberRole reports whether a project can be shared with the role
+func IsProjectMemberRole(role string) bool {
+	return role == PROJECT_ROLE_EDITOR || role == PROJECT_ROLE_VIEWER
+}
+
+// ProjectMember is a user a project is shared with
+type ProjectMember struct {
+	ProjectId int    `json:"project_id"`
+	UserId    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	InvitedBy int    `json:"invited_by"`
+	CreatedAt string `json:"created_at"`
+}
+
+// SharedProject is a project shared with a user, with their role in it
+type SharedProject struct {
+	Project
+	Role          string `json:"role"`
+	OwnerUsername string `json:"owner_username"`
+}
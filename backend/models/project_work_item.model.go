@@ -34,3 +34,38 @@ type ProjectWorkItemWithDetails struct {
 	CategoryName   string  `json:"category_name"`
 	TemplateName   string  `json:"template_name"`
 }
+
+// ProjectWorkItemInput is the JSON API body of a work item. Its costs are calculated from the
+// AHSP template when one is set, otherwise they are the manual costs.
+type ProjectWorkItemInput struct {
+	CategoryId     int                 `json:"category_id" validate:"required"`
+	Description    string              `json:"description" validate:"required,min=1,max=255"`
+	Volume         float64             `json:"volume" validate:"required,gt=0"`
+	Unit           string              `json:"unit" validate:"required,min=1,max=50"`
+	AHSPTemplateId *int                `json:"ahsp_template_id,omitempty"`
+	ManualCosts    []ProjectManualCost `json:"manual_costs,omitempty" validate:"dive"`
+}
+
+// ProjectManualCost is a material or labor line entered by hand instead of taken from an AHSP template
+type ProjectManualCost struct {
+	ItemType  string  `json:"item_type" validate:"required,oneof=MATERIAL LABOR"`
+	ItemName  string  `json:"item_name" validate:"required,min=1,max=255"`
+	Quantity  float64 `json:"quantity" validate:"required,gt=0"`
+	Unit      string  `json:"unit" validate:"max=50"`
+	UnitPrice float64 `json:"unit_price" validate:"required,gt=0"`
+}
+
+type ProjectWorkItemWithCosts struct {
+	ProjectWorkItem
+	Costs []ProjectItemCostWithDetails `json:"costs"`
+}
+
+// WORK_ITEM_UNCATEGORIZED groups the work items without a category in the recap and the workbook
+const WORK_ITEM_UNCATEGORIZED = "Uncategorized"
+
+// CategoryRecap is the cost of the work items of one category, the recap of a RAB
+type CategoryRecap struct {
+	CategoryName string  `json:"category_name"`
+	ItemCount    int     `json:"item_count"`
+	TotalCost    float64 `json:"total_cost"`
+}
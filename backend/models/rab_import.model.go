@@ -0,0 +1,34 @@
+package models
+
+// RabImportColumnMapping maps spreadsheet column indexes to work item fields, -1 means not mapped
+type RabImportColumnMapping struct {
+	Description int `json:"description"`
+	Volume      int `json:"volume"`
+	Unit        int `json:"unit"`
+	UnitPrice   int `json:"unit_price"`
+	Category    int `json:"category"` // optional, section header rows are used when not mapped
+}
+
+type RabImportPreviewRow struct {
+	Line         int     `json:"line"` // 1-based row number in the uploaded sheet
+	Category     string  `json:"category"`
+	NewCategory  bool    `json:"new_category"`
+	Description  string  `json:"description"`
+	Volume       float64 `json:"volume"`
+	Unit         string  `json:"unit"`
+	UnitPrice    float64 `json:"unit_price"`
+	Total        float64 `json:"total"`
+	TemplateId   int     `json:"template_id"` // matched AHSP template, 0 for a lump-sum line
+	TemplateName string  `json:"template_name"`
+	Action       string  `json:"action"` // item, section, skipped or error
+	Message      string  `json:"message"`
+}
+
+type RabImportPreview struct {
+	Rows             []RabImportPreviewRow `json:"rows"`
+	Items            int                   `json:"items"`
+	Sections         int                   `json:"sections"`
+	MatchedTemplates int                   `json:"matched_templates"`
+	Errors           int                   `json:"errors"`
+	LumpSumTotal     float64               `json:"lump_sum_total"`
+}
@@ -0,0 +1,60 @@
+package models
+
+const (
+	ROLE_ADMIN     = "admin"
+	ROLE_ESTIMATOR = "estimator"
+	ROLE_REVIEWER  = "reviewer"
+	ROLE_VIEWER    = "viewer"
+
+	// DEFAULT_ROLE is given to registered users and to accounts from before roles existed
+	DEFAULT_ROLE = ROLE_ESTIMATOR
+)
+
+const (
+	// PERMISSION_READ is viewing projects, master data and reports
+	PERMISSION_READ = "read"
+	// PERMISSION_EDIT is creating and changing your own projects and master data
+	PERMISSION_EDIT = "edit"
+	// PERMISSION_APPROVE is reviewing and approving the RAB of a project
+	PERMISSION_APPROVE = "approve"
+	// PERMISSION_MANAGE_SYSTEM_DATA is changing the system-wide master data (user_id IS NULL)
+	PERMISSION_MANAGE_SYSTEM_DATA = "manage_system_data"
+	// PERMISSION_ADMINISTER is managing users and database backups
+	PERMISSION_ADMINISTER = "administer"
+)
+
+// ROLES lists the roles from the most to the least privileged
+var ROLES = []string{ROLE_ADMIN, ROLE_ESTIMATOR, ROLE_REVIEWER, ROLE_VIEWER}
+
+// ROLE_PERMISSIONS is the permission matrix
+var ROLE_PERMISSIONS = map[string][]string{
+	ROLE_ADMIN:     {PERMISSION_READ, PERMISSION_EDIT, PERMISSION_APPROVE, PERMISSION_MANAGE_SYSTEM_DATA, PERMISSION_ADMINISTER},
+	ROLE_ESTIMATOR: {PERMISSION_READ, PERMISSION_EDIT},
+	ROLE_REVIEWER:  {PERMISSION_READ, PERMISSION_APPROVE},
+	ROLE_VIEWER:    {PERMISSION_READ},
+}
+
+// ROLE_DESCRIPTIONS explain the roles on the users page
+var ROLE_DESCRIPTIONS = map[string]string{
+	ROLE_ADMIN:     "Everything, including users, backups and the system-wide master data",
+	ROLE_ESTIMATOR: "Builds projects and their own master data",
+	ROLE_REVIEWER:  "Reads everything and approves projects",
+	ROLE_VIEWER:    "Reads only",
+}
+
+// IsRole reports whether role is one of ROLES
+func IsRole(role string) bool {
+	_, ok := ROLE_PERMISSIONS[role]
+	return ok
+}
+
+// RoleHasPermission looks the permission up in the matrix, unknown roles have none
+func RoleHasPermission(role, permission string) bool {
+	for _, granted := range ROLE_PERMISSIONS[role] {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
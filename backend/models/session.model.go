@@ -1,7 +1,67 @@
 package models
 
 type SessionUser struct {
-	ID    int    `json:"id"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	// OrgId is the active organization, 0 while the user works in their own workspace
+	OrgId int `json:"org_id"`
+	// OrgIds are the organizations the user is a member of
+	OrgIds []int `json:"org_ids"`
+}
+
+// Can reports whether the user's role has the permission
+func (u SessionUser) Can(permission string) bool {
+	return RoleHasPermission(u.Role, permission)
+}
+
+// Workspace is the data the user is working in, picked with the organization switcher
+func (u SessionUser) Workspace() Workspace {
+	return Workspace{UserId: u.ID, OrgId: u.OrgId}
+}
+
+// IsMemberOf reports whether the user belongs to the organization
+func (u SessionUser) IsMemberOf(orgId int) bool {
+	for _, id := range u.OrgIds {
+		if id == orgId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanView reports whether the user may see a row owned by userId or orgId. Rows of an
+// organization are seen by its members, both 0 is a system-wide default everyone sees.
+func (u SessionUser) CanView(userId, orgId int) bool {
+	if orgId != 0 {
+		return u.IsMemberOf(orgId)
+	}
+
+	return userId == 0 || userId == u.ID
+}
+
+// OwnsProject reports whether the project is the user's own or belongs to one of their
+// organizations. Unlike CanView, a project without a user is nobody's.
+func (u SessionUser) OwnsProject(project Project) bool {
+	if project.OrgId != 0 {
+		return u.IsMemberOf(project.OrgId)
+	}
+
+	return project.UserId != 0 && project.UserId == u.ID
+}
+
+// CanModify reports whether the user may change a row owned by userId or orgId. Both 0 is a
+// system-wide default, only admins change those.
+func (u SessionUser) CanModify(userId, orgId int) bool {
+	if orgId != 0 {
+		return u.IsMemberOf(orgId) && u.Can(PERMISSION_EDIT)
+	}
+
+	if userId == 0 {
+		return u.Can(PERMISSION_MANAGE_SYSTEM_DATA)
+	}
+
+	return userId == u.ID && u.Can(PERMISSION_EDIT)
 }
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+const (
+	// SHARE_LINK_PREFIX starts the token of every share link, telling it apart from API tokens
+	SHARE_LINK_PREFIX = "rs_"
+	// SHARE_LINK_PREFIX_LENGTH is how much of a link's token is kept in clear to tell links apart
+	SHARE_LINK_PREFIX_LENGTH = 11
+	// SHARE_LINK_PASSWORD_MIN_LENGTH is the shortest password a link can be protected with
+	SHARE_LINK_PASSWORD_MIN_LENGTH = 6
+)
+
+// ShareLink opens a read-only version of a project to anyone with its URL, the token in
+// the URL is only known by its hash
+type ShareLink struct {
+	LinkId         int    `json:"link_id"`
+	ProjectId      int    `json:"project_id"`
+	CreatedBy      int    `json:"created_by"` // 0 once the user is deleted
+	Label          string `json:"label"`
+	TokenPrefix    string `json:"token_prefix"`
+	TokenHash      string `json:"-"`
+	PasswordHash   string `json:"-"`                // empty when the link has no password
+	ExpiresAt      string `json:"expires_at"`       // empty when the link does not expire
+	RevokedAt      string `json:"revoked_at"`       // empty until the link is revoked
+	AccessCount    int    `json:"access_count"`     // views of the shared page
+	LastAccessedAt string `json:"last_accessed_at"` // empty until the link is opened
+	CreatedAt      string `json:"created_at"`
+}
+
+type ShareLinkCreate struct {
+	ProjectId    int    `json:"project_id"`
+	CreatedBy    int    `json:"created_by"`
+	Label        string `json:"label" validate:"required,min=1,max=100"`
+	TokenPrefix  string `json:"-"`
+	TokenHash    string `json:"-"`
+	PasswordHash string `json:"-"`
+	ExpiresAt    string `json:"expires_at"`
+}
+
+// HasPassword reports whether the link asks for a password before showing the project
+func (l ShareLink) HasPassword() bool {
+	return l.PasswordHash != ""
+}
+
+// IsExpired reports whether the link's expiry has passed at now
+func (l ShareLink) IsExpired(now time.Time) bool {
+	return APIToken{ExpiresAt: l.ExpiresAt}.IsExpired(now)
+}
+
+// IsActive reports whether the link still opens the project
+func (l ShareLink) IsActive(now time.Time) bool {
+	return l.RevokedAt == "" && !l.IsExpired(now)
+}
+
+// NewShareLinkToken returns a random token for the URL of a link, the prefix shown to the
+// owner and the hash to store
+func NewShareLinkToken() (token, prefix, hash string, err error) {
+	token, _, _, err = NewAPIToken()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	token = SHARE_LINK_PREFIX + token[len(API_TOKEN_PREFIX):]
+
+	return token, token[:SHARE_LINK_PREFIX_LENGTH], HashAPIToken(token), nil
+}
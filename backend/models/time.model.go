@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// DB_TIME_FORMAT is the format of SQLite's CURRENT_TIMESTAMP, the text every timestamp column holds
+const DB_TIME_FORMAT = "2006-01-02 15:04:05"
+
+// UTCNow is the current time in DB_TIME_FORMAT, set from Go so both database engines store
+// the same text
+func UTCNow() string {
+	return time.Now().UTC().Format(DB_TIME_FORMAT)
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+const (
+	// TRASH_DEFAULT_RETENTION_DAYS is how long deleted rows stay in the trash when
+	// TRASH_RETENTION_DAYS is not set
+	TRASH_DEFAULT_RETENTION_DAYS = 30
+)
+
+// TRASH_ENTITIES lists the entities that go to the trash when deleted, in the order the
+// filter of the trash page shows them
+var TRASH_ENTITIES = []string{
+	AUDIT_ENTITY_PROJECT,
+	AUDIT_ENTITY_WORK_ITEM,
+	AUDIT_ENTITY_MATERIAL,
+	AUDIT_ENTITY_LABOR_TYPE,
+	AUDIT_ENTITY_WORK_CATEGORY,
+	AUDIT_ENTITY_AHSP_TEMPLATE,
+}
+
+// TrashItem is a deleted project, work item or master data row waiting in the trash to be
+// restored or purged. A work item is owned like its project.
+type TrashItem struct {
+	Entity    string `json:"entity"`
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	Detail    string `json:"detail"`     // the unit of master data, the project of a work item
+	UserId    int    `json:"user_id"`    // 0 for system-wide master data
+	OrgId     int    `json:"org_id"`     // 0 outside organizations
+	ProjectId int    `json:"project_id"` // the project itself or the project of a work item, 0 for master data
+	DeletedAt string `json:"deleted_at"` // UTC
+}
+
+// EntityLabel is the entity as the pages show it
+func (t TrashItem) EntityLabel() string {
+	return AuditEntry{Entity: t.Entity}.EntityLabel()
+}
+
+// IsProjectData reports whether the item is a project or a work item, owned like a project
+// rather than like master data
+func (t TrashItem) IsProjectData() bool {
+	return t.Entity == AUDIT_ENTITY_PROJECT || t.Entity == AUDIT_ENTITY_WORK_ITEM
+}
+
+// PurgeAt is when the automatic purge deletes the item for good after retentionDays, the
+// zero time when the automatic purge is disabled or DeletedAt cannot be read
+func (t TrashItem) PurgeAt(retentionDays int) time.Time {
+	if retentionDays <= 0 {
+		return time.Time{}
+	}
+
+	deletedAt, err := time.Parse(DB_TIME_FORMAT, t.DeletedAt)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return deletedAt.AddDate(0, 0, retentionDays)
+}
+
+// CanManageTrashItem reports whether the user may restore or purge an item of the trash: a
+// project or work item of a project they own, or master data they may change
+func (u SessionUser) CanManageTrashItem(item TrashItem) bool {
+	if item.IsProjectData() {
+		return u.Can(PERMISSION_EDIT) && u.OwnsProject(Project{UserId: item.UserId, OrgId: item.OrgId})
+	}
+
+	return u.CanModify(item.UserId, item.OrgId)
+}
@@ -10,6 +10,7 @@ type User struct {
 	UserId    int            `json:"user_id"`
 	Username  string         `json:"username"`
 	Password  string         `json:"password"` // This will store the hashed password
+	Role      string         `json:"role"`
 	CreatedAt string         `json:"created_at"`
 	UpdatedAt string         `json:"updated_at"`
 	DeletedAt sql.NullString `json:"deleted_at,omitempty"` // Soft delete timestamp
@@ -18,6 +19,29 @@ type User struct {
 type UserCreate struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Password string `json:"password" validate:"required,min=6,max=100"` // Plain text password that will be hashed
+	// Role is DEFAULT_ROLE when empty
+	Role string `json:"role" validate:"omitempty,oneof=admin estimator reviewer viewer"`
+}
+
+// IsDisabled reports whether the account was disabled, it can no longer log in
+func (u User) IsDisabled() bool {
+	return u.DeletedAt.Valid
+}
+
+// UserRow is a user on the users administration page
+type UserRow struct {
+	User
+	ConfiguredAdmin bool // admin through ADMIN_USERNAMES, the stored role is not used
+	IsCurrentUser   bool
+}
+
+// EffectiveRole is the role the user signs in with
+func (u UserRow) EffectiveRole() string {
+	if u.ConfiguredAdmin {
+		return ROLE_ADMIN
+	}
+
+	return u.Role
 }
 
 type UserLogin struct {
@@ -25,6 +49,12 @@ type UserLogin struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// NullableUserId converts a user id into a value for the nullable user_id columns.
+// 0 is stored as NULL, which marks the row as a system-wide default.
+func NullableUserId(userId int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(userId), Valid: userId != 0}
+}
+
 // HashPassword hashes a plain text password using bcrypt
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
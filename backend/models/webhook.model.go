@@ -0,0 +1,225 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	WEBHOOK_EVENT_PROJECT_CREATED         = "project.created"
+	WEBHOOK_EVENT_PROJECT_UPDATED         = "project.updated"
+	WEBHOOK_EVENT_PROJECT_DELETED         = "project.deleted"
+	WEBHOOK_EVENT_WORK_ITEM_CHANGED       = "work_item.changed"
+	WEBHOOK_EVENT_MASTER_PRICE_CHANGED    = "master_price.changed"
+	WEBHOOK_EVENT_APPROVAL_STATUS_CHANGED = "approval.status_changed"
+	// WEBHOOK_EVENT_PING is sent by the Send test button, every webhook receives it
+	WEBHOOK_EVENT_PING = "ping"
+
+	WEBHOOK_DELIVERY_PENDING   = "pending"
+	WEBHOOK_DELIVERY_DELIVERED = "delivered"
+	WEBHOOK_DELIVERY_FAILED    = "failed"
+
+	WEBHOOK_ACTION_CREATED = "created"
+	WEBHOOK_ACTION_UPDATED = "updated"
+	WEBHOOK_ACTION_DELETED = "deleted"
+
+	// WEBHOOK_SECRET_PREFIX starts every signing secret
+	WEBHOOK_SECRET_PREFIX = "whsec_"
+
+	WEBHOOK_TIME_FORMAT = "2006-01-02 15:04:05"
+)
+
+// WebhookEvent is an event a webhook can subscribe to
+type WebhookEvent struct {
+	Name        string
+	Description string
+}
+
+// WEBHOOK_EVENTS lists the events in the order the settings page shows them
+var WEBHOOK_EVENTS = []WebhookEvent{
+	{WEBHOOK_EVENT_PROJECT_CREATED, "A project was created"},
+	{WEBHOOK_EVENT_PROJECT_UPDATED, "A project's name, location or client changed"},
+	{WEBHOOK_EVENT_PROJECT_DELETED, "A project was deleted"},
+	{WEBHOOK_EVENT_WORK_ITEM_CHANGED, "A work item was added, changed or removed, with the new project total"},
+	{WEBHOOK_EVENT_MASTER_PRICE_CHANGED, "The price of a material or the wage of a labor type changed"},
+	{WEBHOOK_EVENT_APPROVAL_STATUS_CHANGED, "A project's approval status changed"},
+}
+
+// IsWebhookEvent reports whether name is an event webhooks can subscribe to
+func IsWebhookEvent(name string) bool {
+	for _, event := range WEBHOOK_EVENTS {
+		if event.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Webhook struct {
+	WebhookId  int      `json:"webhook_id"`
+	UserId     int      `json:"user_id"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"-"`
+	Events     []string `json:"events"`
+	DisabledAt string   `json:"disabled_at"` // empty while the webhook is active
+	CreatedAt  string   `json:"created_at"`
+	UpdatedAt  string   `json:"updated_at"`
+}
+
+// IsActive reports whether deliveries are sent to the webhook
+func (w Webhook) IsActive() bool {
+	return w.DisabledAt == ""
+}
+
+// Subscribes reports whether the webhook receives event, every webhook receives pings
+func (w Webhook) Subscribes(event string) bool {
+	if event == WEBHOOK_EVENT_PING {
+		return true
+	}
+
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewWebhookSecret generates a signing secret. It is kept in clear, unlike API tokens,
+// since every delivery is signed with it.
+func NewWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return WEBHOOK_SECRET_PREFIX + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+type WebhookCreate struct {
+	UserId int      `json:"user_id"`
+	URL    string   `json:"url" validate:"required,url,max=500"`
+	Secret string   `json:"-"`
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+}
+
+// JoinWebhookEvents and SplitWebhookEvents convert the events to and from the events column
+func JoinWebhookEvents(events []string) string {
+	return strings.Join(events, ",")
+}
+
+func SplitWebhookEvents(events string) []string {
+	if events == "" {
+		return nil
+	}
+
+	return strings.Split(events, ",")
+}
+
+// WebhookDelivery is one event for one webhook, a row of the outbox
+type WebhookDelivery struct {
+	DeliveryId     int    `json:"delivery_id"`
+	WebhookId      int    `json:"webhook_id"`
+	EventId        string `json:"event_id"`
+	Event          string `json:"event"`
+	Payload        string `json:"payload"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	NextAttemptAt  string `json:"next_attempt_at"`
+	LastStatusCode int    `json:"last_status_code"` // 0 when no response was received
+	LastError      string `json:"last_error"`
+	DeliveredAt    string `json:"delivered_at"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type WebhookDeliveryCreate struct {
+	WebhookId     int
+	EventId       string
+	Event         string
+	Payload       string
+	NextAttemptAt string
+}
+
+// WebhookDueDelivery is a delivery to send with the address and secret of its webhook
+type WebhookDueDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// WebhookDeliveryAttempt is the outcome of sending a delivery once
+type WebhookDeliveryAttempt struct {
+	DeliveryId    int
+	Status        string
+	Attempts      int
+	StatusCode    int    // 0 when no response was received
+	Error         string // empty when the receiver answered 2xx
+	NextAttemptAt string
+	DeliveredAt   string
+}
+
+// WebhookPayload is the JSON body of every delivery
+type WebhookPayload struct {
+	EventId   string      `json:"event_id"`
+	Event     string      `json:"event"`
+	CreatedAt string      `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookWorkItemChange is the data of work_item.changed, WorkItem is nil when it was deleted
+type WebhookWorkItemChange struct {
+	Action       string           `json:"action"`
+	ProjectId    int              `json:"project_id"`
+	WorkItemId   int              `json:"work_item_id"`
+	WorkItem     *ProjectWorkItem `json:"work_item"`
+	ProjectTotal float64          `json:"project_total"`
+}
+
+// WebhookApprovalChange is the data of approval.status_changed, ProjectTotal is the total the
+// step was taken on
+type WebhookApprovalChange struct {
+	Action       string  `json:"action"`
+	ProjectId    int     `json:"project_id"`
+	ProjectName  string  `json:"project_name"`
+	Revision     int     `json:"revision"`
+	FromStatus   string  `json:"from_status"`
+	ToStatus     string  `json:"to_status"`
+	Comment      string  `json:"comment"`
+	ProjectTotal float64 `json:"project_total"`
+	Username     string  `json:"username"`
+}
+
+// WebhookPriceChange is the data of master_price.changed
+type WebhookPriceChange struct {
+	ItemType ItemType `json:"item_type"`
+	ItemId   int      `json:"item_id"`
+	ItemName string   `json:"item_name"`
+	Unit     string   `json:"unit"`
+	OldPrice float64  `json:"old_price"`
+	NewPrice float64  `json:"new_price"`
+}
+
+func NewMaterialPriceChange(material MasterMaterial, oldPrice float64) WebhookPriceChange {
+	return WebhookPriceChange{
+		ItemType: PROJECT_ITEM_TYPE_MATERIAL,
+		ItemId:   material.MaterialId,
+		ItemName: material.MaterialName,
+		Unit:     material.Unit,
+		OldPrice: oldPrice,
+		NewPrice: material.DefaultUnitPrice,
+	}
+}
+
+func NewLaborWageChange(laborType MasterLaborType, oldWage float64) WebhookPriceChange {
+	return WebhookPriceChange{
+		ItemType: PROJECT_ITEM_TYPE_LABOR,
+		ItemId:   laborType.LaborTypeId,
+		ItemName: laborType.RoleName,
+		Unit:     laborType.Unit,
+		OldPrice: oldWage,
+		NewPrice: laborType.DefaultDailyWage,
+	}
+}
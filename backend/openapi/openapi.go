@@ -0,0 +1,389 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	OPENAPI_VERSION = "3.0.3"
+
+	JSON_CONTENT_TYPE = "application/json"
+)
+
+// Document is the part of an OpenAPI 3.0 document the JSON API uses. Maps are written with
+// sorted keys, so the same routes and models always give the same JSON.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// SecurityRequirement names the security schemes of which one must be satisfied
+type SecurityRequirement map[string][]string
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of one path
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+}
+
+// SetOperation sets the operation of an HTTP method, it reports false for a method OpenAPI
+// paths do not describe here
+func (p *PathItem) SetOperation(method string, operation *Operation) bool {
+	switch strings.ToUpper(method) {
+	case "GET":
+		p.Get = operation
+	case "PUT":
+		p.Put = operation
+	case "POST":
+		p.Post = operation
+	case "DELETE":
+		p.Delete = operation
+	case "PATCH":
+		p.Patch = operation
+	default:
+		return false
+	}
+
+	return true
+}
+
+type Operation struct {
+	Tags        []string             `json:"tags,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	OperationId string               `json:"operationId,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`                   // http or apiKey
+	Scheme       string `json:"scheme,omitempty"`       // bearer for http
+	BearerFormat string `json:"bearerFormat,omitempty"` // a hint, bearer tokens are opaque
+	In           string `json:"in,omitempty"`           // cookie, header or query for apiKey
+	Name         string `json:"name,omitempty"`         // the cookie or header name for apiKey
+	Description  string `json:"description,omitempty"`
+}
+
+// Schema is an OpenAPI 3.0 schema object. Ref is set alone, 3.0 ignores anything next to it.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Schemas builds schemas from Go types the way encoding/json writes them. Named structs become
+// components referenced by $ref, their validate tags become constraints.
+type Schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func NewSchemas() *Schemas {
+	return &Schemas{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+// Components returns the schemas of the named structs seen so far, keyed by name
+func (s *Schemas) Components() map[string]*Schema {
+	return s.components
+}
+
+// SchemaOf returns the schema of the type of value
+func (s *Schemas) SchemaOf(value interface{}) *Schema {
+	return s.schemaFor(reflect.TypeOf(value))
+}
+
+func (s *Schemas) schemaFor(t reflect.Type) *Schema {
+	if t == nil {
+		// an interface{} field, any JSON value
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.schemaFor(t.Elem())
+		if schema.Ref != "" {
+			return &Schema{AllOf: []*Schema{schema}, Nullable: true}
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as base64
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	default:
+		// interface{} and anything encoding/json cannot write
+		return &Schema{}
+	}
+}
+
+// component registers a named struct and returns its component name. The name is reserved
+// before the fields are read, so a struct that refers to itself does not recurse forever.
+func (s *Schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := s.components[name]; taken {
+		// the same name in another package
+		name = strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + name
+	}
+
+	s.names[t] = name
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.structSchema(t)
+
+	return name
+}
+
+// structSchema lists the fields encoding/json writes. Fields of an embedded struct are added
+// unless the outer struct has a field of the same name.
+func (s *Schemas) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	var embedded []reflect.StructField
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embedded = append(embedded, field)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.schemaFor(field.Type)
+		if applyValidateTag(property, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+
+	for _, field := range embedded {
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		inner := s.structSchema(fieldType)
+		for name, property := range inner.Properties {
+			if _, ok := schema.Properties[name]; ok {
+				continue
+			}
+			schema.Properties[name] = property
+			for _, required := range inner.Required {
+				if required == name {
+					schema.Required = append(schema.Required, name)
+				}
+			}
+		}
+	}
+
+	return schema
+}
+
+// applyValidateTag turns the validator rules of a field into schema constraints and reports
+// whether the field is required. Rules after dive apply to the items of a list, rules the
+// schema cannot express are left to the server.
+func applyValidateTag(schema *Schema, tag string) bool {
+	required := false
+	target := schema
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "required":
+			if target == schema {
+				required = true
+			}
+		case "dive":
+			if target.Items == nil {
+				return required
+			}
+			target = target.Items
+		case "min", "max", "gt", "gte", "lt", "lte":
+			applyLimit(target, name, param)
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				target.Enum = append(target.Enum, enumValue(target, value))
+			}
+		}
+	}
+
+	return required
+}
+
+// applyLimit sets a length, size or value limit depending on what the schema describes, the
+// way the validator reads min and max
+func applyLimit(schema *Schema, rule, param string) {
+	if schema.Ref != "" || len(schema.AllOf) > 0 {
+		return
+	}
+
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch schema.Type {
+	case "string", "array":
+		count := int(value)
+		switch rule {
+		case "gt":
+			count++
+		case "lt":
+			count--
+		}
+
+		lower := rule == "min" || rule == "gt" || rule == "gte"
+		switch {
+		case schema.Type == "string" && lower:
+			schema.MinLength = &count
+		case schema.Type == "string":
+			schema.MaxLength = &count
+		case lower:
+			schema.MinItems = &count
+		default:
+			schema.MaxItems = &count
+		}
+	case "integer", "number":
+		switch rule {
+		case "min", "gte":
+			schema.Minimum = &value
+		case "gt":
+			schema.Minimum = &value
+			schema.ExclusiveMinimum = true
+		case "max", "lte":
+			schema.Maximum = &value
+		case "lt":
+			schema.Maximum = &value
+			schema.ExclusiveMaximum = true
+		}
+	}
+}
+
+// enumValue writes a oneof value as the type of the field
+func enumValue(schema *Schema, value string) interface{} {
+	switch schema.Type {
+	case "integer":
+		if number, err := strconv.Atoi(value); err == nil {
+			return number
+		}
+	case "number":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,79 @@
+package openapi
+
+import (
+	"testing"
+)
+
+type testNode struct {
+	Name     string      `json:"name" validate:"required,min=2,max=10"`
+	Kind     int         `json:"kind" validate:"oneof=1 2"`
+	Weight   float64     `json:"weight" validate:"gte=0,lt=100"`
+	Tags     []string    `json:"tags" validate:"max=5,dive,max=20"`
+	Parent   *testNode   `json:"parent"`
+	Children []*testNode `json:"children,omitempty"`
+	Secret   string      `json:"-"`
+	internal string
+}
+
+type testEmbedded struct {
+	testNode
+	Name  string `json:"name"` // the outer field wins
+	Extra string
+}
+
+// TestSchemaOf_ValidateTags verifies validate rules become constraints and required fields
+func TestSchemaOf_ValidateTags(t *testing.T) {
+	schemas := NewSchemas()
+
+	if ref := schemas.SchemaOf(testNode{}); ref.Ref != "#/components/schemas/testNode" {
+		t.Fatalf("Expected a reference, got %+v", ref)
+	}
+
+	node := schemas.Components()["testNode"]
+	if len(node.Properties) != 6 {
+		t.Errorf("Expected six properties, got %d", len(node.Properties))
+	}
+	if len(node.Required) != 1 || node.Required[0] != "name" {
+		t.Errorf("Expected name to be required, got %v", node.Required)
+	}
+
+	name := node.Properties["name"]
+	if *name.MinLength != 2 || *name.MaxLength != 10 {
+		t.Errorf("Unexpected name limits: %+v", name)
+	}
+
+	if kind := node.Properties["kind"]; len(kind.Enum) != 2 || kind.Enum[0] != 1 {
+		t.Errorf("Expected the kinds as numbers, got %v", kind.Enum)
+	}
+
+	weight := node.Properties["weight"]
+	if *weight.Minimum != 0 || weight.ExclusiveMinimum || *weight.Maximum != 100 || !weight.ExclusiveMaximum {
+		t.Errorf("Unexpected weight limits: %+v", weight)
+	}
+
+	tags := node.Properties["tags"]
+	if *tags.MaxItems != 5 || tags.Items.MaxLength == nil || *tags.Items.MaxLength != 20 {
+		t.Errorf("Expected the list and its items to be limited, got %+v %+v", tags, tags.Items)
+	}
+
+	if parent := node.Properties["parent"]; !parent.Nullable || len(parent.AllOf) != 1 || parent.AllOf[0].Ref == "" {
+		t.Errorf("Expected a nullable reference to the node, got %+v", parent)
+	}
+}
+
+// TestSchemaOf_Embedded verifies embedded struct fields are flattened like encoding/json does
+func TestSchemaOf_Embedded(t *testing.T) {
+	schemas := NewSchemas()
+	schemas.SchemaOf(testEmbedded{})
+
+	embedded := schemas.Components()["testEmbedded"]
+	if len(embedded.Properties) != 7 || embedded.Properties["Extra"] == nil {
+		t.Errorf("Unexpected properties: %v", embedded.Properties)
+	}
+	if name := embedded.Properties["name"]; name.MinLength != nil {
+		t.Errorf("Expected the outer name without the embedded rules, got %+v", name)
+	}
+	if len(embedded.Required) != 0 {
+		t.Errorf("Expected no required fields, got %v", embedded.Required)
+	}
+}
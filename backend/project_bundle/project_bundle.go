@@ -0,0 +1,651 @@
+package project_bundle
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_labor_components"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_material_components"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	"github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+const (
+	BUNDLE_FORMAT = "rab-maker-project"
+
+	// BUNDLE_VERSION is bumped on every incompatible change of models.ProjectBundle,
+	// older versions stay importable
+	BUNDLE_VERSION = 1
+
+	DEFAULT_CATEGORY_NAME = "Uncategorized"
+)
+
+type Bundler struct {
+	projectsRepo           projects.Repository
+	workItemsRepo          project_work_items.Repository
+	itemCostsRepo          project_item_costs.Repository
+	categoriesRepo         master_work_categories.Repository
+	materialsRepo          master_materials.Repository
+	laborTypesRepo         master_labor_types.Repository
+	ahspTemplatesRepo      ahsptemplates.Repository
+	materialComponentsRepo ahsp_material_components.Repository
+	laborComponentsRepo    ahsp_labor_components.Repository
+}
+
+func NewBundler(
+	projectsRepo projects.Repository,
+	workItemsRepo project_work_items.Repository,
+	itemCostsRepo project_item_costs.Repository,
+	categoriesRepo master_work_categories.Repository,
+	materialsRepo master_materials.Repository,
+	laborTypesRepo master_labor_types.Repository,
+	ahspTemplatesRepo ahsptemplates.Repository,
+	materialComponentsRepo ahsp_material_components.Repository,
+	laborComponentsRepo ahsp_labor_components.Repository,
+) *Bundler {
+	return &Bundler{
+		projectsRepo:           projectsRepo,
+		workItemsRepo:          workItemsRepo,
+		itemCostsRepo:          itemCostsRepo,
+		categoriesRepo:         categoriesRepo,
+		materialsRepo:          materialsRepo,
+		laborTypesRepo:         laborTypesRepo,
+		ahspTemplatesRepo:      ahspTemplatesRepo,
+		materialComponentsRepo: materialComponentsRepo,
+		laborComponentsRepo:    laborComponentsRepo,
+	}
+}
+
+// Parse decodes a bundle file and checks it is a project bundle this version can read
+func Parse(data []byte) (models.ProjectBundle, error) {
+	var bundle models.ProjectBundle
+
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return bundle, errors.New("the file is not a valid project bundle (invalid JSON)")
+	}
+
+	if err := checkVersion(bundle); err != nil {
+		return bundle, err
+	}
+
+	return bundle, nil
+}
+
+func checkVersion(bundle models.ProjectBundle) error {
+	if bundle.Format != BUNDLE_FORMAT {
+		return errors.New("the file is not a RAB Maker project bundle")
+	}
+	if bundle.Version < 1 || bundle.Version > BUNDLE_VERSION {
+		return fmt.Errorf("project bundle version %d is not supported, this install reads versions 1 to %d", bundle.Version, BUNDLE_VERSION)
+	}
+
+	return nil
+}
+
+// ==========================
+// ========================== EXPORT
+// ==========================
+
+// exportState collects the master data referenced by the project, each entry once
+type exportState struct {
+	bundle     *models.ProjectBundle
+	materials  map[int]bool
+	laborTypes map[int]bool
+	templates  map[int]bool
+}
+
+// Export builds the bundle of a project with the templates, materials and labor types it references.
+// Returns sql.ErrNoRows when the project does not exist.
+func (b *Bundler) Export(ctx context.Context, tx *sql.Tx, projectId int) (models.ProjectBundle, error) {
+	bundle := models.ProjectBundle{
+		Format:     BUNDLE_FORMAT,
+		Version:    BUNDLE_VERSION,
+		ExportedAt: time.Now().Format(time.RFC3339),
+		Materials:  []models.ProjectBundleMaterial{},
+		LaborTypes: []models.ProjectBundleLaborType{},
+		Templates:  []models.ProjectBundleTemplate{},
+		WorkItems:  []models.ProjectBundleWorkItem{},
+	}
+
+	project, err := b.projectsRepo.FindById(ctx, tx, projectId)
+	if err != nil {
+		return bundle, err
+	}
+	if project.ProjectId == 0 {
+		return bundle, sql.ErrNoRows
+	}
+
+	bundle.Project = models.ProjectBundleProject{
+		ProjectName: project.ProjectName,
+		Location:    project.Location,
+		ClientName:  project.ClientName,
+	}
+
+	workItems, err := b.workItemsRepo.FindByProjectIdWithDetails(ctx, tx, projectId)
+	if err != nil {
+		return bundle, err
+	}
+
+	// keep the order the items were entered in, the importer recreates them in bundle order
+	sort.Slice(workItems, func(i, j int) bool {
+		return workItems[i].WorkItemId < workItems[j].WorkItemId
+	})
+
+	costs, err := b.itemCostsRepo.FindByProjectId(ctx, tx, projectId)
+	if err != nil {
+		return bundle, err
+	}
+
+	costsByWorkItem := map[int][]models.ProjectItemCostWithDetails{}
+	for _, cost := range costs {
+		costsByWorkItem[cost.WorkItemId] = append(costsByWorkItem[cost.WorkItemId], cost)
+	}
+
+	state := &exportState{
+		bundle:     &bundle,
+		materials:  map[int]bool{},
+		laborTypes: map[int]bool{},
+		templates:  map[int]bool{},
+	}
+
+	for _, workItem := range workItems {
+		bundleItem := models.ProjectBundleWorkItem{
+			CategoryName: workItem.CategoryName,
+			Description:  workItem.Description,
+			Volume:       workItem.Volume,
+			Unit:         workItem.Unit,
+			Costs:        []models.ProjectBundleCost{},
+		}
+
+		if workItem.AHSPTemplateId != nil {
+			exported, err := b.exportTemplate(ctx, tx, state, *workItem.AHSPTemplateId)
+			if err != nil {
+				return bundle, err
+			}
+			if exported {
+				bundleItem.TemplateId = *workItem.AHSPTemplateId
+			}
+		}
+
+		for _, cost := range costsByWorkItem[workItem.WorkItemId] {
+			bundleCost := models.ProjectBundleCost{
+				ItemType:            cost.ItemType,
+				ItemName:            cost.ItemName,
+				Coefficient:         cost.Coefficient,
+				QuantityNeeded:      cost.QuantityNeeded,
+				Unit:                cost.Unit,
+				UnitPriceAtCreation: cost.UnitPriceAtCreation,
+				TotalCost:           cost.TotalCost,
+			}
+
+			// a cost whose master item was deleted travels as a lump sum line, it keeps name, unit and price
+			if cost.ItemId != 0 {
+				exported, err := b.exportItem(ctx, tx, state, cost.ItemType, cost.ItemId)
+				if err != nil {
+					return bundle, err
+				}
+				if exported {
+					bundleCost.ItemId = cost.ItemId
+				}
+			}
+
+			bundleItem.Costs = append(bundleItem.Costs, bundleCost)
+		}
+
+		bundle.WorkItems = append(bundle.WorkItems, bundleItem)
+	}
+
+	return bundle, nil
+}
+
+// exportTemplate adds a template with its components, false when the template no longer exists
+func (b *Bundler) exportTemplate(ctx context.Context, tx *sql.Tx, state *exportState, templateId int) (bool, error) {
+	if state.templates[templateId] {
+		return true, nil
+	}
+
+	template, err := b.ahspTemplatesRepo.FindById(ctx, tx, templateId)
+	if err != nil {
+		return false, err
+	}
+	if template.TemplateId == 0 {
+		return false, nil
+	}
+
+	bundleTemplate := models.ProjectBundleTemplate{
+		Id:                 template.TemplateId,
+		Code:               template.Code,
+		TemplateName:       template.TemplateName,
+		Unit:               template.Unit,
+		MaterialComponents: []models.ProjectBundleComponent{},
+		LaborComponents:    []models.ProjectBundleComponent{},
+	}
+
+	materialComponents, err := b.materialComponentsRepo.FindByTemplateId(ctx, tx, templateId)
+	if err != nil {
+		return false, err
+	}
+	for _, component := range materialComponents {
+		exported, err := b.exportItem(ctx, tx, state, string(models.PROJECT_ITEM_TYPE_MATERIAL), component.MaterialId)
+		if err != nil {
+			return false, err
+		}
+		if exported {
+			bundleTemplate.MaterialComponents = append(bundleTemplate.MaterialComponents, models.ProjectBundleComponent{
+				ItemId:      component.MaterialId,
+				Coefficient: component.Coefficient,
+			})
+		}
+	}
+
+	laborComponents, err := b.laborComponentsRepo.FindByTemplateId(ctx, tx, templateId)
+	if err != nil {
+		return false, err
+	}
+	for _, component := range laborComponents {
+		exported, err := b.exportItem(ctx, tx, state, string(models.PROJECT_ITEM_TYPE_LABOR), component.LaborTypeId)
+		if err != nil {
+			return false, err
+		}
+		if exported {
+			bundleTemplate.LaborComponents = append(bundleTemplate.LaborComponents, models.ProjectBundleComponent{
+				ItemId:      component.LaborTypeId,
+				Coefficient: component.Coefficient,
+			})
+		}
+	}
+
+	state.templates[templateId] = true
+	state.bundle.Templates = append(state.bundle.Templates, bundleTemplate)
+
+	return true, nil
+}
+
+// exportItem adds a material or labor type, false when it no longer exists
+func (b *Bundler) exportItem(ctx context.Context, tx *sql.Tx, state *exportState, itemType string, itemId int) (bool, error) {
+	if itemType == string(models.PROJECT_ITEM_TYPE_LABOR) {
+		if state.laborTypes[itemId] {
+			return true, nil
+		}
+
+		laborType, err := b.laborTypesRepo.FindById(ctx, tx, itemId)
+		if err != nil {
+			return false, err
+		}
+		if laborType.LaborTypeId == 0 {
+			return false, nil
+		}
+
+		state.laborTypes[itemId] = true
+		state.bundle.LaborTypes = append(state.bundle.LaborTypes, models.ProjectBundleLaborType{
+			Id:               laborType.LaborTypeId,
+			RoleName:         laborType.RoleName,
+			Unit:             laborType.Unit,
+			DefaultDailyWage: laborType.DefaultDailyWage,
+		})
+
+		return true, nil
+	}
+
+	if state.materials[itemId] {
+		return true, nil
+	}
+
+	material, err := b.materialsRepo.FindById(ctx, tx, itemId)
+	if err != nil {
+		return false, err
+	}
+	if material.MaterialId == 0 {
+		return false, nil
+	}
+
+	state.materials[itemId] = true
+	state.bundle.Materials = append(state.bundle.Materials, models.ProjectBundleMaterial{
+		Id:               material.MaterialId,
+		MaterialName:     material.MaterialName,
+		Unit:             material.Unit,
+		DefaultUnitPrice: material.DefaultUnitPrice,
+		IsEquipment:      material.IsEquipment,
+	})
+
+	return true, nil
+}
+
+// ==========================
+// ========================== IMPORT
+// ==========================
+
+// Import creates the bundle as a new project of the workspace.
+// Materials, labor types and templates are matched to the workspace's own or system-wide ones by
+// name and unit (case-insensitive); missing ones are created in the workspace with the bundle prices.
+// Matched masters keep their local prices, the project costs keep the prices captured in the bundle.
+func (b *Bundler) Import(ctx context.Context, tx *sql.Tx, bundle models.ProjectBundle, workspace models.Workspace) (models.ProjectBundleImportResult, error) {
+	var result models.ProjectBundleImportResult
+
+	if err := checkVersion(bundle); err != nil {
+		return result, err
+	}
+
+	projectData := models.ProjectCreate{
+		ProjectName: bundle.Project.ProjectName,
+		Location:    bundle.Project.Location,
+		ClientName:  bundle.Project.ClientName,
+		UserId:      workspace.UserId,
+		OrgId:       workspace.OrgId,
+	}
+	if err := utils.ValidateStruct(projectData); err != nil {
+		return result, fmt.Errorf("invalid project in bundle: %w", err)
+	}
+
+	// bundle ids to local ids
+	materialIds := map[int]int{}
+	laborTypeIds := map[int]int{}
+	templateIds := map[int]int{}
+	categoryIds := map[string]int{}
+
+	for _, material := range bundle.Materials {
+		localId, err := b.resolveMaterial(ctx, tx, material, workspace, &result)
+		if err != nil {
+			return result, err
+		}
+		materialIds[material.Id] = localId
+	}
+
+	for _, laborType := range bundle.LaborTypes {
+		localId, err := b.resolveLaborType(ctx, tx, laborType, workspace, &result)
+		if err != nil {
+			return result, err
+		}
+		laborTypeIds[laborType.Id] = localId
+	}
+
+	for _, template := range bundle.Templates {
+		localId, err := b.resolveTemplate(ctx, tx, template, workspace, materialIds, laborTypeIds, &result)
+		if err != nil {
+			return result, err
+		}
+		templateIds[template.Id] = localId
+	}
+
+	projectId, err := b.projectsRepo.Create(ctx, tx, projectData)
+	if err != nil {
+		return result, err
+	}
+	result.ProjectId = projectId
+
+	for index, workItem := range bundle.WorkItems {
+		categoryId, err := b.resolveCategory(ctx, tx, workItem.CategoryName, workspace, categoryIds, &result)
+		if err != nil {
+			return result, err
+		}
+
+		workItemData := models.ProjectWorkItemCreate{
+			ProjectId:   projectId,
+			CategoryId:  categoryId,
+			Description: workItem.Description,
+			Volume:      workItem.Volume,
+			Unit:        workItem.Unit,
+		}
+
+		if workItem.TemplateId != 0 {
+			templateId, ok := templateIds[workItem.TemplateId]
+			if !ok {
+				return result, fmt.Errorf("work item %d references template %d which is not in the bundle", index+1, workItem.TemplateId)
+			}
+			workItemData.AHSPTemplateId = &templateId
+		}
+
+		if err := utils.ValidateStruct(workItemData); err != nil {
+			return result, fmt.Errorf("invalid work item %d (%s): %w", index+1, workItem.Description, err)
+		}
+
+		workItemId, err := b.workItemsRepo.Create(ctx, tx, workItemData)
+		if err != nil {
+			return result, err
+		}
+
+		var costs []models.ProjectItemCostCreate
+		for _, cost := range workItem.Costs {
+			masterItemId := 0
+
+			if cost.ItemId != 0 {
+				var ok bool
+				switch cost.ItemType {
+				case string(models.PROJECT_ITEM_TYPE_MATERIAL):
+					masterItemId, ok = materialIds[cost.ItemId]
+				case string(models.PROJECT_ITEM_TYPE_LABOR):
+					masterItemId, ok = laborTypeIds[cost.ItemId]
+				}
+				if !ok {
+					return result, fmt.Errorf("work item %d references %s %d which is not in the bundle", index+1, strings.ToLower(cost.ItemType), cost.ItemId)
+				}
+			} else if cost.ItemType != string(models.PROJECT_ITEM_TYPE_MATERIAL) && cost.ItemType != string(models.PROJECT_ITEM_TYPE_LABOR) {
+				return result, fmt.Errorf("work item %d has a cost of unknown type %q", index+1, cost.ItemType)
+			}
+
+			costs = append(costs, models.ProjectItemCostCreate{
+				WorkItemId:          workItemId,
+				ItemType:            cost.ItemType,
+				MasterItemId:        masterItemId,
+				ItemName:            cost.ItemName,
+				Coefficient:         cost.Coefficient,
+				QuantityNeeded:      cost.QuantityNeeded,
+				Unit:                cost.Unit,
+				UnitPriceAtCreation: cost.UnitPriceAtCreation,
+				TotalCost:           cost.TotalCost,
+			})
+		}
+
+		if err := b.itemCostsRepo.CreateMultiple(ctx, tx, costs); err != nil {
+			return result, err
+		}
+
+		result.WorkItems++
+		result.Costs += len(costs)
+	}
+
+	return result, nil
+}
+
+func (b *Bundler) resolveMaterial(ctx context.Context, tx *sql.Tx, material models.ProjectBundleMaterial, workspace models.Workspace, result *models.ProjectBundleImportResult) (int, error) {
+	existing, err := b.materialsRepo.FindByNameAndUnit(ctx, tx, material.MaterialName, material.Unit, workspace)
+	if err == nil {
+		result.MaterialsMatched++
+		return existing.MaterialId, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	// names are unique per owner, a material of the workspace in another unit cannot be created again
+	existing, err = b.materialsRepo.FindByName(ctx, tx, material.MaterialName, workspace)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	if err == nil && (existing.UserId != 0 || existing.OrgId != 0) {
+		return 0, fmt.Errorf("material %s is in the bundle as %s but already exists with unit %s", material.MaterialName, material.Unit, existing.Unit)
+	}
+
+	if material.MaterialName == "" || material.Unit == "" || material.DefaultUnitPrice < 0 {
+		return 0, fmt.Errorf("invalid material %d in bundle", material.Id)
+	}
+
+	if err := b.materialsRepo.Create(ctx, tx, models.MasterMaterialCreate{
+		MaterialName:     material.MaterialName,
+		Unit:             material.Unit,
+		DefaultUnitPrice: material.DefaultUnitPrice,
+		IsEquipment:      material.IsEquipment,
+		UserId:           workspace.OwnerUserId(),
+		OrgId:            workspace.OrgId,
+	}); err != nil {
+		return 0, err
+	}
+
+	existing, err = b.materialsRepo.FindByNameAndUnit(ctx, tx, material.MaterialName, material.Unit, workspace)
+	if err != nil {
+		return 0, err
+	}
+
+	result.MaterialsCreated++
+	return existing.MaterialId, nil
+}
+
+func (b *Bundler) resolveLaborType(ctx context.Context, tx *sql.Tx, laborType models.ProjectBundleLaborType, workspace models.Workspace, result *models.ProjectBundleImportResult) (int, error) {
+	existing, err := b.laborTypesRepo.FindByNameAndUnit(ctx, tx, laborType.RoleName, laborType.Unit, workspace)
+	if err == nil {
+		result.LaborTypesMatched++
+		return existing.LaborTypeId, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	// names are unique per owner, a labor type of the workspace in another unit cannot be created again
+	existing, err = b.laborTypesRepo.FindByName(ctx, tx, laborType.RoleName, workspace)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	if err == nil && (existing.UserId != 0 || existing.OrgId != 0) {
+		return 0, fmt.Errorf("labor type %s is in the bundle as %s but already exists with unit %s", laborType.RoleName, laborType.Unit, existing.Unit)
+	}
+
+	if laborType.RoleName == "" || laborType.Unit == "" || laborType.DefaultDailyWage < 0 {
+		return 0, fmt.Errorf("invalid labor type %d in bundle", laborType.Id)
+	}
+
+	if err := b.laborTypesRepo.Create(ctx, tx, models.MasterLaborTypeCreate{
+		RoleName:         laborType.RoleName,
+		Unit:             laborType.Unit,
+		DefaultDailyWage: laborType.DefaultDailyWage,
+		UserId:           workspace.OwnerUserId(),
+		OrgId:            workspace.OrgId,
+	}); err != nil {
+		return 0, err
+	}
+
+	existing, err = b.laborTypesRepo.FindByNameAndUnit(ctx, tx, laborType.RoleName, laborType.Unit, workspace)
+	if err != nil {
+		return 0, err
+	}
+
+	result.LaborTypesCreated++
+	return existing.LaborTypeId, nil
+}
+
+// resolveTemplate reuses a local template with the same name and unit as is, otherwise the
+// template is created in the workspace with its components pointing to the resolved masters.
+// A template of the workspace with the same name in another unit fails the import.
+func (b *Bundler) resolveTemplate(ctx context.Context, tx *sql.Tx, template models.ProjectBundleTemplate, workspace models.Workspace, materialIds, laborTypeIds map[int]int, result *models.ProjectBundleImportResult) (int, error) {
+	existing, err := b.ahspTemplatesRepo.FindByNameAndUnit(ctx, tx, template.TemplateName, template.Unit, workspace)
+	if err == nil {
+		result.TemplatesMatched++
+		return existing.TemplateId, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	// names are unique per owner, a template of the workspace in another unit cannot be created again
+	existing, err = b.ahspTemplatesRepo.FindByName(ctx, tx, template.TemplateName, workspace)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	if err == nil && (existing.UserId != 0 || existing.OrgId != 0) {
+		return 0, fmt.Errorf("template %s is in the bundle as %s but already exists with unit %s", template.TemplateName, template.Unit, existing.Unit)
+	}
+
+	templateData := models.AHSPTemplateCreate{
+		Code:         template.Code,
+		TemplateName: template.TemplateName,
+		Unit:         template.Unit,
+		UserId:       workspace.OwnerUserId(),
+		OrgId:        workspace.OrgId,
+	}
+	if err := utils.ValidateStruct(templateData); err != nil {
+		return 0, fmt.Errorf("invalid template %d in bundle: %w", template.Id, err)
+	}
+
+	if err := b.ahspTemplatesRepo.Create(ctx, tx, templateData); err != nil {
+		return 0, err
+	}
+
+	existing, err = b.ahspTemplatesRepo.FindByNameAndUnit(ctx, tx, template.TemplateName, template.Unit, workspace)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, component := range template.MaterialComponents {
+		materialId, ok := materialIds[component.ItemId]
+		if !ok {
+			return 0, fmt.Errorf("template %s references material %d which is not in the bundle", template.TemplateName, component.ItemId)
+		}
+
+		if err := b.materialComponentsRepo.Create(ctx, tx, models.AHSPMaterialComponentCreate{
+			TemplateId:  existing.TemplateId,
+			MaterialId:  materialId,
+			Coefficient: component.Coefficient,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, component := range template.LaborComponents {
+		laborTypeId, ok := laborTypeIds[component.ItemId]
+		if !ok {
+			return 0, fmt.Errorf("template %s references labor type %d which is not in the bundle", template.TemplateName, component.ItemId)
+		}
+
+		if err := b.laborComponentsRepo.Create(ctx, tx, models.AHSPLaborComponentCreate{
+			TemplateId:  existing.TemplateId,
+			LaborTypeId: laborTypeId,
+			Coefficient: component.Coefficient,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	result.TemplatesCreated++
+	return existing.TemplateId, nil
+}
+
+func (b *Bundler) resolveCategory(ctx context.Context, tx *sql.Tx, categoryName string, workspace models.Workspace, cache map[string]int, result *models.ProjectBundleImportResult) (int, error) {
+	if strings.TrimSpace(categoryName) == "" {
+		categoryName = DEFAULT_CATEGORY_NAME
+	}
+
+	key := strings.ToLower(categoryName)
+	if categoryId, ok := cache[key]; ok {
+		return categoryId, nil
+	}
+
+	category, err := b.categoriesRepo.FindByName(ctx, tx, categoryName, workspace)
+	if errors.Is(err, sql.ErrNoRows) {
+		category.CategoryId, err = b.categoriesRepo.Create(ctx, tx, models.MasterWorkCategoryCreate{
+			CategoryName: categoryName,
+			UserId:       workspace.OwnerUserId(),
+			OrgId:        workspace.OrgId,
+		})
+		if err == nil {
+			result.CategoriesCreated++
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cache[key] = category.CategoryId
+	return category.CategoryId, nil
+}
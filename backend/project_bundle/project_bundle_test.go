@@ -0,0 +1,380 @@
+package project_bundle
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_labor_components"
+	"github.com/momokii/go-rab-maker/backend/repository/ahsp_material_components"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	"github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	_ "modernc.org/sqlite"
+)
+
+// setupTestDB creates a temporary database for testing
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	tmpDB := t.TempDir() + "/test.db"
+
+	db, err := sql.Open("sqlite", "file:"+tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	// Create test schema
+	_, err = db.Exec(`
+		CREATE TABLE projects (
+			project_id INTEGER PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			org_id INTEGER,
+			project_name TEXT NOT NULL,
+			location TEXT NOT NULL,
+			client_name TEXT NOT NULL,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE master_work_categories (
+			category_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			category_name TEXT NOT NULL,
+			display_order INTEGER DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE master_materials (
+			material_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			material_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			default_unit_price REAL NOT NULL DEFAULT 0,
+			is_equipment INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE master_labor_types (
+			labor_type_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			role_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			default_daily_wage REAL NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE ahsp_templates (
+			template_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			code TEXT,
+			template_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE ahsp_material_components (
+			component_id INTEGER PRIMARY KEY,
+			template_id INTEGER NOT NULL,
+			material_id INTEGER NOT NULL,
+			coefficient REAL NOT NULL,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE ahsp_labor_components (
+			component_id INTEGER PRIMARY KEY,
+			template_id INTEGER NOT NULL,
+			labor_type_id INTEGER NOT NULL,
+			coefficient REAL NOT NULL,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE project_work_items (
+			work_item_id INTEGER PRIMARY KEY,
+			project_id INTEGER NOT NULL,
+			category_id INTEGER,
+			description TEXT NOT NULL,
+			volume REAL NOT NULL,
+			unit TEXT NOT NULL,
+			ahsp_template_id INTEGER,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE project_item_costs (
+			cost_id INTEGER PRIMARY KEY,
+			work_item_id INTEGER NOT NULL,
+			item_type TEXT NOT NULL,
+			master_item_id INTEGER,
+			item_name TEXT NOT NULL,
+			coefficient REAL NOT NULL,
+			quantity_needed REAL NOT NULL,
+			unit TEXT NOT NULL,
+			unit_price_at_creation REAL NOT NULL,
+			total_cost REAL NOT NULL,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE audit_log (
+			audit_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			username TEXT NOT NULL DEFAULT '',
+			entity TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			project_id INTEGER,
+			action TEXT NOT NULL,
+			before_json TEXT NOT NULL DEFAULT '',
+			after_json TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- user 1 owns the project, its cement and the wall template are private
+		INSERT INTO master_work_categories (category_id, user_id, category_name) VALUES (1, 1, 'Pekerjaan Dinding');
+		INSERT INTO master_materials (material_id, user_id, material_name, unit, default_unit_price) VALUES
+			(10, 1, 'Semen Portland', 'kg', 1500),
+			(11, NULL, 'Bata merah', 'bh', 800);
+		INSERT INTO master_labor_types (labor_type_id, user_id, role_name, unit, default_daily_wage) VALUES
+			(20, NULL, 'Tukang batu', 'OH', 150000);
+		INSERT INTO ahsp_templates (template_id, user_id, template_name, unit) VALUES
+			(30, 1, 'Pasangan bata 1:4', 'm2');
+		INSERT INTO ahsp_material_components (template_id, material_id, coefficient) VALUES
+			(30, 10, 10), (30, 11, 70);
+		INSERT INTO ahsp_labor_components (template_id, labor_type_id, coefficient) VALUES
+			(30, 20, 0.2);
+
+		INSERT INTO projects (project_id, user_id, project_name, location, client_name) VALUES
+			(1, 1, 'Rumah Tinggal', 'Bandung', 'Pak Budi');
+		INSERT INTO project_work_items (work_item_id, project_id, category_id, description, volume, unit, ahsp_template_id) VALUES
+			(100, 1, 1, 'Dinding bata', 50, 'm2', 30),
+			(101, 1, 1, 'Mobilisasi', 1, 'ls', NULL);
+		INSERT INTO project_item_costs (work_item_id, item_type, master_item_id, item_name, coefficient, quantity_needed, unit, unit_price_at_creation, total_cost) VALUES
+			(100, 'MATERIAL', 10, 'Semen Portland', 10, 500, 'kg', 1400, 700000),
+			(100, 'MATERIAL', 11, 'Bata merah', 70, 3500, 'bh', 800, 2800000),
+			(100, 'LABOR', 20, 'Tukang batu', 0.2, 10, 'OH', 150000, 1500000),
+			(101, 'MATERIAL', 0, 'Mobilisasi', 1, 1, 'ls', 2000000, 2000000);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+
+	return db
+}
+
+func newTestBundler() *Bundler {
+	return NewBundler(
+		projects.NewProjectsRepo(),
+		project_work_items.NewProjectWorkItemRepo(),
+		project_item_costs.NewProjectItemCostsRepo(),
+		master_work_categories.NewMasterWorkCategoriesRepo(),
+		master_materials.NewMasterMaterialsRepo(),
+		master_labor_types.NewMasterLaborTypesRepo(),
+		ahsptemplates.NewAhspTemplatesRepo(),
+		ahsp_material_components.NewAHSPMaterialComponentsRepo(),
+		ahsp_labor_components.NewAHSPLaborComponentsRepo(),
+	)
+}
+
+// TestExportImport_RemapsIdsForAnotherUser verifies a bundle exported by one user imports for another
+// with new ids, private masters and categories recreated, shared masters reused and the captured prices kept
+func TestExportImport_RemapsIdsForAnotherUser(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	bundler := newTestBundler()
+
+	bundle, err := bundler.Export(ctx, tx, 1)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(bundle.Materials) != 2 || len(bundle.LaborTypes) != 1 || len(bundle.Templates) != 1 || len(bundle.WorkItems) != 2 {
+		t.Fatalf("Unexpected bundle contents: %+v", bundle)
+	}
+
+	// the bundle goes through a file between installs
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	bundle, err = Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	result, err := bundler.Import(ctx, tx, bundle, models.Workspace{UserId: 2})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if result.MaterialsCreated != 1 || result.MaterialsMatched != 1 || result.LaborTypesMatched != 1 || result.TemplatesCreated != 1 {
+		t.Errorf("Unexpected reconciliation: %+v", result)
+	}
+	if result.ProjectId == 1 || result.WorkItems != 2 || result.Costs != 4 {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+
+	// the category of user 1 stays theirs, user 2 gets its own
+	if result.CategoriesCreated != 1 {
+		t.Errorf("Expected the category to be created for user 2, got %+v", result)
+	}
+	var otherUserItems int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM project_work_items WHERE project_id = ? AND category_id = 1", result.ProjectId).Scan(&otherUserItems); err != nil || otherUserItems != 0 {
+		t.Errorf("Expected no work items in the category of user 1, got %d (%v)", otherUserItems, err)
+	}
+
+	// user 2 now has its own cement and template, the template points at the new cement
+	var cementId, templateId int
+	if err := tx.QueryRow("SELECT material_id FROM master_materials WHERE user_id = 2 AND material_name = 'Semen Portland'").Scan(&cementId); err != nil {
+		t.Fatalf("Cement was not created for user 2: %v", err)
+	}
+	if err := tx.QueryRow("SELECT template_id FROM ahsp_templates WHERE user_id = 2").Scan(&templateId); err != nil {
+		t.Fatalf("Template was not created for user 2: %v", err)
+	}
+
+	var componentCount int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM ahsp_material_components WHERE template_id = ? AND material_id IN (?, 11)", templateId, cementId).Scan(&componentCount); err != nil || componentCount != 2 {
+		t.Errorf("Expected the new template to use the new cement and the shared bricks, got %d (%v)", componentCount, err)
+	}
+
+	var workItemTemplateId int
+	if err := tx.QueryRow("SELECT ahsp_template_id FROM project_work_items WHERE project_id = ? AND description = 'Dinding bata'", result.ProjectId).Scan(&workItemTemplateId); err != nil || workItemTemplateId != templateId {
+		t.Errorf("Expected work item to reference template %d, got %d (%v)", templateId, workItemTemplateId, err)
+	}
+
+	// captured prices and lump sum lines survive the trip
+	var cementPrice float64
+	if err := tx.QueryRow(`
+		SELECT pic.unit_price_at_creation FROM project_item_costs pic
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
+		WHERE pwi.project_id = ? AND pic.master_item_id = ?`, result.ProjectId, cementId).Scan(&cementPrice); err != nil || cementPrice != 1400 {
+		t.Errorf("Expected captured cement price 1400, got %v (%v)", cementPrice, err)
+	}
+
+	var lumpSums int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM project_item_costs pic
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
+		WHERE pwi.project_id = ? AND pic.master_item_id = 0`, result.ProjectId).Scan(&lumpSums); err != nil || lumpSums != 1 {
+		t.Errorf("Expected 1 lump sum line, got %d (%v)", lumpSums, err)
+	}
+
+	// importing again for the same user reuses everything it created the first time
+	again, err := bundler.Import(ctx, tx, bundle, models.Workspace{UserId: 2})
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if again.MaterialsCreated != 0 || again.TemplatesCreated != 0 || again.TemplatesMatched != 1 || again.CategoriesCreated != 0 {
+		t.Errorf("Expected second import to only match masters, got %+v", again)
+	}
+}
+
+// TestParse_RejectsUnknownBundles verifies foreign files and newer versions are refused
+func TestParse_RejectsUnknownBundles(t *testing.T) {
+	cases := map[string]string{
+		"not json":      "hello",
+		"other format":  `{"format": "something-else", "version": 1}`,
+		"newer version": `{"format": "rab-maker-project", "version": 99}`,
+	}
+
+	for name, data := range cases {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := Parse([]byte(`{"format": "rab-maker-project", "version": 99}`)); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("Expected the version in the error, got %v", err)
+	}
+}
+
+// TestImport_MissingReference verifies a bundle pointing at an entry it does not contain is rejected
+func TestImport_MissingReference(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	bundle := models.ProjectBundle{
+		Format:  BUNDLE_FORMAT,
+		Version: BUNDLE_VERSION,
+		Project: models.ProjectBundleProject{ProjectName: "Gudang", Location: "Bekasi", ClientName: "PT Maju"},
+		WorkItems: []models.ProjectBundleWorkItem{{
+			CategoryName: "Persiapan",
+			Description:  "Urugan",
+			Volume:       1,
+			Unit:         "m3",
+			Costs:        []models.ProjectBundleCost{{ItemType: "MATERIAL", ItemId: 42, ItemName: "Pasir", QuantityNeeded: 1, Unit: "m3"}},
+		}},
+	}
+
+	if _, err := newTestBundler().Import(ctx, tx, bundle, models.Workspace{UserId: 2}); err == nil || !strings.Contains(err.Error(), "material 42") {
+		t.Errorf("Expected missing material error, got %v", err)
+	}
+}
+
+// TestImport_NameInAnotherUnit verifies a material the user already has under the same name in
+// another unit fails the import with the unit clash instead of the unique name constraint
+func TestImport_NameInAnotherUnit(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec("INSERT INTO master_materials (material_id, user_id, material_name, unit, default_unit_price) VALUES (12, 2, 'Semen Portland', 'zak', 65000)"); err != nil {
+		t.Fatalf("Failed to insert material: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	bundler := newTestBundler()
+
+	bundle, err := bundler.Export(ctx, tx, 1)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	_, err = bundler.Import(ctx, tx, bundle, models.Workspace{UserId: 2})
+	if err == nil || !strings.Contains(err.Error(), "already exists with unit zak") {
+		t.Errorf("Expected the unit clash in the error, got %v", err)
+	}
+}
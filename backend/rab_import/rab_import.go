@@ -0,0 +1,356 @@
+package rab_import
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	master_work_categories "github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+const (
+	ACTION_ITEM    = "item"
+	ACTION_SECTION = "section"
+	ACTION_SKIPPED = "skipped"
+	ACTION_ERROR   = "error"
+)
+
+var (
+	// section numbering such as "A.", "III.", "2)" or "1.2." in front of a category name
+	sectionNumberPattern = regexp.MustCompile(`^\s*(?:[A-Z]|[IVXLC]+|\d+(?:\.\d+)*)[.)]\s+`)
+
+	// summary rows at the end of a section or sheet
+	summaryPrefixes = []string{"jumlah", "sub total", "subtotal", "total", "grand total", "ppn", "pajak", "dibulatkan", "terbilang"}
+
+	// header keywords (English and Indonesian) used to guess the column mapping
+	totalKeywords       = []string{"jumlah", "total"}
+	unitPriceKeywords   = []string{"price", "harga", "rate", "biaya"}
+	unitKeywords        = []string{"satuan", "unit", "sat"}
+	volumeKeywords      = []string{"volume", "vol", "qty", "quantity", "kuantitas"}
+	categoryKeywords    = []string{"category", "kategori", "divisi", "bab", "section"}
+	descriptionKeywords = []string{"uraian", "description", "pekerjaan", "item", "nama", "name"}
+)
+
+// TemplateCostFunc creates the cost lines of a work item from an AHSP template
+type TemplateCostFunc func(ctx context.Context, tx *sql.Tx, templateId int, volume float64, workItemId int) error
+
+// GuessMapping picks the first header matching each field, -1 when nothing matches
+func GuessMapping(headers []string) models.RabImportColumnMapping {
+	mapping := models.RabImportColumnMapping{Description: -1, Volume: -1, Unit: -1, UnitPrice: -1, Category: -1}
+
+	for i, header := range headers {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+
+		switch {
+		case containsAny(header, totalKeywords):
+			// "Jumlah Harga" is the line total, not the unit price
+		case mapping.UnitPrice == -1 && containsAny(header, unitPriceKeywords):
+			mapping.UnitPrice = i
+		case mapping.Unit == -1 && containsAny(header, unitKeywords):
+			mapping.Unit = i
+		case mapping.Volume == -1 && containsAny(header, volumeKeywords):
+			mapping.Volume = i
+		case mapping.Category == -1 && containsAny(header, categoryKeywords):
+			mapping.Category = i
+		case mapping.Description == -1 && containsAny(header, descriptionKeywords):
+			mapping.Description = i
+		}
+	}
+
+	return mapping
+}
+
+func containsAny(value string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(value, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+type Importer struct {
+	categoriesRepo master_work_categories.Repository
+	templatesRepo  ahsptemplates.Repository
+	workItemsRepo  project_work_items.Repository
+	itemCostsRepo  project_item_costs.Repository
+}
+
+func NewImporter(
+	categoriesRepo master_work_categories.Repository,
+	templatesRepo ahsptemplates.Repository,
+	workItemsRepo project_work_items.Repository,
+	itemCostsRepo project_item_costs.Repository,
+) *Importer {
+	return &Importer{
+		categoriesRepo: categoriesRepo,
+		templatesRepo:  templatesRepo,
+		workItemsRepo:  workItemsRepo,
+		itemCostsRepo:  itemCostsRepo,
+	}
+}
+
+// Preview classifies every data row (the first row is the header).
+// A row with a description but no volume and no unit price is a section header and becomes the
+// category of the rows below it, unless a category column is mapped. Summary rows ("Jumlah", "Total")
+// are skipped. With matchTemplates, items are matched to AHSP templates by name and unit.
+func (i *Importer) Preview(ctx context.Context, tx *sql.Tx, rows [][]string, mapping models.RabImportColumnMapping, matchTemplates bool, workspace models.Workspace) (models.RabImportPreview, error) {
+	var preview models.RabImportPreview
+
+	if mapping.Description < 0 || mapping.Volume < 0 || mapping.Unit < 0 || mapping.UnitPrice < 0 {
+		return preview, errors.New("description, volume, unit and unit price columns must all be mapped")
+	}
+
+	currentSection := ""
+	knownCategories := map[string]bool{}
+
+	for rowIdx := 1; rowIdx < len(rows); rowIdx++ {
+		row := rows[rowIdx]
+
+		previewRow := models.RabImportPreviewRow{
+			Line:        rowIdx + 1,
+			Description: strings.TrimSpace(cell(row, mapping.Description)),
+			Unit:        strings.TrimSpace(cell(row, mapping.Unit)),
+			Category:    strings.TrimSpace(cell(row, mapping.Category)),
+		}
+		if previewRow.Description == "" {
+			continue
+		}
+
+		volumeStr := strings.TrimSpace(cell(row, mapping.Volume))
+		unitPriceStr := strings.TrimSpace(cell(row, mapping.UnitPrice))
+
+		if isSummaryRow(previewRow.Description) {
+			previewRow.Action = ACTION_SKIPPED
+			previewRow.Message = "Summary row"
+			addRow(&preview, previewRow)
+			continue
+		}
+
+		if volumeStr == "" && unitPriceStr == "" {
+			currentSection = sectionNumberPattern.ReplaceAllString(previewRow.Description, "")
+			previewRow.Category = currentSection
+			previewRow.Action = ACTION_SECTION
+			addRow(&preview, previewRow)
+			continue
+		}
+
+		if previewRow.Category == "" {
+			previewRow.Category = currentSection
+		}
+
+		if err := i.fillItem(ctx, tx, &previewRow, volumeStr, unitPriceStr, matchTemplates, workspace); err != nil {
+			if !isRowError(err) {
+				return preview, err
+			}
+			previewRow.Action = ACTION_ERROR
+			previewRow.Message = err.Error()
+			addRow(&preview, previewRow)
+			continue
+		}
+
+		// flag categories that will be created on import
+		categoryKey := strings.ToLower(previewRow.Category)
+		if known, ok := knownCategories[categoryKey]; ok {
+			previewRow.NewCategory = !known
+		} else {
+			_, err := i.categoriesRepo.FindByName(ctx, tx, previewRow.Category, workspace)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return preview, err
+			}
+			previewRow.NewCategory = err != nil
+			knownCategories[categoryKey] = err == nil
+		}
+
+		addRow(&preview, previewRow)
+	}
+
+	return preview, nil
+}
+
+// rowError is a problem with the row itself, reported in the preview instead of failing it
+type rowError struct{ message string }
+
+func (e rowError) Error() string { return e.message }
+
+func isRowError(err error) bool {
+	var target rowError
+	return errors.As(err, &target)
+}
+
+func (i *Importer) fillItem(ctx context.Context, tx *sql.Tx, previewRow *models.RabImportPreviewRow, volumeStr, unitPriceStr string, matchTemplates bool, workspace models.Workspace) error {
+	volume, err := utils.ParseNumber(volumeStr)
+	if err != nil {
+		return rowError{"Invalid volume: " + err.Error()}
+	}
+	previewRow.Volume = volume
+
+	if previewRow.Category == "" {
+		return rowError{"No category, map a category column or add a section header row above it"}
+	}
+
+	// validate with the same rules as the work item form
+	if err := utils.ValidateStruct(models.ProjectWorkItemCreate{
+		ProjectId:   1,
+		CategoryId:  1,
+		Description: previewRow.Description,
+		Volume:      previewRow.Volume,
+		Unit:        previewRow.Unit,
+	}); err != nil {
+		return rowError{strings.Join(utils.GetValidationErrors(err), "; ")}
+	}
+
+	if matchTemplates {
+		template, err := i.templatesRepo.FindByNameAndUnit(ctx, tx, previewRow.Description, previewRow.Unit, workspace)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil {
+			previewRow.TemplateId = template.TemplateId
+			previewRow.TemplateName = template.TemplateName
+		}
+	}
+
+	unitPrice, err := utils.ParseNumber(unitPriceStr)
+	if err != nil || unitPrice <= 0 {
+		if previewRow.TemplateId != 0 {
+			// costs come from the template, the sheet price is not needed
+			return nil
+		}
+		return rowError{"Unit price must be a number greater than 0"}
+	}
+
+	previewRow.UnitPrice = unitPrice
+	previewRow.Total = unitPrice * volume
+
+	return nil
+}
+
+// Commit creates the work items of a preview in the project, creating missing categories on the way.
+// Matched rows get their costs from templateCosts, the others a single lump-sum cost line.
+func (i *Importer) Commit(ctx context.Context, tx *sql.Tx, projectId int, preview models.RabImportPreview, workspace models.Workspace, templateCosts TemplateCostFunc) error {
+	categoryIds := map[string]int{}
+
+	for _, row := range preview.Rows {
+		if row.Action != ACTION_ITEM {
+			continue
+		}
+
+		categoryId, err := i.resolveCategory(ctx, tx, row.Category, workspace, categoryIds)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", row.Line, err)
+		}
+
+		workItem := models.ProjectWorkItemCreate{
+			ProjectId:   projectId,
+			CategoryId:  categoryId,
+			Description: row.Description,
+			Volume:      row.Volume,
+			Unit:        row.Unit,
+		}
+		if row.TemplateId != 0 {
+			templateId := row.TemplateId
+			workItem.AHSPTemplateId = &templateId
+		}
+
+		workItemId, err := i.workItemsRepo.Create(ctx, tx, workItem)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", row.Line, err)
+		}
+
+		if row.TemplateId != 0 {
+			if err := templateCosts(ctx, tx, row.TemplateId, row.Volume, workItemId); err != nil {
+				return fmt.Errorf("row %d: %w", row.Line, err)
+			}
+			continue
+		}
+
+		if err := i.itemCostsRepo.Create(ctx, tx, models.ProjectItemCostCreate{
+			WorkItemId:          workItemId,
+			ItemType:            string(models.PROJECT_ITEM_TYPE_MATERIAL),
+			MasterItemId:        0, // lump sum, not linked to a master item
+			ItemName:            row.Description,
+			Coefficient:         1,
+			QuantityNeeded:      row.Volume,
+			Unit:                row.Unit,
+			UnitPriceAtCreation: row.UnitPrice,
+			TotalCost:           row.Total,
+		}); err != nil {
+			return fmt.Errorf("row %d: %w", row.Line, err)
+		}
+	}
+
+	return nil
+}
+
+func (i *Importer) resolveCategory(ctx context.Context, tx *sql.Tx, categoryName string, workspace models.Workspace, cache map[string]int) (int, error) {
+	key := strings.ToLower(categoryName)
+	if categoryId, ok := cache[key]; ok {
+		return categoryId, nil
+	}
+
+	category, err := i.categoriesRepo.FindByName(ctx, tx, categoryName, workspace)
+	if errors.Is(err, sql.ErrNoRows) {
+		category.CategoryId, err = i.categoriesRepo.Create(ctx, tx, models.MasterWorkCategoryCreate{
+			CategoryName: categoryName,
+			UserId:       workspace.OwnerUserId(),
+			OrgId:        workspace.OrgId,
+		})
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cache[key] = category.CategoryId
+	return category.CategoryId, nil
+}
+
+func addRow(preview *models.RabImportPreview, row models.RabImportPreviewRow) {
+	if row.Action == "" {
+		row.Action = ACTION_ITEM
+	}
+
+	switch row.Action {
+	case ACTION_ITEM:
+		preview.Items++
+		if row.TemplateId != 0 {
+			preview.MatchedTemplates++
+		} else {
+			preview.LumpSumTotal += row.Total
+		}
+	case ACTION_SECTION:
+		preview.Sections++
+	case ACTION_ERROR:
+		preview.Errors++
+	}
+
+	preview.Rows = append(preview.Rows, row)
+}
+
+func isSummaryRow(description string) bool {
+	description = strings.ToLower(description)
+	for _, prefix := range summaryPrefixes {
+		if strings.HasPrefix(description, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func cell(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
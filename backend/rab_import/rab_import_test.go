@@ -0,0 +1,369 @@
+package rab_import
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	master_work_categories "github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	_ "modernc.org/sqlite"
+)
+
+// setupTestDB creates a temporary database for testing
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	tmpDB := t.TempDir() + "/test.db"
+
+	db, err := sql.Open("sqlite", "file:"+tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	// Create test schema
+	_, err = db.Exec(`
+		CREATE TABLE users (
+			user_id INTEGER PRIMARY KEY,
+			username TEXT NOT NULL
+		);
+
+		CREATE TABLE projects (
+			project_id INTEGER PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			org_id INTEGER,
+			project_name TEXT NOT NULL,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE master_work_categories (
+			category_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			category_name TEXT NOT NULL,
+			display_order INTEGER DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE ahsp_templates (
+			template_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			org_id INTEGER,
+			code TEXT,
+			template_name TEXT NOT NULL,
+			unit TEXT NOT NULL,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE project_work_items (
+			work_item_id INTEGER PRIMARY KEY,
+			project_id INTEGER NOT NULL,
+			category_id INTEGER,
+			description TEXT NOT NULL,
+			volume REAL NOT NULL,
+			unit TEXT NOT NULL,
+			ahsp_template_id INTEGER,
+			created_at TEXT,
+			updated_at TEXT,
+			deleted_at TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE project_item_costs (
+			cost_id INTEGER PRIMARY KEY,
+			work_item_id INTEGER NOT NULL,
+			item_type TEXT NOT NULL,
+			master_item_id INTEGER,
+			item_name TEXT NOT NULL,
+			coefficient REAL NOT NULL,
+			quantity_needed REAL NOT NULL,
+			unit TEXT NOT NULL,
+			unit_price_at_creation REAL NOT NULL,
+			total_cost REAL NOT NULL,
+			created_at TEXT,
+			updated_at TEXT
+		);
+
+		CREATE TABLE audit_log (
+			audit_id INTEGER PRIMARY KEY,
+			user_id INTEGER,
+			username TEXT NOT NULL DEFAULT '',
+			entity TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			project_id INTEGER,
+			action TEXT NOT NULL,
+			before_json TEXT NOT NULL DEFAULT '',
+			after_json TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		INSERT INTO users (user_id, username) VALUES (1, 'estimator');
+		INSERT INTO projects (project_id, user_id, project_name) VALUES (1, 1, 'Rumah Tinggal');
+		INSERT INTO master_work_categories (category_id, user_id, category_name) VALUES (1, 1, 'Pekerjaan Persiapan');
+		INSERT INTO ahsp_templates (template_id, user_id, template_name, unit) VALUES
+			(1, NULL, 'Pasangan dinding bata merah', 'm2');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+
+	return db
+}
+
+func newTestImporter() *Importer {
+	return NewImporter(
+		master_work_categories.NewMasterWorkCategoriesRepo(),
+		ahsptemplates.NewAhspTemplatesRepo(),
+		project_work_items.NewProjectWorkItemRepo(),
+		project_item_costs.NewProjectItemCostsRepo(),
+	)
+}
+
+func testSheet() [][]string {
+	return [][]string{
+		{"No", "Uraian Pekerjaan", "Volume", "Satuan", "Harga Satuan", "Jumlah Harga"},
+		{"A.", "Pekerjaan Persiapan", "", "", "", ""},
+		{"1", "Pembersihan lahan", "120", "m2", "Rp 15.000", "1.800.000"},
+		{"2", "Bouwplank", "40,5", "m'", "35000", "1.417.500"},
+		{"", "Jumlah A", "", "", "", "3.217.500"},
+		{"B.", "Pekerjaan Dinding", "", "", "", ""},
+		{"1", "Pasangan dinding bata merah", "85", "m2", "", ""},
+		{"2", "Plesteran", "abc", "m2", "45000", ""},
+		{"3", "Acian", "170", "m2", "", ""},
+	}
+}
+
+func TestGuessMapping(t *testing.T) {
+	tests := []struct {
+		headers  []string
+		expected models.RabImportColumnMapping
+	}{
+		{
+			[]string{"No", "Uraian Pekerjaan", "Volume", "Satuan", "Harga Satuan", "Jumlah Harga"},
+			models.RabImportColumnMapping{Description: 1, Volume: 2, Unit: 3, UnitPrice: 4, Category: -1},
+		},
+		{
+			[]string{"Category", "Description", "Qty", "Unit", "Unit Price", "Total Price"},
+			models.RabImportColumnMapping{Description: 1, Volume: 2, Unit: 3, UnitPrice: 4, Category: 0},
+		},
+		{
+			[]string{"A", "B"},
+			models.RabImportColumnMapping{Description: -1, Volume: -1, Unit: -1, UnitPrice: -1, Category: -1},
+		},
+	}
+
+	for _, test := range tests {
+		if got := GuessMapping(test.headers); got != test.expected {
+			t.Errorf("GuessMapping(%v) = %+v, expected %+v", test.headers, got, test.expected)
+		}
+	}
+}
+
+// TestPreview_ClassifiesRows verifies sections, items, summary rows, template matches and errors
+func TestPreview_ClassifiesRows(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows := testSheet()
+	preview, err := newTestImporter().Preview(ctx, tx, rows, GuessMapping(rows[0]), true, models.Workspace{UserId: 1})
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	if preview.Items != 3 || preview.Sections != 2 || preview.MatchedTemplates != 1 || preview.Errors != 2 {
+		t.Errorf("Expected 3 items, 2 sections, 1 match and 2 errors, got %+v", preview)
+	}
+
+	expected := map[int]string{
+		2: ACTION_SECTION,
+		3: ACTION_ITEM,
+		4: ACTION_ITEM,
+		5: ACTION_SKIPPED,
+		6: ACTION_SECTION,
+		7: ACTION_ITEM,
+		8: ACTION_ERROR,
+		9: ACTION_ERROR,
+	}
+	for _, row := range preview.Rows {
+		if row.Action != expected[row.Line] {
+			t.Errorf("Row %d: expected %s, got %s (%s)", row.Line, expected[row.Line], row.Action, row.Message)
+		}
+	}
+
+	first := preview.Rows[1]
+	if first.Category != "Pekerjaan Persiapan" || first.NewCategory || first.Total != 1800000 {
+		t.Errorf("Unexpected first item: %+v", first)
+	}
+
+	wall := preview.Rows[5]
+	if wall.Category != "Pekerjaan Dinding" || !wall.NewCategory || wall.TemplateId != 1 {
+		t.Errorf("Expected the wall row in a new category and matched to template 1, got %+v", wall)
+	}
+
+	if preview.LumpSumTotal != 1800000+40.5*35000 {
+		t.Errorf("Expected lump sum total %v, got %v", 1800000+40.5*35000, preview.LumpSumTotal)
+	}
+}
+
+// TestPreview_RequiresCategory verifies items without a section or category column are rejected
+func TestPreview_RequiresCategory(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows := [][]string{
+		{"Description", "Volume", "Unit", "Unit Price"},
+		{"Pembersihan lahan", "120", "m2", "15000"},
+	}
+
+	preview, err := newTestImporter().Preview(ctx, tx, rows, GuessMapping(rows[0]), false, models.Workspace{UserId: 1})
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	if preview.Errors != 1 || preview.Items != 0 {
+		t.Errorf("Expected the row to be rejected, got %+v", preview)
+	}
+}
+
+// TestCommit_CreatesWorkItems verifies work items, lump-sum costs and missing categories are created
+func TestCommit_CreatesWorkItems(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+
+	importer := newTestImporter()
+	rows := testSheet()
+
+	preview, err := importer.Preview(ctx, tx, rows, GuessMapping(rows[0]), true, models.Workspace{UserId: 1})
+	if err != nil {
+		tx.Rollback()
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	var templateCalls []int
+	templateCosts := func(ctx context.Context, tx *sql.Tx, templateId int, volume float64, workItemId int) error {
+		templateCalls = append(templateCalls, templateId)
+		return nil
+	}
+
+	if err := importer.Commit(ctx, tx, 1, preview, models.Workspace{UserId: 1}, templateCosts); err != nil {
+		tx.Rollback()
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	var workItems, costs, categories int
+	db.QueryRow("SELECT COUNT(*) FROM project_work_items WHERE project_id = 1").Scan(&workItems)
+	db.QueryRow("SELECT COUNT(*) FROM project_item_costs").Scan(&costs)
+	db.QueryRow("SELECT COUNT(*) FROM master_work_categories").Scan(&categories)
+
+	if workItems != 3 {
+		t.Errorf("Expected 3 work items, got %d", workItems)
+	}
+	if costs != 2 {
+		t.Errorf("Expected 2 lump-sum cost lines, got %d", costs)
+	}
+	if categories != 2 {
+		t.Errorf("Expected the missing category to be created once, got %d categories", categories)
+	}
+	if len(templateCalls) != 1 || templateCalls[0] != 1 {
+		t.Errorf("Expected template costs for template 1 once, got %v", templateCalls)
+	}
+
+	var templateId sql.NullInt64
+	db.QueryRow("SELECT ahsp_template_id FROM project_work_items WHERE description = 'Pasangan dinding bata merah'").Scan(&templateId)
+	if !templateId.Valid || templateId.Int64 != 1 {
+		t.Errorf("Expected matched work item to reference template 1, got %v", templateId)
+	}
+
+	var total float64
+	db.QueryRow("SELECT total_cost FROM project_item_costs WHERE item_name = 'Bouwplank'").Scan(&total)
+	if total != 40.5*35000 {
+		t.Errorf("Expected lump-sum total %v, got %v", 40.5*35000, total)
+	}
+}
+
+// TestCommit_CategoriesOfTheWorkspace verifies another user's category with the same name is
+// neither offered in the preview nor used, the importing workspace gets its own
+func TestCommit_CategoriesOfTheWorkspace(t *testing.T) {
+	ctx := t.Context()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		INSERT INTO users (user_id, username) VALUES (2, 'sari');
+		INSERT INTO projects (project_id, user_id, project_name) VALUES (2, 2, 'Gudang');
+	`); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+
+	importer := newTestImporter()
+	rows := testSheet()[:4]
+	workspace := models.Workspace{UserId: 2}
+
+	preview, err := importer.Preview(ctx, tx, rows, GuessMapping(rows[0]), false, workspace)
+	if err != nil {
+		tx.Rollback()
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if first := preview.Rows[1]; first.Category != "Pekerjaan Persiapan" || !first.NewCategory {
+		t.Errorf("Expected the category to be new in the workspace, got %+v", first)
+	}
+
+	if err := importer.Commit(ctx, tx, 2, preview, workspace, nil); err != nil {
+		tx.Rollback()
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	var otherUserItems, ownCategories int
+	db.QueryRow("SELECT COUNT(*) FROM project_work_items WHERE project_id = 2 AND category_id = 1").Scan(&otherUserItems)
+	db.QueryRow("SELECT COUNT(*) FROM master_work_categories WHERE user_id = 2 AND category_name = 'Pekerjaan Persiapan'").Scan(&ownCategories)
+
+	if otherUserItems != 0 {
+		t.Errorf("Expected no work items in the other user's category, got %d", otherUserItems)
+	}
+	if ownCategories != 1 {
+		t.Errorf("Expected the category to be created in the workspace, got %d", ownCategories)
+	}
+}
@@ -1,11 +1,26 @@
 package ahsp_labor_components
 
 import (
+	"context"
 	"database/sql"
 
+	"github.com/momokii/go-rab-maker/backend/audit"
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores the labor components of AHSP templates
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, ahspLaborComponentId int) (models.AHSPLaborComponent, error)
+	FindByTemplateId(ctx context.Context, tx *sql.Tx, templateId int) ([]models.AHSPLaborComponent, error)
+	FindByTemplateIdWithLaborInfo(ctx context.Context, tx *sql.Tx, templateId int) ([]models.AHSPLaborComponentWithLabor, error)
+	Create(ctx context.Context, tx *sql.Tx, componentData models.AHSPLaborComponentCreate) error
+	Update(ctx context.Context, tx *sql.Tx, componentId int, componentData models.AHSPLaborComponentUpdate) error
+	Delete(ctx context.Context, tx *sql.Tx, componentId int) error
+	DeleteByTemplateId(ctx context.Context, tx *sql.Tx, templateId int) error
+}
+
+var _ Repository = (*AHSPLaborComponentsRepo)(nil)
+
 type AHSPLaborComponentsRepo struct{}
 
 func NewAHSPLaborComponentsRepo() *AHSPLaborComponentsRepo {
@@ -13,12 +28,12 @@ func NewAHSPLaborComponentsRepo() *AHSPLaborComponentsRepo {
 }
 
 // FindById retrieves an AHSP labor component by ID
-func (r *AHSPLaborComponentsRepo) FindById(tx *sql.Tx, ahspLaborComponentId int) (models.AHSPLaborComponent, error) {
+func (r *AHSPLaborComponentsRepo) FindById(ctx context.Context, tx *sql.Tx, ahspLaborComponentId int) (models.AHSPLaborComponent, error) {
 	var component models.AHSPLaborComponent
 
 	query := "SELECT component_id, template_id, labor_type_id, coefficient, created_at, updated_at FROM ahsp_labor_components WHERE component_id = ?"
 
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query,
 		ahspLaborComponentId,
 	).Scan(
@@ -36,12 +51,12 @@ func (r *AHSPLaborComponentsRepo) FindById(tx *sql.Tx, ahspLaborComponentId int)
 }
 
 // FindByTemplateId retrieves all AHSP labor components for a template
-func (r *AHSPLaborComponentsRepo) FindByTemplateId(tx *sql.Tx, templateId int) ([]models.AHSPLaborComponent, error) {
+func (r *AHSPLaborComponentsRepo) FindByTemplateId(ctx context.Context, tx *sql.Tx, templateId int) ([]models.AHSPLaborComponent, error) {
 	var components []models.AHSPLaborComponent
 
 	query := "SELECT component_id, template_id, labor_type_id, coefficient, created_at, updated_at FROM ahsp_labor_components WHERE template_id = ? ORDER BY component_id"
 
-	rows, err := tx.Query(query, templateId)
+	rows, err := tx.QueryContext(ctx, query, templateId)
 	if err != nil {
 		return components, err
 	}
@@ -73,7 +88,7 @@ func (r *AHSPLaborComponentsRepo) FindByTemplateId(tx *sql.Tx, templateId int) (
 }
 
 // FindByTemplateIdWithLaborInfo retrieves all AHSP labor components for a template with labor type information
-func (r *AHSPLaborComponentsRepo) FindByTemplateIdWithLaborInfo(tx *sql.Tx, templateId int) ([]models.AHSPLaborComponentWithLabor, error) {
+func (r *AHSPLaborComponentsRepo) FindByTemplateIdWithLaborInfo(ctx context.Context, tx *sql.Tx, templateId int) ([]models.AHSPLaborComponentWithLabor, error) {
 	var components []models.AHSPLaborComponentWithLabor
 
 	query := `SELECT
@@ -91,7 +106,7 @@ func (r *AHSPLaborComponentsRepo) FindByTemplateIdWithLaborInfo(tx *sql.Tx, temp
 			  WHERE alc.template_id = ?
 			  ORDER BY alc.component_id`
 
-	rows, err := tx.Query(query, templateId)
+	rows, err := tx.QueryContext(ctx, query, templateId)
 	if err != nil {
 		return components, err
 	}
@@ -126,24 +141,31 @@ func (r *AHSPLaborComponentsRepo) FindByTemplateIdWithLaborInfo(tx *sql.Tx, temp
 }
 
 // Create creates a new AHSP labor component
-func (r *AHSPLaborComponentsRepo) Create(tx *sql.Tx, componentData models.AHSPLaborComponentCreate) error {
-	query := "INSERT INTO ahsp_labor_components (template_id, labor_type_id, coefficient) VALUES (?, ?, ?)"
-	if _, err := tx.Exec(
+func (r *AHSPLaborComponentsRepo) Create(ctx context.Context, tx *sql.Tx, componentData models.AHSPLaborComponentCreate) error {
+	query := "INSERT INTO ahsp_labor_components (template_id, labor_type_id, coefficient) VALUES (?, ?, ?) RETURNING component_id"
+
+	var componentId int
+	if err := tx.QueryRowContext(ctx,
 		query,
 		componentData.TemplateId,
 		componentData.LaborTypeId,
 		componentData.Coefficient,
-	); err != nil {
+	).Scan(&componentId); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Created(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, componentId)
 }
 
 // Update updates an existing AHSP labor component
-func (r *AHSPLaborComponentsRepo) Update(tx *sql.Tx, componentId int, componentData models.AHSPLaborComponentUpdate) error {
+func (r *AHSPLaborComponentsRepo) Update(ctx context.Context, tx *sql.Tx, componentId int, componentData models.AHSPLaborComponentUpdate) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, componentId)
+	if err != nil {
+		return err
+	}
+
 	query := "UPDATE ahsp_labor_components SET labor_type_id = ?, coefficient = ? WHERE component_id = ?"
-	if _, err := tx.Exec(
+	if _, err := tx.ExecContext(ctx,
 		query,
 		componentData.LaborTypeId,
 		componentData.Coefficient,
@@ -152,25 +174,35 @@ func (r *AHSPLaborComponentsRepo) Update(tx *sql.Tx, componentId int, componentD
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, before)
 }
 
 // Delete deletes an AHSP labor component
-func (r *AHSPLaborComponentsRepo) Delete(tx *sql.Tx, componentId int) error {
+func (r *AHSPLaborComponentsRepo) Delete(ctx context.Context, tx *sql.Tx, componentId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, componentId)
+	if err != nil {
+		return err
+	}
+
 	query := "DELETE FROM ahsp_labor_components WHERE component_id = ?"
-	if _, err := tx.Exec(query, componentId); err != nil {
+	if _, err := tx.ExecContext(ctx, query, componentId); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, before)
 }
 
 // DeleteByTemplateId deletes all AHSP labor components for a template
-func (r *AHSPLaborComponentsRepo) DeleteByTemplateId(tx *sql.Tx, templateId int) error {
+func (r *AHSPLaborComponentsRepo) DeleteByTemplateId(ctx context.Context, tx *sql.Tx, templateId int) error {
+	before, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, "t.template_id = ?", templateId)
+	if err != nil {
+		return err
+	}
+
 	query := "DELETE FROM ahsp_labor_components WHERE template_id = ?"
-	if _, err := tx.Exec(query, templateId); err != nil {
+	if _, err := tx.ExecContext(ctx, query, templateId); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, before...)
 }
@@ -1,11 +1,26 @@
 package ahsp_material_components
 
 import (
+	"context"
 	"database/sql"
 
+	"github.com/momokii/go-rab-maker/backend/audit"
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores the material components of AHSP templates
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, ahspMaterialComponentId int) (models.AHSPMaterialComponent, error)
+	FindByTemplateId(ctx context.Context, tx *sql.Tx, templateId int) ([]models.AHSPMaterialComponent, error)
+	FindByTemplateIdWithMaterialInfo(ctx context.Context, tx *sql.Tx, templateId int) ([]models.AHSPMaterialComponentWithMaterial, error)
+	Create(ctx context.Context, tx *sql.Tx, componentData models.AHSPMaterialComponentCreate) error
+	Update(ctx context.Context, tx *sql.Tx, componentId int, componentData models.AHSPMaterialComponentUpdate) error
+	Delete(ctx context.Context, tx *sql.Tx, componentId int) error
+	DeleteByTemplateId(ctx context.Context, tx *sql.Tx, templateId int) error
+}
+
+var _ Repository = (*AHSPMaterialComponentsRepo)(nil)
+
 type AHSPMaterialComponentsRepo struct{}
 
 func NewAHSPMaterialComponentsRepo() *AHSPMaterialComponentsRepo {
@@ -13,12 +28,12 @@ func NewAHSPMaterialComponentsRepo() *AHSPMaterialComponentsRepo {
 }
 
 // FindById retrieves an AHSP material component by ID
-func (r *AHSPMaterialComponentsRepo) FindById(tx *sql.Tx, ahspMaterialComponentId int) (models.AHSPMaterialComponent, error) {
+func (r *AHSPMaterialComponentsRepo) FindById(ctx context.Context, tx *sql.Tx, ahspMaterialComponentId int) (models.AHSPMaterialComponent, error) {
 	var component models.AHSPMaterialComponent
 
 	query := "SELECT component_id, template_id, material_id, coefficient, created_at, updated_at FROM ahsp_material_components WHERE component_id = ?"
 
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query,
 		ahspMaterialComponentId,
 	).Scan(
@@ -36,12 +51,12 @@ func (r *AHSPMaterialComponentsRepo) FindById(tx *sql.Tx, ahspMaterialComponentI
 }
 
 // FindByTemplateId retrieves all AHSP material components for a template
-func (r *AHSPMaterialComponentsRepo) FindByTemplateId(tx *sql.Tx, templateId int) ([]models.AHSPMaterialComponent, error) {
+func (r *AHSPMaterialComponentsRepo) FindByTemplateId(ctx context.Context, tx *sql.Tx, templateId int) ([]models.AHSPMaterialComponent, error) {
 	var components []models.AHSPMaterialComponent
 
 	query := "SELECT component_id, template_id, material_id, coefficient, created_at, updated_at FROM ahsp_material_components WHERE template_id = ? ORDER BY component_id"
 
-	rows, err := tx.Query(query, templateId)
+	rows, err := tx.QueryContext(ctx, query, templateId)
 	if err != nil {
 		return components, err
 	}
@@ -73,7 +88,7 @@ func (r *AHSPMaterialComponentsRepo) FindByTemplateId(tx *sql.Tx, templateId int
 }
 
 // FindByTemplateIdWithMaterialInfo retrieves all AHSP material components for a template with material information
-func (r *AHSPMaterialComponentsRepo) FindByTemplateIdWithMaterialInfo(tx *sql.Tx, templateId int) ([]models.AHSPMaterialComponentWithMaterial, error) {
+func (r *AHSPMaterialComponentsRepo) FindByTemplateIdWithMaterialInfo(ctx context.Context, tx *sql.Tx, templateId int) ([]models.AHSPMaterialComponentWithMaterial, error) {
 	var components []models.AHSPMaterialComponentWithMaterial
 
 	query := `SELECT
@@ -85,13 +100,14 @@ func (r *AHSPMaterialComponentsRepo) FindByTemplateIdWithMaterialInfo(tx *sql.Tx
 				amc.updated_at,
 				mm.material_name,
 				mm.unit,
-				mm.default_unit_price
+				mm.default_unit_price,
+				COALESCE(mm.is_equipment, 0)
 			  FROM ahsp_material_components amc
 			  LEFT JOIN master_materials mm ON amc.material_id = mm.material_id
 			  WHERE amc.template_id = ?
 			  ORDER BY amc.component_id`
 
-	rows, err := tx.Query(query, templateId)
+	rows, err := tx.QueryContext(ctx, query, templateId)
 	if err != nil {
 		return components, err
 	}
@@ -110,6 +126,7 @@ func (r *AHSPMaterialComponentsRepo) FindByTemplateIdWithMaterialInfo(tx *sql.Tx
 			&component.MaterialName,
 			&component.MaterialUnit,
 			&component.MaterialPrice,
+			&component.IsEquipment,
 		); err != nil {
 			return components, err
 		} else {
@@ -126,24 +143,31 @@ func (r *AHSPMaterialComponentsRepo) FindByTemplateIdWithMaterialInfo(tx *sql.Tx
 }
 
 // Create creates a new AHSP material component
-func (r *AHSPMaterialComponentsRepo) Create(tx *sql.Tx, componentData models.AHSPMaterialComponentCreate) error {
-	query := "INSERT INTO ahsp_material_components (template_id, material_id, coefficient) VALUES (?, ?, ?)"
-	if _, err := tx.Exec(
+func (r *AHSPMaterialComponentsRepo) Create(ctx context.Context, tx *sql.Tx, componentData models.AHSPMaterialComponentCreate) error {
+	query := "INSERT INTO ahsp_material_components (template_id, material_id, coefficient) VALUES (?, ?, ?) RETURNING component_id"
+
+	var componentId int
+	if err := tx.QueryRowContext(ctx,
 		query,
 		componentData.TemplateId,
 		componentData.MaterialId,
 		componentData.Coefficient,
-	); err != nil {
+	).Scan(&componentId); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Created(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, componentId)
 }
 
 // Update updates an existing AHSP material component
-func (r *AHSPMaterialComponentsRepo) Update(tx *sql.Tx, componentId int, componentData models.AHSPMaterialComponentUpdate) error {
+func (r *AHSPMaterialComponentsRepo) Update(ctx context.Context, tx *sql.Tx, componentId int, componentData models.AHSPMaterialComponentUpdate) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, componentId)
+	if err != nil {
+		return err
+	}
+
 	query := "UPDATE ahsp_material_components SET material_id = ?, coefficient = ? WHERE component_id = ?"
-	if _, err := tx.Exec(
+	if _, err := tx.ExecContext(ctx,
 		query,
 		componentData.MaterialId,
 		componentData.Coefficient,
@@ -152,25 +176,35 @@ func (r *AHSPMaterialComponentsRepo) Update(tx *sql.Tx, componentId int, compone
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, before)
 }
 
 // Delete deletes an AHSP material component
-func (r *AHSPMaterialComponentsRepo) Delete(tx *sql.Tx, componentId int) error {
+func (r *AHSPMaterialComponentsRepo) Delete(ctx context.Context, tx *sql.Tx, componentId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, componentId)
+	if err != nil {
+		return err
+	}
+
 	query := "DELETE FROM ahsp_material_components WHERE component_id = ?"
-	if _, err := tx.Exec(query, componentId); err != nil {
+	if _, err := tx.ExecContext(ctx, query, componentId); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, before)
 }
 
 // DeleteByTemplateId deletes all AHSP material components for a template
-func (r *AHSPMaterialComponentsRepo) DeleteByTemplateId(tx *sql.Tx, templateId int) error {
+func (r *AHSPMaterialComponentsRepo) DeleteByTemplateId(ctx context.Context, tx *sql.Tx, templateId int) error {
+	before, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, "t.template_id = ?", templateId)
+	if err != nil {
+		return err
+	}
+
 	query := "DELETE FROM ahsp_material_components WHERE template_id = ?"
-	if _, err := tx.Exec(query, templateId); err != nil {
+	if _, err := tx.ExecContext(ctx, query, templateId); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, before...)
 }
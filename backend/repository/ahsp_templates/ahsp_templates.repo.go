@@ -1,12 +1,37 @@
 package ahsptemplates
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"math"
 
+	"github.com/momokii/go-rab-maker/backend/audit"
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores AHSP templates, the unit price analyses reused across projects
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, ahspTemplateId int) (models.AHSPTemplate, error)
+	FindByCode(ctx context.Context, tx *sql.Tx, code string, workspace models.Workspace) (models.AHSPTemplate, error)
+	FindByName(ctx context.Context, tx *sql.Tx, templateName string, workspace models.Workspace) (models.AHSPTemplate, error)
+	FindByNameAndUnit(ctx context.Context, tx *sql.Tx, templateName, unit string, workspace models.Workspace) (models.AHSPTemplate, error)
+	Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput, workspace models.Workspace) ([]models.AHSPTemplate, models.PaginationInfo, error)
+	FindAll(ctx context.Context, tx *sql.Tx, workspace models.Workspace, search string) ([]models.AHSPTemplate, error)
+	Create(ctx context.Context, tx *sql.Tx, templateData models.AHSPTemplateCreate) error
+	Update(ctx context.Context, tx *sql.Tx, templateData models.AHSPTemplate) error
+	Delete(ctx context.Context, tx *sql.Tx, templateData models.AHSPTemplate) error
+	Restore(ctx context.Context, tx *sql.Tx, templateId int) error
+	Purge(ctx context.Context, tx *sql.Tx, templateId int) error
+}
+
+// ErrTemplateInUse is returned when deleting a template that work items, in the trash or
+// not, still use
+var ErrTemplateInUse = errors.New("cannot delete template")
+
+var _ Repository = (*AhspTemplatesRepo)(nil)
+
 type AhspTemplatesRepo struct{}
 
 func NewAhspTemplatesRepo() *AhspTemplatesRepo {
@@ -14,18 +39,20 @@ func NewAhspTemplatesRepo() *AhspTemplatesRepo {
 }
 
 // FindById retrieves an AHSP template by ID
-func (r *AhspTemplatesRepo) FindById(tx *sql.Tx, ahspTemplateId int) (models.AHSPTemplate, error) {
+func (r *AhspTemplatesRepo) FindById(ctx context.Context, tx *sql.Tx, ahspTemplateId int) (models.AHSPTemplate, error) {
 	var template models.AHSPTemplate
-	var userId sql.NullInt64
+	var userId, orgId sql.NullInt64
 
-	query := "SELECT template_id, user_id, template_name, unit, created_at, updated_at FROM ahsp_templates WHERE template_id = ?"
+	query := "SELECT template_id, user_id, org_id, COALESCE(code, ''), template_name, unit, created_at, updated_at FROM ahsp_templates WHERE template_id = ? AND deleted_at IS NULL"
 
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query,
 		ahspTemplateId,
 	).Scan(
 		&template.TemplateId,
 		&userId,
+		&orgId,
+		&template.Code,
 		&template.TemplateName,
 		&template.Unit,
 		&template.CreatedAt,
@@ -40,12 +67,111 @@ func (r *AhspTemplatesRepo) FindById(tx *sql.Tx, ahspTemplateId int) (models.AHS
 	} else {
 		template.UserId = 0
 	}
+	template.OrgId = int(orgId.Int64)
+
+	return template, nil
+}
+
+// FindByCode retrieves the AHSP template with the given official code owned by the workspace,
+// the system-wide defaults are not searched unless it is the zero workspace.
+// Returns sql.ErrNoRows when nothing matches.
+func (r *AhspTemplatesRepo) FindByCode(ctx context.Context, tx *sql.Tx, code string, workspace models.Workspace) (models.AHSPTemplate, error) {
+	var template models.AHSPTemplate
+	var templateUserId, templateOrgId sql.NullInt64
+
+	params := []interface{}{code}
+	ownerCondition := "user_id IS NULL AND org_id IS NULL"
+	if workspace.OrgId != 0 {
+		ownerCondition = "org_id = ?"
+		params = append(params, workspace.OrgId)
+	} else if workspace.UserId != 0 {
+		ownerCondition = "user_id = ? AND org_id IS NULL"
+		params = append(params, workspace.UserId)
+	}
+
+	query := `
+		SELECT template_id, user_id, org_id, code, template_name, unit, created_at, updated_at
+		FROM ahsp_templates
+		WHERE code = ? AND deleted_at IS NULL AND ` + ownerCondition + `
+		ORDER BY template_id
+		LIMIT 1`
+	if err := tx.QueryRowContext(ctx,
+		query,
+		params...,
+	).Scan(
+		&template.TemplateId,
+		&templateUserId,
+		&templateOrgId,
+		&template.Code,
+		&template.TemplateName,
+		&template.Unit,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	); err != nil {
+		return template, err
+	}
+
+	if templateUserId.Valid {
+		template.UserId = int(templateUserId.Int64)
+	}
+	template.OrgId = int(templateOrgId.Int64)
+
+	return template, nil
+}
+
+// FindByNameAndUnit looks up an AHSP template by exact name and unit (case-insensitive).
+// A template of the workspace wins over a system-wide default with the same name and unit.
+// Returns sql.ErrNoRows when nothing matches.
+func (r *AhspTemplatesRepo) FindByNameAndUnit(ctx context.Context, tx *sql.Tx, templateName, unit string, workspace models.Workspace) (models.AHSPTemplate, error) {
+	return r.findFirst(ctx, tx, "LOWER(template_name) = LOWER(?) AND LOWER(unit) = LOWER(?)", []interface{}{templateName, unit}, workspace)
+}
+
+// FindByName looks up an AHSP template by exact name (case-insensitive) whatever its unit, the
+// names of a user's templates are unique. A template of the workspace wins over a system-wide
+// default with the same name. Returns sql.ErrNoRows when nothing matches.
+func (r *AhspTemplatesRepo) FindByName(ctx context.Context, tx *sql.Tx, templateName string, workspace models.Workspace) (models.AHSPTemplate, error) {
+	return r.findFirst(ctx, tx, "LOWER(template_name) = LOWER(?)", []interface{}{templateName}, workspace)
+}
+
+// findFirst returns the first template of the workspace or the system-wide defaults matching the condition
+func (r *AhspTemplatesRepo) findFirst(ctx context.Context, tx *sql.Tx, match string, matchArgs []interface{}, workspace models.Workspace) (models.AHSPTemplate, error) {
+	var template models.AHSPTemplate
+	var templateUserId, templateOrgId sql.NullInt64
+
+	condition, args := workspace.MasterDataCondition("")
+	query := `
+		SELECT template_id, user_id, org_id, COALESCE(code, ''), template_name, unit, created_at, updated_at
+		FROM ahsp_templates
+		WHERE ` + match + `
+			AND deleted_at IS NULL AND ` + condition + `
+		ORDER BY user_id IS NULL AND org_id IS NULL, template_id
+		LIMIT 1`
+	if err := tx.QueryRowContext(ctx,
+		query,
+		append(matchArgs, args...)...,
+	).Scan(
+		&template.TemplateId,
+		&templateUserId,
+		&templateOrgId,
+		&template.Code,
+		&template.TemplateName,
+		&template.Unit,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	); err != nil {
+		return template, err
+	}
+
+	if templateUserId.Valid {
+		template.UserId = int(templateUserId.Int64)
+	}
+	template.OrgId = int(templateOrgId.Int64)
 
 	return template, nil
 }
 
 // Find retrieves AHSP templates with pagination and search
-func (r *AhspTemplatesRepo) Find(tx *sql.Tx, paginationInput models.TablePaginationDataInput, user_id int) ([]models.AHSPTemplate, models.PaginationInfo, error) {
+func (r *AhspTemplatesRepo) Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput, workspace models.Workspace) ([]models.AHSPTemplate, models.PaginationInfo, error) {
 	var templates []models.AHSPTemplate
 	var paginationData models.PaginationInfo
 	var totalData int
@@ -54,25 +180,25 @@ func (r *AhspTemplatesRepo) Find(tx *sql.Tx, paginationInput models.TablePaginat
 	offset := (paginationInput.Page - 1) * paginationInput.PerPage
 
 	params := []interface{}{}
-	base_query := "SELECT template_id, user_id, template_name, unit, created_at, updated_at FROM ahsp_templates WHERE 1=1"
-	query_total := "SELECT COUNT(template_id) FROM ahsp_templates WHERE 1=1"
+	base_query := "SELECT template_id, user_id, org_id, COALESCE(code, ''), template_name, unit, created_at, updated_at FROM ahsp_templates WHERE deleted_at IS NULL"
+	query_total := "SELECT COUNT(template_id) FROM ahsp_templates WHERE deleted_at IS NULL"
 
 	// if using search data
 	if paginationInput.Search != "" {
-		base_query += " AND template_name LIKE ?"
-		query_total += " AND template_name LIKE ?"
-		params = append(params, "%"+paginationInput.Search+"%")
+		base_query += " AND (LOWER(template_name) LIKE LOWER(?) OR LOWER(code) LIKE LOWER(?))"
+		query_total += " AND (LOWER(template_name) LIKE LOWER(?) OR LOWER(code) LIKE LOWER(?))"
+		searchTerm := "%" + paginationInput.Search + "%"
+		params = append(params, searchTerm, searchTerm)
 	}
 
-	if user_id != 0 {
-		// Include both user-specific items and system-wide defaults (user_id IS NULL)
-		base_query += " AND (user_id = ? OR user_id IS NULL)"
-		query_total += " AND (user_id = ? OR user_id IS NULL)"
-		params = append(params, user_id)
-	}
+	// Include both the workspace's items and system-wide defaults
+	condition, args := workspace.MasterDataCondition("")
+	base_query += " AND " + condition
+	query_total += " AND " + condition
+	params = append(params, args...)
 
 	// get total data
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query_total,
 		params...,
 	).Scan(&totalData); err != nil {
@@ -83,18 +209,20 @@ func (r *AhspTemplatesRepo) Find(tx *sql.Tx, paginationInput models.TablePaginat
 	base_query += " ORDER BY template_id LIMIT ? OFFSET ?"
 	params = append(params, paginationInput.PerPage, offset)
 
-	rows, err := tx.Query(base_query, params...)
+	rows, err := tx.QueryContext(ctx, base_query, params...)
 	if err != nil {
 		return templates, paginationData, err
 	}
 
 	for rows.Next() {
 		var template models.AHSPTemplate
-		var userId sql.NullInt64
+		var userId, orgId sql.NullInt64
 
 		if err := rows.Scan(
 			&template.TemplateId,
 			&userId,
+			&orgId,
+			&template.Code,
 			&template.TemplateName,
 			&template.Unit,
 			&template.CreatedAt,
@@ -109,6 +237,7 @@ func (r *AhspTemplatesRepo) Find(tx *sql.Tx, paginationInput models.TablePaginat
 		} else {
 			template.UserId = 0
 		}
+		template.OrgId = int(orgId.Int64)
 
 		templates = append(templates, template)
 	}
@@ -129,74 +258,280 @@ func (r *AhspTemplatesRepo) Find(tx *sql.Tx, paginationInput models.TablePaginat
 	return templates, paginationData, nil
 }
 
-// Create creates a new AHSP template
-func (r *AhspTemplatesRepo) Create(tx *sql.Tx, templateData models.AHSPTemplateCreate) error {
-	query := "INSERT INTO ahsp_templates (user_id, template_name, unit) VALUES (?, ?, ?)"
-	if _, err := tx.Exec(
+// FindAll retrieves every template visible in the workspace (its own and system-wide), optionally
+// filtered by name or code, ordered by code then name
+func (r *AhspTemplatesRepo) FindAll(ctx context.Context, tx *sql.Tx, workspace models.Workspace, search string) ([]models.AHSPTemplate, error) {
+	var templates []models.AHSPTemplate
+
+	condition, params := workspace.MasterDataCondition("")
+	query := "SELECT template_id, user_id, org_id, COALESCE(code, ''), template_name, unit, created_at, updated_at FROM ahsp_templates WHERE deleted_at IS NULL AND " + condition
+
+	if search != "" {
+		query += " AND (LOWER(template_name) LIKE LOWER(?) OR LOWER(code) LIKE LOWER(?))"
+		searchTerm := "%" + search + "%"
+		params = append(params, searchTerm, searchTerm)
+	}
+
+	query += " ORDER BY COALESCE(code, '') = '', code, template_name"
+
+	rows, err := tx.QueryContext(ctx, query, params...)
+	if err != nil {
+		return templates, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var template models.AHSPTemplate
+		var templateUserId, templateOrgId sql.NullInt64
+
+		if err := rows.Scan(
+			&template.TemplateId,
+			&templateUserId,
+			&templateOrgId,
+			&template.Code,
+			&template.TemplateName,
+			&template.Unit,
+			&template.CreatedAt,
+			&template.UpdatedAt,
+		); err != nil {
+			return templates, err
+		}
+
+		if templateUserId.Valid {
+			template.UserId = int(templateUserId.Int64)
+		}
+		template.OrgId = int(templateOrgId.Int64)
+
+		templates = append(templates, template)
+	}
+
+	// if data nil, just return array
+	if len(templates) == 0 {
+		return []models.AHSPTemplate{}, nil
+	}
+
+	return templates, nil
+}
+
+// Create creates a new AHSP template. A template of the user in the trash keeps its name taken,
+// creating the name again brings that template back, emptied of its old components.
+func (r *AhspTemplatesRepo) Create(ctx context.Context, tx *sql.Tx, templateData models.AHSPTemplateCreate) error {
+	trashedId, err := findTrashed(ctx, tx, templateData.UserId, templateData.TemplateName)
+	if err != nil {
+		return err
+	}
+	if trashedId != 0 {
+		return r.revive(ctx, tx, trashedId, templateData)
+	}
+
+	query := "INSERT INTO ahsp_templates (user_id, org_id, code, template_name, unit) VALUES (?, ?, ?, ?, ?) RETURNING template_id"
+
+	var templateId int
+	if err := tx.QueryRowContext(ctx,
 		query,
-		templateData.UserId,
+		models.NullableUserId(templateData.UserId),
+		models.NullableOrgId(templateData.OrgId),
+		sql.NullString{String: templateData.Code, Valid: templateData.Code != ""},
 		templateData.TemplateName,
 		templateData.Unit,
+	).Scan(&templateId); err != nil {
+		return err
+	}
+
+	return audit.Created(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, templateId)
+}
+
+// findTrashed returns the ID of the template of the user in the trash with the name, 0 when
+// there is none. Organization and system-wide templates have no user_id, their names never clash.
+func findTrashed(ctx context.Context, tx *sql.Tx, userId int, templateName string) (int, error) {
+	if userId == 0 {
+		return 0, nil
+	}
+
+	var templateId int
+	query := "SELECT template_id FROM ahsp_templates WHERE user_id = ? AND template_name = ? AND deleted_at IS NOT NULL"
+	if err := tx.QueryRowContext(ctx, query, userId, templateName).Scan(&templateId); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	return templateId, nil
+}
+
+// revive takes a template out of the trash with the details it is created again with. The
+// components it was deleted with are removed, a created template starts without any.
+func (r *AhspTemplatesRepo) revive(ctx context.Context, tx *sql.Tx, templateId int, templateData models.AHSPTemplateCreate) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, templateId)
+	if err != nil {
+		return err
+	}
+
+	materialsBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, "t.template_id = ?", templateId)
+	if err != nil {
+		return err
+	}
+
+	laborBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, "t.template_id = ?", templateId)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM ahsp_material_components WHERE template_id = ?", templateId); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM ahsp_labor_components WHERE template_id = ?", templateId); err != nil {
+		return err
+	}
+
+	now := models.UTCNow()
+	query := "UPDATE ahsp_templates SET org_id = ?, code = ?, unit = ?, created_at = ?, updated_at = ?, deleted_at = NULL WHERE template_id = ?"
+	if _, err := tx.ExecContext(ctx,
+		query,
+		models.NullableOrgId(templateData.OrgId),
+		sql.NullString{String: templateData.Code, Valid: templateData.Code != ""},
+		templateData.Unit,
+		now,
+		now,
+		templateId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, materialsBefore...); err != nil {
+		return err
+	}
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, laborBefore...); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, before)
 }
 
 // Update updates an existing AHSP template
-func (r *AhspTemplatesRepo) Update(tx *sql.Tx, templateData models.AHSPTemplate) error {
-	query := "UPDATE ahsp_templates SET template_name = ?, unit = ? WHERE template_id = ? AND user_id = ?"
-	if _, err := tx.Exec(
+func (r *AhspTemplatesRepo) Update(ctx context.Context, tx *sql.Tx, templateData models.AHSPTemplate) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, templateData.TemplateId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE ahsp_templates SET code = ?, template_name = ?, unit = ? WHERE template_id = ? AND COALESCE(user_id, 0) = ? AND COALESCE(org_id, 0) = ?"
+	if _, err := tx.ExecContext(ctx,
 		query,
+		sql.NullString{String: templateData.Code, Valid: templateData.Code != ""},
 		templateData.TemplateName,
 		templateData.Unit,
 		templateData.TemplateId,
 		templateData.UserId,
+		templateData.OrgId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, before)
 }
 
-// Delete deletes an AHSP template and its associated components.
-// IMPORTANT: Blocks deletion if the template is currently used in any work items
-// to maintain data integrity and prevent breaking existing project estimates.
-func (r *AhspTemplatesRepo) Delete(tx *sql.Tx, templateData models.AHSPTemplate) error {
-	// VALIDATION: Check if template is used in any work items
-	var usageCount int
-	checkQuery := `
-		SELECT COUNT(*)
-		FROM project_work_items
-		WHERE ahsp_template_id = ?`
-	err := tx.QueryRow(checkQuery, templateData.TemplateId).Scan(&usageCount)
+// Delete moves an AHSP template to the trash, its components are kept for when it is restored.
+// IMPORTANT: Blocks deletion if the template is used in any work items, those in the trash
+// included, to maintain data integrity and prevent breaking existing project estimates.
+func (r *AhspTemplatesRepo) Delete(ctx context.Context, tx *sql.Tx, templateData models.AHSPTemplate) error {
+	if err := checkTemplateUnused(ctx, tx, templateData.TemplateId); err != nil {
+		return err
+	}
+
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, templateData.TemplateId)
 	if err != nil {
 		return err
 	}
 
-	// Block deletion if template is in use
-	if usageCount > 0 {
-		return sql.ErrTxDone // Use a recognizable error for "used" case
+	query := "UPDATE ahsp_templates SET deleted_at = ? WHERE template_id = ? AND deleted_at IS NULL"
+	if _, err := tx.ExecContext(ctx, query, models.UTCNow(), templateData.TemplateId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, before)
+}
+
+// Restore takes an AHSP template out of the trash
+func (r *AhspTemplatesRepo) Restore(ctx context.Context, tx *sql.Tx, templateId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, templateId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE ahsp_templates SET deleted_at = NULL WHERE template_id = ?"
+	if _, err := tx.ExecContext(ctx, query, templateId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, before)
+}
+
+// Purge deletes an AHSP template and its associated components for good, blocked like Delete
+// while work items use it
+func (r *AhspTemplatesRepo) Purge(ctx context.Context, tx *sql.Tx, templateId int) error {
+	if err := checkTemplateUnused(ctx, tx, templateId); err != nil {
+		return err
+	}
+
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, templateId)
+	if err != nil {
+		return err
+	}
+
+	materialsBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, "t.template_id = ?", templateId)
+	if err != nil {
+		return err
+	}
+
+	laborBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, "t.template_id = ?", templateId)
+	if err != nil {
+		return err
 	}
 
 	// Delete related material components
 	query_delete_material := "DELETE FROM ahsp_material_components WHERE template_id = ?"
-	if _, err := tx.Exec(query_delete_material, templateData.TemplateId); err != nil {
+	if _, err := tx.ExecContext(ctx, query_delete_material, templateId); err != nil {
 		return err
 	}
 
 	// Delete related labor components
 	query_delete_labor := "DELETE FROM ahsp_labor_components WHERE template_id = ?"
-	if _, err := tx.Exec(query_delete_labor, templateData.TemplateId); err != nil {
+	if _, err := tx.ExecContext(ctx, query_delete_labor, templateId); err != nil {
 		return err
 	}
 
 	// Finally, delete the template
 	query := "DELETE FROM ahsp_templates WHERE template_id = ?"
-	if _, err := tx.Exec(query, templateData.TemplateId); err != nil {
+	if _, err := tx.ExecContext(ctx, query, templateId); err != nil {
+		return err
+	}
+
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, materialsBefore...); err != nil {
+		return err
+	}
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, laborBefore...); err != nil {
 		return err
 	}
 
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_TEMPLATE, before)
+}
+
+// checkTemplateUnused refuses to delete a template used in any work items
+func checkTemplateUnused(ctx context.Context, tx *sql.Tx, templateId int) error {
+	// VALIDATION: Check if template is used in any work items
+	var usageCount int
+	checkQuery := `
+		SELECT COUNT(*)
+		FROM project_work_items
+		WHERE ahsp_template_id = ?`
+	err := tx.QueryRowContext(ctx, checkQuery, templateId).Scan(&usageCount)
+	if err != nil {
+		return err
+	}
+
+	// Block deletion if template is in use
+	if usageCount > 0 {
+		return fmt.Errorf("%w: used in %d work items", ErrTemplateInUse, usageCount)
+	}
+
 	return nil
 }
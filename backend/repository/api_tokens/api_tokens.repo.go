@@ -0,0 +1,117 @@
+package api_tokens
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// Repository stores personal access tokens
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, tokenId int) (models.APIToken, error)
+	FindByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.APIToken, error)
+	FindByHash(ctx context.Context, tx *sql.Tx, tokenHash string) (models.APIToken, error)
+	Create(ctx context.Context, tx *sql.Tx, tokenData models.APITokenCreate) (int, error)
+	Revoke(ctx context.Context, tx *sql.Tx, tokenId int) error
+	UpdateLastUsed(ctx context.Context, tx *sql.Tx, tokenId int) error
+}
+
+var _ Repository = (*APITokensRepo)(nil)
+
+type APITokensRepo struct{}
+
+func NewAPITokensRepo() *APITokensRepo {
+	return &APITokensRepo{}
+}
+
+const selectAPITokenColumns = `SELECT t.token_id, t.user_id, t.token_name, t.token_prefix, t.token_hash, t.scope,
+	COALESCE(t.expires_at, ''), COALESCE(t.last_used_at, ''), COALESCE(t.revoked_at, ''), t.created_at
+	FROM api_tokens t`
+
+func scanAPIToken(row interface{ Scan(dest ...any) error }) (models.APIToken, error) {
+	var token models.APIToken
+	err := row.Scan(
+		&token.TokenId,
+		&token.UserId,
+		&token.TokenName,
+		&token.TokenPrefix,
+		&token.TokenHash,
+		&token.Scope,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+
+	return token, err
+}
+
+// FindById retrieves a token by its ID
+func (r *APITokensRepo) FindById(ctx context.Context, tx *sql.Tx, tokenId int) (models.APIToken, error) {
+	return scanAPIToken(tx.QueryRowContext(ctx, selectAPITokenColumns+" WHERE t.token_id = ?", tokenId))
+}
+
+// FindByUserId retrieves every token of a user, newest first, including revoked and expired ones
+func (r *APITokensRepo) FindByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.APIToken, error) {
+	rows, err := tx.QueryContext(ctx, selectAPITokenColumns+" WHERE t.user_id = ? ORDER BY t.token_id DESC", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// FindByHash retrieves the token with the hash when its user is still active (not soft deleted).
+// Revoked and expired tokens are returned too, the caller decides with IsActive.
+func (r *APITokensRepo) FindByHash(ctx context.Context, tx *sql.Tx, tokenHash string) (models.APIToken, error) {
+	query := selectAPITokenColumns + " JOIN users u ON u.user_id = t.user_id WHERE t.token_hash = ? AND u.deleted_at IS NULL"
+	return scanAPIToken(tx.QueryRowContext(ctx, query, tokenHash))
+}
+
+// Create stores a new token and returns its ID, an empty ExpiresAt is stored as NULL
+func (r *APITokensRepo) Create(ctx context.Context, tx *sql.Tx, tokenData models.APITokenCreate) (int, error) {
+	query := "INSERT INTO api_tokens (user_id, token_name, token_prefix, token_hash, scope, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING token_id"
+
+	expiresAt := sql.NullString{String: tokenData.ExpiresAt, Valid: tokenData.ExpiresAt != ""}
+
+	var tokenId int
+	if err := tx.QueryRowContext(ctx,
+		query,
+		tokenData.UserId,
+		tokenData.TokenName,
+		tokenData.TokenPrefix,
+		tokenData.TokenHash,
+		tokenData.Scope,
+		expiresAt,
+		models.UTCNow(),
+	).Scan(&tokenId); err != nil {
+		return 0, err
+	}
+
+	return tokenId, nil
+}
+
+// Revoke marks a token as revoked, a token that is already revoked keeps its first revocation time
+func (r *APITokensRepo) Revoke(ctx context.Context, tx *sql.Tx, tokenId int) error {
+	query := "UPDATE api_tokens SET revoked_at = ? WHERE token_id = ? AND revoked_at IS NULL"
+	_, err := tx.ExecContext(ctx, query, models.UTCNow(), tokenId)
+	return err
+}
+
+// UpdateLastUsed records that the token authenticated a request
+func (r *APITokensRepo) UpdateLastUsed(ctx context.Context, tx *sql.Tx, tokenId int) error {
+	query := "UPDATE api_tokens SET last_used_at = ? WHERE token_id = ?"
+	_, err := tx.ExecContext(ctx, query, models.UTCNow(), tokenId)
+	return err
+}
@@ -0,0 +1,91 @@
+package api_tokens_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/api_tokens"
+)
+
+// TestAPITokens_Lifecycle verifies a token is found by its hash until it is revoked,
+// and is never found once its user is disabled
+func TestAPITokens_Lifecycle(t *testing.T) {
+	ctx := t.Context()
+
+	dbtest.Run(t, func(t *testing.T, db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec("INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret')"); err != nil {
+			t.Fatalf("Failed to insert user: %v", err)
+		}
+
+		repo := api_tokens.NewAPITokensRepo()
+
+		token, prefix, hash, err := models.NewAPIToken()
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+
+		tokenId, err := repo.Create(ctx, tx, models.APITokenCreate{
+			UserId:      1,
+			TokenName:   "CI export",
+			TokenPrefix: prefix,
+			TokenHash:   hash,
+			Scope:       models.API_TOKEN_SCOPE_READ,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		if _, err := repo.Create(ctx, tx, models.APITokenCreate{
+			UserId:      1,
+			TokenName:   "Deploy",
+			TokenPrefix: "rab_second",
+			TokenHash:   models.HashAPIToken("rab_second"),
+			Scope:       models.API_TOKEN_SCOPE_WRITE,
+			ExpiresAt:   "2030-01-01 00:00:00",
+		}); err != nil {
+			t.Fatalf("Failed to create second token: %v", err)
+		}
+
+		found, err := repo.FindByHash(ctx, tx, models.HashAPIToken(token))
+		if err != nil {
+			t.Fatalf("Token not found by hash: %v", err)
+		}
+		if found.TokenId != tokenId || found.TokenName != "CI export" || found.ExpiresAt != "" || !found.IsActive(time.Now()) {
+			t.Errorf("Unexpected token: %+v", found)
+		}
+
+		tokens, err := repo.FindByUserId(ctx, tx, 1)
+		if err != nil {
+			t.Fatalf("Failed to list tokens: %v", err)
+		}
+		if len(tokens) != 2 || tokens[0].TokenName != "Deploy" || tokens[0].ExpiresAt != "2030-01-01 00:00:00" {
+			t.Errorf("Expected the newest token first, got %+v", tokens)
+		}
+
+		if err := repo.Revoke(ctx, tx, tokenId); err != nil {
+			t.Fatalf("Failed to revoke token: %v", err)
+		}
+		revoked, err := repo.FindById(ctx, tx, tokenId)
+		if err != nil {
+			t.Fatalf("Failed to find token: %v", err)
+		}
+		if revoked.RevokedAt == "" || revoked.IsActive(time.Now()) {
+			t.Errorf("Expected the token to be revoked, got %+v", revoked)
+		}
+
+		if _, err := tx.Exec("UPDATE users SET deleted_at = '2024-01-01 00:00:00' WHERE user_id = 1"); err != nil {
+			t.Fatalf("Failed to disable user: %v", err)
+		}
+		if _, err := repo.FindByHash(ctx, tx, models.HashAPIToken("rab_second")); err != sql.ErrNoRows {
+			t.Errorf("Expected no token for a disabled user, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package audit_log
+
+import (
+	"context"
+	"database/sql"
+	"math"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// Repository stores the audit trail of data changes, entries are only ever added
+type Repository interface {
+	Find(ctx context.Context, tx *sql.Tx, filter models.AuditFilter, paginationInput models.TablePaginationDataInput) ([]models.AuditEntry, models.PaginationInfo, error)
+	FindAll(ctx context.Context, tx *sql.Tx, filter models.AuditFilter, limit int) ([]models.AuditEntry, error)
+	Create(ctx context.Context, tx *sql.Tx, entryData models.AuditEntryCreate) (int, error)
+}
+
+var _ Repository = (*AuditLogRepo)(nil)
+
+type AuditLogRepo struct{}
+
+func NewAuditLogRepo() *AuditLogRepo {
+	return &AuditLogRepo{}
+}
+
+const selectAuditEntryColumns = `SELECT audit_id, COALESCE(user_id, 0), username, entity, entity_id, COALESCE(project_id, 0),
+	action, before_json, after_json, created_at
+	FROM audit_log`
+
+// filterCondition is the WHERE clause of a filter with its arguments
+func filterCondition(filter models.AuditFilter) (string, []interface{}) {
+	condition := " WHERE 1=1"
+	args := []interface{}{}
+
+	if filter.Entity != "" {
+		condition += " AND entity = ?"
+		args = append(args, filter.Entity)
+	}
+	if filter.Action != "" {
+		condition += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.Username != "" {
+		condition += " AND LOWER(username) LIKE LOWER(?)"
+		args = append(args, "%"+filter.Username+"%")
+	}
+	if filter.ProjectId != 0 {
+		condition += " AND project_id = ?"
+		args = append(args, filter.ProjectId)
+	}
+	if filter.DateFrom != "" {
+		condition += " AND created_at >= ?"
+		args = append(args, filter.DateFrom+" 00:00:00")
+	}
+	if filter.DateTo != "" {
+		condition += " AND created_at <= ?"
+		args = append(args, filter.DateTo+" 23:59:59")
+	}
+
+	return condition, args
+}
+
+func scanAuditEntries(rows *sql.Rows) ([]models.AuditEntry, error) {
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := rows.Scan(
+			&entry.AuditId,
+			&entry.UserId,
+			&entry.Username,
+			&entry.Entity,
+			&entry.EntityId,
+			&entry.ProjectId,
+			&entry.Action,
+			&entry.BeforeJSON,
+			&entry.AfterJSON,
+			&entry.CreatedAt,
+		); err != nil {
+			return entries, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Find retrieves the entries matching the filter, newest first
+func (r *AuditLogRepo) Find(ctx context.Context, tx *sql.Tx, filter models.AuditFilter, paginationInput models.TablePaginationDataInput) ([]models.AuditEntry, models.PaginationInfo, error) {
+	var paginationData models.PaginationInfo
+	var totalData int
+
+	offset := (paginationInput.Page - 1) * paginationInput.PerPage
+	condition, args := filterCondition(filter)
+
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(audit_id) FROM audit_log"+condition, args...).Scan(&totalData); err != nil {
+		return nil, paginationData, err
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		selectAuditEntryColumns+condition+" ORDER BY audit_id DESC LIMIT ? OFFSET ?",
+		append(args, paginationInput.PerPage, offset)...,
+	)
+	if err != nil {
+		return nil, paginationData, err
+	}
+
+	entries, err := scanAuditEntries(rows)
+	if err != nil {
+		return nil, paginationData, err
+	}
+
+	paginationData = models.PaginationInfo{
+		TotalItems:   totalData,
+		ItemsPerPage: paginationInput.PerPage,
+		CurrentPage:  paginationInput.Page,
+		TotalPages:   int(math.Ceil(float64(totalData) / float64(paginationInput.PerPage))),
+	}
+
+	return entries, paginationData, nil
+}
+
+// FindAll retrieves at most limit entries matching the filter, newest first, for exports
+func (r *AuditLogRepo) FindAll(ctx context.Context, tx *sql.Tx, filter models.AuditFilter, limit int) ([]models.AuditEntry, error) {
+	condition, args := filterCondition(filter)
+
+	rows, err := tx.QueryContext(ctx,
+		selectAuditEntryColumns+condition+" ORDER BY audit_id DESC LIMIT ?",
+		append(args, limit)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAuditEntries(rows)
+}
+
+// Create adds an entry, a project id of 0 is stored as NULL
+func (r *AuditLogRepo) Create(ctx context.Context, tx *sql.Tx, entryData models.AuditEntryCreate) (int, error) {
+	query := `INSERT INTO audit_log (user_id, username, entity, entity_id, project_id, action, before_json, after_json)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING audit_id`
+
+	var auditId int
+	if err := tx.QueryRowContext(ctx,
+		query,
+		models.NullableUserId(entryData.UserId),
+		entryData.Username,
+		entryData.Entity,
+		entryData.EntityId,
+		sql.NullInt64{Int64: int64(entryData.ProjectId), Valid: entryData.ProjectId != 0},
+		entryData.Action,
+		entryData.BeforeJSON,
+		entryData.AfterJSON,
+	).Scan(&auditId); err != nil {
+		return 0, err
+	}
+
+	return auditId, nil
+}
@@ -1,11 +1,28 @@
 package dashboard
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository computes the dashboard statistics of a workspace
+type Repository interface {
+	GetProjectsTotalCost(ctx context.Context, tx *sql.Tx, workspace models.Workspace) (float64, error)
+	GetProjectCount(ctx context.Context, tx *sql.Tx, workspace models.Workspace) (int, error)
+	GetRecentProjects(ctx context.Context, tx *sql.Tx, workspace models.Workspace, limit int) ([]models.Project, error)
+	GetWorkItemsCount(ctx context.Context, tx *sql.Tx, workspace models.Workspace) (int, error)
+	GetTypeCostBreakdown(ctx context.Context, tx *sql.Tx, workspace models.Workspace) ([]models.TypeCostBreakdown, error)
+	GetCategoryBreakdown(ctx context.Context, tx *sql.Tx, workspace models.Workspace, limit int) ([]models.CategoryBreakdown, error)
+	GetTopExpensiveItems(ctx context.Context, tx *sql.Tx, workspace models.Workspace, limit int) ([]models.TopExpensiveItem, error)
+	GetProjectBreakdown(ctx context.Context, tx *sql.Tx, workspace models.Workspace) ([]models.ProjectBreakdown, error)
+	GetEnhancedRecentProjects(ctx context.Context, tx *sql.Tx, workspace models.Workspace, limit int) ([]models.EnhancedProjectData, error)
+	GetMaterialSummaryStats(ctx context.Context, tx *sql.Tx, workspace models.Workspace) (models.MaterialSummaryStats, error)
+}
+
+var _ Repository = (*DashboardRepo)(nil)
+
 type DashboardRepo struct{}
 
 func NewDashboardRepo() *DashboardRepo {
@@ -13,20 +30,22 @@ func NewDashboardRepo() *DashboardRepo {
 }
 
 // GetProjectsTotalCost gets total cost for all user's projects
-func (r *DashboardRepo) GetProjectsTotalCost(tx *sql.Tx, userId int) (float64, error) {
+func (r *DashboardRepo) GetProjectsTotalCost(ctx context.Context, tx *sql.Tx, workspace models.Workspace) (float64, error) {
+	condition, args := workspace.ProjectCondition("p")
+
 	query := `
 		SELECT COALESCE(SUM(total_cost), 0) as total
 		FROM (
 			SELECT SUM(pic.total_cost) as total_cost
 			FROM project_item_costs pic
-			JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
-			JOIN projects p ON pwi.project_id = p.project_id
-			WHERE p.user_id = ?
+			JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
+			JOIN projects p ON pwi.project_id = p.project_id AND p.deleted_at IS NULL
+			WHERE ` + condition + `
 		) as costs
 	`
 
 	var total float64
-	if err := tx.QueryRow(query, userId).Scan(&total); err != nil {
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
 		return 0, err
 	}
 
@@ -34,11 +53,13 @@ func (r *DashboardRepo) GetProjectsTotalCost(tx *sql.Tx, userId int) (float64, e
 }
 
 // GetProjectCount gets total number of projects for a user
-func (r *DashboardRepo) GetProjectCount(tx *sql.Tx, userId int) (int, error) {
-	query := "SELECT COUNT(*) FROM projects WHERE user_id = ?"
+func (r *DashboardRepo) GetProjectCount(ctx context.Context, tx *sql.Tx, workspace models.Workspace) (int, error) {
+	condition, args := workspace.ProjectCondition("")
+
+	query := "SELECT COUNT(*) FROM projects WHERE deleted_at IS NULL AND " + condition
 
 	var count int
-	if err := tx.QueryRow(query, userId).Scan(&count); err != nil {
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
 		return 0, err
 	}
 
@@ -46,16 +67,18 @@ func (r *DashboardRepo) GetProjectCount(tx *sql.Tx, userId int) (int, error) {
 }
 
 // GetRecentProjects gets recent projects for a user
-func (r *DashboardRepo) GetRecentProjects(tx *sql.Tx, userId int, limit int) ([]models.Project, error) {
+func (r *DashboardRepo) GetRecentProjects(ctx context.Context, tx *sql.Tx, workspace models.Workspace, limit int) ([]models.Project, error) {
+	condition, args := workspace.ProjectCondition("")
+
 	query := `
 		SELECT project_id, user_id, project_name, location, client_name, created_at, updated_at
 		FROM projects
-		WHERE user_id = ?
+		WHERE deleted_at IS NULL AND ` + condition + `
 		ORDER BY created_at DESC
 		LIMIT ?
 	`
 
-	rows, err := tx.Query(query, userId, limit)
+	rows, err := tx.QueryContext(ctx, query, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -82,16 +105,18 @@ func (r *DashboardRepo) GetRecentProjects(tx *sql.Tx, userId int, limit int) ([]
 }
 
 // GetWorkItemsCount gets total count of work items for a user
-func (r *DashboardRepo) GetWorkItemsCount(tx *sql.Tx, userId int) (int, error) {
+func (r *DashboardRepo) GetWorkItemsCount(ctx context.Context, tx *sql.Tx, workspace models.Workspace) (int, error) {
+	condition, args := workspace.ProjectCondition("p")
+
 	query := `
 		SELECT COUNT(*)
 		FROM project_work_items pwi
-		JOIN projects p ON pwi.project_id = p.project_id
-		WHERE p.user_id = ?
+		JOIN projects p ON pwi.project_id = p.project_id AND p.deleted_at IS NULL
+		WHERE pwi.deleted_at IS NULL AND ` + condition + `
 	`
 
 	var count int
-	if err := tx.QueryRow(query, userId).Scan(&count); err != nil {
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
 		return 0, err
 	}
 
@@ -99,17 +124,19 @@ func (r *DashboardRepo) GetWorkItemsCount(tx *sql.Tx, userId int) (int, error) {
 }
 
 // GetTypeCostBreakdown gets cost breakdown by item type (Material vs Labor)
-func (r *DashboardRepo) GetTypeCostBreakdown(tx *sql.Tx, userId int) ([]models.TypeCostBreakdown, error) {
+func (r *DashboardRepo) GetTypeCostBreakdown(ctx context.Context, tx *sql.Tx, workspace models.Workspace) ([]models.TypeCostBreakdown, error) {
+	condition, args := workspace.ProjectCondition("p")
+
 	query := `
 		SELECT pic.item_type, COALESCE(SUM(pic.total_cost), 0) as total_cost
 		FROM project_item_costs pic
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
-		JOIN projects p ON pwi.project_id = p.project_id
-		WHERE p.user_id = ?
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
+		JOIN projects p ON pwi.project_id = p.project_id AND p.deleted_at IS NULL
+		WHERE ` + condition + `
 		GROUP BY pic.item_type
 	`
 
-	rows, err := tx.Query(query, userId)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +155,9 @@ func (r *DashboardRepo) GetTypeCostBreakdown(tx *sql.Tx, userId int) ([]models.T
 }
 
 // GetCategoryBreakdown gets statistics grouped by work category
-func (r *DashboardRepo) GetCategoryBreakdown(tx *sql.Tx, userId int, limit int) ([]models.CategoryBreakdown, error) {
+func (r *DashboardRepo) GetCategoryBreakdown(ctx context.Context, tx *sql.Tx, workspace models.Workspace, limit int) ([]models.CategoryBreakdown, error) {
+	condition, args := workspace.ProjectCondition("p")
+
 	query := `
 		SELECT c.category_id, c.category_name,
 		       COUNT(DISTINCT pwi.work_item_id) as item_count,
@@ -136,16 +165,16 @@ func (r *DashboardRepo) GetCategoryBreakdown(tx *sql.Tx, userId int, limit int)
 		       COALESCE(SUM(pic.total_cost), 0) as total_cost
 		FROM project_work_items pwi
 		JOIN master_work_categories c ON pwi.category_id = c.category_id
-		JOIN projects p ON pwi.project_id = p.project_id
+		JOIN projects p ON pwi.project_id = p.project_id AND p.deleted_at IS NULL
 		LEFT JOIN project_item_costs pic ON pwi.work_item_id = pic.work_item_id
-		WHERE p.user_id = ?
+		WHERE pwi.deleted_at IS NULL AND ` + condition + `
 		GROUP BY c.category_id, c.category_name
 		ORDER BY total_cost DESC
 	`
 
 	if limit > 0 {
 		query += " LIMIT ?"
-		rows, err := tx.Query(query, userId, limit)
+		rows, err := tx.QueryContext(ctx, query, append(args, limit)...)
 		if err != nil {
 			return nil, err
 		}
@@ -163,7 +192,7 @@ func (r *DashboardRepo) GetCategoryBreakdown(tx *sql.Tx, userId int, limit int)
 		return results, nil
 	}
 
-	rows, err := tx.Query(query, userId)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -182,7 +211,9 @@ func (r *DashboardRepo) GetCategoryBreakdown(tx *sql.Tx, userId int, limit int)
 }
 
 // GetTopExpensiveItems gets the most expensive items across all projects, grouped by project
-func (r *DashboardRepo) GetTopExpensiveItems(tx *sql.Tx, userId int, limit int) ([]models.TopExpensiveItem, error) {
+func (r *DashboardRepo) GetTopExpensiveItems(ctx context.Context, tx *sql.Tx, workspace models.Workspace, limit int) ([]models.TopExpensiveItem, error) {
+	condition, args := workspace.ProjectCondition("p")
+
 	query := `
 		SELECT p.project_name,
 		       pic.item_name,
@@ -191,17 +222,17 @@ func (r *DashboardRepo) GetTopExpensiveItems(tx *sql.Tx, userId int, limit int)
 		       COALESCE(SUM(pic.quantity_needed), 0) as total_quantity,
 		       COALESCE(m.unit, l.unit, pic.unit) as unit
 		FROM project_item_costs pic
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
-		JOIN projects p ON pwi.project_id = p.project_id
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
+		JOIN projects p ON pwi.project_id = p.project_id AND p.deleted_at IS NULL
 		LEFT JOIN master_materials m ON pic.master_item_id = m.material_id AND pic.item_type = 'MATERIAL'
 		LEFT JOIN master_labor_types l ON pic.master_item_id = l.labor_type_id AND pic.item_type = 'LABOR'
-		WHERE p.user_id = ?
+		WHERE ` + condition + `
 		GROUP BY p.project_name, pic.item_name, pic.item_type, COALESCE(m.unit, l.unit, pic.unit)
 		ORDER BY total_cost DESC, p.project_name, pic.item_name
 		LIMIT ?
 	`
 
-	rows, err := tx.Query(query, userId, limit)
+	rows, err := tx.QueryContext(ctx, query, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +251,9 @@ func (r *DashboardRepo) GetTopExpensiveItems(tx *sql.Tx, userId int, limit int)
 }
 
 // GetProjectBreakdown gets cost breakdown by project for Material Summary
-func (r *DashboardRepo) GetProjectBreakdown(tx *sql.Tx, userId int) ([]models.ProjectBreakdown, error) {
+func (r *DashboardRepo) GetProjectBreakdown(ctx context.Context, tx *sql.Tx, workspace models.Workspace) ([]models.ProjectBreakdown, error) {
+	condition, args := workspace.ProjectCondition("p")
+
 	query := `
 		SELECT p.project_id, p.project_name,
 		       COUNT(DISTINCT pwi.work_item_id) as work_item_count,
@@ -228,14 +261,14 @@ func (r *DashboardRepo) GetProjectBreakdown(tx *sql.Tx, userId int) ([]models.Pr
 		       COALESCE(SUM(CASE WHEN pic.item_type = 'LABOR' THEN pic.total_cost ELSE 0 END), 0) as labor_cost,
 		       COALESCE(SUM(pic.total_cost), 0) as total_cost
 		FROM projects p
-		LEFT JOIN project_work_items pwi ON p.project_id = pwi.project_id
+		LEFT JOIN project_work_items pwi ON p.project_id = pwi.project_id AND pwi.deleted_at IS NULL
 		LEFT JOIN project_item_costs pic ON pwi.work_item_id = pic.work_item_id
-		WHERE p.user_id = ?
+		WHERE p.deleted_at IS NULL AND ` + condition + `
 		GROUP BY p.project_id, p.project_name
 		ORDER BY total_cost DESC
 	`
 
-	rows, err := tx.Query(query, userId)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -254,21 +287,23 @@ func (r *DashboardRepo) GetProjectBreakdown(tx *sql.Tx, userId int) ([]models.Pr
 }
 
 // GetEnhancedRecentProjects gets recent projects with additional statistics
-func (r *DashboardRepo) GetEnhancedRecentProjects(tx *sql.Tx, userId int, limit int) ([]models.EnhancedProjectData, error) {
+func (r *DashboardRepo) GetEnhancedRecentProjects(ctx context.Context, tx *sql.Tx, workspace models.Workspace, limit int) ([]models.EnhancedProjectData, error) {
+	condition, args := workspace.ProjectCondition("p")
+
 	query := `
 		SELECT p.project_id, p.project_name, p.location, p.client_name, p.created_at, p.updated_at,
 		       COUNT(DISTINCT pwi.work_item_id) as work_item_count,
 		       COALESCE(SUM(pic.total_cost), 0) as total_cost
 		FROM projects p
-		LEFT JOIN project_work_items pwi ON p.project_id = pwi.project_id
+		LEFT JOIN project_work_items pwi ON p.project_id = pwi.project_id AND pwi.deleted_at IS NULL
 		LEFT JOIN project_item_costs pic ON pwi.work_item_id = pic.work_item_id
-		WHERE p.user_id = ?
+		WHERE p.deleted_at IS NULL AND ` + condition + `
 		GROUP BY p.project_id, p.project_name, p.location, p.client_name, p.created_at, p.updated_at
 		ORDER BY p.created_at DESC
 		LIMIT ?
 	`
 
-	rows, err := tx.Query(query, userId, limit)
+	rows, err := tx.QueryContext(ctx, query, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -296,7 +331,9 @@ func (r *DashboardRepo) GetEnhancedRecentProjects(tx *sql.Tx, userId int, limit
 }
 
 // GetMaterialSummaryStats gets overall statistics for Material Summary page
-func (r *DashboardRepo) GetMaterialSummaryStats(tx *sql.Tx, userId int) (models.MaterialSummaryStats, error) {
+func (r *DashboardRepo) GetMaterialSummaryStats(ctx context.Context, tx *sql.Tx, workspace models.Workspace) (models.MaterialSummaryStats, error) {
+	condition, args := workspace.ProjectCondition("p")
+
 	query := `
 		SELECT COUNT(DISTINCT CONCAT(pic.master_item_id, '_', pic.item_type)) as total_items,
 		       COALESCE(SUM(CASE WHEN pic.item_type = 'MATERIAL' THEN pic.total_cost ELSE 0 END), 0) as material_cost,
@@ -304,13 +341,13 @@ func (r *DashboardRepo) GetMaterialSummaryStats(tx *sql.Tx, userId int) (models.
 		       COALESCE(SUM(pic.total_cost), 0) as total_cost,
 		       COUNT(DISTINCT p.project_id) as unique_projects
 		FROM project_item_costs pic
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
-		JOIN projects p ON pwi.project_id = p.project_id
-		WHERE p.user_id = ?
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
+		JOIN projects p ON pwi.project_id = p.project_id AND p.deleted_at IS NULL
+		WHERE ` + condition + `
 	`
 
 	var stats models.MaterialSummaryStats
-	if err := tx.QueryRow(query, userId).Scan(
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
 		&stats.TotalItems,
 		&stats.MaterialCost,
 		&stats.LaborCost,
@@ -1,12 +1,30 @@
 package master_labor_types
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"math"
 
+	"github.com/momokii/go-rab-maker/backend/audit"
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores the master list of labor types and their daily wages
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, masterLaborTypeId int) (models.MasterLaborType, error)
+	Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput, workspace models.Workspace) ([]models.MasterLaborType, models.PaginationInfo, error)
+	Create(ctx context.Context, tx *sql.Tx, laborData models.MasterLaborTypeCreate) error
+	FindByNameAndUnit(ctx context.Context, tx *sql.Tx, roleName, unit string, workspace models.Workspace) (models.MasterLaborType, error)
+	FindByName(ctx context.Context, tx *sql.Tx, roleName string, workspace models.Workspace) (models.MasterLaborType, error)
+	Update(ctx context.Context, tx *sql.Tx, laborData models.MasterLaborType) error
+	Delete(ctx context.Context, tx *sql.Tx, laborData models.MasterLaborType) error
+	Restore(ctx context.Context, tx *sql.Tx, laborTypeId int) error
+	Purge(ctx context.Context, tx *sql.Tx, laborTypeId int) error
+}
+
+var _ Repository = (*MasterLaborTypesRepo)(nil)
+
 type MasterLaborTypesRepo struct{}
 
 func NewMasterLaborTypesRepo() *MasterLaborTypesRepo {
@@ -14,18 +32,19 @@ func NewMasterLaborTypesRepo() *MasterLaborTypesRepo {
 }
 
 // TODO: Testing FindById [MasterLaborTypesRepo]
-func (r *MasterLaborTypesRepo) FindById(tx *sql.Tx, masterLaborTypeId int) (models.MasterLaborType, error) {
+func (r *MasterLaborTypesRepo) FindById(ctx context.Context, tx *sql.Tx, masterLaborTypeId int) (models.MasterLaborType, error) {
 
 	var laborData models.MasterLaborType
-	var userId sql.NullInt64
+	var userId, orgId sql.NullInt64
 
-	query := "SELECT labor_type_id, user_id, role_name, unit, default_daily_wage, created_at, updated_at FROM master_labor_types WHERE labor_type_id = ?"
-	if err := tx.QueryRow(
+	query := "SELECT labor_type_id, user_id, org_id, role_name, unit, default_daily_wage, created_at, updated_at FROM master_labor_types WHERE labor_type_id = ? AND deleted_at IS NULL"
+	if err := tx.QueryRowContext(ctx,
 		query,
 		masterLaborTypeId,
 	).Scan(
 		&laborData.LaborTypeId,
 		&userId,
+		&orgId,
 		&laborData.RoleName,
 		&laborData.Unit,
 		&laborData.DefaultDailyWage,
@@ -41,12 +60,13 @@ func (r *MasterLaborTypesRepo) FindById(tx *sql.Tx, masterLaborTypeId int) (mode
 	} else {
 		laborData.UserId = 0
 	}
+	laborData.OrgId = int(orgId.Int64)
 
 	return laborData, nil
 }
 
 // Find finds labor types with pagination
-func (r *MasterLaborTypesRepo) Find(tx *sql.Tx, paginationInput models.TablePaginationDataInput, user_id int) ([]models.MasterLaborType, models.PaginationInfo, error) {
+func (r *MasterLaborTypesRepo) Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput, workspace models.Workspace) ([]models.MasterLaborType, models.PaginationInfo, error) {
 
 	var laborTypes []models.MasterLaborType
 	var paginationData models.PaginationInfo
@@ -56,26 +76,25 @@ func (r *MasterLaborTypesRepo) Find(tx *sql.Tx, paginationInput models.TablePagi
 	offset := (paginationInput.Page - 1) * paginationInput.PerPage
 
 	params := []interface{}{}
-	base_query := "SELECT labor_type_id, user_id, role_name, unit, default_daily_wage, created_at, updated_at FROM master_labor_types WHERE 1=1"
-	query_total := "SELECT COUNT(labor_type_id) FROM master_labor_types WHERE 1=1"
+	base_query := "SELECT labor_type_id, user_id, org_id, role_name, unit, default_daily_wage, created_at, updated_at FROM master_labor_types WHERE deleted_at IS NULL"
+	query_total := "SELECT COUNT(labor_type_id) FROM master_labor_types WHERE deleted_at IS NULL"
 
 	// if using search data
 	if paginationInput.Search != "" {
-		base_query += " AND role_name LIKE ?"
-		query_total += " AND role_name LIKE ?"
+		base_query += " AND LOWER(role_name) LIKE LOWER(?)"
+		query_total += " AND LOWER(role_name) LIKE LOWER(?)"
 		params = append(params, "%"+paginationInput.Search+"%")
 	}
 
 	// if using for per user
-	if user_id != 0 {
-		// Include both user-specific items and system-wide defaults (user_id IS NULL)
-		base_query += " AND (user_id = ? OR user_id IS NULL)"
-		query_total += " AND (user_id = ? OR user_id IS NULL)"
-		params = append(params, user_id)
-	}
+	// Include both the workspace's items and system-wide defaults
+	condition, args := workspace.MasterDataCondition("")
+	base_query += " AND " + condition
+	query_total += " AND " + condition
+	params = append(params, args...)
 
 	// get total data
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query_total,
 		params...,
 	).Scan(&totalData); err != nil {
@@ -86,18 +105,19 @@ func (r *MasterLaborTypesRepo) Find(tx *sql.Tx, paginationInput models.TablePagi
 	base_query += " ORDER BY labor_type_id LIMIT ? OFFSET ?"
 	params = append(params, paginationInput.PerPage, offset)
 
-	rows, err := tx.Query(base_query, params...)
+	rows, err := tx.QueryContext(ctx, base_query, params...)
 	if err != nil {
 		return laborTypes, paginationData, err
 	}
 
 	for rows.Next() {
 		var laborData models.MasterLaborType
-		var userId sql.NullInt64
+		var userId, orgId sql.NullInt64
 
 		if err := rows.Scan(
 			&laborData.LaborTypeId,
 			&userId,
+			&orgId,
 			&laborData.RoleName,
 			&laborData.Unit,
 			&laborData.DefaultDailyWage,
@@ -113,6 +133,7 @@ func (r *MasterLaborTypesRepo) Find(tx *sql.Tx, paginationInput models.TablePagi
 		} else {
 			laborData.UserId = 0
 		}
+		laborData.OrgId = int(orgId.Int64)
 
 		laborTypes = append(laborTypes, laborData)
 	}
@@ -133,50 +154,218 @@ func (r *MasterLaborTypesRepo) Find(tx *sql.Tx, paginationInput models.TablePagi
 	return laborTypes, paginationData, nil
 }
 
-// Create creates a new labor type
-func (r *MasterLaborTypesRepo) Create(tx *sql.Tx, laborData models.MasterLaborTypeCreate) error {
+// Create creates a new labor type. A labor type of the user in the trash keeps its name taken,
+// creating the name again brings that labor type back with the new details.
+func (r *MasterLaborTypesRepo) Create(ctx context.Context, tx *sql.Tx, laborData models.MasterLaborTypeCreate) error {
+	trashedId, err := findTrashed(ctx, tx, laborData.UserId, laborData.RoleName)
+	if err != nil {
+		return err
+	}
+	if trashedId != 0 {
+		return r.revive(ctx, tx, trashedId, laborData)
+	}
+
+	query := "INSERT INTO master_labor_types (role_name, unit, default_daily_wage, user_id, org_id) VALUES (?, ?, ?, ?, ?) RETURNING labor_type_id"
 
-	query := "INSERT INTO master_labor_types (role_name, unit, default_daily_wage, user_id) VALUES (?, ?, ?, ?)"
-	if _, err := tx.Exec(
+	var laborTypeId int
+	if err := tx.QueryRowContext(ctx,
 		query,
 		laborData.RoleName,
 		laborData.Unit,
 		laborData.DefaultDailyWage,
-		laborData.UserId,
+		models.NullableUserId(laborData.UserId),
+		models.NullableOrgId(laborData.OrgId),
+	).Scan(&laborTypeId); err != nil {
+		return err
+	}
+
+	return audit.Created(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, laborTypeId)
+}
+
+// findTrashed returns the ID of the labor type of the user in the trash with the role name, 0 when
+// there is none. Organization and system-wide labor types have no user_id, their names never clash.
+func findTrashed(ctx context.Context, tx *sql.Tx, userId int, roleName string) (int, error) {
+	if userId == 0 {
+		return 0, nil
+	}
+
+	var laborTypeId int
+	query := "SELECT labor_type_id FROM master_labor_types WHERE user_id = ? AND role_name = ? AND deleted_at IS NOT NULL"
+	if err := tx.QueryRowContext(ctx, query, userId, roleName).Scan(&laborTypeId); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	return laborTypeId, nil
+}
+
+// revive takes a labor type out of the trash with the details it is created again with
+func (r *MasterLaborTypesRepo) revive(ctx context.Context, tx *sql.Tx, laborTypeId int, laborData models.MasterLaborTypeCreate) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, laborTypeId)
+	if err != nil {
+		return err
+	}
+
+	now := models.UTCNow()
+	query := "UPDATE master_labor_types SET unit = ?, default_daily_wage = ?, org_id = ?, created_at = ?, updated_at = ?, deleted_at = NULL WHERE labor_type_id = ?"
+	if _, err := tx.ExecContext(ctx,
+		query,
+		laborData.Unit,
+		laborData.DefaultDailyWage,
+		models.NullableOrgId(laborData.OrgId),
+		now,
+		now,
+		laborTypeId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, before)
+}
+
+// FindByNameAndUnit looks up a labor type by exact role name and unit (case-insensitive).
+// A labor type of the workspace wins over a system-wide default with the same name and unit;
+// the zero workspace only searches the system-wide defaults.
+// Returns sql.ErrNoRows when nothing matches.
+func (r *MasterLaborTypesRepo) FindByNameAndUnit(ctx context.Context, tx *sql.Tx, roleName, unit string, workspace models.Workspace) (models.MasterLaborType, error) {
+	return r.findFirst(ctx, tx, "LOWER(role_name) = LOWER(?) AND LOWER(unit) = LOWER(?)", []interface{}{roleName, unit}, workspace)
+}
+
+// FindByName looks up a labor type by exact role name (case-insensitive) whatever its unit,
+// the role names of a user's labor types are unique. A labor type of the workspace wins over a
+// system-wide default with the same name. Returns sql.ErrNoRows when nothing matches.
+func (r *MasterLaborTypesRepo) FindByName(ctx context.Context, tx *sql.Tx, roleName string, workspace models.Workspace) (models.MasterLaborType, error) {
+	return r.findFirst(ctx, tx, "LOWER(role_name) = LOWER(?)", []interface{}{roleName}, workspace)
+}
+
+// findFirst returns the first labor type of the workspace or the system-wide defaults matching the condition
+func (r *MasterLaborTypesRepo) findFirst(ctx context.Context, tx *sql.Tx, match string, matchArgs []interface{}, workspace models.Workspace) (models.MasterLaborType, error) {
+	var laborData models.MasterLaborType
+	var laborUserId, laborOrgId sql.NullInt64
+
+	condition, args := workspace.MasterDataCondition("")
+	query := `
+		SELECT labor_type_id, user_id, org_id, role_name, unit, default_daily_wage, created_at, updated_at
+		FROM master_labor_types
+		WHERE ` + match + `
+			AND deleted_at IS NULL AND ` + condition + `
+		ORDER BY user_id IS NULL AND org_id IS NULL, labor_type_id
+		LIMIT 1`
+	if err := tx.QueryRowContext(ctx,
+		query,
+		append(matchArgs, args...)...,
+	).Scan(
+		&laborData.LaborTypeId,
+		&laborUserId,
+		&laborOrgId,
+		&laborData.RoleName,
+		&laborData.Unit,
+		&laborData.DefaultDailyWage,
+		&laborData.CreatedAt,
+		&laborData.UpdatedAt,
+	); err != nil {
+		return laborData, err
+	}
+
+	if laborUserId.Valid {
+		laborData.UserId = int(laborUserId.Int64)
+	}
+	laborData.OrgId = int(laborOrgId.Int64)
+
+	return laborData, nil
 }
 
 // Update updates a master labor type.
 // IMPORTANT: This does NOT update project_item_costs to preserve historical cost data.
 // When a labor wage changes, historical project estimates should remain unchanged
 // to reflect the costs at the time the project was created.
-func (r *MasterLaborTypesRepo) Update(tx *sql.Tx, laborData models.MasterLaborType) error {
+func (r *MasterLaborTypesRepo) Update(ctx context.Context, tx *sql.Tx, laborData models.MasterLaborType) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, laborData.LaborTypeId)
+	if err != nil {
+		return err
+	}
 
-	query := "UPDATE master_labor_types SET role_name = ?, unit = ?, default_daily_wage = ? WHERE labor_type_id = ? AND user_id = ?"
-	if _, err := tx.Exec(
+	query := "UPDATE master_labor_types SET role_name = ?, unit = ?, default_daily_wage = ? WHERE labor_type_id = ? AND COALESCE(user_id, 0) = ? AND COALESCE(org_id, 0) = ?"
+	if _, err := tx.ExecContext(ctx,
 		query,
 		laborData.RoleName,
 		laborData.Unit,
 		laborData.DefaultDailyWage,
 		laborData.LaborTypeId,
 		laborData.UserId,
+		laborData.OrgId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, before)
+}
+
+// Delete moves a master labor type to the trash.
+// IMPORTANT: Blocks deletion if the labor type is used in any AHSP template, the templates
+// would otherwise price a labor type nobody can see.
+func (r *MasterLaborTypesRepo) Delete(ctx context.Context, tx *sql.Tx, laborData models.MasterLaborType) error {
+	// VALIDATION: Check if labor type is used in any AHSP components
+	var usageCount int
+	checkQuery := "SELECT COUNT(*) FROM ahsp_labor_components WHERE labor_type_id = ?"
+	if err := tx.QueryRowContext(ctx, checkQuery, laborData.LaborTypeId).Scan(&usageCount); err != nil {
+		return err
+	}
+
+	if usageCount > 0 {
+		return fmt.Errorf("cannot delete labor type: used in %d AHSP components", usageCount)
+	}
+
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, laborData.LaborTypeId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE master_labor_types SET deleted_at = ? WHERE labor_type_id = ? AND deleted_at IS NULL"
+	if _, err := tx.ExecContext(ctx, query, models.UTCNow(), laborData.LaborTypeId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, before)
+}
+
+// Restore takes a master labor type out of the trash
+func (r *MasterLaborTypesRepo) Restore(ctx context.Context, tx *sql.Tx, laborTypeId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, laborTypeId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE master_labor_types SET deleted_at = NULL WHERE labor_type_id = ?"
+	if _, err := tx.ExecContext(ctx, query, laborTypeId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, before)
 }
 
-func (r *MasterLaborTypesRepo) Delete(tx *sql.Tx, laborData models.MasterLaborType) error {
+// Purge deletes a master labor type for good, with its AHSP components and the project costs
+// priced from it
+func (r *MasterLaborTypesRepo) Purge(ctx context.Context, tx *sql.Tx, laborTypeId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, laborTypeId)
+	if err != nil {
+		return err
+	}
+
+	componentsBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, "t.labor_type_id = ?", laborTypeId)
+	if err != nil {
+		return err
+	}
+
+	costsBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, "t.master_item_id = ?", laborTypeId)
+	if err != nil {
+		return err
+	}
+
 	// delete main data
 	query := "DELETE FROM master_labor_types WHERE labor_type_id = ?"
-	if _, err := tx.Exec(
+	if _, err := tx.ExecContext(ctx,
 		query,
-		laborData.LaborTypeId,
+		laborTypeId,
 	); err != nil {
 		return err
 	}
@@ -185,21 +374,28 @@ func (r *MasterLaborTypesRepo) Delete(tx *sql.Tx, laborData models.MasterLaborTy
 
 	// make sure to delete the related id at ahsp_material_component table
 	query_delete_ahsp_material := "DELETE FROM ahsp_labor_components WHERE labor_type_id = ?"
-	if _, err := tx.Exec(
+	if _, err := tx.ExecContext(ctx,
 		query_delete_ahsp_material,
-		laborData.LaborTypeId,
+		laborTypeId,
 	); err != nil {
 		return err
 	}
 
 	// make sure also delete from the project_item_costs table
 	query_delete_project_item_costs := "DELETE FROM project_item_costs WHERE master_item_id = ?"
-	if _, err := tx.Exec(
+	if _, err := tx.ExecContext(ctx,
 		query_delete_project_item_costs,
-		laborData.LaborTypeId,
+		laborTypeId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, costsBefore...); err != nil {
+		return err
+	}
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_LABOR_COMPONENT, componentsBefore...); err != nil {
+		return err
+	}
+
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_LABOR_TYPE, before)
 }
@@ -1,33 +1,53 @@
 package master_materials
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"math"
 
+	"github.com/momokii/go-rab-maker/backend/audit"
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores the master list of materials and their default prices
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, masterMaterialId int) (models.MasterMaterial, error)
+	FindByName(ctx context.Context, tx *sql.Tx, materialName string, workspace models.Workspace) (models.MasterMaterial, error)
+	FindByNameAndUnit(ctx context.Context, tx *sql.Tx, materialName, unit string, workspace models.Workspace) (models.MasterMaterial, error)
+	Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput, workspace models.Workspace) ([]models.MasterMaterial, models.PaginationInfo, error)
+	Create(ctx context.Context, tx *sql.Tx, materialData models.MasterMaterialCreate) error
+	Update(ctx context.Context, tx *sql.Tx, materialData models.MasterMaterial) error
+	Delete(ctx context.Context, tx *sql.Tx, materialData models.MasterMaterial) error
+	Restore(ctx context.Context, tx *sql.Tx, materialId int) error
+	Purge(ctx context.Context, tx *sql.Tx, materialId int) error
+}
+
+var _ Repository = (*MasterMaterialsRepo)(nil)
+
 type MasterMaterialsRepo struct{}
 
 func NewMasterMaterialsRepo() *MasterMaterialsRepo {
 	return &MasterMaterialsRepo{}
 }
 
-func (r *MasterMaterialsRepo) FindById(tx *sql.Tx, masterMaterialId int) (models.MasterMaterial, error) {
+func (r *MasterMaterialsRepo) FindById(ctx context.Context, tx *sql.Tx, masterMaterialId int) (models.MasterMaterial, error) {
 
 	var material models.MasterMaterial
-	var userId sql.NullInt64
+	var userId, orgId sql.NullInt64
 
-	query := "SELECT material_id, user_id, material_name, unit, default_unit_price, created_at, updated_at FROM master_materials WHERE material_id = ?"
-	if err := tx.QueryRow(
+	query := "SELECT material_id, user_id, org_id, material_name, unit, default_unit_price, is_equipment, created_at, updated_at FROM master_materials WHERE material_id = ? AND deleted_at IS NULL"
+	if err := tx.QueryRowContext(ctx,
 		query,
 		masterMaterialId,
 	).Scan(
 		&material.MaterialId,
 		&userId,
+		&orgId,
 		&material.MaterialName,
 		&material.Unit,
 		&material.DefaultUnitPrice,
+		&material.IsEquipment,
 		&material.CreatedAt,
 		&material.UpdatedAt,
 	); err != nil && err != sql.ErrNoRows {
@@ -42,11 +62,65 @@ func (r *MasterMaterialsRepo) FindById(tx *sql.Tx, masterMaterialId int) (models
 	} else {
 		material.UserId = 0
 	}
+	material.OrgId = int(orgId.Int64)
+
+	return material, nil
+}
+
+// FindByNameAndUnit looks up a material by exact name and unit (case-insensitive).
+// A material of the workspace wins over a system-wide default with the same name and unit;
+// the zero workspace only searches the system-wide defaults.
+// Returns sql.ErrNoRows when nothing matches.
+func (r *MasterMaterialsRepo) FindByNameAndUnit(ctx context.Context, tx *sql.Tx, materialName, unit string, workspace models.Workspace) (models.MasterMaterial, error) {
+	return r.findFirst(ctx, tx, "LOWER(material_name) = LOWER(?) AND LOWER(unit) = LOWER(?)", []interface{}{materialName, unit}, workspace)
+}
+
+// FindByName looks up a material by exact name (case-insensitive) whatever its unit, the
+// names of a user's materials are unique. A material of the workspace wins over a system-wide
+// default with the same name. Returns sql.ErrNoRows when nothing matches.
+func (r *MasterMaterialsRepo) FindByName(ctx context.Context, tx *sql.Tx, materialName string, workspace models.Workspace) (models.MasterMaterial, error) {
+	return r.findFirst(ctx, tx, "LOWER(material_name) = LOWER(?)", []interface{}{materialName}, workspace)
+}
+
+// findFirst returns the first material of the workspace or the system-wide defaults matching the condition
+func (r *MasterMaterialsRepo) findFirst(ctx context.Context, tx *sql.Tx, match string, matchArgs []interface{}, workspace models.Workspace) (models.MasterMaterial, error) {
+	var material models.MasterMaterial
+	var materialUserId, materialOrgId sql.NullInt64
+
+	condition, args := workspace.MasterDataCondition("")
+	query := `
+		SELECT material_id, user_id, org_id, material_name, unit, default_unit_price, is_equipment, created_at, updated_at
+		FROM master_materials
+		WHERE ` + match + `
+			AND deleted_at IS NULL AND ` + condition + `
+		ORDER BY user_id IS NULL AND org_id IS NULL, material_id
+		LIMIT 1`
+	if err := tx.QueryRowContext(ctx,
+		query,
+		append(matchArgs, args...)...,
+	).Scan(
+		&material.MaterialId,
+		&materialUserId,
+		&materialOrgId,
+		&material.MaterialName,
+		&material.Unit,
+		&material.DefaultUnitPrice,
+		&material.IsEquipment,
+		&material.CreatedAt,
+		&material.UpdatedAt,
+	); err != nil {
+		return material, err
+	}
+
+	if materialUserId.Valid {
+		material.UserId = int(materialUserId.Int64)
+	}
+	material.OrgId = int(materialOrgId.Int64)
 
 	return material, nil
 }
 
-func (r *MasterMaterialsRepo) Find(tx *sql.Tx, paginationInput models.TablePaginationDataInput, userId int) ([]models.MasterMaterial, models.PaginationInfo, error) {
+func (r *MasterMaterialsRepo) Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput, workspace models.Workspace) ([]models.MasterMaterial, models.PaginationInfo, error) {
 	var materials []models.MasterMaterial
 	var paginationData models.PaginationInfo
 	var totalData int
@@ -55,26 +129,24 @@ func (r *MasterMaterialsRepo) Find(tx *sql.Tx, paginationInput models.TablePagin
 	offset := (paginationInput.Page - 1) * paginationInput.PerPage
 
 	params := []interface{}{}
-	base_query := "SELECT material_id, user_id, material_name, unit, default_unit_price, created_at, updated_at FROM master_materials WHERE 1=1"
-	query_total := "SELECT COUNT(material_id) FROM master_materials WHERE 1=1"
+	base_query := "SELECT material_id, user_id, org_id, material_name, unit, default_unit_price, is_equipment, created_at, updated_at FROM master_materials WHERE deleted_at IS NULL"
+	query_total := "SELECT COUNT(material_id) FROM master_materials WHERE deleted_at IS NULL"
 
 	// if using search data
 	if paginationInput.Search != "" {
-		base_query += " AND material_name LIKE ?"
-		query_total += " AND material_name LIKE ?"
+		base_query += " AND LOWER(material_name) LIKE LOWER(?)"
+		query_total += " AND LOWER(material_name) LIKE LOWER(?)"
 		params = append(params, "%"+paginationInput.Search+"%")
 	}
 
-	// if using user_id
-	if userId != 0 {
-		// Include both user-specific items and system-wide defaults (user_id IS NULL)
-		base_query += " AND (user_id = ? OR user_id IS NULL)"
-		query_total += " AND (user_id = ? OR user_id IS NULL)"
-		params = append(params, userId)
-	}
+	// Include both the workspace's items and system-wide defaults
+	condition, args := workspace.MasterDataCondition("")
+	base_query += " AND " + condition
+	query_total += " AND " + condition
+	params = append(params, args...)
 
 	// get total data
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query_total,
 		params...,
 	).Scan(&totalData); err != nil {
@@ -85,21 +157,23 @@ func (r *MasterMaterialsRepo) Find(tx *sql.Tx, paginationInput models.TablePagin
 	base_query += " ORDER BY material_id LIMIT ? OFFSET ?"
 	params = append(params, paginationInput.PerPage, offset)
 
-	rows, err := tx.Query(base_query, params...)
+	rows, err := tx.QueryContext(ctx, base_query, params...)
 	if err != nil {
 		return materials, paginationData, err
 	}
 
 	for rows.Next() {
 		var mateial models.MasterMaterial
-		var userId sql.NullInt64
+		var userId, orgId sql.NullInt64
 
 		if err := rows.Scan(
 			&mateial.MaterialId,
 			&userId,
+			&orgId,
 			&mateial.MaterialName,
 			&mateial.Unit,
 			&mateial.DefaultUnitPrice,
+			&mateial.IsEquipment,
 			&mateial.CreatedAt,
 			&mateial.UpdatedAt,
 		); err != nil {
@@ -112,6 +186,7 @@ func (r *MasterMaterialsRepo) Find(tx *sql.Tx, paginationInput models.TablePagin
 		} else {
 			mateial.UserId = 0
 		}
+		mateial.OrgId = int(orgId.Int64)
 
 		materials = append(materials, mateial)
 	}
@@ -132,51 +207,170 @@ func (r *MasterMaterialsRepo) Find(tx *sql.Tx, paginationInput models.TablePagin
 	return materials, paginationData, nil
 }
 
-func (r *MasterMaterialsRepo) Create(tx *sql.Tx, materialData models.MasterMaterialCreate) error {
+// Create creates a new master material. A material of the user in the trash keeps its name
+// taken, creating the name again brings that material back with the new details.
+func (r *MasterMaterialsRepo) Create(ctx context.Context, tx *sql.Tx, materialData models.MasterMaterialCreate) error {
+	trashedId, err := findTrashed(ctx, tx, materialData.UserId, materialData.MaterialName)
+	if err != nil {
+		return err
+	}
+	if trashedId != 0 {
+		return r.revive(ctx, tx, trashedId, materialData)
+	}
+
+	query := "INSERT INTO master_materials (material_name, unit, default_unit_price, is_equipment, user_id, org_id) VALUES (?, ?, ?, ?, ?, ?) RETURNING material_id"
 
-	query := "INSERT INTO master_materials (material_name, unit, default_unit_price, user_id) VALUES (?, ?, ?, ?)"
-	if _, err := tx.Exec(
+	var materialId int
+	if err := tx.QueryRowContext(ctx,
 		query,
 		materialData.MaterialName,
 		materialData.Unit,
 		materialData.DefaultUnitPrice,
-		materialData.UserId,
+		materialData.IsEquipment,
+		models.NullableUserId(materialData.UserId),
+		models.NullableOrgId(materialData.OrgId),
+	).Scan(&materialId); err != nil {
+		return err
+	}
+
+	return audit.Created(ctx, tx, models.AUDIT_ENTITY_MATERIAL, materialId)
+}
+
+// findTrashed returns the ID of the material of the user in the trash with the name, 0 when
+// there is none. Organization and system-wide materials have no user_id, their names never clash.
+func findTrashed(ctx context.Context, tx *sql.Tx, userId int, materialName string) (int, error) {
+	if userId == 0 {
+		return 0, nil
+	}
+
+	var materialId int
+	query := "SELECT material_id FROM master_materials WHERE user_id = ? AND material_name = ? AND deleted_at IS NOT NULL"
+	if err := tx.QueryRowContext(ctx, query, userId, materialName).Scan(&materialId); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	return materialId, nil
+}
+
+// revive takes a material out of the trash with the details it is created again with
+func (r *MasterMaterialsRepo) revive(ctx context.Context, tx *sql.Tx, materialId int, materialData models.MasterMaterialCreate) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_MATERIAL, materialId)
+	if err != nil {
+		return err
+	}
+
+	now := models.UTCNow()
+	query := "UPDATE master_materials SET unit = ?, default_unit_price = ?, is_equipment = ?, org_id = ?, created_at = ?, updated_at = ?, deleted_at = NULL WHERE material_id = ?"
+	if _, err := tx.ExecContext(ctx,
+		query,
+		materialData.Unit,
+		materialData.DefaultUnitPrice,
+		materialData.IsEquipment,
+		models.NullableOrgId(materialData.OrgId),
+		now,
+		now,
+		materialId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_MATERIAL, before)
 }
 
 // Update updates a master material.
 // IMPORTANT: This does NOT update project_item_costs to preserve historical cost data.
 // When a material price changes, historical project estimates should remain unchanged
 // to reflect the costs at the time the project was created.
-func (r *MasterMaterialsRepo) Update(tx *sql.Tx, materialData models.MasterMaterial) error {
+func (r *MasterMaterialsRepo) Update(ctx context.Context, tx *sql.Tx, materialData models.MasterMaterial) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_MATERIAL, materialData.MaterialId)
+	if err != nil {
+		return err
+	}
 
 	// update main data
-	query := "UPDATE master_materials SET material_name = ?, unit = ?, default_unit_price = ? WHERE material_id = ? AND user_id = ?"
-	if _, err := tx.Exec(
+	query := "UPDATE master_materials SET material_name = ?, unit = ?, default_unit_price = ?, is_equipment = ? WHERE material_id = ? AND COALESCE(user_id, 0) = ? AND COALESCE(org_id, 0) = ?"
+	if _, err := tx.ExecContext(ctx,
 		query,
 		materialData.MaterialName,
 		materialData.Unit,
 		materialData.DefaultUnitPrice,
+		materialData.IsEquipment,
 		materialData.MaterialId,
 		materialData.UserId,
+		materialData.OrgId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_MATERIAL, before)
+}
+
+// Delete moves a master material to the trash.
+// IMPORTANT: Blocks deletion if the material is used in any AHSP template, the templates
+// would otherwise price a material nobody can see.
+func (r *MasterMaterialsRepo) Delete(ctx context.Context, tx *sql.Tx, materialData models.MasterMaterial) error {
+	// VALIDATION: Check if material is used in any AHSP components
+	var usageCount int
+	checkQuery := "SELECT COUNT(*) FROM ahsp_material_components WHERE material_id = ?"
+	if err := tx.QueryRowContext(ctx, checkQuery, materialData.MaterialId).Scan(&usageCount); err != nil {
+		return err
+	}
+
+	if usageCount > 0 {
+		return fmt.Errorf("cannot delete material: used in %d AHSP components", usageCount)
+	}
+
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_MATERIAL, materialData.MaterialId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE master_materials SET deleted_at = ? WHERE material_id = ? AND deleted_at IS NULL"
+	if _, err := tx.ExecContext(ctx, query, models.UTCNow(), materialData.MaterialId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_MATERIAL, before)
 }
 
-func (r *MasterMaterialsRepo) Delete(tx *sql.Tx, materialData models.MasterMaterial) error {
+// Restore takes a master material out of the trash
+func (r *MasterMaterialsRepo) Restore(ctx context.Context, tx *sql.Tx, materialId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_MATERIAL, materialId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE master_materials SET deleted_at = NULL WHERE material_id = ?"
+	if _, err := tx.ExecContext(ctx, query, materialId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_MATERIAL, before)
+}
+
+// Purge deletes a master material for good, with its AHSP components and the project costs
+// priced from it
+func (r *MasterMaterialsRepo) Purge(ctx context.Context, tx *sql.Tx, materialId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_MATERIAL, materialId)
+	if err != nil {
+		return err
+	}
+
+	componentsBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, "t.material_id = ?", materialId)
+	if err != nil {
+		return err
+	}
+
+	costsBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, "t.master_item_id = ?", materialId)
+	if err != nil {
+		return err
+	}
 
 	// delete main data
 	query_delete_material := "DELETE FROM master_materials WHERE material_id = ?"
-	if _, err := tx.Exec(
+	if _, err := tx.ExecContext(ctx,
 		query_delete_material,
-		materialData.MaterialId,
+		materialId,
 	); err != nil {
 		return err
 	}
@@ -185,21 +379,28 @@ func (r *MasterMaterialsRepo) Delete(tx *sql.Tx, materialData models.MasterMater
 
 	// make sure to delete the related id at ahsp_material_component table
 	query_delete_ahsp_material := "DELETE FROM ahsp_material_components WHERE material_id = ?"
-	if _, err := tx.Exec(
+	if _, err := tx.ExecContext(ctx,
 		query_delete_ahsp_material,
-		materialData.MaterialId,
+		materialId,
 	); err != nil {
 		return err
 	}
 
 	// make sure also delete from the project_item_costs table
 	query_delete_project_item_costs := "DELETE FROM project_item_costs WHERE master_item_id = ?"
-	if _, err := tx.Exec(
+	if _, err := tx.ExecContext(ctx,
 		query_delete_project_item_costs,
-		materialData.MaterialId,
+		materialId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, costsBefore...); err != nil {
+		return err
+	}
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_AHSP_MATERIAL_COMPONENT, componentsBefore...); err != nil {
+		return err
+	}
+
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_MATERIAL, before)
 }
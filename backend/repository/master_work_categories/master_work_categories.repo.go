@@ -1,31 +1,53 @@
 package master_work_categories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 
+	"github.com/momokii/go-rab-maker/backend/audit"
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores the work categories used to group project work items
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, masterWorkCategoryId int) (models.MasterWorkCategory, error)
+	FindByName(ctx context.Context, tx *sql.Tx, categoryName string, workspace models.Workspace) (models.MasterWorkCategory, error)
+	Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput, workspace models.Workspace) ([]models.MasterWorkCategory, models.PaginationInfo, error)
+	Create(ctx context.Context, tx *sql.Tx, categoriesData models.MasterWorkCategoryCreate) (int, error)
+	Update(ctx context.Context, tx *sql.Tx, categoriesData models.MasterWorkCategory) error
+	Delete(ctx context.Context, tx *sql.Tx, categoriesData models.MasterWorkCategory) error
+	Restore(ctx context.Context, tx *sql.Tx, categoryId int) error
+	Purge(ctx context.Context, tx *sql.Tx, categoryId int) error
+}
+
+// ErrCategoryInUse is returned when deleting a category that work items, in the trash or
+// not, still use
+var ErrCategoryInUse = errors.New("cannot delete category")
+
+var _ Repository = (*MasterWorkCategoriesRepo)(nil)
+
 type MasterWorkCategoriesRepo struct{}
 
 func NewMasterWorkCategoriesRepo() *MasterWorkCategoriesRepo {
 	return &MasterWorkCategoriesRepo{}
 }
 
-func (r *MasterWorkCategoriesRepo) FindById(tx *sql.Tx, masterWorkCategoryId int) (models.MasterWorkCategory, error) {
+func (r *MasterWorkCategoriesRepo) FindById(ctx context.Context, tx *sql.Tx, masterWorkCategoryId int) (models.MasterWorkCategory, error) {
 
 	var workCategory models.MasterWorkCategory
 
-	query := "SELECT category_id, user_id, category_name, display_order, created_at, updated_at FROM master_work_categories WHERE category_id = ?"
+	query := "SELECT category_id, COALESCE(user_id, 0), COALESCE(org_id, 0), category_name, display_order, created_at, updated_at FROM master_work_categories WHERE category_id = ? AND deleted_at IS NULL"
 
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query,
 		masterWorkCategoryId,
 	).Scan(
 		&workCategory.CategoryId,
 		&workCategory.UserId,
+		&workCategory.OrgId,
 		&workCategory.CategoryName,
 		&workCategory.DisplayOrder,
 		&workCategory.CreatedAt,
@@ -37,7 +59,41 @@ func (r *MasterWorkCategoriesRepo) FindById(tx *sql.Tx, masterWorkCategoryId int
 	return workCategory, nil
 }
 
-func (r *MasterWorkCategoriesRepo) Find(tx *sql.Tx, paginationInput models.TablePaginationDataInput) ([]models.MasterWorkCategory, models.PaginationInfo, error) {
+// FindByName looks up a work category by exact name (case-insensitive).
+// A category of the workspace wins over a system-wide default with the same name;
+// the zero workspace only searches the system-wide defaults.
+// Returns sql.ErrNoRows when nothing matches.
+func (r *MasterWorkCategoriesRepo) FindByName(ctx context.Context, tx *sql.Tx, categoryName string, workspace models.Workspace) (models.MasterWorkCategory, error) {
+
+	var workCategory models.MasterWorkCategory
+
+	condition, args := workspace.MasterDataCondition("")
+	query := `
+		SELECT category_id, COALESCE(user_id, 0), COALESCE(org_id, 0), category_name, display_order, created_at, updated_at
+		FROM master_work_categories
+		WHERE LOWER(category_name) = LOWER(?) AND deleted_at IS NULL AND ` + condition + `
+		ORDER BY user_id IS NULL AND org_id IS NULL, category_id
+		LIMIT 1`
+
+	if err := tx.QueryRowContext(ctx,
+		query,
+		append([]interface{}{categoryName}, args...)...,
+	).Scan(
+		&workCategory.CategoryId,
+		&workCategory.UserId,
+		&workCategory.OrgId,
+		&workCategory.CategoryName,
+		&workCategory.DisplayOrder,
+		&workCategory.CreatedAt,
+		&workCategory.UpdatedAt,
+	); err != nil {
+		return workCategory, err
+	}
+
+	return workCategory, nil
+}
+
+func (r *MasterWorkCategoriesRepo) Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput, workspace models.Workspace) ([]models.MasterWorkCategory, models.PaginationInfo, error) {
 
 	var masterWorkCategories []models.MasterWorkCategory
 	var paginationData models.PaginationInfo
@@ -47,18 +103,24 @@ func (r *MasterWorkCategoriesRepo) Find(tx *sql.Tx, paginationInput models.Table
 	offset := (paginationInput.Page - 1) * paginationInput.PerPage
 
 	params := []interface{}{}
-	base_query := "SELECT category_id, user_id, category_name, display_order, created_at, updated_at FROM master_work_categories WHERE 1=1"
-	query_total := "SELECT COUNT(category_id) FROM master_work_categories WHERE 1=1"
+	base_query := "SELECT category_id, COALESCE(user_id, 0), COALESCE(org_id, 0), category_name, display_order, created_at, updated_at FROM master_work_categories WHERE deleted_at IS NULL"
+	query_total := "SELECT COUNT(category_id) FROM master_work_categories WHERE deleted_at IS NULL"
 
 	// if using search data
 	if paginationInput.Search != "" {
-		base_query += " AND category_name LIKE ?"
-		query_total += " AND category_name LIKE ?"
+		base_query += " AND LOWER(category_name) LIKE LOWER(?)"
+		query_total += " AND LOWER(category_name) LIKE LOWER(?)"
 		params = append(params, "%"+paginationInput.Search+"%")
 	}
 
+	// Include both the workspace's categories and system-wide defaults
+	condition, args := workspace.MasterDataCondition("")
+	base_query += " AND " + condition
+	query_total += " AND " + condition
+	params = append(params, args...)
+
 	// get total data
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query_total,
 		params...,
 	).Scan(&totalData); err != nil {
@@ -69,7 +131,7 @@ func (r *MasterWorkCategoriesRepo) Find(tx *sql.Tx, paginationInput models.Table
 	base_query += " ORDER BY display_order, category_id LIMIT ? OFFSET ?"
 	params = append(params, paginationInput.PerPage, offset)
 
-	rows, err := tx.Query(base_query, params...)
+	rows, err := tx.QueryContext(ctx, base_query, params...)
 	if err != nil {
 		return masterWorkCategories, paginationData, err
 	}
@@ -80,6 +142,7 @@ func (r *MasterWorkCategoriesRepo) Find(tx *sql.Tx, paginationInput models.Table
 		if err := rows.Scan(
 			&category.CategoryId,
 			&category.UserId,
+			&category.OrgId,
 			&category.CategoryName,
 			&category.DisplayOrder,
 			&category.CreatedAt,
@@ -107,61 +170,169 @@ func (r *MasterWorkCategoriesRepo) Find(tx *sql.Tx, paginationInput models.Table
 	return masterWorkCategories, paginationData, nil
 }
 
-func (r *MasterWorkCategoriesRepo) Create(tx *sql.Tx, categoriesData models.MasterWorkCategoryCreate) error {
+// Create creates a new work category and returns its ID. A category of the user in the trash
+// keeps its name taken, creating the name again brings that category back instead.
+func (r *MasterWorkCategoriesRepo) Create(ctx context.Context, tx *sql.Tx, categoriesData models.MasterWorkCategoryCreate) (int, error) {
+	trashedId, err := findTrashed(ctx, tx, categoriesData.UserId, categoriesData.CategoryName)
+	if err != nil {
+		return 0, err
+	}
+	if trashedId != 0 {
+		return trashedId, r.revive(ctx, tx, trashedId, categoriesData)
+	}
+
+	query := "INSERT INTO master_work_categories (user_id, org_id, category_name, display_order) VALUES (?, ?, ?, ?) RETURNING category_id"
 
-	query := "INSERT INTO master_work_categories (user_id, category_name, display_order) VALUES (?, ?, ?)"
-	if _, err := tx.Exec(
+	var categoryId int
+	if err := tx.QueryRowContext(ctx,
 		query,
-		categoriesData.UserId,
+		models.NullableUserId(categoriesData.UserId),
+		models.NullableOrgId(categoriesData.OrgId),
 		categoriesData.CategoryName,
 		categoriesData.DisplayOrder,
+	).Scan(&categoryId); err != nil {
+		return 0, err
+	}
+
+	if err := audit.Created(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, categoryId); err != nil {
+		return 0, err
+	}
+
+	return categoryId, nil
+}
+
+// findTrashed returns the ID of the category of the user in the trash with the name, 0 when
+// there is none. Organization and system-wide categories have no user_id, their names never clash.
+func findTrashed(ctx context.Context, tx *sql.Tx, userId int, categoryName string) (int, error) {
+	if userId == 0 {
+		return 0, nil
+	}
+
+	var categoryId int
+	query := "SELECT category_id FROM master_work_categories WHERE user_id = ? AND category_name = ? AND deleted_at IS NOT NULL"
+	if err := tx.QueryRowContext(ctx, query, userId, categoryName).Scan(&categoryId); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	return categoryId, nil
+}
+
+// revive takes a category out of the trash with the details it is created again with
+func (r *MasterWorkCategoriesRepo) revive(ctx context.Context, tx *sql.Tx, categoryId int, categoriesData models.MasterWorkCategoryCreate) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, categoryId)
+	if err != nil {
+		return err
+	}
+
+	now := models.UTCNow()
+	query := "UPDATE master_work_categories SET org_id = ?, display_order = ?, created_at = ?, updated_at = ?, deleted_at = NULL WHERE category_id = ?"
+	if _, err := tx.ExecContext(ctx,
+		query,
+		models.NullableOrgId(categoriesData.OrgId),
+		categoriesData.DisplayOrder,
+		now,
+		now,
+		categoryId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, before)
 }
 
-func (r *MasterWorkCategoriesRepo) Update(tx *sql.Tx, categoriesData models.MasterWorkCategory) error {
+func (r *MasterWorkCategoriesRepo) Update(ctx context.Context, tx *sql.Tx, categoriesData models.MasterWorkCategory) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, categoriesData.CategoryId)
+	if err != nil {
+		return err
+	}
 
-	query := "UPDATE master_work_categories SET category_name = ?, display_order = ? WHERE category_id = ? AND user_id = ?"
-	if _, err := tx.Exec(
+	query := "UPDATE master_work_categories SET category_name = ?, display_order = ? WHERE category_id = ? AND COALESCE(user_id, 0) = ? AND COALESCE(org_id, 0) = ?"
+	if _, err := tx.ExecContext(ctx,
 		query,
 		categoriesData.CategoryName,
 		categoriesData.DisplayOrder,
 		categoriesData.CategoryId,
 		categoriesData.UserId,
+		categoriesData.OrgId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, before)
+}
+
+// Delete moves a work category to the trash.
+// IMPORTANT: Blocks deletion if the category is used in any work items, those in the trash
+// included, to maintain data integrity and prevent breaking existing project structures.
+func (r *MasterWorkCategoriesRepo) Delete(ctx context.Context, tx *sql.Tx, categoriesData models.MasterWorkCategory) error {
+	if err := checkCategoryUnused(ctx, tx, categoriesData.CategoryId); err != nil {
+		return err
+	}
+
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, categoriesData.CategoryId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE master_work_categories SET deleted_at = ? WHERE category_id = ? AND deleted_at IS NULL"
+	if _, err := tx.ExecContext(ctx, query, models.UTCNow(), categoriesData.CategoryId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, before)
+}
+
+// Restore takes a work category out of the trash
+func (r *MasterWorkCategoriesRepo) Restore(ctx context.Context, tx *sql.Tx, categoryId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, categoryId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE master_work_categories SET deleted_at = NULL WHERE category_id = ?"
+	if _, err := tx.ExecContext(ctx, query, categoryId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, before)
 }
 
-// Delete deletes a work category.
-// IMPORTANT: Blocks deletion if the category is currently used in any work items
-// to maintain data integrity and prevent breaking existing project structures.
-func (r *MasterWorkCategoriesRepo) Delete(tx *sql.Tx, categoriesData models.MasterWorkCategory) error {
+// Purge deletes a work category for good, blocked like Delete while work items use it
+func (r *MasterWorkCategoriesRepo) Purge(ctx context.Context, tx *sql.Tx, categoryId int) error {
+	if err := checkCategoryUnused(ctx, tx, categoryId); err != nil {
+		return err
+	}
+
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, categoryId)
+	if err != nil {
+		return err
+	}
+
+	// Delete the category
+	query := "DELETE FROM master_work_categories WHERE category_id = ?"
+	if _, err := tx.ExecContext(ctx, query, categoryId); err != nil {
+		return err
+	}
+
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_WORK_CATEGORY, before)
+}
+
+// checkCategoryUnused refuses to delete a category used in any work items
+func checkCategoryUnused(ctx context.Context, tx *sql.Tx, categoryId int) error {
 	// VALIDATION: Check if category is used in any work items
 	var usageCount int
 	checkQuery := `
 		SELECT COUNT(*)
 		FROM project_work_items
 		WHERE category_id = ?`
-	err := tx.QueryRow(checkQuery, categoriesData.CategoryId).Scan(&usageCount)
+	err := tx.QueryRowContext(ctx, checkQuery, categoryId).Scan(&usageCount)
 	if err != nil {
 		return err
 	}
 
 	// Block deletion if category is in use
 	if usageCount > 0 {
-		return fmt.Errorf("cannot delete category: used in %d work items", usageCount)
-	}
-
-	// Delete the category
-	query := "DELETE FROM master_work_categories WHERE category_id = ?"
-	if _, err := tx.Exec(query, categoriesData.CategoryId); err != nil {
-		return err
+		return fmt.Errorf("%w: used in %d work items", ErrCategoryInUse, usageCount)
 	}
 
 	return nil
@@ -1,11 +1,20 @@
 package material_summary
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository aggregates material and labor needs across projects
+type Repository interface {
+	GetAllMaterialsSummary(ctx context.Context, tx *sql.Tx, workspace models.Workspace) ([]models.MaterialSummary, error)
+	GetProjectMaterialSummary(ctx context.Context, tx *sql.Tx, projectId int) ([]models.MaterialSummary, error)
+}
+
+var _ Repository = (*MaterialSummaryRepo)(nil)
+
 type MaterialSummaryRepo struct{}
 
 func NewMaterialSummaryRepo() *MaterialSummaryRepo {
@@ -13,13 +22,15 @@ func NewMaterialSummaryRepo() *MaterialSummaryRepo {
 }
 
 // GetAllMaterialsSummary gets material summary across all projects for a user, grouped by project
-func (r *MaterialSummaryRepo) GetAllMaterialsSummary(tx *sql.Tx, userId int) ([]models.MaterialSummary, error) {
+func (r *MaterialSummaryRepo) GetAllMaterialsSummary(ctx context.Context, tx *sql.Tx, workspace models.Workspace) ([]models.MaterialSummary, error) {
+	condition, args := workspace.ProjectCondition("p")
+
 	// Get materials
 	materialsQuery := `
 		SELECT
 			p.project_id,
 			p.project_name,
-			COALESCE(m.material_id, 0) as item_id,
+			COALESCE(MAX(m.material_id), 0) as item_id,
 			pic.item_name,
 			SUM(pic.quantity_needed) as total_quantity,
 			COALESCE(m.unit, pic.unit) as unit,
@@ -27,9 +38,9 @@ func (r *MaterialSummaryRepo) GetAllMaterialsSummary(tx *sql.Tx, userId int) ([]
 			SUM(pic.total_cost) as total_cost
 		FROM project_item_costs pic
 		LEFT JOIN master_materials m ON pic.master_item_id = m.material_id
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
-		JOIN projects p ON pwi.project_id = p.project_id
-		WHERE p.user_id = ? AND pic.item_type = 'MATERIAL'
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
+		JOIN projects p ON pwi.project_id = p.project_id AND p.deleted_at IS NULL
+		WHERE ` + condition + ` AND pic.item_type = 'MATERIAL'
 		GROUP BY p.project_id, p.project_name, pic.master_item_id, pic.item_name, COALESCE(m.unit, pic.unit)
 		ORDER BY p.project_name, pic.item_name
 	`
@@ -39,7 +50,7 @@ func (r *MaterialSummaryRepo) GetAllMaterialsSummary(tx *sql.Tx, userId int) ([]
 		SELECT
 			p.project_id,
 			p.project_name,
-			COALESCE(lt.labor_type_id, 0) as item_id,
+			COALESCE(MAX(lt.labor_type_id), 0) as item_id,
 			pic.item_name,
 			SUM(pic.quantity_needed) as total_quantity,
 			COALESCE(lt.unit, pic.unit) as unit,
@@ -47,15 +58,15 @@ func (r *MaterialSummaryRepo) GetAllMaterialsSummary(tx *sql.Tx, userId int) ([]
 			SUM(pic.total_cost) as total_cost
 		FROM project_item_costs pic
 		LEFT JOIN master_labor_types lt ON pic.master_item_id = lt.labor_type_id
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
-		JOIN projects p ON pwi.project_id = p.project_id
-		WHERE p.user_id = ? AND pic.item_type = 'LABOR'
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
+		JOIN projects p ON pwi.project_id = p.project_id AND p.deleted_at IS NULL
+		WHERE ` + condition + ` AND pic.item_type = 'LABOR'
 		GROUP BY p.project_id, p.project_name, pic.master_item_id, pic.item_name, COALESCE(lt.unit, pic.unit)
 		ORDER BY p.project_name, pic.item_name
 	`
 
 	// Execute materials query
-	materialsRows, err := tx.Query(materialsQuery, userId)
+	materialsRows, err := tx.QueryContext(ctx, materialsQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +91,7 @@ func (r *MaterialSummaryRepo) GetAllMaterialsSummary(tx *sql.Tx, userId int) ([]
 	}
 
 	// Execute labor query
-	laborRows, err := tx.Query(laborQuery, userId)
+	laborRows, err := tx.QueryContext(ctx, laborQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -107,11 +118,11 @@ func (r *MaterialSummaryRepo) GetAllMaterialsSummary(tx *sql.Tx, userId int) ([]
 }
 
 // GetProjectMaterialSummary gets material summary for a specific project
-func (r *MaterialSummaryRepo) GetProjectMaterialSummary(tx *sql.Tx, projectId int) ([]models.MaterialSummary, error) {
+func (r *MaterialSummaryRepo) GetProjectMaterialSummary(ctx context.Context, tx *sql.Tx, projectId int) ([]models.MaterialSummary, error) {
 	// Get project info first
 	var projectName string
 	var projectID int
-	err := tx.QueryRow("SELECT project_id, project_name FROM projects WHERE project_id = ?", projectId).Scan(&projectID, &projectName)
+	err := tx.QueryRowContext(ctx, "SELECT project_id, project_name FROM projects WHERE project_id = ? AND deleted_at IS NULL", projectId).Scan(&projectID, &projectName)
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +130,7 @@ func (r *MaterialSummaryRepo) GetProjectMaterialSummary(tx *sql.Tx, projectId in
 	// Get materials
 	materialsQuery := `
 		SELECT
-			COALESCE(m.material_id, 0) as item_id,
+			COALESCE(MAX(m.material_id), 0) as item_id,
 			pic.item_name,
 			SUM(pic.quantity_needed) as total_quantity,
 			COALESCE(m.unit, pic.unit) as unit,
@@ -127,7 +138,7 @@ func (r *MaterialSummaryRepo) GetProjectMaterialSummary(tx *sql.Tx, projectId in
 			SUM(pic.total_cost) as total_cost
 		FROM project_item_costs pic
 		LEFT JOIN master_materials m ON pic.master_item_id = m.material_id
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
 		WHERE pwi.project_id = ? AND pic.item_type = 'MATERIAL'
 		GROUP BY pic.master_item_id, pic.item_name, COALESCE(m.unit, pic.unit)
 		ORDER BY pic.item_name
@@ -136,7 +147,7 @@ func (r *MaterialSummaryRepo) GetProjectMaterialSummary(tx *sql.Tx, projectId in
 	// Get labor
 	laborQuery := `
 		SELECT
-			COALESCE(lt.labor_type_id, 0) as item_id,
+			COALESCE(MAX(lt.labor_type_id), 0) as item_id,
 			pic.item_name,
 			SUM(pic.quantity_needed) as total_quantity,
 			COALESCE(lt.unit, pic.unit) as unit,
@@ -144,14 +155,14 @@ func (r *MaterialSummaryRepo) GetProjectMaterialSummary(tx *sql.Tx, projectId in
 			SUM(pic.total_cost) as total_cost
 		FROM project_item_costs pic
 		LEFT JOIN master_labor_types lt ON pic.master_item_id = lt.labor_type_id
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
 		WHERE pwi.project_id = ? AND pic.item_type = 'LABOR'
 		GROUP BY pic.master_item_id, pic.item_name, COALESCE(lt.unit, pic.unit)
 		ORDER BY pic.item_name
 	`
 
 	// Execute materials query
-	materialsRows, err := tx.Query(materialsQuery, projectId)
+	materialsRows, err := tx.QueryContext(ctx, materialsQuery, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +188,7 @@ func (r *MaterialSummaryRepo) GetProjectMaterialSummary(tx *sql.Tx, projectId in
 	}
 
 	// Execute labor query
-	laborRows, err := tx.Query(laborQuery, projectId)
+	laborRows, err := tx.QueryContext(ctx, laborQuery, projectId)
 	if err != nil {
 		return nil, err
 	}
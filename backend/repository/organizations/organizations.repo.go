@@ -0,0 +1,176 @@
+package organizations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// Repository stores organizations and their members
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, orgId int) (models.Organization, error)
+	FindByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.OrganizationMembership, error)
+	FindMembers(ctx context.Context, tx *sql.Tx, orgId int) ([]models.OrganizationMember, error)
+	FindMember(ctx context.Context, tx *sql.Tx, orgId, userId int) (models.OrganizationMember, error)
+	Create(ctx context.Context, tx *sql.Tx, orgData models.OrganizationCreate, ownerId int) (int, error)
+	AddMember(ctx context.Context, tx *sql.Tx, orgId, userId int, role string) error
+	RemoveMember(ctx context.Context, tx *sql.Tx, orgId, userId int) error
+	CountOwners(ctx context.Context, tx *sql.Tx, orgId int) (int, error)
+	CountOwnedRows(ctx context.Context, tx *sql.Tx, orgId int) (int, error)
+	Delete(ctx context.Context, tx *sql.Tx, orgId int) error
+}
+
+var _ Repository = (*OrganizationsRepo)(nil)
+
+type OrganizationsRepo struct{}
+
+func NewOrganizationsRepo() *OrganizationsRepo {
+	return &OrganizationsRepo{}
+}
+
+// ownedTables are the tables whose rows an organization can own
+var ownedTables = []string{"projects", "master_materials", "master_labor_types", "master_work_categories", "ahsp_templates"}
+
+// FindById retrieves an organization by its ID
+func (r *OrganizationsRepo) FindById(ctx context.Context, tx *sql.Tx, orgId int) (models.Organization, error) {
+	var org models.Organization
+	query := "SELECT org_id, name, created_at, updated_at FROM organizations WHERE org_id = ?"
+
+	err := tx.QueryRowContext(ctx, query, orgId).Scan(&org.OrgId, &org.Name, &org.CreatedAt, &org.UpdatedAt)
+	return org, err
+}
+
+// FindByUserId retrieves the organizations a user belongs to with their role, by name
+func (r *OrganizationsRepo) FindByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.OrganizationMembership, error) {
+	query := `SELECT o.org_id, o.name, o.created_at, o.updated_at, m.role,
+		(SELECT COUNT(*) FROM organization_members c WHERE c.org_id = o.org_id)
+		FROM organizations o
+		JOIN organization_members m ON m.org_id = o.org_id
+		WHERE m.user_id = ?
+		ORDER BY o.name`
+
+	rows, err := tx.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memberships []models.OrganizationMembership
+	for rows.Next() {
+		var membership models.OrganizationMembership
+		if err := rows.Scan(
+			&membership.OrgId,
+			&membership.Name,
+			&membership.CreatedAt,
+			&membership.UpdatedAt,
+			&membership.Role,
+			&membership.MemberCount,
+		); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+
+	return memberships, rows.Err()
+}
+
+// FindMembers retrieves the members of an organization, owners first
+func (r *OrganizationsRepo) FindMembers(ctx context.Context, tx *sql.Tx, orgId int) ([]models.OrganizationMember, error) {
+	query := `SELECT m.org_id, m.user_id, u.username, m.role, m.created_at
+		FROM organization_members m
+		JOIN users u ON u.user_id = m.user_id
+		WHERE m.org_id = ?
+		ORDER BY m.role = 'owner' DESC, u.username`
+
+	rows, err := tx.QueryContext(ctx, query, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.OrganizationMember
+	for rows.Next() {
+		var member models.OrganizationMember
+		if err := rows.Scan(&member.OrgId, &member.UserId, &member.Username, &member.Role, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// FindMember retrieves the membership of a user in an organization, sql.ErrNoRows when they are not a member
+func (r *OrganizationsRepo) FindMember(ctx context.Context, tx *sql.Tx, orgId, userId int) (models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	query := `SELECT m.org_id, m.user_id, u.username, m.role, m.created_at
+		FROM organization_members m
+		JOIN users u ON u.user_id = m.user_id
+		WHERE m.org_id = ? AND m.user_id = ?`
+
+	err := tx.QueryRowContext(ctx, query, orgId, userId).Scan(&member.OrgId, &member.UserId, &member.Username, &member.Role, &member.CreatedAt)
+	return member, err
+}
+
+// Create stores a new organization with ownerId as its first owner and returns its ID
+func (r *OrganizationsRepo) Create(ctx context.Context, tx *sql.Tx, orgData models.OrganizationCreate, ownerId int) (int, error) {
+	var orgId int
+	if err := tx.QueryRowContext(ctx, "INSERT INTO organizations (name) VALUES (?) RETURNING org_id", orgData.Name).Scan(&orgId); err != nil {
+		return 0, err
+	}
+
+	if err := r.AddMember(ctx, tx, orgId, ownerId, models.ORG_ROLE_OWNER); err != nil {
+		return 0, err
+	}
+
+	return orgId, nil
+}
+
+// AddMember adds a user to an organization with the role
+func (r *OrganizationsRepo) AddMember(ctx context.Context, tx *sql.Tx, orgId, userId int, role string) error {
+	query := "INSERT INTO organization_members (org_id, user_id, role) VALUES (?, ?, ?)"
+	_, err := tx.ExecContext(ctx, query, orgId, userId, role)
+	return err
+}
+
+// RemoveMember removes a user from an organization, the rows they created there stay with it
+func (r *OrganizationsRepo) RemoveMember(ctx context.Context, tx *sql.Tx, orgId, userId int) error {
+	query := "DELETE FROM organization_members WHERE org_id = ? AND user_id = ?"
+	_, err := tx.ExecContext(ctx, query, orgId, userId)
+	return err
+}
+
+// CountOwners returns how many owners an organization has
+func (r *OrganizationsRepo) CountOwners(ctx context.Context, tx *sql.Tx, orgId int) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM organization_members WHERE org_id = ? AND role = ?"
+
+	err := tx.QueryRowContext(ctx, query, orgId, models.ORG_ROLE_OWNER).Scan(&count)
+	return count, err
+}
+
+// CountOwnedRows returns how many projects and master data rows belong to an organization
+func (r *OrganizationsRepo) CountOwnedRows(ctx context.Context, tx *sql.Tx, orgId int) (int, error) {
+	total := 0
+	for _, table := range ownedTables {
+		var count int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+" WHERE org_id = ?", orgId).Scan(&count); err != nil {
+			return 0, err
+		}
+		total += count
+	}
+
+	return total, nil
+}
+
+// Delete removes an organization with its memberships. The caller checks it owns no rows first,
+// org_id columns have no foreign key to clean them up.
+func (r *OrganizationsRepo) Delete(ctx context.Context, tx *sql.Tx, orgId int) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM organization_members WHERE org_id = ?", orgId); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "DELETE FROM organizations WHERE org_id = ?", orgId)
+	return err
+}
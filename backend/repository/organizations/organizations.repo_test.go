@@ -0,0 +1,68 @@
+package organizations_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/organizations"
+)
+
+// TestOrganizations_Membership verifies the creator becomes the owner, members are listed
+// with their role, and an organization owning rows is reported as such
+func TestOrganizations_Membership(t *testing.T) {
+	ctx := t.Context()
+
+	dbtest.Run(t, func(t *testing.T, db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec("INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret'), (2, 'sari', 'secret')"); err != nil {
+			t.Fatalf("Failed to insert users: %v", err)
+		}
+
+		repo := organizations.NewOrganizationsRepo()
+
+		orgId, err := repo.Create(ctx, tx, models.OrganizationCreate{Name: "CV Maju"}, 1)
+		if err != nil {
+			t.Fatalf("Failed to create organization: %v", err)
+		}
+		if err := repo.AddMember(ctx, tx, orgId, 2, models.ORG_ROLE_MEMBER); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+
+		memberships, err := repo.FindByUserId(ctx, tx, 2)
+		if err != nil || len(memberships) != 1 {
+			t.Fatalf("Expected one membership, got %+v (%v)", memberships, err)
+		}
+		if memberships[0].Name != "CV Maju" || memberships[0].Role != models.ORG_ROLE_MEMBER || memberships[0].MemberCount != 2 {
+			t.Errorf("Unexpected membership: %+v", memberships[0])
+		}
+
+		members, err := repo.FindMembers(ctx, tx, orgId)
+		if err != nil || len(members) != 2 || members[0].Username != "budi" || members[0].Role != models.ORG_ROLE_OWNER {
+			t.Errorf("Expected the owner listed first, got %+v (%v)", members, err)
+		}
+		if owners, err := repo.CountOwners(ctx, tx, orgId); err != nil || owners != 1 {
+			t.Errorf("Expected one owner, got %d (%v)", owners, err)
+		}
+
+		if _, err := tx.Exec("INSERT INTO master_materials (user_id, org_id, material_name, unit, default_unit_price) VALUES (NULL, ?, 'Cement', 'bag', 100.0)", orgId); err != nil {
+			t.Fatalf("Failed to insert material: %v", err)
+		}
+		if owned, err := repo.CountOwnedRows(ctx, tx, orgId); err != nil || owned != 1 {
+			t.Errorf("Expected one owned row, got %d (%v)", owned, err)
+		}
+
+		if err := repo.RemoveMember(ctx, tx, orgId, 2); err != nil {
+			t.Fatalf("Failed to remove member: %v", err)
+		}
+		if _, err := repo.FindMember(ctx, tx, orgId, 2); err != sql.ErrNoRows {
+			t.Errorf("Expected the removed member to be gone, got %v", err)
+		}
+	})
+}
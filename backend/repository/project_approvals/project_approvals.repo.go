@@ -0,0 +1,170 @@
+package project_approvals
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// Repository stores where projects are in the approval workflow and the history of each one
+type Repository interface {
+	FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) (models.ProjectApproval, error)
+	FindEvents(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ApprovalEvent, error)
+	FindPending(ctx context.Context, tx *sql.Tx, workspace models.Workspace, userId int) ([]models.PendingApproval, error)
+	Save(ctx context.Context, tx *sql.Tx, approval models.ProjectApproval) error
+	AddEvent(ctx context.Context, tx *sql.Tx, eventData models.ApprovalEventCreate) (int, error)
+}
+
+var _ Repository = (*ProjectApprovalsRepo)(nil)
+
+type ProjectApprovalsRepo struct{}
+
+func NewProjectApprovalsRepo() *ProjectApprovalsRepo {
+	return &ProjectApprovalsRepo{}
+}
+
+// FindByProjectId retrieves the approval state of a project, a project that was never
+// submitted is a draft of revision 1
+func (r *ProjectApprovalsRepo) FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) (models.ProjectApproval, error) {
+	query := "SELECT project_id, status, revision, COALESCE(updated_by, 0), updated_at FROM project_approvals WHERE project_id = ?"
+
+	var approval models.ProjectApproval
+	err := tx.QueryRowContext(ctx, query, projectId).Scan(
+		&approval.ProjectId,
+		&approval.Status,
+		&approval.Revision,
+		&approval.UpdatedBy,
+		&approval.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return models.NewProjectApproval(projectId), nil
+	}
+
+	return approval, err
+}
+
+// FindEvents retrieves the history of a project, newest first
+func (r *ProjectApprovalsRepo) FindEvents(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ApprovalEvent, error) {
+	query := `SELECT e.event_id, e.project_id, e.revision, e.action, e.from_status, e.to_status, e.comment,
+		e.total_cost, COALESCE(e.user_id, 0), COALESCE(u.username, ''), e.created_at
+		FROM project_approval_events e
+		LEFT JOIN users u ON u.user_id = e.user_id
+		WHERE e.project_id = ?
+		ORDER BY e.event_id DESC`
+
+	rows, err := tx.QueryContext(ctx, query, projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.ApprovalEvent
+	for rows.Next() {
+		var event models.ApprovalEvent
+		if err := rows.Scan(
+			&event.EventId,
+			&event.ProjectId,
+			&event.Revision,
+			&event.Action,
+			&event.FromStatus,
+			&event.ToStatus,
+			&event.Comment,
+			&event.TotalCost,
+			&event.UserId,
+			&event.Username,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// FindPending retrieves the submitted and in review projects of the workspace and those shared
+// with the user, the longest waiting first
+func (r *ProjectApprovalsRepo) FindPending(ctx context.Context, tx *sql.Tx, workspace models.Workspace, userId int) ([]models.PendingApproval, error) {
+	condition, args := workspace.ProjectCondition("p")
+	query := `SELECT p.project_id, p.user_id, COALESCE(p.org_id, 0), p.project_name, p.location, p.client_name,
+		p.created_at, p.updated_at, a.status, a.revision, COALESCE(a.updated_by, 0), a.updated_at, u.username
+		FROM project_approvals a
+		JOIN projects p ON p.project_id = a.project_id AND p.deleted_at IS NULL
+		JOIN users u ON u.user_id = p.user_id
+		WHERE a.status IN (?, ?)
+		AND (` + condition + ` OR EXISTS (SELECT 1 FROM project_members m WHERE m.project_id = p.project_id AND m.user_id = ?))
+		ORDER BY a.updated_at ASC, p.project_id ASC`
+
+	params := append([]interface{}{models.APPROVAL_STATUS_SUBMITTED, models.APPROVAL_STATUS_IN_REVIEW}, args...)
+	params = append(params, userId)
+
+	rows, err := tx.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []models.PendingApproval
+	for rows.Next() {
+		var project models.PendingApproval
+		if err := rows.Scan(
+			&project.ProjectId,
+			&project.UserId,
+			&project.OrgId,
+			&project.ProjectName,
+			&project.Location,
+			&project.ClientName,
+			&project.CreatedAt,
+			&project.UpdatedAt,
+			&project.Approval.Status,
+			&project.Approval.Revision,
+			&project.Approval.UpdatedBy,
+			&project.Approval.UpdatedAt,
+			&project.OwnerUsername,
+		); err != nil {
+			return nil, err
+		}
+		project.Approval.ProjectId = project.ProjectId
+		pending = append(pending, project)
+	}
+
+	return pending, rows.Err()
+}
+
+// Save stores the approval state of a project
+func (r *ProjectApprovalsRepo) Save(ctx context.Context, tx *sql.Tx, approval models.ProjectApproval) error {
+	query := `INSERT INTO project_approvals (project_id, status, revision, updated_by, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (project_id) DO UPDATE SET status = excluded.status, revision = excluded.revision,
+		updated_by = excluded.updated_by, updated_at = excluded.updated_at`
+
+	updatedBy := sql.NullInt64{Int64: int64(approval.UpdatedBy), Valid: approval.UpdatedBy != 0}
+	_, err := tx.ExecContext(ctx, query, approval.ProjectId, approval.Status, approval.Revision, updatedBy, models.UTCNow())
+	return err
+}
+
+// AddEvent adds a step or a comment to the history of a project and returns its ID
+func (r *ProjectApprovalsRepo) AddEvent(ctx context.Context, tx *sql.Tx, eventData models.ApprovalEventCreate) (int, error) {
+	query := `INSERT INTO project_approval_events (project_id, revision, action, from_status, to_status, comment, total_cost, user_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING event_id`
+
+	userId := sql.NullInt64{Int64: int64(eventData.UserId), Valid: eventData.UserId != 0}
+
+	var eventId int
+	if err := tx.QueryRowContext(ctx,
+		query,
+		eventData.ProjectId,
+		eventData.Revision,
+		eventData.Action,
+		eventData.FromStatus,
+		eventData.ToStatus,
+		eventData.Comment,
+		eventData.TotalCost,
+		userId,
+		models.UTCNow(),
+	).Scan(&eventId); err != nil {
+		return 0, err
+	}
+
+	return eventId, nil
+}
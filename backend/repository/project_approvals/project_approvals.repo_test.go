@@ -0,0 +1,110 @@
+package project_approvals_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
+)
+
+// TestProjectApprovals_Workflow verifies a project starts as a draft, keeps its saved status and
+// history, is listed as pending for the workspace and its members only while waiting for a
+// reviewer, and loses its approval data with the project
+func TestProjectApprovals_Workflow(t *testing.T) {
+	ctx := t.Context()
+
+	dbtest.Run(t, func(t *testing.T, db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec("INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret'), (2, 'sari', 'secret'), (3, 'joko', 'secret')"); err != nil {
+			t.Fatalf("Failed to insert users: %v", err)
+		}
+		if _, err := tx.Exec("INSERT INTO projects (project_id, user_id, project_name, location, client_name) VALUES (5, 1, 'Rumah Tinggal', 'Bandung', 'Pak Andi'), (6, 1, 'Gudang', 'Bogor', 'Bu Rina')"); err != nil {
+			t.Fatalf("Failed to insert projects: %v", err)
+		}
+		if _, err := tx.Exec("INSERT INTO project_members (project_id, user_id, role) VALUES (5, 2, 'viewer')"); err != nil {
+			t.Fatalf("Failed to insert member: %v", err)
+		}
+
+		repo := project_approvals.NewProjectApprovalsRepo()
+
+		approval, err := repo.FindByProjectId(ctx, tx, 5)
+		if err != nil {
+			t.Fatalf("Failed to find approval: %v", err)
+		}
+		if approval.Status != models.APPROVAL_STATUS_DRAFT || approval.Revision != 1 || approval.IsLocked() {
+			t.Errorf("Expected an unlocked draft of revision 1, got %+v", approval)
+		}
+
+		approval.Status = models.APPROVAL_STATUS_SUBMITTED
+		approval.UpdatedBy = 1
+		if err := repo.Save(ctx, tx, approval); err != nil {
+			t.Fatalf("Failed to save approval: %v", err)
+		}
+		if _, err := repo.AddEvent(ctx, tx, models.ApprovalEventCreate{ProjectId: 5, Revision: 1, Action: models.APPROVAL_ACTION_SUBMIT, FromStatus: models.APPROVAL_STATUS_DRAFT, ToStatus: models.APPROVAL_STATUS_SUBMITTED, TotalCost: 1500000, UserId: 1}); err != nil {
+			t.Fatalf("Failed to add event: %v", err)
+		}
+		if _, err := repo.AddEvent(ctx, tx, models.ApprovalEventCreate{ProjectId: 5, Revision: 1, Action: models.APPROVAL_ACTION_COMMENT, FromStatus: models.APPROVAL_STATUS_SUBMITTED, ToStatus: models.APPROVAL_STATUS_SUBMITTED, Comment: "Cek harga semen"}); err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+
+		saved, err := repo.FindByProjectId(ctx, tx, 5)
+		if err != nil {
+			t.Fatalf("Failed to find approval: %v", err)
+		}
+		if saved.Status != models.APPROVAL_STATUS_SUBMITTED || saved.UpdatedBy != 1 || !saved.IsLocked() {
+			t.Errorf("Expected a locked submitted project, got %+v", saved)
+		}
+
+		events, err := repo.FindEvents(ctx, tx, 5)
+		if err != nil {
+			t.Fatalf("Failed to list events: %v", err)
+		}
+		if len(events) != 2 || events[0].Comment != "Cek harga semen" || events[0].UserId != 0 || events[1].Username != "budi" || events[1].TotalCost != 1500000 {
+			t.Errorf("Expected the newest event first, got %+v", events)
+		}
+
+		for _, tc := range []struct {
+			name      string
+			workspace models.Workspace
+			userId    int
+			expected  int
+		}{
+			{"owner", models.Workspace{UserId: 1}, 1, 1},
+			{"member", models.Workspace{UserId: 2}, 2, 1},
+			{"stranger", models.Workspace{UserId: 3}, 3, 0},
+		} {
+			pending, err := repo.FindPending(ctx, tx, tc.workspace, tc.userId)
+			if err != nil {
+				t.Fatalf("Failed to list pending projects: %v", err)
+			}
+			if len(pending) != tc.expected {
+				t.Errorf("%s: expected %d pending projects, got %+v", tc.name, tc.expected, pending)
+			}
+			if len(pending) == 1 && (pending[0].ProjectId != 5 || pending[0].OwnerUsername != "budi" || pending[0].Approval.Status != models.APPROVAL_STATUS_SUBMITTED) {
+				t.Errorf("%s: unexpected pending project %+v", tc.name, pending[0])
+			}
+		}
+
+		saved.Status = models.APPROVAL_STATUS_APPROVED
+		if err := repo.Save(ctx, tx, saved); err != nil {
+			t.Fatalf("Failed to save approval: %v", err)
+		}
+		if pending, err := repo.FindPending(ctx, tx, models.Workspace{UserId: 1}, 1); err != nil || len(pending) != 0 {
+			t.Errorf("Expected an approved project not to be pending, got %+v %v", pending, err)
+		}
+
+		if _, err := tx.Exec("DELETE FROM projects WHERE project_id = 5"); err != nil {
+			t.Fatalf("Failed to delete project: %v", err)
+		}
+		if events, err := repo.FindEvents(ctx, tx, 5); err != nil || len(events) != 0 {
+			t.Errorf("Expected the history of a deleted project to be gone, got %+v %v", events, err)
+		}
+	})
+}
@@ -1,12 +1,28 @@
 package project_item_costs
 
 import (
+	"context"
 	"database/sql"
-	"time"
 
+	"github.com/momokii/go-rab-maker/backend/audit"
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores the material and labor costs of project work items
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, projectItemCostId int) (models.ProjectItemCost, error)
+	FindByWorkItemId(ctx context.Context, tx *sql.Tx, workItemId int) ([]models.ProjectItemCostWithDetails, error)
+	FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectItemCostWithDetails, error)
+	Create(ctx context.Context, tx *sql.Tx, cost models.ProjectItemCostCreate) error
+	CreateMultiple(ctx context.Context, tx *sql.Tx, costs []models.ProjectItemCostCreate) error
+	DeleteByWorkItemId(ctx context.Context, tx *sql.Tx, workItemId int) error
+	FindUnitPricesByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectItemPrice, error)
+	GetMaterialSummaryByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.MaterialSummary, error)
+	GetDetailedMaterialSummaryByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.DetailedMaterialSummary, error)
+}
+
+var _ Repository = (*ProjectItemCostsRepo)(nil)
+
 type ProjectItemCostsRepo struct{}
 
 func NewProjectItemCostsRepo() *ProjectItemCostsRepo {
@@ -14,7 +30,7 @@ func NewProjectItemCostsRepo() *ProjectItemCostsRepo {
 }
 
 // FindById retrieves a project item cost by its ID
-func (r *ProjectItemCostsRepo) FindById(tx *sql.Tx, projectItemCostId int) (models.ProjectItemCost, error) {
+func (r *ProjectItemCostsRepo) FindById(ctx context.Context, tx *sql.Tx, projectItemCostId int) (models.ProjectItemCost, error) {
 	query := `
 		SELECT
 			cost_id, work_item_id, item_type, master_item_id, item_name,
@@ -25,7 +41,7 @@ func (r *ProjectItemCostsRepo) FindById(tx *sql.Tx, projectItemCostId int) (mode
 	`
 
 	var cost models.ProjectItemCost
-	err := tx.QueryRow(query, projectItemCostId).Scan(
+	err := tx.QueryRowContext(ctx, query, projectItemCostId).Scan(
 		&cost.CostId,
 		&cost.WorkItemId,
 		&cost.ItemType,
@@ -48,7 +64,7 @@ func (r *ProjectItemCostsRepo) FindById(tx *sql.Tx, projectItemCostId int) (mode
 }
 
 // FindByWorkItemId retrieves all item costs for a specific work item
-func (r *ProjectItemCostsRepo) FindByWorkItemId(tx *sql.Tx, workItemId int) ([]models.ProjectItemCostWithDetails, error) {
+func (r *ProjectItemCostsRepo) FindByWorkItemId(ctx context.Context, tx *sql.Tx, workItemId int) ([]models.ProjectItemCostWithDetails, error) {
 	query := `
 		SELECT
 			pic.cost_id, pic.work_item_id, pic.item_type, pic.master_item_id, pic.item_name,
@@ -67,7 +83,7 @@ func (r *ProjectItemCostsRepo) FindByWorkItemId(tx *sql.Tx, workItemId int) ([]m
 		ORDER BY pic.item_type, pic.item_name
 	`
 
-	rows, err := tx.Query(query, workItemId)
+	rows, err := tx.QueryContext(ctx, query, workItemId)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +116,7 @@ func (r *ProjectItemCostsRepo) FindByWorkItemId(tx *sql.Tx, workItemId int) ([]m
 }
 
 // FindByProjectId retrieves all item costs for a specific project
-func (r *ProjectItemCostsRepo) FindByProjectId(tx *sql.Tx, projectId int) ([]models.ProjectItemCostWithDetails, error) {
+func (r *ProjectItemCostsRepo) FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectItemCostWithDetails, error) {
 	query := `
 		SELECT
 			pic.cost_id, pic.work_item_id, pic.item_type, pic.master_item_id, pic.item_name,
@@ -114,14 +130,14 @@ func (r *ProjectItemCostsRepo) FindByProjectId(tx *sql.Tx, projectId int) ([]mod
 				ELSE pic.unit
 			END as unit
 		FROM project_item_costs pic
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
 		LEFT JOIN master_materials mm ON pic.item_type = 'MATERIAL' AND pic.master_item_id = mm.material_id
 		LEFT JOIN master_labor_types mlt ON pic.item_type = 'LABOR' AND pic.master_item_id = mlt.labor_type_id
 		WHERE pwi.project_id = ?
 		ORDER BY pwi.description, pic.item_type, pic.item_name
 	`
 
-	rows, err := tx.Query(query, projectId)
+	rows, err := tx.QueryContext(ctx, query, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -155,16 +171,19 @@ func (r *ProjectItemCostsRepo) FindByProjectId(tx *sql.Tx, projectId int) ([]mod
 }
 
 // Create inserts a new project item cost
-func (r *ProjectItemCostsRepo) Create(tx *sql.Tx, cost models.ProjectItemCostCreate) error {
+func (r *ProjectItemCostsRepo) Create(ctx context.Context, tx *sql.Tx, cost models.ProjectItemCostCreate) error {
 	query := `
 		INSERT INTO project_item_costs
 		(work_item_id, item_type, master_item_id, item_name, coefficient,
 		 quantity_needed, unit, unit_price_at_creation, total_cost, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING cost_id
 	`
 
-	now := time.Now().Format("2006-01-02 15:04:05")
-	_, err := tx.Exec(
+	now := models.UTCNow()
+
+	var costId int
+	if err := tx.QueryRowContext(ctx,
 		query,
 		cost.WorkItemId,
 		cost.ItemType,
@@ -177,43 +196,17 @@ func (r *ProjectItemCostsRepo) Create(tx *sql.Tx, cost models.ProjectItemCostCre
 		cost.TotalCost,
 		now,
 		now,
-	)
+	).Scan(&costId); err != nil {
+		return err
+	}
 
-	return err
+	return audit.Created(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, costId)
 }
 
 // CreateMultiple inserts multiple project item costs in a single transaction
-func (r *ProjectItemCostsRepo) CreateMultiple(tx *sql.Tx, costs []models.ProjectItemCostCreate) error {
-	if len(costs) == 0 {
-		return nil
-	}
-
-	now := time.Now().Format("2006-01-02 15:04:05")
-
+func (r *ProjectItemCostsRepo) CreateMultiple(ctx context.Context, tx *sql.Tx, costs []models.ProjectItemCostCreate) error {
 	for _, cost := range costs {
-		query := `
-			INSERT INTO project_item_costs
-			(work_item_id, item_type, master_item_id, item_name, coefficient,
-			 quantity_needed, unit, unit_price_at_creation, total_cost, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		`
-
-		_, err := tx.Exec(
-			query,
-			cost.WorkItemId,
-			cost.ItemType,
-			cost.MasterItemId,
-			cost.ItemName,
-			cost.Coefficient,
-			cost.QuantityNeeded,
-			cost.Unit,
-			cost.UnitPriceAtCreation,
-			cost.TotalCost,
-			now,
-			now,
-		)
-
-		if err != nil {
+		if err := r.Create(ctx, tx, cost); err != nil {
 			return err
 		}
 	}
@@ -222,20 +215,65 @@ func (r *ProjectItemCostsRepo) CreateMultiple(tx *sql.Tx, costs []models.Project
 }
 
 // DeleteByWorkItemId deletes all item costs for a specific work item
-func (r *ProjectItemCostsRepo) DeleteByWorkItemId(tx *sql.Tx, workItemId int) error {
+func (r *ProjectItemCostsRepo) DeleteByWorkItemId(ctx context.Context, tx *sql.Tx, workItemId int) error {
+	before, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, "t.work_item_id = ?", workItemId)
+	if err != nil {
+		return err
+	}
+
 	query := `DELETE FROM project_item_costs WHERE work_item_id = ?`
-	_, err := tx.Exec(query, workItemId)
-	return err
+	if _, err := tx.ExecContext(ctx, query, workItemId); err != nil {
+		return err
+	}
+
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, before...)
 }
 
-// GetMaterialSummaryByProjectId retrieves a summary of all materials needed for a project
-func (r *ProjectItemCostsRepo) GetMaterialSummaryByProjectId(tx *sql.Tx, projectId int) ([]models.MaterialSummary, error) {
+// FindUnitPricesByProjectId returns the unit prices captured in a project's cost lines,
+// one per master item (the most recent line wins). Manual lines without a master item are left out.
+func (r *ProjectItemCostsRepo) FindUnitPricesByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectItemPrice, error) {
+	query := `
+		SELECT pic.item_type, pic.master_item_id, pic.unit_price_at_creation
+		FROM project_item_costs pic
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
+		WHERE pwi.project_id = ? AND pic.master_item_id != 0
+			AND pic.cost_id = (
+				SELECT MAX(latest.cost_id)
+				FROM project_item_costs latest
+				JOIN project_work_items latest_pwi ON latest.work_item_id = latest_pwi.work_item_id AND latest_pwi.deleted_at IS NULL
+				WHERE latest_pwi.project_id = pwi.project_id
+					AND latest.item_type = pic.item_type
+					AND latest.master_item_id = pic.master_item_id
+			)
+		ORDER BY pic.item_type, pic.master_item_id
+	`
+
+	rows, err := tx.QueryContext(ctx, query, projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []models.ProjectItemPrice
+	for rows.Next() {
+		var price models.ProjectItemPrice
+		if err := rows.Scan(&price.ItemType, &price.ItemId, &price.UnitPrice); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+
+	return prices, nil
+}
+
+// GetMaterialSummaryByProjectId retrieves a summary of all materials needed for a project.
+// Lines not linked to a master item, such as imported lump sums, are left out.
+func (r *ProjectItemCostsRepo) GetMaterialSummaryByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.MaterialSummary, error) {
 	query := `
 		SELECT
 			pic.master_item_id, pic.item_name,
 			SUM(pic.quantity_needed) as total_quantity,
 			CASE
-				WHEN pic.master_item_id = 0 THEN pic.unit
 				WHEN pic.item_type = 'MATERIAL' THEN COALESCE(mm.unit, pic.unit)
 				WHEN pic.item_type = 'LABOR' THEN COALESCE(mlt.unit, pic.unit)
 				ELSE pic.unit
@@ -243,13 +281,12 @@ func (r *ProjectItemCostsRepo) GetMaterialSummaryByProjectId(tx *sql.Tx, project
 			pic.item_type,
 			SUM(pic.total_cost) as total_cost
 		FROM project_item_costs pic
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
 		LEFT JOIN master_materials mm ON pic.item_type = 'MATERIAL' AND pic.master_item_id = mm.material_id
 		LEFT JOIN master_labor_types mlt ON pic.item_type = 'LABOR' AND pic.master_item_id = mlt.labor_type_id
-		WHERE pwi.project_id = ?
+		WHERE pwi.project_id = ? AND pic.master_item_id <> 0
 		GROUP BY pic.master_item_id, pic.item_name,
 		         CASE
-		             WHEN pic.master_item_id = 0 THEN pic.unit
 		             WHEN pic.item_type = 'MATERIAL' THEN COALESCE(mm.unit, pic.unit)
 		             WHEN pic.item_type = 'LABOR' THEN COALESCE(mlt.unit, pic.unit)
 		             ELSE pic.unit
@@ -258,7 +295,7 @@ func (r *ProjectItemCostsRepo) GetMaterialSummaryByProjectId(tx *sql.Tx, project
 		ORDER BY pic.item_type, pic.item_name
 	`
 
-	rows, err := tx.Query(query, projectId)
+	rows, err := tx.QueryContext(ctx, query, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -284,14 +321,14 @@ func (r *ProjectItemCostsRepo) GetMaterialSummaryByProjectId(tx *sql.Tx, project
 	return summary, nil
 }
 
-// GetDetailedMaterialSummaryByProjectId retrieves a detailed summary with work item breakdown
-func (r *ProjectItemCostsRepo) GetDetailedMaterialSummaryByProjectId(tx *sql.Tx, projectId int) ([]models.DetailedMaterialSummary, error) {
+// GetDetailedMaterialSummaryByProjectId retrieves a detailed summary with work item breakdown,
+// leaving out the lines not linked to a master item like GetMaterialSummaryByProjectId
+func (r *ProjectItemCostsRepo) GetDetailedMaterialSummaryByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.DetailedMaterialSummary, error) {
 	// First get all unique items for the project
 	query := `
 		SELECT DISTINCT
 			pic.master_item_id, pic.item_name,
 			CASE
-				WHEN pic.master_item_id = 0 THEN pic.unit
 				WHEN pic.item_type = 'MATERIAL' THEN COALESCE(mm.unit, pic.unit)
 				WHEN pic.item_type = 'LABOR' THEN COALESCE(mlt.unit, pic.unit)
 				ELSE pic.unit
@@ -300,13 +337,12 @@ func (r *ProjectItemCostsRepo) GetDetailedMaterialSummaryByProjectId(tx *sql.Tx,
 			SUM(pic.total_cost) as total_cost,
 			SUM(pic.quantity_needed) as total_quantity
 		FROM project_item_costs pic
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
 		LEFT JOIN master_materials mm ON pic.item_type = 'MATERIAL' AND pic.master_item_id = mm.material_id
 		LEFT JOIN master_labor_types mlt ON pic.item_type = 'LABOR' AND pic.master_item_id = mlt.labor_type_id
-		WHERE pwi.project_id = ?
+		WHERE pwi.project_id = ? AND pic.master_item_id <> 0
 		GROUP BY pic.master_item_id, pic.item_name,
 		         CASE
-		             WHEN pic.master_item_id = 0 THEN pic.unit
 		             WHEN pic.item_type = 'MATERIAL' THEN COALESCE(mm.unit, pic.unit)
 		             WHEN pic.item_type = 'LABOR' THEN COALESCE(mlt.unit, pic.unit)
 		             ELSE pic.unit
@@ -315,7 +351,7 @@ func (r *ProjectItemCostsRepo) GetDetailedMaterialSummaryByProjectId(tx *sql.Tx,
 		ORDER BY pic.item_type, pic.item_name
 	`
 
-	rows, err := tx.Query(query, projectId)
+	rows, err := tx.QueryContext(ctx, query, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -337,7 +373,7 @@ func (r *ProjectItemCostsRepo) GetDetailedMaterialSummaryByProjectId(tx *sql.Tx,
 		}
 
 		// Get work item breakdown for this item
-		workItemBreakdown, err := r.getWorkItemBreakdownForItem(tx, projectId, item.ItemId, item.ItemType)
+		workItemBreakdown, err := r.getWorkItemBreakdownForItem(ctx, tx, projectId, item.ItemId, item.ItemType)
 		if err != nil {
 			return nil, err
 		}
@@ -350,7 +386,7 @@ func (r *ProjectItemCostsRepo) GetDetailedMaterialSummaryByProjectId(tx *sql.Tx,
 }
 
 // getWorkItemBreakdownForItem gets the breakdown of a specific item across work items
-func (r *ProjectItemCostsRepo) getWorkItemBreakdownForItem(tx *sql.Tx, projectId, itemId int, itemType string) ([]models.WorkItemBreakdown, error) {
+func (r *ProjectItemCostsRepo) getWorkItemBreakdownForItem(ctx context.Context, tx *sql.Tx, projectId, itemId int, itemType string) ([]models.WorkItemBreakdown, error) {
 	query := `
 		SELECT
 			pic.work_item_id,
@@ -360,12 +396,12 @@ func (r *ProjectItemCostsRepo) getWorkItemBreakdownForItem(tx *sql.Tx, projectId
 			pwi.volume,
 			pic.coefficient
 		FROM project_item_costs pic
-		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
+		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id AND pwi.deleted_at IS NULL
 		WHERE pwi.project_id = ? AND pic.master_item_id = ? AND pic.item_type = ?
 		ORDER BY pwi.created_at
 	`
 
-	rows, err := tx.Query(query, projectId, itemId, itemType)
+	rows, err := tx.QueryContext(ctx, query, projectId, itemId, itemType)
 	if err != nil {
 		return nil, err
 	}
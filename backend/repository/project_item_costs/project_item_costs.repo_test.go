@@ -0,0 +1,56 @@
+package project_item_costs_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
+)
+
+// TestMaterialSummary_SkipsUnlinkedLines verifies lines without a master item, like the lump sums
+// of an imported RAB, stay out of both material summaries of the project
+func TestMaterialSummary_SkipsUnlinkedLines(t *testing.T) {
+	ctx := t.Context()
+
+	dbtest.Run(t, func(t *testing.T, db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		for _, statement := range []string{
+			"INSERT INTO users (user_id, username, password) VALUES (1, 'testuser', 'secret')",
+			"INSERT INTO master_work_categories (category_id, user_id, category_name) VALUES (1, 1, 'Persiapan')",
+			"INSERT INTO master_materials (material_id, user_id, material_name, unit, default_unit_price) VALUES (1, 1, 'Semen Portland', 'kg', 1500)",
+			"INSERT INTO projects (project_id, user_id, project_name) VALUES (1, 1, 'Gudang')",
+			"INSERT INTO project_work_items (work_item_id, project_id, category_id, description, volume, unit) VALUES (1, 1, 1, 'Lantai kerja', 10, 'm2'), (2, 1, 1, 'Mobilisasi', 1, 'ls')",
+			`INSERT INTO project_item_costs (work_item_id, item_type, master_item_id, item_name, coefficient, quantity_needed, unit, unit_price_at_creation, total_cost) VALUES
+				(1, 'MATERIAL', 1, 'Semen Portland', 5, 50, 'kg', 1500, 75000),
+				(2, 'MATERIAL', 0, 'Mobilisasi', 1, 1, 'ls', 2000000, 2000000)`,
+		} {
+			if _, err := tx.Exec(statement); err != nil {
+				t.Fatalf("Failed to insert test data: %v", err)
+			}
+		}
+
+		repo := project_item_costs.NewProjectItemCostsRepo()
+
+		summary, err := repo.GetMaterialSummaryByProjectId(ctx, tx, 1)
+		if err != nil {
+			t.Fatalf("GetMaterialSummaryByProjectId failed: %v", err)
+		}
+		if len(summary) != 1 || summary[0].ItemName != "Semen Portland" {
+			t.Errorf("Expected only Semen Portland in the summary, got %+v", summary)
+		}
+
+		detailed, err := repo.GetDetailedMaterialSummaryByProjectId(ctx, tx, 1)
+		if err != nil {
+			t.Fatalf("GetDetailedMaterialSummaryByProjectId failed: %v", err)
+		}
+		if len(detailed) != 1 || detailed[0].ItemName != "Semen Portland" {
+			t.Errorf("Expected only Semen Portland in the detailed summary, got %+v", detailed)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package project_members
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// Repository stores the users a project is shared with
+type Repository interface {
+	FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectMember, error)
+	FindMember(ctx context.Context, tx *sql.Tx, projectId, userId int) (models.ProjectMember, error)
+	FindSharedWithUser(ctx context.Context, tx *sql.Tx, userId int) ([]models.SharedProject, error)
+	Save(ctx context.Context, tx *sql.Tx, member models.ProjectMember) error
+	Remove(ctx context.Context, tx *sql.Tx, projectId, userId int) error
+}
+
+var _ Repository = (*ProjectMembersRepo)(nil)
+
+type ProjectMembersRepo struct{}
+
+func NewProjectMembersRepo() *ProjectMembersRepo {
+	return &ProjectMembersRepo{}
+}
+
+const memberColumns = "m.project_id, m.user_id, u.username, m.role, COALESCE(m.invited_by, 0), m.created_at"
+
+func memberDest(member *models.ProjectMember) []any {
+	return []any{&member.ProjectId, &member.UserId, &member.Username, &member.Role, &member.InvitedBy, &member.CreatedAt}
+}
+
+// FindByProjectId retrieves the users a project is shared with, editors first
+func (r *ProjectMembersRepo) FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectMember, error) {
+	query := "SELECT " + memberColumns + `
+		FROM project_members m
+		JOIN users u ON u.user_id = m.user_id
+		WHERE m.project_id = ?
+		ORDER BY m.role = 'editor' DESC, u.username`
+
+	rows, err := tx.QueryContext(ctx, query, projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.ProjectMember
+	for rows.Next() {
+		var member models.ProjectMember
+		if err := rows.Scan(memberDest(&member)...); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// FindMember retrieves the membership of a user in a project, sql.ErrNoRows when it is not shared with them
+func (r *ProjectMembersRepo) FindMember(ctx context.Context, tx *sql.Tx, projectId, userId int) (models.ProjectMember, error) {
+	var member models.ProjectMember
+	query := "SELECT " + memberColumns + `
+		FROM project_members m
+		JOIN users u ON u.user_id = m.user_id
+		WHERE m.project_id = ? AND m.user_id = ?`
+
+	err := tx.QueryRowContext(ctx, query, projectId, userId).Scan(memberDest(&member)...)
+	return member, err
+}
+
+// FindSharedWithUser retrieves the projects shared with a user with their role and the
+// project owner's username, newest share first
+func (r *ProjectMembersRepo) FindSharedWithUser(ctx context.Context, tx *sql.Tx, userId int) ([]models.SharedProject, error) {
+	query := `SELECT p.project_id, p.user_id, COALESCE(p.org_id, 0), p.project_name, p.location, p.client_name,
+		p.created_at, p.updated_at, m.role, u.username
+		FROM project_members m
+		JOIN projects p ON p.project_id = m.project_id AND p.deleted_at IS NULL
+		JOIN users u ON u.user_id = p.user_id
+		WHERE m.user_id = ?
+		ORDER BY m.created_at DESC, p.project_id DESC`
+
+	rows, err := tx.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shared []models.SharedProject
+	for rows.Next() {
+		var project models.SharedProject
+		if err := rows.Scan(
+			&project.ProjectId,
+			&project.UserId,
+			&project.OrgId,
+			&project.ProjectName,
+			&project.Location,
+			&project.ClientName,
+			&project.CreatedAt,
+			&project.UpdatedAt,
+			&project.Role,
+			&project.OwnerUsername,
+		); err != nil {
+			return nil, err
+		}
+		shared = append(shared, project)
+	}
+
+	return shared, rows.Err()
+}
+
+// Save shares a project with a user, or changes their role when it already is
+func (r *ProjectMembersRepo) Save(ctx context.Context, tx *sql.Tx, member models.ProjectMember) error {
+	query := `INSERT INTO project_members (project_id, user_id, role, invited_by) VALUES (?, ?, ?, ?)
+		ON CONFLICT (project_id, user_id) DO UPDATE SET role = excluded.role`
+
+	invitedBy := sql.NullInt64{Int64: int64(member.InvitedBy), Valid: member.InvitedBy != 0}
+	_, err := tx.ExecContext(ctx, query, member.ProjectId, member.UserId, member.Role, invitedBy)
+	return err
+}
+
+// Remove stops sharing a project with a user
+func (r *ProjectMembersRepo) Remove(ctx context.Context, tx *sql.Tx, projectId, userId int) error {
+	query := "DELETE FROM project_members WHERE project_id = ? AND user_id = ?"
+	_, err := tx.ExecContext(ctx, query, projectId, userId)
+	return err
+}
@@ -0,0 +1,60 @@
+package project_members_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/project_members"
+)
+
+// TestProjectMembers_Sharing verifies a project is listed as shared with its members, sharing
+// it again changes the role, and deleting the project removes its members
+func TestProjectMembers_Sharing(t *testing.T) {
+	ctx := t.Context()
+
+	dbtest.Run(t, func(t *testing.T, db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec("INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret'), (2, 'sari', 'secret')"); err != nil {
+			t.Fatalf("Failed to insert users: %v", err)
+		}
+		if _, err := tx.Exec("INSERT INTO projects (project_id, user_id, project_name, location, client_name) VALUES (5, 1, 'Rumah Tinggal', 'Bandung', 'Pak Andi')"); err != nil {
+			t.Fatalf("Failed to insert project: %v", err)
+		}
+
+		repo := project_members.NewProjectMembersRepo()
+
+		if err := repo.Save(ctx, tx, models.ProjectMember{ProjectId: 5, UserId: 2, Role: models.PROJECT_ROLE_VIEWER, InvitedBy: 1}); err != nil {
+			t.Fatalf("Failed to share project: %v", err)
+		}
+		if err := repo.Save(ctx, tx, models.ProjectMember{ProjectId: 5, UserId: 2, Role: models.PROJECT_ROLE_EDITOR, InvitedBy: 1}); err != nil {
+			t.Fatalf("Failed to change role: %v", err)
+		}
+
+		member, err := repo.FindMember(ctx, tx, 5, 2)
+		if err != nil || member.Username != "sari" || member.Role != models.PROJECT_ROLE_EDITOR || member.InvitedBy != 1 {
+			t.Errorf("Expected sari as editor, got %+v (%v)", member, err)
+		}
+
+		shared, err := repo.FindSharedWithUser(ctx, tx, 2)
+		if err != nil || len(shared) != 1 {
+			t.Fatalf("Expected one shared project, got %+v (%v)", shared, err)
+		}
+		if shared[0].ProjectName != "Rumah Tinggal" || shared[0].OwnerUsername != "budi" || shared[0].Role != models.PROJECT_ROLE_EDITOR {
+			t.Errorf("Unexpected shared project: %+v", shared[0])
+		}
+
+		if _, err := tx.Exec("DELETE FROM projects WHERE project_id = 5"); err != nil {
+			t.Fatalf("Failed to delete project: %v", err)
+		}
+		if members, err := repo.FindByProjectId(ctx, tx, 5); err != nil || len(members) != 0 {
+			t.Errorf("Expected the members of a deleted project to be gone, got %+v (%v)", members, err)
+		}
+	})
+}
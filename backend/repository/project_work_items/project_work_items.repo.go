@@ -1,30 +1,47 @@
 package project_work_items
 
 import (
+	"context"
 	"database/sql"
-	"time"
 
+	"github.com/momokii/go-rab-maker/backend/audit"
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores the work items of a project
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, projectWorkItemId int) (models.ProjectWorkItem, error)
+	FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectWorkItem, error)
+	FindByProjectIdWithDetails(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectWorkItemWithDetails, error)
+	Create(ctx context.Context, tx *sql.Tx, workItem models.ProjectWorkItemCreate) (int, error)
+	Update(ctx context.Context, tx *sql.Tx, workItem models.ProjectWorkItem) error
+	Delete(ctx context.Context, tx *sql.Tx, workItem models.ProjectWorkItem) error
+	Restore(ctx context.Context, tx *sql.Tx, workItemId int) error
+	Purge(ctx context.Context, tx *sql.Tx, workItemId int) error
+	DeleteByProjectId(ctx context.Context, tx *sql.Tx, projectId int) error
+	GetProjectTotalCost(ctx context.Context, tx *sql.Tx, projectId int) (float64, error)
+}
+
+var _ Repository = (*ProjectWorkItemRepo)(nil)
+
 type ProjectWorkItemRepo struct{}
 
 func NewProjectWorkItemRepo() *ProjectWorkItemRepo {
 	return &ProjectWorkItemRepo{}
 }
 
-// FindById retrieves a project work item by its ID
-func (r *ProjectWorkItemRepo) FindById(tx *sql.Tx, projectWorkItemId int) (models.ProjectWorkItem, error) {
+// FindById retrieves a project work item by its ID, a work item in the trash is not found
+func (r *ProjectWorkItemRepo) FindById(ctx context.Context, tx *sql.Tx, projectWorkItemId int) (models.ProjectWorkItem, error) {
 	query := `
 		SELECT
 			work_item_id, project_id, category_id, description,
 			volume, unit, ahsp_template_id, created_at, updated_at
 		FROM project_work_items
-		WHERE work_item_id = ?
+		WHERE work_item_id = ? AND deleted_at IS NULL
 	`
 
 	var workItem models.ProjectWorkItem
-	err := tx.QueryRow(query, projectWorkItemId).Scan(
+	err := tx.QueryRowContext(ctx, query, projectWorkItemId).Scan(
 		&workItem.WorkItemId,
 		&workItem.ProjectId,
 		&workItem.CategoryId,
@@ -44,17 +61,17 @@ func (r *ProjectWorkItemRepo) FindById(tx *sql.Tx, projectWorkItemId int) (model
 }
 
 // FindByProjectId retrieves all work items for a specific project
-func (r *ProjectWorkItemRepo) FindByProjectId(tx *sql.Tx, projectId int) ([]models.ProjectWorkItem, error) {
+func (r *ProjectWorkItemRepo) FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectWorkItem, error) {
 	query := `
 		SELECT
 			work_item_id, project_id, category_id, description,
 			volume, unit, ahsp_template_id, created_at, updated_at
 		FROM project_work_items
-		WHERE project_id = ?
+		WHERE project_id = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
-	rows, err := tx.Query(query, projectId)
+	rows, err := tx.QueryContext(ctx, query, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +101,7 @@ func (r *ProjectWorkItemRepo) FindByProjectId(tx *sql.Tx, projectId int) ([]mode
 }
 
 // FindByProjectIdWithDetails retrieves work items with category and template details
-func (r *ProjectWorkItemRepo) FindByProjectIdWithDetails(tx *sql.Tx, projectId int) ([]models.ProjectWorkItemWithDetails, error) {
+func (r *ProjectWorkItemRepo) FindByProjectIdWithDetails(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ProjectWorkItemWithDetails, error) {
 	query := `
 		SELECT
 			pwi.work_item_id, pwi.project_id, pwi.category_id, pwi.description,
@@ -94,11 +111,11 @@ func (r *ProjectWorkItemRepo) FindByProjectIdWithDetails(tx *sql.Tx, projectId i
 		FROM project_work_items pwi
 		LEFT JOIN master_work_categories mwc ON pwi.category_id = mwc.category_id
 		LEFT JOIN ahsp_templates at ON pwi.ahsp_template_id = at.template_id
-		WHERE pwi.project_id = ?
+		WHERE pwi.project_id = ? AND pwi.deleted_at IS NULL
 		ORDER BY mwc.display_order ASC, pwi.created_at DESC
 	`
 
-	rows, err := tx.Query(query, projectId)
+	rows, err := tx.QueryContext(ctx, query, projectId)
 	if err != nil {
 		return nil, err
 	}
@@ -139,15 +156,19 @@ func (r *ProjectWorkItemRepo) FindByProjectIdWithDetails(tx *sql.Tx, projectId i
 }
 
 // Create inserts a new project work item and returns the ID
-func (r *ProjectWorkItemRepo) Create(tx *sql.Tx, workItem models.ProjectWorkItemCreate) (int, error) {
+func (r *ProjectWorkItemRepo) Create(ctx context.Context, tx *sql.Tx, workItem models.ProjectWorkItemCreate) (int, error) {
 	query := `
 		INSERT INTO project_work_items
 		(project_id, category_id, description, volume, unit, ahsp_template_id, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING work_item_id
 	`
 
-	now := time.Now().Format("2006-01-02 15:04:05")
-	result, err := tx.Exec(
+	now := models.UTCNow()
+
+	// RETURNING instead of LastInsertId, the PostgreSQL driver has no LastInsertId
+	var workItemId int
+	if err := tx.QueryRowContext(ctx,
 		query,
 		workItem.ProjectId,
 		workItem.CategoryId,
@@ -157,23 +178,24 @@ func (r *ProjectWorkItemRepo) Create(tx *sql.Tx, workItem models.ProjectWorkItem
 		workItem.AHSPTemplateId,
 		now,
 		now,
-	)
-
-	if err != nil {
+	).Scan(&workItemId); err != nil {
 		return 0, err
 	}
 
-	// Get the ID of the newly inserted work item
-	workItemId, err := result.LastInsertId()
-	if err != nil {
+	if err := audit.Created(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, workItemId); err != nil {
 		return 0, err
 	}
 
-	return int(workItemId), nil
+	return workItemId, nil
 }
 
 // Update updates an existing project work item
-func (r *ProjectWorkItemRepo) Update(tx *sql.Tx, workItem models.ProjectWorkItem) error {
+func (r *ProjectWorkItemRepo) Update(ctx context.Context, tx *sql.Tx, workItem models.ProjectWorkItem) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, workItem.WorkItemId)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE project_work_items
 		SET category_id = ?, description = ?, volume = ?, unit = ?,
@@ -181,8 +203,8 @@ func (r *ProjectWorkItemRepo) Update(tx *sql.Tx, workItem models.ProjectWorkItem
 		WHERE work_item_id = ?
 	`
 
-	now := time.Now().Format("2006-01-02 15:04:05")
-	_, err := tx.Exec(
+	now := models.UTCNow()
+	if _, err := tx.ExecContext(ctx,
 		query,
 		workItem.CategoryId,
 		workItem.Description,
@@ -191,28 +213,88 @@ func (r *ProjectWorkItemRepo) Update(tx *sql.Tx, workItem models.ProjectWorkItem
 		workItem.AHSPTemplateId,
 		now,
 		workItem.WorkItemId,
-	)
+	); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, before)
+}
+
+// Delete moves a project work item to the trash, its costs are kept for when it is restored
+func (r *ProjectWorkItemRepo) Delete(ctx context.Context, tx *sql.Tx, workItem models.ProjectWorkItem) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, workItem.WorkItemId)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE project_work_items SET deleted_at = ? WHERE work_item_id = ? AND deleted_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, models.UTCNow(), workItem.WorkItemId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, before)
+}
+
+// Restore takes a project work item out of the trash
+func (r *ProjectWorkItemRepo) Restore(ctx context.Context, tx *sql.Tx, workItemId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, workItemId)
+	if err != nil {
+		return err
+	}
 
-	return err
+	query := `UPDATE project_work_items SET deleted_at = NULL WHERE work_item_id = ?`
+	if _, err := tx.ExecContext(ctx, query, workItemId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, before)
 }
 
-// Delete deletes a project work item and cascades to associated project item costs
-func (r *ProjectWorkItemRepo) Delete(tx *sql.Tx, workItem models.ProjectWorkItem) error {
+// Purge deletes a project work item for good and cascades to associated project item costs
+func (r *ProjectWorkItemRepo) Purge(ctx context.Context, tx *sql.Tx, workItemId int) error {
+	costsBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, "t.work_item_id = ?", workItemId)
+	if err != nil {
+		return err
+	}
+
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, workItemId)
+	if err != nil {
+		return err
+	}
+
 	// STEP 1: Delete associated costs first (child table)
 	// This prevents orphaned records in project_item_costs
 	queryDeleteCosts := `DELETE FROM project_item_costs WHERE work_item_id = ?`
-	if _, err := tx.Exec(queryDeleteCosts, workItem.WorkItemId); err != nil {
+	if _, err := tx.ExecContext(ctx, queryDeleteCosts, workItemId); err != nil {
 		return err
 	}
 
 	// STEP 2: Delete the work item (parent table)
 	query := `DELETE FROM project_work_items WHERE work_item_id = ?`
-	_, err := tx.Exec(query, workItem.WorkItemId)
-	return err
+	if _, err := tx.ExecContext(ctx, query, workItemId); err != nil {
+		return err
+	}
+
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, costsBefore...); err != nil {
+		return err
+	}
+
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, before)
 }
 
-// DeleteByProjectId deletes all work items for a specific project and cascades to associated project item costs
-func (r *ProjectWorkItemRepo) DeleteByProjectId(tx *sql.Tx, projectId int) error {
+// DeleteByProjectId deletes all work items for a specific project for good, those in the trash
+// included, and cascades to associated project item costs
+func (r *ProjectWorkItemRepo) DeleteByProjectId(ctx context.Context, tx *sql.Tx, projectId int) error {
+	costsBefore, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, "w.project_id = ?", projectId)
+	if err != nil {
+		return err
+	}
+
+	before, err := audit.SnapshotWhere(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, "t.project_id = ?", projectId)
+	if err != nil {
+		return err
+	}
+
 	// STEP 1: Delete associated costs using subquery
 	// This prevents orphaned records in project_item_costs
 	queryDeleteCosts := `
@@ -222,27 +304,34 @@ func (r *ProjectWorkItemRepo) DeleteByProjectId(tx *sql.Tx, projectId int) error
 			FROM project_work_items
 			WHERE project_id = ?
 		)`
-	if _, err := tx.Exec(queryDeleteCosts, projectId); err != nil {
+	if _, err := tx.ExecContext(ctx, queryDeleteCosts, projectId); err != nil {
 		return err
 	}
 
 	// STEP 2: Delete work items
 	query := `DELETE FROM project_work_items WHERE project_id = ?`
-	_, err := tx.Exec(query, projectId)
-	return err
+	if _, err := tx.ExecContext(ctx, query, projectId); err != nil {
+		return err
+	}
+
+	if err := audit.Deleted(ctx, tx, models.AUDIT_ENTITY_ITEM_COST, costsBefore...); err != nil {
+		return err
+	}
+
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, before...)
 }
 
 // GetProjectTotalCost calculates the total cost of all work items in a project
-func (r *ProjectWorkItemRepo) GetProjectTotalCost(tx *sql.Tx, projectId int) (float64, error) {
+func (r *ProjectWorkItemRepo) GetProjectTotalCost(ctx context.Context, tx *sql.Tx, projectId int) (float64, error) {
 	query := `
 		SELECT COALESCE(SUM(total_cost), 0)
 		FROM project_item_costs pic
 		JOIN project_work_items pwi ON pic.work_item_id = pwi.work_item_id
-		WHERE pwi.project_id = ?
+		WHERE pwi.project_id = ? AND pwi.deleted_at IS NULL
 	`
 
 	var totalCost float64
-	err := tx.QueryRow(query, projectId).Scan(&totalCost)
+	err := tx.QueryRowContext(ctx, query, projectId).Scan(&totalCost)
 	if err != nil {
 		return 0, err
 	}
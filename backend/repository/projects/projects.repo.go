@@ -1,30 +1,47 @@
 package projects
 
 import (
+	"context"
 	"database/sql"
 	"math"
 
+	"github.com/momokii/go-rab-maker/backend/audit"
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores projects
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, projectId int) (models.Project, error)
+	Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput) ([]models.Project, models.PaginationInfo, error)
+	FindByWorkspace(ctx context.Context, tx *sql.Tx, workspace models.Workspace, paginationInput models.TablePaginationDataInput) ([]models.Project, models.PaginationInfo, error)
+	Create(ctx context.Context, tx *sql.Tx, projectData models.ProjectCreate) (int, error)
+	Update(ctx context.Context, tx *sql.Tx, projectData models.Project) error
+	Delete(ctx context.Context, tx *sql.Tx, projectData models.Project) error
+	Restore(ctx context.Context, tx *sql.Tx, projectId int) error
+	Purge(ctx context.Context, tx *sql.Tx, projectId int) error
+}
+
+var _ Repository = (*ProjectsRepo)(nil)
+
 type ProjectsRepo struct{}
 
 func NewProjectsRepo() *ProjectsRepo {
 	return &ProjectsRepo{}
 }
 
-// FindById retrieves a project by ID
-func (r *ProjectsRepo) FindById(tx *sql.Tx, projectId int) (models.Project, error) {
+// FindById retrieves a project by ID, a project in the trash is not found
+func (r *ProjectsRepo) FindById(ctx context.Context, tx *sql.Tx, projectId int) (models.Project, error) {
 	var project models.Project
 
-	query := "SELECT project_id, user_id, project_name, location, client_name, created_at, updated_at FROM projects WHERE project_id = ?"
+	query := "SELECT project_id, user_id, COALESCE(org_id, 0), project_name, location, client_name, created_at, updated_at FROM projects WHERE project_id = ? AND deleted_at IS NULL"
 
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query,
 		projectId,
 	).Scan(
 		&project.ProjectId,
 		&project.UserId,
+		&project.OrgId,
 		&project.ProjectName,
 		&project.Location,
 		&project.ClientName,
@@ -38,7 +55,7 @@ func (r *ProjectsRepo) FindById(tx *sql.Tx, projectId int) (models.Project, erro
 }
 
 // Find retrieves projects with pagination and search
-func (r *ProjectsRepo) Find(tx *sql.Tx, paginationInput models.TablePaginationDataInput) ([]models.Project, models.PaginationInfo, error) {
+func (r *ProjectsRepo) Find(ctx context.Context, tx *sql.Tx, paginationInput models.TablePaginationDataInput) ([]models.Project, models.PaginationInfo, error) {
 	var projects []models.Project
 	var paginationData models.PaginationInfo
 	var totalData int
@@ -47,19 +64,19 @@ func (r *ProjectsRepo) Find(tx *sql.Tx, paginationInput models.TablePaginationDa
 	offset := (paginationInput.Page - 1) * paginationInput.PerPage
 
 	params := []interface{}{}
-	base_query := "SELECT project_id, user_id, project_name, location, client_name, created_at, updated_at FROM projects WHERE 1=1"
-	query_total := "SELECT COUNT(project_id) FROM projects WHERE 1=1"
+	base_query := "SELECT project_id, user_id, COALESCE(org_id, 0), project_name, location, client_name, created_at, updated_at FROM projects WHERE deleted_at IS NULL"
+	query_total := "SELECT COUNT(project_id) FROM projects WHERE deleted_at IS NULL"
 
 	// if using search data
 	if paginationInput.Search != "" {
-		base_query += " AND (project_name LIKE ? OR location LIKE ? OR client_name LIKE ?)"
-		query_total += " AND (project_name LIKE ? OR location LIKE ? OR client_name LIKE ?)"
+		base_query += " AND (LOWER(project_name) LIKE LOWER(?) OR LOWER(location) LIKE LOWER(?) OR LOWER(client_name) LIKE LOWER(?))"
+		query_total += " AND (LOWER(project_name) LIKE LOWER(?) OR LOWER(location) LIKE LOWER(?) OR LOWER(client_name) LIKE LOWER(?))"
 		searchTerm := "%" + paginationInput.Search + "%"
 		params = append(params, searchTerm, searchTerm, searchTerm)
 	}
 
 	// get total data
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query_total,
 		params...,
 	).Scan(&totalData); err != nil {
@@ -70,7 +87,7 @@ func (r *ProjectsRepo) Find(tx *sql.Tx, paginationInput models.TablePaginationDa
 	base_query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
 	params = append(params, paginationInput.PerPage, offset)
 
-	rows, err := tx.Query(base_query, params...)
+	rows, err := tx.QueryContext(ctx, base_query, params...)
 	if err != nil {
 		return projects, paginationData, err
 	}
@@ -81,6 +98,7 @@ func (r *ProjectsRepo) Find(tx *sql.Tx, paginationInput models.TablePaginationDa
 		if err := rows.Scan(
 			&project.ProjectId,
 			&project.UserId,
+			&project.OrgId,
 			&project.ProjectName,
 			&project.Location,
 			&project.ClientName,
@@ -109,8 +127,9 @@ func (r *ProjectsRepo) Find(tx *sql.Tx, paginationInput models.TablePaginationDa
 	return projects, paginationData, nil
 }
 
-// FindByUserId retrieves projects for a specific user with pagination and search
-func (r *ProjectsRepo) FindByUserId(tx *sql.Tx, userId int, paginationInput models.TablePaginationDataInput) ([]models.Project, models.PaginationInfo, error) {
+// FindByWorkspace retrieves the projects of a workspace, the user's own or an organization's,
+// with pagination and search
+func (r *ProjectsRepo) FindByWorkspace(ctx context.Context, tx *sql.Tx, workspace models.Workspace, paginationInput models.TablePaginationDataInput) ([]models.Project, models.PaginationInfo, error) {
 	var projects []models.Project
 	var paginationData models.PaginationInfo
 	var totalData int
@@ -118,20 +137,20 @@ func (r *ProjectsRepo) FindByUserId(tx *sql.Tx, userId int, paginationInput mode
 	// Calculate offset for pagination
 	offset := (paginationInput.Page - 1) * paginationInput.PerPage
 
-	params := []interface{}{userId}
-	base_query := "SELECT project_id, user_id, project_name, location, client_name, created_at, updated_at FROM projects WHERE user_id = ?"
-	query_total := "SELECT COUNT(project_id) FROM projects WHERE user_id = ?"
+	condition, params := workspace.ProjectCondition("")
+	base_query := "SELECT project_id, user_id, COALESCE(org_id, 0), project_name, location, client_name, created_at, updated_at FROM projects WHERE deleted_at IS NULL AND " + condition
+	query_total := "SELECT COUNT(project_id) FROM projects WHERE deleted_at IS NULL AND " + condition
 
 	// if using search data
 	if paginationInput.Search != "" {
-		base_query += " AND (project_name LIKE ? OR location LIKE ? OR client_name LIKE ?)"
-		query_total += " AND (project_name LIKE ? OR location LIKE ? OR client_name LIKE ?)"
+		base_query += " AND (LOWER(project_name) LIKE LOWER(?) OR LOWER(location) LIKE LOWER(?) OR LOWER(client_name) LIKE LOWER(?))"
+		query_total += " AND (LOWER(project_name) LIKE LOWER(?) OR LOWER(location) LIKE LOWER(?) OR LOWER(client_name) LIKE LOWER(?))"
 		searchTerm := "%" + paginationInput.Search + "%"
 		params = append(params, searchTerm, searchTerm, searchTerm)
 	}
 
 	// get total data
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		query_total,
 		params...,
 	).Scan(&totalData); err != nil {
@@ -142,7 +161,7 @@ func (r *ProjectsRepo) FindByUserId(tx *sql.Tx, userId int, paginationInput mode
 	base_query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
 	params = append(params, paginationInput.PerPage, offset)
 
-	rows, err := tx.Query(base_query, params...)
+	rows, err := tx.QueryContext(ctx, base_query, params...)
 	if err != nil {
 		return projects, paginationData, err
 	}
@@ -153,6 +172,7 @@ func (r *ProjectsRepo) FindByUserId(tx *sql.Tx, userId int, paginationInput mode
 		if err := rows.Scan(
 			&project.ProjectId,
 			&project.UserId,
+			&project.OrgId,
 			&project.ProjectName,
 			&project.Location,
 			&project.ClientName,
@@ -182,40 +202,91 @@ func (r *ProjectsRepo) FindByUserId(tx *sql.Tx, userId int, paginationInput mode
 }
 
 // Create creates a new project
-func (r *ProjectsRepo) Create(tx *sql.Tx, projectData models.ProjectCreate) error {
-	query := "INSERT INTO projects (user_id, project_name, location, client_name) VALUES (?, ?, ?, ?)"
-	if _, err := tx.Exec(
+func (r *ProjectsRepo) Create(ctx context.Context, tx *sql.Tx, projectData models.ProjectCreate) (int, error) {
+	// RETURNING instead of LastInsertId, the PostgreSQL driver has no LastInsertId
+	query := "INSERT INTO projects (user_id, org_id, project_name, location, client_name) VALUES (?, ?, ?, ?, ?) RETURNING project_id"
+
+	var projectId int
+	if err := tx.QueryRowContext(ctx,
 		query,
 		projectData.UserId,
+		models.NullableOrgId(projectData.OrgId),
 		projectData.ProjectName,
 		projectData.Location,
 		projectData.ClientName,
-	); err != nil {
-		return err
+	).Scan(&projectId); err != nil {
+		return 0, err
+	}
+
+	if err := audit.Created(ctx, tx, models.AUDIT_ENTITY_PROJECT, projectId); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return projectId, nil
 }
 
 // Update updates an existing project
-func (r *ProjectsRepo) Update(tx *sql.Tx, projectData models.Project) error {
-	query := "UPDATE projects SET project_name = ?, location = ?, client_name = ? WHERE project_id = ? AND user_id = ?"
-	if _, err := tx.Exec(
+func (r *ProjectsRepo) Update(ctx context.Context, tx *sql.Tx, projectData models.Project) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_PROJECT, projectData.ProjectId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE projects SET project_name = ?, location = ?, client_name = ? WHERE project_id = ? AND user_id = ? AND COALESCE(org_id, 0) = ?"
+	if _, err := tx.ExecContext(ctx,
 		query,
 		projectData.ProjectName,
 		projectData.Location,
 		projectData.ClientName,
 		projectData.ProjectId,
 		projectData.UserId,
+		projectData.OrgId,
 	); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_PROJECT, before)
+}
+
+// Delete moves a project to the trash. Its work items stay as they are and come back with it
+// when it is restored.
+func (r *ProjectsRepo) Delete(ctx context.Context, tx *sql.Tx, projectData models.Project) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_PROJECT, projectData.ProjectId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE projects SET deleted_at = ? WHERE project_id = ? AND deleted_at IS NULL"
+	if _, err := tx.ExecContext(ctx, query, models.UTCNow(), projectData.ProjectId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_PROJECT, before)
 }
 
-// Delete deletes a project
-func (r *ProjectsRepo) Delete(tx *sql.Tx, projectData models.Project) error {
+// Restore takes a project out of the trash
+func (r *ProjectsRepo) Restore(ctx context.Context, tx *sql.Tx, projectId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_PROJECT, projectId)
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE projects SET deleted_at = NULL WHERE project_id = ?"
+	if _, err := tx.ExecContext(ctx, query, projectId); err != nil {
+		return err
+	}
+
+	return audit.Updated(ctx, tx, models.AUDIT_ENTITY_PROJECT, before)
+}
+
+// Purge deletes a project for good, with its work items and their costs. The audit log records
+// the project, not the work items and costs deleted with it.
+func (r *ProjectsRepo) Purge(ctx context.Context, tx *sql.Tx, projectId int) error {
+	before, err := audit.Snapshot(ctx, tx, models.AUDIT_ENTITY_PROJECT, projectId)
+	if err != nil {
+		return err
+	}
+
 	// -------------------------------------------------------------------------
 	// STEP 1: Delete Grandchild Data (project_item_costs)
 	// -------------------------------------------------------------------------
@@ -229,7 +300,7 @@ func (r *ProjectsRepo) Delete(tx *sql.Tx, projectData models.Project) error {
             WHERE project_id = ?
         )`
 
-	if _, err := tx.Exec(query_delete_item_costs, projectData.ProjectId); err != nil {
+	if _, err := tx.ExecContext(ctx, query_delete_item_costs, projectId); err != nil {
 		return err
 	}
 
@@ -238,7 +309,7 @@ func (r *ProjectsRepo) Delete(tx *sql.Tx, projectData models.Project) error {
 	// -------------------------------------------------------------------------
 	// Now that the costs are gone, we can safely remove the work items.
 	query_delete_work_items := "DELETE FROM project_work_items WHERE project_id = ?"
-	if _, err := tx.Exec(query_delete_work_items, projectData.ProjectId); err != nil {
+	if _, err := tx.ExecContext(ctx, query_delete_work_items, projectId); err != nil {
 		return err
 	}
 
@@ -247,9 +318,9 @@ func (r *ProjectsRepo) Delete(tx *sql.Tx, projectData models.Project) error {
 	// -------------------------------------------------------------------------
 	// Finally, remove the project itself.
 	query := "DELETE FROM projects WHERE project_id = ?"
-	if _, err := tx.Exec(query, projectData.ProjectId); err != nil {
+	if _, err := tx.ExecContext(ctx, query, projectId); err != nil {
 		return err
 	}
 
-	return nil
+	return audit.Deleted(ctx, tx, models.AUDIT_ENTITY_PROJECT, before)
 }
@@ -0,0 +1,122 @@
+package share_links
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// Repository stores the read-only share links of projects
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, linkId int) (models.ShareLink, error)
+	FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ShareLink, error)
+	FindByHash(ctx context.Context, tx *sql.Tx, tokenHash string) (models.ShareLink, error)
+	Create(ctx context.Context, tx *sql.Tx, linkData models.ShareLinkCreate) (int, error)
+	Revoke(ctx context.Context, tx *sql.Tx, linkId int) error
+	RecordAccess(ctx context.Context, tx *sql.Tx, linkId int) error
+}
+
+var _ Repository = (*ShareLinksRepo)(nil)
+
+type ShareLinksRepo struct{}
+
+func NewShareLinksRepo() *ShareLinksRepo {
+	return &ShareLinksRepo{}
+}
+
+const selectShareLinkColumns = `SELECT l.link_id, l.project_id, COALESCE(l.created_by, 0), l.label, l.token_prefix, l.token_hash,
+	COALESCE(l.password_hash, ''), COALESCE(l.expires_at, ''), COALESCE(l.revoked_at, ''), l.access_count,
+	COALESCE(l.last_accessed_at, ''), l.created_at
+	FROM project_share_links l`
+
+func scanShareLink(row interface{ Scan(dest ...any) error }) (models.ShareLink, error) {
+	var link models.ShareLink
+	err := row.Scan(
+		&link.LinkId,
+		&link.ProjectId,
+		&link.CreatedBy,
+		&link.Label,
+		&link.TokenPrefix,
+		&link.TokenHash,
+		&link.PasswordHash,
+		&link.ExpiresAt,
+		&link.RevokedAt,
+		&link.AccessCount,
+		&link.LastAccessedAt,
+		&link.CreatedAt,
+	)
+
+	return link, err
+}
+
+// FindById retrieves a share link by its ID
+func (r *ShareLinksRepo) FindById(ctx context.Context, tx *sql.Tx, linkId int) (models.ShareLink, error) {
+	return scanShareLink(tx.QueryRowContext(ctx, selectShareLinkColumns+" WHERE l.link_id = ?", linkId))
+}
+
+// FindByProjectId retrieves every link of a project, newest first, including revoked and expired ones
+func (r *ShareLinksRepo) FindByProjectId(ctx context.Context, tx *sql.Tx, projectId int) ([]models.ShareLink, error) {
+	rows, err := tx.QueryContext(ctx, selectShareLinkColumns+" WHERE l.project_id = ? ORDER BY l.link_id DESC", projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.ShareLink
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// FindByHash retrieves the link with the hash. Revoked and expired links are returned too,
+// the caller decides with IsActive.
+func (r *ShareLinksRepo) FindByHash(ctx context.Context, tx *sql.Tx, tokenHash string) (models.ShareLink, error) {
+	return scanShareLink(tx.QueryRowContext(ctx, selectShareLinkColumns+" WHERE l.token_hash = ?", tokenHash))
+}
+
+// Create stores a new link and returns its ID, an empty PasswordHash or ExpiresAt is stored as NULL
+func (r *ShareLinksRepo) Create(ctx context.Context, tx *sql.Tx, linkData models.ShareLinkCreate) (int, error) {
+	query := "INSERT INTO project_share_links (project_id, created_by, label, token_prefix, token_hash, password_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING link_id"
+
+	createdBy := sql.NullInt64{Int64: int64(linkData.CreatedBy), Valid: linkData.CreatedBy != 0}
+	passwordHash := sql.NullString{String: linkData.PasswordHash, Valid: linkData.PasswordHash != ""}
+	expiresAt := sql.NullString{String: linkData.ExpiresAt, Valid: linkData.ExpiresAt != ""}
+
+	var linkId int
+	if err := tx.QueryRowContext(ctx,
+		query,
+		linkData.ProjectId,
+		createdBy,
+		linkData.Label,
+		linkData.TokenPrefix,
+		linkData.TokenHash,
+		passwordHash,
+		expiresAt,
+		models.UTCNow(),
+	).Scan(&linkId); err != nil {
+		return 0, err
+	}
+
+	return linkId, nil
+}
+
+// Revoke marks a link as revoked, a link that is already revoked keeps its first revocation time
+func (r *ShareLinksRepo) Revoke(ctx context.Context, tx *sql.Tx, linkId int) error {
+	query := "UPDATE project_share_links SET revoked_at = ? WHERE link_id = ? AND revoked_at IS NULL"
+	_, err := tx.ExecContext(ctx, query, models.UTCNow(), linkId)
+	return err
+}
+
+// RecordAccess counts a view of the shared project
+func (r *ShareLinksRepo) RecordAccess(ctx context.Context, tx *sql.Tx, linkId int) error {
+	query := "UPDATE project_share_links SET access_count = access_count + 1, last_accessed_at = ? WHERE link_id = ?"
+	_, err := tx.ExecContext(ctx, query, models.UTCNow(), linkId)
+	return err
+}
@@ -0,0 +1,104 @@
+package share_links_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/share_links"
+)
+
+// TestShareLinks_Lifecycle verifies a link is found by its hash, counts its views, stops being
+// active once revoked, and is removed with its project
+func TestShareLinks_Lifecycle(t *testing.T) {
+	ctx := t.Context()
+
+	dbtest.Run(t, func(t *testing.T, db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec("INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret')"); err != nil {
+			t.Fatalf("Failed to insert user: %v", err)
+		}
+		if _, err := tx.Exec("INSERT INTO projects (project_id, user_id, project_name, location, client_name) VALUES (5, 1, 'Rumah Tinggal', 'Bandung', 'Pak Andi')"); err != nil {
+			t.Fatalf("Failed to insert project: %v", err)
+		}
+
+		repo := share_links.NewShareLinksRepo()
+
+		token, prefix, hash, err := models.NewShareLinkToken()
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+
+		linkId, err := repo.Create(ctx, tx, models.ShareLinkCreate{
+			ProjectId:   5,
+			CreatedBy:   1,
+			Label:       "Pak Andi",
+			TokenPrefix: prefix,
+			TokenHash:   hash,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create link: %v", err)
+		}
+		if _, err := repo.Create(ctx, tx, models.ShareLinkCreate{
+			ProjectId:    5,
+			CreatedBy:    1,
+			Label:        "Bank",
+			TokenPrefix:  "rs_second",
+			TokenHash:    models.HashAPIToken("rs_second"),
+			PasswordHash: "hashed",
+			ExpiresAt:    "2030-01-01 00:00:00",
+		}); err != nil {
+			t.Fatalf("Failed to create second link: %v", err)
+		}
+
+		found, err := repo.FindByHash(ctx, tx, models.HashAPIToken(token))
+		if err != nil {
+			t.Fatalf("Link not found by hash: %v", err)
+		}
+		if found.LinkId != linkId || found.Label != "Pak Andi" || found.HasPassword() || found.ExpiresAt != "" || !found.IsActive(time.Now()) {
+			t.Errorf("Unexpected link: %+v", found)
+		}
+
+		for range 2 {
+			if err := repo.RecordAccess(ctx, tx, linkId); err != nil {
+				t.Fatalf("Failed to record access: %v", err)
+			}
+		}
+
+		links, err := repo.FindByProjectId(ctx, tx, 5)
+		if err != nil {
+			t.Fatalf("Failed to list links: %v", err)
+		}
+		if len(links) != 2 || links[0].Label != "Bank" || !links[0].HasPassword() || links[0].ExpiresAt != "2030-01-01 00:00:00" {
+			t.Errorf("Expected the newest link first, got %+v", links)
+		}
+		if links[1].AccessCount != 2 || links[1].LastAccessedAt == "" {
+			t.Errorf("Expected two recorded views, got %+v", links[1])
+		}
+
+		if err := repo.Revoke(ctx, tx, linkId); err != nil {
+			t.Fatalf("Failed to revoke link: %v", err)
+		}
+		revoked, err := repo.FindById(ctx, tx, linkId)
+		if err != nil {
+			t.Fatalf("Failed to find link: %v", err)
+		}
+		if revoked.RevokedAt == "" || revoked.IsActive(time.Now()) {
+			t.Errorf("Expected the link to be revoked, got %+v", revoked)
+		}
+
+		if _, err := tx.Exec("DELETE FROM projects WHERE project_id = 5"); err != nil {
+			t.Fatalf("Failed to delete project: %v", err)
+		}
+		if _, err := repo.FindByHash(ctx, tx, models.HashAPIToken("rs_second")); err != sql.ErrNoRows {
+			t.Errorf("Expected the links of a deleted project to be gone, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package trash
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// Repository lists what is in the trash across the tables that soft delete their rows,
+// restoring and purging goes through the repository of each table
+type Repository interface {
+	Find(ctx context.Context, tx *sql.Tx, workspace models.Workspace, entity string) ([]models.TrashItem, error)
+	FindById(ctx context.Context, tx *sql.Tx, entity string, id int) (models.TrashItem, error)
+	FindDeletedBefore(ctx context.Context, tx *sql.Tx, before string) ([]models.TrashItem, error)
+}
+
+var _ Repository = (*TrashRepo)(nil)
+
+type TrashRepo struct{}
+
+func NewTrashRepo() *TrashRepo {
+	return &TrashRepo{}
+}
+
+// trashSource selects the trashed rows of one table. The owner columns are those of the
+// alias o, the project of a work item, and deleted is the column the row was deleted at.
+type trashSource struct {
+	entity  string
+	query   string
+	id      string
+	deleted string
+	project bool // owned like a project rather than like master data
+}
+
+// trashSources are in the order the automatic purge goes through them: work items before the
+// categories and templates they use
+var trashSources = []trashSource{
+	{
+		entity: models.AUDIT_ENTITY_PROJECT,
+		query: `SELECT o.project_id, o.project_name, COALESCE(o.location, ''), COALESCE(o.user_id, 0), COALESCE(o.org_id, 0), o.project_id, o.deleted_at
+			FROM projects o
+			WHERE o.deleted_at IS NOT NULL`,
+		id:      "o.project_id",
+		deleted: "o.deleted_at",
+		project: true,
+	},
+	{
+		// the work items of a project in the trash go and come back with it
+		entity: models.AUDIT_ENTITY_WORK_ITEM,
+		query: `SELECT t.work_item_id, t.description, o.project_name, COALESCE(o.user_id, 0), COALESCE(o.org_id, 0), o.project_id, t.deleted_at
+			FROM project_work_items t
+			JOIN projects o ON o.project_id = t.project_id AND o.deleted_at IS NULL
+			WHERE t.deleted_at IS NOT NULL`,
+		id:      "t.work_item_id",
+		deleted: "t.deleted_at",
+		project: true,
+	},
+	{
+		entity: models.AUDIT_ENTITY_AHSP_TEMPLATE,
+		query: `SELECT o.template_id, o.template_name, o.unit, COALESCE(o.user_id, 0), COALESCE(o.org_id, 0), 0, o.deleted_at
+			FROM ahsp_templates o
+			WHERE o.deleted_at IS NOT NULL`,
+		id:      "o.template_id",
+		deleted: "o.deleted_at",
+	},
+	{
+		entity: models.AUDIT_ENTITY_MATERIAL,
+		query: `SELECT o.material_id, o.material_name, o.unit, COALESCE(o.user_id, 0), COALESCE(o.org_id, 0), 0, o.deleted_at
+			FROM master_materials o
+			WHERE o.deleted_at IS NOT NULL`,
+		id:      "o.material_id",
+		deleted: "o.deleted_at",
+	},
+	{
+		entity: models.AUDIT_ENTITY_LABOR_TYPE,
+		query: `SELECT o.labor_type_id, o.role_name, o.unit, COALESCE(o.user_id, 0), COALESCE(o.org_id, 0), 0, o.deleted_at
+			FROM master_labor_types o
+			WHERE o.deleted_at IS NOT NULL`,
+		id:      "o.labor_type_id",
+		deleted: "o.deleted_at",
+	},
+	{
+		entity: models.AUDIT_ENTITY_WORK_CATEGORY,
+		query: `SELECT o.category_id, o.category_name, '', COALESCE(o.user_id, 0), COALESCE(o.org_id, 0), 0, o.deleted_at
+			FROM master_work_categories o
+			WHERE o.deleted_at IS NOT NULL`,
+		id:      "o.category_id",
+		deleted: "o.deleted_at",
+	},
+}
+
+// Find lists what the workspace has in the trash, narrowed to an entity unless it is empty,
+// the most recently deleted first
+func (r *TrashRepo) Find(ctx context.Context, tx *sql.Tx, workspace models.Workspace, entity string) ([]models.TrashItem, error) {
+	items := []models.TrashItem{}
+
+	for _, source := range trashSources {
+		if entity != "" && source.entity != entity {
+			continue
+		}
+
+		condition, args := workspace.MasterDataCondition("o")
+		if source.project {
+			condition, args = workspace.ProjectCondition("o")
+		}
+
+		found, err := findTrashItems(ctx, tx, source, source.query+" AND "+condition, args...)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, found...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt > items[j].DeletedAt
+	})
+
+	return items, nil
+}
+
+// FindById retrieves an item of the trash, sql.ErrNoRows when the row is not in the trash
+func (r *TrashRepo) FindById(ctx context.Context, tx *sql.Tx, entity string, id int) (models.TrashItem, error) {
+	for _, source := range trashSources {
+		if source.entity != entity {
+			continue
+		}
+
+		items, err := findTrashItems(ctx, tx, source, source.query+" AND "+source.id+" = ?", id)
+		if err != nil {
+			return models.TrashItem{}, err
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		return items[0], nil
+	}
+
+	return models.TrashItem{}, sql.ErrNoRows
+}
+
+// FindDeletedBefore lists the items of every workspace deleted before a UTC time in the
+// format of SQLite's CURRENT_TIMESTAMP, in the order they can be purged
+func (r *TrashRepo) FindDeletedBefore(ctx context.Context, tx *sql.Tx, before string) ([]models.TrashItem, error) {
+	items := []models.TrashItem{}
+
+	for _, source := range trashSources {
+		found, err := findTrashItems(ctx, tx, source, source.query+" AND "+source.deleted+" < ? ORDER BY "+source.deleted, before)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, found...)
+	}
+
+	return items, nil
+}
+
+func findTrashItems(ctx context.Context, tx *sql.Tx, source trashSource, query string, args ...interface{}) ([]models.TrashItem, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.TrashItem
+	for rows.Next() {
+		item := models.TrashItem{Entity: source.entity}
+		if err := rows.Scan(
+			&item.Id,
+			&item.Name,
+			&item.Detail,
+			&item.UserId,
+			&item.OrgId,
+			&item.ProjectId,
+			&item.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
@@ -0,0 +1,121 @@
+package trash_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/repository/trash"
+)
+
+// TestTrash_FindByWorkspace verifies a deleted project, work item or material leaves the
+// queries of its table for the trash of its workspace, a work item only while its project is
+// not in the trash itself, and comes back when restored
+func TestTrash_FindByWorkspace(t *testing.T) {
+	ctx := t.Context()
+
+	dbtest.Run(t, func(t *testing.T, db *sql.DB) {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		for _, query := range []string{
+			"INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret'), (2, 'sari', 'secret')",
+			"INSERT INTO master_work_categories (category_id, user_id, category_name) VALUES (1, 1, 'Pekerjaan Dinding')",
+			"INSERT INTO master_materials (material_id, user_id, material_name, unit, default_unit_price) VALUES (4, 1, 'Semen', 'kg', 1500), (5, 2, 'Pasir', 'm3', 250000)",
+			"INSERT INTO projects (project_id, user_id, project_name, location, client_name) VALUES (3, 1, 'Rumah Tinggal', 'Bandung', ''), (6, 1, 'Gudang', 'Bogor', '')",
+			"INSERT INTO project_work_items (work_item_id, project_id, category_id, description, volume, unit) VALUES (7, 3, 1, 'Pasangan bata', 10, 'm2'), (8, 6, 1, 'Plesteran', 20, 'm2')",
+		} {
+			if _, err := tx.Exec(query); err != nil {
+				t.Fatalf("Failed to insert test data: %v", err)
+			}
+		}
+
+		projectsRepo := projects.NewProjectsRepo()
+		workItemsRepo := project_work_items.NewProjectWorkItemRepo()
+		materialsRepo := master_materials.NewMasterMaterialsRepo()
+		repo := trash.NewTrashRepo()
+
+		if err := workItemsRepo.Delete(ctx, tx, models.ProjectWorkItem{WorkItemId: 7, ProjectId: 3}); err != nil {
+			t.Fatalf("Failed to delete work item: %v", err)
+		}
+		if err := projectsRepo.Delete(ctx, tx, models.Project{ProjectId: 6, UserId: 1}); err != nil {
+			t.Fatalf("Failed to delete project: %v", err)
+		}
+		if err := materialsRepo.Delete(ctx, tx, models.MasterMaterial{MaterialId: 4, UserId: 1}); err != nil {
+			t.Fatalf("Failed to delete material: %v", err)
+		}
+		if err := materialsRepo.Delete(ctx, tx, models.MasterMaterial{MaterialId: 5, UserId: 2}); err != nil {
+			t.Fatalf("Failed to delete material: %v", err)
+		}
+
+		// the deleted rows are gone from the queries of their tables
+		found, _, err := projectsRepo.FindByWorkspace(ctx, tx, models.Workspace{UserId: 1}, models.TablePaginationDataInput{Page: 1, PerPage: 10})
+		if err != nil {
+			t.Fatalf("Failed to find projects: %v", err)
+		}
+		if len(found) != 1 || found[0].ProjectId != 3 {
+			t.Errorf("Expected only the project outside the trash, got %+v", found)
+		}
+		if items, err := workItemsRepo.FindByProjectId(ctx, tx, 3); err != nil || len(items) != 0 {
+			t.Errorf("Expected no work items outside the trash, got %+v, err: %v", items, err)
+		}
+
+		items, err := repo.Find(ctx, tx, models.Workspace{UserId: 1}, "")
+		if err != nil {
+			t.Fatalf("Failed to find the trash: %v", err)
+		}
+		// Plesteran is not listed, it goes and comes back with its deleted project
+		names := map[string]bool{}
+		for _, item := range items {
+			names[item.Entity+" "+item.Name] = true
+		}
+		if len(items) != 3 || !names["project Gudang"] || !names["work_item Pasangan bata"] || !names["material Semen"] {
+			t.Errorf("Expected the project, work item and material of budi, got %+v", items)
+		}
+
+		materials, err := repo.Find(ctx, tx, models.Workspace{UserId: 2}, models.AUDIT_ENTITY_MATERIAL)
+		if err != nil {
+			t.Fatalf("Failed to find the trash: %v", err)
+		}
+		if len(materials) != 1 || materials[0].Name != "Pasir" || materials[0].Detail != "m3" || materials[0].UserId != 2 {
+			t.Errorf("Expected only the material of sari, got %+v", materials)
+		}
+
+		workItem, err := repo.FindById(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, 7)
+		if err != nil {
+			t.Fatalf("Failed to find work item in the trash: %v", err)
+		}
+		if workItem.ProjectId != 3 || workItem.Detail != "Rumah Tinggal" || workItem.UserId != 1 || workItem.DeletedAt == "" {
+			t.Errorf("Expected the work item with its project, got %+v", workItem)
+		}
+
+		if err := workItemsRepo.Restore(ctx, tx, 7); err != nil {
+			t.Fatalf("Failed to restore work item: %v", err)
+		}
+		if _, err := repo.FindById(ctx, tx, models.AUDIT_ENTITY_WORK_ITEM, 7); err != sql.ErrNoRows {
+			t.Errorf("Expected the restored work item to leave the trash, got %v", err)
+		}
+		if items, err := workItemsRepo.FindByProjectId(ctx, tx, 3); err != nil || len(items) != 1 {
+			t.Errorf("Expected the restored work item back in its project, got %+v, err: %v", items, err)
+		}
+
+		// everything deleted so far is before a time in the future, projects first
+		expired, err := repo.FindDeletedBefore(ctx, tx, "2999-01-01 00:00:00")
+		if err != nil {
+			t.Fatalf("Failed to find expired items: %v", err)
+		}
+		if len(expired) != 3 || expired[0].Entity != models.AUDIT_ENTITY_PROJECT {
+			t.Errorf("Expected the project and both materials, got %+v", expired)
+		}
+		if expired, err := repo.FindDeletedBefore(ctx, tx, "2000-01-01 00:00:00"); err != nil || len(expired) != 0 {
+			t.Errorf("Expected nothing deleted before 2000, got %+v, err: %v", expired, err)
+		}
+	})
+}
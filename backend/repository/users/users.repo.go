@@ -1,32 +1,56 @@
 package users
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/momokii/go-rab-maker/backend/models"
 )
 
+// Repository stores user accounts
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, userId int) (models.User, error)
+	FindByUsername(ctx context.Context, tx *sql.Tx, username string) (models.User, error)
+	FindAll(ctx context.Context, tx *sql.Tx) ([]models.User, error)
+	CountActiveByRole(ctx context.Context, tx *sql.Tx, role string) (int, error)
+	Create(ctx context.Context, tx *sql.Tx, userData models.UserCreate) error
+	Update(ctx context.Context, tx *sql.Tx, userData models.User) error
+	UpdateRole(ctx context.Context, tx *sql.Tx, userId int, role string) error
+	SoftDelete(ctx context.Context, tx *sql.Tx, userId int) error
+	Restore(ctx context.Context, tx *sql.Tx, userId int) error
+}
+
+var _ Repository = (*UsersRepo)(nil)
+
 type UsersRepo struct{}
 
 func NewUsersRepo() *UsersRepo {
 	return &UsersRepo{}
 }
 
-// FindById retrieves a user by their ID
-func (r *UsersRepo) FindById(tx *sql.Tx, userId int) (models.User, error) {
-	var user models.User
+const selectUserColumns = "SELECT user_id, username, password, role, created_at, updated_at, deleted_at FROM users"
 
-	query := "SELECT user_id, username, password, created_at, updated_at FROM users WHERE user_id = ?"
-	if err := tx.QueryRow(
-		query,
-		userId,
-	).Scan(
+func userDest(user *models.User) []any {
+	return []any{
 		&user.UserId,
 		&user.Username,
 		&user.Password,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
-	); err != nil {
+		&user.DeletedAt,
+	}
+}
+
+// FindById retrieves a user by their ID, disabled users included
+func (r *UsersRepo) FindById(ctx context.Context, tx *sql.Tx, userId int) (models.User, error) {
+	var user models.User
+
+	query := selectUserColumns + " WHERE user_id = ?"
+	if err := tx.QueryRowContext(ctx,
+		query,
+		userId,
+	).Scan(userDest(&user)...); err != nil {
 		return user, err
 	}
 
@@ -34,34 +58,63 @@ func (r *UsersRepo) FindById(tx *sql.Tx, userId int) (models.User, error) {
 }
 
 // FindByUsername retrieves an active (non-deleted) user by their username
-func (r *UsersRepo) FindByUsername(tx *sql.Tx, username string) (models.User, error) {
+func (r *UsersRepo) FindByUsername(ctx context.Context, tx *sql.Tx, username string) (models.User, error) {
 	var user models.User
 
-	query := "SELECT user_id, username, password, created_at, updated_at FROM users WHERE username = ?"
-	if err := tx.QueryRow(
+	query := selectUserColumns + " WHERE username = ? AND deleted_at IS NULL"
+	if err := tx.QueryRowContext(ctx,
 		query,
 		username,
-	).Scan(
-		&user.UserId,
-		&user.Username,
-		&user.Password,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	); err != nil {
+	).Scan(userDest(&user)...); err != nil {
 		return user, err
 	}
 
 	return user, nil
 }
 
-// Create creates a new user in the database
-func (r *UsersRepo) Create(tx *sql.Tx, userData models.UserCreate) error {
+// FindAll retrieves every user, active users first and then by username
+func (r *UsersRepo) FindAll(ctx context.Context, tx *sql.Tx) ([]models.User, error) {
+	query := selectUserColumns + " ORDER BY deleted_at IS NOT NULL, username, user_id"
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(userDest(&user)...); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// CountActiveByRole counts the users that are not disabled and have the role
+func (r *UsersRepo) CountActiveByRole(ctx context.Context, tx *sql.Tx, role string) (int, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE role = ? AND deleted_at IS NULL", role).Scan(&count)
+
+	return count, err
+}
+
+// Create creates a new user in the database, with DEFAULT_ROLE when no role is given
+func (r *UsersRepo) Create(ctx context.Context, tx *sql.Tx, userData models.UserCreate) error {
+	role := userData.Role
+	if role == "" {
+		role = models.DEFAULT_ROLE
+	}
 
-	query := "INSERT INTO users (username, password) VALUES (?, ?)"
-	if _, err := tx.Exec(
+	query := "INSERT INTO users (username, password, role) VALUES (?, ?, ?)"
+	if _, err := tx.ExecContext(ctx,
 		query,
 		userData.Username,
 		userData.Password,
+		role,
 	); err != nil {
 		return err
 	}
@@ -70,13 +123,14 @@ func (r *UsersRepo) Create(tx *sql.Tx, userData models.UserCreate) error {
 }
 
 // Update updates an existing user's information
-func (r *UsersRepo) Update(tx *sql.Tx, userData models.User) error {
+func (r *UsersRepo) Update(ctx context.Context, tx *sql.Tx, userData models.User) error {
 
-	query := "UPDATE users SET username = ?, password = ?, updated_at = CURRENT_TIMESTAMP WHERE user_id = ?"
-	if _, err := tx.Exec(
+	query := "UPDATE users SET username = ?, password = ?, updated_at = ? WHERE user_id = ?"
+	if _, err := tx.ExecContext(ctx,
 		query,
 		userData.Username,
 		userData.Password,
+		models.UTCNow(),
 		userData.UserId,
 	); err != nil {
 		return err
@@ -85,10 +139,25 @@ func (r *UsersRepo) Update(tx *sql.Tx, userData models.User) error {
 	return nil
 }
 
+// UpdateRole changes the role of a user
+func (r *UsersRepo) UpdateRole(ctx context.Context, tx *sql.Tx, userId int, role string) error {
+	query := "UPDATE users SET role = ?, updated_at = ? WHERE user_id = ?"
+	_, err := tx.ExecContext(ctx, query, role, models.UTCNow(), userId)
+	return err
+}
+
 // SoftDelete marks a user as deleted without removing the record
 // This preserves data for audit purposes while preventing login
-func (r *UsersRepo) SoftDelete(tx *sql.Tx, userId int) error {
-	query := `UPDATE users SET deleted_at = datetime('now') WHERE user_id = ?`
-	_, err := tx.Exec(query, userId)
+func (r *UsersRepo) SoftDelete(ctx context.Context, tx *sql.Tx, userId int) error {
+	query := `UPDATE users SET deleted_at = ? WHERE user_id = ?`
+	_, err := tx.ExecContext(ctx, query, models.UTCNow(), userId)
+	return err
+}
+
+// Restore lets a disabled user log in again. It fails on the unique username index when
+// another active account took the username in the meantime.
+func (r *UsersRepo) Restore(ctx context.Context, tx *sql.Tx, userId int) error {
+	query := `UPDATE users SET deleted_at = NULL WHERE user_id = ?`
+	_, err := tx.ExecContext(ctx, query, userId)
 	return err
 }
@@ -0,0 +1,187 @@
+package webhook_deliveries
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// Repository stores the webhook outbox, one delivery per event and webhook
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, deliveryId int) (models.WebhookDelivery, error)
+	FindByWebhookId(ctx context.Context, tx *sql.Tx, webhookId int, limit int) ([]models.WebhookDelivery, error)
+	FindDue(ctx context.Context, tx *sql.Tx, now string, limit int) ([]models.WebhookDueDelivery, error)
+	Create(ctx context.Context, tx *sql.Tx, deliveryData models.WebhookDeliveryCreate) (int, error)
+	Claim(ctx context.Context, tx *sql.Tx, deliveryId int, dueAt string, until string) (bool, error)
+	RecordAttempt(ctx context.Context, tx *sql.Tx, attempt models.WebhookDeliveryAttempt) error
+	Retry(ctx context.Context, tx *sql.Tx, deliveryId int) error
+	DeleteFinishedBefore(ctx context.Context, tx *sql.Tx, before string) (int64, error)
+}
+
+var _ Repository = (*WebhookDeliveriesRepo)(nil)
+
+type WebhookDeliveriesRepo struct{}
+
+func NewWebhookDeliveriesRepo() *WebhookDeliveriesRepo {
+	return &WebhookDeliveriesRepo{}
+}
+
+const selectDeliveryColumns = `SELECT d.delivery_id, d.webhook_id, d.event_id, d.event, d.payload, d.status, d.attempts,
+	d.next_attempt_at, COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), COALESCE(d.delivered_at, ''), d.created_at`
+
+func deliveryDest(delivery *models.WebhookDelivery) []any {
+	return []any{
+		&delivery.DeliveryId,
+		&delivery.WebhookId,
+		&delivery.EventId,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	}
+}
+
+// FindById retrieves a delivery by its ID
+func (r *WebhookDeliveriesRepo) FindById(ctx context.Context, tx *sql.Tx, deliveryId int) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := tx.QueryRowContext(ctx, selectDeliveryColumns+" FROM webhook_deliveries d WHERE d.delivery_id = ?", deliveryId).
+		Scan(deliveryDest(&delivery)...)
+
+	return delivery, err
+}
+
+// FindByWebhookId retrieves the newest deliveries of a webhook, at most limit
+func (r *WebhookDeliveriesRepo) FindByWebhookId(ctx context.Context, tx *sql.Tx, webhookId int, limit int) ([]models.WebhookDelivery, error) {
+	query := selectDeliveryColumns + " FROM webhook_deliveries d WHERE d.webhook_id = ? ORDER BY d.delivery_id DESC LIMIT ?"
+
+	rows, err := tx.QueryContext(ctx, query, webhookId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := rows.Scan(deliveryDest(&delivery)...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// FindDue retrieves pending deliveries whose next attempt is at or before now, oldest first.
+// Deliveries of disabled webhooks wait until the webhook is enabled again.
+func (r *WebhookDeliveriesRepo) FindDue(ctx context.Context, tx *sql.Tx, now string, limit int) ([]models.WebhookDueDelivery, error) {
+	query := selectDeliveryColumns + `, w.url, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.webhook_id = d.webhook_id
+		WHERE d.status = ? AND d.next_attempt_at <= ? AND w.disabled_at IS NULL
+		ORDER BY d.next_attempt_at, d.delivery_id
+		LIMIT ?`
+
+	rows, err := tx.QueryContext(ctx, query, models.WEBHOOK_DELIVERY_PENDING, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDueDelivery
+	for rows.Next() {
+		var delivery models.WebhookDueDelivery
+		dest := append(deliveryDest(&delivery.WebhookDelivery), &delivery.URL, &delivery.Secret)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Create adds a pending delivery to the outbox and returns its ID
+func (r *WebhookDeliveriesRepo) Create(ctx context.Context, tx *sql.Tx, deliveryData models.WebhookDeliveryCreate) (int, error) {
+	query := "INSERT INTO webhook_deliveries (webhook_id, event_id, event, payload, status, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING delivery_id"
+
+	var deliveryId int
+	if err := tx.QueryRowContext(ctx,
+		query,
+		deliveryData.WebhookId,
+		deliveryData.EventId,
+		deliveryData.Event,
+		deliveryData.Payload,
+		models.WEBHOOK_DELIVERY_PENDING,
+		deliveryData.NextAttemptAt,
+		models.UTCNow(),
+	).Scan(&deliveryId); err != nil {
+		return 0, err
+	}
+
+	return deliveryId, nil
+}
+
+// Claim moves the next attempt of a due delivery from dueAt to until, so it is not picked up
+// again while it is being sent; if the sender stops before recording the attempt, it is retried
+// then. It reports false when another sender claimed the delivery first.
+func (r *WebhookDeliveriesRepo) Claim(ctx context.Context, tx *sql.Tx, deliveryId int, dueAt string, until string) (bool, error) {
+	query := "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE delivery_id = ? AND status = ? AND next_attempt_at = ?"
+
+	result, err := tx.ExecContext(ctx, query, until, deliveryId, models.WEBHOOK_DELIVERY_PENDING, dueAt)
+	if err != nil {
+		return false, err
+	}
+
+	claimed, err := result.RowsAffected()
+	return claimed == 1, err
+}
+
+// RecordAttempt saves the outcome of sending a delivery
+func (r *WebhookDeliveriesRepo) RecordAttempt(ctx context.Context, tx *sql.Tx, attempt models.WebhookDeliveryAttempt) error {
+	query := `UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ?
+		WHERE delivery_id = ?`
+
+	statusCode := sql.NullInt64{Int64: int64(attempt.StatusCode), Valid: attempt.StatusCode != 0}
+	lastError := sql.NullString{String: attempt.Error, Valid: attempt.Error != ""}
+	deliveredAt := sql.NullString{String: attempt.DeliveredAt, Valid: attempt.DeliveredAt != ""}
+
+	_, err := tx.ExecContext(ctx,
+		query,
+		attempt.Status,
+		attempt.Attempts,
+		attempt.NextAttemptAt,
+		statusCode,
+		lastError,
+		deliveredAt,
+		attempt.DeliveryId,
+	)
+	return err
+}
+
+// Retry makes a failed delivery pending again with a fresh set of attempts, sent right away
+func (r *WebhookDeliveriesRepo) Retry(ctx context.Context, tx *sql.Tx, deliveryId int) error {
+	query := "UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ? WHERE delivery_id = ? AND status = ?"
+	_, err := tx.ExecContext(ctx, query, models.WEBHOOK_DELIVERY_PENDING, models.UTCNow(), deliveryId, models.WEBHOOK_DELIVERY_FAILED)
+	return err
+}
+
+// DeleteFinishedBefore removes delivered and failed deliveries created before the time and
+// returns how many were removed, pending deliveries are kept
+func (r *WebhookDeliveriesRepo) DeleteFinishedBefore(ctx context.Context, tx *sql.Tx, before string) (int64, error) {
+	query := "DELETE FROM webhook_deliveries WHERE status IN (?, ?) AND created_at < ?"
+
+	result, err := tx.ExecContext(ctx, query, models.WEBHOOK_DELIVERY_DELIVERED, models.WEBHOOK_DELIVERY_FAILED, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
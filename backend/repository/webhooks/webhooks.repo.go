@@ -0,0 +1,126 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/momokii/go-rab-maker/backend/models"
+)
+
+// Repository stores the webhooks users send events to
+type Repository interface {
+	FindById(ctx context.Context, tx *sql.Tx, webhookId int) (models.Webhook, error)
+	FindByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.Webhook, error)
+	FindActiveByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.Webhook, error)
+	Create(ctx context.Context, tx *sql.Tx, webhookData models.WebhookCreate) (int, error)
+	Update(ctx context.Context, tx *sql.Tx, webhookData models.Webhook) error
+	Delete(ctx context.Context, tx *sql.Tx, webhookId int) error
+}
+
+var _ Repository = (*WebhooksRepo)(nil)
+
+type WebhooksRepo struct{}
+
+func NewWebhooksRepo() *WebhooksRepo {
+	return &WebhooksRepo{}
+}
+
+const selectWebhookColumns = `SELECT webhook_id, user_id, url, secret, events, COALESCE(disabled_at, ''), created_at, updated_at
+	FROM webhooks`
+
+func scanWebhook(row interface{ Scan(dest ...any) error }) (models.Webhook, error) {
+	var webhook models.Webhook
+	var events string
+	err := row.Scan(
+		&webhook.WebhookId,
+		&webhook.UserId,
+		&webhook.URL,
+		&webhook.Secret,
+		&events,
+		&webhook.DisabledAt,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	webhook.Events = models.SplitWebhookEvents(events)
+
+	return webhook, err
+}
+
+func (r *WebhooksRepo) findAll(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]models.Webhook, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// FindById retrieves a webhook by its ID
+func (r *WebhooksRepo) FindById(ctx context.Context, tx *sql.Tx, webhookId int) (models.Webhook, error) {
+	return scanWebhook(tx.QueryRowContext(ctx, selectWebhookColumns+" WHERE webhook_id = ?", webhookId))
+}
+
+// FindByUserId retrieves every webhook of a user, newest first
+func (r *WebhooksRepo) FindByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.Webhook, error) {
+	return r.findAll(ctx, tx, selectWebhookColumns+" WHERE user_id = ? ORDER BY webhook_id DESC", userId)
+}
+
+// FindActiveByUserId retrieves the webhooks of a user that are not disabled
+func (r *WebhooksRepo) FindActiveByUserId(ctx context.Context, tx *sql.Tx, userId int) ([]models.Webhook, error) {
+	return r.findAll(ctx, tx, selectWebhookColumns+" WHERE user_id = ? AND disabled_at IS NULL ORDER BY webhook_id", userId)
+}
+
+// Create stores a new, active webhook and returns its ID
+func (r *WebhooksRepo) Create(ctx context.Context, tx *sql.Tx, webhookData models.WebhookCreate) (int, error) {
+	query := "INSERT INTO webhooks (user_id, url, secret, events, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING webhook_id"
+
+	now := models.UTCNow()
+
+	var webhookId int
+	if err := tx.QueryRowContext(ctx,
+		query,
+		webhookData.UserId,
+		webhookData.URL,
+		webhookData.Secret,
+		models.JoinWebhookEvents(webhookData.Events),
+		now,
+		now,
+	).Scan(&webhookId); err != nil {
+		return 0, err
+	}
+
+	return webhookId, nil
+}
+
+// Update saves the URL, events and disabled time of a webhook, its secret does not change
+func (r *WebhooksRepo) Update(ctx context.Context, tx *sql.Tx, webhookData models.Webhook) error {
+	query := "UPDATE webhooks SET url = ?, events = ?, disabled_at = ?, updated_at = ? WHERE webhook_id = ?"
+
+	disabledAt := sql.NullString{String: webhookData.DisabledAt, Valid: webhookData.DisabledAt != ""}
+
+	_, err := tx.ExecContext(ctx,
+		query,
+		webhookData.URL,
+		models.JoinWebhookEvents(webhookData.Events),
+		disabledAt,
+		models.UTCNow(),
+		webhookData.WebhookId,
+	)
+	return err
+}
+
+// Delete removes a webhook, its deliveries are removed with it
+func (r *WebhooksRepo) Delete(ctx context.Context, tx *sql.Tx, webhookId int) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE webhook_id = ?", webhookId)
+	return err
+}
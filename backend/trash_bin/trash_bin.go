@@ -0,0 +1,255 @@
+package trash_bin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/models"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	"github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/repository/trash"
+)
+
+// Config controls the automatic purge of the trash, zero RetentionDays keeps deleted rows
+// until they are purged by hand
+type Config struct {
+	RetentionDays int
+	Interval      time.Duration // how often the trash is checked for expired rows
+}
+
+// LoadConfig reads TRASH_RETENTION_DAYS, models.TRASH_DEFAULT_RETENTION_DAYS when it is not set
+func LoadConfig() (Config, error) {
+	config := Config{
+		RetentionDays: models.TRASH_DEFAULT_RETENTION_DAYS,
+		Interval:      time.Hour,
+	}
+
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return config, fmt.Errorf("invalid TRASH_RETENTION_DAYS: %q", value)
+		}
+		config.RetentionDays = days
+	}
+
+	return config, nil
+}
+
+// errProjectLocked skips the expired rows of a submitted, in review or approved project, they
+// are purged once the project is unlocked
+var errProjectLocked = errors.New("project is locked")
+
+// Bin restores and purges the rows of the trash through the repository of their table, and
+// purges the rows deleted more than RetentionDays ago in the background
+type Bin struct {
+	dbService      databases.DatabaseServices
+	trashRepo      trash.Repository
+	projectsRepo   projects.Repository
+	workItemsRepo  project_work_items.Repository
+	materialsRepo  master_materials.Repository
+	laborTypesRepo master_labor_types.Repository
+	categoriesRepo master_work_categories.Repository
+	templatesRepo  ahsptemplates.Repository
+	approvalsRepo  project_approvals.Repository
+	config         Config
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewBin(
+	dbService databases.DatabaseServices,
+	trashRepo trash.Repository,
+	projectsRepo projects.Repository,
+	workItemsRepo project_work_items.Repository,
+	materialsRepo master_materials.Repository,
+	laborTypesRepo master_labor_types.Repository,
+	categoriesRepo master_work_categories.Repository,
+	templatesRepo ahsptemplates.Repository,
+	approvalsRepo project_approvals.Repository,
+	config Config,
+) *Bin {
+	return &Bin{
+		dbService:      dbService,
+		trashRepo:      trashRepo,
+		projectsRepo:   projectsRepo,
+		workItemsRepo:  workItemsRepo,
+		materialsRepo:  materialsRepo,
+		laborTypesRepo: laborTypesRepo,
+		categoriesRepo: categoriesRepo,
+		templatesRepo:  templatesRepo,
+		approvalsRepo:  approvalsRepo,
+		config:         config,
+	}
+}
+
+// RetentionDays is how long deleted rows stay in the trash, 0 when they stay until purged by hand
+func (b *Bin) RetentionDays() int {
+	return b.config.RetentionDays
+}
+
+// Restore takes an item out of the trash
+func (b *Bin) Restore(ctx context.Context, tx *sql.Tx, item models.TrashItem) error {
+	switch item.Entity {
+	case models.AUDIT_ENTITY_PROJECT:
+		return b.projectsRepo.Restore(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_WORK_ITEM:
+		return b.workItemsRepo.Restore(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_MATERIAL:
+		return b.materialsRepo.Restore(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_LABOR_TYPE:
+		return b.laborTypesRepo.Restore(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_WORK_CATEGORY:
+		return b.categoriesRepo.Restore(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_AHSP_TEMPLATE:
+		return b.templatesRepo.Restore(ctx, tx, item.Id)
+	}
+
+	return fmt.Errorf("%s is not kept in the trash", item.Entity)
+}
+
+// Purge deletes an item of the trash for good
+func (b *Bin) Purge(ctx context.Context, tx *sql.Tx, item models.TrashItem) error {
+	switch item.Entity {
+	case models.AUDIT_ENTITY_PROJECT:
+		return b.projectsRepo.Purge(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_WORK_ITEM:
+		return b.workItemsRepo.Purge(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_MATERIAL:
+		return b.materialsRepo.Purge(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_LABOR_TYPE:
+		return b.laborTypesRepo.Purge(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_WORK_CATEGORY:
+		return b.categoriesRepo.Purge(ctx, tx, item.Id)
+	case models.AUDIT_ENTITY_AHSP_TEMPLATE:
+		return b.templatesRepo.Purge(ctx, tx, item.Id)
+	}
+
+	return fmt.Errorf("%s is not kept in the trash", item.Entity)
+}
+
+// Start purges expired rows in a background goroutine until Stop is called
+func (b *Bin) Start() {
+	if b.config.RetentionDays <= 0 {
+		log.Println("Automatic trash purge is disabled (TRASH_RETENTION_DAYS is 0)")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+
+	go b.run(ctx)
+
+	log.Printf("Deleted rows are purged from the trash after %d days", b.config.RetentionDays)
+}
+
+// Stop ends the automatic purge, waiting for a purge in progress to finish
+func (b *Bin) Stop() {
+	if b.cancel == nil {
+		return
+	}
+
+	b.cancel()
+	<-b.done
+
+	log.Println("Automatic trash purge stopped")
+}
+
+func (b *Bin) run(ctx context.Context) {
+	defer close(b.done)
+
+	// rows may have expired while the application was down
+	if _, err := b.RunOnce(ctx); err != nil {
+		log.Printf("Trash purge failed: %v", err)
+	}
+
+	ticker := time.NewTicker(b.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := b.RunOnce(ctx); err != nil {
+				log.Printf("Trash purge failed: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce purges the rows deleted more than RetentionDays ago and returns how many were
+// purged. Each row is purged in its own transaction, one that cannot be purged yet (a
+// category still used by a work item) is logged and left for the next run. The rows of a
+// locked project are left until it is unlocked.
+func (b *Bin) RunOnce(ctx context.Context) (int, error) {
+	if b.config.RetentionDays <= 0 {
+		return 0, nil
+	}
+
+	before := time.Now().UTC().AddDate(0, 0, -b.config.RetentionDays).Format(models.DB_TIME_FORMAT)
+
+	var expired []models.TrashItem
+	if _, err := b.dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		expired, err = b.trashRepo.FindDeletedBefore(ctx, tx, before)
+		return 0, err
+	}); err != nil {
+		return 0, err
+	}
+
+	// a purge that started is allowed to finish on shutdown
+	purgeCtx := context.WithoutCancel(ctx)
+
+	purged := 0
+	for _, item := range expired {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if _, err := b.dbService.Transaction(purgeCtx, func(tx *sql.Tx) (int, error) {
+			// the item may have been restored or purged by hand since it was found
+			if _, err := b.trashRepo.FindById(purgeCtx, tx, item.Entity, item.Id); err != nil {
+				return 0, err
+			}
+
+			if item.IsProjectData() {
+				approval, err := b.approvalsRepo.FindByProjectId(purgeCtx, tx, item.ProjectId)
+				if err != nil {
+					return 0, err
+				}
+				if approval.IsLocked() {
+					return 0, errProjectLocked
+				}
+			}
+
+			return 0, b.Purge(purgeCtx, tx, item)
+		}); err != nil {
+			if err != sql.ErrNoRows && err != errProjectLocked {
+				log.Printf("Failed to purge %s %d from the trash: %v", item.Entity, item.Id, err)
+			}
+			continue
+		}
+
+		purged++
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d expired rows from the trash", purged)
+	}
+
+	return purged, nil
+}
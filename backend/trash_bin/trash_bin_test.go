@@ -0,0 +1,173 @@
+package trash_bin
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
+	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
+	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
+	"github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
+	"github.com/momokii/go-rab-maker/backend/repository/project_approvals"
+	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
+	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/repository/trash"
+)
+
+func testBin(dbService databases.DatabaseServices, retentionDays int) *Bin {
+	return NewBin(
+		dbService,
+		trash.NewTrashRepo(),
+		projects.NewProjectsRepo(),
+		project_work_items.NewProjectWorkItemRepo(),
+		master_materials.NewMasterMaterialsRepo(),
+		master_labor_types.NewMasterLaborTypesRepo(),
+		master_work_categories.NewMasterWorkCategoriesRepo(),
+		ahsptemplates.NewAhspTemplatesRepo(),
+		project_approvals.NewProjectApprovalsRepo(),
+		Config{RetentionDays: retentionDays, Interval: time.Hour},
+	)
+}
+
+func countRows(t *testing.T, db *sql.DB, query string) int {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow(query).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+
+	return count
+}
+
+// TestRunOnce_PurgesExpired verifies the purge deletes for good the rows deleted more than
+// RetentionDays ago, a work item before the category it uses, and keeps the recent ones
+func TestRunOnce_PurgesExpired(t *testing.T) {
+	ctx := t.Context()
+
+	for _, dialect := range dbtest.Engines() {
+		t.Run(string(dialect), func(t *testing.T) {
+			dbService := dbtest.OpenServices(t, dialect)
+			db := dbService.GetDB().Write
+
+			expired := time.Now().UTC().AddDate(0, 0, -31).Format("2006-01-02 15:04:05")
+			recent := time.Now().UTC().AddDate(0, 0, -2).Format("2006-01-02 15:04:05")
+
+			for _, query := range []string{
+				"INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret')",
+				"INSERT INTO master_work_categories (category_id, user_id, category_name, deleted_at) VALUES (1, 1, 'Pekerjaan Dinding', '" + expired + "')",
+				"INSERT INTO master_materials (material_id, user_id, material_name, unit, default_unit_price, deleted_at) VALUES (4, 1, 'Semen', 'kg', 1500, '" + recent + "'), (5, 1, 'Pasir', 'm3', 250000, NULL)",
+				"INSERT INTO projects (project_id, user_id, project_name, client_name, deleted_at) VALUES (3, 1, 'Rumah Tinggal', '', NULL), (6, 1, 'Gudang', '', '" + expired + "')",
+				"INSERT INTO project_work_items (work_item_id, project_id, category_id, description, volume, unit, deleted_at) VALUES (7, 3, 1, 'Pasangan bata', 10, 'm2', '" + expired + "'), (8, 6, 1, 'Plesteran', 20, 'm2', NULL)",
+				"INSERT INTO project_item_costs (work_item_id, item_type, master_item_id, item_name, coefficient, quantity_needed, unit, unit_price_at_creation, total_cost) VALUES (7, 'MATERIAL', 5, 'Pasir', 0.1, 1, 'm3', 250000, 250000)",
+			} {
+				if _, err := db.Exec(query); err != nil {
+					t.Fatalf("Failed to insert test data: %v", err)
+				}
+			}
+
+			purged, err := testBin(dbService, 30).RunOnce(ctx)
+			if err != nil {
+				t.Fatalf("Failed to purge: %v", err)
+			}
+			if purged != 3 {
+				t.Errorf("Expected the project, the work item and the category to be purged, got %d", purged)
+			}
+
+			for query, want := range map[string]int{
+				"SELECT COUNT(*) FROM projects":                               1,
+				"SELECT COUNT(*) FROM project_work_items":                     0,
+				"SELECT COUNT(*) FROM project_item_costs":                     0,
+				"SELECT COUNT(*) FROM master_work_categories":                 0,
+				"SELECT COUNT(*) FROM master_materials":                       2,
+				"SELECT COUNT(*) FROM master_materials WHERE material_id = 4": 1,
+			} {
+				if got := countRows(t, db, query); got != want {
+					t.Errorf("%s: expected %d, got %d", query, want, got)
+				}
+			}
+
+			// nothing is left to purge, and a disabled purge never deletes
+			if purged, err := testBin(dbService, 30).RunOnce(ctx); err != nil || purged != 0 {
+				t.Errorf("Expected nothing left to purge, got %d, err: %v", purged, err)
+			}
+			if purged, err := testBin(dbService, 0).RunOnce(ctx); err != nil || purged != 0 {
+				t.Errorf("Expected a disabled purge to do nothing, got %d, err: %v", purged, err)
+			}
+			if purged, err := testBin(dbService, 1).RunOnce(context.Background()); err != nil || purged != 1 {
+				t.Errorf("Expected the recent material to expire after a day, got %d, err: %v", purged, err)
+			}
+		})
+	}
+}
+
+// TestRunOnce_SkipsLockedProjects verifies an expired project or work item stays in the trash
+// while its project is submitted, in review or approved, and is purged once it is unlocked
+func TestRunOnce_SkipsLockedProjects(t *testing.T) {
+	ctx := t.Context()
+
+	for _, dialect := range dbtest.Engines() {
+		t.Run(string(dialect), func(t *testing.T) {
+			dbService := dbtest.OpenServices(t, dialect)
+			db := dbService.GetDB().Write
+
+			expired := time.Now().UTC().AddDate(0, 0, -31).Format("2006-01-02 15:04:05")
+
+			for _, query := range []string{
+				"INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret')",
+				"INSERT INTO master_work_categories (category_id, user_id, category_name) VALUES (1, 1, 'Pekerjaan Dinding')",
+				"INSERT INTO projects (project_id, user_id, project_name, client_name, deleted_at) VALUES (3, 1, 'Rumah Tinggal', '', NULL), (6, 1, 'Gudang', '', '" + expired + "')",
+				"INSERT INTO project_work_items (work_item_id, project_id, category_id, description, volume, unit, deleted_at) VALUES (7, 3, 1, 'Pasangan bata', 10, 'm2', '" + expired + "')",
+				"INSERT INTO project_approvals (project_id, status) VALUES (3, 'approved'), (6, 'submitted')",
+			} {
+				if _, err := db.Exec(query); err != nil {
+					t.Fatalf("Failed to insert test data: %v", err)
+				}
+			}
+
+			if purged, err := testBin(dbService, 30).RunOnce(ctx); err != nil || purged != 0 {
+				t.Errorf("Expected the rows of locked projects to stay, got %d, err: %v", purged, err)
+			}
+			if got := countRows(t, db, "SELECT COUNT(*) FROM projects") + countRows(t, db, "SELECT COUNT(*) FROM project_work_items"); got != 3 {
+				t.Errorf("Expected both projects and the work item to stay, got %d rows", got)
+			}
+
+			if _, err := db.Exec("UPDATE project_approvals SET status = 'rejected'"); err != nil {
+				t.Fatalf("Failed to unlock the projects: %v", err)
+			}
+			if purged, err := testBin(dbService, 30).RunOnce(ctx); err != nil || purged != 2 {
+				t.Errorf("Expected the project and the work item to be purged once unlocked, got %d, err: %v", purged, err)
+			}
+		})
+	}
+}
+
+// TestLoadConfig verifies TRASH_RETENTION_DAYS defaults to 30 days, 0 disables the purge and
+// other values are refused
+func TestLoadConfig(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  int
+		err   bool
+	}{
+		{"", 30, false},
+		{"7", 7, false},
+		{"0", 0, false},
+		{"-1", 0, true},
+		{"a week", 0, true},
+	} {
+		t.Setenv("TRASH_RETENTION_DAYS", tc.value)
+
+		config, err := LoadConfig()
+		if (err != nil) != tc.err {
+			t.Errorf("%q: expected error %v, got %v", tc.value, tc.err, err)
+		}
+		if !tc.err && config.RetentionDays != tc.want {
+			t.Errorf("%q: expected %d days, got %d", tc.value, tc.want, config.RetentionDays)
+		}
+	}
+}
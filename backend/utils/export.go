@@ -2,65 +2,328 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/jung-kurt/gofpdf"
 	"github.com/xuri/excelize/v2"
 )
 
+const (
+	// EXCEL_FORMAT_RUPIAH shows whole rupiah with thousand separators, e.g. Rp 1,250,000
+	EXCEL_FORMAT_RUPIAH = `"Rp "#,##0`
+	// EXCEL_FORMAT_NUMBER shows quantities with two decimals
+	EXCEL_FORMAT_NUMBER = `#,##0.00`
+)
+
+// ExcelFormula is a cell value written as a live formula instead of a literal.
+// The {row} placeholder is replaced with the row number of the cell, e.g. ExcelFormula("C{row}*E{row}").
+type ExcelFormula string
+
+// ExcelSheetOptions controls the layout of a sheet written by AddSheetWithOptions
+type ExcelSheetOptions struct {
+	Title         string    // merged, bold title row above the table
+	Subtitles     []string  // merged rows below the title, e.g. project name and location
+	ColumnWidths  []float64 // per column, 15 when missing or 0
+	ColumnFormats []string  // per column number format for data cells (EXCEL_FORMAT_*), "" for none
+	BoldRows      []int     // indexes into rows written in bold, e.g. category and subtotal rows
+	FreezeHeader  bool      // keep the title and header rows visible while scrolling
+	Landscape     bool      // print orientation, portrait when false
+}
+
+// HeaderRow returns the 1-based row number of the column headers
+func (o ExcelSheetOptions) HeaderRow() int {
+	if o.Title == "" && len(o.Subtitles) == 0 {
+		return 1
+	}
+
+	// title, subtitles and one blank spacer row
+	return len(o.Subtitles) + 3
+}
+
+// FirstDataRow returns the 1-based row number where rows[0] is written,
+// so callers can build formulas (SUM ranges, references from other sheets) before writing
+func (o ExcelSheetOptions) FirstDataRow() int {
+	return o.HeaderRow() + 1
+}
+
+// ExcelCellName returns the cell name of a 1-based column and row, e.g. (28, 3) -> AB3
+func ExcelCellName(col, row int) string {
+	name, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// ExcelSheetCellRef returns an absolute reference to a cell of another sheet, e.g. 'RAB'!$F$12
+func ExcelSheetCellRef(sheetName string, col, row int) string {
+	name, err := excelize.CoordinatesToCellName(col, row, true)
+	if err != nil {
+		return ""
+	}
+	return "'" + strings.ReplaceAll(sheetName, "'", "''") + "'!" + name
+}
+
 // ExcelExporter handles Excel file generation
 type ExcelExporter struct {
-	file *excelize.File
+	file   *excelize.File
+	sheets int
+	styles map[string]int
 }
 
 // NewExcelExporter creates a new Excel exporter
 func NewExcelExporter() *ExcelExporter {
 	return &ExcelExporter{
-		file: excelize.NewFile(),
+		file:   excelize.NewFile(),
+		styles: map[string]int{},
 	}
 }
 
-// AddSheet adds a new sheet with data
+// AddSheet adds a new sheet with a header row and plain data rows
 func (e *ExcelExporter) AddSheet(sheetName string, headers []string, rows [][]interface{}) error {
+	return e.addSheet(sheetName, headers, rows, ExcelSheetOptions{}, false)
+}
+
+// AddSheetWithOptions adds a new sheet with an optional title block, bold bordered headers,
+// number formats, ExcelFormula cells, frozen panes and A4 print setup
+func (e *ExcelExporter) AddSheetWithOptions(sheetName string, headers []string, rows [][]interface{}, options ExcelSheetOptions) error {
+	return e.addSheet(sheetName, headers, rows, options, true)
+}
+
+func (e *ExcelExporter) addSheet(sheetName string, headers []string, rows [][]interface{}, options ExcelSheetOptions, formatted bool) error {
 	// Create a new sheet
 	index, err := e.file.NewSheet(sheetName)
 	if err != nil {
 		return fmt.Errorf("failed to create sheet: %w", err)
 	}
 
+	// drop the empty default sheet of a new workbook
+	if e.sheets == 0 && sheetName != "Sheet1" {
+		if err := e.file.DeleteSheet("Sheet1"); err != nil {
+			return fmt.Errorf("failed to remove default sheet: %w", err)
+		}
+		if index, err = e.file.GetSheetIndex(sheetName); err != nil {
+			return fmt.Errorf("failed to create sheet: %w", err)
+		}
+	}
+	e.sheets++
+
+	lastCol := len(headers)
+	if lastCol == 0 {
+		lastCol = 1
+	}
+
+	// Title block
+	if options.Title != "" || len(options.Subtitles) > 0 {
+		lines := append([]string{options.Title}, options.Subtitles...)
+		for i, line := range lines {
+			row := i + 1
+			if err := e.file.SetCellValue(sheetName, ExcelCellName(1, row), line); err != nil {
+				return fmt.Errorf("failed to set title: %w", err)
+			}
+			if err := e.file.MergeCell(sheetName, ExcelCellName(1, row), ExcelCellName(lastCol, row)); err != nil {
+				return fmt.Errorf("failed to merge title: %w", err)
+			}
+
+			style, err := e.style(i == 0, false, true, "")
+			if err != nil {
+				return err
+			}
+			if err := e.file.SetCellStyle(sheetName, ExcelCellName(1, row), ExcelCellName(lastCol, row), style); err != nil {
+				return fmt.Errorf("failed to style title: %w", err)
+			}
+		}
+	}
+
 	// Write headers
+	headerRow := options.HeaderRow()
 	for i, header := range headers {
-		cell := string(rune('A'+i)) + "1"
-		if err := e.file.SetCellValue(sheetName, cell, header); err != nil {
+		if err := e.file.SetCellValue(sheetName, ExcelCellName(i+1, headerRow), header); err != nil {
 			return fmt.Errorf("failed to set header: %w", err)
 		}
 	}
+	if len(headers) > 0 && formatted {
+		style, err := e.style(true, true, false, "")
+		if err != nil {
+			return err
+		}
+		if err := e.file.SetCellStyle(sheetName, ExcelCellName(1, headerRow), ExcelCellName(lastCol, headerRow), style); err != nil {
+			return fmt.Errorf("failed to style header: %w", err)
+		}
+	}
 
 	// Write data rows
+	boldRows := map[int]bool{}
+	for _, rowIdx := range options.BoldRows {
+		boldRows[rowIdx] = true
+	}
+
 	for rowIdx, row := range rows {
+		rowNumber := options.FirstDataRow() + rowIdx
 		for colIdx, value := range row {
-			cell := string(rune('A'+colIdx)) + fmt.Sprintf("%d", rowIdx+2)
-			if err := e.file.SetCellValue(sheetName, cell, value); err != nil {
+			cell := ExcelCellName(colIdx+1, rowNumber)
+
+			if formula, ok := value.(ExcelFormula); ok {
+				if err := e.file.SetCellFormula(sheetName, cell, strings.ReplaceAll(string(formula), "{row}", strconv.Itoa(rowNumber))); err != nil {
+					return fmt.Errorf("failed to set cell formula: %w", err)
+				}
+			} else if err := e.file.SetCellValue(sheetName, cell, value); err != nil {
 				return fmt.Errorf("failed to set cell value: %w", err)
 			}
 		}
+
+		if !formatted {
+			continue
+		}
+
+		for colIdx := 0; colIdx < lastCol; colIdx++ {
+			format := ""
+			if colIdx < len(options.ColumnFormats) {
+				format = options.ColumnFormats[colIdx]
+			}
+
+			style, err := e.style(boldRows[rowIdx], false, false, format)
+			if err != nil {
+				return err
+			}
+			cell := ExcelCellName(colIdx+1, rowNumber)
+			if err := e.file.SetCellStyle(sheetName, cell, cell, style); err != nil {
+				return fmt.Errorf("failed to style cell: %w", err)
+			}
+		}
 	}
 
 	// Set active sheet
 	e.file.SetActiveSheet(index)
 
-	// Auto-fit columns
-	for i := range headers {
-		col := string(rune('A' + i))
-		if err := e.file.SetColWidth(sheetName, col, col, 15); err != nil {
+	// Column widths
+	for i := 0; i < lastCol; i++ {
+		width := 15.0
+		if i < len(options.ColumnWidths) && options.ColumnWidths[i] > 0 {
+			width = options.ColumnWidths[i]
+		}
+
+		col, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return fmt.Errorf("failed to set column width: %w", err)
+		}
+		if err := e.file.SetColWidth(sheetName, col, col, width); err != nil {
 			return fmt.Errorf("failed to set column width: %w", err)
 		}
 	}
 
+	if options.FreezeHeader {
+		if err := e.file.SetPanes(sheetName, &excelize.Panes{
+			Freeze:      true,
+			YSplit:      headerRow,
+			TopLeftCell: ExcelCellName(1, headerRow+1),
+			ActivePane:  "bottomLeft",
+		}); err != nil {
+			return fmt.Errorf("failed to freeze panes: %w", err)
+		}
+	}
+
+	if formatted {
+		if err := e.setPrintLayout(sheetName, headerRow, options.Landscape); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setPrintLayout prints on A4 fitted to the page width, repeats the header row on every page
+// and numbers the pages in the footer
+func (e *ExcelExporter) setPrintLayout(sheetName string, headerRow int, landscape bool) error {
+	size := 9 // A4
+	orientation := "portrait"
+	if landscape {
+		orientation = "landscape"
+	}
+	fitToWidth, fitToHeight := 1, 0
+	fitToPage := true
+
+	if err := e.file.SetPageLayout(sheetName, &excelize.PageLayoutOptions{
+		Size:        &size,
+		Orientation: &orientation,
+		FitToWidth:  &fitToWidth,
+		FitToHeight: &fitToHeight,
+	}); err != nil {
+		return fmt.Errorf("failed to set page layout: %w", err)
+	}
+
+	if err := e.file.SetSheetProps(sheetName, &excelize.SheetPropsOptions{FitToPage: &fitToPage}); err != nil {
+		return fmt.Errorf("failed to set page layout: %w", err)
+	}
+
+	if err := e.file.SetHeaderFooter(sheetName, &excelize.HeaderFooterOptions{
+		OddFooter: "&L&A&RPage &P of &N",
+	}); err != nil {
+		return fmt.Errorf("failed to set footer: %w", err)
+	}
+
+	if err := e.file.SetDefinedName(&excelize.DefinedName{
+		Name:     "_xlnm.Print_Titles",
+		RefersTo: fmt.Sprintf("'%s'!$%d:$%d", strings.ReplaceAll(sheetName, "'", "''"), headerRow, headerRow),
+		Scope:    sheetName,
+	}); err != nil {
+		return fmt.Errorf("failed to set print titles: %w", err)
+	}
+
 	return nil
 }
 
+// style returns a cached style id. Title rows are large and centered without borders,
+// header and data cells are bordered, headers shaded.
+func (e *ExcelExporter) style(bold, header, title bool, numFmt string) (int, error) {
+	key := fmt.Sprintf("%t|%t|%t|%s", bold, header, title, numFmt)
+	if id, ok := e.styles[key]; ok {
+		return id, nil
+	}
+
+	style := &excelize.Style{
+		Font:      &excelize.Font{Bold: bold},
+		Alignment: &excelize.Alignment{Vertical: "center", WrapText: !title},
+	}
+
+	if title {
+		style.Alignment.Horizontal = "center"
+		if bold {
+			style.Font.Size = 14
+		}
+	} else {
+		for _, side := range []string{"left", "right", "top", "bottom"} {
+			style.Border = append(style.Border, excelize.Border{Type: side, Color: "000000", Style: 1})
+		}
+	}
+
+	if header {
+		style.Alignment.Horizontal = "center"
+		style.Fill = excelize.Fill{Type: "pattern", Color: []string{"D9E1F2"}, Pattern: 1}
+	}
+
+	if numFmt != "" {
+		style.CustomNumFmt = &numFmt
+	}
+
+	id, err := e.file.NewStyle(style)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create style: %w", err)
+	}
+
+	e.styles[key] = id
+	return id, nil
+}
+
 // Write outputs the Excel file as bytes
 func (e *ExcelExporter) Write() ([]byte, error) {
+	// formulas are written without cached results, let the spreadsheet app compute them on open
+	fullCalcOnLoad := true
+	if err := e.file.SetCalcProps(&excelize.CalcPropsOptions{FullCalcOnLoad: &fullCalcOnLoad}); err != nil {
+		return nil, fmt.Errorf("failed to set calculation properties: %w", err)
+	}
+
 	buffer, err := e.file.WriteToBuffer()
 	if err != nil {
 		return nil, fmt.Errorf("failed to write excel buffer: %w", err)
@@ -71,22 +334,35 @@ func (e *ExcelExporter) Write() ([]byte, error) {
 // PDFExporter handles PDF file generation
 type PDFExporter struct {
 	pdf *gofpdf.Fpdf
+	tr  func(string) string // UTF-8 to the cp1252 encoding of the core fonts
 }
 
 // NewPDFExporter creates a new PDF exporter
 func NewPDFExporter(orientation, unit, size string) *PDFExporter {
 	pdf := gofpdf.New(orientation, unit, size, "")
 	pdf.AddPage()
-	return &PDFExporter{pdf: pdf}
+	return &PDFExporter{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+}
+
+// AddPage starts a new page
+func (p *PDFExporter) AddPage() {
+	p.pdf.AddPage()
 }
 
 // AddTitle adds a title to the PDF
 func (p *PDFExporter) AddTitle(title string) {
 	p.pdf.SetFont("Arial", "B", 16)
-	p.pdf.Cell(40, 10, title)
+	p.pdf.Cell(40, 10, p.tr(title))
 	p.pdf.Ln(12)
 }
 
+// AddText adds a line of regular text, e.g. the details below a title
+func (p *PDFExporter) AddText(text string) {
+	p.pdf.SetFont("Arial", "", 10)
+	p.pdf.Cell(40, 6, p.tr(text))
+	p.pdf.Ln(6)
+}
+
 // AddTable adds a table to the PDF
 func (p *PDFExporter) AddTable(headers []string, rows [][]string) {
 	// Set font for table
@@ -98,7 +374,7 @@ func (p *PDFExporter) AddTable(headers []string, rows [][]string) {
 	// Write headers
 	p.pdf.SetFont("Arial", "B", 10)
 	for _, header := range headers {
-		p.pdf.CellFormat(colWidth, 7, header, "1", 0, "C", false, 0, "")
+		p.pdf.CellFormat(colWidth, 7, p.tr(header), "1", 0, "C", false, 0, "")
 	}
 	p.pdf.Ln(-1)
 	p.pdf.SetFont("Arial", "", 10)
@@ -106,7 +382,53 @@ func (p *PDFExporter) AddTable(headers []string, rows [][]string) {
 	// Write data rows
 	for _, row := range rows {
 		for _, cell := range row {
-			p.pdf.CellFormat(colWidth, 6, cell, "1", 0, "L", false, 0, "")
+			p.pdf.CellFormat(colWidth, 6, p.tr(cell), "1", 0, "L", false, 0, "")
+		}
+		p.pdf.Ln(-1)
+	}
+}
+
+// PDFTableOptions controls AddTableWithOptions: column widths in page units,
+// alignments ("L", "C" or "R", left when missing) and indexes of rows written in bold
+type PDFTableOptions struct {
+	Widths   []float64
+	Aligns   []string
+	BoldRows []int
+}
+
+// AddTableWithOptions adds a table with per-column widths and alignment and bold rows
+func (p *PDFExporter) AddTableWithOptions(headers []string, rows [][]string, options PDFTableOptions) {
+	width := func(i int) float64 {
+		if i < len(options.Widths) {
+			return options.Widths[i]
+		}
+		return 190.0 / float64(len(headers))
+	}
+
+	p.pdf.SetFont("Arial", "B", 9)
+	for i, header := range headers {
+		p.pdf.CellFormat(width(i), 7, p.tr(header), "1", 0, "C", false, 0, "")
+	}
+	p.pdf.Ln(-1)
+
+	boldRows := map[int]bool{}
+	for _, rowIdx := range options.BoldRows {
+		boldRows[rowIdx] = true
+	}
+
+	for rowIdx, row := range rows {
+		if boldRows[rowIdx] {
+			p.pdf.SetFont("Arial", "B", 9)
+		} else {
+			p.pdf.SetFont("Arial", "", 9)
+		}
+
+		for i, cell := range row {
+			align := "L"
+			if i < len(options.Aligns) && options.Aligns[i] != "" {
+				align = options.Aligns[i]
+			}
+			p.pdf.CellFormat(width(i), 6, p.tr(cell), "1", 0, align, false, 0, "")
 		}
 		p.pdf.Ln(-1)
 	}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func openWorkbook(t *testing.T, e *ExcelExporter) *excelize.File {
+	t.Helper()
+
+	data, err := e.Write()
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	file, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to open written workbook: %v", err)
+	}
+
+	return file
+}
+
+// TestAddSheet_ColumnsPastZ verifies headers and values beyond column Z land in AA, AB, ...
+func TestAddSheet_ColumnsPastZ(t *testing.T) {
+	headers := make([]string, 30)
+	row := make([]interface{}, 30)
+	for i := range headers {
+		headers[i] = ExcelCellName(i+1, 1)
+		row[i] = i + 1
+	}
+
+	excel := NewExcelExporter()
+	if err := excel.AddSheet("Wide", headers, [][]interface{}{row}); err != nil {
+		t.Fatalf("AddSheet failed: %v", err)
+	}
+
+	file := openWorkbook(t, excel)
+
+	if sheets := file.GetSheetList(); len(sheets) != 1 || sheets[0] != "Wide" {
+		t.Errorf("Expected only the Wide sheet, got %v", sheets)
+	}
+
+	for cell, expected := range map[string]string{"Z1": "Z1", "AA1": "AA1", "AD1": "AD1", "AA2": "27", "AD2": "30"} {
+		got, err := file.GetCellValue("Wide", cell)
+		if err != nil {
+			t.Fatalf("GetCellValue(%s) failed: %v", cell, err)
+		}
+		if got != expected {
+			t.Errorf("Cell %s = %q, expected %q", cell, got, expected)
+		}
+	}
+}
+
+// TestAddSheetWithOptions_Formulas verifies the title block offsets the table and formulas are written live
+func TestAddSheetWithOptions_Formulas(t *testing.T) {
+	options := ExcelSheetOptions{
+		Title:         "Bill of Quantities",
+		Subtitles:     []string{"Project: Rumah"},
+		ColumnFormats: []string{"", EXCEL_FORMAT_NUMBER, EXCEL_FORMAT_RUPIAH, EXCEL_FORMAT_RUPIAH},
+		FreezeHeader:  true,
+	}
+
+	if options.HeaderRow() != 4 || options.FirstDataRow() != 5 {
+		t.Fatalf("Expected header row 4 and first data row 5, got %d and %d", options.HeaderRow(), options.FirstDataRow())
+	}
+
+	rows := [][]interface{}{
+		{"Galian tanah", 10.0, 50000.0, ExcelFormula("B{row}*C{row}")},
+		{"Urugan pasir", 2.5, 200000.0, ExcelFormula("B{row}*C{row}")},
+		{"Total", "", "", ExcelFormula("SUM(D5:D6)")},
+	}
+
+	excel := NewExcelExporter()
+	if err := excel.AddSheetWithOptions("RAB", []string{"Description", "Volume", "Unit Price", "Amount"}, rows, options); err != nil {
+		t.Fatalf("AddSheetWithOptions failed: %v", err)
+	}
+	if err := excel.AddSheetWithOptions("Summary", []string{"Amount"}, [][]interface{}{{ExcelFormula(ExcelSheetCellRef("RAB", 4, 7))}}, ExcelSheetOptions{Title: "Summary"}); err != nil {
+		t.Fatalf("AddSheetWithOptions failed: %v", err)
+	}
+
+	file := openWorkbook(t, excel)
+
+	if title, _ := file.GetCellValue("RAB", "A1"); title != "Bill of Quantities" {
+		t.Errorf("Expected title in A1, got %q", title)
+	}
+	if header, _ := file.GetCellValue("RAB", "A4"); header != "Description" {
+		t.Errorf("Expected header in A4, got %q", header)
+	}
+
+	for cell, expected := range map[string]string{"D5": "B5*C5", "D6": "B6*C6", "D7": "SUM(D5:D6)"} {
+		got, err := file.GetCellFormula("RAB", cell)
+		if err != nil {
+			t.Fatalf("GetCellFormula(%s) failed: %v", cell, err)
+		}
+		if got != expected {
+			t.Errorf("Formula %s = %q, expected %q", cell, got, expected)
+		}
+	}
+
+	if got, _ := file.GetCellFormula("Summary", "A4"); got != "'RAB'!$D$7" {
+		t.Errorf("Expected cross-sheet reference, got %q", got)
+	}
+
+	total, err := file.CalcCellValue("RAB", "D7", excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatalf("CalcCellValue failed: %v", err)
+	}
+	if total != "1000000" {
+		t.Errorf("Expected total 1000000, got %s", total)
+	}
+}
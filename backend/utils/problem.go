@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	API_PATH_PREFIX = "/api/"
+
+	PROBLEM_CONTENT_TYPE = "application/problem+json"
+)
+
+// Problem is an RFC 9457 problem details body, the error response of the JSON API
+type Problem struct {
+	Type     string   `json:"type"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Detail   string   `json:"detail,omitempty"`
+	Instance string   `json:"instance,omitempty"`
+	Errors   []string `json:"errors,omitempty"` // validation messages, one per invalid field
+}
+
+// IsAPIRequest reports whether the request is for the JSON API rather than a page
+func IsAPIRequest(c *fiber.Ctx) bool {
+	return strings.HasPrefix(c.Path(), API_PATH_PREFIX)
+}
+
+func ResponseProblem(c *fiber.Ctx, status int, detail string) error {
+	return sendProblem(c, Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.OriginalURL(),
+	})
+}
+
+// ResponseValidationProblem answers 422 with the messages of GetValidationErrors
+func ResponseValidationProblem(c *fiber.Ctx, err error) error {
+	messages := GetValidationErrors(err)
+	if len(messages) == 0 {
+		messages = []string{err.Error()}
+	}
+
+	return sendProblem(c, Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(fiber.StatusUnprocessableEntity),
+		Status:   fiber.StatusUnprocessableEntity,
+		Detail:   "The request body is invalid",
+		Instance: c.OriginalURL(),
+		Errors:   messages,
+	})
+}
+
+func sendProblem(c *fiber.Ctx, problem Problem) error {
+	return c.Status(problem.Status).JSON(problem, PROBLEM_CONTENT_TYPE)
+}
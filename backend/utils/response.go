@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/a-h/templ"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
@@ -49,3 +52,35 @@ func ResponseSuccessWithRedirect(c *fiber.Ctx, title, message, redirectUrl strin
 
 	return adaptor.HTTPHandler(templ.Handler(successComponentModal))(c)
 }
+
+// AttachmentDisposition is the Content-Disposition of a download named filename. The quoted
+// filename keeps the ASCII characters of the name, filename* carries it whole (RFC 6266,
+// RFC 5987) for the browsers that read it.
+func AttachmentDisposition(filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+
+	var encoded strings.Builder
+	for _, b := range []byte(filename) {
+		if isRFC5987AttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+
+	return `attachment; filename="` + fallback + `"; filename*=UTF-8''` + encoded.String()
+}
+
+// isRFC5987AttrChar reports whether b may appear unencoded in an RFC 5987 value
+func isRFC5987AttrChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
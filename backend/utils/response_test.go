@@ -0,0 +1,20 @@
+package utils
+
+import "testing"
+
+// TestAttachmentDisposition verifies names with spaces, quotes, separators and non-ASCII
+// characters stay one quoted filename with the whole name in filename*
+func TestAttachmentDisposition(t *testing.T) {
+	tests := map[string]string{
+		"rab.xlsx":                `attachment; filename="rab.xlsx"; filename*=UTF-8''rab.xlsx`,
+		"rab-Rumah Tinggal.xlsx":  `attachment; filename="rab-Rumah Tinggal.xlsx"; filename*=UTF-8''rab-Rumah%20Tinggal.xlsx`,
+		`rab-Gudang "A"; B.xlsx`:  `attachment; filename="rab-Gudang _A_; B.xlsx"; filename*=UTF-8''rab-Gudang%20%22A%22%3B%20B.xlsx`,
+		"rab-Ruko Café\r\nX.xlsx": `attachment; filename="rab-Ruko Caf___X.xlsx"; filename*=UTF-8''rab-Ruko%20Caf%C3%A9%0D%0AX.xlsx`,
+	}
+
+	for filename, expected := range tests {
+		if got := AttachmentDisposition(filename); got != expected {
+			t.Errorf("AttachmentDisposition(%q) = %s, expected %s", filename, got, expected)
+		}
+	}
+}
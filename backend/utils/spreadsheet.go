@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ReadSpreadsheet reads all rows of an uploaded xlsx or csv file.
+// For xlsx the active sheet is used, for csv the delimiter (comma or semicolon) is detected from the first line.
+func ReadSpreadsheet(filename string, data []byte) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx", ".xlsm":
+		file, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open excel file: %w", err)
+		}
+		defer file.Close()
+
+		rows, err := file.GetRows(file.GetSheetName(file.GetActiveSheetIndex()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read excel rows: %w", err)
+		}
+
+		return rows, nil
+
+	case ".csv":
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // UTF-8 BOM written by Excel
+
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+
+		firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+		if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+			reader.Comma = ';'
+		}
+
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv file: %w", err)
+		}
+
+		return rows, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported file type %q, use .xlsx or .csv", filepath.Ext(filename))
+	}
+}
+
+// ParseNumber parses numbers written the way they appear in price lists,
+// e.g. "Rp 12.500,-", "12,500.00", "12.500,50" or "1.5".
+// A single separator followed by exactly three digits is read as a thousands separator,
+// unless the integer part is zero ("0.125").
+func ParseNumber(value string) (float64, error) {
+	var cleaned strings.Builder
+	for _, r := range value {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' || r == '-' {
+			cleaned.WriteRune(r)
+		}
+	}
+
+	// drop separators left over from "Rp." prefixes and ",-" suffixes
+	number := strings.TrimRight(strings.TrimLeft(cleaned.String(), ".,"), ".,-")
+	if number == "" {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+
+	lastDot := strings.LastIndex(number, ".")
+	lastComma := strings.LastIndex(number, ",")
+
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		// both used, the last one is the decimal separator
+		if lastComma > lastDot {
+			number = strings.ReplaceAll(number, ".", "")
+			number = strings.Replace(number, ",", ".", 1)
+		} else {
+			number = strings.ReplaceAll(number, ",", "")
+		}
+
+	case lastDot >= 0 || lastComma >= 0:
+		separator := "."
+		if lastComma >= 0 {
+			separator = ","
+		}
+
+		lastIndex := strings.LastIndex(number, separator)
+		integerPart := strings.TrimLeft(number[:lastIndex], "-0")
+		if strings.Count(number, separator) > 1 || (integerPart != "" && len(number)-lastIndex-1 == 3) {
+			number = strings.ReplaceAll(number, separator, "")
+		} else {
+			number = strings.Replace(number, separator, ".", 1)
+		}
+	}
+
+	result, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package utils
+
+import "testing"
+
+// TestParseNumber verifies the number formats found in Indonesian and English price lists
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"12500", 12500},
+		{"Rp 12.500", 12500},
+		{"Rp. 150", 150},
+		{"Rp 12.500,-", 12500},
+		{"12,500.00", 12500},
+		{"12.500,50", 12500.5},
+		{"1.250.000", 1250000},
+		{"1,5", 1.5},
+		{"0.125", 0.125},
+		{"7.25", 7.25},
+	}
+
+	for _, test := range tests {
+		got, err := ParseNumber(test.input)
+		if err != nil {
+			t.Errorf("ParseNumber(%q) returned error: %v", test.input, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("ParseNumber(%q) = %v, expected %v", test.input, got, test.expected)
+		}
+	}
+
+	for _, input := range []string{"", "abc", "Rp"} {
+		if _, err := ParseNumber(input); err == nil {
+			t.Errorf("ParseNumber(%q) expected an error", input)
+		}
+	}
+}
+
+// TestReadSpreadsheet_CSV verifies the csv delimiter is detected and the BOM stripped
+func TestReadSpreadsheet_CSV(t *testing.T) {
+	rows, err := ReadSpreadsheet("prices.csv", []byte("\xef\xbb\xbfNama;Satuan;Harga\nSemen;kg;\"1.500\"\n"))
+	if err != nil {
+		t.Fatalf("ReadSpreadsheet failed: %v", err)
+	}
+
+	if len(rows) != 2 || rows[0][0] != "Nama" || rows[1][2] != "1.500" {
+		t.Errorf("Unexpected rows: %v", rows)
+	}
+
+	if _, err := ReadSpreadsheet("prices.pdf", nil); err == nil {
+		t.Error("Expected unsupported file type error")
+	}
+}
@@ -0,0 +1,442 @@
+package webhook_dispatch
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/webhook_deliveries"
+	"github.com/momokii/go-rab-maker/backend/repository/webhooks"
+)
+
+const (
+	HEADER_EVENT     = "X-RAB-Event"
+	HEADER_DELIVERY  = "X-RAB-Delivery"
+	HEADER_SIGNATURE = "X-RAB-Signature"
+
+	USER_AGENT = "go-rab-maker-webhooks/1.0"
+
+	// EVENT_ID_PREFIX starts the id of every event, shared by the deliveries of the event
+	EVENT_ID_PREFIX = "evt_"
+
+	// maxErrorLength caps the error kept for the delivery log
+	maxErrorLength = 500
+	// maxResponseRead is how much of a response is read before the connection is reused
+	maxResponseRead = 64 << 10
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Publisher queues webhook events. Publish runs in the transaction of the change, so the
+// deliveries are stored only if the change is committed.
+type Publisher interface {
+	Publish(ctx context.Context, tx *sql.Tx, userId int, event string, data interface{}) error
+}
+
+var _ Publisher = (*Dispatcher)(nil)
+
+// DispatcherConfig controls how often the outbox is polled and how failed deliveries are retried
+type DispatcherConfig struct {
+	PollInterval time.Duration
+	BatchSize    int           // deliveries sent per poll
+	Timeout      time.Duration // per request
+	BaseDelay    time.Duration // wait after the first failed attempt, doubled after each next one
+	MaxDelay     time.Duration
+	MaxAttempts  int           // a delivery is failed after this many attempts
+	Retention    time.Duration // delivered and failed deliveries are removed after this, 0 keeps them
+}
+
+// DefaultDispatcherConfig retries a delivery 8 times over about an hour and a half
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		PollInterval: 5 * time.Second,
+		BatchSize:    20,
+		Timeout:      10 * time.Second,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     time.Hour,
+		MaxAttempts:  8,
+		Retention:    30 * 24 * time.Hour,
+	}
+}
+
+// Dispatcher stores events in the webhook_deliveries outbox and sends them in the background,
+// signed with the secret of each webhook
+type Dispatcher struct {
+	dbService      databases.DatabaseServices
+	webhooksRepo   webhooks.Repository
+	deliveriesRepo webhook_deliveries.Repository
+	config         DispatcherConfig
+	client         *http.Client
+
+	mu         sync.Mutex
+	lastPruned time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewDispatcher(
+	dbService databases.DatabaseServices,
+	webhooksRepo webhooks.Repository,
+	deliveriesRepo webhook_deliveries.Repository,
+	config DispatcherConfig,
+) *Dispatcher {
+	return &Dispatcher{
+		dbService:      dbService,
+		webhooksRepo:   webhooksRepo,
+		deliveriesRepo: deliveriesRepo,
+		config:         config,
+		client:         &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Publish queues event for every active webhook of the user subscribed to it
+func (d *Dispatcher) Publish(ctx context.Context, tx *sql.Tx, userId int, event string, data interface{}) error {
+	userWebhooks, err := d.webhooksRepo.FindActiveByUserId(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+
+	var subscribed []models.Webhook
+	for _, webhook := range userWebhooks {
+		if webhook.Subscribes(event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	payload, eventId, err := newPayload(event, data)
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range subscribed {
+		if _, err := d.enqueue(ctx, tx, webhook.WebhookId, eventId, event, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Enqueue queues event for one webhook, whether or not it subscribes to it, and returns the delivery ID
+func (d *Dispatcher) Enqueue(ctx context.Context, tx *sql.Tx, webhookId int, event string, data interface{}) (int, error) {
+	payload, eventId, err := newPayload(event, data)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.enqueue(ctx, tx, webhookId, eventId, event, payload)
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, tx *sql.Tx, webhookId int, eventId, event, payload string) (int, error) {
+	return d.deliveriesRepo.Create(ctx, tx, models.WebhookDeliveryCreate{
+		WebhookId:     webhookId,
+		EventId:       eventId,
+		Event:         event,
+		Payload:       payload,
+		NextAttemptAt: formatTime(time.Now()),
+	})
+}
+
+func newPayload(event string, data interface{}) (payload, eventId string, err error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	eventId = EVENT_ID_PREFIX + hex.EncodeToString(id)
+
+	body, err := json.Marshal(models.WebhookPayload{
+		EventId:   eventId,
+		Event:     event,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Data:      data,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode %s payload: %w", event, err)
+	}
+
+	return string(body), eventId, nil
+}
+
+// Start sends due deliveries in a background goroutine until Stop is called
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+
+	go d.run(ctx)
+
+	log.Printf("Webhook deliveries are sent every %s", d.config.PollInterval)
+}
+
+// Stop ends the background sending, waiting for the requests in progress to finish
+func (d *Dispatcher) Stop() {
+	if d.cancel == nil {
+		return
+	}
+
+	d.cancel()
+	<-d.done
+
+	log.Println("Webhook deliveries stopped")
+}
+
+func (d *Dispatcher) run(ctx context.Context) {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.RunOnce(ctx); err != nil {
+				log.Printf("Webhook deliveries failed: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce sends the deliveries that are due and returns how many were attempted. Deliveries are
+// claimed in one transaction, sent outside of it and each outcome is recorded in its own, so a
+// slow receiver does not hold the database. When ctx is cancelled no further delivery is sent,
+// the claimed ones are retried once their claim expires.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	d.prune(ctx)
+
+	due, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// a request that started is allowed to finish on shutdown
+	sendCtx := context.WithoutCancel(ctx)
+
+	attempted := 0
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			break
+		}
+
+		attempt := d.send(sendCtx, delivery)
+		attempted++
+
+		if _, err := d.dbService.Transaction(sendCtx, func(tx *sql.Tx) (int, error) {
+			return 0, d.deliveriesRepo.RecordAttempt(sendCtx, tx, attempt)
+		}); err != nil {
+			return attempted, fmt.Errorf("failed to record delivery %d: %w", delivery.DeliveryId, err)
+		}
+	}
+
+	return attempted, nil
+}
+
+// claim finds the due deliveries and moves their next attempt past the time sending them can take
+func (d *Dispatcher) claim(ctx context.Context) ([]models.WebhookDueDelivery, error) {
+	var claimed []models.WebhookDueDelivery
+
+	now := time.Now()
+	until := formatTime(now.Add(time.Duration(d.config.BatchSize+1) * d.config.Timeout))
+
+	_, err := d.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		due, err := d.deliveriesRepo.FindDue(ctx, tx, formatTime(now), d.config.BatchSize)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, delivery := range due {
+			ok, err := d.deliveriesRepo.Claim(ctx, tx, delivery.DeliveryId, delivery.NextAttemptAt, until)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				claimed = append(claimed, delivery)
+			}
+		}
+
+		return 0, nil
+	})
+
+	return claimed, err
+}
+
+// send posts a delivery once and returns its outcome
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDueDelivery) models.WebhookDeliveryAttempt {
+	attempt := models.WebhookDeliveryAttempt{
+		DeliveryId: delivery.DeliveryId,
+		Attempts:   delivery.Attempts + 1,
+	}
+
+	statusCode, err := d.post(ctx, delivery)
+	attempt.StatusCode = statusCode
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		attempt.Status = models.WEBHOOK_DELIVERY_DELIVERED
+		attempt.NextAttemptAt = formatTime(now)
+		attempt.DeliveredAt = formatTime(now)
+		return attempt
+	case attempt.Attempts >= d.config.MaxAttempts:
+		attempt.Status = models.WEBHOOK_DELIVERY_FAILED
+		attempt.NextAttemptAt = formatTime(now)
+	default:
+		attempt.Status = models.WEBHOOK_DELIVERY_PENDING
+		attempt.NextAttemptAt = formatTime(now.Add(d.Backoff(attempt.Attempts)))
+	}
+
+	attempt.Error = err.Error()
+	if len(attempt.Error) > maxErrorLength {
+		attempt.Error = attempt.Error[:maxErrorLength]
+	}
+
+	return attempt
+}
+
+// post sends the signed payload, any 2xx answer counts as delivered
+func (d *Dispatcher) post(ctx context.Context, delivery models.WebhookDueDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", USER_AGENT)
+	req.Header.Set(HEADER_EVENT, delivery.Event)
+	req.Header.Set(HEADER_DELIVERY, delivery.EventId)
+	req.Header.Set(HEADER_SIGNATURE, Sign(delivery.Secret, time.Now().Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseRead))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Backoff is the wait after the given number of failed attempts
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	delay := d.config.BaseDelay
+	for i := 1; i < attempts && delay < d.config.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, d.config.MaxDelay)
+}
+
+// prune removes finished deliveries past the retention, at most once an hour
+func (d *Dispatcher) prune(ctx context.Context) {
+	if d.config.Retention <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+	if time.Since(d.lastPruned) < time.Hour {
+		d.mu.Unlock()
+		return
+	}
+	d.lastPruned = time.Now()
+	d.mu.Unlock()
+
+	before := formatTime(time.Now().Add(-d.config.Retention))
+
+	var removed int64
+	if _, err := d.dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		removed, err = d.deliveriesRepo.DeleteFinishedBefore(ctx, tx, before)
+		return 0, err
+	}); err != nil {
+		log.Printf("Failed to prune webhook deliveries: %v", err)
+		return
+	}
+
+	if removed > 0 {
+		log.Printf("Pruned %d webhook deliveries", removed)
+	}
+}
+
+// Sign returns the X-RAB-Signature header of a body sent at timestamp (Unix seconds):
+// t=<timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret>
+func Sign(secret string, timestamp int64, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, signature(secret, timestamp, body))
+}
+
+func signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a X-RAB-Signature header against the body, as a receiver does.
+// A tolerance above 0 also rejects signatures older or newer than it, against replays.
+func VerifySignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			timestamp = parsed
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	if tolerance > 0 {
+		age := time.Since(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidSignature)
+		}
+	}
+
+	expected := []byte(signature(secret, timestamp, body))
+	for _, sig := range signatures {
+		if hmac.Equal(expected, []byte(sig)) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(models.WEBHOOK_TIME_FORMAT)
+}
@@ -0,0 +1,279 @@
+package webhook_dispatch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/databases/dbtest"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/webhook_deliveries"
+	"github.com/momokii/go-rab-maker/backend/repository/webhooks"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is a local webhook endpoint answering status and recording what it received
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	received []receivedRequest
+}
+
+func newReceiver(t *testing.T, status int) (*receiver, string) {
+	r := &receiver{status: status}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.received = append(r.received, receivedRequest{header: req.Header.Clone(), body: body})
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(server.Close)
+
+	return r, server.URL
+}
+
+func (r *receiver) requests() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]receivedRequest(nil), r.received...)
+}
+
+func testDispatcher(t *testing.T, dbService databases.DatabaseServices, url string, events []string, config DispatcherConfig) (*Dispatcher, models.Webhook) {
+	t.Helper()
+	ctx := t.Context()
+
+	webhooksRepo := webhooks.NewWebhooksRepo()
+	dispatcher := NewDispatcher(dbService, webhooksRepo, webhook_deliveries.NewWebhookDeliveriesRepo(), config)
+
+	secret, err := models.NewWebhookSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+
+	var webhook models.Webhook
+	if _, err := dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := tx.Exec("INSERT INTO users (user_id, username, password) VALUES (1, 'budi', 'secret')"); err != nil {
+			return 0, err
+		}
+
+		webhookId, err := webhooksRepo.Create(ctx, tx, models.WebhookCreate{UserId: 1, URL: url, Secret: secret, Events: events})
+		if err != nil {
+			return 0, err
+		}
+
+		webhook, err = webhooksRepo.FindById(ctx, tx, webhookId)
+		return 0, err
+	}); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	return dispatcher, webhook
+}
+
+func publish(t *testing.T, dbService databases.DatabaseServices, dispatcher *Dispatcher, event string, data interface{}) {
+	t.Helper()
+	ctx := t.Context()
+
+	if _, err := dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		return 0, dispatcher.Publish(ctx, tx, 1, event, data)
+	}); err != nil {
+		t.Fatalf("Failed to publish %s: %v", event, err)
+	}
+}
+
+func deliveries(t *testing.T, dbService databases.DatabaseServices, webhookId int) []models.WebhookDelivery {
+	t.Helper()
+	ctx := t.Context()
+
+	var found []models.WebhookDelivery
+	if _, err := dbService.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		var err error
+		found, err = webhook_deliveries.NewWebhookDeliveriesRepo().FindByWebhookId(ctx, tx, webhookId, 100)
+		return 0, err
+	}); err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+
+	return found
+}
+
+// TestDispatcher_Delivers verifies a published event reaches the subscribed webhook once,
+// signed with its secret, and unsubscribed events are not queued
+func TestDispatcher_Delivers(t *testing.T) {
+	for _, dialect := range dbtest.Engines() {
+		t.Run(string(dialect), func(t *testing.T) {
+			dbService := dbtest.OpenServices(t, dialect)
+			recv, url := newReceiver(t, http.StatusNoContent)
+
+			dispatcher, webhook := testDispatcher(t, dbService, url, []string{models.WEBHOOK_EVENT_PROJECT_CREATED}, DefaultDispatcherConfig())
+
+			publish(t, dbService, dispatcher, models.WEBHOOK_EVENT_PROJECT_CREATED, map[string]int{"project_id": 4})
+			publish(t, dbService, dispatcher, models.WEBHOOK_EVENT_PROJECT_DELETED, map[string]int{"project_id": 4})
+
+			attempted, err := dispatcher.RunOnce(t.Context())
+			if err != nil || attempted != 1 {
+				t.Fatalf("Expected one delivery, got %d: %v", attempted, err)
+			}
+
+			requests := recv.requests()
+			if len(requests) != 1 {
+				t.Fatalf("Expected one request, got %d", len(requests))
+			}
+			request := requests[0]
+			if request.header.Get(HEADER_EVENT) != models.WEBHOOK_EVENT_PROJECT_CREATED {
+				t.Errorf("Unexpected event header %q", request.header.Get(HEADER_EVENT))
+			}
+			if err := VerifySignature(webhook.Secret, request.header.Get(HEADER_SIGNATURE), request.body, 5*time.Minute); err != nil {
+				t.Errorf("Signature does not verify: %v", err)
+			}
+			if err := VerifySignature("whsec_other", request.header.Get(HEADER_SIGNATURE), request.body, 0); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected another secret to be rejected, got %v", err)
+			}
+
+			found := deliveries(t, dbService, webhook.WebhookId)
+			if len(found) != 1 || found[0].Status != models.WEBHOOK_DELIVERY_DELIVERED || found[0].Attempts != 1 || found[0].LastStatusCode != http.StatusNoContent {
+				t.Fatalf("Unexpected deliveries: %+v", found)
+			}
+			if request.header.Get(HEADER_DELIVERY) != found[0].EventId {
+				t.Errorf("Expected the event id %s in the header, got %s", found[0].EventId, request.header.Get(HEADER_DELIVERY))
+			}
+
+			// nothing is due anymore
+			if attempted, err := dispatcher.RunOnce(t.Context()); err != nil || attempted != 0 {
+				t.Errorf("Expected nothing to send, got %d: %v", attempted, err)
+			}
+		})
+	}
+}
+
+// TestDispatcher_Retries verifies a failed delivery is retried with backoff and failed after MaxAttempts
+func TestDispatcher_Retries(t *testing.T) {
+	for _, dialect := range dbtest.Engines() {
+		t.Run(string(dialect), func(t *testing.T) {
+			ctx := t.Context()
+			dbService := dbtest.OpenServices(t, dialect)
+			recv, url := newReceiver(t, http.StatusInternalServerError)
+
+			config := DefaultDispatcherConfig()
+			config.MaxAttempts = 2
+			dispatcher, webhook := testDispatcher(t, dbService, url, []string{models.WEBHOOK_EVENT_WORK_ITEM_CHANGED}, config)
+
+			publish(t, dbService, dispatcher, models.WEBHOOK_EVENT_WORK_ITEM_CHANGED, nil)
+
+			if _, err := dispatcher.RunOnce(ctx); err != nil {
+				t.Fatalf("Failed to run: %v", err)
+			}
+
+			delivery := deliveries(t, dbService, webhook.WebhookId)[0]
+			if delivery.Status != models.WEBHOOK_DELIVERY_PENDING || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusInternalServerError || delivery.LastError == "" {
+				t.Fatalf("Expected a pending delivery after the first failure, got %+v", delivery)
+			}
+			nextAttempt, _ := time.Parse(models.WEBHOOK_TIME_FORMAT, delivery.NextAttemptAt)
+			if wait := time.Until(nextAttempt); wait < config.BaseDelay-2*time.Second || wait > config.BaseDelay+time.Second {
+				t.Errorf("Expected the next attempt in %s, got %s", config.BaseDelay, wait)
+			}
+
+			// not due yet
+			if attempted, _ := dispatcher.RunOnce(ctx); attempted != 0 {
+				t.Fatalf("Expected the delivery to wait for its backoff, %d sent", attempted)
+			}
+
+			if _, err := dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+				_, err := tx.Exec("UPDATE webhook_deliveries SET next_attempt_at = ?", formatTime(time.Now().Add(-time.Second)))
+				return 0, err
+			}); err != nil {
+				t.Fatalf("Failed to move the next attempt: %v", err)
+			}
+
+			if attempted, err := dispatcher.RunOnce(ctx); err != nil || attempted != 1 {
+				t.Fatalf("Expected the second attempt, got %d: %v", attempted, err)
+			}
+
+			delivery = deliveries(t, dbService, webhook.WebhookId)[0]
+			if delivery.Status != models.WEBHOOK_DELIVERY_FAILED || delivery.Attempts != 2 {
+				t.Fatalf("Expected a failed delivery after MaxAttempts, got %+v", delivery)
+			}
+			if len(recv.requests()) != 2 {
+				t.Errorf("Expected two requests, got %d", len(recv.requests()))
+			}
+
+			// a retry from the delivery log sends it again right away
+			recv.mu.Lock()
+			recv.status = http.StatusOK
+			recv.mu.Unlock()
+
+			if _, err := dbService.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+				return 0, dispatcher.deliveriesRepo.Retry(ctx, tx, delivery.DeliveryId)
+			}); err != nil {
+				t.Fatalf("Failed to retry: %v", err)
+			}
+			if attempted, err := dispatcher.RunOnce(context.Background()); err != nil || attempted != 1 {
+				t.Fatalf("Expected the retried delivery to be sent, got %d: %v", attempted, err)
+			}
+			if delivery = deliveries(t, dbService, webhook.WebhookId)[0]; delivery.Status != models.WEBHOOK_DELIVERY_DELIVERED {
+				t.Errorf("Expected the retried delivery to be delivered, got %+v", delivery)
+			}
+		})
+	}
+}
+
+// TestDispatcher_Backoff verifies the delay doubles up to MaxDelay
+func TestDispatcher_Backoff(t *testing.T) {
+	dispatcher := NewDispatcher(nil, nil, nil, DefaultDispatcherConfig())
+
+	expected := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		7:  32 * time.Minute,
+		8:  time.Hour,
+		20: time.Hour,
+	}
+	for attempts, delay := range expected {
+		if got := dispatcher.Backoff(attempts); got != delay {
+			t.Errorf("Backoff(%d) = %s, expected %s", attempts, got, delay)
+		}
+	}
+}
+
+// TestVerifySignature verifies tampered bodies, stale timestamps and malformed headers are rejected
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"event":"ping"}`)
+	now := time.Now().Unix()
+	header := Sign("whsec_test", now, body)
+
+	if err := VerifySignature("whsec_test", header, body, time.Minute); err != nil {
+		t.Errorf("Expected the signature to verify: %v", err)
+	}
+
+	rejected := map[string]struct {
+		header string
+		body   []byte
+	}{
+		"tampered body": {header, []byte(`{"event":"pong"}`)},
+		"stale":         {Sign("whsec_test", now-3600, body), body},
+		"no timestamp":  {"v1=abc", body},
+		"no signature":  {"t=123", body},
+		"empty":         {"", body},
+	}
+	for name, tc := range rejected {
+		if err := VerifySignature("whsec_test", tc.header, tc.body, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+}
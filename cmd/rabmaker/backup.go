@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/momokii/go-rab-maker/backend/databases"
+)
+
+func runBackup(args []string) error {
+	fs, dbPath := newFlagSet("backup")
+	out := fs.String("out", "", "backup file to write (default: a new file in the backup directory)")
+	fs.Parse(args)
+
+	db, err := openDatabase(*dbPath)
+	if err != nil {
+		return err
+	}
+
+	dest := *out
+	if dest == "" {
+		dest = filepath.Join(db.BackupDir(), databases.NewBackupFileName(databases.BACKUP_PREFIX_MANUAL))
+	}
+
+	if err := db.Backup(context.Background(), dest); err != nil {
+		return err
+	}
+
+	fmt.Println("Backup written to", dest)
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs, dbPath := newFlagSet("restore")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("restore needs exactly one backup file")
+	}
+
+	db, err := openDatabase(*dbPath)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Restore(context.Background(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %s (schema version %d)\n", fs.Arg(0), result.Info.SchemaVersion)
+	fmt.Println("The previous database was saved to", result.SafetyCopy)
+	return nil
+}
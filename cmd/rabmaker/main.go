@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/momokii/go-rab-maker/backend/databases"
+)
+
+const usage = `Usage: rabmaker <command> [flags]
+
+Commands:
+  migrate status|up|down                     show, apply or roll back schema migrations
+  migrate down [--to version] [--dry-run]    roll back to a version, or print the statements only
+  migrate repair                             clear a failed (dirty) migration, accept changed migration files
+  user create --username name [--role role]  add a user (password from --password or prompt)
+  user set-role --username name --role role  make a user admin, estimator, reviewer or viewer
+  user reset-password --username name        set a new password
+  user disable --username name               soft delete a user so they can no longer log in
+  backup [--out file]                        write a consistent copy of the database
+  restore <file>                             replace the database with a backup
+  export-project --id id [--out file]        write a project as a .rab.json bundle
+  seed-demo [--username demo]                add a demo user with a sample project
+
+Every command accepts --db to use another database file. By default it is the
+same database the server uses. With DATABASE_DRIVER=postgres the commands use
+DATABASE_URL instead; backup and restore are SQLite only.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "migrate":
+		err = runMigrate(os.Args[2:])
+	case "user":
+		err = runUser(os.Args[2:])
+	case "backup":
+		err = runBackup(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	case "export-project":
+		err = runExportProject(os.Args[2:])
+	case "seed-demo":
+		err = runSeedDemo(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// newFlagSet creates the flags of a command with the shared --db flag
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	dbPath := fs.String("db", databases.DATABASE_SQLITE_PATH, "path of the database file")
+	return fs, dbPath
+}
+
+// databaseConfig is the configuration of the server with the SQLite file set to path
+func databaseConfig(path string) (databases.DatabaseConfig, error) {
+	config, err := databases.LoadDatabaseConfig()
+	if err != nil {
+		return config, err
+	}
+	config.SQLitePath = path
+
+	return config, nil
+}
+
+// openDatabase opens an existing database, never creating an empty one
+func openDatabase(path string) (databases.DatabaseServices, error) {
+	config, err := databaseConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Driver == databases.DIALECT_SQLITE {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("database not found at %s", path)
+		}
+	}
+
+	return databases.NewDatabases(config)
+}
+
+// openMigratedDatabase opens an existing database whose schema is up to date
+func openMigratedDatabase(path string) (databases.DatabaseServices, error) {
+	db, err := openDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := db.GetDB().MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := 0
+	for _, state := range states {
+		if !state.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return nil, fmt.Errorf("the database has %d pending migration(s), run \"rabmaker migrate up\" first", pending)
+	}
+
+	return db, nil
+}
+
+// createDatabaseDir makes sure the folder of a new database file exists
+func createDatabaseDir(path string) error {
+	return os.MkdirAll(filepath.Dir(path), 0755)
+}
+
+// readPassword returns the flag value, or reads a line from stdin when it is empty
+func readPassword(value string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
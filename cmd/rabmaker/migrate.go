@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/momokii/go-rab-maker/backend/databases"
+)
+
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate needs a subcommand: status, up, down or repair")
+	}
+
+	fs, dbPath := newFlagSet("migrate " + args[0])
+	dryRun := fs.Bool("dry-run", false, "print the statements that would run without changing the database")
+	to := fs.Int("to", -1, "roll back every migration above this version (down only, default: the latest one)")
+	fs.Parse(args[1:])
+
+	switch args[0] {
+	case "status":
+		db, err := openDatabase(*dbPath)
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(db.GetDB())
+
+	case "up":
+		if *dryRun {
+			db, err := openDatabase(*dbPath)
+			if err != nil {
+				return err
+			}
+			steps, err := db.GetDB().PlanUp()
+			if err != nil {
+				return err
+			}
+			return printMigrationSteps(steps)
+		}
+
+		config, err := databaseConfig(*dbPath)
+		if err != nil {
+			return err
+		}
+
+		// a missing database is created, like the server does on its first start
+		if config.Driver == databases.DIALECT_SQLITE {
+			if err := createDatabaseDir(*dbPath); err != nil {
+				return err
+			}
+		}
+		services, err := databases.NewDatabases(config)
+		if err != nil {
+			return err
+		}
+		db := services.GetDB()
+		if err := db.MigrateUp(); err != nil {
+			return err
+		}
+		return printMigrationStatus(db)
+
+	case "down":
+		services, err := openDatabase(*dbPath)
+		if err != nil {
+			return err
+		}
+		db := services.GetDB()
+
+		if *to < 0 {
+			states, err := db.MigrationStatus()
+			if err != nil {
+				return err
+			}
+			*to = databases.PreviousVersion(states)
+		}
+
+		if *dryRun {
+			steps, err := db.PlanDown(*to)
+			if err != nil {
+				return err
+			}
+			return printMigrationSteps(steps)
+		}
+
+		steps, err := db.MigrateDownTo(*to)
+		if err != nil {
+			return err
+		}
+		for _, step := range steps {
+			fmt.Printf("Rolled back %d_%s\n", step.Version, step.Name)
+		}
+		fmt.Printf("The database is now at version %d\n", *to)
+		return nil
+
+	case "repair":
+		db, err := openDatabase(*dbPath)
+		if err != nil {
+			return err
+		}
+		repair, err := db.GetDB().RepairMigrations()
+		if err != nil {
+			return err
+		}
+		if len(repair.Cleared) == 0 && len(repair.Accepted) == 0 {
+			fmt.Println("Nothing to repair")
+			return nil
+		}
+		for _, state := range repair.Cleared {
+			fmt.Printf("Cleared dirty %d_%s, it runs again on the next migrate up\n", state.Version, state.Name)
+		}
+		for _, state := range repair.Accepted {
+			fmt.Printf("Accepted the changed file of %d_%s\n", state.Version, state.Name)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown migrate subcommand %q", args[0])
+}
+
+func printMigrationStatus(db *databases.DB) error {
+	states, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tDOWN")
+
+	applied := 0
+	for _, state := range states {
+		status := "pending"
+		if state.Applied {
+			applied++
+			status = "applied " + state.AppliedAt
+		}
+		if state.Dirty {
+			status = "dirty, started " + state.AppliedAt
+		}
+		if state.Missing {
+			status += " (no file in this build)"
+		}
+		if state.Modified {
+			status += " (file changed since)"
+		}
+
+		down := "-"
+		if state.HasDown {
+			down = "yes"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", state.Version, state.Name, status, down)
+	}
+	w.Flush()
+
+	fmt.Printf("\nCurrent version %d: %d applied, %d pending\n", databases.CurrentVersion(states), applied, len(states)-applied)
+	return nil
+}
+
+// printMigrationSteps writes the steps as a SQL script, each statement the way splitSQL cut it
+func printMigrationSteps(steps []databases.MigrationStep) error {
+	if len(steps) == 0 {
+		fmt.Println("-- nothing to run")
+		return nil
+	}
+
+	for _, step := range steps {
+		fmt.Printf("-- %06d_%s (%s: %s)\n", step.Version, step.Name, step.Direction, step.FileName)
+		for _, stmt := range step.Statements {
+			fmt.Println(strings.TrimSpace(stmt) + ";")
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
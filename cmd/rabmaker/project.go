@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/container"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/project_bundle"
+	"github.com/momokii/go-rab-maker/backend/repository/users"
+)
+
+func runExportProject(args []string) error {
+	ctx := context.Background()
+
+	fs, dbPath := newFlagSet("export-project")
+	projectId := fs.Int("id", 0, "id of the project to export")
+	out := fs.String("out", "", "bundle file to write, - for stdout (default: project-<id>.rab.json)")
+	fs.Parse(args)
+
+	if *projectId <= 0 {
+		return fmt.Errorf("--id is required")
+	}
+
+	db, err := openMigratedDatabase(*dbPath)
+	if err != nil {
+		return err
+	}
+
+	bundler := container.NewBundler(container.NewRepositories())
+
+	var bundle models.ProjectBundle
+	if _, err := db.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		bundle, err = bundler.Export(ctx, tx, *projectId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return http.StatusNotFound, fmt.Errorf("project %d not found", *projectId)
+		}
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	}); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" {
+		_, err := os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+
+	dest := *out
+	if dest == "" {
+		dest = fmt.Sprintf("project-%d.rab.json", *projectId)
+	}
+
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Project %d exported to %s\n", *projectId, dest)
+	return nil
+}
+
+func runSeedDemo(args []string) error {
+	ctx := context.Background()
+
+	fs, dbPath := newFlagSet("seed-demo")
+	username := fs.String("username", "demo", "username of the demo account, created when missing")
+	password := fs.String("password", "demo123", "password of the demo account when it is created")
+	fs.Parse(args)
+
+	db, err := openMigratedDatabase(*dbPath)
+	if err != nil {
+		return err
+	}
+
+	usersRepo := users.NewUsersRepo()
+	bundler := container.NewBundler(container.NewRepositories())
+
+	var result models.ProjectBundleImportResult
+	created := false
+
+	if _, err := db.ReadTransaction(ctx, func(tx *sql.Tx) (int, error) {
+		_, err := usersRepo.FindByUsername(ctx, tx, *username)
+		if errors.Is(err, sql.ErrNoRows) {
+			created = true
+		} else if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	}); err != nil {
+		return err
+	}
+
+	if created {
+		if err := createUser(db, usersRepo, *username, *password, ""); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		user, err := usersRepo.FindByUsername(ctx, tx, *username)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		// the demo project goes through the bundle importer, so it gets the same
+		// masters, templates and categories an imported project would
+		result, err = bundler.Import(ctx, tx, demoBundle(), models.Workspace{UserId: user.UserId})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	}); err != nil {
+		return err
+	}
+
+	if created {
+		fmt.Printf("Demo user %s created with password %s\n", *username, *password)
+	}
+	fmt.Printf("Demo project %d added to %s with %d work items\n", result.ProjectId, *username, result.WorkItems)
+	return nil
+}
+
+// demoBundle is a small house project: masters, two AHSP templates and work items priced from them
+func demoBundle() models.ProjectBundle {
+	bundle := models.ProjectBundle{
+		Format:     project_bundle.BUNDLE_FORMAT,
+		Version:    project_bundle.BUNDLE_VERSION,
+		ExportedAt: time.Now().Format(time.RFC3339),
+		Project: models.ProjectBundleProject{
+			ProjectName: "Rumah Tinggal Tipe 36 (Demo)",
+			Location:    "Bandung",
+			ClientName:  "Bapak Budi",
+		},
+		Materials: []models.ProjectBundleMaterial{
+			{Id: 1, MaterialName: "Bata Merah", Unit: "buah", DefaultUnitPrice: 900},
+			{Id: 2, MaterialName: "Semen Portland", Unit: "zak", DefaultUnitPrice: 75000},
+			{Id: 3, MaterialName: "Pasir Pasang", Unit: "m3", DefaultUnitPrice: 250000},
+		},
+		LaborTypes: []models.ProjectBundleLaborType{
+			{Id: 1, RoleName: "Pekerja", Unit: "OH", DefaultDailyWage: 120000},
+			{Id: 2, RoleName: "Tukang Batu", Unit: "OH", DefaultDailyWage: 150000},
+			{Id: 3, RoleName: "Kepala Tukang", Unit: "OH", DefaultDailyWage: 170000},
+			{Id: 4, RoleName: "Mandor", Unit: "OH", DefaultDailyWage: 180000},
+		},
+		Templates: []models.ProjectBundleTemplate{
+			{
+				Id:           1,
+				Code:         "DEMO.01",
+				TemplateName: "Pemasangan 1 m2 Dinding Bata Merah 1:4",
+				Unit:         "m2",
+				MaterialComponents: []models.ProjectBundleComponent{
+					{ItemId: 1, Coefficient: 70},
+					{ItemId: 2, Coefficient: 0.23},
+					{ItemId: 3, Coefficient: 0.043},
+				},
+				LaborComponents: []models.ProjectBundleComponent{
+					{ItemId: 1, Coefficient: 0.3},
+					{ItemId: 2, Coefficient: 0.1},
+					{ItemId: 3, Coefficient: 0.01},
+					{ItemId: 4, Coefficient: 0.015},
+				},
+			},
+			{
+				Id:           2,
+				Code:         "DEMO.02",
+				TemplateName: "Pemasangan 1 m2 Plesteran 1:4 Tebal 15 mm",
+				Unit:         "m2",
+				MaterialComponents: []models.ProjectBundleComponent{
+					{ItemId: 2, Coefficient: 0.125},
+					{ItemId: 3, Coefficient: 0.026},
+				},
+				LaborComponents: []models.ProjectBundleComponent{
+					{ItemId: 1, Coefficient: 0.3},
+					{ItemId: 2, Coefficient: 0.15},
+					{ItemId: 3, Coefficient: 0.015},
+					{ItemId: 4, Coefficient: 0.015},
+				},
+			},
+		},
+	}
+
+	bundle.WorkItems = []models.ProjectBundleWorkItem{
+		{
+			CategoryName: "Pekerjaan Persiapan",
+			Description:  "Pembersihan lokasi dan pemasangan bouwplank",
+			Volume:       1,
+			Unit:         "ls",
+			Costs: []models.ProjectBundleCost{{
+				ItemType:            string(models.PROJECT_ITEM_TYPE_MATERIAL),
+				ItemName:            "Pembersihan lokasi dan pemasangan bouwplank",
+				Coefficient:         1,
+				QuantityNeeded:      1,
+				Unit:                "ls",
+				UnitPriceAtCreation: 2500000,
+				TotalCost:           2500000,
+			}},
+		},
+		demoWorkItem(bundle, "Pekerjaan Pasangan", "Pasangan dinding bata merah 1:4", 85, 1),
+		demoWorkItem(bundle, "Pekerjaan Plesteran", "Plesteran dinding 1:4 tebal 15 mm", 170, 2),
+	}
+
+	return bundle
+}
+
+// demoWorkItem prices a work item from a bundle template like the work item form does
+func demoWorkItem(bundle models.ProjectBundle, category, description string, volume float64, templateId int) models.ProjectBundleWorkItem {
+	workItem := models.ProjectBundleWorkItem{
+		CategoryName: category,
+		Description:  description,
+		Volume:       volume,
+		TemplateId:   templateId,
+	}
+
+	template := bundle.Templates[templateId-1]
+	workItem.Unit = template.Unit
+
+	for _, component := range template.MaterialComponents {
+		material := bundle.Materials[component.ItemId-1]
+		workItem.Costs = append(workItem.Costs, demoCost(models.PROJECT_ITEM_TYPE_MATERIAL, component, material.MaterialName, material.Unit, material.DefaultUnitPrice, volume))
+	}
+	for _, component := range template.LaborComponents {
+		laborType := bundle.LaborTypes[component.ItemId-1]
+		workItem.Costs = append(workItem.Costs, demoCost(models.PROJECT_ITEM_TYPE_LABOR, component, laborType.RoleName, laborType.Unit, laborType.DefaultDailyWage, volume))
+	}
+
+	return workItem
+}
+
+func demoCost(itemType models.ItemType, component models.ProjectBundleComponent, name, unit string, price, volume float64) models.ProjectBundleCost {
+	quantity := component.Coefficient * volume
+
+	return models.ProjectBundleCost{
+		ItemType:            string(itemType),
+		ItemId:              component.ItemId,
+		ItemName:            name,
+		Coefficient:         component.Coefficient,
+		QuantityNeeded:      quantity,
+		Unit:                unit,
+		UnitPriceAtCreation: price,
+		TotalCost:           quantity * price,
+	}
+}
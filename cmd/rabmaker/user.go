@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/momokii/go-rab-maker/backend/databases"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/repository/users"
+	"github.com/momokii/go-rab-maker/backend/utils"
+)
+
+func runUser(args []string) error {
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		return fmt.Errorf("user needs a subcommand: create, set-role, reset-password or disable")
+	}
+
+	fs, dbPath := newFlagSet("user " + args[0])
+	username := fs.String("username", "", "username of the account")
+	password := fs.String("password", "", "password (prompted when empty)")
+	role := fs.String("role", "", "role: admin, estimator, reviewer or viewer")
+	fs.Parse(args[1:])
+
+	if *username == "" {
+		return fmt.Errorf("--username is required")
+	}
+	if *role != "" && !models.IsRole(*role) {
+		return fmt.Errorf("unknown role %q, use one of %s", *role, strings.Join(models.ROLES, ", "))
+	}
+
+	db, err := openMigratedDatabase(*dbPath)
+	if err != nil {
+		return err
+	}
+
+	usersRepo := users.NewUsersRepo()
+
+	switch args[0] {
+	case "create":
+		plain, err := readPassword(*password)
+		if err != nil {
+			return err
+		}
+		if err := createUser(db, usersRepo, *username, plain, *role); err != nil {
+			return err
+		}
+		fmt.Printf("User %s created\n", *username)
+		return nil
+
+	case "set-role":
+		if *role == "" {
+			return fmt.Errorf("--role is required")
+		}
+
+		if _, err := db.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+			user, err := findActiveUser(ctx, tx, usersRepo, *username)
+			if err != nil {
+				return http.StatusNotFound, err
+			}
+
+			return http.StatusOK, usersRepo.UpdateRole(ctx, tx, user.UserId, *role)
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("User %s is now %s\n", *username, *role)
+		return nil
+
+	case "reset-password":
+		plain, err := readPassword(*password)
+		if err != nil {
+			return err
+		}
+		if err := validatePassword(*username, plain); err != nil {
+			return err
+		}
+		hashed, err := models.HashPassword(plain)
+		if err != nil {
+			return err
+		}
+
+		if _, err := db.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+			user, err := findActiveUser(ctx, tx, usersRepo, *username)
+			if err != nil {
+				return http.StatusNotFound, err
+			}
+
+			user.Password = hashed
+			return http.StatusOK, usersRepo.Update(ctx, tx, user)
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("Password of %s updated\n", *username)
+		return nil
+
+	case "disable":
+		if _, err := db.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+			user, err := findActiveUser(ctx, tx, usersRepo, *username)
+			if err != nil {
+				return http.StatusNotFound, err
+			}
+
+			return http.StatusOK, usersRepo.SoftDelete(ctx, tx, user.UserId)
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("User %s disabled\n", *username)
+		return nil
+	}
+
+	return fmt.Errorf("unknown user subcommand %q", args[0])
+}
+
+// createUser applies the same rules as the registration form, an empty role is the default role
+func createUser(db databases.DatabaseServices, usersRepo users.Repository, username, password, role string) error {
+	ctx := context.Background()
+
+	if err := validatePassword(username, password); err != nil {
+		return err
+	}
+
+	hashed, err := models.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Transaction(ctx, func(tx *sql.Tx) (int, error) {
+		if _, err := usersRepo.FindByUsername(ctx, tx, username); err == nil {
+			return http.StatusConflict, fmt.Errorf("username %s is already taken", username)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, usersRepo.Create(ctx, tx, models.UserCreate{
+			Username: username,
+			Password: hashed,
+			Role:     role,
+		})
+	})
+
+	return err
+}
+
+func validatePassword(username, password string) error {
+	if err := utils.ValidateStruct(models.UserCreate{Username: username, Password: password}); err != nil {
+		return fmt.Errorf("username must be 3 to 50 characters and password 6 to 100 characters")
+	}
+
+	return nil
+}
+
+func findActiveUser(ctx context.Context, tx *sql.Tx, usersRepo users.Repository, username string) (models.User, error) {
+	user, err := usersRepo.FindByUsername(ctx, tx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, fmt.Errorf("user %s not found", username)
+	}
+
+	return user, err
+}
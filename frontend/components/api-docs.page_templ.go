@@ -0,0 +1,81 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.943
+package components
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+// APIDocsPage renders the OpenAPI document at specURL. It is a page of its own without the CDN
+// styles and scripts of BaseMain, so the docs work on a machine without internet access.
+func APIDocsPage(specURL string) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"en\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>API Docs - RAB Maker</title><style>\n\t\t\t\tbody { margin: 0; font-family: system-ui, -apple-system, \"Segoe UI\", Roboto, sans-serif; color: #1f2937; background: #f9fafb; }\n\t\t\t\theader { background: #1e3a8a; color: #fff; padding: 1.25rem 2rem; }\n\t\t\t\theader h1 { margin: 0 0 0.25rem; font-size: 1.5rem; }\n\t\t\t\theader p { margin: 0; opacity: 0.85; font-size: 0.9rem; }\n\t\t\t\theader a { color: #bfdbfe; }\n\t\t\t\tmain { max-width: 1100px; margin: 0 auto; padding: 1.5rem 2rem 4rem; }\n\t\t\t\th2 { text-transform: capitalize; border-bottom: 1px solid #d1d5db; padding-bottom: 0.25rem; margin-top: 2rem; }\n\t\t\t\th4 { margin: 1rem 0 0.5rem; font-size: 0.85rem; text-transform: uppercase; color: #6b7280; }\n\t\t\t\tdetails.operation { background: #fff; border: 1px solid #e5e7eb; border-radius: 0.5rem; margin: 0.5rem 0; }\n\t\t\t\tdetails.operation > summary { cursor: pointer; padding: 0.6rem 0.8rem; display: flex; gap: 0.75rem; align-items: center; }\n\t\t\t\tdetails.operation > div { padding: 0 1rem 1rem; }\n\t\t\t\t.method { font-weight: 700; font-size: 0.75rem; width: 4.5rem; text-align: center; padding: 0.2rem 0; border-radius: 0.25rem; color: #fff; }\n\t\t\t\t.method-get { background: #2563eb; }\n\t\t\t\t.method-post { background: #16a34a; }\n\t\t\t\t.method-put { background: #d97706; }\n\t\t\t\t.method-delete { background: #dc2626; }\n\t\t\t\t.path { font-family: ui-monospace, Menlo, Consolas, monospace; font-weight: 600; }\n\t\t\t\t.summary { color: #4b5563; }\n\t\t\t\ttable { border-collapse: collapse; width: 100%; font-size: 0.85rem; }\n\t\t\t\tth, td { text-align: left; vertical-align: top; padding: 0.35rem 0.5rem; border-bottom: 1px solid #f3f4f6; }\n\t\t\t\tth { color: #6b7280; font-weight: 600; }\n\t\t\t\tcode, .type { font-family: ui-monospace, Menlo, Consolas, monospace; font-size: 0.8rem; }\n\t\t\t\t.type { color: #7c3aed; }\n\t\t\t\t.required { color: #dc2626; font-size: 0.75rem; }\n\t\t\t\t.constraint { color: #6b7280; font-size: 0.75rem; }\n\t\t\t\t.nested { margin-left: 1.25rem; border-left: 2px solid #e5e7eb; padding-left: 0.5rem; }\n\t\t\t\t.error { color: #dc2626; }\n\t\t\t</style></head><body><header><h1 id=\"api-title\">API Docs</h1><p id=\"api-description\"></p><p>OpenAPI document: <a href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var2 templ.SafeURL
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinURLErrs(templ.SafeURL(specURL))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `frontend/components/api-docs.page.templ`, Line: 46, Col: 57}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var3 string
+		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(specURL)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `frontend/components/api-docs.page.templ`, Line: 46, Col: 69}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</a></p></header><main id=\"api-docs\" data-spec=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var4 string
+		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(specURL)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `frontend/components/api-docs.page.templ`, Line: 48, Col: 42}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\">Loading...</main><script>\n\t\t\t\t(function () {\n\t\t\t\t\tconst root = document.getElementById(\"api-docs\");\n\n\t\t\t\t\tfunction el(tag, className, text) {\n\t\t\t\t\t\tconst node = document.createElement(tag);\n\t\t\t\t\t\tif (className) node.className = className;\n\t\t\t\t\t\tif (text !== undefined) node.textContent = text;\n\t\t\t\t\t\treturn node;\n\t\t\t\t\t}\n\n\t\t\t\t\tfunction refName(ref) {\n\t\t\t\t\t\treturn ref.split(\"/\").pop();\n\t\t\t\t\t}\n\n\t\t\t\t\tfunction resolve(spec, schema) {\n\t\t\t\t\t\tif (schema && schema.$ref) return spec.components.schemas[refName(schema.$ref)];\n\t\t\t\t\t\tif (schema && schema.allOf && schema.allOf.length === 1) return resolve(spec, schema.allOf[0]);\n\t\t\t\t\t\treturn schema || {};\n\t\t\t\t\t}\n\n\t\t\t\t\tfunction typeName(schema) {\n\t\t\t\t\t\tif (schema.$ref) return refName(schema.$ref);\n\t\t\t\t\t\tif (schema.allOf && schema.allOf.length === 1) return typeName(schema.allOf[0]) + \" | null\";\n\t\t\t\t\t\tlet name = schema.type || \"any\";\n\t\t\t\t\t\tif (schema.type === \"array\") name = typeName(schema.items || {}) + \"[]\";\n\t\t\t\t\t\tif (schema.nullable) name += \" | null\";\n\t\t\t\t\t\treturn name;\n\t\t\t\t\t}\n\n\t\t\t\t\tfunction constraints(schema) {\n\t\t\t\t\t\tconst parts = [];\n\t\t\t\t\t\tif (schema.enum) parts.push(\"one of \" + schema.enum.join(\", \"));\n\t\t\t\t\t\tif (schema.minLength !== undefined) parts.push(\"min length \" + schema.minLength);\n\t\t\t\t\t\tif (schema.maxLength !== undefined) parts.push(\"max length \" + schema.maxLength);\n\t\t\t\t\t\tif (schema.minimum !== undefined) parts.push((schema.exclusiveMinimum ? \"> \" : \">= \") + schema.minimum);\n\t\t\t\t\t\tif (schema.maximum !== undefined) parts.push((schema.exclusiveMaximum ? \"< \" : \"<= \") + schema.maximum);\n\t\t\t\t\t\tif (schema.minItems !== undefined) parts.push(\"min items \" + schema.minItems);\n\t\t\t\t\t\tif (schema.maxItems !== undefined) parts.push(\"max items \" + schema.maxItems);\n\t\t\t\t\t\tif (schema.default !== undefined) parts.push(\"default \" + schema.default);\n\t\t\t\t\t\treturn parts.join(\", \");\n\t\t\t\t\t}\n\n\t\t\t\t\t// fields lists the properties of an object schema, nested objects are listed below\n\t\t\t\t\t// their field. seen stops a schema that refers to itself.\n\t\t\t\t\tfunction fields(spec, schema, seen) {\n\t\t\t\t\t\tconst object = resolve(spec, schema.type === \"array\" ? schema.items : schema);\n\t\t\t\t\t\tconst table = el(\"table\");\n\t\t\t\t\t\tif (!object.properties) return table;\n\n\t\t\t\t\t\tconst key = schema.$ref || (schema.items && schema.items.$ref);\n\t\t\t\t\t\tif (key && seen.includes(key)) return table;\n\t\t\t\t\t\tconst nextSeen = key ? seen.concat(key) : seen;\n\n\t\t\t\t\t\tfor (const name of Object.keys(object.properties)) {\n\t\t\t\t\t\t\tconst property = object.properties[name];\n\t\t\t\t\t\t\tconst row = table.insertRow();\n\t\t\t\t\t\t\tconst nameCell = row.insertCell();\n\t\t\t\t\t\t\tnameCell.appendChild(el(\"code\", \"\", name));\n\t\t\t\t\t\t\tif ((object.required || []).includes(name)) nameCell.appendChild(el(\"div\", \"required\", \"required\"));\n\t\t\t\t\t\t\trow.insertCell().appendChild(el(\"span\", \"type\", typeName(property)));\n\t\t\t\t\t\t\tconst detail = row.insertCell();\n\t\t\t\t\t\t\tdetail.appendChild(el(\"span\", \"constraint\", constraints(resolve(spec, property))));\n\n\t\t\t\t\t\t\tconst inner = resolve(spec, property.type === \"array\" ? property.items : property);\n\t\t\t\t\t\t\tif (inner && inner.properties) {\n\t\t\t\t\t\t\t\tconst nested = el(\"div\", \"nested\");\n\t\t\t\t\t\t\t\tnested.appendChild(fields(spec, property, nextSeen));\n\t\t\t\t\t\t\t\tdetail.appendChild(nested);\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t}\n\t\t\t\t\t\treturn table;\n\t\t\t\t\t}\n\n\t\t\t\t\tfunction operationView(spec, method, path, operation) {\n\t\t\t\t\t\tconst details = el(\"details\", \"operation\");\n\t\t\t\t\t\tconst summary = el(\"summary\");\n\t\t\t\t\t\tsummary.appendChild(el(\"span\", \"method method-\" + method, method.toUpperCase()));\n\t\t\t\t\t\tsummary.appendChild(el(\"span\", \"path\", path));\n\t\t\t\t\t\tsummary.appendChild(el(\"span\", \"summary\", operation.summary || \"\"));\n\t\t\t\t\t\tdetails.appendChild(summary);\n\n\t\t\t\t\t\tconst body = el(\"div\");\n\t\t\t\t\t\tif (operation.parameters && operation.parameters.length) {\n\t\t\t\t\t\t\tbody.appendChild(el(\"h4\", \"\", \"Parameters\"));\n\t\t\t\t\t\t\tconst table = el(\"table\");\n\t\t\t\t\t\t\tfor (const parameter of operation.parameters) {\n\t\t\t\t\t\t\t\tconst row = table.insertRow();\n\t\t\t\t\t\t\t\trow.insertCell().appendChild(el(\"code\", \"\", parameter.name));\n\t\t\t\t\t\t\t\trow.insertCell().textContent = parameter.in + (parameter.required ? \", required\" : \"\");\n\t\t\t\t\t\t\t\trow.insertCell().appendChild(el(\"span\", \"type\", typeName(parameter.schema)));\n\t\t\t\t\t\t\t\trow.insertCell().textContent = [parameter.description, constraints(parameter.schema)].filter(Boolean).join(\". \");\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\tbody.appendChild(table);\n\t\t\t\t\t\t}\n\n\t\t\t\t\t\tif (operation.requestBody) {\n\t\t\t\t\t\t\tconst contentType = Object.keys(operation.requestBody.content)[0];\n\t\t\t\t\t\t\tconst schema = operation.requestBody.content[contentType].schema;\n\t\t\t\t\t\t\tbody.appendChild(el(\"h4\", \"\", \"Request body (\" + contentType + \"): \" + typeName(schema)));\n\t\t\t\t\t\t\tbody.appendChild(fields(spec, schema, []));\n\t\t\t\t\t\t}\n\n\t\t\t\t\t\tbody.appendChild(el(\"h4\", \"\", \"Responses\"));\n\t\t\t\t\t\tfor (const status of Object.keys(operation.responses)) {\n\t\t\t\t\t\t\tconst response = operation.responses[status];\n\t\t\t\t\t\t\tconst line = el(\"div\");\n\t\t\t\t\t\t\tline.appendChild(el(\"strong\", \"\", status + \" \"));\n\t\t\t\t\t\t\tline.appendChild(document.createTextNode(response.description));\n\t\t\t\t\t\t\tbody.appendChild(line);\n\n\t\t\t\t\t\t\tif (response.content && status.startsWith(\"2\")) {\n\t\t\t\t\t\t\t\tconst schema = response.content[Object.keys(response.content)[0]].schema;\n\t\t\t\t\t\t\t\tconst nested = el(\"div\", \"nested\");\n\t\t\t\t\t\t\t\tnested.appendChild(fields(spec, schema, []));\n\t\t\t\t\t\t\t\tbody.appendChild(nested);\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t}\n\n\t\t\t\t\t\tdetails.appendChild(body);\n\t\t\t\t\t\treturn details;\n\t\t\t\t\t}\n\n\t\t\t\t\tfunction render(spec) {\n\t\t\t\t\t\tdocument.getElementById(\"api-title\").textContent = spec.info.title + \" \" + spec.info.version;\n\t\t\t\t\t\tdocument.getElementById(\"api-description\").textContent = spec.info.description || \"\";\n\t\t\t\t\t\troot.textContent = \"\";\n\n\t\t\t\t\t\tconst server = (spec.servers && spec.servers[0]) ? spec.servers[0].url : \"\";\n\t\t\t\t\t\tconst auth = el(\"p\");\n\t\t\t\t\t\tauth.textContent = \"Paths are relative to \" + server + \". Authenticate with a bearer token (Authorization: Bearer rab_...) or the login session cookie.\";\n\t\t\t\t\t\troot.appendChild(auth);\n\n\t\t\t\t\t\tconst methods = [\"get\", \"post\", \"put\", \"patch\", \"delete\"];\n\t\t\t\t\t\tfor (const tag of (spec.tags || [])) {\n\t\t\t\t\t\t\troot.appendChild(el(\"h2\", \"\", tag.name.replace(/-/g, \" \")));\n\t\t\t\t\t\t\tfor (const path of Object.keys(spec.paths)) {\n\t\t\t\t\t\t\t\tfor (const method of methods) {\n\t\t\t\t\t\t\t\t\tconst operation = spec.paths[path][method];\n\t\t\t\t\t\t\t\t\tif (operation && (operation.tags || []).includes(tag.name)) {\n\t\t\t\t\t\t\t\t\t\troot.appendChild(operationView(spec, method, server + path, operation));\n\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t}\n\t\t\t\t\t}\n\n\t\t\t\t\tfetch(root.dataset.spec)\n\t\t\t\t\t\t.then(function (response) {\n\t\t\t\t\t\t\tif (!response.ok) throw new Error(\"HTTP \" + response.status);\n\t\t\t\t\t\t\treturn response.json();\n\t\t\t\t\t\t})\n\t\t\t\t\t\t.then(render)\n\t\t\t\t\t\t.catch(function (err) {\n\t\t\t\t\t\t\troot.textContent = \"\";\n\t\t\t\t\t\t\troot.appendChild(el(\"p\", \"error\", \"Failed to load the OpenAPI document: \" + err.message));\n\t\t\t\t\t\t});\n\t\t\t\t})();\n\t\t\t</script></body></html>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+var _ = templruntime.GeneratedTemplate
@@ -362,7 +362,7 @@ func LaborTypesPage(laborTypes []models.MasterLaborType, paginationInfo models.P
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "<div class=\"w-full p-4\"><!-- Page Explanation --><div class=\"card bg-gradient-to-r from-emerald-50 to-teal-50 border-l-4 border-emerald-500 shadow-md hover:shadow-lg transition-shadow duration-200\"><div class=\"card-body p-5\"><div class=\"flex items-start gap-4\"><!-- Icon with colored background --><div class=\"flex-shrink-0\"><div class=\"w-12 h-12 rounded-full bg-emerald-100 flex items-center justify-center\"><svg xmlns=\"http://www.w3.org/2000/svg\" class=\"w-6 h-6 text-emerald-600\" fill=\"none\" viewBox=\"0 0 24 24\" stroke=\"currentColor\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M13 16h-1v-4h-1m1-4h.01M21 12a9 9 0 11-18 0 9 9 0 0118 0z\"></path></svg></div></div><!-- Content --><div class=\"flex-1\"><h3 class=\"font-bold text-lg text-gray-900 mb-2\">What are Labor Types?</h3><p class=\"text-sm text-gray-700 leading-relaxed\">Labor Types represent different worker roles or positions in construction (mason, carpenter, painter, etc.). Each type has a daily wage rate. These are used in <strong>AHSP Templates</strong> to define standard labor costs that are automatically applied when creating project work items.</p></div></div></div></div><!-- Action Buttons --><div class=\"flex justify-end mb-4 mt-6\"><button class=\"btn btn-primary\" hx-get=\"/labor_types/new\" hx-target=\"#htmx-modal-container\" hx-swap=\"innerHTML\">Add New Labor Type</button></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "<div class=\"w-full p-4\"><!-- Page Explanation --><div class=\"card bg-gradient-to-r from-emerald-50 to-teal-50 border-l-4 border-emerald-500 shadow-md hover:shadow-lg transition-shadow duration-200\"><div class=\"card-body p-5\"><div class=\"flex items-start gap-4\"><!-- Icon with colored background --><div class=\"flex-shrink-0\"><div class=\"w-12 h-12 rounded-full bg-emerald-100 flex items-center justify-center\"><svg xmlns=\"http://www.w3.org/2000/svg\" class=\"w-6 h-6 text-emerald-600\" fill=\"none\" viewBox=\"0 0 24 24\" stroke=\"currentColor\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M13 16h-1v-4h-1m1-4h.01M21 12a9 9 0 11-18 0 9 9 0 0118 0z\"></path></svg></div></div><!-- Content --><div class=\"flex-1\"><h3 class=\"font-bold text-lg text-gray-900 mb-2\">What are Labor Types?</h3><p class=\"text-sm text-gray-700 leading-relaxed\">Labor Types represent different worker roles or positions in construction (mason, carpenter, painter, etc.). Each type has a daily wage rate. These are used in <strong>AHSP Templates</strong> to define standard labor costs that are automatically applied when creating project work items.</p></div></div></div></div><!-- Action Buttons --><div class=\"flex justify-end gap-2 mb-4 mt-6\"><button class=\"btn btn-outline\" hx-get=\"/labor_types/import\" hx-target=\"#htmx-modal-container\" hx-swap=\"innerHTML\">Import from Excel/CSV</button> <button class=\"btn btn-primary\" hx-get=\"/labor_types/new\" hx-target=\"#htmx-modal-container\" hx-swap=\"innerHTML\">Add New Labor Type</button></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -3,7 +3,12 @@ package components
 import (
 	"fmt"
 	"math"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/momokii/go-rab-maker/backend/models"
 )
 
 // formatCurrency formats a float64 value as Indonesian Rupiah with thousand separators
@@ -31,3 +36,212 @@ func formatCurrency(value float64) string {
 
 	return "Rp " + result.String()
 }
+
+// formatFileSize formats a byte count with a binary unit
+// Example: 1536 -> "1.5 KB"
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGT"[exp])
+}
+
+// formatDuration formats whole days as days and anything else in Go notation without zero units
+// Example: 168h -> "7 days", 90m -> "1h30m"
+func formatDuration(d time.Duration) string {
+	day := 24 * time.Hour
+	if d >= day && d%day == 0 {
+		if d == day {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", d/day)
+	}
+
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// totalBackupSize sums the file sizes of a backup list
+func totalBackupSize(backups []models.BackupFile) int64 {
+	var total int64
+	for _, backup := range backups {
+		total += backup.Size
+	}
+	return total
+}
+
+// recapTotalCost sums the categories of a RAB recap
+func recapTotalCost(recap []models.CategoryRecap) float64 {
+	var total float64
+	for _, category := range recap {
+		total += category.TotalCost
+	}
+	return total
+}
+
+// workItemCategoryName is the category a work item is listed under in the recap
+func workItemCategoryName(workItem models.ProjectWorkItemWithDetails) string {
+	if workItem.CategoryName == "" {
+		return models.WORK_ITEM_UNCATEGORIZED
+	}
+	return workItem.CategoryName
+}
+
+// workItemUnitPrice is the cost of one unit of a work item, 0 without a volume
+func workItemUnitPrice(workItem models.ProjectWorkItemWithDetails, itemTotals map[int]float64) float64 {
+	if workItem.Volume == 0 {
+		return 0
+	}
+	return itemTotals[workItem.WorkItemId] / workItem.Volume
+}
+
+// approvalActionClass is the button style of a step of the approval workflow
+func approvalActionClass(action string) string {
+	switch action {
+	case models.APPROVAL_ACTION_APPROVE:
+		return "btn-success"
+	case models.APPROVAL_ACTION_REJECT:
+		return "btn-error"
+	case models.APPROVAL_ACTION_SUBMIT, models.APPROVAL_ACTION_START_REVIEW:
+		return "btn-primary"
+	}
+	return "btn-outline"
+}
+
+// approvalEventUsername is who took a step, deleted users are no longer named
+func approvalEventUsername(event models.ApprovalEvent) string {
+	if event.Username == "" {
+		return "Deleted user"
+	}
+	return event.Username
+}
+
+// canEditWorkItems reports whether the work items of the project can be changed by the user
+func canEditWorkItems(role string, approval models.ProjectApproval) bool {
+	return models.ProjectRoleAllows(role, models.PROJECT_ROLE_EDITOR) && !approval.IsLocked()
+}
+
+// auditUsername is who made a change, changes without a user were made by the application
+func auditUsername(entry models.AuditEntry) string {
+	if entry.Username == "" {
+		return "System"
+	}
+	return entry.Username
+}
+
+// auditActionClass colours the action of an audit entry
+func auditActionClass(action string) string {
+	switch action {
+	case models.AUDIT_ACTION_CREATE:
+		return "bg-green-100 text-green-800"
+	case models.AUDIT_ACTION_DELETE:
+		return "bg-red-100 text-red-800"
+	}
+	return "bg-blue-100 text-blue-800"
+}
+
+// auditQuery is the query string of the audit page for the filter and page
+func auditQuery(filter models.AuditFilter, page int) string {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"entity":    filter.Entity,
+		"action":    filter.Action,
+		"username":  filter.Username,
+		"date_from": filter.DateFrom,
+		"date_to":   filter.DateTo,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	if filter.ProjectId != 0 {
+		values.Set("project_id", strconv.Itoa(filter.ProjectId))
+	}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+
+	return values.Encode()
+}
+
+// projectHistoryURL is the history tab of a project for the entity filter and page
+func projectHistoryURL(projectId int, entity string, page int) string {
+	values := url.Values{}
+	if entity != "" {
+		values.Set("entity", entity)
+	}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+
+	historyURL := "/project/" + strconv.Itoa(projectId) + "/history"
+	if len(values) > 0 {
+		historyURL += "?" + values.Encode()
+	}
+	return historyURL
+}
+
+// auditPageURL is the audit page, or with format its export, for the filter and page
+func auditPageURL(filter models.AuditFilter, page int, format string) string {
+	pageURL := "/admin/audit"
+	if format != "" {
+		pageURL += "/export"
+	}
+
+	query := auditQuery(filter, page)
+	if format != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += "format=" + format
+	}
+
+	if query != "" {
+		pageURL += "?" + query
+	}
+	return pageURL
+}
+
+// auditProjectIdValue is the project filter as the form shows it, empty for all projects
+func auditProjectIdValue(filter models.AuditFilter) string {
+	if filter.ProjectId == 0 {
+		return ""
+	}
+	return strconv.Itoa(filter.ProjectId)
+}
+
+// trashItemURL is the route restoring or purging an item of the trash
+func trashItemURL(item models.TrashItem, action string) string {
+	return "/trash/" + item.Entity + "/" + strconv.Itoa(item.Id) + "/" + action
+}
+
+// trashPurgeLabel is when the automatic purge deletes an item for good, with the days left
+func trashPurgeLabel(item models.TrashItem, retentionDays int, now time.Time) string {
+	purgeAt := item.PurgeAt(retentionDays)
+	if purgeAt.IsZero() {
+		return "Never"
+	}
+
+	daysLeft := int(math.Ceil(purgeAt.Sub(now).Hours() / 24))
+	switch {
+	case daysLeft <= 0:
+		return purgeAt.Format("2006-01-02") + " (next run)"
+	case daysLeft == 1:
+		return purgeAt.Format("2006-01-02") + " (1 day left)"
+	}
+	return purgeAt.Format("2006-01-02") + " (" + strconv.Itoa(daysLeft) + " days left)"
+}
@@ -9,107 +9,70 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/momokii/go-rab-maker/backend/container"
 	"github.com/momokii/go-rab-maker/backend/databases"
 	"github.com/momokii/go-rab-maker/backend/handlers"
+	"github.com/momokii/go-rab-maker/backend/master_import"
 	"github.com/momokii/go-rab-maker/backend/middlewares"
-	"github.com/momokii/go-rab-maker/backend/repository/ahsp_labor_components"
-	ahsp_material_components "github.com/momokii/go-rab-maker/backend/repository/ahsp_material_components"
-	ahsptemplates "github.com/momokii/go-rab-maker/backend/repository/ahsp_templates"
-	"github.com/momokii/go-rab-maker/backend/repository/dashboard"
-	master_work_categories "github.com/momokii/go-rab-maker/backend/repository/master_work_categories"
-	"github.com/momokii/go-rab-maker/backend/repository/master_labor_types"
-	"github.com/momokii/go-rab-maker/backend/repository/master_materials"
-	"github.com/momokii/go-rab-maker/backend/repository/material_summary"
-	"github.com/momokii/go-rab-maker/backend/repository/project_item_costs"
-	"github.com/momokii/go-rab-maker/backend/repository/project_work_items"
-	"github.com/momokii/go-rab-maker/backend/repository/projects"
+	"github.com/momokii/go-rab-maker/backend/models"
+	"github.com/momokii/go-rab-maker/backend/trash_bin"
 	"github.com/momokii/go-rab-maker/backend/utils"
+	"github.com/momokii/go-rab-maker/backend/webhook_dispatch"
 
 	_ "github.com/joho/godotenv/autoload"
 )
 
 func main() {
-	// databases setup using sqlite
-	databases.InitDatabaseSQLite()
-	dbServices, err := databases.NewSQLiteDatabases(databases.DATABASE_SQLITE_PATH)
+	// databases setup, sqlite unless DATABASE_DRIVER selects postgres
+	databaseConfig, err := databases.LoadDatabaseConfig()
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	dbServices, err := databases.InitDatabase(databaseConfig)
 	if err != nil {
 		log.Println(err.Error())
 		return
 	}
 
-	// repositories
-	materialsRepo := master_materials.NewMasterMaterialsRepo()
-	laborTypesRepo := master_labor_types.NewMasterLaborTypesRepo()
-	workCategoriesRepo := master_work_categories.NewMasterWorkCategoriesRepo()
-	ahspTemplatesRepo := ahsptemplates.NewAhspTemplatesRepo()
-	ahspMaterialComponentsRepo := ahsp_material_components.NewAHSPMaterialComponentsRepo()
-	ahspLaborComponentsRepo := ahsp_labor_components.NewAHSPLaborComponentsRepo()
-	projectWorkItemsRepo := project_work_items.NewProjectWorkItemRepo()
-	projectItemCostsRepo := project_item_costs.NewProjectItemCostsRepo()
-	projectsRepo := projects.NewProjectsRepo()
-	dashboardRepo := dashboard.NewDashboardRepo()
-	materialSummaryRepo := material_summary.NewMaterialSummaryRepo()
-
-	// handlers
-	authHandler := handlers.NewAuthHandler(dbServices)
-	masterMaterialsHandler := handlers.NewMaterialsHandler(
-		dbServices,
-		*materialsRepo,
-	)
-	masterLaborTypesHandler := handlers.NewLaborTypeHandler(
-		dbServices,
-		*laborTypesRepo,
-	)
-	masterWorkCategoriesHandler := handlers.NewWorkCategoryHandler(
-		dbServices,
-		workCategoriesRepo,
-	)
-	ahspTemplatesHandler := handlers.NewAhspTemplateHandler(
-		dbServices,
-		ahspTemplatesRepo,
-	)
-	ahspMaterialComponentHandler := handlers.NewAhspMaterialComponentHandler(
-		dbServices,
-		ahspMaterialComponentsRepo,
-		ahspTemplatesRepo,
-		materialsRepo,
-		ahspLaborComponentsRepo,
-	)
-	ahspLaborComponentHandler := handlers.NewAhspLaborComponentHandler(
-		dbServices,
-		ahspLaborComponentsRepo,
-		ahspTemplatesRepo,
-		laborTypesRepo,
-	)
-	projectsHandler := handlers.NewProjectsHandler(
-		dbServices,
-		projectsRepo,
-	)
-	projectWorkItemsHandler := handlers.NewProjectWorkItemsHandler(
-		dbServices,
-		projectWorkItemsRepo,
-		projectItemCostsRepo,
-		ahspTemplatesRepo,
-		ahspMaterialComponentsRepo,
-		materialsRepo,
-		laborTypesRepo,
-		ahspLaborComponentsRepo,
-	)
-	dashboardHandler := handlers.NewDashboardHandler(
-		dbServices,
-		*dashboardRepo,
-		*projectsRepo,
-	)
-	materialSummaryHandler := handlers.NewMaterialSummaryHandler(
+	// scheduled backups
+	backupScheduleConfig, err := databases.LoadBackupScheduleConfig()
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	backupScheduler := databases.NewBackupScheduler(dbServices, backupScheduleConfig)
+
+	repos := container.NewRepositories()
+
+	// outgoing webhooks, sent from the outbox in the background
+	webhookDispatcher := webhook_dispatch.NewDispatcher(dbServices, repos.Webhooks, repos.WebhookDeliveries, webhook_dispatch.DefaultDispatcherConfig())
+
+	// deleted rows wait in the trash, expired ones are purged in the background
+	trashConfig, err := trash_bin.LoadConfig()
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	trashBin := trash_bin.NewBin(
 		dbServices,
-		*materialSummaryRepo,
-		*projectsRepo,
-		projectItemCostsRepo,
-		*dashboardRepo,
+		repos.Trash,
+		repos.Projects,
+		repos.ProjectWorkItems,
+		repos.Materials,
+		repos.LaborTypes,
+		repos.WorkCategories,
+		repos.AhspTemplates,
+		repos.ProjectApprovals,
+		trashConfig,
 	)
 
-	// session for auth
-	session := middlewares.NewSessionMiddleware()
+	// repositories, handlers and middlewares
+	deps := container.New(dbServices, backupScheduler, webhookDispatcher, trashBin, repos)
+	h := deps.Handlers
+	session := deps.Middlewares.Session
+	roleMiddleware := deps.Middlewares.Role
+	tokenMiddleware := deps.Middlewares.Token
 
 	app := fiber.New(fiber.Config{
 		ServerHeader:    "RAB Maker",
@@ -129,6 +92,10 @@ func main() {
 				}
 
 			}
+			if utils.IsAPIRequest(c) {
+				return utils.ResponseProblem(c, code, err.Error())
+			}
+
 			// handle actual error
 			return utils.ResponseErrorModal(c, "Error "+string(rune(code)), err.Error())
 		},
@@ -138,105 +105,234 @@ func main() {
 	middlewares.SetupAppMiddlewares(app)
 
 	// Authentication routes
-	app.Get("/login", session.IsNotAuth, authHandler.LoginView)
-	app.Post("/login", session.IsNotAuth, authHandler.Login)
-	app.Get("/register", session.IsNotAuth, authHandler.RegisterView)
-	app.Post("/register", session.IsNotAuth, authHandler.Register)
-	app.Post("/logout", session.IsAuth, authHandler.Logout)
-
-	app.Get("/", session.IsAuth, dashboardHandler.Dashboard)
+	app.Get("/login", session.IsNotAuth, h.Auth.LoginView)
+	app.Post("/login", session.IsNotAuth, h.Auth.Login)
+	app.Get("/register", session.IsNotAuth, h.Auth.RegisterView)
+	app.Post("/register", session.IsNotAuth, h.Auth.Register)
+	app.Post("/logout", session.IsAuth, h.Auth.Logout)
+
+	// Route groups check the role of the user (models.ROLE_PERMISSIONS). Everyone signed in
+	// can read; changes need the permission of the group. Whether a system-wide master data
+	// row can be changed is decided by the handlers, only admins may.
+	edits := roleMiddleware.Writes(models.PERMISSION_EDIT)
+	systemData := roleMiddleware.Require(models.PERMISSION_MANAGE_SYSTEM_DATA)
+
+	app.Get("/", session.IsAuth, h.Dashboard.Dashboard)
 	// materials
-	app.Get("/materials", session.IsAuth, masterMaterialsHandler.MaterialsMainPageTableView)
-	app.Get("/materials/new", session.IsAuth, masterMaterialsHandler.MaterialCreateModalView)
-	app.Post("/materials/new", session.IsAuth, masterMaterialsHandler.CreateMaterial)
-	app.Get("/materials/:id/edit", session.IsAuth, masterMaterialsHandler.MaterialEditModalView)
-	app.Post("/materials/:id/edit", session.IsAuth, masterMaterialsHandler.UpdateMaterial)
-	app.Get("/materials/:id/delete", session.IsAuth, masterMaterialsHandler.MaterialDeleteModalView)
-	app.Delete("/materials/:id/delete", session.IsAuth, masterMaterialsHandler.DeleteMaterial)
+	materials := app.Group("/materials", session.IsAuth, edits)
+	materials.Get("/", h.Materials.MaterialsMainPageTableView)
+	materials.Get("/new", h.Materials.MaterialCreateModalView)
+	materials.Post("/new", h.Materials.CreateMaterial)
+	materials.Get("/:id/edit", h.Materials.MaterialEditModalView)
+	materials.Post("/:id/edit", h.Materials.UpdateMaterial)
+	materials.Get("/:id/delete", h.Materials.MaterialDeleteModalView)
+	materials.Delete("/:id/delete", h.Materials.DeleteMaterial)
+	materials.Get("/import", h.MasterImport.MasterImportModalView(master_import.KIND_MATERIALS))
+	materials.Get("/import/template", h.MasterImport.DownloadMasterImportTemplate(master_import.KIND_MATERIALS))
+	materials.Post("/import/preview", h.MasterImport.MasterImportPreviewView(master_import.KIND_MATERIALS))
+	materials.Post("/import/commit", h.MasterImport.CommitMasterImport(master_import.KIND_MATERIALS))
 
 	// labor types
-	app.Get("/labor_types", session.IsAuth, masterLaborTypesHandler.LaborTypesMainPageTableView)
-	app.Get("/labor_types/new", session.IsAuth, masterLaborTypesHandler.LaborTypeCreateModalView)
-	app.Post("/labor_types/new", session.IsAuth, masterLaborTypesHandler.CreateLaborType)
-	app.Get("/labor_types/:id/edit", session.IsAuth, masterLaborTypesHandler.LaborTypeEditModalView)
-	app.Post("/labor_types/:id/edit", session.IsAuth, masterLaborTypesHandler.UpdateLaborType)
-	app.Get("/labor_types/:id/delete", session.IsAuth, masterLaborTypesHandler.LaborTypeDeleteModalView)
-	app.Delete("/labor_types/:id/delete", session.IsAuth, masterLaborTypesHandler.DeleteLaborType)
+	laborTypes := app.Group("/labor_types", session.IsAuth, edits)
+	laborTypes.Get("/", h.LaborTypes.LaborTypesMainPageTableView)
+	laborTypes.Get("/new", h.LaborTypes.LaborTypeCreateModalView)
+	laborTypes.Post("/new", h.LaborTypes.CreateLaborType)
+	laborTypes.Get("/:id/edit", h.LaborTypes.LaborTypeEditModalView)
+	laborTypes.Post("/:id/edit", h.LaborTypes.UpdateLaborType)
+	laborTypes.Get("/:id/delete", h.LaborTypes.LaborTypeDeleteModalView)
+	laborTypes.Delete("/:id/delete", h.LaborTypes.DeleteLaborType)
+	laborTypes.Get("/import", h.MasterImport.MasterImportModalView(master_import.KIND_LABOR_TYPES))
+	laborTypes.Get("/import/template", h.MasterImport.DownloadMasterImportTemplate(master_import.KIND_LABOR_TYPES))
+	laborTypes.Post("/import/preview", h.MasterImport.MasterImportPreviewView(master_import.KIND_LABOR_TYPES))
+	laborTypes.Post("/import/commit", h.MasterImport.CommitMasterImport(master_import.KIND_LABOR_TYPES))
 
 	// work categories
-	app.Get("/work_categories", session.IsAuth, masterWorkCategoriesHandler.WorkCategoriesMainPageTableView)
-	app.Get("/work_categories/new", session.IsAuth, masterWorkCategoriesHandler.WorkCategoryCreateModalView)
-	app.Post("/work_categories/new", session.IsAuth, masterWorkCategoriesHandler.CreateWorkCategory)
-	app.Get("/work_categories/:id/edit", session.IsAuth, masterWorkCategoriesHandler.WorkCategoryEditModalView)
-	app.Post("/work_categories/:id/edit", session.IsAuth, masterWorkCategoriesHandler.UpdateWorkCategory)
-	app.Get("/work_categories/:id/delete", session.IsAuth, masterWorkCategoriesHandler.WorkCategoryDeleteModalView)
-	app.Delete("/work_categories/:id/delete", session.IsAuth, masterWorkCategoriesHandler.DeleteWorkCategory)
-
-	// AHSP templates
-	app.Get("/ahsp_templates", session.IsAuth, ahspTemplatesHandler.AhspTemplatesMainPageTableView)
-	app.Get("/ahsp_templates/new", session.IsAuth, ahspTemplatesHandler.AhspTemplateCreateModalView)
-	app.Post("/ahsp_templates/new", session.IsAuth, ahspTemplatesHandler.CreateAhspTemplate)
-	app.Get("/ahsp_templates/:id/edit", session.IsAuth, ahspTemplatesHandler.AhspTemplateEditModalView)
-	app.Post("/ahsp_templates/:id/edit", session.IsAuth, ahspTemplatesHandler.UpdateAhspTemplate)
-	app.Get("/ahsp_templates/:id/delete", session.IsAuth, ahspTemplatesHandler.AhspTemplateDeleteModalView)
-	app.Delete("/ahsp_templates/:id/delete", session.IsAuth, ahspTemplatesHandler.DeleteAhspTemplate)
+	workCategories := app.Group("/work_categories", session.IsAuth, edits)
+	workCategories.Get("/", h.WorkCategories.WorkCategoriesMainPageTableView)
+	workCategories.Get("/new", h.WorkCategories.WorkCategoryCreateModalView)
+	workCategories.Post("/new", h.WorkCategories.CreateWorkCategory)
+	workCategories.Get("/:id/edit", h.WorkCategories.WorkCategoryEditModalView)
+	workCategories.Post("/:id/edit", h.WorkCategories.UpdateWorkCategory)
+	workCategories.Get("/:id/delete", h.WorkCategories.WorkCategoryDeleteModalView)
+	workCategories.Delete("/:id/delete", h.WorkCategories.DeleteWorkCategory)
+
+	// AHSP templates, the standard library is imported as system-wide templates
+	ahspTemplates := app.Group("/ahsp_templates", session.IsAuth, edits)
+	ahspTemplates.Get("/", h.AhspTemplates.AhspTemplatesMainPageTableView)
+	ahspTemplates.Get("/new", h.AhspTemplates.AhspTemplateCreateModalView)
+	ahspTemplates.Get("/library", systemData, h.AhspLibrary.AhspLibraryImportModalView)
+	ahspTemplates.Post("/library/import", systemData, h.AhspLibrary.ImportAhspLibrary)
+	ahspTemplates.Get("/analysis/export", h.AhspAnalysis.AhspAnalysisExportModalView)
+	ahspTemplates.Get("/analysis/download", h.AhspAnalysis.ExportAhspAnalysis)
+	ahspTemplates.Post("/new", h.AhspTemplates.CreateAhspTemplate)
+	ahspTemplates.Get("/:id/edit", h.AhspTemplates.AhspTemplateEditModalView)
+	ahspTemplates.Post("/:id/edit", h.AhspTemplates.UpdateAhspTemplate)
+	ahspTemplates.Get("/:id/delete", h.AhspTemplates.AhspTemplateDeleteModalView)
+	ahspTemplates.Delete("/:id/delete", h.AhspTemplates.DeleteAhspTemplate)
 
 	// AHSP template material components
-	app.Get("/ahsp_templates/:templateId", session.IsAuth, ahspMaterialComponentHandler.AhspMaterialComponentsPage)
-	app.Get("/ahsp_templates/:templateId/material_components/new", session.IsAuth, ahspMaterialComponentHandler.AhspMaterialComponentCreateModalView)
-	app.Post("/ahsp_templates/:templateId/material_components/new", session.IsAuth, ahspMaterialComponentHandler.CreateAhspMaterialComponent)
-	app.Get("/ahsp_templates/:templateId/material_components/:componentId/edit", session.IsAuth, ahspMaterialComponentHandler.AhspMaterialComponentEditModalView)
-	app.Post("/ahsp_templates/:templateId/material_components/:componentId/edit", session.IsAuth, ahspMaterialComponentHandler.UpdateAhspMaterialComponent)
-	app.Get("/ahsp_templates/:templateId/material_components/:componentId/delete", session.IsAuth, ahspMaterialComponentHandler.AhspMaterialComponentDeleteModalView)
-	app.Delete("/ahsp_templates/:templateId/material_components/:componentId/delete", session.IsAuth, ahspMaterialComponentHandler.DeleteAhspMaterialComponent)
+	ahspTemplates.Get("/:templateId", h.AhspMaterialComponents.AhspMaterialComponentsPage)
+	ahspTemplates.Get("/:templateId/analysis/export", h.AhspAnalysis.AhspAnalysisExportModalView)
+	ahspTemplates.Get("/:templateId/analysis/download", h.AhspAnalysis.ExportAhspAnalysis)
+	ahspTemplates.Get("/:templateId/material_components/new", h.AhspMaterialComponents.AhspMaterialComponentCreateModalView)
+	ahspTemplates.Post("/:templateId/material_components/new", h.AhspMaterialComponents.CreateAhspMaterialComponent)
+	ahspTemplates.Get("/:templateId/material_components/:componentId/edit", h.AhspMaterialComponents.AhspMaterialComponentEditModalView)
+	ahspTemplates.Post("/:templateId/material_components/:componentId/edit", h.AhspMaterialComponents.UpdateAhspMaterialComponent)
+	ahspTemplates.Get("/:templateId/material_components/:componentId/delete", h.AhspMaterialComponents.AhspMaterialComponentDeleteModalView)
+	ahspTemplates.Delete("/:templateId/material_components/:componentId/delete", h.AhspMaterialComponents.DeleteAhspMaterialComponent)
 
 	// AHSP template labor components
-	app.Get("/ahsp_templates/:templateId/labor_components", session.IsAuth, ahspLaborComponentHandler.AhspLaborComponentsPage)
-	app.Get("/ahsp_templates/:templateId/labor_components/new", session.IsAuth, ahspLaborComponentHandler.AhspLaborComponentCreateModalView)
-	app.Post("/ahsp_templates/:templateId/labor_components/new", session.IsAuth, ahspLaborComponentHandler.CreateAhspLaborComponent)
-	app.Get("/ahsp_templates/:templateId/labor_components/:componentId/edit", session.IsAuth, ahspLaborComponentHandler.AhspLaborComponentEditModalView)
-	app.Post("/ahsp_templates/:templateId/labor_components/:componentId/edit", session.IsAuth, ahspLaborComponentHandler.UpdateAhspLaborComponent)
-	app.Get("/ahsp_templates/:templateId/labor_components/:componentId/delete", session.IsAuth, ahspLaborComponentHandler.AhspLaborComponentDeleteModalView)
-	app.Delete("/ahsp_templates/:templateId/labor_components/:componentId/delete", session.IsAuth, ahspLaborComponentHandler.DeleteAhspLaborComponent)
+	ahspTemplates.Get("/:templateId/labor_components", h.AhspLaborComponents.AhspLaborComponentsPage)
+	ahspTemplates.Get("/:templateId/labor_components/new", h.AhspLaborComponents.AhspLaborComponentCreateModalView)
+	ahspTemplates.Post("/:templateId/labor_components/new", h.AhspLaborComponents.CreateAhspLaborComponent)
+	ahspTemplates.Get("/:templateId/labor_components/:componentId/edit", h.AhspLaborComponents.AhspLaborComponentEditModalView)
+	ahspTemplates.Post("/:templateId/labor_components/:componentId/edit", h.AhspLaborComponents.UpdateAhspLaborComponent)
+	ahspTemplates.Get("/:templateId/labor_components/:componentId/delete", h.AhspLaborComponents.AhspLaborComponentDeleteModalView)
+	ahspTemplates.Delete("/:templateId/labor_components/:componentId/delete", h.AhspLaborComponents.DeleteAhspLaborComponent)
 
 	// projects
-	app.Get("/projects", session.IsAuth, projectsHandler.ProjectsMainPageTableView)
-	app.Get("/projects/new", session.IsAuth, projectsHandler.ProjectCreateModalView)
-	app.Post("/projects/new", session.IsAuth, projectsHandler.CreateProject)
-	app.Get("/projects/:id/edit", session.IsAuth, projectsHandler.ProjectEditModalView)
-	app.Post("/projects/:id/edit", session.IsAuth, projectsHandler.UpdateProject)
-	app.Get("/projects/:id/delete", session.IsAuth, projectsHandler.ProjectDeleteModalView)
-	app.Delete("/projects/:id/delete", session.IsAuth, projectsHandler.DeleteProject)
+	projects := app.Group("/projects", session.IsAuth, edits)
+	projects.Get("/", h.Projects.ProjectsMainPageTableView)
+	projects.Get("/new", h.Projects.ProjectCreateModalView)
+	projects.Get("/import", h.ProjectBundle.ProjectBundleImportModalView)
+	projects.Post("/import", h.ProjectBundle.ImportProjectBundle)
+	projects.Post("/new", h.Projects.CreateProject)
+	projects.Get("/:id/edit", h.Projects.ProjectEditModalView)
+	projects.Post("/:id/edit", h.Projects.UpdateProject)
+	projects.Get("/:id/delete", h.Projects.ProjectDeleteModalView)
+	projects.Delete("/:id/delete", h.Projects.DeleteProject)
+
+	// Project Material Summary routes
+	projects.Get("/:id/material-summary", h.MaterialSummary.ProjectMaterialSummary)
+	projects.Get("/:id/material-summary/export", h.MaterialSummary.ExportProjectMaterialSummary)
 
-	// project detail page
-	app.Get("/project/:id", session.IsAuth, projectWorkItemsHandler.ProjectDetailPage)
+	// project detail page, with a parameter the group does not also match /projects
+	project := app.Group("/project/:id", session.IsAuth, edits)
+	project.Get("/", h.ProjectWorkItems.ProjectDetailPage)
 
 	// project work items
-	app.Get("/project/:id/work-items/new", session.IsAuth, projectWorkItemsHandler.ProjectWorkItemCreateModalView)
-	app.Post("/project/:id/work-items", session.IsAuth, projectWorkItemsHandler.CreateProjectWorkItem)
-	app.Get("/project/:id/work-items/:workItemId/edit", session.IsAuth, projectWorkItemsHandler.ProjectWorkItemEditModalView)
-	app.Post("/project/:id/work-items/:workItemId/edit", session.IsAuth, projectWorkItemsHandler.UpdateProjectWorkItem)
-	app.Get("/project/:id/work-items/:workItemId/delete", session.IsAuth, projectWorkItemsHandler.ProjectWorkItemDeleteModalView)
-	app.Delete("/project/:id/work-items/:workItemId/delete", session.IsAuth, projectWorkItemsHandler.DeleteProjectWorkItem)
+	project.Get("/work-items/new", h.ProjectWorkItems.ProjectWorkItemCreateModalView)
+	project.Post("/work-items", h.ProjectWorkItems.CreateProjectWorkItem)
+	project.Get("/work-items/:workItemId/edit", h.ProjectWorkItems.ProjectWorkItemEditModalView)
+	project.Post("/work-items/:workItemId/edit", h.ProjectWorkItems.UpdateProjectWorkItem)
+	project.Get("/work-items/:workItemId/delete", h.ProjectWorkItems.ProjectWorkItemDeleteModalView)
+	project.Delete("/work-items/:workItemId/delete", h.ProjectWorkItems.DeleteProjectWorkItem)
+
+	// share the project with single users, as editors or viewers
+	project.Get("/members", h.ProjectMembers.ProjectMembersModalView)
+	project.Post("/members", h.ProjectMembers.InviteProjectMember)
+	project.Delete("/members/:userId", h.ProjectMembers.RemoveProjectMember)
+
+	// read-only links for clients without an account
+	project.Get("/share-links", h.ShareLinks.ShareLinksModalView)
+	project.Post("/share-links", h.ShareLinks.CreateShareLink)
+	project.Delete("/share-links/:linkId", h.ShareLinks.RevokeShareLink)
+
+	// changes to the project, its work items and their costs
+	project.Get("/history", h.AuditLog.ProjectHistoryView)
+	project.Get("/history/export", h.AuditLog.ExportProjectHistory)
+
+	// RAB workbook export
+	project.Get("/export/excel", h.ProjectExport.ExportProjectRab)
+	project.Get("/export/bundle", h.ProjectBundle.ExportProjectBundle)
+
+	// import an existing RAB spreadsheet into a project
+	project.Get("/import", h.RabImport.RabImportModalView)
+	project.Post("/import/preview", h.RabImport.RabImportPreviewView)
+	project.Post("/import/commit", h.RabImport.CommitRabImport)
+
+	// approval workflow, outside the project group so reviewers without edit rights take their steps
+	approvals := app.Group("/approvals", session.IsAuth)
+	approvals.Get("/", h.ProjectApprovals.ApprovalsView)
+	approvals.Get("/:id", h.ProjectApprovals.ProjectApprovalModalView)
+	approvals.Post("/:id", h.ProjectApprovals.ChangeApprovalStatus)
+
+	// the shared project a link opens, without signing in
+	share := app.Group("/share/:token")
+	share.Get("/", h.ShareLinks.SharedProjectView)
+	share.Post("/", h.ShareLinks.UnlockSharedProject)
+	share.Get("/export/excel", h.ShareLinks.ExportSharedRab)
+	share.Get("/material-summary/export", h.ShareLinks.ExportSharedMaterialSummary)
 
 	// project work item costs
-	app.Get("/work-items/:id/costs", session.IsAuth, projectWorkItemsHandler.ProjectWorkItemCostsView)
+	app.Get("/work-items/:id/costs", session.IsAuth, h.ProjectWorkItems.ProjectWorkItemCostsView)
 
 	// Dashboard route (replacing countries as the main landing page)
-	app.Get("/dashboard", session.IsAuth, dashboardHandler.Dashboard)
+	app.Get("/dashboard", session.IsAuth, h.Dashboard.Dashboard)
 
 	// Material Summary routes
-	app.Get("/material-summary", session.IsAuth, materialSummaryHandler.MaterialSummary)
-	app.Get("/material-summary/export", session.IsAuth, materialSummaryHandler.ExportMaterialSummary)
-
-	// Project Material Summary routes
-	app.Get("/projects/:id/material-summary", session.IsAuth, materialSummaryHandler.ProjectMaterialSummary)
-	app.Get("/projects/:id/material-summary/export", session.IsAuth, materialSummaryHandler.ExportProjectMaterialSummary)
+	app.Get("/material-summary", session.IsAuth, h.MaterialSummary.MaterialSummary)
+	app.Get("/material-summary/export", session.IsAuth, h.MaterialSummary.ExportMaterialSummary)
+
+	// deleted projects, work items and master data, to restore or delete for good
+	trash := app.Group("/trash", session.IsAuth, edits)
+	trash.Get("/", h.Trash.TrashView)
+	trash.Post("/:entity/:id/restore", h.Trash.RestoreTrashItem)
+	trash.Delete("/:entity/:id/delete", h.Trash.PurgeTrashItem)
+
+	// Administration: users, database backups and the audit log
+	admin := app.Group("/admin", session.IsAuth, roleMiddleware.IsAdmin)
+	admin.Get("/users", h.Users.UsersView)
+	admin.Post("/users/:id/role", h.Users.UpdateUserRole)
+	admin.Post("/users/:id/disable", h.Users.DisableUser)
+	admin.Post("/users/:id/enable", h.Users.EnableUser)
+	admin.Get("/backups", h.Backup.BackupsView)
+	admin.Post("/backups", h.Backup.CreateBackup)
+	admin.Get("/backups/upload", h.Backup.UploadBackupModalView)
+	admin.Post("/backups/upload", h.Backup.UploadBackup)
+	admin.Get("/backups/:name/download", h.Backup.DownloadBackup)
+	admin.Get("/backups/:name/restore", h.Backup.RestoreBackupModalView)
+	admin.Post("/backups/:name/restore", h.Backup.RestoreBackup)
+	admin.Get("/audit", h.AuditLog.AuditLogView)
+	admin.Get("/audit/export", h.AuditLog.ExportAuditLog)
+
+	// personal settings
+	settings := app.Group("/settings", session.IsAuth)
+
+	// personal access tokens for the JSON API
+	settings.Get("/tokens", h.APITokens.APITokensView)
+	settings.Get("/tokens/new", h.APITokens.APITokenCreateModalView)
+	settings.Post("/tokens/new", h.APITokens.CreateAPIToken)
+	settings.Post("/tokens/:id/revoke", h.APITokens.RevokeAPIToken)
+
+	// organizations sharing master data and projects
+	settings.Get("/organizations", h.Organizations.OrganizationsView)
+	settings.Get("/organizations/new", h.Organizations.OrganizationCreateModalView)
+	settings.Post("/organizations/new", h.Organizations.CreateOrganization)
+	settings.Get("/organizations/:id", h.Organizations.OrganizationMembersView)
+	settings.Post("/organizations/:id/members", h.Organizations.AddOrganizationMember)
+	settings.Delete("/organizations/:id/members/:userId", h.Organizations.RemoveOrganizationMember)
+	settings.Delete("/organizations/:id/delete", h.Organizations.DeleteOrganization)
+
+	// the workspace the pages work in, picked with the switcher of the top bar
+	app.Get("/workspace/switcher", session.IsAuth, h.Organizations.WorkspaceSwitcherView)
+	app.Post("/workspace", session.IsAuth, h.Organizations.SwitchWorkspace)
+
+	// outgoing webhooks and their delivery log
+	settings.Get("/webhooks", h.Webhooks.WebhooksView)
+	settings.Get("/webhooks/new", h.Webhooks.WebhookCreateModalView)
+	settings.Post("/webhooks/new", h.Webhooks.CreateWebhook)
+	settings.Get("/webhooks/:id", h.Webhooks.WebhookDeliveriesView)
+	settings.Post("/webhooks/:id/toggle", h.Webhooks.ToggleWebhook)
+	settings.Post("/webhooks/:id/ping", h.Webhooks.PingWebhook)
+	settings.Delete("/webhooks/:id/delete", h.Webhooks.DeleteWebhook)
+	settings.Post("/webhooks/:id/deliveries/:deliveryId/retry", h.Webhooks.RetryWebhookDelivery)
+
+	// OpenAPI document of the JSON API and its docs, generated from the same routes
+	app.Get(handlers.OPENAPI_PATH, h.OpenAPI.OpenAPIJSON)
+	app.Get(handlers.API_DOCS_PATH, h.OpenAPI.APIDocsView)
+
+	// JSON API, the routes are listed by the handlers. A bearer token is checked first, the session otherwise,
+	// and the role of the user decides whether it may change data
+	api := app.Group(handlers.API_V1_PATH, tokenMiddleware.IsAuthBearer, session.IsAuthAPI, edits)
+	for _, route := range h.API.Routes() {
+		api.Add(route.Method, route.Path, route.Handler)
+	}
 
-	startServerWithGracefulShutdown(app)
+	startServerWithGracefulShutdown(app, backupScheduler, webhookDispatcher, trashBin)
 }
 
-func startServerWithGracefulShutdown(app *fiber.App) {
+func startServerWithGracefulShutdown(app *fiber.App, backupScheduler *databases.BackupScheduler, webhookDispatcher *webhook_dispatch.Dispatcher, trashBin *trash_bin.Bin) {
 	// channel for shutdown signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -255,6 +351,11 @@ func startServerWithGracefulShutdown(app *fiber.App) {
 
 	log.Println("Server started successfully.")
 
+	// background jobs run alongside the server and stop before it shuts down
+	backupScheduler.Start()
+	webhookDispatcher.Start()
+	trashBin.Start()
+
 	// Wait for shutdown signal
 	<-quit
 	log.Println("Shutdown signal received...")
@@ -266,6 +367,10 @@ func startServerWithGracefulShutdown(app *fiber.App) {
 	// Graceful shutdown
 	log.Println("Gracefully shutting down...")
 
+	backupScheduler.Stop()
+	webhookDispatcher.Stop()
+	trashBin.Stop()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 